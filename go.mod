@@ -1,10 +1,20 @@
 module github.com/mariotoffia/goservice
 
 require (
+	github.com/DATA-DOG/go-sqlmock v1.5.2
 	github.com/ahmetb/go-linq/v3 v3.2.0
 	github.com/aws/aws-sdk-go-v2 v1.3.4
 	github.com/aws/aws-sdk-go-v2/service/kms v1.2.2
 	github.com/stretchr/testify v1.6.1
+	golang.org/x/crypto v0.17.0
+	gopkg.in/yaml.v3 v3.0.0-20200313102051-9f266ea9e77c
 )
 
-go 1.16
+require (
+	github.com/aws/smithy-go v1.3.1 // indirect
+	github.com/davecgh/go-spew v1.1.0 // indirect
+	github.com/pmezard/go-difflib v1.0.0 // indirect
+	golang.org/x/sys v0.15.0 // indirect
+)
+
+go 1.20
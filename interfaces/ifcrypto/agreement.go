@@ -0,0 +1,12 @@
+package ifcrypto
+
+// KeyAgreement is implemented by private keys that may derive a shared secret with a peer,
+// for example _ECDH_ over _X25519_ or the _NIST_ curves. Remote keys may implement it by
+// deriving the secret within the _KMS_ or hardware unit.
+//
+// The shared secret is the raw output of the key agreement and must be passed through a key
+// derivation function, such as _HKDF_, before it is used as a key.
+type KeyAgreement interface {
+	// DeriveSharedSecret derives the shared secret between this private key and the _peer_ public key.
+	DeriveSharedSecret(peer PublicKey) ([]byte, error)
+}
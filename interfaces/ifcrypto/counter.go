@@ -0,0 +1,22 @@
+package ifcrypto
+
+import "context"
+
+// CounterStore is a persistent, monotonically increasing, set of named counters.
+//
+// It is used by counter based nonces, _HOTP_ and rollback protection where a value
+// must never be handed out twice, not even across process restarts or crashes.
+//
+// Implementations must make sure that the new counter value is durable before it
+// is returned. It is allowed to skip values (e.g. after a crash) but never to repeat one.
+type CounterStore interface {
+	// Next increments the counter _name_ and returns the new value.
+	//
+	// A counter that do not exist starts at zero, hence the first value returned is one.
+	Next(c context.Context, name string) (uint64, error)
+	// Reserve reserves _n_ consecutive values of the counter _name_ and returns the
+	// first value. The caller owns the values _first_ up to and including _first + n - 1_.
+	Reserve(c context.Context, name string, n uint64) (first uint64, err error)
+	// Current returns the last value handed out for the counter _name_ (zero if none).
+	Current(c context.Context, name string) (uint64, error)
+}
@@ -0,0 +1,109 @@
+package ifcrypto
+
+import (
+	"context"
+	"sync"
+)
+
+// CryptoDecision is a policy decision recorded in a `CryptoContext`.
+type CryptoDecision struct {
+	Operation PolicyOperation `json:"operation"`
+	KeyID     string          `json:"key_id,omitempty"`
+	Allow     bool            `json:"allow"`
+	Reason    string          `json:"reason,omitempty"`
+}
+
+// CryptoContext describes on whose behalf crypto operations are performed. It flows with the
+// `context.Context` through sign and encrypt calls, see `WithCryptoContext`, and is embedded
+// into audit events and envelope metadata such that a downstream verifier can reconstruct who
+// encrypted what on behalf of whom.
+type CryptoContext struct {
+	// KeyID is the key the operations are performed with, if known up front.
+	KeyID     string `json:"key_id,omitempty"`
+	Tenant    string `json:"tenant,omitempty"`
+	RequestID string `json:"request_id,omitempty"`
+	// Principal is the caller, it is used as policy principal unless `WithPolicyPrincipal` is set.
+	Principal string `json:"principal,omitempty"`
+	// Decisions is the policy decisions made in the scope of the context, in order.
+	Decisions []CryptoDecision `json:"decisions,omitempty"`
+}
+
+// IsZero returns `true` if nothing is set.
+func (cc CryptoContext) IsZero() bool {
+
+	return cc.KeyID == "" && cc.Tenant == "" && cc.RequestID == "" &&
+		cc.Principal == "" && len(cc.Decisions) == 0
+
+}
+
+// Metadata returns the set fields, except decisions, as a flat map, e.g. to be used as _KMS_
+// encryption context or transport headers.
+func (cc CryptoContext) Metadata() map[string]string {
+
+	metadata := map[string]string{}
+
+	for k, v := range map[string]string{
+		"key_id": cc.KeyID, "tenant": cc.Tenant, "request_id": cc.RequestID, "principal": cc.Principal,
+	} {
+
+		if v != "" {
+			metadata[k] = v
+		}
+
+	}
+
+	return metadata
+
+}
+
+// cryptoContextCarrier holds the `CryptoContext` of a `context.Context` such that decisions
+// can be recorded while the context flows downwards.
+type cryptoContextCarrier struct {
+	mu sync.Mutex
+	cc CryptoContext
+}
+
+type cryptoContextKey struct{}
+
+// WithCryptoContext returns a copy of _c_ that carries _cc_. Decisions recorded in the returned
+// context, see `RecordCryptoDecision`, are not seen by _c_.
+func WithCryptoContext(c context.Context, cc CryptoContext) context.Context {
+
+	cc.Decisions = append([]CryptoDecision{}, cc.Decisions...)
+
+	return context.WithValue(c, cryptoContextKey{}, &cryptoContextCarrier{cc: cc})
+
+}
+
+// CryptoContextFrom returns a copy of the `CryptoContext` of _c_, or a zero one if not set.
+func CryptoContextFrom(c context.Context) CryptoContext {
+
+	carrier, ok := c.Value(cryptoContextKey{}).(*cryptoContextCarrier)
+	if !ok {
+		return CryptoContext{}
+	}
+
+	carrier.mu.Lock()
+	defer carrier.mu.Unlock()
+
+	cc := carrier.cc
+	cc.Decisions = append([]CryptoDecision{}, carrier.cc.Decisions...)
+
+	return cc
+
+}
+
+// RecordCryptoDecision appends the _decision_ to the `CryptoContext` of _c_, if any.
+func RecordCryptoDecision(c context.Context, decision CryptoDecision) {
+
+	carrier, ok := c.Value(cryptoContextKey{}).(*cryptoContextCarrier)
+	if !ok {
+		return
+	}
+
+	carrier.mu.Lock()
+	defer carrier.mu.Unlock()
+
+	carrier.cc.Decisions = append(carrier.cc.Decisions, decision)
+
+}
@@ -0,0 +1,24 @@
+package ifcrypto
+
+import (
+	"context"
+	"fmt"
+)
+
+// ErrDataKeyNotFound is returned by a `DataKeyStore` when there is no key with the id.
+var ErrDataKeyNotFound = fmt.Errorf("data key not found")
+
+// DataKeyStore stores wrapped per-record data encryption keys.
+//
+// It is used for crypto-shredding where each record, or user, has its own key and deleting
+// the key renders all data encrypted with it unrecoverable. An implementation must make
+// sure that a deleted key is not recoverable, e.g. from backups, once the retention of the
+// store has passed.
+type DataKeyStore interface {
+	// Put stores the _wrapped_ key under _id_. It fails if the id already exists.
+	Put(c context.Context, id string, wrapped []byte) error
+	// Get returns the wrapped key with _id_ or `ErrDataKeyNotFound`.
+	Get(c context.Context, id string) ([]byte, error)
+	// Delete removes the keys with _ids_. Ids that do not exist are ignored.
+	Delete(c context.Context, ids ...string) error
+}
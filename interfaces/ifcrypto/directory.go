@@ -0,0 +1,17 @@
+package ifcrypto
+
+import (
+	"context"
+	"fmt"
+)
+
+// ErrServiceKeyNotFound is returned by a `PublicKeyDirectory` when the service has no key for the usage.
+var ErrServiceKeyNotFound = fmt.Errorf("service key not found")
+
+// PublicKeyDirectory maps service names to their current public keys.
+type PublicKeyDirectory interface {
+	// Lookup returns the current public key of _service_ that has the _usage_, e.g.
+	// `KeyUsageVerify` for the key that verifies the service signatures or
+	// `KeyUsageKeyAgreement` for the key to encrypt to the service with.
+	Lookup(c context.Context, service string, usage KeyUsage) (PublicKey, error)
+}
@@ -0,0 +1,43 @@
+package ifcrypto
+
+import (
+	"context"
+	"time"
+)
+
+// EscrowRecord is a escrowed copy of a private key.
+//
+// The key is _PKCS #8_ encoded and encrypted with a one time _AES-256-GCM_ key that in turn is
+// wrapped to the escrow public key. Each record holds the hash of the previous record, hence
+// any modification, removal or reordering of records breaks the chain.
+type EscrowRecord struct {
+	// Sequence is the position of the record in the chain, starting at one.
+	Sequence uint64 `json:"sequence"`
+	// KeyID is the id of the escrowed key.
+	KeyID string `json:"key_id"`
+	// KeyType is the type of the escrowed key.
+	KeyType KeyType `json:"key_type"`
+	// Created is when the key was escrowed.
+	Created time.Time `json:"created"`
+	// EscrowKeyID identifies the escrow public key that the key is wrapped to.
+	EscrowKeyID string `json:"escrow_key_id"`
+	// WrappedKey is the content encryption key wrapped to the escrow public key.
+	WrappedKey []byte `json:"wrapped_key"`
+	// Ciphertext is the encrypted private key.
+	Ciphertext []byte `json:"ciphertext"`
+	// PrevHash is the hash of the previous record, empty for the first record.
+	PrevHash []byte `json:"prev_hash"`
+	// Hash is the hash of this record, including the _PrevHash_.
+	Hash []byte `json:"hash"`
+}
+
+// EscrowSink is a write once, read many, store of `EscrowRecord`.
+//
+// An implementation must never overwrite or delete a record and must reject a record whose
+// sequence do not directly follow the last stored record.
+type EscrowSink interface {
+	// Append stores the _record_ last in the chain.
+	Append(c context.Context, record EscrowRecord) error
+	// Last returns the last record or `nil` if the sink is empty.
+	Last(c context.Context) (*EscrowRecord, error)
+}
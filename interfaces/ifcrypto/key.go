@@ -18,6 +18,11 @@ const (
 	KeyUsageDecrypt KeyUsage = "decrypt"
 	// KeyUsageEncrypt allows the key do encrypt a message
 	KeyUsageEncrypt KeyUsage = "encrypt"
+	// KeyUsageKeyAgreement allows the key to derive a shared secret with a peer
+	KeyUsageKeyAgreement KeyUsage = "key-agreement"
+	// KeyUsageExport marks, at creation, that the private key material may be exported. Keys
+	// without this usage are non-exportable.
+	KeyUsageExport KeyUsage = "export"
 )
 
 // KeyType is the type of key
@@ -27,8 +32,12 @@ const (
 	KeyTypeRsa           KeyType = "rsa"
 	KeyTypeEccNistP      KeyType = "ecc-nist-p"
 	KeyTypeEccSecgP256k1 KeyType = "ecc-secg_p256K1"
+	KeyTypeEd25519       KeyType = "ed25519"
+	KeyTypeX25519        KeyType = "x25519"
+	// KeyTypeHmac is a symmetric key used to sign and verify using _HMAC_.
+	KeyTypeHmac KeyType = "hmac"
 	// KeyTypeSymmetric is a key to use for symmetric operations in contrast to all other
-	// `KeyType` where those are asymmetric.
+	// `KeyType`, except `KeyTypeHmac`, where those are asymmetric.
 	KeyTypeSymmetric KeyType = "symmetric"
 )
 
@@ -37,8 +46,11 @@ const (
 // If omitted, empty array, it is possibly unlimited.
 var KeySizes = map[KeyType][]int{
 	KeyTypeRsa:           {2048, 3072, 4096},
-	KeyTypeEccNistP:      {256, 384, 521},
+	KeyTypeEccNistP:      {224, 256, 384, 521},
 	KeyTypeEccSecgP256k1: {256},
+	KeyTypeEd25519:       {256},
+	KeyTypeX25519:        {256},
+	KeyTypeHmac:          {},
 	KeyTypeSymmetric:     {},
 }
 
@@ -56,12 +68,23 @@ const (
 	SignAlgorithmEcdSha256         SignAlgorithm = "ecd-sha256"
 	SignAlgorithmEcdSha384         SignAlgorithm = "ecd-sha384"
 	SignAlgorithmEcdSha512         SignAlgorithm = "ecd-sha512"
+	SignAlgorithmEd25519           SignAlgorithm = "ed25519"
+	SignAlgorithmHmacSha256        SignAlgorithm = "hmac-sha256"
+	SignAlgorithmHmacSha384        SignAlgorithm = "hmac-sha384"
+	SignAlgorithmHmacSha512        SignAlgorithm = "hmac-sha512"
 )
 
 type Chipher string
 
 const (
+	ChiperAES128 Chipher = "aes128"
+	ChiperAES192 Chipher = "aes192"
 	ChiperAES256 Chipher = "aes256"
+	// ChiperChaCha20Poly1305 is the _RFC 8439_ _AEAD_ with a 96 bit nonce.
+	ChiperChaCha20Poly1305 Chipher = "chacha20-poly1305"
+	// ChiperXChaCha20Poly1305 is the extended nonce variant with a 192 bit nonce, safe to
+	// generate at random for any number of messages.
+	ChiperXChaCha20Poly1305 Chipher = "xchacha20-poly1305"
 )
 
 // Key represents a single key.
@@ -0,0 +1,75 @@
+package ifcrypto
+
+import (
+	"context"
+	"fmt"
+)
+
+// ErrKeyNotFound is returned by a `KeyStore` when there is no key with the id.
+var ErrKeyNotFound = fmt.Errorf("key not found")
+
+// ErrKeyExists is returned by a `KeyStore` when a key with the id already exists.
+var ErrKeyExists = fmt.Errorf("key already exists")
+
+// KeyFilter selects keys in `KeyStore.List`. A empty filter selects all keys.
+type KeyFilter struct {
+	// Usages, when set, selects keys that have at least one of the usages.
+	Usages []KeyUsage
+	// KeyTypes, when set, selects keys of one of the types.
+	KeyTypes []KeyType
+}
+
+// Match returns `true` if _key_ is selected by the filter.
+func (f KeyFilter) Match(key Key) bool {
+
+	if len(f.KeyTypes) > 0 {
+
+		found := false
+
+		for _, kt := range f.KeyTypes {
+
+			if kt == key.GetKeyType() {
+				found = true
+				break
+			}
+
+		}
+
+		if !found {
+			return false
+		}
+
+	}
+
+	if len(f.Usages) == 0 {
+		return true
+	}
+
+	for _, want := range f.Usages {
+
+		for _, usage := range key.GetKeyUsage() {
+
+			if usage == want {
+				return true
+			}
+
+		}
+
+	}
+
+	return false
+
+}
+
+// KeyStore holds keys by their id, such that a service registers its keys once and resolves
+// them by id when used.
+type KeyStore interface {
+	// Get returns the key with _id_ or `ErrKeyNotFound`.
+	Get(c context.Context, id string) (Key, error)
+	// Put stores the _key_ under its id. It fails with `ErrKeyExists` if the id already exists.
+	Put(c context.Context, key Key) error
+	// Delete removes the keys with _ids_. Ids that do not exist are ignored.
+	Delete(c context.Context, ids ...string) error
+	// List returns the keys selected by _filter_, sorted by id.
+	List(c context.Context, filter KeyFilter) ([]Key, error)
+}
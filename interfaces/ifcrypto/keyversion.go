@@ -0,0 +1,26 @@
+package ifcrypto
+
+import (
+	"context"
+	"time"
+)
+
+// KeyVersion is a single version of a rotated key.
+type KeyVersion struct {
+	// KeyID is the id of the key that the version belongs to.
+	KeyID string `json:"key_id"`
+	// Version identifies the version within the key.
+	Version string `json:"version"`
+	// Created is when the version was created.
+	Created time.Time `json:"created"`
+	// Retired is when the version stopped being used for new operations, zero if it is active.
+	Retired time.Time `json:"retired,omitempty"`
+}
+
+// KeyVersionStore lists and destroys key versions.
+type KeyVersionStore interface {
+	// ListVersions returns all versions of all keys.
+	ListVersions(c context.Context) ([]KeyVersion, error)
+	// DestroyVersion irrecoverably destroys the key material of the _version_.
+	DestroyVersion(c context.Context, version KeyVersion) error
+}
@@ -0,0 +1,25 @@
+package ifcrypto
+
+import (
+	"context"
+	"fmt"
+)
+
+var (
+	// ErrAlreadyStarted is returned when starting a `Lifecycle` that has already been started.
+	ErrAlreadyStarted = fmt.Errorf("already started")
+	// ErrDrainTimeout is returned by `Lifecycle.Stop` when in-flight operations did not complete
+	// before the context was done. The operations are then canceled.
+	ErrDrainTimeout = fmt.Errorf("drain timed out")
+)
+
+// Lifecycle is implemented by background components, e.g. rotation schedulers and cache
+// refreshers, such that services embedding them can shut down cleanly.
+type Lifecycle interface {
+	// Start starts the background work.
+	Start(c context.Context) error
+	// Stop stops accepting new work and waits for in-flight operations to complete. When _c_
+	// is done before, the operations are canceled and a error wrapping `ErrDrainTimeout` is
+	// returned.
+	Stop(c context.Context) error
+}
@@ -11,3 +11,20 @@ type PEMWriter interface {
 	// will in addition write the public portion as well.
 	PEMWrite(w io.Writer, public bool) error
 }
+
+// EncryptedPEMWriter is implemented by private keys that may be written encrypted, such that
+// the key never hits disk in plaintext.
+type EncryptedPEMWriter interface {
+
+	// PEMWriteEncrypted will write the private key onto _w_ as a _PKCS #8_
+	// _ENCRYPTED PRIVATE KEY_ encrypted with _passphrase_.
+	PEMWriteEncrypted(w io.Writer, passphrase []byte) error
+}
+
+// DERWriter allows for writing the key as binary _DER_, e.g. for a _HSM_ or a _Java_ keystore.
+type DERWriter interface {
+
+	// DERWrite will write the _DER_ of the key onto _w_. Private keys are written as _PKCS #8_,
+	// public keys as _PKIX_ and symmetric keys as the raw key bytes.
+	DERWrite(w io.Writer) error
+}
@@ -0,0 +1,89 @@
+package ifcrypto
+
+import (
+	"context"
+	"fmt"
+)
+
+// ErrPolicyDenied is returned when a `PolicyEngine` denies a operation.
+var ErrPolicyDenied = fmt.Errorf("denied by policy")
+
+// PolicyOperation is the operation that a `PolicyEngine` is asked to authorize.
+type PolicyOperation string
+
+const (
+	// PolicyOperationSign is a sign operation with a private key.
+	PolicyOperationSign PolicyOperation = "sign"
+	// PolicyOperationVerify is a verify operation with a public key.
+	PolicyOperationVerify PolicyOperation = "verify"
+	// PolicyOperationEncrypt is a encrypt operation with a public or symmetric key.
+	PolicyOperationEncrypt PolicyOperation = "encrypt"
+	// PolicyOperationDecrypt is a decrypt operation with a private key.
+	PolicyOperationDecrypt PolicyOperation = "decrypt"
+	// PolicyOperationKeyAgreement derives a shared secret with a private key.
+	PolicyOperationKeyAgreement PolicyOperation = "key-agreement"
+	// PolicyOperationExport exports private key material.
+	PolicyOperationExport PolicyOperation = "export"
+	// PolicyOperationIssueCertificate issues a certificate.
+	PolicyOperationIssueCertificate PolicyOperation = "issue-certificate"
+)
+
+// PolicyCertificate describes the certificate of a `PolicyOperationIssueCertificate`.
+type PolicyCertificate struct {
+	Subject     string   `json:"subject"`
+	DNSNames    []string `json:"dns_names,omitempty"`
+	IPAddresses []string `json:"ip_addresses,omitempty"`
+	URIs        []string `json:"uris,omitempty"`
+	Emails      []string `json:"emails,omitempty"`
+	Issuer      string   `json:"issuer"`
+	// Validity is the certificate lifetime in seconds.
+	Validity int64 `json:"validity"`
+	IsCA     bool  `json:"is_ca"`
+}
+
+// PolicyInput is the input document of a policy evaluation.
+type PolicyInput struct {
+	Operation PolicyOperation `json:"operation"`
+	// Principal is the caller, e.g. a service or user, see `WithPolicyPrincipal`.
+	Principal string     `json:"principal,omitempty"`
+	KeyID     string     `json:"key_id,omitempty"`
+	KeyType   KeyType    `json:"key_type,omitempty"`
+	KeyUsage  []KeyUsage `json:"key_usage,omitempty"`
+	// Certificate is set for `PolicyOperationIssueCertificate`.
+	Certificate *PolicyCertificate `json:"certificate,omitempty"`
+	// Attributes is operation specific attributes, e.g. the export recipient.
+	Attributes map[string]string `json:"attributes,omitempty"`
+}
+
+// PolicyDecision is the outcome of a policy evaluation.
+type PolicyDecision struct {
+	Allow bool `json:"allow"`
+	// Reason is a human readable reason, typically set when denied.
+	Reason string `json:"reason,omitempty"`
+}
+
+// PolicyEngine evaluates "who may do what with which key" outside of code.
+//
+// An error means that no decision could be made and the operation must be denied.
+type PolicyEngine interface {
+	Evaluate(c context.Context, input PolicyInput) (PolicyDecision, error)
+}
+
+type policyPrincipalKey struct{}
+
+// WithPolicyPrincipal returns a copy of _c_ that carries the _principal_ evaluated by policies.
+func WithPolicyPrincipal(c context.Context, principal string) context.Context {
+	return context.WithValue(c, policyPrincipalKey{}, principal)
+}
+
+// PolicyPrincipal returns the principal of _c_, or the principal of its `CryptoContext`, or a
+// empty string if neither is set.
+func PolicyPrincipal(c context.Context) string {
+
+	if principal, ok := c.Value(policyPrincipalKey{}).(string); ok {
+		return principal
+	}
+
+	return CryptoContextFrom(c).Principal
+
+}
@@ -0,0 +1,21 @@
+package ifcrypto
+
+import (
+	"context"
+	"time"
+)
+
+// ReplayCache tracks single use identifiers, such as a _JWT_ _jti_, a _DPoP_ proof _jti_ or
+// a webhook nonce, to detect replays.
+//
+// An identifier is remembered until _expiresAt_, which should be the expiry of the token or
+// message carrying it. After that the token is rejected on its own, hence the identifier
+// can be forgotten.
+type ReplayCache interface {
+	// Use records the identifier _id_ within _scope_ (e.g. issuer or verifier name) and returns
+	// _replayed_ as `true` if it already was recorded and has not yet expired.
+	//
+	// Implementations must make the check and the record atomic so that two concurrent
+	// calls with the same identifier never both returns `false`.
+	Use(c context.Context, scope, id string, expiresAt time.Time) (replayed bool, err error)
+}
@@ -1,10 +1,17 @@
 package ifcrypto
 
 import (
+	"context"
+	"crypto"
+	"fmt"
+
 	"github.com/mariotoffia/goservice/interfaces/ifctx"
 	"github.com/mariotoffia/goservice/model/coremodel"
 )
 
+// ErrInvalidSignature is returned when a signature do not verify.
+var ErrInvalidSignature = fmt.Errorf("invalid signature")
+
 // Signer is a entity that may sign a signature.
 //
 // NOTE: Some keys do implement `crypto.Signer` interface directly on the key.
@@ -30,3 +37,52 @@ type Verifier interface {
 		tags ...coremodel.Meta,
 	) error
 }
+
+// SignatureVerifier is implemented by public keys that may verify signatures created by the
+// `crypto.Signer` of the private key, hence consumers do not need to type assert `GetKey`.
+type SignatureVerifier interface {
+	// Verify verifies the _signature_ of the _digest_ using the same _opts_ as when signed.
+	//
+	// It returns `ErrInvalidSignature` when the signature do not verify. Keys that sign the
+	// message itself, such as _Ed25519_, expects the message as _digest_ and a zero hash in _opts_.
+	Verify(digest, signature []byte, opts crypto.SignerOpts) error
+}
+
+// MessageSigner is implemented by private keys that hash the message themselves, hence the
+// caller do not need to pick a digest and `crypto.SignerOpts` that matches the key type.
+type MessageSigner interface {
+	// SignMessage hashes the _msg_ using _hash_ and signs the digest. Keys that sign the message
+	// itself, such as _Ed25519_, ignores _hash_. A zero _hash_ defaults to _SHA-256_.
+	SignMessage(msg []byte, hash crypto.Hash) ([]byte, error)
+}
+
+// MessageVerifier is the counterpart of `MessageSigner` and implemented by public keys.
+type MessageVerifier interface {
+	// VerifyMessage verifies the _signature_ of the _msg_ created by `MessageSigner.SignMessage`
+	// with the same _hash_. It returns `ErrInvalidSignature` when the signature do not verify.
+	VerifyMessage(msg, signature []byte, hash crypto.Hash) error
+}
+
+// SignRequest is a single sign operation within a batch.
+type SignRequest struct {
+	// Digest is the digest to sign.
+	Digest []byte
+	// Opts is the same as in `crypto.Signer`.
+	Opts crypto.SignerOpts
+}
+
+// SignResult is the outcome of a single `SignRequest`.
+type SignResult struct {
+	// Signature is set when _Err_ is `nil`.
+	Signature []byte
+	// Err is set if the sign operation failed.
+	Err error
+}
+
+// BatchSigner is implemented by providers that are able to sign several digests in
+// one go, for example by pipelining the requests over a single connection to a _HSM_
+// or a remote _KMS_.
+type BatchSigner interface {
+	// SignBatch signs all _requests_ and returns one `SignResult` per request in the same order.
+	SignBatch(c context.Context, requests []SignRequest) []SignResult
+}
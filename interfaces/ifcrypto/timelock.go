@@ -0,0 +1,34 @@
+package ifcrypto
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// ErrNotYetReleased is returned by a `KeyReleaseService` when the private key of a epoch is
+// requested before its release time.
+var ErrNotYetReleased = fmt.Errorf("key not yet released")
+
+// TimeLockEpoch is a release epoch of a `KeyReleaseService`.
+type TimeLockEpoch struct {
+	// ID identifies the epoch.
+	ID string `json:"id"`
+	// ReleaseAt is when the private key of the epoch is released.
+	ReleaseAt time.Time `json:"release_at"`
+	// PublicKey is the 32 byte _X25519_ public key of the epoch.
+	PublicKey []byte `json:"public_key"`
+}
+
+// KeyReleaseService is a trusted service that publishes a public key for each release epoch
+// in advance and releases the corresponding private key once the epoch has been reached.
+//
+// Data encrypted to a epoch public key can thus only be decrypted after the epoch, provided
+// that the service keeps the private key secret until then.
+type KeyReleaseService interface {
+	// Epoch returns the first epoch that releases at, or after, _at_.
+	Epoch(c context.Context, at time.Time) (*TimeLockEpoch, error)
+	// PrivateKey returns the 32 byte _X25519_ private key of the epoch with _id_ or
+	// `ErrNotYetReleased` if the epoch has not been reached.
+	PrivateKey(c context.Context, id string) ([]byte, error)
+}
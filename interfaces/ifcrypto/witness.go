@@ -0,0 +1,67 @@
+package ifcrypto
+
+import (
+	"context"
+	"time"
+)
+
+// KeystoreEntry is the metadata of a single key in a `KeystoreSnapshot`.
+//
+// It never holds any key material, only a fingerprint of it.
+type KeystoreEntry struct {
+	// KeyID is the id of the key.
+	KeyID string `json:"key_id"`
+	// Version is the version of the key, empty if the keystore do not version keys.
+	Version string `json:"version,omitempty"`
+	// KeyType is the type of the key.
+	KeyType KeyType `json:"key_type"`
+	// Fingerprint is the hex encoded _SHA-256_ fingerprint of the key, empty for remote keys.
+	Fingerprint string `json:"fingerprint,omitempty"`
+	// State is the keystore specific state of the key, e.g. _active_ or _retired_.
+	State string `json:"state,omitempty"`
+}
+
+// SnapshotCosignature is a witness countersignature of a `KeystoreSnapshot`.
+type SnapshotCosignature struct {
+	// WitnessID identifies the witness and its key.
+	WitnessID string `json:"witness_id"`
+	// Created is when the witness signed.
+	Created time.Time `json:"created"`
+	// Signature is the witness signature of the snapshot _Hash_.
+	Signature []byte `json:"signature"`
+}
+
+// KeystoreSnapshot is a signed statement of all keys in a keystore at a point in time.
+//
+// Each snapshot holds the hash of the previous snapshot, hence a keystore can not present
+// different histories to different auditors without breaking the chain. Witnesses countersign
+// the snapshot after they have checked that it extends the chain they have seen.
+type KeystoreSnapshot struct {
+	// Sequence is the position of the snapshot in the chain, starting at one.
+	Sequence uint64 `json:"sequence"`
+	// Created is when the snapshot was taken.
+	Created time.Time `json:"created"`
+	// SignerID identifies the key that signed the snapshot.
+	SignerID string `json:"signer_id"`
+	// Entries is all keys sorted by key id and version.
+	Entries []KeystoreEntry `json:"entries"`
+	// PrevHash is the hash of the previous snapshot, empty for the first snapshot.
+	PrevHash []byte `json:"prev_hash"`
+	// Hash is the hash of this snapshot, including the _PrevHash_.
+	Hash []byte `json:"hash"`
+	// Signature is the signature of the _Hash_ by the _SignerID_ key.
+	Signature []byte `json:"signature"`
+	// Cosignatures is the witness countersignatures.
+	Cosignatures []SnapshotCosignature `json:"cosignatures,omitempty"`
+}
+
+// SnapshotWitness is a external party that countersigns `KeystoreSnapshot`.
+//
+// A witness must only countersign a snapshot that is correctly signed and that directly
+// follows the last snapshot it has countersigned.
+type SnapshotWitness interface {
+	// WitnessID returns the id of the witness, it is set on each `SnapshotCosignature`.
+	WitnessID() string
+	// Cosign verifies and countersigns the _snapshot_.
+	Cosign(c context.Context, snapshot KeystoreSnapshot) (*SnapshotCosignature, error)
+}
@@ -0,0 +1,44 @@
+package awskms
+
+import (
+	"github.com/mariotoffia/goservice/managers/go/gocrypto"
+)
+
+// KmsKeyStoreFactory returns a `gocrypto.ComponentFactory` of `KmsKeyStore` using _client_,
+// with the options _alias_prefix_ and _tags_. The _KMS_ client is configured by the service,
+// hence it must be registered before `gocrypto.FromConfig` is used.
+//
+// .Example
+// [source,go]
+// ----
+// gocrypto.RegisterComponent(gocrypto.ComponentKeyStore, "aws-kms", KmsKeyStoreFactory(kms.NewFromConfig(cfg)))
+//
+// assembly, err := gocrypto.FromConfig(config)
+// ----
+func KmsKeyStoreFactory(client KmsClient) gocrypto.ComponentFactory {
+
+	return func(a *gocrypto.Assembly, cfg gocrypto.ComponentConfig) (interface{}, error) {
+
+		var options struct {
+			AliasPrefix string            `json:"alias_prefix"`
+			Tags        map[string]string `json:"tags"`
+		}
+
+		if err := cfg.Decode(&options); err != nil {
+			return nil, err
+		}
+
+		opts := []KmsKeyStoreOption{}
+		if options.AliasPrefix != "" {
+			opts = append(opts, WithKmsAliasPrefix(options.AliasPrefix))
+		}
+
+		for k, v := range options.Tags {
+			opts = append(opts, WithKmsTag(k, v))
+		}
+
+		return NewKmsKeyStore(client, opts...), nil
+
+	}
+
+}
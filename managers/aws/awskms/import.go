@@ -0,0 +1,77 @@
+package awskms
+
+import (
+	"crypto/rand"
+	"fmt"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/service/kms"
+	"github.com/aws/aws-sdk-go-v2/service/kms/types"
+	"github.com/mariotoffia/goservice/interfaces/ifctx"
+	"github.com/mariotoffia/goservice/utils"
+	"github.com/mariotoffia/goservice/utils/cryptoutils"
+)
+
+// ImportKeyMaterial imports the local _material_ into the _KMS_ key with _keyID_.
+//
+// The key must have been created with origin _EXTERNAL_. It will fetch a wrapping key and
+// import token from _KMS_, wrap the _material_ using _alg_ and import it. Use
+// `cryptoutils.PrivateKeyToImportMaterial` to encode the key material.
+//
+// If _validTo_ is `nil` the key material is imported as _KEY_MATERIAL_DOES_NOT_EXPIRE_.
+func (km *AwsKms) ImportKeyMaterial(
+	c ifctx.ServiceContext,
+	keyID string,
+	material []byte,
+	alg cryptoutils.BYOKWrapAlgorithm,
+	validTo *time.Time,
+) error {
+
+	client, err := kmsClientFromContext(c)
+	if err != nil {
+		return err
+	}
+
+	var spec types.AlgorithmSpec
+
+	switch alg {
+	case cryptoutils.BYOKRsaOaepSha1:
+		spec = types.AlgorithmSpecRsaesOaepSha1
+	case cryptoutils.BYOKRsaOaepSha256:
+		spec = types.AlgorithmSpecRsaesOaepSha256
+	default:
+		return fmt.Errorf("wrap algorithm not supported by KMS import: %s", alg)
+	}
+
+	params, err := client.GetParametersForImport(c, &kms.GetParametersForImportInput{
+		KeyId:             utils.ToStringPtrNil(keyID),
+		WrappingAlgorithm: spec,
+		WrappingKeySpec:   types.WrappingKeySpecRsa2048,
+	})
+
+	if err != nil {
+		return err
+	}
+
+	wrapped, err := cryptoutils.BYOKWrapWithDER(rand.Reader, params.PublicKey, alg, material)
+	if err != nil {
+		return err
+	}
+
+	input := &kms.ImportKeyMaterialInput{
+		KeyId:                utils.ToStringPtrNil(keyID),
+		ImportToken:          params.ImportToken,
+		EncryptedKeyMaterial: wrapped,
+		ExpirationModel:      types.ExpirationModelTypeKeyMaterialDoesNotExpire,
+	}
+
+	if validTo != nil {
+		input.ExpirationModel = types.ExpirationModelTypeKeyMaterialExpires
+		input.ValidTo = validTo
+	}
+
+	_, err = client.ImportKeyMaterial(c, input)
+
+	return err
+
+}
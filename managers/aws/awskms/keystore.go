@@ -0,0 +1,306 @@
+package awskms
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/aws/aws-sdk-go-v2/service/kms"
+	"github.com/aws/aws-sdk-go-v2/service/kms/types"
+	"github.com/mariotoffia/goservice/interfaces/ifcrypto"
+	"github.com/mariotoffia/goservice/managers/go/gocrypto"
+	"github.com/mariotoffia/goservice/utils"
+)
+
+// ErrKmsReadOnly is returned by `KmsKeyStore.Put` and `KmsKeyStore.Delete` since _KMS_ keys
+// are created and scheduled for deletion in _KMS_, not by the service.
+var ErrKmsReadOnly = fmt.Errorf("KMS key store is read-only")
+
+// KmsKeyStoreOption configures a `KmsKeyStore`.
+type KmsKeyStoreOption func(s *KmsKeyStore)
+
+// WithKmsAliasPrefix lists the keys that has a alias starting with _prefix_, e.g.
+// _alias/payments/_, instead of all keys in the account.
+func WithKmsAliasPrefix(prefix string) KmsKeyStoreOption {
+	return func(s *KmsKeyStore) {
+		s.aliasPrefix = prefix
+	}
+}
+
+// WithKmsTag lists only the keys that has the tag _key_ with _value_. When used multiple
+// times all tags must match.
+func WithKmsTag(key, value string) KmsKeyStoreOption {
+	return func(s *KmsKeyStore) {
+		s.tags[key] = value
+	}
+}
+
+// KmsKeyStore implements the `ifcrypto.KeyStore` interface on top of _KMS_. Keys are resolved
+// by id, _ARN_ or alias to a `KmsKey` and cached by the given id.
+//
+// It also generates and unwraps data keys for envelope encryption, see `GenerateDataKey`.
+//
+// .Example
+// [source,go]
+// ----
+// store := NewKmsKeyStore(kms.NewFromConfig(cfg), WithKmsAliasPrefix("alias/payments/"))
+//
+// keys, err := store.List(c, ifcrypto.KeyFilter{Usages: []ifcrypto.KeyUsage{ifcrypto.KeyUsageSign}})
+// ----
+type KmsKeyStore struct {
+	client      KmsClient
+	aliasPrefix string
+	tags        map[string]string
+	mu          sync.Mutex
+	keys        map[string]*KmsKey
+}
+
+// NewKmsKeyStore creates a new `KmsKeyStore` using the _client_.
+func NewKmsKeyStore(client KmsClient, opts ...KmsKeyStoreOption) *KmsKeyStore {
+
+	s := &KmsKeyStore{
+		client: client,
+		tags:   map[string]string{},
+		keys:   map[string]*KmsKey{},
+	}
+
+	for _, opt := range opts {
+		opt(s)
+	}
+
+	return s
+
+}
+
+// Get implements the `ifcrypto.KeyStore` interface. The _id_ is a key id, _ARN_ or alias.
+func (s *KmsKeyStore) Get(c context.Context, id string) (ifcrypto.Key, error) {
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if key, ok := s.keys[id]; ok {
+		return key, nil
+	}
+
+	key, err := NewKmsKey(c, s.client, id)
+	if err != nil {
+		return nil, err
+	}
+
+	s.keys[id] = key
+
+	return key, nil
+
+}
+
+// Put implements the `ifcrypto.KeyStore` interface, it always fails with `ErrKmsReadOnly`.
+func (s *KmsKeyStore) Put(c context.Context, key ifcrypto.Key) error {
+	return ErrKmsReadOnly
+}
+
+// Delete implements the `ifcrypto.KeyStore` interface, it always fails with `ErrKmsReadOnly`.
+func (s *KmsKeyStore) Delete(c context.Context, ids ...string) error {
+	return ErrKmsReadOnly
+}
+
+// List implements the `ifcrypto.KeyStore` interface. It lists the keys with the alias prefix,
+// or all keys in the account, that has the configured tags and matches _filter_.
+func (s *KmsKeyStore) List(c context.Context, filter ifcrypto.KeyFilter) ([]ifcrypto.Key, error) {
+
+	ids, err := s.listKeyIDs(c)
+	if err != nil {
+		return nil, err
+	}
+
+	keys := []ifcrypto.Key{}
+
+	for _, id := range ids {
+
+		tagged, err := s.hasTags(c, id)
+		if err != nil {
+			return nil, err
+		}
+
+		if !tagged {
+			continue
+		}
+
+		key, err := s.Get(c, id)
+		if err != nil {
+			return nil, err
+		}
+
+		if filter.Match(key) {
+			keys = append(keys, key)
+		}
+
+	}
+
+	sort.Slice(keys, func(i, j int) bool { return keys[i].GetID() < keys[j].GetID() })
+
+	return keys, nil
+
+}
+
+// GenerateDataKey generates a _AES-256_ data key in _KMS_, wrapped by the symmetric _keyID_,
+// for envelope encryption. The _plaintext_ should be zeroed when used and the _wrapped_ key
+// stored with the data, see `DecryptDataKey`.
+//
+// The _encryptionContext_ is bound to the wrapped key and must be given again when unwrapped.
+func (s *KmsKeyStore) GenerateDataKey(
+	c context.Context,
+	keyID string,
+	encryptionContext map[string]string,
+) (plaintext, wrapped []byte, err error) {
+
+	generated, err := s.client.GenerateDataKey(c, &kms.GenerateDataKeyInput{
+		KeyId:             utils.ToStringPtrNil(keyID),
+		KeySpec:           types.DataKeySpecAes256,
+		EncryptionContext: encryptionContext,
+	})
+
+	if err != nil {
+		return nil, nil, kmsError(keyID, err)
+	}
+
+	return generated.Plaintext, generated.CiphertextBlob, nil
+
+}
+
+// DecryptDataKey unwraps the _wrapped_ data key from `GenerateDataKey` using the _keyID_ and
+// _encryptionContext_ it was generated with.
+func (s *KmsKeyStore) DecryptDataKey(
+	c context.Context,
+	keyID string,
+	wrapped []byte,
+	encryptionContext map[string]string,
+) ([]byte, error) {
+
+	// Data keys are always wrapped by a symmetric key, see `GenerateDataKey`
+	key := &KmsKey{KeyBase: gocrypto.NewKeyBase(keyID, ifcrypto.KeyTypeSymmetric, 256)}
+
+	if err := gocrypto.CheckAlgorithm(ifcrypto.PolicyOperationDecrypt, key, 0); err != nil {
+		return nil, err
+	}
+
+	decrypted, err := s.client.Decrypt(c, &kms.DecryptInput{
+		KeyId:             utils.ToStringPtrNil(keyID),
+		CiphertextBlob:    wrapped,
+		EncryptionContext: encryptionContext,
+	})
+
+	if err != nil {
+		return nil, kmsError(keyID, err)
+	}
+
+	return decrypted.Plaintext, nil
+
+}
+
+// listKeyIDs returns the key _ARN_ of all keys in the account, or the target key ids of the
+// aliases with the alias prefix.
+func (s *KmsKeyStore) listKeyIDs(c context.Context) ([]string, error) {
+
+	ids := []string{}
+
+	if s.aliasPrefix == "" {
+
+		pages := kms.NewListKeysPaginator(s.client, &kms.ListKeysInput{})
+
+		for pages.HasMorePages() {
+
+			page, err := pages.NextPage(c)
+			if err != nil {
+				return nil, err
+			}
+
+			for _, key := range page.Keys {
+
+				if key.KeyArn != nil {
+					ids = append(ids, *key.KeyArn)
+				}
+
+			}
+
+		}
+
+		return ids, nil
+
+	}
+
+	seen := map[string]bool{}
+	pages := kms.NewListAliasesPaginator(s.client, &kms.ListAliasesInput{})
+
+	for pages.HasMorePages() {
+
+		page, err := pages.NextPage(c)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, alias := range page.Aliases {
+
+			if alias.AliasName == nil || alias.TargetKeyId == nil ||
+				!strings.HasPrefix(*alias.AliasName, s.aliasPrefix) || seen[*alias.TargetKeyId] {
+
+				continue
+
+			}
+
+			seen[*alias.TargetKeyId] = true
+			ids = append(ids, *alias.TargetKeyId)
+
+		}
+
+	}
+
+	return ids, nil
+
+}
+
+// hasTags returns `true` if the key with _id_ has all configured tags.
+func (s *KmsKeyStore) hasTags(c context.Context, id string) (bool, error) {
+
+	if len(s.tags) == 0 {
+		return true, nil
+	}
+
+	found := map[string]string{}
+	input := &kms.ListResourceTagsInput{KeyId: utils.ToStringPtrNil(id)}
+
+	for {
+
+		page, err := s.client.ListResourceTags(c, input)
+		if err != nil {
+			return false, kmsError(id, err)
+		}
+
+		for _, tag := range page.Tags {
+
+			if tag.TagKey != nil && tag.TagValue != nil {
+				found[*tag.TagKey] = *tag.TagValue
+			}
+
+		}
+
+		if !page.Truncated || page.NextMarker == nil {
+			break
+		}
+
+		input.Marker = page.NextMarker
+
+	}
+
+	for key, value := range s.tags {
+
+		if found[key] != value {
+			return false, nil
+		}
+
+	}
+
+	return true, nil
+
+}
@@ -1,11 +1,312 @@
 package awskms
 
-import "github.com/mariotoffia/goservice/managers/go/gocrypto"
+import (
+	"bytes"
+	"context"
+	"crypto"
+	"crypto/rsa"
+	"errors"
+	"fmt"
+	"io"
 
-// KmsKey implements the `ifcrypto.Key` interface.
+	"github.com/aws/aws-sdk-go-v2/service/kms"
+	"github.com/aws/aws-sdk-go-v2/service/kms/types"
+	"github.com/mariotoffia/goservice/interfaces/ifcrypto"
+	"github.com/mariotoffia/goservice/managers/go/gocrypto"
+	"github.com/mariotoffia/goservice/utils"
+)
+
+// KmsClient is the subset of the `*kms.Client` used by the `KmsKey` and `KmsKeyStore`, such
+// that it may be replaced in tests.
+type KmsClient interface {
+	DescribeKey(c context.Context, params *kms.DescribeKeyInput, optFns ...func(*kms.Options)) (*kms.DescribeKeyOutput, error)
+	GetPublicKey(c context.Context, params *kms.GetPublicKeyInput, optFns ...func(*kms.Options)) (*kms.GetPublicKeyOutput, error)
+	Sign(c context.Context, params *kms.SignInput, optFns ...func(*kms.Options)) (*kms.SignOutput, error)
+	Verify(c context.Context, params *kms.VerifyInput, optFns ...func(*kms.Options)) (*kms.VerifyOutput, error)
+	ListKeys(c context.Context, params *kms.ListKeysInput, optFns ...func(*kms.Options)) (*kms.ListKeysOutput, error)
+	ListAliases(c context.Context, params *kms.ListAliasesInput, optFns ...func(*kms.Options)) (*kms.ListAliasesOutput, error)
+	ListResourceTags(c context.Context, params *kms.ListResourceTagsInput, optFns ...func(*kms.Options)) (*kms.ListResourceTagsOutput, error)
+	GenerateDataKey(c context.Context, params *kms.GenerateDataKeyInput, optFns ...func(*kms.Options)) (*kms.GenerateDataKeyOutput, error)
+	Decrypt(c context.Context, params *kms.DecryptInput, optFns ...func(*kms.Options)) (*kms.DecryptOutput, error)
+}
+
+// KmsKey implements the `ifcrypto.KeyPair` interface for a _KMS_ key. The private key never
+// leaves _KMS_, hence `IsRemoteKey` is always `true`.
 //
-// The `GetID` represents the _KMS ARN_, alias or id of this key.
+// The `GetID` represents the _KMS ARN_ of this key.
+//
+// Asymmetric _SIGN_VERIFY_ keys implements `crypto.Signer` and `ifcrypto.SignatureVerifier`
+// where each operation is a _KMS_ request. The `GetPublic` key verifies in process. Symmetric
+// keys have no public key and are used to wrap data keys, see `KmsKeyStore.GenerateDataKey`.
 type KmsKey struct {
 	// Derive from `gocrypto.KeyBase`
 	gocrypto.KeyBase
+	client KmsClient
+	public ifcrypto.PublicKey
+}
+
+// NewKmsKey resolves the _keyID_, i.e. a id, _ARN_ or alias, in _KMS_ and creates a new
+// `KmsKey` of it.
+func NewKmsKey(c context.Context, client KmsClient, keyID string) (*KmsKey, error) {
+
+	described, err := client.DescribeKey(c, &kms.DescribeKeyInput{KeyId: utils.ToStringPtrNil(keyID)})
+	if err != nil {
+		return nil, kmsError(keyID, err)
+	}
+
+	meta := described.KeyMetadata
+	if meta == nil || meta.Arn == nil {
+		return nil, fmt.Errorf("KMS key %s has no metadata", keyID)
+	}
+
+	keyType, keySize, err := kmsKeyType(meta.CustomerMasterKeySpec)
+	if err != nil {
+		return nil, err
+	}
+
+	var usage []ifcrypto.KeyUsage
+
+	switch meta.KeyUsage {
+	case types.KeyUsageTypeSignVerify:
+		usage = []ifcrypto.KeyUsage{ifcrypto.KeyUsageSign, ifcrypto.KeyUsageVerify}
+	case types.KeyUsageTypeEncryptDecrypt:
+		usage = []ifcrypto.KeyUsage{ifcrypto.KeyUsageEncrypt, ifcrypto.KeyUsageDecrypt}
+	}
+
+	key := &KmsKey{
+		KeyBase: gocrypto.NewKeyBase(*meta.Arn, keyType, keySize, usage...),
+		client:  client,
+	}
+
+	if keyType == ifcrypto.KeyTypeSymmetric {
+		return key, nil
+	}
+
+	public, err := client.GetPublicKey(c, &kms.GetPublicKeyInput{KeyId: meta.Arn})
+	if err != nil {
+		return nil, kmsError(keyID, err)
+	}
+
+	loaded, err := gocrypto.LoadKey(bytes.NewReader(public.PublicKey), *meta.Arn, ifcrypto.KeyUsageVerify)
+	if err != nil {
+		return nil, err
+	}
+
+	if key.public, _ = loaded.(ifcrypto.PublicKey); key.public == nil {
+		return nil, fmt.Errorf("KMS key %s has no public key", keyID)
+	}
+
+	return key, nil
+
+}
+
+// GetPublic returns the public key, or `nil` if symmetric.
+func (k *KmsKey) GetPublic() ifcrypto.PublicKey {
+	return k.public
+}
+
+// GetKey returns the _ARN_ since the key material never leaves _KMS_.
+func (k *KmsKey) GetKey() interface{} {
+	return k.GetID()
+}
+
+// IsSymmetric returns `true` if this is a _SYMMETRIC_DEFAULT_ key.
+func (k *KmsKey) IsSymmetric() bool {
+	return k.GetKeyType() == ifcrypto.KeyTypeSymmetric
+}
+
+// IsPrivate returns `true` since a _KMS_ key always holds the private key.
+func (k *KmsKey) IsPrivate() bool {
+	return true
+}
+
+// IsRemoteKey returns `true`.
+func (k *KmsKey) IsRemoteKey() bool {
+	return true
+}
+
+// Public implements the `crypto.Signer` _interface_.
+func (k *KmsKey) Public() crypto.PublicKey {
+
+	if k.public == nil {
+		return nil
+	}
+
+	return k.public.GetKey()
+
+}
+
+// Sign implements the `crypto.Signer` _interface_, see `SignContext`. The _rand_ is not used.
+func (k *KmsKey) Sign(rand io.Reader, digest []byte, opts crypto.SignerOpts) ([]byte, error) {
+	return k.SignContext(context.Background(), digest, opts)
+}
+
+// SignContext signs the _digest_ in _KMS_. If _opts_ is a `*rsa.PSSOptions` the _PSS_
+// algorithm is used, otherwise _PKCS #1 v1.5_ for _RSA_ keys. _ECDSA_ signatures are
+// _ASN.1 DER_ encoded.
+func (k *KmsKey) SignContext(c context.Context, digest []byte, opts crypto.SignerOpts) ([]byte, error) {
+
+	if gocrypto.IsVerifyOnly() {
+		return nil, gocrypto.ErrVerifyOnly
+	}
+
+	if !k.HasUsage(ifcrypto.KeyUsageSign) {
+		return nil, fmt.Errorf("KMS key %s is not a signing key", k.GetID())
+	}
+
+	algorithm, err := k.signingAlgorithm(digest, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := gocrypto.CheckAlgorithm(ifcrypto.PolicyOperationSign, k, opts.HashFunc()); err != nil {
+		return nil, err
+	}
+
+	signed, err := k.client.Sign(c, &kms.SignInput{
+		KeyId:            utils.ToStringPtrNil(k.GetID()),
+		Message:          digest,
+		MessageType:      types.MessageTypeDigest,
+		SigningAlgorithm: algorithm,
+	})
+
+	if err != nil {
+		return nil, kmsError(k.GetID(), err)
+	}
+
+	return signed.Signature, nil
+
+}
+
+// SignMessage implements the `ifcrypto.MessageSigner` interface, see `gocrypto.SignMessage`.
+func (k *KmsKey) SignMessage(msg []byte, hash crypto.Hash) ([]byte, error) {
+	return gocrypto.SignMessage(k, msg, hash)
+}
+
+// Verify implements the `ifcrypto.SignatureVerifier` interface, see `VerifyContext`.
+func (k *KmsKey) Verify(digest, signature []byte, opts crypto.SignerOpts) error {
+	return k.VerifyContext(context.Background(), digest, signature, opts)
+}
+
+// VerifyContext verifies the _signature_ of the _digest_ in _KMS_, using the same _opts_ as
+// when signed. It returns `ifcrypto.ErrInvalidSignature` when the signature do not verify.
+//
+// Use the `GetPublic` key to verify in process without a _KMS_ request.
+func (k *KmsKey) VerifyContext(c context.Context, digest, signature []byte, opts crypto.SignerOpts) error {
+
+	if !k.HasUsage(ifcrypto.KeyUsageVerify) {
+		return fmt.Errorf("KMS key %s is not a signing key", k.GetID())
+	}
+
+	algorithm, err := k.signingAlgorithm(digest, opts)
+	if err != nil {
+		return err
+	}
+
+	verified, err := k.client.Verify(c, &kms.VerifyInput{
+		KeyId:            utils.ToStringPtrNil(k.GetID()),
+		Message:          digest,
+		MessageType:      types.MessageTypeDigest,
+		Signature:        signature,
+		SigningAlgorithm: algorithm,
+	})
+
+	var invalid *types.KMSInvalidSignatureException
+
+	if errors.As(err, &invalid) || (err == nil && !verified.SignatureValid) {
+		return ifcrypto.ErrInvalidSignature
+	}
+
+	if err != nil {
+		return kmsError(k.GetID(), err)
+	}
+
+	return nil
+
+}
+
+// signingAlgorithm returns the _KMS_ signing algorithm of _opts_ for this key.
+func (k *KmsKey) signingAlgorithm(digest []byte, opts crypto.SignerOpts) (types.SigningAlgorithmSpec, error) {
+
+	if opts == nil {
+		return "", fmt.Errorf("KMS key %s requires a hash", k.GetID())
+	}
+
+	hash := opts.HashFunc()
+
+	if hash != crypto.SHA256 && hash != crypto.SHA384 && hash != crypto.SHA512 {
+		return "", fmt.Errorf("KMS do not support hash: %s", hash)
+	}
+
+	if len(digest) != hash.Size() {
+		return "", fmt.Errorf("digest is %d bytes, %s requires %d", len(digest), hash, hash.Size())
+	}
+
+	bits := hash.Size() * 8
+
+	switch k.GetKeyType() {
+	case ifcrypto.KeyTypeRsa:
+
+		if pss, ok := opts.(*rsa.PSSOptions); ok {
+
+			// KMS always uses a salt of the hash size
+			if pss.SaltLength != rsa.PSSSaltLengthEqualsHash &&
+				pss.SaltLength != rsa.PSSSaltLengthAuto &&
+				pss.SaltLength != hash.Size() {
+
+				return "", fmt.Errorf("KMS do not support PSS salt length: %d", pss.SaltLength)
+
+			}
+
+			return types.SigningAlgorithmSpec(fmt.Sprintf("RSASSA_PSS_SHA_%d", bits)), nil
+
+		}
+
+		return types.SigningAlgorithmSpec(fmt.Sprintf("RSASSA_PKCS1_V1_5_SHA_%d", bits)), nil
+
+	case ifcrypto.KeyTypeEccNistP, ifcrypto.KeyTypeEccSecgP256k1:
+		return types.SigningAlgorithmSpec(fmt.Sprintf("ECDSA_SHA_%d", bits)), nil
+	}
+
+	return "", fmt.Errorf("KMS key %s can not sign", k.GetID())
+
+}
+
+// kmsKeyType maps the _KMS_ key _spec_ to `ifcrypto.KeyType` and key size.
+func kmsKeyType(spec types.CustomerMasterKeySpec) (ifcrypto.KeyType, int, error) {
+
+	switch spec {
+	case types.CustomerMasterKeySpecRsa2048:
+		return ifcrypto.KeyTypeRsa, 2048, nil
+	case types.CustomerMasterKeySpecRsa3072:
+		return ifcrypto.KeyTypeRsa, 3072, nil
+	case types.CustomerMasterKeySpecRsa4096:
+		return ifcrypto.KeyTypeRsa, 4096, nil
+	case types.CustomerMasterKeySpecEccNistP256:
+		return ifcrypto.KeyTypeEccNistP, 256, nil
+	case types.CustomerMasterKeySpecEccNistP384:
+		return ifcrypto.KeyTypeEccNistP, 384, nil
+	case types.CustomerMasterKeySpecEccNistP521:
+		return ifcrypto.KeyTypeEccNistP, 521, nil
+	case types.CustomerMasterKeySpecEccSecgP256k1:
+		return ifcrypto.KeyTypeEccSecgP256k1, 256, nil
+	case types.CustomerMasterKeySpecSymmetricDefault:
+		return ifcrypto.KeyTypeSymmetric, 256, nil
+	}
+
+	return "", 0, fmt.Errorf("unsupported KMS key spec: %s", spec)
+
+}
+
+// kmsError maps a _KMS_ not found error to `ifcrypto.ErrKeyNotFound`.
+func kmsError(keyID string, err error) error {
+
+	var notFound *types.NotFoundException
+
+	if errors.As(err, &notFound) {
+		return fmt.Errorf("%w: %s", ifcrypto.ErrKeyNotFound, keyID)
+	}
+
+	return err
+
 }
@@ -0,0 +1,417 @@
+//go:build !verifyonly
+// +build !verifyonly
+
+package awskms
+
+import (
+	"context"
+	"crypto"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/service/kms"
+	"github.com/aws/aws-sdk-go-v2/service/kms/types"
+	"github.com/mariotoffia/goservice/interfaces/ifcrypto"
+	"github.com/mariotoffia/goservice/managers/go/gocrypto"
+	"github.com/mariotoffia/goservice/utils"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeKmsKey struct {
+	spec   types.CustomerMasterKeySpec
+	usage  types.KeyUsageType
+	signer crypto.Signer
+	aead   cipher.AEAD
+	tags   map[string]string
+}
+
+// fakeKms is a in memory `KmsClient`, keys are looked up by _ARN_ or alias.
+type fakeKms struct {
+	keys    map[string]*fakeKmsKey
+	aliases map[string]string
+}
+
+func newFakeKms(t *testing.T) *fakeKms {
+
+	ec, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+
+	block, err := aes.NewCipher(make([]byte, 32))
+	require.NoError(t, err)
+
+	aead, err := cipher.NewGCM(block)
+	require.NoError(t, err)
+
+	return &fakeKms{
+		keys: map[string]*fakeKmsKey{
+			"arn:aws:kms:eu-west-1:111122223333:key/ec": {
+				spec: types.CustomerMasterKeySpecEccNistP256, usage: types.KeyUsageTypeSignVerify,
+				signer: ec, tags: map[string]string{"team": "payments"},
+			},
+			"arn:aws:kms:eu-west-1:111122223333:key/rsa": {
+				spec: types.CustomerMasterKeySpecRsa2048, usage: types.KeyUsageTypeSignVerify,
+				signer: rsaKey, tags: map[string]string{"team": "identity"},
+			},
+			"arn:aws:kms:eu-west-1:111122223333:key/kek": {
+				spec: types.CustomerMasterKeySpecSymmetricDefault, usage: types.KeyUsageTypeEncryptDecrypt,
+				aead: aead, tags: map[string]string{"team": "payments"},
+			},
+		},
+		aliases: map[string]string{
+			"alias/payments/signer": "arn:aws:kms:eu-west-1:111122223333:key/ec",
+			"alias/payments/kek":    "arn:aws:kms:eu-west-1:111122223333:key/kek",
+			"alias/identity/signer": "arn:aws:kms:eu-west-1:111122223333:key/rsa",
+		},
+	}
+
+}
+
+func (f *fakeKms) key(id *string) (string, *fakeKmsKey, error) {
+
+	arn := *id
+	if target, ok := f.aliases[arn]; ok {
+		arn = target
+	}
+
+	if key, ok := f.keys[arn]; ok {
+		return arn, key, nil
+	}
+
+	return "", nil, &types.NotFoundException{Message: id}
+
+}
+
+func (f *fakeKms) DescribeKey(c context.Context, params *kms.DescribeKeyInput, optFns ...func(*kms.Options)) (*kms.DescribeKeyOutput, error) {
+
+	arn, key, err := f.key(params.KeyId)
+	if err != nil {
+		return nil, err
+	}
+
+	return &kms.DescribeKeyOutput{KeyMetadata: &types.KeyMetadata{
+		Arn: utils.ToStringPtr(arn), CustomerMasterKeySpec: key.spec, KeyUsage: key.usage,
+	}}, nil
+
+}
+
+func (f *fakeKms) GetPublicKey(c context.Context, params *kms.GetPublicKeyInput, optFns ...func(*kms.Options)) (*kms.GetPublicKeyOutput, error) {
+
+	_, key, err := f.key(params.KeyId)
+	if err != nil {
+		return nil, err
+	}
+
+	der, err := x509.MarshalPKIXPublicKey(key.signer.Public())
+	if err != nil {
+		return nil, err
+	}
+
+	return &kms.GetPublicKeyOutput{PublicKey: der}, nil
+
+}
+
+func (f *fakeKms) Sign(c context.Context, params *kms.SignInput, optFns ...func(*kms.Options)) (*kms.SignOutput, error) {
+
+	_, key, err := f.key(params.KeyId)
+	if err != nil {
+		return nil, err
+	}
+
+	var opts crypto.SignerOpts = crypto.SHA256
+
+	if strings.HasPrefix(string(params.SigningAlgorithm), "RSASSA_PSS") {
+		opts = &rsa.PSSOptions{SaltLength: rsa.PSSSaltLengthEqualsHash, Hash: crypto.SHA256}
+	}
+
+	signature, err := key.signer.Sign(rand.Reader, params.Message, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	return &kms.SignOutput{Signature: signature, SigningAlgorithm: params.SigningAlgorithm}, nil
+
+}
+
+func (f *fakeKms) Verify(c context.Context, params *kms.VerifyInput, optFns ...func(*kms.Options)) (*kms.VerifyOutput, error) {
+
+	_, key, err := f.key(params.KeyId)
+	if err != nil {
+		return nil, err
+	}
+
+	var valid bool
+
+	switch public := key.signer.Public().(type) {
+	case *ecdsa.PublicKey:
+		valid = ecdsa.VerifyASN1(public, params.Message, params.Signature)
+	case *rsa.PublicKey:
+
+		if strings.HasPrefix(string(params.SigningAlgorithm), "RSASSA_PSS") {
+			valid = rsa.VerifyPSS(public, crypto.SHA256, params.Message, params.Signature, nil) == nil
+		} else {
+			valid = rsa.VerifyPKCS1v15(public, crypto.SHA256, params.Message, params.Signature) == nil
+		}
+
+	}
+
+	if !valid {
+		return nil, &types.KMSInvalidSignatureException{}
+	}
+
+	return &kms.VerifyOutput{SignatureValid: true}, nil
+
+}
+
+func (f *fakeKms) ListKeys(c context.Context, params *kms.ListKeysInput, optFns ...func(*kms.Options)) (*kms.ListKeysOutput, error) {
+
+	out := &kms.ListKeysOutput{}
+
+	for arn := range f.keys {
+		out.Keys = append(out.Keys, types.KeyListEntry{KeyArn: utils.ToStringPtr(arn)})
+	}
+
+	return out, nil
+
+}
+
+func (f *fakeKms) ListAliases(c context.Context, params *kms.ListAliasesInput, optFns ...func(*kms.Options)) (*kms.ListAliasesOutput, error) {
+
+	out := &kms.ListAliasesOutput{}
+
+	for alias, arn := range f.aliases {
+		out.Aliases = append(out.Aliases, types.AliasListEntry{AliasName: utils.ToStringPtr(alias), TargetKeyId: utils.ToStringPtr(arn)})
+	}
+
+	return out, nil
+
+}
+
+func (f *fakeKms) ListResourceTags(c context.Context, params *kms.ListResourceTagsInput, optFns ...func(*kms.Options)) (*kms.ListResourceTagsOutput, error) {
+
+	_, key, err := f.key(params.KeyId)
+	if err != nil {
+		return nil, err
+	}
+
+	out := &kms.ListResourceTagsOutput{}
+
+	for k, v := range key.tags {
+		out.Tags = append(out.Tags, types.Tag{TagKey: utils.ToStringPtr(k), TagValue: utils.ToStringPtr(v)})
+	}
+
+	return out, nil
+
+}
+
+func (f *fakeKms) GenerateDataKey(c context.Context, params *kms.GenerateDataKeyInput, optFns ...func(*kms.Options)) (*kms.GenerateDataKeyOutput, error) {
+
+	_, key, err := f.key(params.KeyId)
+	if err != nil {
+		return nil, err
+	}
+
+	plaintext := make([]byte, 32)
+	nonce := make([]byte, key.aead.NonceSize())
+
+	_, _ = rand.Read(plaintext)
+	_, _ = rand.Read(nonce)
+
+	wrapped := key.aead.Seal(nonce, nonce, plaintext, []byte(params.EncryptionContext["tenant"]))
+
+	return &kms.GenerateDataKeyOutput{Plaintext: plaintext, CiphertextBlob: wrapped}, nil
+
+}
+
+func (f *fakeKms) Decrypt(c context.Context, params *kms.DecryptInput, optFns ...func(*kms.Options)) (*kms.DecryptOutput, error) {
+
+	_, key, err := f.key(params.KeyId)
+	if err != nil {
+		return nil, err
+	}
+
+	size := key.aead.NonceSize()
+
+	plaintext, err := key.aead.Open(nil, params.CiphertextBlob[:size], params.CiphertextBlob[size:], []byte(params.EncryptionContext["tenant"]))
+	if err != nil {
+		return nil, &types.InvalidCiphertextException{}
+	}
+
+	return &kms.DecryptOutput{Plaintext: plaintext}, nil
+
+}
+
+func TestKmsKeySignsAndVerifies(t *testing.T) {
+
+	client := newFakeKms(t)
+	digest := sha256.Sum256([]byte("hello world"))
+
+	for _, tc := range []struct {
+		alias string
+		opts  crypto.SignerOpts
+	}{
+		{"alias/payments/signer", crypto.SHA256},
+		{"alias/identity/signer", crypto.SHA256},
+		{"alias/identity/signer", &rsa.PSSOptions{SaltLength: rsa.PSSSaltLengthEqualsHash, Hash: crypto.SHA256}},
+	} {
+
+		key, err := NewKmsKey(context.Background(), client, tc.alias)
+		require.NoError(t, err, tc.alias)
+
+		assert.True(t, key.IsRemoteKey())
+		assert.True(t, key.IsPrivate())
+		assert.True(t, strings.HasPrefix(key.GetID(), "arn:aws:kms:"))
+		assert.True(t, key.CanSign(ifcrypto.SignAlgorithmEcdSha256) || key.CanSign(ifcrypto.SignAlgorithmRsaPssSha256))
+
+		signature, err := key.Sign(rand.Reader, digest[:], tc.opts)
+		require.NoError(t, err)
+
+		assert.NoError(t, key.Verify(digest[:], signature, tc.opts))
+
+		verifier, ok := key.GetPublic().(ifcrypto.SignatureVerifier)
+		require.True(t, ok)
+		assert.NoError(t, verifier.Verify(digest[:], signature, tc.opts))
+
+		signature[len(signature)-1] ^= 0x01
+		assert.True(t, errors.Is(key.Verify(digest[:], signature, tc.opts), ifcrypto.ErrInvalidSignature))
+
+	}
+
+}
+
+func TestKmsKeyRejectsUnsupportedSigning(t *testing.T) {
+
+	client := newFakeKms(t)
+	digest := sha256.Sum256([]byte("hello world"))
+
+	key, err := NewKmsKey(context.Background(), client, "alias/identity/signer")
+	require.NoError(t, err)
+
+	_, err = key.Sign(rand.Reader, digest[:], crypto.SHA1)
+	assert.Error(t, err)
+
+	_, err = key.Sign(rand.Reader, digest[:20], crypto.SHA256)
+	assert.Error(t, err)
+
+	_, err = key.Sign(rand.Reader, digest[:], &rsa.PSSOptions{SaltLength: 8, Hash: crypto.SHA256})
+	assert.Error(t, err)
+
+	kek, err := NewKmsKey(context.Background(), client, "alias/payments/kek")
+	require.NoError(t, err)
+	assert.True(t, kek.IsSymmetric())
+	assert.Nil(t, kek.GetPublic())
+
+	_, err = kek.Sign(rand.Reader, digest[:], crypto.SHA256)
+	assert.Error(t, err)
+
+	_, err = NewKmsKey(context.Background(), client, "alias/missing")
+	assert.True(t, errors.Is(err, ifcrypto.ErrKeyNotFound))
+
+}
+
+func TestKmsKeyStoreListsByAliasAndTag(t *testing.T) {
+
+	client := newFakeKms(t)
+	c := context.Background()
+
+	store := NewKmsKeyStore(client, WithKmsAliasPrefix("alias/payments/"))
+
+	keys, err := store.List(c, ifcrypto.KeyFilter{})
+	require.NoError(t, err)
+	require.Len(t, keys, 2)
+	assert.Equal(t, "arn:aws:kms:eu-west-1:111122223333:key/ec", keys[0].GetID())
+	assert.Equal(t, "arn:aws:kms:eu-west-1:111122223333:key/kek", keys[1].GetID())
+
+	keys, err = store.List(c, ifcrypto.KeyFilter{Usages: []ifcrypto.KeyUsage{ifcrypto.KeyUsageSign}})
+	require.NoError(t, err)
+	require.Len(t, keys, 1)
+
+	store = NewKmsKeyStore(client, WithKmsTag("team", "identity"))
+
+	keys, err = store.List(c, ifcrypto.KeyFilter{})
+	require.NoError(t, err)
+	require.Len(t, keys, 1)
+	assert.Equal(t, ifcrypto.KeyTypeRsa, keys[0].GetKeyType())
+
+	key, err := store.Get(c, "alias/identity/signer")
+	require.NoError(t, err)
+	assert.Equal(t, keys[0].GetID(), key.GetID())
+
+	assert.True(t, errors.Is(store.Put(c, key), ErrKmsReadOnly))
+	assert.True(t, errors.Is(store.Delete(c, key.GetID()), ErrKmsReadOnly))
+
+}
+
+func TestKmsKeyStoreGeneratesDataKeys(t *testing.T) {
+
+	client := newFakeKms(t)
+	c := context.Background()
+	store := NewKmsKeyStore(client)
+
+	plaintext, wrapped, err := store.GenerateDataKey(c, "alias/payments/kek", map[string]string{"tenant": "acme"})
+	require.NoError(t, err)
+	assert.Len(t, plaintext, 32)
+
+	unwrapped, err := store.DecryptDataKey(c, "alias/payments/kek", wrapped, map[string]string{"tenant": "acme"})
+	require.NoError(t, err)
+	assert.Equal(t, plaintext, unwrapped)
+
+	_, err = store.DecryptDataKey(c, "alias/payments/kek", wrapped, map[string]string{"tenant": "other"})
+	assert.Error(t, err)
+
+}
+
+func TestKmsKeyEnforcesAlgorithmDenyList(t *testing.T) {
+
+	client := newFakeKms(t)
+	c := context.Background()
+	digest := sha256.Sum256([]byte("hello world"))
+
+	list := gocrypto.NewAlgorithmDenyList(
+		gocrypto.WithDeniedAlgorithm("SHA-256", ifcrypto.PolicyOperationSign),
+		gocrypto.WithDeniedAlgorithm("AES", ifcrypto.PolicyOperationDecrypt),
+	)
+
+	key, err := NewKmsKey(c, client, "alias/identity/signer")
+	require.NoError(t, err)
+
+	signature, err := key.Sign(rand.Reader, digest[:], crypto.SHA256)
+	require.NoError(t, err)
+
+	store := NewKmsKeyStore(client)
+
+	_, wrapped, err := store.GenerateDataKey(c, "alias/payments/kek", nil)
+	require.NoError(t, err)
+
+	gocrypto.SetAlgorithmDenyList(list)
+	defer gocrypto.SetAlgorithmDenyList(nil)
+
+	_, err = key.Sign(rand.Reader, digest[:], crypto.SHA256)
+	assert.True(t, errors.Is(err, gocrypto.ErrAlgorithmDenied))
+
+	_, err = store.DecryptDataKey(c, "alias/payments/kek", wrapped, nil)
+	assert.True(t, errors.Is(err, gocrypto.ErrAlgorithmDenied))
+
+	// Only the denied operations are blocked
+	assert.NoError(t, key.Verify(digest[:], signature, crypto.SHA256))
+	assert.Equal(t, map[string]uint64{"sign SHA-256": 1, "decrypt AES": 1}, list.Denied())
+
+	// The key size is resolved from the public key
+	gocrypto.SetAlgorithmDenyList(gocrypto.NewAlgorithmDenyList(gocrypto.WithDeniedAlgorithm("RSA-2048")))
+
+	_, err = key.Sign(rand.Reader, digest[:], &rsa.PSSOptions{SaltLength: rsa.PSSSaltLengthEqualsHash, Hash: crypto.SHA256})
+	assert.True(t, errors.Is(err, gocrypto.ErrAlgorithmDenied))
+
+}
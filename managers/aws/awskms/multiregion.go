@@ -0,0 +1,268 @@
+package awskms
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ErrAllRegionsFailed is returned by `RegionFailover.Do` when the operation failed in all regions.
+var ErrAllRegionsFailed = fmt.Errorf("operation failed in all regions")
+
+// ParseKeyARN parses a _KMS_ key _ARN_ such as
+// _arn:aws:kms:eu-west-1:111122223333:key/mrk-1234abcd_ into its region and key id.
+func ParseKeyARN(arn string) (region, keyID string, err error) {
+
+	parts := strings.SplitN(arn, ":", 6)
+
+	if len(parts) != 6 || parts[0] != "arn" || parts[2] != "kms" || !strings.HasPrefix(parts[5], "key/") {
+		return "", "", fmt.Errorf("not a KMS key ARN: %s", arn)
+	}
+
+	if parts[3] == "" {
+		return "", "", fmt.Errorf("KMS key ARN is missing region: %s", arn)
+	}
+
+	return parts[3], strings.TrimPrefix(parts[5], "key/"), nil
+
+}
+
+// MultiRegionKey is a _KMS_ multi-region key with a primary and its replicas.
+//
+// All keys share the same key id and key material, hence a signature or ciphertext produced
+// in one region may be verified or decrypted in any other.
+type MultiRegionKey struct {
+	// Primary is the _ARN_ of the primary key.
+	Primary string
+	// Replicas is the _ARN_ of the replica keys.
+	Replicas []string
+	// regions maps region to _ARN_.
+	regions map[string]string
+}
+
+// NewMultiRegionKey creates a `MultiRegionKey` from the _primary_ and _replicas_ _ARN_.
+//
+// All _ARN_ must have the same multi-region key id, prefixed with _mrk-_, and be in different regions.
+func NewMultiRegionKey(primary string, replicas ...string) (*MultiRegionKey, error) {
+
+	_, keyID, err := ParseKeyARN(primary)
+	if err != nil {
+		return nil, err
+	}
+
+	if !strings.HasPrefix(keyID, "mrk-") {
+		return nil, fmt.Errorf("not a multi-region key: %s", primary)
+	}
+
+	key := &MultiRegionKey{Primary: primary, Replicas: replicas, regions: map[string]string{}}
+
+	for _, arn := range append([]string{primary}, replicas...) {
+
+		region, id, err := ParseKeyARN(arn)
+		if err != nil {
+			return nil, err
+		}
+
+		if id != keyID {
+			return nil, fmt.Errorf("replica %s do not have key id %s", arn, keyID)
+		}
+
+		if _, ok := key.regions[region]; ok {
+			return nil, fmt.Errorf("multiple keys in region %s", region)
+		}
+
+		key.regions[region] = arn
+
+	}
+
+	return key, nil
+
+}
+
+// ARN returns the _ARN_ of the key in _region_.
+func (k *MultiRegionKey) ARN(region string) (string, bool) {
+
+	arn, ok := k.regions[region]
+	return arn, ok
+
+}
+
+// Regions returns the regions of the key, primary first.
+func (k *MultiRegionKey) Regions() []string {
+
+	regions := make([]string, 0, len(k.regions))
+
+	for _, arn := range append([]string{k.Primary}, k.Replicas...) {
+
+		region, _, _ := ParseKeyARN(arn)
+		regions = append(regions, region)
+
+	}
+
+	return regions
+
+}
+
+// RegionFailoverOption configures a `RegionFailover`.
+type RegionFailoverOption func(f *RegionFailover)
+
+// WithRegionPreference sets the order in which regions are tried, nearest first. Regions of
+// the key that are not in the list are tried last, in key order.
+func WithRegionPreference(regions ...string) RegionFailoverOption {
+	return func(f *RegionFailover) {
+		f.preference = regions
+	}
+}
+
+// WithRegionCooldown sets for how long a failed region is skipped, default 30 seconds.
+func WithRegionCooldown(cooldown time.Duration) RegionFailoverOption {
+	return func(f *RegionFailover) {
+		f.cooldown = cooldown
+	}
+}
+
+// WithRegionalErrorClassifier sets the function that decides if a error is regional, i.e.
+// the operation should be retried in another region. By default all errors except context
+// cancellation are regional.
+func WithRegionalErrorClassifier(regional func(err error) bool) RegionFailoverOption {
+	return func(f *RegionFailover) {
+		f.regional = regional
+	}
+}
+
+// WithRegionClock sets the clock used for the cooldown.
+func WithRegionClock(clock func() time.Time) RegionFailoverOption {
+	return func(f *RegionFailover) {
+		f.clock = clock
+	}
+}
+
+// RegionFailover selects the nearest healthy region of a `MultiRegionKey` and fails over to
+// the next region when a operation fails with a regional error.
+//
+// A region that fails is skipped until its cooldown has passed. If all regions are in
+// cooldown, all are tried in preference order rather than failing immediately.
+type RegionFailover struct {
+	key        *MultiRegionKey
+	preference []string
+	cooldown   time.Duration
+	regional   func(err error) bool
+	clock      func() time.Time
+	mu         sync.Mutex
+	downUntil  map[string]time.Time
+}
+
+// NewRegionFailover creates a new `RegionFailover` for the _key_.
+func NewRegionFailover(key *MultiRegionKey, opts ...RegionFailoverOption) *RegionFailover {
+
+	f := &RegionFailover{
+		key:       key,
+		cooldown:  30 * time.Second,
+		clock:     time.Now,
+		downUntil: map[string]time.Time{},
+		regional: func(err error) bool {
+			return !errors.Is(err, context.Canceled)
+		},
+	}
+
+	for _, opt := range opts {
+		opt(f)
+	}
+
+	return f
+
+}
+
+// Candidates returns the regions in the order they are tried, healthy regions first.
+func (f *RegionFailover) Candidates() []string {
+
+	ordered := []string{}
+	seen := map[string]bool{}
+
+	for _, region := range append(append([]string{}, f.preference...), f.key.Regions()...) {
+
+		if _, ok := f.key.ARN(region); ok && !seen[region] {
+			seen[region] = true
+			ordered = append(ordered, region)
+		}
+
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	now := f.clock()
+	healthy := []string{}
+	down := []string{}
+
+	for _, region := range ordered {
+
+		if until, ok := f.downUntil[region]; ok && now.Before(until) {
+			down = append(down, region)
+		} else {
+			healthy = append(healthy, region)
+		}
+
+	}
+
+	return append(healthy, down...)
+
+}
+
+// Do runs _fn_ with the _ARN_ of the nearest healthy region and fails over to the next
+// region on regional errors. It returns the error of _fn_ if not regional, or
+// `ErrAllRegionsFailed` wrapping the last error.
+func (f *RegionFailover) Do(c context.Context, fn func(c context.Context, region, arn string) error) error {
+
+	var last error
+
+	for _, region := range f.Candidates() {
+
+		if err := c.Err(); err != nil {
+			return err
+		}
+
+		arn, _ := f.key.ARN(region)
+
+		err := fn(c, region, arn)
+
+		if err == nil {
+			f.MarkUp(region)
+			return nil
+		}
+
+		if !f.regional(err) {
+			return err
+		}
+
+		f.MarkDown(region)
+		last = err
+
+	}
+
+	return fmt.Errorf("%w: %v", ErrAllRegionsFailed, last)
+
+}
+
+// MarkDown skips _region_ until the cooldown has passed.
+func (f *RegionFailover) MarkDown(region string) {
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.downUntil[region] = f.clock().Add(f.cooldown)
+
+}
+
+// MarkUp marks _region_ as healthy.
+func (f *RegionFailover) MarkUp(region string) {
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	delete(f.downUntil, region)
+
+}
@@ -0,0 +1,77 @@
+package awskms
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	primaryARN = "arn:aws:kms:us-east-1:111122223333:key/mrk-1234abcd"
+	euARN      = "arn:aws:kms:eu-west-1:111122223333:key/mrk-1234abcd"
+	apARN      = "arn:aws:kms:ap-southeast-2:111122223333:key/mrk-1234abcd"
+)
+
+func TestNewMultiRegionKeyValidatesReplicas(t *testing.T) {
+
+	key, err := NewMultiRegionKey(primaryARN, euARN, apARN)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"us-east-1", "eu-west-1", "ap-southeast-2"}, key.Regions())
+
+	_, err = NewMultiRegionKey(primaryARN, "arn:aws:kms:eu-west-1:111122223333:key/mrk-other")
+	assert.Error(t, err)
+
+	_, err = NewMultiRegionKey("arn:aws:kms:us-east-1:111122223333:key/1234abcd")
+	assert.Error(t, err)
+
+}
+
+func TestRegionFailoverPrefersNearestAndFailsOver(t *testing.T) {
+
+	key, err := NewMultiRegionKey(primaryARN, euARN, apARN)
+	require.NoError(t, err)
+
+	now := time.Unix(1700000000, 0)
+
+	failover := NewRegionFailover(key,
+		WithRegionPreference("eu-west-1"),
+		WithRegionCooldown(time.Minute),
+		WithRegionClock(func() time.Time { return now }),
+	)
+
+	assert.Equal(t, []string{"eu-west-1", "us-east-1", "ap-southeast-2"}, failover.Candidates())
+
+	tried := []string{}
+
+	err = failover.Do(context.Background(), func(c context.Context, region, arn string) error {
+
+		tried = append(tried, region)
+
+		if region == "eu-west-1" {
+			return fmt.Errorf("service unavailable")
+		}
+
+		assert.Equal(t, primaryARN, arn)
+		return nil
+
+	})
+
+	require.NoError(t, err)
+	assert.Equal(t, []string{"eu-west-1", "us-east-1"}, tried)
+	assert.Equal(t, []string{"us-east-1", "ap-southeast-2", "eu-west-1"}, failover.Candidates())
+
+	now = now.Add(2 * time.Minute)
+	assert.Equal(t, "eu-west-1", failover.Candidates()[0])
+
+	err = failover.Do(context.Background(), func(c context.Context, region, arn string) error {
+		return fmt.Errorf("down")
+	})
+
+	assert.True(t, errors.Is(err, ErrAllRegionsFailed))
+
+}
@@ -0,0 +1,356 @@
+package azkv
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mariotoffia/goservice/interfaces/ifcrypto"
+	"github.com/mariotoffia/goservice/utils/cryptoutils"
+)
+
+// Resources to request managed identity tokens for, see `ManagedIdentityTokenSource`.
+const (
+	ResourceKeyVault   = "https://vault.azure.net"
+	ResourceManagedHSM = "https://managedhsm.azure.net"
+)
+
+// KeyAttributes is the _Key Vault_ _KeyAttributes_ resource.
+type KeyAttributes struct {
+	Enabled bool `json:"enabled"`
+}
+
+// KeyBundle is the _Key Vault_ _KeyBundle_ resource, i.e. a key version.
+type KeyBundle struct {
+	// Key is the public portion of the key, its _KeyID_ is the key version identifier, i.e.
+	// _https://{vault}/keys/{name}/{version}_.
+	Key        cryptoutils.JSONWebKey `json:"key"`
+	Attributes KeyAttributes          `json:"attributes"`
+	Tags       map[string]string      `json:"tags,omitempty"`
+}
+
+// KeyItem is the _Key Vault_ _KeyItem_ resource returned when listing keys.
+type KeyItem struct {
+	// KeyID is the key identifier without version, i.e. _https://{vault}/keys/{name}_.
+	KeyID      string            `json:"kid"`
+	Attributes KeyAttributes     `json:"attributes"`
+	Tags       map[string]string `json:"tags,omitempty"`
+}
+
+// Client is the subset of the _Key Vault_, or _Managed HSM_, _API_ used by the `KeyVaultKey`
+// and `KeyVaultKeyStore`, such that it may be replaced in tests. See `RESTClient`.
+type Client interface {
+	// GetKey returns the key _name_, i.e. _{name}_ for the current version or _{name}/{version}_.
+	GetKey(c context.Context, name string) (*KeyBundle, error)
+	// ListKeys returns all keys in the vault.
+	ListKeys(c context.Context) ([]KeyItem, error)
+	// Sign signs the _digest_ with the key version _kid_ using the _JWS_ _algorithm_.
+	Sign(c context.Context, kid, algorithm string, digest []byte) ([]byte, error)
+	// WrapKey wraps the _key_ with the key version _kid_ using the _JWE_ _algorithm_.
+	WrapKey(c context.Context, kid, algorithm string, key []byte) ([]byte, error)
+	// UnwrapKey unwraps the _wrapped_ key with the key version _kid_ using the _JWE_ _algorithm_.
+	UnwrapKey(c context.Context, kid, algorithm string, wrapped []byte) ([]byte, error)
+}
+
+// TokenSource returns a _OAuth 2.0_ access token for the _Key Vault_ _API_.
+type TokenSource func(c context.Context) (string, error)
+
+// ManagedIdentityTokenSource returns a `TokenSource` of the managed identity of the instance,
+// e.g. a _AKS_ pod or _App Service_, fetched from the instance metadata service for the
+// _resource_, see `ResourceKeyVault` and `ResourceManagedHSM`. The _clientID_ selects a user
+// assigned identity, leave empty for the system assigned identity. The token is cached until
+// shortly before it expires.
+func ManagedIdentityTokenSource(client *http.Client, resource, clientID string) TokenSource {
+
+	query := url.Values{"api-version": {"2018-02-01"}, "resource": {resource}}
+
+	if clientID != "" {
+		query.Set("client_id", clientID)
+	}
+
+	tokenURL := "http://169.254.169.254/metadata/identity/oauth2/token?" + query.Encode()
+
+	var (
+		mu      sync.Mutex
+		token   string
+		expires time.Time
+	)
+
+	return func(c context.Context) (string, error) {
+
+		mu.Lock()
+		defer mu.Unlock()
+
+		if token != "" && time.Now().Before(expires) {
+			return token, nil
+		}
+
+		req, err := http.NewRequestWithContext(c, http.MethodGet, tokenURL, nil)
+		if err != nil {
+			return "", err
+		}
+
+		req.Header.Set("Metadata", "true")
+
+		resp, err := client.Do(req)
+		if err != nil {
+			return "", err
+		}
+
+		defer resp.Body.Close()
+
+		if resp.StatusCode != http.StatusOK {
+			return "", fmt.Errorf("instance metadata service returned %s", resp.Status)
+		}
+
+		// expires_in is a string in the instance metadata service response
+		var body struct {
+			AccessToken string `json:"access_token"`
+			ExpiresIn   string `json:"expires_in"`
+		}
+
+		if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+			return "", err
+		}
+
+		seconds, err := strconv.Atoi(body.ExpiresIn)
+		if err != nil {
+			return "", fmt.Errorf("invalid token expiry: %s", body.ExpiresIn)
+		}
+
+		token = body.AccessToken
+		expires = time.Now().Add(time.Duration(seconds)*time.Second - time.Minute)
+
+		return token, nil
+
+	}
+
+}
+
+// ErrUntrustedTarget is returned when a request, e.g. to a next link or key id returned by
+// the server, targets another scheme or host than the vault. The access token is never sent.
+var ErrUntrustedTarget = fmt.Errorf("request target is not the vault")
+
+// RESTClientOption configures a `RESTClient`.
+type RESTClientOption func(r *RESTClient)
+
+// WithHTTPClient sets the _client_ (default `http.DefaultClient`).
+func WithHTTPClient(client *http.Client) RESTClientOption {
+	return func(r *RESTClient) {
+		r.client = client
+	}
+}
+
+// WithAPIVersion sets the _API_ version (default _7.4_).
+func WithAPIVersion(version string) RESTClientOption {
+	return func(r *RESTClient) {
+		r.apiVersion = version
+	}
+}
+
+// RESTClient implements the `Client` interface on the _Key Vault_ _REST_ _API_. The same _API_
+// is served by _Managed HSM_, hence only the _vaultURL_ and token resource differs.
+type RESTClient struct {
+	vaultURL   string
+	tokens     TokenSource
+	apiVersion string
+	client     *http.Client
+}
+
+// NewRESTClient creates a new `RESTClient` of the _vaultURL_, e.g. _https://{vault}.vault.azure.net_
+// or _https://{hsm}.managedhsm.azure.net_, authenticated by _tokens_.
+func NewRESTClient(vaultURL string, tokens TokenSource, opts ...RESTClientOption) *RESTClient {
+
+	r := &RESTClient{
+		vaultURL:   strings.TrimSuffix(vaultURL, "/"),
+		tokens:     tokens,
+		apiVersion: "7.4",
+		client:     http.DefaultClient,
+	}
+
+	for _, opt := range opts {
+		opt(r)
+	}
+
+	return r
+
+}
+
+// GetKey implements the `Client` interface. The _name_, and version, are path escaped.
+func (r *RESTClient) GetKey(c context.Context, name string) (*KeyBundle, error) {
+
+	segments := strings.Split(name, "/")
+	if len(segments) > 2 {
+		return nil, fmt.Errorf("invalid Key Vault key name: %q", name)
+	}
+
+	for i, segment := range segments {
+
+		if segment == "" || segment == "." || segment == ".." {
+			return nil, fmt.Errorf("invalid Key Vault key name: %q", name)
+		}
+
+		segments[i] = url.PathEscape(segment)
+
+	}
+
+	var bundle KeyBundle
+
+	if err := r.do(c, http.MethodGet, r.vaultURL+"/keys/"+strings.Join(segments, "/"), nil, &bundle); err != nil {
+		return nil, err
+	}
+
+	return &bundle, nil
+
+}
+
+// ListKeys implements the `Client` interface.
+func (r *RESTClient) ListKeys(c context.Context) ([]KeyItem, error) {
+
+	items := []KeyItem{}
+	next := r.vaultURL + "/keys"
+
+	for next != "" {
+
+		var page struct {
+			Value    []KeyItem `json:"value"`
+			NextLink string    `json:"nextLink"`
+		}
+
+		if err := r.do(c, http.MethodGet, next, nil, &page); err != nil {
+			return nil, err
+		}
+
+		items = append(items, page.Value...)
+		next = page.NextLink
+
+	}
+
+	return items, nil
+
+}
+
+// Sign implements the `Client` interface.
+func (r *RESTClient) Sign(c context.Context, kid, algorithm string, digest []byte) ([]byte, error) {
+	return r.operation(c, kid+"/sign", algorithm, digest)
+}
+
+// WrapKey implements the `Client` interface.
+func (r *RESTClient) WrapKey(c context.Context, kid, algorithm string, key []byte) ([]byte, error) {
+	return r.operation(c, kid+"/wrapkey", algorithm, key)
+}
+
+// UnwrapKey implements the `Client` interface.
+func (r *RESTClient) UnwrapKey(c context.Context, kid, algorithm string, wrapped []byte) ([]byte, error) {
+	return r.operation(c, kid+"/unwrapkey", algorithm, wrapped)
+}
+
+// operation posts a _KeyOperationsParameters_ of _algorithm_ and _value_ to _target_ and
+// returns the value of the _KeyOperationResult_. Values are _base64url_ encoded.
+func (r *RESTClient) operation(c context.Context, target, algorithm string, value []byte) ([]byte, error) {
+
+	req := map[string]string{"alg": algorithm, "value": base64.RawURLEncoding.EncodeToString(value)}
+
+	var resp struct {
+		Value string `json:"value"`
+	}
+
+	if err := r.do(c, http.MethodPost, target, req, &resp); err != nil {
+		return nil, err
+	}
+
+	return base64.RawURLEncoding.DecodeString(strings.TrimRight(resp.Value, "="))
+
+}
+
+// checkTarget checks that _target_ has the scheme and host of the vault.
+func (r *RESTClient) checkTarget(target *url.URL) error {
+
+	vault, err := url.Parse(r.vaultURL)
+	if err != nil {
+		return err
+	}
+
+	if !strings.EqualFold(target.Scheme, vault.Scheme) || !strings.EqualFold(target.Host, vault.Host) {
+		return fmt.Errorf("%w: %s://%s", ErrUntrustedTarget, target.Scheme, target.Host)
+	}
+
+	return nil
+
+}
+
+// do sends a request with the _JSON_ _body_, if any, to _target_ and decodes the _JSON_
+// response into _out_. A _404_ is returned as `ifcrypto.ErrKeyNotFound` and a _target_ outside
+// of the vault as `ErrUntrustedTarget`.
+func (r *RESTClient) do(c context.Context, method, target string, body, out interface{}) error {
+
+	var reader io.Reader
+
+	if body != nil {
+
+		data, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+
+		reader = bytes.NewReader(data)
+
+	}
+
+	// A next link already carries the api-version
+	if !strings.Contains(target, "api-version=") {
+		target += "?api-version=" + url.QueryEscape(r.apiVersion)
+	}
+
+	req, err := http.NewRequestWithContext(c, method, target, reader)
+	if err != nil {
+		return err
+	}
+
+	// Next links and key ids are returned by the server, the token must only be sent to the vault
+	if err := r.checkTarget(req.URL); err != nil {
+		return err
+	}
+
+	token, err := r.tokens(c)
+	if err != nil {
+		return err
+	}
+
+	req.Header.Set("Authorization", "Bearer "+token)
+
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := r.client.Do(req)
+	if err != nil {
+		return err
+	}
+
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+
+	switch {
+	case resp.StatusCode == http.StatusNotFound:
+		return fmt.Errorf("%w: %s", ifcrypto.ErrKeyNotFound, req.URL.Path)
+	case resp.StatusCode != http.StatusOK:
+		return fmt.Errorf("Key Vault %s %s returned %s: %s", method, req.URL.Path, resp.Status, strings.TrimSpace(string(data)))
+	}
+
+	return json.Unmarshal(data, out)
+
+}
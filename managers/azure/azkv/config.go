@@ -0,0 +1,55 @@
+package azkv
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/mariotoffia/goservice/managers/go/gocrypto"
+)
+
+func init() {
+	gocrypto.RegisterComponent(gocrypto.ComponentKeyStore, "azure-key-vault", keyVaultKeyStoreFactory)
+}
+
+// keyVaultKeyStoreFactory creates a `KeyVaultKeyStore` of a _vault_url_, authenticated by the
+// managed identity of the instance. The token _resource_ defaults to `ResourceManagedHSM` for
+// _Managed HSM_ urls and `ResourceKeyVault` otherwise.
+func keyVaultKeyStoreFactory(a *gocrypto.Assembly, cfg gocrypto.ComponentConfig) (interface{}, error) {
+
+	var options struct {
+		VaultURL string            `json:"vault_url"`
+		Resource string            `json:"resource"`
+		ClientID string            `json:"client_id"`
+		Tags     map[string]string `json:"tags"`
+	}
+
+	if err := cfg.Decode(&options); err != nil {
+		return nil, err
+	}
+
+	vault, err := url.Parse(options.VaultURL)
+	if err != nil || vault.Host == "" {
+		return nil, fmt.Errorf("vault_url %q is not a url", options.VaultURL)
+	}
+
+	if options.Resource == "" {
+
+		options.Resource = ResourceKeyVault
+		if strings.HasSuffix(vault.Hostname(), ".managedhsm.azure.net") {
+			options.Resource = ResourceManagedHSM
+		}
+
+	}
+
+	opts := []KeyVaultKeyStoreOption{}
+	for k, v := range options.Tags {
+		opts = append(opts, WithKeyVaultTag(k, v))
+	}
+
+	tokens := ManagedIdentityTokenSource(http.DefaultClient, options.Resource, options.ClientID)
+
+	return NewKeyVaultKeyStore(NewRESTClient(options.VaultURL, tokens), opts...), nil
+
+}
@@ -0,0 +1,350 @@
+package azkv
+
+import (
+	"context"
+	"crypto"
+	"crypto/rsa"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/mariotoffia/goservice/interfaces/ifcrypto"
+	"github.com/mariotoffia/goservice/managers/go/gocrypto"
+	"github.com/mariotoffia/goservice/utils/cryptoutils"
+)
+
+// keyVaultKeyOps maps the _Key Vault_ key operations to `ifcrypto.KeyUsage`.
+var keyVaultKeyOps = map[string]ifcrypto.KeyUsage{
+	"sign":      ifcrypto.KeyUsageSign,
+	"verify":    ifcrypto.KeyUsageVerify,
+	"encrypt":   ifcrypto.KeyUsageEncrypt,
+	"wrapKey":   ifcrypto.KeyUsageEncrypt,
+	"decrypt":   ifcrypto.KeyUsageDecrypt,
+	"unwrapKey": ifcrypto.KeyUsageDecrypt,
+}
+
+// KeyVaultKey implements the `ifcrypto.KeyPair` interface for a _Key Vault_, or _Managed HSM_,
+// key. The private key never leaves the vault, hence `IsRemoteKey` is always `true`.
+//
+// The `GetID` is the key version identifier, i.e. _https://{vault}/keys/{name}/{version}_.
+//
+// _RSA_ and _EC_ keys implements `crypto.Signer` where each signature is a vault request.
+// `Verify` uses the `GetPublic` key in process. _RSA_ and _oct-HSM_ keys may wrap keys, see
+// `WrapKeyContext`.
+type KeyVaultKey struct {
+	// Derive from `gocrypto.KeyBase`
+	gocrypto.KeyBase
+	client Client
+	public ifcrypto.PublicKey
+}
+
+// NewKeyVaultKey resolves the key _name_, i.e. _{name}_ for the current version or
+// _{name}/{version}_, and creates a new `KeyVaultKey` of it. Disabled keys are rejected.
+func NewKeyVaultKey(c context.Context, client Client, name string) (*KeyVaultKey, error) {
+
+	bundle, err := client.GetKey(c, name)
+	if err != nil {
+		return nil, err
+	}
+
+	if !bundle.Attributes.Enabled {
+		return nil, fmt.Errorf("Key Vault key %s is disabled", name)
+	}
+
+	return newKeyVaultKey(client, bundle)
+
+}
+
+// newKeyVaultKey creates a new `KeyVaultKey` of the _bundle_.
+func newKeyVaultKey(client Client, bundle *KeyBundle) (*KeyVaultKey, error) {
+
+	jwk := bundle.Key
+	usage := []ifcrypto.KeyUsage{}
+
+	for _, op := range jwk.KeyOps {
+
+		if u, ok := keyVaultKeyOps[op]; ok && !hasUsage(usage, u) {
+			usage = append(usage, u)
+		}
+
+	}
+
+	// Normalize the vault JWK into a standard public JWK
+	jwk.KeyType = strings.TrimSuffix(jwk.KeyType, "-HSM")
+	jwk.KeyOps = nil
+
+	if jwk.Curve == "P-256K" {
+		jwk.Curve = "secp256k1"
+	}
+
+	if jwk.KeyType == "oct" {
+
+		return &KeyVaultKey{
+			KeyBase: gocrypto.NewKeyBase(bundle.Key.KeyID, ifcrypto.KeyTypeSymmetric, 256, usage...),
+			client:  client,
+		}, nil
+
+	}
+
+	loaded, err := gocrypto.NewKeyFromJSONWebKey(&jwk, ifcrypto.KeyUsageVerify)
+	if err != nil {
+		return nil, err
+	}
+
+	public, ok := loaded.(ifcrypto.PublicKey)
+	if !ok {
+		return nil, fmt.Errorf("Key Vault key %s has no public key", bundle.Key.KeyID)
+	}
+
+	return &KeyVaultKey{
+		KeyBase: gocrypto.NewKeyBase(bundle.Key.KeyID, public.GetKeyType(), public.GetKeySize(), usage...),
+		client:  client,
+		public:  public,
+	}, nil
+
+}
+
+// GetPublic returns the public key, or `nil` if symmetric.
+func (k *KeyVaultKey) GetPublic() ifcrypto.PublicKey {
+	return k.public
+}
+
+// GetKey returns the key version identifier since the key material never leaves the vault.
+func (k *KeyVaultKey) GetKey() interface{} {
+	return k.GetID()
+}
+
+// IsSymmetric returns `true` if this is a _oct-HSM_ key.
+func (k *KeyVaultKey) IsSymmetric() bool {
+	return k.GetKeyType() == ifcrypto.KeyTypeSymmetric
+}
+
+// IsPrivate returns `true` since a vault key always holds the private key.
+func (k *KeyVaultKey) IsPrivate() bool {
+	return true
+}
+
+// IsRemoteKey returns `true`.
+func (k *KeyVaultKey) IsRemoteKey() bool {
+	return true
+}
+
+// Public implements the `crypto.Signer` _interface_.
+func (k *KeyVaultKey) Public() crypto.PublicKey {
+
+	if k.public == nil {
+		return nil
+	}
+
+	return k.public.GetKey()
+
+}
+
+// Sign implements the `crypto.Signer` _interface_, see `SignContext`. The _rand_ is not used.
+func (k *KeyVaultKey) Sign(rand io.Reader, digest []byte, opts crypto.SignerOpts) ([]byte, error) {
+	return k.SignContext(context.Background(), digest, opts)
+}
+
+// SignContext signs the _digest_ in the vault. If _opts_ is a `*rsa.PSSOptions` the _PSS_
+// algorithm is used, otherwise _PKCS #1 v1.5_ for _RSA_ keys. _ECDSA_ signatures are
+// _ASN.1 DER_ encoded unless _opts_ is a `*gocrypto.ECDSASignerOpts` with _Raw_ set.
+func (k *KeyVaultKey) SignContext(c context.Context, digest []byte, opts crypto.SignerOpts) ([]byte, error) {
+
+	if gocrypto.IsVerifyOnly() {
+		return nil, gocrypto.ErrVerifyOnly
+	}
+
+	if !k.HasUsage(ifcrypto.KeyUsageSign) {
+		return nil, fmt.Errorf("Key Vault key %s is not a signing key", k.GetID())
+	}
+
+	algorithm, err := k.signingAlgorithm(digest, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := gocrypto.CheckAlgorithm(ifcrypto.PolicyOperationSign, k, opts.HashFunc()); err != nil {
+		return nil, err
+	}
+
+	signature, err := k.client.Sign(c, k.GetID(), algorithm, digest)
+	if err != nil {
+		return nil, err
+	}
+
+	// Key Vault returns the JWS r || s encoding of ECDSA signatures
+	if k.GetKeyType() == ifcrypto.KeyTypeRsa {
+		return signature, nil
+	}
+
+	if raw, ok := opts.(*gocrypto.ECDSASignerOpts); ok && raw.Raw {
+		return signature, nil
+	}
+
+	return cryptoutils.ECDSASignatureFromRaw(signature)
+
+}
+
+// SignMessage implements the `ifcrypto.MessageSigner` interface, see `gocrypto.SignMessage`.
+func (k *KeyVaultKey) SignMessage(msg []byte, hash crypto.Hash) ([]byte, error) {
+	return gocrypto.SignMessage(k, msg, hash)
+}
+
+// Verify implements the `ifcrypto.SignatureVerifier` interface using the public key.
+func (k *KeyVaultKey) Verify(digest, signature []byte, opts crypto.SignerOpts) error {
+
+	verifier, ok := k.public.(ifcrypto.SignatureVerifier)
+	if !ok {
+		return fmt.Errorf("Key Vault key %s can not verify", k.GetID())
+	}
+
+	return verifier.Verify(digest, signature, opts)
+
+}
+
+// WrapKeyContext wraps the _key_, e.g. a data encryption key, with _RSA-OAEP-256_ for _RSA_
+// keys and _A256KW_ for _oct-HSM_ keys.
+func (k *KeyVaultKey) WrapKeyContext(c context.Context, key []byte) ([]byte, error) {
+
+	algorithm, err := k.wrapAlgorithm(ifcrypto.KeyUsageEncrypt)
+	if err != nil {
+		return nil, err
+	}
+
+	return k.client.WrapKey(c, k.GetID(), algorithm, key)
+
+}
+
+// UnwrapKeyContext unwraps the _wrapped_ key from `WrapKeyContext`.
+func (k *KeyVaultKey) UnwrapKeyContext(c context.Context, wrapped []byte) ([]byte, error) {
+
+	if gocrypto.IsVerifyOnly() {
+		return nil, gocrypto.ErrVerifyOnly
+	}
+
+	algorithm, err := k.wrapAlgorithm(ifcrypto.KeyUsageDecrypt)
+	if err != nil {
+		return nil, err
+	}
+
+	// RSA-OAEP-256 unwraps with SHA-256, A256KW has no hash
+	hash := crypto.Hash(0)
+
+	if k.GetKeyType() == ifcrypto.KeyTypeRsa {
+		hash = crypto.SHA256
+	}
+
+	if err := gocrypto.CheckAlgorithm(ifcrypto.PolicyOperationDecrypt, k, hash); err != nil {
+		return nil, err
+	}
+
+	return k.client.UnwrapKey(c, k.GetID(), algorithm, wrapped)
+
+}
+
+// wrapAlgorithm returns the _JWE_ key wrap algorithm of this key if it has the _usage_.
+func (k *KeyVaultKey) wrapAlgorithm(usage ifcrypto.KeyUsage) (string, error) {
+
+	if !k.HasUsage(usage) {
+		return "", fmt.Errorf("Key Vault key %s do not have usage %s", k.GetID(), usage)
+	}
+
+	switch k.GetKeyType() {
+	case ifcrypto.KeyTypeRsa:
+		return "RSA-OAEP-256", nil
+	case ifcrypto.KeyTypeSymmetric:
+		return "A256KW", nil
+	}
+
+	return "", fmt.Errorf("Key Vault key %s can not wrap keys", k.GetID())
+
+}
+
+// signingAlgorithm returns the _JWS_ algorithm of _opts_ for this key.
+func (k *KeyVaultKey) signingAlgorithm(digest []byte, opts crypto.SignerOpts) (string, error) {
+
+	if opts == nil {
+		return "", fmt.Errorf("Key Vault key %s requires a hash", k.GetID())
+	}
+
+	hash := opts.HashFunc()
+
+	if hash != crypto.SHA256 && hash != crypto.SHA384 && hash != crypto.SHA512 {
+		return "", fmt.Errorf("Key Vault do not support hash: %s", hash)
+	}
+
+	if len(digest) != hash.Size() {
+		return "", fmt.Errorf("digest is %d bytes, %s requires %d", len(digest), hash, hash.Size())
+	}
+
+	bits := hash.Size() * 8
+
+	switch k.GetKeyType() {
+	case ifcrypto.KeyTypeRsa:
+
+		if pss, ok := opts.(*rsa.PSSOptions); ok {
+
+			// Key Vault always uses a salt of the hash size
+			if pss.SaltLength != rsa.PSSSaltLengthEqualsHash &&
+				pss.SaltLength != rsa.PSSSaltLengthAuto &&
+				pss.SaltLength != hash.Size() {
+
+				return "", fmt.Errorf("Key Vault do not support PSS salt length: %d", pss.SaltLength)
+
+			}
+
+			return fmt.Sprintf("PS%d", bits), nil
+
+		}
+
+		return fmt.Sprintf("RS%d", bits), nil
+
+	case ifcrypto.KeyTypeEccNistP, ifcrypto.KeyTypeEccSecgP256k1:
+
+		// The curve decides the hash, e.g. ES384 for P-384
+		algorithm := ecAlgorithm(k.GetKeyType(), k.GetKeySize())
+
+		if algorithm != fmt.Sprintf("ES%d", bits) && algorithm != fmt.Sprintf("ES%dK", bits) {
+			return "", fmt.Errorf("Key Vault key %s requires %s", k.GetID(), algorithm)
+		}
+
+		return algorithm, nil
+
+	}
+
+	return "", fmt.Errorf("Key Vault key %s can not sign", k.GetID())
+
+}
+
+// ecAlgorithm returns the _JWS_ algorithm of a _EC_ key.
+func ecAlgorithm(keyType ifcrypto.KeyType, keySize int) string {
+
+	if keyType == ifcrypto.KeyTypeEccSecgP256k1 {
+		return "ES256K"
+	}
+
+	switch keySize {
+	case 256:
+		return "ES256"
+	case 384:
+		return "ES384"
+	}
+
+	return "ES512"
+
+}
+
+// hasUsage returns `true` if _usage_ contains _u_.
+func hasUsage(usage []ifcrypto.KeyUsage, u ifcrypto.KeyUsage) bool {
+
+	for _, v := range usage {
+
+		if v == u {
+			return true
+		}
+
+	}
+
+	return false
+
+}
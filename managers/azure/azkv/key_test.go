@@ -0,0 +1,336 @@
+//go:build !verifyonly
+// +build !verifyonly
+
+package azkv
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/mariotoffia/goservice/interfaces/ifcrypto"
+	"github.com/mariotoffia/goservice/managers/go/gocrypto"
+	"github.com/mariotoffia/goservice/utils/cryptoutils"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeKeyVault serves the subset of the _Key Vault_ _REST_ _API_ used by the `RESTClient`.
+type fakeKeyVault struct {
+	url     string
+	bundles map[string]KeyBundle
+	signers map[string]crypto.Signer
+	kek     []byte
+}
+
+func newFakeKeyVault(t *testing.T) (*fakeKeyVault, *RESTClient) {
+
+	ec, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+
+	f := &fakeKeyVault{
+		bundles: map[string]KeyBundle{},
+		signers: map[string]crypto.Signer{"ec": ec, "rsa": rsaKey},
+		kek:     make([]byte, 32),
+	}
+
+	server := httptest.NewServer(f)
+	t.Cleanup(server.Close)
+
+	f.url = server.URL
+
+	add := func(name string, public crypto.PublicKey, kty string, enabled bool, tags map[string]string, ops ...string) {
+
+		jwk := &cryptoutils.JSONWebKey{KeyType: kty}
+
+		if public != nil {
+			jwk, err = cryptoutils.PublicKeyToJWK(public)
+			require.NoError(t, err)
+			jwk.KeyType += "-HSM"
+		}
+
+		jwk.KeyID = f.url + "/keys/" + name + "/v1"
+		jwk.KeyOps = ops
+
+		f.bundles[name] = KeyBundle{Key: *jwk, Attributes: KeyAttributes{Enabled: enabled}, Tags: tags}
+
+	}
+
+	add("ec", ec.Public(), "", true, map[string]string{"team": "payments"}, "sign", "verify")
+	add("rsa", rsaKey.Public(), "", true, nil, "sign", "verify", "wrapKey", "unwrapKey")
+	add("aes", nil, "oct-HSM", true, map[string]string{"team": "payments"}, "wrapKey", "unwrapKey")
+	add("old", ec.Public(), "", false, map[string]string{"team": "payments"}, "sign", "verify")
+
+	client := NewRESTClient(
+		server.URL,
+		func(c context.Context) (string, error) { return "token", nil },
+		WithHTTPClient(server.Client()),
+	)
+
+	return f, client
+
+}
+
+func (f *fakeKeyVault) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+
+	if r.Header.Get("Authorization") != "Bearer token" || r.URL.Query().Get("api-version") != "7.4" {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	reply := func(v interface{}) {
+		_ = json.NewEncoder(w).Encode(v)
+	}
+
+	// keys[/{name}[/{version}[/{operation}]]]
+	parts := strings.Split(strings.TrimPrefix(r.URL.Path, "/"), "/")
+
+	if len(parts) == 1 {
+
+		items := []KeyItem{}
+		for name, bundle := range f.bundles {
+			items = append(items, KeyItem{KeyID: f.url + "/keys/" + name, Attributes: bundle.Attributes, Tags: bundle.Tags})
+		}
+
+		reply(map[string]interface{}{"value": items})
+		return
+
+	}
+
+	bundle, ok := f.bundles[parts[1]]
+	if !ok || (len(parts) > 2 && parts[2] != "v1") {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	if len(parts) < 4 {
+		reply(bundle)
+		return
+	}
+
+	var req struct {
+		Algorithm string `json:"alg"`
+		Value     string `json:"value"`
+	}
+
+	_ = json.NewDecoder(r.Body).Decode(&req)
+	value, _ := base64.RawURLEncoding.DecodeString(req.Value)
+
+	var (
+		result []byte
+		err    error
+	)
+
+	switch parts[3] {
+	case "sign":
+
+		switch signer := f.signers[parts[1]].(type) {
+		case *ecdsa.PrivateKey:
+
+			var der []byte
+			if der, err = signer.Sign(rand.Reader, value, crypto.SHA256); err == nil {
+				result, err = cryptoutils.ECDSASignatureToRaw(der, 32)
+			}
+
+		case *rsa.PrivateKey:
+
+			if req.Algorithm == "PS256" {
+				result, err = rsa.SignPSS(rand.Reader, signer, crypto.SHA256, value, &rsa.PSSOptions{SaltLength: rsa.PSSSaltLengthEqualsHash})
+			} else {
+				result, err = rsa.SignPKCS1v15(rand.Reader, signer, crypto.SHA256, value)
+			}
+
+		}
+
+	case "wrapkey":
+
+		if req.Algorithm == "A256KW" {
+			result, err = cryptoutils.AESKeyWrap(f.kek, value)
+		} else {
+			result, err = rsa.EncryptOAEP(sha256.New(), rand.Reader, f.signers[parts[1]].Public().(*rsa.PublicKey), value, nil)
+		}
+
+	case "unwrapkey":
+
+		if req.Algorithm == "A256KW" {
+			result, err = cryptoutils.AESKeyUnwrap(f.kek, value)
+		} else {
+			result, err = rsa.DecryptOAEP(sha256.New(), rand.Reader, f.signers[parts[1]].(*rsa.PrivateKey), value, nil)
+		}
+
+	}
+
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	reply(map[string]string{"kid": bundle.Key.KeyID, "value": base64.RawURLEncoding.EncodeToString(result)})
+
+}
+
+func TestKeyVaultKeySigns(t *testing.T) {
+
+	f, client := newFakeKeyVault(t)
+	digest := sha256.Sum256([]byte("hello world"))
+
+	key, err := NewKeyVaultKey(context.Background(), client, "ec")
+	require.NoError(t, err)
+
+	assert.Equal(t, f.url+"/keys/ec/v1", key.GetID())
+	assert.True(t, key.IsRemoteKey())
+	assert.Equal(t, ifcrypto.KeyTypeEccNistP, key.GetKeyType())
+	assert.Equal(t, 256, key.GetKeySize())
+
+	signature, err := key.Sign(rand.Reader, digest[:], crypto.SHA256)
+	require.NoError(t, err)
+
+	assert.NoError(t, key.Verify(digest[:], signature, crypto.SHA256))
+	assert.True(t, ecdsa.VerifyASN1(f.signers["ec"].Public().(*ecdsa.PublicKey), digest[:], signature))
+
+	raw, err := key.Sign(rand.Reader, digest[:], &gocrypto.ECDSASignerOpts{Hash: crypto.SHA256, Raw: true})
+	require.NoError(t, err)
+	assert.Len(t, raw, 64)
+
+	_, err = key.Sign(rand.Reader, make([]byte, 48), crypto.SHA384)
+	assert.Error(t, err, "P-256 requires ES256")
+
+	rsaKey, err := NewKeyVaultKey(context.Background(), client, "rsa/v1")
+	require.NoError(t, err)
+	assert.Equal(t, 2048, rsaKey.GetKeySize())
+
+	pss := &rsa.PSSOptions{SaltLength: rsa.PSSSaltLengthEqualsHash, Hash: crypto.SHA256}
+
+	signature, err = rsaKey.Sign(rand.Reader, digest[:], pss)
+	require.NoError(t, err)
+	assert.NoError(t, rsaKey.Verify(digest[:], signature, pss))
+
+	signature[0] ^= 0x01
+	assert.Error(t, rsaKey.Verify(digest[:], signature, pss))
+
+	_, err = NewKeyVaultKey(context.Background(), client, "old")
+	assert.Error(t, err)
+
+	_, err = NewKeyVaultKey(context.Background(), client, "missing")
+	assert.True(t, errors.Is(err, ifcrypto.ErrKeyNotFound))
+
+}
+
+func TestKeyVaultKeyWrapsKeys(t *testing.T) {
+
+	_, client := newFakeKeyVault(t)
+	c := context.Background()
+	dek := []byte("0123456789abcdef0123456789abcdef")
+
+	for _, name := range []string{"rsa", "aes"} {
+
+		key, err := NewKeyVaultKey(c, client, name)
+		require.NoError(t, err)
+
+		wrapped, err := key.WrapKeyContext(c, dek)
+		require.NoError(t, err)
+		assert.NotEqual(t, dek, wrapped)
+
+		unwrapped, err := key.UnwrapKeyContext(c, wrapped)
+		require.NoError(t, err)
+		assert.Equal(t, dek, unwrapped)
+
+	}
+
+	key, err := NewKeyVaultKey(c, client, "ec")
+	require.NoError(t, err)
+
+	_, err = key.WrapKeyContext(c, dek)
+	assert.Error(t, err)
+
+}
+
+func TestKeyVaultKeyStoreListsByTag(t *testing.T) {
+
+	f, client := newFakeKeyVault(t)
+	c := context.Background()
+
+	store := NewKeyVaultKeyStore(client)
+
+	keys, err := store.List(c, ifcrypto.KeyFilter{Usages: []ifcrypto.KeyUsage{ifcrypto.KeyUsageSign}})
+	require.NoError(t, err)
+	require.Len(t, keys, 2)
+	assert.Equal(t, f.url+"/keys/ec/v1", keys[0].GetID())
+	assert.Equal(t, f.url+"/keys/rsa/v1", keys[1].GetID())
+
+	store = NewKeyVaultKeyStore(client, WithKeyVaultTag("team", "payments"))
+
+	keys, err = store.List(c, ifcrypto.KeyFilter{})
+	require.NoError(t, err)
+	require.Len(t, keys, 2)
+	assert.Equal(t, f.url+"/keys/aes/v1", keys[0].GetID())
+	assert.Equal(t, f.url+"/keys/ec/v1", keys[1].GetID())
+
+	assert.True(t, errors.Is(store.Put(c, keys[0]), ErrKeyVaultReadOnly))
+	assert.True(t, errors.Is(store.Delete(c, keys[0].GetID()), ErrKeyVaultReadOnly))
+
+}
+
+func TestRESTClientOnlySendsTokenToVault(t *testing.T) {
+
+	var leaked int32
+
+	foreign := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+
+		if r.Header.Get("Authorization") != "" {
+			atomic.AddInt32(&leaked, 1)
+		}
+
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{"value": []KeyItem{}})
+
+	}))
+
+	defer foreign.Close()
+
+	f, client := newFakeKeyVault(t)
+	c := context.Background()
+
+	vault := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{"value": []KeyItem{}, "nextLink": foreign.URL + "/keys?api-version=7.4"})
+	}))
+
+	defer vault.Close()
+
+	paging := NewRESTClient(vault.URL, func(c context.Context) (string, error) { return "token", nil })
+
+	_, err := paging.ListKeys(c)
+	assert.True(t, errors.Is(err, ErrUntrustedTarget))
+
+	_, err = client.Sign(c, foreign.URL+"/keys/ec/v1", "ES256", make([]byte, 32))
+	assert.True(t, errors.Is(err, ErrUntrustedTarget))
+
+	assert.Equal(t, int32(0), atomic.LoadInt32(&leaked))
+
+	// Names are escaped, not interpreted as path or query
+	for _, name := range []string{"ec?x=1", "ec%2Fv1", "ec/v1/sign", "../keys/ec", "ec/.."} {
+
+		_, err = client.GetKey(c, name)
+		assert.Error(t, err, name)
+
+	}
+
+	bundle, err := client.GetKey(c, "ec/v1")
+	require.NoError(t, err)
+	assert.Equal(t, f.url+"/keys/ec/v1", bundle.Key.KeyID)
+
+}
@@ -0,0 +1,143 @@
+package azkv
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/mariotoffia/goservice/interfaces/ifcrypto"
+)
+
+// ErrKeyVaultReadOnly is returned by `KeyVaultKeyStore.Put` and `KeyVaultKeyStore.Delete` since
+// vault keys are created and deleted in the vault, not by the service.
+var ErrKeyVaultReadOnly = fmt.Errorf("Key Vault key store is read-only")
+
+// KeyVaultKeyStoreOption configures a `KeyVaultKeyStore`.
+type KeyVaultKeyStoreOption func(s *KeyVaultKeyStore)
+
+// WithKeyVaultTag lists only the keys that has the tag _key_ with _value_. When used multiple
+// times all tags must match.
+func WithKeyVaultTag(key, value string) KeyVaultKeyStoreOption {
+	return func(s *KeyVaultKeyStore) {
+		s.tags[key] = value
+	}
+}
+
+// KeyVaultKeyStore implements the `ifcrypto.KeyStore` interface on top of a _Key Vault_ or
+// _Managed HSM_. Keys are resolved by name, or name and version, to a `KeyVaultKey` and
+// cached by the given name.
+//
+// .Example
+// [source,go]
+// ----
+// tokens := ManagedIdentityTokenSource(http.DefaultClient, ResourceKeyVault, "")
+// store := NewKeyVaultKeyStore(NewRESTClient("https://payments.vault.azure.net", tokens))
+//
+// key, err := store.Get(c, "signer")
+// ----
+type KeyVaultKeyStore struct {
+	client Client
+	tags   map[string]string
+	mu     sync.Mutex
+	keys   map[string]*KeyVaultKey
+}
+
+// NewKeyVaultKeyStore creates a new `KeyVaultKeyStore` of the vault served by _client_.
+func NewKeyVaultKeyStore(client Client, opts ...KeyVaultKeyStoreOption) *KeyVaultKeyStore {
+
+	s := &KeyVaultKeyStore{
+		client: client,
+		tags:   map[string]string{},
+		keys:   map[string]*KeyVaultKey{},
+	}
+
+	for _, opt := range opts {
+		opt(s)
+	}
+
+	return s
+
+}
+
+// Get implements the `ifcrypto.KeyStore` interface. The _id_ is a key name, that resolves to
+// its current version, or _{name}/{version}_.
+func (s *KeyVaultKeyStore) Get(c context.Context, id string) (ifcrypto.Key, error) {
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if key, ok := s.keys[id]; ok {
+		return key, nil
+	}
+
+	key, err := NewKeyVaultKey(c, s.client, id)
+	if err != nil {
+		return nil, err
+	}
+
+	s.keys[id] = key
+
+	return key, nil
+
+}
+
+// Put implements the `ifcrypto.KeyStore` interface, it always fails with `ErrKeyVaultReadOnly`.
+func (s *KeyVaultKeyStore) Put(c context.Context, key ifcrypto.Key) error {
+	return ErrKeyVaultReadOnly
+}
+
+// Delete implements the `ifcrypto.KeyStore` interface, it always fails with `ErrKeyVaultReadOnly`.
+func (s *KeyVaultKeyStore) Delete(c context.Context, ids ...string) error {
+	return ErrKeyVaultReadOnly
+}
+
+// List implements the `ifcrypto.KeyStore` interface. It lists the current version of the
+// enabled keys in the vault that has the configured tags and matches _filter_.
+func (s *KeyVaultKeyStore) List(c context.Context, filter ifcrypto.KeyFilter) ([]ifcrypto.Key, error) {
+
+	items, err := s.client.ListKeys(c)
+	if err != nil {
+		return nil, err
+	}
+
+	keys := []ifcrypto.Key{}
+
+	for _, item := range items {
+
+		if !item.Attributes.Enabled || !s.hasTags(item.Tags) {
+			continue
+		}
+
+		key, err := s.Get(c, item.KeyID[strings.LastIndex(item.KeyID, "/keys/")+len("/keys/"):])
+		if err != nil {
+			return nil, err
+		}
+
+		if filter.Match(key) {
+			keys = append(keys, key)
+		}
+
+	}
+
+	sort.Slice(keys, func(i, j int) bool { return keys[i].GetID() < keys[j].GetID() })
+
+	return keys, nil
+
+}
+
+// hasTags returns `true` if _tags_ has all configured tags.
+func (s *KeyVaultKeyStore) hasTags(tags map[string]string) bool {
+
+	for k, v := range s.tags {
+
+		if tags[k] != v {
+			return false
+		}
+
+	}
+
+	return true
+
+}
@@ -0,0 +1,395 @@
+package gcpkms
+
+import (
+	"bytes"
+	"context"
+	"crypto"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mariotoffia/goservice/interfaces/ifcrypto"
+)
+
+// Cloud KMS key purposes.
+const (
+	PurposeEncryptDecrypt = "ENCRYPT_DECRYPT"
+	PurposeAsymmetricSign = "ASYMMETRIC_SIGN"
+)
+
+// CryptoKeyVersionStateEnabled is the state of a usable key version.
+const CryptoKeyVersionStateEnabled = "ENABLED"
+
+// CryptoKey is the _Cloud KMS_ _CryptoKey_ resource.
+type CryptoKey struct {
+	// Name is _projects/*/locations/*/keyRings/*/cryptoKeys/*_.
+	Name    string            `json:"name"`
+	Purpose string            `json:"purpose"`
+	Primary *CryptoKeyVersion `json:"primary,omitempty"`
+	Labels  map[string]string `json:"labels,omitempty"`
+}
+
+// CryptoKeyVersion is the _Cloud KMS_ _CryptoKeyVersion_ resource.
+type CryptoKeyVersion struct {
+	// Name is _projects/*/locations/*/keyRings/*/cryptoKeys/*/cryptoKeyVersions/*_.
+	Name      string `json:"name"`
+	State     string `json:"state"`
+	Algorithm string `json:"algorithm"`
+}
+
+// Client is the subset of the _Cloud KMS_ _API_ used by the `CloudKmsKey` and
+// `CloudKmsKeyStore`, such that it may be replaced in tests. See `RESTClient`.
+type Client interface {
+	// GetCryptoKey returns the crypto key with _name_.
+	GetCryptoKey(c context.Context, name string) (*CryptoKey, error)
+	// ListCryptoKeys returns all crypto keys in the _keyRing_.
+	ListCryptoKeys(c context.Context, keyRing string) ([]CryptoKey, error)
+	// GetCryptoKeyVersion returns the crypto key version with _name_.
+	GetCryptoKeyVersion(c context.Context, name string) (*CryptoKeyVersion, error)
+	// ListCryptoKeyVersions returns all versions of the _cryptoKey_.
+	ListCryptoKeyVersions(c context.Context, cryptoKey string) ([]CryptoKeyVersion, error)
+	// GetPublicKey returns the _PEM_ encoded public key of the _version_.
+	GetPublicKey(c context.Context, version string) ([]byte, error)
+	// AsymmetricSign signs the _digest_, of _hash_, with the _version_.
+	AsymmetricSign(c context.Context, version string, hash crypto.Hash, digest []byte) ([]byte, error)
+	// Encrypt encrypts _plaintext_ with the primary version of _cryptoKey_. It returns the
+	// ciphertext and the name of the version used.
+	Encrypt(c context.Context, cryptoKey string, plaintext, aad []byte) ([]byte, string, error)
+	// Decrypt decrypts _ciphertext_ with the version of _cryptoKey_ it was encrypted with.
+	Decrypt(c context.Context, cryptoKey string, ciphertext, aad []byte) ([]byte, error)
+}
+
+// TokenSource returns a _OAuth 2.0_ access token for the _Cloud KMS_ _API_.
+type TokenSource func(c context.Context) (string, error)
+
+// MetadataTokenSource returns a `TokenSource` of the service account attached to the instance,
+// e.g. the workload identity of a _GKE_ pod, fetched from the metadata server. The token is
+// cached until shortly before it expires.
+func MetadataTokenSource(client *http.Client) TokenSource {
+
+	const tokenURL = "http://metadata.google.internal/computeMetadata/v1/instance/service-accounts/default/token"
+
+	var (
+		mu      sync.Mutex
+		token   string
+		expires time.Time
+	)
+
+	return func(c context.Context) (string, error) {
+
+		mu.Lock()
+		defer mu.Unlock()
+
+		if token != "" && time.Now().Before(expires) {
+			return token, nil
+		}
+
+		req, err := http.NewRequestWithContext(c, http.MethodGet, tokenURL, nil)
+		if err != nil {
+			return "", err
+		}
+
+		req.Header.Set("Metadata-Flavor", "Google")
+
+		resp, err := client.Do(req)
+		if err != nil {
+			return "", err
+		}
+
+		defer resp.Body.Close()
+
+		if resp.StatusCode != http.StatusOK {
+			return "", fmt.Errorf("metadata server returned %s", resp.Status)
+		}
+
+		var body struct {
+			AccessToken string `json:"access_token"`
+			ExpiresIn   int    `json:"expires_in"`
+		}
+
+		if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+			return "", err
+		}
+
+		token = body.AccessToken
+		expires = time.Now().Add(time.Duration(body.ExpiresIn)*time.Second - time.Minute)
+
+		return token, nil
+
+	}
+
+}
+
+// RESTClientOption configures a `RESTClient`.
+type RESTClientOption func(r *RESTClient)
+
+// WithEndpoint sets the _API_ endpoint (default _https://cloudkms.googleapis.com/v1/_), e.g.
+// a private service connect endpoint.
+func WithEndpoint(endpoint string) RESTClientOption {
+	return func(r *RESTClient) {
+		r.endpoint = strings.TrimSuffix(endpoint, "/") + "/"
+	}
+}
+
+// WithHTTPClient sets the _client_ (default `http.DefaultClient`).
+func WithHTTPClient(client *http.Client) RESTClientOption {
+	return func(r *RESTClient) {
+		r.client = client
+	}
+}
+
+// RESTClient implements the `Client` interface on the _Cloud KMS_ _REST_ _API_.
+type RESTClient struct {
+	tokens   TokenSource
+	endpoint string
+	client   *http.Client
+}
+
+// NewRESTClient creates a new `RESTClient` authenticated by _tokens_.
+func NewRESTClient(tokens TokenSource, opts ...RESTClientOption) *RESTClient {
+
+	r := &RESTClient{
+		tokens:   tokens,
+		endpoint: "https://cloudkms.googleapis.com/v1/",
+		client:   http.DefaultClient,
+	}
+
+	for _, opt := range opts {
+		opt(r)
+	}
+
+	return r
+
+}
+
+// GetCryptoKey implements the `Client` interface.
+func (r *RESTClient) GetCryptoKey(c context.Context, name string) (*CryptoKey, error) {
+
+	var key CryptoKey
+
+	if err := r.do(c, http.MethodGet, name, nil, &key); err != nil {
+		return nil, err
+	}
+
+	return &key, nil
+
+}
+
+// GetCryptoKeyVersion implements the `Client` interface.
+func (r *RESTClient) GetCryptoKeyVersion(c context.Context, name string) (*CryptoKeyVersion, error) {
+
+	var version CryptoKeyVersion
+
+	if err := r.do(c, http.MethodGet, name, nil, &version); err != nil {
+		return nil, err
+	}
+
+	return &version, nil
+
+}
+
+// ListCryptoKeys implements the `Client` interface.
+func (r *RESTClient) ListCryptoKeys(c context.Context, keyRing string) ([]CryptoKey, error) {
+
+	keys := []CryptoKey{}
+	token := ""
+
+	for {
+
+		var page struct {
+			CryptoKeys    []CryptoKey `json:"cryptoKeys"`
+			NextPageToken string      `json:"nextPageToken"`
+		}
+
+		if err := r.do(c, http.MethodGet, keyRing+"/cryptoKeys"+pageQuery(token), nil, &page); err != nil {
+			return nil, err
+		}
+
+		keys = append(keys, page.CryptoKeys...)
+
+		if token = page.NextPageToken; token == "" {
+			return keys, nil
+		}
+
+	}
+
+}
+
+// ListCryptoKeyVersions implements the `Client` interface.
+func (r *RESTClient) ListCryptoKeyVersions(c context.Context, cryptoKey string) ([]CryptoKeyVersion, error) {
+
+	versions := []CryptoKeyVersion{}
+	token := ""
+
+	for {
+
+		var page struct {
+			CryptoKeyVersions []CryptoKeyVersion `json:"cryptoKeyVersions"`
+			NextPageToken     string             `json:"nextPageToken"`
+		}
+
+		if err := r.do(c, http.MethodGet, cryptoKey+"/cryptoKeyVersions"+pageQuery(token), nil, &page); err != nil {
+			return nil, err
+		}
+
+		versions = append(versions, page.CryptoKeyVersions...)
+
+		if token = page.NextPageToken; token == "" {
+			return versions, nil
+		}
+
+	}
+
+}
+
+// GetPublicKey implements the `Client` interface.
+func (r *RESTClient) GetPublicKey(c context.Context, version string) ([]byte, error) {
+
+	var resp struct {
+		PEM string `json:"pem"`
+	}
+
+	if err := r.do(c, http.MethodGet, version+"/publicKey", nil, &resp); err != nil {
+		return nil, err
+	}
+
+	return []byte(resp.PEM), nil
+
+}
+
+// AsymmetricSign implements the `Client` interface.
+func (r *RESTClient) AsymmetricSign(c context.Context, version string, hash crypto.Hash, digest []byte) ([]byte, error) {
+
+	var field string
+
+	switch hash {
+	case crypto.SHA256:
+		field = "sha256"
+	case crypto.SHA384:
+		field = "sha384"
+	case crypto.SHA512:
+		field = "sha512"
+	default:
+		return nil, fmt.Errorf("Cloud KMS do not support hash: %s", hash)
+	}
+
+	req := map[string]interface{}{"digest": map[string][]byte{field: digest}}
+
+	var resp struct {
+		Signature []byte `json:"signature"`
+	}
+
+	if err := r.do(c, http.MethodPost, version+":asymmetricSign", req, &resp); err != nil {
+		return nil, err
+	}
+
+	return resp.Signature, nil
+
+}
+
+// Encrypt implements the `Client` interface.
+func (r *RESTClient) Encrypt(c context.Context, cryptoKey string, plaintext, aad []byte) ([]byte, string, error) {
+
+	req := map[string][]byte{"plaintext": plaintext, "additionalAuthenticatedData": aad}
+
+	var resp struct {
+		Name       string `json:"name"`
+		Ciphertext []byte `json:"ciphertext"`
+	}
+
+	if err := r.do(c, http.MethodPost, cryptoKey+":encrypt", req, &resp); err != nil {
+		return nil, "", err
+	}
+
+	return resp.Ciphertext, resp.Name, nil
+
+}
+
+// Decrypt implements the `Client` interface.
+func (r *RESTClient) Decrypt(c context.Context, cryptoKey string, ciphertext, aad []byte) ([]byte, error) {
+
+	req := map[string][]byte{"ciphertext": ciphertext, "additionalAuthenticatedData": aad}
+
+	var resp struct {
+		Plaintext []byte `json:"plaintext"`
+	}
+
+	if err := r.do(c, http.MethodPost, cryptoKey+":decrypt", req, &resp); err != nil {
+		return nil, err
+	}
+
+	return resp.Plaintext, nil
+
+}
+
+// do sends a request with the _JSON_ _body_, if any, to _path_ and decodes the _JSON_
+// response into _out_. A _404_ is returned as `ifcrypto.ErrKeyNotFound`.
+func (r *RESTClient) do(c context.Context, method, path string, body, out interface{}) error {
+
+	var reader io.Reader
+
+	if body != nil {
+
+		data, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+
+		reader = bytes.NewReader(data)
+
+	}
+
+	req, err := http.NewRequestWithContext(c, method, r.endpoint+path, reader)
+	if err != nil {
+		return err
+	}
+
+	token, err := r.tokens(c)
+	if err != nil {
+		return err
+	}
+
+	req.Header.Set("Authorization", "Bearer "+token)
+
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := r.client.Do(req)
+	if err != nil {
+		return err
+	}
+
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+
+	switch {
+	case resp.StatusCode == http.StatusNotFound:
+		return fmt.Errorf("%w: %s", ifcrypto.ErrKeyNotFound, path)
+	case resp.StatusCode != http.StatusOK:
+		return fmt.Errorf("Cloud KMS %s %s returned %s: %s", method, path, resp.Status, strings.TrimSpace(string(data)))
+	}
+
+	return json.Unmarshal(data, out)
+
+}
+
+// pageQuery returns the query of the page with _token_, if any.
+func pageQuery(token string) string {
+
+	if token == "" {
+		return ""
+	}
+
+	return "?pageToken=" + url.QueryEscape(token)
+
+}
@@ -0,0 +1,46 @@
+package gcpkms
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/mariotoffia/goservice/managers/go/gocrypto"
+)
+
+func init() {
+	gocrypto.RegisterComponent(gocrypto.ComponentKeyStore, "gcp-kms", cloudKmsKeyStoreFactory)
+}
+
+// cloudKmsKeyStoreFactory creates a `CloudKmsKeyStore` of a _key_ring_, authenticated by the
+// service account attached to the instance.
+func cloudKmsKeyStoreFactory(a *gocrypto.Assembly, cfg gocrypto.ComponentConfig) (interface{}, error) {
+
+	var options struct {
+		KeyRing  string            `json:"key_ring"`
+		Endpoint string            `json:"endpoint"`
+		Labels   map[string]string `json:"labels"`
+	}
+
+	if err := cfg.Decode(&options); err != nil {
+		return nil, err
+	}
+
+	if options.KeyRing == "" {
+		return nil, fmt.Errorf("key_ring is required")
+	}
+
+	clientOpts := []RESTClientOption{}
+	if options.Endpoint != "" {
+		clientOpts = append(clientOpts, WithEndpoint(options.Endpoint))
+	}
+
+	opts := []CloudKmsKeyStoreOption{}
+	for k, v := range options.Labels {
+		opts = append(opts, WithCloudKmsLabel(k, v))
+	}
+
+	client := NewRESTClient(MetadataTokenSource(http.DefaultClient), clientOpts...)
+
+	return NewCloudKmsKeyStore(client, options.KeyRing, opts...), nil
+
+}
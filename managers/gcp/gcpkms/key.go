@@ -0,0 +1,313 @@
+package gcpkms
+
+import (
+	"bytes"
+	"context"
+	"crypto"
+	"crypto/rsa"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/mariotoffia/goservice/interfaces/ifcrypto"
+	"github.com/mariotoffia/goservice/managers/go/gocrypto"
+)
+
+// cloudKmsAlgorithm is the key type, size, signing hash and padding of a _Cloud KMS_ algorithm.
+type cloudKmsAlgorithm struct {
+	keyType ifcrypto.KeyType
+	keySize int
+	hash    crypto.Hash
+	pss     bool
+}
+
+// cloudKmsAlgorithms are the supported _Cloud KMS_ algorithms.
+var cloudKmsAlgorithms = map[string]cloudKmsAlgorithm{
+	"GOOGLE_SYMMETRIC_ENCRYPTION": {ifcrypto.KeyTypeSymmetric, 256, 0, false},
+	"EC_SIGN_P256_SHA256":         {ifcrypto.KeyTypeEccNistP, 256, crypto.SHA256, false},
+	"EC_SIGN_P384_SHA384":         {ifcrypto.KeyTypeEccNistP, 384, crypto.SHA384, false},
+	"EC_SIGN_SECP256K1_SHA256":    {ifcrypto.KeyTypeEccSecgP256k1, 256, crypto.SHA256, false},
+	"RSA_SIGN_PSS_2048_SHA256":    {ifcrypto.KeyTypeRsa, 2048, crypto.SHA256, true},
+	"RSA_SIGN_PSS_3072_SHA256":    {ifcrypto.KeyTypeRsa, 3072, crypto.SHA256, true},
+	"RSA_SIGN_PSS_4096_SHA256":    {ifcrypto.KeyTypeRsa, 4096, crypto.SHA256, true},
+	"RSA_SIGN_PSS_4096_SHA512":    {ifcrypto.KeyTypeRsa, 4096, crypto.SHA512, true},
+	"RSA_SIGN_PKCS1_2048_SHA256":  {ifcrypto.KeyTypeRsa, 2048, crypto.SHA256, false},
+	"RSA_SIGN_PKCS1_3072_SHA256":  {ifcrypto.KeyTypeRsa, 3072, crypto.SHA256, false},
+	"RSA_SIGN_PKCS1_4096_SHA256":  {ifcrypto.KeyTypeRsa, 4096, crypto.SHA256, false},
+	"RSA_SIGN_PKCS1_4096_SHA512":  {ifcrypto.KeyTypeRsa, 4096, crypto.SHA512, false},
+}
+
+// CloudKmsKey implements the `ifcrypto.KeyPair` interface for a _Cloud KMS_ key. The private
+// key never leaves _Cloud KMS_, hence `IsRemoteKey` is always `true`.
+//
+// A _ASYMMETRIC_SIGN_ key is bound to a single key version, the `GetID`, and implements
+// `crypto.Signer` where each signature is a _Cloud KMS_ request. Since _Cloud KMS_ has no
+// verify operation, `Verify` uses the `GetPublic` key in process.
+//
+// A _ENCRYPT_DECRYPT_ key has the crypto key name as `GetID` since _Cloud KMS_ encrypts with
+// the primary version and decrypts with the version that encrypted, see `EncryptContext`.
+type CloudKmsKey struct {
+	// Derive from `gocrypto.KeyBase`
+	gocrypto.KeyBase
+	client    Client
+	algorithm cloudKmsAlgorithm
+	public    ifcrypto.PublicKey
+}
+
+// NewCloudKmsKey resolves the _name_ of a crypto key, or crypto key version, and creates a new
+// `CloudKmsKey` of it, see `ResolveKeyVersion`.
+func NewCloudKmsKey(c context.Context, client Client, name string) (*CloudKmsKey, error) {
+
+	version, err := ResolveKeyVersion(c, client, name)
+	if err != nil {
+		return nil, err
+	}
+
+	algorithm, ok := cloudKmsAlgorithms[version.Algorithm]
+	if !ok {
+		return nil, fmt.Errorf("unsupported Cloud KMS algorithm: %s", version.Algorithm)
+	}
+
+	if algorithm.keyType == ifcrypto.KeyTypeSymmetric {
+
+		return &CloudKmsKey{
+			KeyBase: gocrypto.NewKeyBase(
+				cryptoKeyName(version.Name), algorithm.keyType, algorithm.keySize,
+				ifcrypto.KeyUsageEncrypt, ifcrypto.KeyUsageDecrypt,
+			),
+			client:    client,
+			algorithm: algorithm,
+		}, nil
+
+	}
+
+	data, err := client.GetPublicKey(c, version.Name)
+	if err != nil {
+		return nil, err
+	}
+
+	loaded, err := gocrypto.LoadKey(bytes.NewReader(data), version.Name, ifcrypto.KeyUsageVerify)
+	if err != nil {
+		return nil, err
+	}
+
+	public, ok := loaded.(ifcrypto.PublicKey)
+	if !ok {
+		return nil, fmt.Errorf("Cloud KMS key %s has no public key", version.Name)
+	}
+
+	return &CloudKmsKey{
+		KeyBase: gocrypto.NewKeyBase(
+			version.Name, algorithm.keyType, algorithm.keySize,
+			ifcrypto.KeyUsageSign, ifcrypto.KeyUsageVerify,
+		),
+		client:    client,
+		algorithm: algorithm,
+		public:    public,
+	}, nil
+
+}
+
+// ResolveKeyVersion resolves _name_ to a key version.
+//
+// A crypto key version name resolves to itself. A crypto key resolves to its primary version,
+// or for asymmetric keys that has no primary, the latest enabled version.
+func ResolveKeyVersion(c context.Context, client Client, name string) (*CryptoKeyVersion, error) {
+
+	if strings.Contains(name, "/cryptoKeyVersions/") {
+		return client.GetCryptoKeyVersion(c, name)
+	}
+
+	key, err := client.GetCryptoKey(c, name)
+	if err != nil {
+		return nil, err
+	}
+
+	if key.Primary != nil {
+		return key.Primary, nil
+	}
+
+	versions, err := client.ListCryptoKeyVersions(c, name)
+	if err != nil {
+		return nil, err
+	}
+
+	var (
+		latest *CryptoKeyVersion
+		number int
+	)
+
+	for i := range versions {
+
+		if versions[i].State != CryptoKeyVersionStateEnabled {
+			continue
+		}
+
+		n, err := strconv.Atoi(versions[i].Name[strings.LastIndex(versions[i].Name, "/")+1:])
+		if err == nil && n > number {
+			latest, number = &versions[i], n
+		}
+
+	}
+
+	if latest == nil {
+		return nil, fmt.Errorf("Cloud KMS key %s has no enabled version", name)
+	}
+
+	return latest, nil
+
+}
+
+// GetPublic returns the public key, or `nil` if symmetric.
+func (k *CloudKmsKey) GetPublic() ifcrypto.PublicKey {
+	return k.public
+}
+
+// GetKey returns the key version, or crypto key, name since the key material never leaves
+// _Cloud KMS_.
+func (k *CloudKmsKey) GetKey() interface{} {
+	return k.GetID()
+}
+
+// IsSymmetric returns `true` if this is a _ENCRYPT_DECRYPT_ key.
+func (k *CloudKmsKey) IsSymmetric() bool {
+	return k.GetKeyType() == ifcrypto.KeyTypeSymmetric
+}
+
+// IsPrivate returns `true` since a _Cloud KMS_ key always holds the private key.
+func (k *CloudKmsKey) IsPrivate() bool {
+	return true
+}
+
+// IsRemoteKey returns `true`.
+func (k *CloudKmsKey) IsRemoteKey() bool {
+	return true
+}
+
+// Public implements the `crypto.Signer` _interface_.
+func (k *CloudKmsKey) Public() crypto.PublicKey {
+
+	if k.public == nil {
+		return nil
+	}
+
+	return k.public.GetKey()
+
+}
+
+// Sign implements the `crypto.Signer` _interface_, see `SignContext`. The _rand_ is not used.
+func (k *CloudKmsKey) Sign(rand io.Reader, digest []byte, opts crypto.SignerOpts) ([]byte, error) {
+	return k.SignContext(context.Background(), digest, opts)
+}
+
+// SignContext signs the _digest_ in _Cloud KMS_. The hash of _opts_ must be the hash of the
+// key version algorithm and, for _RSA_ keys, _opts_ must be a `*rsa.PSSOptions` if and only if
+// the algorithm is _PSS_.
+func (k *CloudKmsKey) SignContext(c context.Context, digest []byte, opts crypto.SignerOpts) ([]byte, error) {
+
+	if gocrypto.IsVerifyOnly() {
+		return nil, gocrypto.ErrVerifyOnly
+	}
+
+	if err := k.checkOpts(digest, opts); err != nil {
+		return nil, err
+	}
+
+	if err := gocrypto.CheckAlgorithm(ifcrypto.PolicyOperationSign, k, opts.HashFunc()); err != nil {
+		return nil, err
+	}
+
+	return k.client.AsymmetricSign(c, k.GetID(), k.algorithm.hash, digest)
+
+}
+
+// SignMessage implements the `ifcrypto.MessageSigner` interface, see `gocrypto.SignMessage`.
+func (k *CloudKmsKey) SignMessage(msg []byte, hash crypto.Hash) ([]byte, error) {
+	return gocrypto.SignMessage(k, msg, hash)
+}
+
+// Verify implements the `ifcrypto.SignatureVerifier` interface using the public key.
+func (k *CloudKmsKey) Verify(digest, signature []byte, opts crypto.SignerOpts) error {
+
+	if err := k.checkOpts(digest, opts); err != nil {
+		return err
+	}
+
+	verifier, ok := k.public.(ifcrypto.SignatureVerifier)
+	if !ok {
+		return fmt.Errorf("Cloud KMS key %s can not verify", k.GetID())
+	}
+
+	return verifier.Verify(digest, signature, opts)
+
+}
+
+// EncryptContext encrypts the _plaintext_ with the primary version of the key, binding the
+// _aad_. It returns the ciphertext and the name of the key version that encrypted.
+func (k *CloudKmsKey) EncryptContext(c context.Context, plaintext, aad []byte) ([]byte, string, error) {
+
+	if !k.IsSymmetric() {
+		return nil, "", fmt.Errorf("Cloud KMS key %s is not a encryption key", k.GetID())
+	}
+
+	return k.client.Encrypt(c, k.GetID(), plaintext, aad)
+
+}
+
+// DecryptContext decrypts the _ciphertext_ from `EncryptContext` with the same _aad_.
+func (k *CloudKmsKey) DecryptContext(c context.Context, ciphertext, aad []byte) ([]byte, error) {
+
+	if gocrypto.IsVerifyOnly() {
+		return nil, gocrypto.ErrVerifyOnly
+	}
+
+	if !k.IsSymmetric() {
+		return nil, fmt.Errorf("Cloud KMS key %s is not a encryption key", k.GetID())
+	}
+
+	if err := gocrypto.CheckAlgorithm(ifcrypto.PolicyOperationDecrypt, k, 0); err != nil {
+		return nil, err
+	}
+
+	return k.client.Decrypt(c, k.GetID(), ciphertext, aad)
+
+}
+
+// checkOpts checks that the _digest_ and _opts_ matches the key version algorithm.
+func (k *CloudKmsKey) checkOpts(digest []byte, opts crypto.SignerOpts) error {
+
+	if k.IsSymmetric() {
+		return fmt.Errorf("Cloud KMS key %s is not a signing key", k.GetID())
+	}
+
+	if opts == nil || opts.HashFunc() != k.algorithm.hash {
+		return fmt.Errorf("Cloud KMS key %s requires hash %s", k.GetID(), k.algorithm.hash)
+	}
+
+	if len(digest) != k.algorithm.hash.Size() {
+		return fmt.Errorf("digest is %d bytes, %s requires %d", len(digest), k.algorithm.hash, k.algorithm.hash.Size())
+	}
+
+	if k.GetKeyType() == ifcrypto.KeyTypeRsa {
+
+		if _, pss := opts.(*rsa.PSSOptions); pss != k.algorithm.pss {
+			return fmt.Errorf("Cloud KMS key %s do not match the RSA padding of opts", k.GetID())
+		}
+
+	}
+
+	return nil
+
+}
+
+// cryptoKeyName returns the crypto key name of a crypto key version _name_.
+func cryptoKeyName(name string) string {
+
+	if i := strings.Index(name, "/cryptoKeyVersions/"); i >= 0 {
+		return name[:i]
+	}
+
+	return name
+
+}
@@ -0,0 +1,316 @@
+//go:build !verifyonly
+// +build !verifyonly
+
+package gcpkms
+
+import (
+	"context"
+	"crypto"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/mariotoffia/goservice/interfaces/ifcrypto"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const keyRing = "projects/p/locations/europe-west1/keyRings/ring"
+
+// fakeCloudKms serves the subset of the _Cloud KMS_ _REST_ _API_ used by the `RESTClient`.
+type fakeCloudKms struct {
+	keys     map[string]CryptoKey
+	versions map[string][]CryptoKeyVersion
+	signers  map[string]crypto.Signer
+	aead     cipher.AEAD
+}
+
+func newFakeCloudKms(t *testing.T) (*fakeCloudKms, *RESTClient) {
+
+	ec, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	ec2, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+
+	block, err := aes.NewCipher(make([]byte, 32))
+	require.NoError(t, err)
+
+	aead, err := cipher.NewGCM(block)
+	require.NoError(t, err)
+
+	kek := CryptoKeyVersion{Name: keyRing + "/cryptoKeys/kek/cryptoKeyVersions/1", State: "ENABLED", Algorithm: "GOOGLE_SYMMETRIC_ENCRYPTION"}
+
+	f := &fakeCloudKms{
+		keys: map[string]CryptoKey{
+			keyRing + "/cryptoKeys/ec":  {Name: keyRing + "/cryptoKeys/ec", Purpose: PurposeAsymmetricSign, Labels: map[string]string{"team": "payments"}},
+			keyRing + "/cryptoKeys/rsa": {Name: keyRing + "/cryptoKeys/rsa", Purpose: PurposeAsymmetricSign},
+			keyRing + "/cryptoKeys/kek": {Name: keyRing + "/cryptoKeys/kek", Purpose: PurposeEncryptDecrypt, Primary: &kek, Labels: map[string]string{"team": "payments"}},
+		},
+		versions: map[string][]CryptoKeyVersion{
+			keyRing + "/cryptoKeys/ec": {
+				{Name: keyRing + "/cryptoKeys/ec/cryptoKeyVersions/1", State: "ENABLED", Algorithm: "EC_SIGN_P256_SHA256"},
+				{Name: keyRing + "/cryptoKeys/ec/cryptoKeyVersions/2", State: "ENABLED", Algorithm: "EC_SIGN_P256_SHA256"},
+				{Name: keyRing + "/cryptoKeys/ec/cryptoKeyVersions/3", State: "DISABLED", Algorithm: "EC_SIGN_P256_SHA256"},
+			},
+			keyRing + "/cryptoKeys/rsa": {
+				{Name: keyRing + "/cryptoKeys/rsa/cryptoKeyVersions/1", State: "ENABLED", Algorithm: "RSA_SIGN_PSS_2048_SHA256"},
+			},
+			keyRing + "/cryptoKeys/kek": {kek},
+		},
+		signers: map[string]crypto.Signer{
+			keyRing + "/cryptoKeys/ec/cryptoKeyVersions/1":  ec,
+			keyRing + "/cryptoKeys/ec/cryptoKeyVersions/2":  ec2,
+			keyRing + "/cryptoKeys/rsa/cryptoKeyVersions/1": rsaKey,
+		},
+		aead: aead,
+	}
+
+	server := httptest.NewServer(f)
+	t.Cleanup(server.Close)
+
+	client := NewRESTClient(
+		func(c context.Context) (string, error) { return "token", nil },
+		WithEndpoint(server.URL+"/v1"),
+		WithHTTPClient(server.Client()),
+	)
+
+	return f, client
+
+}
+
+func (f *fakeCloudKms) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+
+	if r.Header.Get("Authorization") != "Bearer token" {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	path := strings.TrimPrefix(r.URL.Path, "/v1/")
+	action := ""
+
+	if i := strings.LastIndex(path, ":"); i >= 0 {
+		path, action = path[:i], path[i+1:]
+	}
+
+	var body map[string]json.RawMessage
+
+	if r.Method == http.MethodPost {
+		_ = json.NewDecoder(r.Body).Decode(&body)
+	}
+
+	bytesField := func(name string) []byte {
+
+		var b []byte
+
+		_ = json.Unmarshal(body[name], &b)
+
+		return b
+
+	}
+
+	reply := func(v interface{}) {
+		_ = json.NewEncoder(w).Encode(v)
+	}
+
+	switch {
+	case action == "asymmetricSign":
+
+		var digest map[string][]byte
+
+		_ = json.Unmarshal(body["digest"], &digest)
+
+		var opts crypto.SignerOpts = crypto.SHA256
+
+		if _, ok := f.signers[path].(*rsa.PrivateKey); ok {
+			opts = &rsa.PSSOptions{SaltLength: rsa.PSSSaltLengthEqualsHash, Hash: crypto.SHA256}
+		}
+
+		signature, _ := f.signers[path].Sign(rand.Reader, digest["sha256"], opts)
+		reply(map[string][]byte{"signature": signature})
+
+	case action == "encrypt":
+
+		nonce := make([]byte, f.aead.NonceSize())
+		_, _ = rand.Read(nonce)
+
+		reply(map[string]interface{}{
+			"name":       f.keys[path].Primary.Name,
+			"ciphertext": f.aead.Seal(nonce, nonce, bytesField("plaintext"), bytesField("additionalAuthenticatedData")),
+		})
+
+	case action == "decrypt":
+
+		ciphertext := bytesField("ciphertext")
+		size := f.aead.NonceSize()
+
+		plaintext, err := f.aead.Open(nil, ciphertext[:size], ciphertext[size:], bytesField("additionalAuthenticatedData"))
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		reply(map[string][]byte{"plaintext": plaintext})
+
+	case strings.HasSuffix(path, "/publicKey"):
+
+		der, _ := x509.MarshalPKIXPublicKey(f.signers[strings.TrimSuffix(path, "/publicKey")].Public())
+		reply(map[string]string{"pem": string(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}))})
+
+	case strings.HasSuffix(path, "/cryptoKeyVersions"):
+		reply(map[string]interface{}{"cryptoKeyVersions": f.versions[strings.TrimSuffix(path, "/cryptoKeyVersions")]})
+
+	case strings.HasSuffix(path, "/cryptoKeys"):
+
+		keys := []CryptoKey{}
+		for _, key := range f.keys {
+			keys = append(keys, key)
+		}
+
+		reply(map[string]interface{}{"cryptoKeys": keys})
+
+	case strings.Contains(path, "/cryptoKeyVersions/"):
+
+		for _, version := range f.versions[cryptoKeyName(path)] {
+
+			if version.Name == path {
+				reply(version)
+				return
+			}
+
+		}
+
+		w.WriteHeader(http.StatusNotFound)
+
+	default:
+
+		key, ok := f.keys[path]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+
+		reply(key)
+
+	}
+
+}
+
+func TestCloudKmsKeyResolvesLatestVersionAndSigns(t *testing.T) {
+
+	f, client := newFakeCloudKms(t)
+	digest := sha256.Sum256([]byte("hello world"))
+
+	key, err := NewCloudKmsKey(context.Background(), client, keyRing+"/cryptoKeys/ec")
+	require.NoError(t, err)
+
+	assert.Equal(t, keyRing+"/cryptoKeys/ec/cryptoKeyVersions/2", key.GetID())
+	assert.True(t, key.IsRemoteKey())
+	assert.Equal(t, ifcrypto.KeyTypeEccNistP, key.GetKeyType())
+
+	signature, err := key.Sign(rand.Reader, digest[:], crypto.SHA256)
+	require.NoError(t, err)
+
+	assert.NoError(t, key.Verify(digest[:], signature, crypto.SHA256))
+	assert.True(t, ecdsa.VerifyASN1(f.signers[key.GetID()].Public().(*ecdsa.PublicKey), digest[:], signature))
+
+	signature[len(signature)-1] ^= 0x01
+	assert.True(t, errors.Is(key.Verify(digest[:], signature, crypto.SHA256), ifcrypto.ErrInvalidSignature))
+
+	pinned, err := NewCloudKmsKey(context.Background(), client, keyRing+"/cryptoKeys/ec/cryptoKeyVersions/1")
+	require.NoError(t, err)
+	assert.Equal(t, keyRing+"/cryptoKeys/ec/cryptoKeyVersions/1", pinned.GetID())
+
+	_, err = NewCloudKmsKey(context.Background(), client, keyRing+"/cryptoKeys/missing")
+	assert.True(t, errors.Is(err, ifcrypto.ErrKeyNotFound))
+
+}
+
+func TestCloudKmsKeyEnforcesAlgorithm(t *testing.T) {
+
+	_, client := newFakeCloudKms(t)
+	digest := sha256.Sum256([]byte("hello world"))
+
+	key, err := NewCloudKmsKey(context.Background(), client, keyRing+"/cryptoKeys/rsa")
+	require.NoError(t, err)
+
+	pss := &rsa.PSSOptions{SaltLength: rsa.PSSSaltLengthEqualsHash, Hash: crypto.SHA256}
+
+	signature, err := key.Sign(rand.Reader, digest[:], pss)
+	require.NoError(t, err)
+	assert.NoError(t, key.Verify(digest[:], signature, pss))
+
+	_, err = key.Sign(rand.Reader, digest[:], crypto.SHA256)
+	assert.Error(t, err, "PKCS #1 v1.5 with a PSS key")
+
+	_, err = key.Sign(rand.Reader, make([]byte, 48), crypto.SHA384)
+	assert.Error(t, err)
+
+}
+
+func TestCloudKmsKeyEncryptsWithPrimary(t *testing.T) {
+
+	_, client := newFakeCloudKms(t)
+	c := context.Background()
+
+	key, err := NewCloudKmsKey(c, client, keyRing+"/cryptoKeys/kek")
+	require.NoError(t, err)
+	assert.True(t, key.IsSymmetric())
+	assert.Equal(t, keyRing+"/cryptoKeys/kek", key.GetID())
+
+	ciphertext, version, err := key.EncryptContext(c, []byte("secret"), []byte("tenant=acme"))
+	require.NoError(t, err)
+	assert.Equal(t, keyRing+"/cryptoKeys/kek/cryptoKeyVersions/1", version)
+
+	plaintext, err := key.DecryptContext(c, ciphertext, []byte("tenant=acme"))
+	require.NoError(t, err)
+	assert.Equal(t, []byte("secret"), plaintext)
+
+	_, err = key.DecryptContext(c, ciphertext, []byte("tenant=other"))
+	assert.Error(t, err)
+
+	_, err = key.Sign(rand.Reader, make([]byte, 32), crypto.SHA256)
+	assert.Error(t, err)
+
+}
+
+func TestCloudKmsKeyStoreListsByLabel(t *testing.T) {
+
+	_, client := newFakeCloudKms(t)
+	c := context.Background()
+
+	store := NewCloudKmsKeyStore(client, keyRing)
+
+	keys, err := store.List(c, ifcrypto.KeyFilter{Usages: []ifcrypto.KeyUsage{ifcrypto.KeyUsageSign}})
+	require.NoError(t, err)
+	require.Len(t, keys, 2)
+	assert.Equal(t, keyRing+"/cryptoKeys/ec/cryptoKeyVersions/2", keys[0].GetID())
+	assert.Equal(t, keyRing+"/cryptoKeys/rsa/cryptoKeyVersions/1", keys[1].GetID())
+
+	store = NewCloudKmsKeyStore(client, keyRing, WithCloudKmsLabel("team", "payments"))
+
+	keys, err = store.List(c, ifcrypto.KeyFilter{})
+	require.NoError(t, err)
+	require.Len(t, keys, 2)
+	assert.Equal(t, keyRing+"/cryptoKeys/ec/cryptoKeyVersions/2", keys[0].GetID())
+	assert.Equal(t, keyRing+"/cryptoKeys/kek", keys[1].GetID())
+
+	assert.True(t, errors.Is(store.Put(c, keys[0]), ErrCloudKmsReadOnly))
+	assert.True(t, errors.Is(store.Delete(c, keys[0].GetID()), ErrCloudKmsReadOnly))
+
+}
@@ -0,0 +1,145 @@
+package gcpkms
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/mariotoffia/goservice/interfaces/ifcrypto"
+)
+
+// ErrCloudKmsReadOnly is returned by `CloudKmsKeyStore.Put` and `CloudKmsKeyStore.Delete` since
+// _Cloud KMS_ keys are created and destroyed in _Cloud KMS_, not by the service.
+var ErrCloudKmsReadOnly = fmt.Errorf("Cloud KMS key store is read-only")
+
+// CloudKmsKeyStoreOption configures a `CloudKmsKeyStore`.
+type CloudKmsKeyStoreOption func(s *CloudKmsKeyStore)
+
+// WithCloudKmsLabel lists only the keys that has the label _key_ with _value_. When used
+// multiple times all labels must match.
+func WithCloudKmsLabel(key, value string) CloudKmsKeyStoreOption {
+	return func(s *CloudKmsKeyStore) {
+		s.labels[key] = value
+	}
+}
+
+// CloudKmsKeyStore implements the `ifcrypto.KeyStore` interface on top of a _Cloud KMS_ key
+// ring. Keys are resolved by crypto key, or crypto key version, name to a `CloudKmsKey` and
+// cached by the given name.
+//
+// .Example
+// [source,go]
+// ----
+// client := NewRESTClient(MetadataTokenSource(http.DefaultClient))
+// store := NewCloudKmsKeyStore(client, "projects/p/locations/europe-west1/keyRings/payments")
+//
+// key, err := store.Get(c, "projects/p/locations/europe-west1/keyRings/payments/cryptoKeys/signer")
+// ----
+type CloudKmsKeyStore struct {
+	client  Client
+	keyRing string
+	labels  map[string]string
+	mu      sync.Mutex
+	keys    map[string]*CloudKmsKey
+}
+
+// NewCloudKmsKeyStore creates a new `CloudKmsKeyStore` of the _keyRing_, i.e.
+// _projects/*/locations/*/keyRings/*_.
+func NewCloudKmsKeyStore(client Client, keyRing string, opts ...CloudKmsKeyStoreOption) *CloudKmsKeyStore {
+
+	s := &CloudKmsKeyStore{
+		client:  client,
+		keyRing: keyRing,
+		labels:  map[string]string{},
+		keys:    map[string]*CloudKmsKey{},
+	}
+
+	for _, opt := range opts {
+		opt(s)
+	}
+
+	return s
+
+}
+
+// Get implements the `ifcrypto.KeyStore` interface. The _id_ is a crypto key, that resolves to
+// its current version, or a crypto key version name.
+func (s *CloudKmsKeyStore) Get(c context.Context, id string) (ifcrypto.Key, error) {
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if key, ok := s.keys[id]; ok {
+		return key, nil
+	}
+
+	key, err := NewCloudKmsKey(c, s.client, id)
+	if err != nil {
+		return nil, err
+	}
+
+	s.keys[id] = key
+
+	return key, nil
+
+}
+
+// Put implements the `ifcrypto.KeyStore` interface, it always fails with `ErrCloudKmsReadOnly`.
+func (s *CloudKmsKeyStore) Put(c context.Context, key ifcrypto.Key) error {
+	return ErrCloudKmsReadOnly
+}
+
+// Delete implements the `ifcrypto.KeyStore` interface, it always fails with `ErrCloudKmsReadOnly`.
+func (s *CloudKmsKeyStore) Delete(c context.Context, ids ...string) error {
+	return ErrCloudKmsReadOnly
+}
+
+// List implements the `ifcrypto.KeyStore` interface. It lists the current version of the keys
+// in the key ring that has the configured labels and matches _filter_.
+func (s *CloudKmsKeyStore) List(c context.Context, filter ifcrypto.KeyFilter) ([]ifcrypto.Key, error) {
+
+	cryptoKeys, err := s.client.ListCryptoKeys(c, s.keyRing)
+	if err != nil {
+		return nil, err
+	}
+
+	keys := []ifcrypto.Key{}
+
+	for _, cryptoKey := range cryptoKeys {
+
+		if !s.hasLabels(cryptoKey) {
+			continue
+		}
+
+		key, err := s.Get(c, cryptoKey.Name)
+		if err != nil {
+			return nil, err
+		}
+
+		if filter.Match(key) {
+			keys = append(keys, key)
+		}
+
+	}
+
+	sort.Slice(keys, func(i, j int) bool { return keys[i].GetID() < keys[j].GetID() })
+
+	return keys, nil
+
+}
+
+// hasLabels returns `true` if _key_ has all configured labels.
+func (s *CloudKmsKeyStore) hasLabels(key CryptoKey) bool {
+
+	for k, v := range s.labels {
+
+		if key.Labels[k] != v {
+			return false
+		}
+
+	}
+
+	return true
+
+}
@@ -0,0 +1,232 @@
+// Package goconformance publishes golden test vectors for the byte level formats of this
+// module and a runner that checks them against the _Go_ implementation.
+//
+// The vectors are in _vectors.json_ and are meant to be consumed by implementations in other
+// languages as well. All binary values are hex encoded, tokens and envelopes are given in
+// their textual form.
+package goconformance
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ed25519"
+	_ "embed" // vectors.json
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/mariotoffia/goservice/managers/go/godsse"
+	"github.com/mariotoffia/goservice/utils/cryptoutils"
+)
+
+// Vector kinds.
+const (
+	// KindAESKeyWrap is _RFC 3394_ key wrap, _key_ is the _KEK_.
+	KindAESKeyWrap = "aes-key-wrap"
+	// KindAESKeyWrapPad is _RFC 5649_ key wrap with padding, _key_ is the _KEK_.
+	KindAESKeyWrapPad = "aes-key-wrap-pad"
+	// KindFernet is a _Fernet_ token, _params_ has the _time_ and _iv_ used to encode it.
+	KindFernet = "fernet"
+	// KindBranca is a _Branca_ token, _params_ has the _timestamp_ and _nonce_ used to encode it.
+	KindBranca = "branca"
+	// KindDSSEPAE is the _DSSE_ pre-authentication encoding, _params_ has the _payload_type_.
+	KindDSSEPAE = "dsse-pae"
+	// KindDSSEEd25519 is a _DSSE_ envelope signed with the _Ed25519_ seed in _key_.
+	KindDSSEEd25519 = "dsse-ed25519"
+)
+
+//go:embed vectors.json
+var vectorsJSON []byte
+
+// Vector is a single golden test vector.
+type Vector struct {
+	Name   string            `json:"name"`
+	Kind   string            `json:"kind"`
+	Key    string            `json:"key,omitempty"`
+	Input  string            `json:"input"`
+	Output string            `json:"output"`
+	Params map[string]string `json:"params,omitempty"`
+}
+
+// VectorFile is the document in _vectors.json_.
+type VectorFile struct {
+	Format  int      `json:"format"`
+	Vectors []Vector `json:"vectors"`
+}
+
+// Result is the outcome of checking a `Vector`.
+type Result struct {
+	Vector Vector
+	// Err is `nil` if the implementation conforms.
+	Err error
+}
+
+// Vectors returns the published vectors.
+func Vectors() ([]Vector, error) {
+
+	var file VectorFile
+
+	if err := json.Unmarshal(vectorsJSON, &file); err != nil {
+		return nil, err
+	}
+
+	return file.Vectors, nil
+
+}
+
+// VectorsJSON returns the raw _vectors.json_ document, e.g. to publish it.
+func VectorsJSON() []byte {
+	return append([]byte{}, vectorsJSON...)
+}
+
+// Run checks all published vectors and returns one result per vector.
+func Run() ([]Result, error) {
+
+	vectors, err := Vectors()
+	if err != nil {
+		return nil, err
+	}
+
+	results := make([]Result, len(vectors))
+
+	for i, v := range vectors {
+		results[i] = Result{Vector: v, Err: Check(v)}
+	}
+
+	return results, nil
+
+}
+
+// Check checks a single vector against the implementation in this module.
+func Check(v Vector) error {
+
+	key, input, err := decodeHex(v.Key, v.Input)
+	if err != nil {
+		return err
+	}
+
+	switch v.Kind {
+	case KindAESKeyWrap, KindAESKeyWrapPad:
+
+		wrap, unwrap := cryptoutils.AESKeyWrap, cryptoutils.AESKeyUnwrap
+		if v.Kind == KindAESKeyWrapPad {
+			wrap, unwrap = cryptoutils.AESKeyWrapWithPadding, cryptoutils.AESKeyUnwrapWithPadding
+		}
+
+		wrapped, err := wrap(key, input)
+		if err != nil {
+			return err
+		}
+
+		if hex.EncodeToString(wrapped) != v.Output {
+			return fmt.Errorf("wrapped key mismatch: %x", wrapped)
+		}
+
+		unwrapped, err := unwrap(key, wrapped)
+		if err != nil {
+			return err
+		}
+
+		return expectEqual(input, unwrapped)
+
+	case KindFernet:
+
+		f, err := cryptoutils.NewFernet(key)
+		if err != nil {
+			return err
+		}
+
+		plaintext, err := f.DecodeAt(v.Output, 0, time.Now())
+		if err != nil {
+			return err
+		}
+
+		return expectEqual(input, plaintext)
+
+	case KindBranca:
+
+		b, err := cryptoutils.NewBranca(key)
+		if err != nil {
+			return err
+		}
+
+		plaintext, err := b.DecodeAt(v.Output, 0, time.Now())
+		if err != nil {
+			return err
+		}
+
+		return expectEqual(input, plaintext)
+
+	case KindDSSEPAE:
+
+		return expectEqual(mustHex(v.Output), godsse.PAE(v.Params["payload_type"], input))
+
+	case KindDSSEEd25519:
+
+		if len(key) != ed25519.SeedSize {
+			return fmt.Errorf("invalid ed25519 seed size: %d", len(key))
+		}
+
+		signer := ed25519.NewKeyFromSeed(key)
+
+		envelope, err := godsse.Sign(v.Params["payload_type"], input, godsse.KeySigner{KeyID: v.Params["keyid"], Signer: signer})
+		if err != nil {
+			return err
+		}
+
+		data, err := envelope.Marshal()
+		if err != nil {
+			return err
+		}
+
+		if string(data) != v.Output {
+			return fmt.Errorf("envelope mismatch: %s", data)
+		}
+
+		_, err = envelope.Verify(map[string]crypto.PublicKey{v.Params["keyid"]: signer.Public()}, 1)
+
+		return err
+
+	}
+
+	return fmt.Errorf("unknown vector kind: %s", v.Kind)
+
+}
+
+// decodeHex decodes the _key_ and _input_.
+func decodeHex(key, input string) ([]byte, []byte, error) {
+
+	k, err := hex.DecodeString(key)
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid key: %v", err)
+	}
+
+	in, err := hex.DecodeString(input)
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid input: %v", err)
+	}
+
+	return k, in, nil
+
+}
+
+// mustHex decodes _s_, a invalid value gives `nil` which never matches.
+func mustHex(s string) []byte {
+
+	b, _ := hex.DecodeString(s)
+
+	return b
+
+}
+
+// expectEqual returns a error if _expected_ and _actual_ differs.
+func expectEqual(expected, actual []byte) error {
+
+	if !bytes.Equal(expected, actual) {
+		return fmt.Errorf("expected %x, got %x", expected, actual)
+	}
+
+	return nil
+
+}
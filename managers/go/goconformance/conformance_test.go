@@ -0,0 +1,32 @@
+package goconformance
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConformanceVectors(t *testing.T) {
+
+	results, err := Run()
+	require.NoError(t, err)
+	require.NotEmpty(t, results)
+
+	for _, result := range results {
+		assert.NoError(t, result.Err, result.Vector.Name)
+	}
+
+}
+
+func TestCheckDetectsMismatch(t *testing.T) {
+
+	vectors, err := Vectors()
+	require.NoError(t, err)
+
+	v := vectors[0]
+	v.Output = "00" + v.Output[2:]
+
+	assert.Error(t, Check(v))
+
+}
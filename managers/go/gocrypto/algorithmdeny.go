@@ -0,0 +1,290 @@
+package gocrypto
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/rsa"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mariotoffia/goservice/interfaces/ifcrypto"
+)
+
+// ErrAlgorithmDenied is returned by key operations that use a algorithm, curve or hash that is
+// denied by the `AlgorithmDenyList`.
+var ErrAlgorithmDenied = fmt.Errorf("algorithm is denied")
+
+var (
+	denyListMu sync.RWMutex
+	denyList   *AlgorithmDenyList
+)
+
+// SetAlgorithmDenyList sets the _list_ that is checked by all sign, verify, encrypt, decrypt
+// and key agreement operations of the keys in this package. A denied operation fails with
+// `ErrAlgorithmDenied`.
+//
+// By default nothing is denied. Set _list_ to `nil` to disable it again. The list may be
+// replaced at any time, e.g. when the configuration is reloaded, to ratchet down legacy
+// algorithms without code changes.
+func SetAlgorithmDenyList(list *AlgorithmDenyList) {
+
+	denyListMu.Lock()
+	defer denyListMu.Unlock()
+
+	denyList = list
+
+}
+
+// AlgorithmDenial is a denied attempt, emitted by the `AlgorithmDenyList`.
+type AlgorithmDenial struct {
+	Time      time.Time
+	Operation ifcrypto.PolicyOperation
+	KeyID     string
+	// Algorithm is the deny-list entry that denied the attempt, e.g. _SHA-1_.
+	Algorithm string
+}
+
+// AlgorithmDenyListOption configures a `AlgorithmDenyList`.
+type AlgorithmDenyListOption func(l *AlgorithmDenyList)
+
+// WithDeniedAlgorithm denies _name_ for _ops_, or for all operations if no _ops_.
+//
+// The _name_ is matched, case insensitive, against:
+//
+// * The algorithm: _RSA_, _ECDSA_, _Ed25519_, _X25519_, _HMAC_, _AES_ or _ChaCha20-Poly1305_.
+// * The key size or curve: _RSA-1024_, _P-224_, _P-256_, _secp256k1_, _AES-128_ etc.
+// * The hash: _SHA-1_, _SHA-224_, _SHA-256_ etc. as of `crypto.Hash.String`.
+//
+// For example `WithDeniedAlgorithm("SHA-1", ifcrypto.PolicyOperationVerify)` disables _SHA-1_
+// signature verification.
+func WithDeniedAlgorithm(name string, ops ...ifcrypto.PolicyOperation) AlgorithmDenyListOption {
+	return func(l *AlgorithmDenyList) {
+		l.entries[strings.ToUpper(name)] = append([]ifcrypto.PolicyOperation{}, ops...)
+	}
+}
+
+// WithAlgorithmDenialAuditor sets a function that receives all denied attempts.
+func WithAlgorithmDenialAuditor(auditor func(denial AlgorithmDenial)) AlgorithmDenyListOption {
+	return func(l *AlgorithmDenyList) {
+		l.auditor = auditor
+	}
+}
+
+// AlgorithmDenyList denies algorithms, key sizes, curves and hashes across all keys of this
+// package, see `SetAlgorithmDenyList`. It counts the denied attempts, see `Denied`.
+type AlgorithmDenyList struct {
+	entries map[string][]ifcrypto.PolicyOperation
+	auditor func(denial AlgorithmDenial)
+	mu      sync.Mutex
+	denied  map[string]uint64
+}
+
+// NewAlgorithmDenyList creates a new `AlgorithmDenyList`.
+func NewAlgorithmDenyList(opts ...AlgorithmDenyListOption) *AlgorithmDenyList {
+
+	l := &AlgorithmDenyList{
+		entries: map[string][]ifcrypto.PolicyOperation{},
+		auditor: func(AlgorithmDenial) {},
+		denied:  map[string]uint64{},
+	}
+
+	for _, opt := range opts {
+		opt(l)
+	}
+
+	return l
+
+}
+
+// Entries returns the denied names, upper cased and sorted.
+func (l *AlgorithmDenyList) Entries() []string {
+
+	names := make([]string, 0, len(l.entries))
+	for name := range l.entries {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	return names
+
+}
+
+// Check returns a error wrapping `ErrAlgorithmDenied` if _op_ with _key_ and _hash_ is denied.
+// The _hash_ is zero when not applicable, e.g. for _Ed25519_.
+func (l *AlgorithmDenyList) Check(op ifcrypto.PolicyOperation, key ifcrypto.Key, hash crypto.Hash) error {
+
+	algorithm, size := keyAlgorithmNames(key)
+
+	for _, name := range [...]string{algorithm, size, hashName(hash)} {
+
+		if name == "" {
+			continue
+		}
+
+		ops, ok := l.entries[strings.ToUpper(name)]
+		if !ok || !deniesOperation(ops, op) {
+			continue
+		}
+
+		l.mu.Lock()
+		l.denied[string(op)+" "+name]++
+		l.mu.Unlock()
+
+		l.auditor(AlgorithmDenial{Time: time.Now().UTC(), Operation: op, KeyID: key.GetID(), Algorithm: name})
+
+		return fmt.Errorf("%w: %s %s with %s", ErrAlgorithmDenied, op, key.GetID(), name)
+
+	}
+
+	return nil
+
+}
+
+// Denied returns the number of denied attempts keyed by operation and algorithm, e.g.
+// _verify SHA-1_, for export as metrics.
+func (l *AlgorithmDenyList) Denied() map[string]uint64 {
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	denied := make(map[string]uint64, len(l.denied))
+	for k, v := range l.denied {
+		denied[k] = v
+	}
+
+	return denied
+
+}
+
+// CheckAlgorithm checks _op_ with _key_ and _hash_ against the `SetAlgorithmDenyList` list, if
+// any. The _hash_ is zero when not applicable.
+//
+// The keys of this package checks all operations. Keys in other packages, e.g. remote _KMS_
+// keys, must call it before each sign, decrypt or key agreement request.
+func CheckAlgorithm(op ifcrypto.PolicyOperation, key ifcrypto.Key, hash crypto.Hash) error {
+
+	denyListMu.RLock()
+	list := denyList
+	denyListMu.RUnlock()
+
+	if list == nil {
+		return nil
+	}
+
+	return list.Check(op, key, hash)
+
+}
+
+// signerHash returns the hash of _opts_, or zero.
+func signerHash(opts crypto.SignerOpts) crypto.Hash {
+
+	if opts == nil {
+		return 0
+	}
+
+	return opts.HashFunc()
+
+}
+
+// decrypterHash returns the _OAEP_ hash of _opts_, or zero.
+func decrypterHash(opts crypto.DecrypterOpts) crypto.Hash {
+
+	if oaep, ok := opts.(*rsa.OAEPOptions); ok {
+		return oaep.Hash
+	}
+
+	return 0
+
+}
+
+// deniesOperation returns `true` if _ops_ is empty or contains _op_.
+func deniesOperation(ops []ifcrypto.PolicyOperation, op ifcrypto.PolicyOperation) bool {
+
+	if len(ops) == 0 {
+		return true
+	}
+
+	for _, o := range ops {
+
+		if o == op {
+			return true
+		}
+
+	}
+
+	return false
+
+}
+
+// hashName returns the name of _hash_, or a empty string if zero.
+func hashName(hash crypto.Hash) string {
+
+	if hash == 0 {
+		return ""
+	}
+
+	return hash.String()
+
+}
+
+// keyAlgorithmNames returns the algorithm and the key size or curve name of _key_.
+func keyAlgorithmNames(key ifcrypto.Key) (string, string) {
+
+	material := key.GetKey()
+
+	// Remote keys returns their id, the size or curve is in the public key.
+	if pair, ok := key.(ifcrypto.KeyPair); ok && key.IsRemoteKey() && pair.GetPublic() != nil {
+		material = pair.GetPublic().GetKey()
+	}
+
+	switch key.GetKeyType() {
+	case ifcrypto.KeyTypeRsa:
+
+		switch k := material.(type) {
+		case *rsa.PrivateKey:
+			return "RSA", "RSA-" + strconv.Itoa(k.N.BitLen())
+		case *rsa.PublicKey:
+			return "RSA", "RSA-" + strconv.Itoa(k.N.BitLen())
+		}
+
+		return "RSA", ""
+
+	case ifcrypto.KeyTypeEccNistP, ifcrypto.KeyTypeEccSecgP256k1:
+
+		switch k := material.(type) {
+		case *ecdsa.PrivateKey:
+			return "ECDSA", k.Params().Name
+		case *ecdsa.PublicKey:
+			return "ECDSA", k.Params().Name
+		}
+
+		return "ECDSA", ""
+
+	case ifcrypto.KeyTypeEd25519:
+		return "Ed25519", ""
+	case ifcrypto.KeyTypeX25519:
+		return "X25519", ""
+	case ifcrypto.KeyTypeHmac:
+		return "HMAC", ""
+	case ifcrypto.KeyTypeSymmetric:
+
+		if _, ok := key.(*ChaChaKey); ok {
+			return "ChaCha20-Poly1305", ""
+		}
+
+		if key.GetKeySize() == 0 {
+			return "AES", ""
+		}
+
+		return "AES", "AES-" + strconv.Itoa(key.GetKeySize())
+
+	}
+
+	return string(key.GetKeyType()), ""
+
+}
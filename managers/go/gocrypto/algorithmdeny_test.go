@@ -0,0 +1,130 @@
+//go:build !verifyonly
+// +build !verifyonly
+
+package gocrypto
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha1"
+	"crypto/sha256"
+	"errors"
+	"testing"
+
+	"github.com/mariotoffia/goservice/interfaces/ifcrypto"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAlgorithmDenyList(t *testing.T) {
+
+	rsaKey, err := NewRSAPrivateKey("legacy", 1024, ifcrypto.KeyUsageSign)
+	require.NoError(t, err)
+
+	ecKey, err := NewECDSAPrivateKey("ec", 256, ifcrypto.KeyUsageSign)
+	require.NoError(t, err)
+
+	sha1Digest := sha1.Sum([]byte("message"))
+	sha256Digest := sha256.Sum256([]byte("message"))
+
+	legacySignature, err := rsaKey.Sign(rand.Reader, sha1Digest[:], crypto.SHA1)
+	require.NoError(t, err)
+
+	var denials []AlgorithmDenial
+
+	list := NewAlgorithmDenyList(
+		WithDeniedAlgorithm("sha-1", ifcrypto.PolicyOperationVerify),
+		WithDeniedAlgorithm("RSA-1024", ifcrypto.PolicyOperationSign),
+		WithDeniedAlgorithm("P-224"),
+		WithAlgorithmDenialAuditor(func(denial AlgorithmDenial) { denials = append(denials, denial) }),
+	)
+
+	SetAlgorithmDenyList(list)
+	defer SetAlgorithmDenyList(nil)
+
+	assert.Equal(t, []string{"P-224", "RSA-1024", "SHA-1"}, list.Entries())
+
+	err = rsaKey.GetPublic().(ifcrypto.SignatureVerifier).Verify(sha1Digest[:], legacySignature, crypto.SHA1)
+	assert.True(t, errors.Is(err, ErrAlgorithmDenied))
+
+	_, err = rsaKey.Sign(rand.Reader, sha256Digest[:], crypto.SHA256)
+	assert.True(t, errors.Is(err, ErrAlgorithmDenied))
+
+	_, err = rsaKey.Decrypt(rand.Reader, []byte{0}, &rsa.OAEPOptions{Hash: crypto.SHA256})
+	assert.False(t, errors.Is(err, ErrAlgorithmDenied))
+
+	// SHA-1 is only denied for verify, and P-256 is not denied.
+	_, err = ecKey.Sign(rand.Reader, sha1Digest[:], crypto.SHA1)
+	require.NoError(t, err)
+
+	signature, err := ecKey.SignMessage([]byte("message"), crypto.SHA256)
+	require.NoError(t, err)
+	require.NoError(t, ecKey.GetPublic().(ifcrypto.MessageVerifier).VerifyMessage([]byte("message"), signature, crypto.SHA256))
+
+	assert.Equal(t, map[string]uint64{"verify SHA-1": 1, "sign RSA-1024": 1}, list.Denied())
+	require.Len(t, denials, 2)
+	assert.Equal(t, "legacy", denials[0].KeyID)
+	assert.Equal(t, ifcrypto.PolicyOperationVerify, denials[0].Operation)
+
+	SetAlgorithmDenyList(nil)
+
+	_, err = rsaKey.Sign(rand.Reader, sha256Digest[:], crypto.SHA256)
+	require.NoError(t, err)
+
+}
+
+func TestAlgorithmDenyListHMAC(t *testing.T) {
+
+	key, err := NewHMACKey("webhook", crypto.SHA256, ifcrypto.KeyUsageSign)
+	require.NoError(t, err)
+
+	mac, err := key.Sign(nil, []byte("payload"), nil)
+	require.NoError(t, err)
+
+	SetAlgorithmDenyList(NewAlgorithmDenyList(WithDeniedAlgorithm("HMAC", ifcrypto.PolicyOperationSign)))
+	defer SetAlgorithmDenyList(nil)
+
+	_, err = key.Sign(nil, []byte("payload"), nil)
+	assert.True(t, errors.Is(err, ErrAlgorithmDenied))
+	require.NoError(t, key.VerifyMAC([]byte("payload"), mac))
+
+}
+
+func TestAlgorithmDenyListAEAD(t *testing.T) {
+
+	aes, err := NewSymmetricKey("aes", 128, ifcrypto.KeyUsageEncrypt, ifcrypto.KeyUsageDecrypt)
+	require.NoError(t, err)
+
+	chacha, err := NewChaChaKey("chacha", ifcrypto.KeyUsageEncrypt, ifcrypto.KeyUsageDecrypt)
+	require.NoError(t, err)
+
+	sealed, err := aes.Encrypt(ifcrypto.ChiperAES128, []byte("message"), []byte("aad"))
+	require.NoError(t, err)
+
+	opened, err := aes.Decrypt(ifcrypto.ChiperAES128, sealed, []byte("aad"))
+	require.NoError(t, err)
+	assert.Equal(t, "message", string(opened))
+
+	_, err = aes.Encrypt(ifcrypto.ChiperAES256, []byte("message"), nil)
+	assert.Error(t, err, "chiper do not match the key size")
+
+	SetAlgorithmDenyList(NewAlgorithmDenyList(
+		WithDeniedAlgorithm("AES-128", ifcrypto.PolicyOperationEncrypt),
+		WithDeniedAlgorithm("ChaCha20-Poly1305", ifcrypto.PolicyOperationDecrypt),
+	))
+	defer SetAlgorithmDenyList(nil)
+
+	_, err = aes.Encrypt(ifcrypto.ChiperAES128, []byte("message"), nil)
+	assert.True(t, errors.Is(err, ErrAlgorithmDenied))
+
+	_, err = aes.Decrypt(ifcrypto.ChiperAES128, sealed, []byte("aad"))
+	assert.NoError(t, err, "only encryption is denied")
+
+	sealed, err = chacha.Encrypt(ifcrypto.ChiperXChaCha20Poly1305, []byte("message"), nil)
+	require.NoError(t, err)
+
+	_, err = chacha.Decrypt(ifcrypto.ChiperXChaCha20Poly1305, sealed, nil)
+	assert.True(t, errors.Is(err, ErrAlgorithmDenied))
+
+}
@@ -0,0 +1,151 @@
+package gocrypto
+
+import (
+	"context"
+	"crypto/cipher"
+	"runtime"
+	"sync"
+)
+
+// EncryptedRecord is the ciphertext of a record, as returned by `Shredder.Encrypt`.
+type EncryptedRecord struct {
+	ID         string
+	Ciphertext []byte
+}
+
+// DecryptResult is the outcome of a single record in `Shredder.DecryptBatch`.
+type DecryptResult struct {
+	ID        string
+	Plaintext []byte
+	// Err is the error of the record, e.g. `ErrDataShredded`, other records are not affected.
+	Err error
+}
+
+// BatchDecryptOption configures a `Shredder.DecryptBatch`.
+type BatchDecryptOption func(b *batchDecrypt)
+
+// WithDecryptWorkers sets the number of concurrent workers (default `runtime.GOMAXPROCS`).
+func WithDecryptWorkers(workers int) BatchDecryptOption {
+	return func(b *batchDecrypt) {
+		if workers > 0 {
+			b.workers = workers
+		}
+	}
+}
+
+// batchDecrypt is the state of a single `Shredder.DecryptBatch`.
+type batchDecrypt struct {
+	shredder *Shredder
+	workers  int
+	mu       sync.Mutex
+	keys     map[string]*batchKey
+}
+
+// batchKey is a data key that is unwrapped once per batch.
+type batchKey struct {
+	once sync.Once
+	aead cipher.AEAD
+	err  error
+}
+
+// DecryptBatch decrypts all _records_ concurrently on a bounded number of workers, e.g. for a
+// analytics job that decrypts millions of small records.
+//
+// The results are in the same order as the _records_. Each data key is fetched and unwrapped
+// once per batch, even if many records share it. A failing record does not stop the batch, its
+// error is set on its result. When _c_ is done, the remaining records fail with the context
+// error, which is also returned.
+func (s *Shredder) DecryptBatch(
+	c context.Context,
+	records []EncryptedRecord,
+	opts ...BatchDecryptOption,
+) ([]DecryptResult, error) {
+
+	b := &batchDecrypt{
+		shredder: s,
+		workers:  runtime.GOMAXPROCS(0),
+		keys:     map[string]*batchKey{},
+	}
+
+	for _, opt := range opts {
+		opt(b)
+	}
+
+	workers := b.workers
+	if workers > len(records) {
+		workers = len(records)
+	}
+
+	results := make([]DecryptResult, len(records))
+	jobs := make(chan int)
+
+	var wg sync.WaitGroup
+
+	wg.Add(workers)
+
+	for i := 0; i < workers; i++ {
+
+		go func() {
+
+			defer wg.Done()
+
+			for idx := range jobs {
+				results[idx] = b.decrypt(c, records[idx])
+			}
+
+		}()
+
+	}
+
+	for idx := range records {
+		jobs <- idx
+	}
+
+	close(jobs)
+	wg.Wait()
+
+	return results, c.Err()
+
+}
+
+// decrypt decrypts a single _record_.
+func (b *batchDecrypt) decrypt(c context.Context, record EncryptedRecord) DecryptResult {
+
+	result := DecryptResult{ID: record.ID}
+
+	if result.Err = c.Err(); result.Err != nil {
+		return result
+	}
+
+	aead, err := b.aead(c, record.ID)
+	if err != nil {
+		result.Err = err
+		return result
+	}
+
+	result.Plaintext, result.Err = shredderOpen(aead, record.ID, record.Ciphertext)
+
+	return result
+
+}
+
+// aead returns the cipher for the data key of _id_, unwrapping it on first use.
+func (b *batchDecrypt) aead(c context.Context, id string) (cipher.AEAD, error) {
+
+	b.mu.Lock()
+
+	key, ok := b.keys[id]
+	if !ok {
+		key = &batchKey{}
+		b.keys[id] = key
+	}
+
+	b.mu.Unlock()
+
+	key.once.Do(func() {
+		key.aead, key.err = b.shredder.aead(c, id, false)
+	})
+
+	return key.aead, key.err
+
+}
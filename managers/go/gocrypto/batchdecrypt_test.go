@@ -0,0 +1,90 @@
+package gocrypto
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// countingDataKeyStore counts the `Get` calls.
+type countingDataKeyStore struct {
+	*MemoryDataKeyStore
+	gets int32
+}
+
+func (s *countingDataKeyStore) Get(c context.Context, id string) ([]byte, error) {
+
+	atomic.AddInt32(&s.gets, 1)
+
+	return s.MemoryDataKeyStore.Get(c, id)
+
+}
+
+func TestShredderDecryptBatch(t *testing.T) {
+
+	store := &countingDataKeyStore{MemoryDataKeyStore: NewMemoryDataKeyStore()}
+
+	shredder, err := NewShredder(store, make([]byte, 32))
+	require.NoError(t, err)
+
+	c := context.Background()
+
+	var records []EncryptedRecord
+
+	for i := 0; i < 200; i++ {
+
+		id := fmt.Sprintf("user-%d", i%5)
+
+		ciphertext, err := shredder.Encrypt(c, id, []byte(fmt.Sprintf("record %d", i)))
+		require.NoError(t, err)
+
+		records = append(records, EncryptedRecord{ID: id, Ciphertext: ciphertext})
+
+	}
+
+	require.NoError(t, shredder.Shred(c, "erasure", "user-3"))
+
+	records[1].Ciphertext[len(records[1].Ciphertext)-1] ^= 0xff
+
+	atomic.StoreInt32(&store.gets, 0)
+
+	results, err := shredder.DecryptBatch(c, records, WithDecryptWorkers(8))
+	require.NoError(t, err)
+	require.Len(t, results, len(records))
+
+	// One lookup per data key, not per record.
+	assert.Equal(t, int32(5), atomic.LoadInt32(&store.gets))
+
+	for i, result := range results {
+
+		assert.Equal(t, records[i].ID, result.ID)
+
+		switch {
+		case i == 1:
+			assert.Error(t, result.Err)
+		case result.ID == "user-3":
+			assert.True(t, errors.Is(result.Err, ErrDataShredded))
+		default:
+			require.NoError(t, result.Err)
+			assert.Equal(t, fmt.Sprintf("record %d", i), string(result.Plaintext))
+		}
+
+	}
+
+	cancelled, cancel := context.WithCancel(c)
+	cancel()
+
+	results, err = shredder.DecryptBatch(cancelled, records)
+	assert.True(t, errors.Is(err, context.Canceled))
+	assert.True(t, errors.Is(results[0].Err, context.Canceled))
+
+	results, err = shredder.DecryptBatch(c, nil)
+	require.NoError(t, err)
+	assert.Empty(t, results)
+
+}
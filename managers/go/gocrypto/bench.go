@@ -0,0 +1,279 @@
+package gocrypto
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+)
+
+// BenchOperation is an operation measured by `Bench`.
+type BenchOperation string
+
+const (
+	// BenchSign measures signing of a digest.
+	BenchSign BenchOperation = "sign"
+	// BenchVerify measures verification of a signature, done locally on the public key.
+	BenchVerify BenchOperation = "verify"
+	// BenchEncrypt measures _RSA-OAEP_ encryption, done locally on the public key.
+	BenchEncrypt BenchOperation = "encrypt"
+	// BenchDecrypt measures _RSA-OAEP_ decryption, the key must be a `crypto.Decrypter`.
+	BenchDecrypt BenchOperation = "decrypt"
+)
+
+// BenchTarget is a key, or provider, to benchmark.
+type BenchTarget struct {
+	// Name identifies the target in the results, e.g. _kms-rsa-2048_.
+	Name string
+	// Signer is the key or provider.
+	Signer crypto.Signer
+	// Hash is the digest algorithm, default `crypto.SHA256`. Ignored for _Ed25519_.
+	Hash crypto.Hash
+}
+
+// BenchSpec describes a benchmark run.
+type BenchSpec struct {
+	// Targets is the keys to benchmark.
+	Targets []BenchTarget
+	// Operations is the operations to measure, default sign and verify.
+	Operations []BenchOperation
+	// Duration is how long each operation is measured per target, default one second.
+	Duration time.Duration
+	// Concurrency is the number of concurrent workers, default one.
+	Concurrency int
+	// PayloadSize is the size of the message that is hashed and signed or encrypted,
+	// default 32 bytes.
+	PayloadSize int
+}
+
+// BenchResult is the outcome of measuring one operation on one target.
+type BenchResult struct {
+	Target       string         `json:"target"`
+	Operation    BenchOperation `json:"operation"`
+	Concurrency  int            `json:"concurrency"`
+	Operations   int            `json:"operations"`
+	Errors       int            `json:"errors"`
+	Elapsed      time.Duration  `json:"elapsed"`
+	OpsPerSecond float64        `json:"ops_per_second"`
+	LatencyP50   time.Duration  `json:"latency_p50"`
+	LatencyP99   time.Duration  `json:"latency_p99"`
+	// Err is set when the operation is not supported by the target.
+	Err string `json:"error,omitempty"`
+}
+
+// Bench measures the throughput and latency of the operations in _spec_ for each target.
+//
+// The targets and operations are measured one at the time so that they do not affect each
+// other. Errors returned by the operations are counted, not returned, since a remote
+// provider may fail individual requests under load.
+func Bench(spec BenchSpec) ([]BenchResult, error) {
+
+	if len(spec.Targets) == 0 {
+		return nil, fmt.Errorf("no benchmark targets")
+	}
+
+	if len(spec.Operations) == 0 {
+		spec.Operations = []BenchOperation{BenchSign, BenchVerify}
+	}
+
+	if spec.Duration <= 0 {
+		spec.Duration = time.Second
+	}
+
+	if spec.Concurrency <= 0 {
+		spec.Concurrency = 1
+	}
+
+	if spec.PayloadSize <= 0 {
+		spec.PayloadSize = 32
+	}
+
+	payload := make([]byte, spec.PayloadSize)
+
+	if _, err := rand.Read(payload); err != nil {
+		return nil, err
+	}
+
+	results := []BenchResult{}
+
+	for _, target := range spec.Targets {
+
+		if target.Hash == 0 {
+			target.Hash = crypto.SHA256
+		}
+
+		for _, op := range spec.Operations {
+
+			result := BenchResult{Target: target.Name, Operation: op, Concurrency: spec.Concurrency}
+
+			fn, err := benchFunc(target, op, payload)
+			if err != nil {
+				result.Err = err.Error()
+			} else {
+				runBench(&result, fn, spec.Duration, spec.Concurrency)
+			}
+
+			results = append(results, result)
+
+		}
+
+	}
+
+	return results, nil
+
+}
+
+// runBench runs _fn_ on _concurrency_ workers for _duration_ and records the outcome.
+func runBench(result *BenchResult, fn func() error, duration time.Duration, concurrency int) {
+
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+
+	latencies := []time.Duration{}
+	start := time.Now()
+	deadline := start.Add(duration)
+
+	for w := 0; w < concurrency; w++ {
+
+		wg.Add(1)
+
+		go func() {
+
+			defer wg.Done()
+
+			local := []time.Duration{}
+			errors := 0
+
+			for time.Now().Before(deadline) {
+
+				opStart := time.Now()
+
+				if fn() != nil {
+					errors++
+				}
+
+				local = append(local, time.Since(opStart))
+
+			}
+
+			mu.Lock()
+			latencies = append(latencies, local...)
+			result.Errors += errors
+			mu.Unlock()
+
+		}()
+
+	}
+
+	wg.Wait()
+
+	result.Elapsed = time.Since(start)
+	result.Operations = len(latencies)
+	result.OpsPerSecond = float64(len(latencies)) / result.Elapsed.Seconds()
+
+	if len(latencies) > 0 {
+
+		sort.Slice(latencies, func(i, j int) bool { return latencies[i] < latencies[j] })
+
+		result.LatencyP50 = latencies[len(latencies)*50/100]
+		result.LatencyP99 = latencies[len(latencies)*99/100]
+
+	}
+
+}
+
+// benchFunc returns the function that performs a single _op_ on the _target_.
+func benchFunc(target BenchTarget, op BenchOperation, payload []byte) (func() error, error) {
+
+	pub := target.Signer.Public()
+	_, isEd25519 := pub.(ed25519.PublicKey)
+
+	message := payload
+	opts := crypto.SignerOpts(crypto.Hash(0))
+
+	if !isEd25519 {
+
+		h := target.Hash.New()
+		h.Write(payload)
+		message = h.Sum(nil)
+		opts = target.Hash
+
+	}
+
+	switch op {
+	case BenchSign:
+
+		return func() error {
+			_, err := target.Signer.Sign(rand.Reader, message, opts)
+			return err
+		}, nil
+
+	case BenchVerify:
+
+		sig, err := target.Signer.Sign(rand.Reader, message, opts)
+		if err != nil {
+			return nil, err
+		}
+
+		switch key := pub.(type) {
+		case *rsa.PublicKey:
+			return func() error { return rsa.VerifyPKCS1v15(key, target.Hash, message, sig) }, nil
+		case *ecdsa.PublicKey:
+			return func() error {
+				if !ecdsa.VerifyASN1(key, message, sig) {
+					return fmt.Errorf("invalid signature")
+				}
+				return nil
+			}, nil
+		case ed25519.PublicKey:
+			return func() error {
+				if !ed25519.Verify(key, message, sig) {
+					return fmt.Errorf("invalid signature")
+				}
+				return nil
+			}, nil
+		}
+
+	case BenchEncrypt, BenchDecrypt:
+
+		key, ok := pub.(*rsa.PublicKey)
+		if !ok {
+			return nil, fmt.Errorf("%s is only supported for RSA keys", op)
+		}
+
+		if op == BenchEncrypt {
+			return func() error {
+				_, err := rsa.EncryptOAEP(target.Hash.New(), rand.Reader, key, payload, nil)
+				return err
+			}, nil
+		}
+
+		decrypter, ok := target.Signer.(crypto.Decrypter)
+		if !ok {
+			return nil, fmt.Errorf("target do not implement crypto.Decrypter")
+		}
+
+		ciphertext, err := rsa.EncryptOAEP(target.Hash.New(), rand.Reader, key, payload, nil)
+		if err != nil {
+			return nil, err
+		}
+
+		return func() error {
+			_, err := decrypter.Decrypt(rand.Reader, ciphertext, &rsa.OAEPOptions{Hash: target.Hash})
+			return err
+		}, nil
+
+	default:
+
+		return nil, fmt.Errorf("unknown benchmark operation: %s", op)
+
+	}
+
+	return nil, fmt.Errorf("unsupported key type: %T", pub)
+
+}
@@ -0,0 +1,50 @@
+package gocrypto
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBench(t *testing.T) {
+
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 1024)
+	require.NoError(t, err)
+
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	results, err := Bench(BenchSpec{
+		Targets: []BenchTarget{
+			{Name: "rsa", Signer: rsaKey},
+			{Name: "ec", Signer: ecKey},
+		},
+		Operations:  []BenchOperation{BenchSign, BenchVerify, BenchDecrypt},
+		Duration:    20 * time.Millisecond,
+		Concurrency: 2,
+	})
+	require.NoError(t, err)
+	require.Len(t, results, 6)
+
+	for _, result := range results {
+
+		if result.Target == "ec" && result.Operation == BenchDecrypt {
+			assert.NotEmpty(t, result.Err)
+			continue
+		}
+
+		assert.Empty(t, result.Err, "%s/%s", result.Target, result.Operation)
+		assert.True(t, result.Operations > 0)
+		assert.Zero(t, result.Errors)
+		assert.True(t, result.OpsPerSecond > 0)
+		assert.True(t, result.LatencyP99 >= result.LatencyP50)
+
+	}
+
+}
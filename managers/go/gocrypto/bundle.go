@@ -0,0 +1,230 @@
+package gocrypto
+
+import (
+	"bytes"
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"io"
+	"sort"
+
+	"github.com/mariotoffia/goservice/interfaces/ifcrypto"
+)
+
+// KeyIDPEMHeader is the _PEM_ header that carries the id of a block in a `KeyBundle`.
+const KeyIDPEMHeader = "Key-Id"
+
+// ErrDuplicateBundleID is returned by `KeyBundle.ReadFrom` when two keys, or two certificates,
+// have the same id.
+var ErrDuplicateBundleID = fmt.Errorf("duplicate bundle id")
+
+// KeyBundle is the keys and certificates of a single _PEM_ file, e.g. a secrets file that carries
+// all key material of a service, keyed by their id.
+//
+// Each block is named by its _Key-Id_ header. It implements `io.WriterTo` and `io.ReaderFrom`.
+type KeyBundle struct {
+	Keys         map[string]ifcrypto.Key
+	Certificates map[string]*x509.Certificate
+	// Usage is the usage of keys read by `ReadFrom`.
+	Usage []ifcrypto.KeyUsage
+}
+
+// ReadFrom implements the `io.ReaderFrom` interface and adds all keys and certificates read
+// from _r_ to the bundle. Other blocks are skipped.
+//
+// A block without a _Key-Id_ header is named by the hex encoded _SHA-256_ of its _DER_. A public
+// key with the same id as a private key, as written by `PEMWrite` with the public portion, is
+// merged into the private key. Encrypted keys are not supported.
+func (b *KeyBundle) ReadFrom(r io.Reader) (int64, error) {
+
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return int64(len(data)), err
+	}
+
+	if b.Keys == nil {
+		b.Keys = map[string]ifcrypto.Key{}
+	}
+
+	if b.Certificates == nil {
+		b.Certificates = map[string]*x509.Certificate{}
+	}
+
+	for rest := data; ; {
+
+		var block *pem.Block
+
+		block, rest = pem.Decode(rest)
+		if block == nil {
+			break
+		}
+
+		id := block.Headers[KeyIDPEMHeader]
+		if id == "" {
+			id = publicKeySetID(block.Bytes)
+		}
+
+		if block.Type == "CERTIFICATE" {
+
+			if _, ok := b.Certificates[id]; ok {
+				return int64(len(data)), fmt.Errorf("%w: certificate %s", ErrDuplicateBundleID, id)
+			}
+
+			cert, err := x509.ParseCertificate(block.Bytes)
+			if err != nil {
+				return int64(len(data)), fmt.Errorf("certificate %s: %w", id, err)
+			}
+
+			b.Certificates[id] = cert
+			continue
+
+		}
+
+		key, err := keyFromPEMBlock(block, id, b.Usage...)
+		if err != nil {
+			return int64(len(data)), fmt.Errorf("key %s: %w", id, err)
+		}
+
+		if key == nil {
+			continue
+		}
+
+		if existing, ok := b.Keys[id]; ok {
+
+			_, pair := existing.(ifcrypto.KeyPair)
+			_, newPair := key.(ifcrypto.KeyPair)
+
+			if pair == newPair {
+				return int64(len(data)), fmt.Errorf("%w: key %s", ErrDuplicateBundleID, id)
+			}
+
+			if pair {
+				continue
+			}
+
+		}
+
+		b.Keys[id] = key
+
+	}
+
+	return int64(len(data)), nil
+
+}
+
+// WriteTo implements the `io.WriterTo` interface and writes all keys and then all certificates,
+// each sorted by id, as _PEM_ onto _w_. The same bundle is always written as the same bytes.
+//
+// Key pairs are written without their public portion. Since the private keys leave the process
+// they must have `ifcrypto.KeyUsageExport`, otherwise `ErrKeyNotExportable` is returned.
+func (b *KeyBundle) WriteTo(w io.Writer) (int64, error) {
+
+	cw := &countingWriter{w: w}
+
+	for _, id := range sortedKeyIDs(b.Keys) {
+
+		data, err := bundleKeyPEM(b.Keys[id])
+		if err != nil {
+			return cw.n, err
+		}
+
+		for {
+
+			var block *pem.Block
+
+			block, data = pem.Decode(data)
+			if block == nil {
+				break
+			}
+
+			if err := writeBundleBlock(cw, block, id); err != nil {
+				return cw.n, err
+			}
+
+		}
+
+	}
+
+	ids := make([]string, 0, len(b.Certificates))
+	for id := range b.Certificates {
+		ids = append(ids, id)
+	}
+
+	sort.Strings(ids)
+
+	for _, id := range ids {
+
+		block := &pem.Block{Type: "CERTIFICATE", Bytes: b.Certificates[id].Raw}
+
+		if err := writeBundleBlock(cw, block, id); err != nil {
+			return cw.n, err
+		}
+
+	}
+
+	return cw.n, nil
+
+}
+
+// bundleKeyPEM returns the _PEM_ of _key_, without the public portion of a key pair.
+func bundleKeyPEM(key ifcrypto.Key) ([]byte, error) {
+
+	writer, ok := key.(ifcrypto.PEMWriter)
+	if !ok {
+		return nil, fmt.Errorf("key %s can not be written as PEM", key.GetID())
+	}
+
+	public := true
+
+	if _, ok := key.(ifcrypto.KeyPair); ok {
+
+		if key.IsRemoteKey() {
+			return nil, fmt.Errorf("%w: %s is a remote key", ErrKeyNotExportable, key.GetID())
+		}
+
+		if !hasKeyUsage(key, ifcrypto.KeyUsageExport) {
+			return nil, fmt.Errorf("%w: %s", ErrKeyNotExportable, key.GetID())
+		}
+
+		public = false
+
+	}
+
+	var buf bytes.Buffer
+
+	if err := writer.PEMWrite(&buf, public); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+
+}
+
+// writeBundleBlock writes _block_ with the _Key-Id_ header set to _id_.
+func writeBundleBlock(w io.Writer, block *pem.Block, id string) error {
+
+	headers := map[string]string{KeyIDPEMHeader: id}
+
+	for k, v := range block.Headers {
+		headers[k] = v
+	}
+
+	block.Headers = headers
+
+	return pem.Encode(w, block)
+
+}
+
+// sortedKeyIDs returns the ids of _keys_ in sorted order.
+func sortedKeyIDs(keys map[string]ifcrypto.Key) []string {
+
+	ids := make([]string, 0, len(keys))
+	for id := range keys {
+		ids = append(ids, id)
+	}
+
+	sort.Strings(ids)
+
+	return ids
+
+}
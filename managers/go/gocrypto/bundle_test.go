@@ -0,0 +1,115 @@
+//go:build !verifyonly
+// +build !verifyonly
+
+package gocrypto
+
+import (
+	"bytes"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"testing"
+
+	"github.com/mariotoffia/goservice/interfaces/ifcrypto"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestKeyBundleRoundTrip(t *testing.T) {
+
+	rsaKey, err := NewRSAPrivateKey("rsa", 2048, ifcrypto.KeyUsageSign, ifcrypto.KeyUsageExport)
+	require.NoError(t, err)
+
+	edKey, err := NewED25519PrivateKey("ed", ifcrypto.KeyUsageSign, ifcrypto.KeyUsageExport)
+	require.NoError(t, err)
+
+	aesKey, err := NewSymmetricKey("aes", 256, ifcrypto.KeyUsageEncrypt, ifcrypto.KeyUsageExport)
+	require.NoError(t, err)
+
+	peer, err := NewECDSAPrivateKey("peer", 256, ifcrypto.KeyUsageSign)
+	require.NoError(t, err)
+
+	bundle := &KeyBundle{
+		Keys: map[string]ifcrypto.Key{
+			"signing": rsaKey,
+			"webhook": edKey,
+			"data":    aesKey,
+			"peer":    peer.GetPublic(),
+		},
+		Certificates: map[string]*x509.Certificate{
+			"tls": testCertificate(t, "api.example.com"),
+			"ca":  testCertificate(t, "ca.example.com"),
+		},
+	}
+
+	var buf bytes.Buffer
+
+	n, err := bundle.WriteTo(&buf)
+	require.NoError(t, err)
+	assert.Equal(t, int64(buf.Len()), n)
+
+	var again bytes.Buffer
+
+	_, err = bundle.WriteTo(&again)
+	require.NoError(t, err)
+	assert.Equal(t, buf.Bytes(), again.Bytes())
+
+	read := &KeyBundle{Usage: []ifcrypto.KeyUsage{ifcrypto.KeyUsageSign}}
+
+	_, err = read.ReadFrom(&buf)
+	require.NoError(t, err)
+	require.Len(t, read.Keys, 4)
+	require.Len(t, read.Certificates, 2)
+
+	assert.IsType(t, &RSAPrivateKey{}, read.Keys["signing"])
+	assert.IsType(t, &ED25519PrivateKey{}, read.Keys["webhook"])
+	assert.IsType(t, &SymmetricKey{}, read.Keys["data"])
+	assert.IsType(t, &ECDSAPublicKey{}, read.Keys["peer"])
+	assert.Equal(t, "signing", read.Keys["signing"].GetID())
+	assert.Equal(t, "api.example.com", read.Certificates["tls"].Subject.CommonName)
+
+	_, err = (&KeyBundle{Keys: map[string]ifcrypto.Key{"peer": peer}}).WriteTo(&again)
+	assert.True(t, errors.Is(err, ErrKeyNotExportable))
+
+}
+
+func TestKeyBundleReadSkipsPublicPortionAndRejectsDuplicates(t *testing.T) {
+
+	edKey, err := NewED25519PrivateKey("ed", ifcrypto.KeyUsageSign)
+	require.NoError(t, err)
+
+	var buf bytes.Buffer
+	require.NoError(t, edKey.PEMWrite(&buf, true))
+
+	// Without a Key-Id header the blocks are named by their DER.
+	read := &KeyBundle{}
+
+	_, err = read.ReadFrom(bytes.NewReader(buf.Bytes()))
+	require.NoError(t, err)
+	require.Len(t, read.Keys, 2)
+
+	var named bytes.Buffer
+
+	for data := buf.Bytes(); ; {
+
+		var block *pem.Block
+
+		if block, data = pem.Decode(data); block == nil {
+			break
+		}
+
+		require.NoError(t, writeBundleBlock(&named, block, "ed"))
+
+	}
+
+	read = &KeyBundle{}
+
+	_, err = read.ReadFrom(bytes.NewReader(named.Bytes()))
+	require.NoError(t, err)
+	require.Len(t, read.Keys, 1)
+	assert.IsType(t, &ED25519PrivateKey{}, read.Keys["ed"])
+
+	_, err = read.ReadFrom(bytes.NewReader(named.Bytes()))
+	assert.True(t, errors.Is(err, ErrDuplicateBundleID))
+
+}
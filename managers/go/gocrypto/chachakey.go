@@ -0,0 +1,196 @@
+package gocrypto
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/pem"
+	"fmt"
+	"io"
+
+	"github.com/mariotoffia/goservice/interfaces/ifcrypto"
+	"github.com/mariotoffia/goservice/utils/cryptoutils"
+	"golang.org/x/crypto/chacha20poly1305"
+)
+
+// ChaChaKeyPEMType is the _PEM_ block type used to armor a `ChaChaKey`.
+const ChaChaKeyPEMType = "CHACHA20 KEY"
+
+// ChaChaKey implements the `ifcrypto.KeyPair` interface for a 256 bit _ChaCha20-Poly1305_ key.
+//
+// It is a alternative to `SymmetricKey` in environments without _AES_ hardware acceleration,
+// where _ChaCha20_ is both faster and not exposed to cache timing attacks. The key may be used
+// with `ifcrypto.ChiperChaCha20Poly1305` and `ifcrypto.ChiperXChaCha20Poly1305`.
+//
+// A symmetric key has no public portion, hence `GetPublic` returns `nil`.
+type ChaChaKey struct {
+	KeyBase
+	key []byte
+}
+
+// NewChaChaKeyFromPEM initializes a new `ChaChaKey` from the underlying _PEM_ block.
+func NewChaChaKeyFromPEM(block pem.Block, id string, usage ...ifcrypto.KeyUsage) (*ChaChaKey, error) {
+
+	if block.Type == ChaChaKeyPEMType {
+		return NewChaChaKeyFromBytes(id, block.Bytes, usage...)
+	}
+
+	return nil, fmt.Errorf("unsupported PEM block: %s", block.Type)
+
+}
+
+// NewChaChaKey generates a new `ChaChaKey` using the `rand.Reader`, or the `EntropyGuard` reader, as entropy.
+func NewChaChaKey(id string, usage ...ifcrypto.KeyUsage) (*ChaChaKey, error) {
+
+	reader, err := keyGenerationReader()
+	if err != nil {
+		return nil, err
+	}
+
+	key := make([]byte, chacha20poly1305.KeySize)
+
+	if _, err := io.ReadFull(reader, key); err != nil {
+		return nil, err
+	}
+
+	return NewChaChaKeyFromBytes(id, key, usage...)
+
+}
+
+// AEAD returns the _chiper_ keyed with this key.
+func (r *ChaChaKey) AEAD(chiper ifcrypto.Chipher) (cipher.AEAD, error) {
+
+	if chiper != ifcrypto.ChiperChaCha20Poly1305 && chiper != ifcrypto.ChiperXChaCha20Poly1305 {
+		return nil, fmt.Errorf("unsupported chiper for ChaCha20 key: %s", chiper)
+	}
+
+	return NewAEAD(chiper, r.key)
+
+}
+
+// Encrypt encrypts and authenticates the _plaintext_ and authenticates the _additionalData_
+// using the _chiper_. The random nonce is prepended to the returned ciphertext.
+//
+// Prefer `ifcrypto.ChiperXChaCha20Poly1305` when many messages are encrypted with the same
+// key, the 96 bit nonce of `ifcrypto.ChiperChaCha20Poly1305` must not be randomly generated
+// for more than 2^32 messages.
+func (r *ChaChaKey) Encrypt(chiper ifcrypto.Chipher, plaintext, additionalData []byte) ([]byte, error) {
+
+	if err := CheckAlgorithm(ifcrypto.PolicyOperationEncrypt, r, 0); err != nil {
+		return nil, err
+	}
+
+	aead, err := r.AEAD(chiper)
+	if err != nil {
+		return nil, err
+	}
+
+	return sealAEAD(aead, plaintext, additionalData)
+
+}
+
+// GetPublic returns `nil` since a symmetric key has no public portion.
+func (r *ChaChaKey) GetPublic() ifcrypto.PublicKey {
+	return nil
+}
+
+// PEMWrite will write the key onto _w_ as a _CHACHA20 KEY_ PEM block.
+//
+// Since there is no public portion, the _public_ parameter is ignored.
+func (r *ChaChaKey) PEMWrite(w io.Writer, public bool) error {
+
+	return pem.Encode(w, &pem.Block{Type: ChaChaKeyPEMType, Bytes: r.key})
+
+}
+
+// DERWrite implements the `ifcrypto.DERWriter` interface, the raw key bytes are written. Use
+// `NewChaChaKeyFromBytes` to read them.
+func (r *ChaChaKey) DERWrite(w io.Writer) error {
+
+	_, err := w.Write(r.key)
+
+	return err
+
+}
+
+// ToJWK implements the `JWKMarshaler` interface and returns a _oct_ _JWK_ with the secret key.
+func (r *ChaChaKey) ToJWK() (*cryptoutils.JSONWebKey, error) {
+	return keyToJWK(r)
+}
+
+// GetKey gets the underlying key, the raw key bytes.
+//
+// Some keys are remote and not possible to fetch. In such situations the function returns a remote id,
+// most often the same as GetID() returns.
+func (r *ChaChaKey) GetKey() interface{} {
+	return r.key
+}
+
+// IsSymmetric returns `true` if this is a `KeyTypeSymmetric`
+//
+// This is a convenience function instead of `GetKeyType`.
+func (r *ChaChaKey) IsSymmetric() bool {
+	return true
+}
+
+// IsPrivate returns `true` if this is a `KeyType` other than `KeyTypeSymmetric` and is a private key.
+//
+// If `KeyTypeSymmetric` it will return `true` since all symmetric keys are considered as private.
+func (r *ChaChaKey) IsPrivate() bool {
+	return true
+}
+
+// IsRemoteKey returns `true` if the key is not present in current process memory.
+//
+// Typically hardware units or remote services will not reveal their private key. In such case, this
+// method returns `true`. If present in memory such as a `*rsa.PrivateKey` it returns `false`.
+func (r *ChaChaKey) IsRemoteKey() bool {
+	return false
+}
+
+// NewAEAD creates the _AEAD_ of _chiper_ keyed with _key_. The _AES_ chipers use _GCM_.
+func NewAEAD(chiper ifcrypto.Chipher, key []byte) (cipher.AEAD, error) {
+
+	switch chiper {
+	case ifcrypto.ChiperChaCha20Poly1305:
+		return chacha20poly1305.New(key)
+	case ifcrypto.ChiperXChaCha20Poly1305:
+		return chacha20poly1305.NewX(key)
+	case ifcrypto.ChiperAES128, ifcrypto.ChiperAES192, ifcrypto.ChiperAES256:
+
+		block, err := aes.NewCipher(key)
+		if err != nil {
+			return nil, err
+		}
+
+		return cipher.NewGCM(block)
+
+	}
+
+	return nil, fmt.Errorf("unsupported chiper: %s", chiper)
+
+}
+
+// sealAEAD encrypts _plaintext_ with a random nonce that is prepended to the ciphertext.
+func sealAEAD(aead cipher.AEAD, plaintext, additionalData []byte) ([]byte, error) {
+
+	nonce := make([]byte, aead.NonceSize(), aead.NonceSize()+len(plaintext)+aead.Overhead())
+
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return nil, err
+	}
+
+	return aead.Seal(nonce, nonce, plaintext, additionalData), nil
+
+}
+
+// openAEAD decrypts a ciphertext created by `sealAEAD`.
+func openAEAD(aead cipher.AEAD, ciphertext, additionalData []byte) ([]byte, error) {
+
+	if len(ciphertext) < aead.NonceSize()+aead.Overhead() {
+		return nil, fmt.Errorf("ciphertext too short")
+	}
+
+	return aead.Open(nil, ciphertext[:aead.NonceSize()], ciphertext[aead.NonceSize():], additionalData)
+
+}
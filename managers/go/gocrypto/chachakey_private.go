@@ -0,0 +1,61 @@
+//go:build !verifyonly
+// +build !verifyonly
+
+package gocrypto
+
+import (
+	"fmt"
+
+	"github.com/mariotoffia/goservice/interfaces/ifcrypto"
+	"golang.org/x/crypto/chacha20poly1305"
+)
+
+// NewChaChaKeyFromBytes creates a new `ChaChaKey` from a 32 byte _key_.
+//
+// The _key_ is copied.
+func NewChaChaKeyFromBytes(id string, key []byte, usage ...ifcrypto.KeyUsage) (*ChaChaKey, error) {
+
+	if IsVerifyOnly() {
+		return nil, ErrVerifyOnly
+	}
+
+	if len(key) != chacha20poly1305.KeySize {
+		return nil, fmt.Errorf("invalid ChaCha20 key size: %d bytes", len(key))
+	}
+
+	return &ChaChaKey{
+		KeyBase: KeyBase{
+			id:      id,
+			keyType: ifcrypto.KeyTypeSymmetric,
+			keySize: 256,
+			usage:   usage,
+			chiper: []ifcrypto.Chipher{
+				ifcrypto.ChiperXChaCha20Poly1305,
+				ifcrypto.ChiperChaCha20Poly1305,
+			},
+		},
+		key: append([]byte{}, key...),
+	}, nil
+
+}
+
+// Decrypt decrypts and authenticates _ciphertext_, created by `Encrypt`, and authenticates
+// the _additionalData_ using the _chiper_.
+func (r *ChaChaKey) Decrypt(chiper ifcrypto.Chipher, ciphertext, additionalData []byte) ([]byte, error) {
+
+	if IsVerifyOnly() {
+		return nil, ErrVerifyOnly
+	}
+
+	if err := CheckAlgorithm(ifcrypto.PolicyOperationDecrypt, r, 0); err != nil {
+		return nil, err
+	}
+
+	aead, err := r.AEAD(chiper)
+	if err != nil {
+		return nil, err
+	}
+
+	return openAEAD(aead, ciphertext, additionalData)
+
+}
@@ -0,0 +1,73 @@
+//go:build !verifyonly
+// +build !verifyonly
+
+package gocrypto
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/pem"
+	"testing"
+
+	"github.com/mariotoffia/goservice/interfaces/ifcrypto"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestChaChaKeyEncryptDecrypt(t *testing.T) {
+
+	key, err := NewChaChaKey("dek", ifcrypto.KeyUsageEncrypt, ifcrypto.KeyUsageDecrypt)
+	require.NoError(t, err)
+
+	var _ ifcrypto.KeyPair = key
+
+	assert.True(t, key.IsSymmetric())
+	assert.Nil(t, key.GetPublic())
+
+	for _, chiper := range key.GetSupportedChiphers() {
+
+		ciphertext, err := key.Encrypt(chiper, []byte("hello"), []byte("record-1"))
+		require.NoError(t, err)
+
+		plaintext, err := key.Decrypt(chiper, ciphertext, []byte("record-1"))
+		require.NoError(t, err)
+		assert.Equal(t, "hello", string(plaintext))
+
+		_, err = key.Decrypt(chiper, ciphertext, []byte("record-2"))
+		assert.Error(t, err)
+
+	}
+
+	_, err = key.Encrypt(ifcrypto.ChiperAES256, []byte("hello"), nil)
+	assert.Error(t, err)
+
+	var buf bytes.Buffer
+	require.NoError(t, key.PEMWrite(&buf, true))
+
+	block, _ := pem.Decode(buf.Bytes())
+	require.NotNil(t, block)
+
+	loaded, err := NewChaChaKeyFromPEM(*block, "dek")
+	require.NoError(t, err)
+	assert.Equal(t, key.GetKey(), loaded.GetKey())
+
+}
+
+func TestChaCha20Poly1305RFC8439Vector(t *testing.T) {
+
+	// RFC 8439 section 2.8.2
+	key, _ := hex.DecodeString("808182838485868788898a8b8c8d8e8f909192939495969798999a9b9c9d9e9f")
+	nonce, _ := hex.DecodeString("070000004041424344454647")
+	aad, _ := hex.DecodeString("50515253c0c1c2c3c4c5c6c7")
+
+	plaintext := []byte("Ladies and Gentlemen of the class of '99: If I could offer you " +
+		"only one tip for the future, sunscreen would be it.")
+
+	aead, err := NewAEAD(ifcrypto.ChiperChaCha20Poly1305, key)
+	require.NoError(t, err)
+
+	sealed := aead.Seal(nil, nonce, plaintext, aad)
+
+	assert.Equal(t, "1ae10b594f09e26a7e902ecbd0600691", hex.EncodeToString(sealed[len(sealed)-16:]))
+
+}
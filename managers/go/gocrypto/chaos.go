@@ -0,0 +1,225 @@
+package gocrypto
+
+import (
+	"context"
+	"crypto"
+	"fmt"
+	"io"
+	"math/rand"
+	"sync"
+	"time"
+
+	"github.com/mariotoffia/goservice/interfaces/ifcrypto"
+)
+
+// ErrInjectedFault is the default error returned by a `ChaosProvider` when it injects a failure.
+var ErrInjectedFault = fmt.Errorf("injected fault")
+
+// FaultConfig describes the faults a `ChaosProvider` injects.
+type FaultConfig struct {
+	// ErrorRate is the probability, 0 - 1, that a operation fails with _Err_.
+	ErrorRate float64
+	// Err is the error returned by failed operations, default `ErrInjectedFault`.
+	Err error
+	// Latency is added to each operation before it is forwarded.
+	Latency time.Duration
+	// Jitter is a random extra latency between zero and _Jitter_.
+	Jitter time.Duration
+	// PartialFailureRate is the probability, 0 - 1, that a single request within a batch
+	// fails while the rest of the batch succeeds.
+	PartialFailureRate float64
+}
+
+// ChaosProvider decorates a key provider with configurable latency and failures, e.g. to
+// rehearse a _KMS_ outage in a resilience test.
+//
+// It wraps a `crypto.Signer` and, if the wrapped signer supports it, `crypto.Decrypter`
+// and `ifcrypto.BatchSigner`. Faults are only injected while the provider is enabled and
+// both the configuration and the enabled state may be changed at runtime.
+type ChaosProvider struct {
+	signer  crypto.Signer
+	mu      sync.RWMutex
+	config  FaultConfig
+	enabled bool
+	rngMu   sync.Mutex
+	rng     *rand.Rand
+}
+
+// NewChaosProvider creates a new, disabled, `ChaosProvider` wrapping _signer_.
+func NewChaosProvider(signer crypto.Signer, config FaultConfig) *ChaosProvider {
+
+	return &ChaosProvider{
+		signer: signer,
+		config: config,
+		rng:    rand.New(rand.NewSource(time.Now().UnixNano())),
+	}
+
+}
+
+// Enable starts injecting faults.
+func (p *ChaosProvider) Enable() {
+	p.mu.Lock()
+	p.enabled = true
+	p.mu.Unlock()
+}
+
+// Disable stops injecting faults, all operations are forwarded as is.
+func (p *ChaosProvider) Disable() {
+	p.mu.Lock()
+	p.enabled = false
+	p.mu.Unlock()
+}
+
+// SetConfig replaces the fault configuration.
+func (p *ChaosProvider) SetConfig(config FaultConfig) {
+	p.mu.Lock()
+	p.config = config
+	p.mu.Unlock()
+}
+
+// Public implements the `crypto.Signer` interface.
+func (p *ChaosProvider) Public() crypto.PublicKey {
+	return p.signer.Public()
+}
+
+// Sign implements the `crypto.Signer` interface.
+func (p *ChaosProvider) Sign(rand io.Reader, digest []byte, opts crypto.SignerOpts) ([]byte, error) {
+
+	if err := p.inject(context.Background()); err != nil {
+		return nil, err
+	}
+
+	return p.signer.Sign(rand, digest, opts)
+
+}
+
+// Decrypt implements the `crypto.Decrypter` interface, it fails if the wrapped signer is
+// not a `crypto.Decrypter`.
+func (p *ChaosProvider) Decrypt(rand io.Reader, msg []byte, opts crypto.DecrypterOpts) ([]byte, error) {
+
+	decrypter, ok := p.signer.(crypto.Decrypter)
+	if !ok {
+		return nil, fmt.Errorf("wrapped provider do not support decryption")
+	}
+
+	if err := p.inject(context.Background()); err != nil {
+		return nil, err
+	}
+
+	return decrypter.Decrypt(rand, msg, opts)
+
+}
+
+// SignBatch implements the `ifcrypto.BatchSigner` interface. If the wrapped signer is not a
+// `ifcrypto.BatchSigner` each request is signed in sequence.
+func (p *ChaosProvider) SignBatch(c context.Context, requests []ifcrypto.SignRequest) []ifcrypto.SignResult {
+
+	if err := p.inject(c); err != nil {
+
+		results := make([]ifcrypto.SignResult, len(requests))
+
+		for i := range results {
+			results[i].Err = err
+		}
+
+		return results
+
+	}
+
+	var results []ifcrypto.SignResult
+
+	if batch, ok := p.signer.(ifcrypto.BatchSigner); ok {
+		results = batch.SignBatch(c, requests)
+	} else {
+		results = (&SequentialBatchSigner{Signer: p.signer}).SignBatch(c, requests)
+	}
+
+	config, enabled := p.current()
+
+	if enabled && config.PartialFailureRate > 0 {
+
+		for i := range results {
+
+			if p.roll(config.PartialFailureRate) {
+				results[i] = ifcrypto.SignResult{Err: faultError(config)}
+			}
+
+		}
+
+	}
+
+	return results
+
+}
+
+// inject sleeps and possibly fails according to the current configuration.
+func (p *ChaosProvider) inject(c context.Context) error {
+
+	config, enabled := p.current()
+	if !enabled {
+		return nil
+	}
+
+	delay := config.Latency
+
+	if config.Jitter > 0 {
+		p.rngMu.Lock()
+		delay += time.Duration(p.rng.Int63n(int64(config.Jitter)))
+		p.rngMu.Unlock()
+	}
+
+	if delay > 0 {
+
+		timer := time.NewTimer(delay)
+		defer timer.Stop()
+
+		select {
+		case <-timer.C:
+		case <-c.Done():
+			return c.Err()
+		}
+
+	}
+
+	if p.roll(config.ErrorRate) {
+		return faultError(config)
+	}
+
+	return nil
+
+}
+
+// current returns the current configuration and enabled state.
+func (p *ChaosProvider) current() (FaultConfig, bool) {
+
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	return p.config, p.enabled
+
+}
+
+// roll returns `true` with the _probability_.
+func (p *ChaosProvider) roll(probability float64) bool {
+
+	if probability <= 0 {
+		return false
+	}
+
+	p.rngMu.Lock()
+	defer p.rngMu.Unlock()
+
+	return p.rng.Float64() < probability
+
+}
+
+// faultError returns the configured error or `ErrInjectedFault`.
+func faultError(config FaultConfig) error {
+
+	if config.Err != nil {
+		return config.Err
+	}
+
+	return ErrInjectedFault
+
+}
@@ -0,0 +1,66 @@
+package gocrypto
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"testing"
+	"time"
+
+	"github.com/mariotoffia/goservice/interfaces/ifcrypto"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestChaosProviderInjectsFaultsOnlyWhenEnabled(t *testing.T) {
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	digest := sha256.Sum256([]byte("msg"))
+	chaos := NewChaosProvider(key, FaultConfig{ErrorRate: 1})
+
+	_, err = chaos.Sign(rand.Reader, digest[:], crypto.SHA256)
+	assert.NoError(t, err, "disabled")
+
+	chaos.Enable()
+
+	_, err = chaos.Sign(rand.Reader, digest[:], crypto.SHA256)
+	assert.Equal(t, ErrInjectedFault, err)
+
+	chaos.SetConfig(FaultConfig{Latency: 20 * time.Millisecond})
+
+	start := time.Now()
+	_, err = chaos.Sign(rand.Reader, digest[:], crypto.SHA256)
+	assert.NoError(t, err)
+	assert.True(t, time.Since(start) >= 20*time.Millisecond)
+
+	chaos.SetConfig(FaultConfig{PartialFailureRate: 0.5})
+
+	requests := make([]ifcrypto.SignRequest, 200)
+	for i := range requests {
+		requests[i] = ifcrypto.SignRequest{Digest: digest[:], Opts: crypto.SHA256}
+	}
+
+	failed := 0
+	for _, result := range chaos.SignBatch(context.Background(), requests) {
+		if result.Err != nil {
+			failed++
+		}
+	}
+
+	assert.True(t, failed > 0 && failed < len(requests), "partial failures: %d", failed)
+
+	chaos.Disable()
+
+	for _, result := range chaos.SignBatch(context.Background(), requests) {
+		assert.NoError(t, result.Err)
+	}
+
+	_, err = chaos.Decrypt(rand.Reader, nil, nil)
+	assert.Error(t, err, "ecdsa can not decrypt")
+
+}
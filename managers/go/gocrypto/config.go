@@ -0,0 +1,511 @@
+package gocrypto
+
+import (
+	"bytes"
+	"context"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/mariotoffia/goservice/interfaces/ifcrypto"
+	"github.com/mariotoffia/goservice/utils/cryptoutils"
+	"gopkg.in/yaml.v3"
+)
+
+// ErrInvalidConfig is returned when a `Config` can not be assembled.
+var ErrInvalidConfig = fmt.Errorf("invalid crypto config")
+
+// Config is the configuration of the crypto subsystem of a service, see `FromConfig`.
+//
+// Components are named, such that other components may refer to them, e.g. a token issuer
+// to the keystore that holds its key. Secrets are never part of the configuration, options
+// refer to environment variables holding them instead, e.g. _passphrase_env_.
+//
+// It is decoded from _JSON_ by `ParseConfig` and from _YAML_ by `ParseConfigYAML`, the
+// _YAML_ tags mirror the _JSON_ tags.
+//
+// .Example
+// [source,json]
+// ----
+// {"keystores": {
+// "local": {"type": "file", "options": {"dir": "/var/lib/keys", "passphrase_env": "KEYSTORE_PASSPHRASE"}},
+// "kms": {"type": "gcp-kms", "options": {"key_ring": "projects/p/locations/l/keyRings/r"}}},
+// "truststores": {"mesh": {"type": "pem", "options": {"files": ["/etc/mesh/ca.pem"]}}},
+// "rotation": {"local": {"keystore": "local", "retention": "2160h", "min_versions": 2}},
+// "issuers": {"session": {"type": "branca", "options": {"keystore": "local", "key_id": "session"}}}}
+// ----
+type Config struct {
+	KeyStores   map[string]ComponentConfig `json:"keystores,omitempty" yaml:"keystores,omitempty"`
+	TrustStores map[string]ComponentConfig `json:"truststores,omitempty" yaml:"truststores,omitempty"`
+	Rotation    map[string]RotationConfig  `json:"rotation,omitempty" yaml:"rotation,omitempty"`
+	Issuers     map[string]ComponentConfig `json:"issuers,omitempty" yaml:"issuers,omitempty"`
+}
+
+// ComponentConfig configures a component of a registered _Type_, see `RegisterComponent`.
+type ComponentConfig struct {
+	Type    string                 `json:"type" yaml:"type"`
+	Options map[string]interface{} `json:"options,omitempty" yaml:"options,omitempty"`
+}
+
+// Decode decodes the _Options_ into _v_, a pointer to a _JSON_ tagged struct. Unknown options
+// are rejected to catch misspelled options.
+func (cc ComponentConfig) Decode(v interface{}) error {
+
+	data, err := json.Marshal(cc.Options)
+	if err != nil {
+		return err
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+
+	return decoder.Decode(v)
+
+}
+
+// RotationConfig configures the `KeyRetentionPolicy` of the rotated key versions in a keystore.
+type RotationConfig struct {
+	// KeyStore is the name of the keystore.
+	KeyStore string `json:"keystore" yaml:"keystore"`
+	// Retention is a `time.ParseDuration` duration, e.g. _2160h_.
+	Retention   string `json:"retention" yaml:"retention"`
+	MinVersions int    `json:"min_versions,omitempty" yaml:"min_versions,omitempty"`
+}
+
+// ParseConfig parses a _JSON_ encoded `Config`. Unknown fields are rejected.
+func ParseConfig(r io.Reader) (*Config, error) {
+
+	var cfg Config
+
+	decoder := json.NewDecoder(r)
+	decoder.DisallowUnknownFields()
+
+	if err := decoder.Decode(&cfg); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidConfig, err)
+	}
+
+	return &cfg, nil
+
+}
+
+// ParseConfigYAML parses a _YAML_ encoded `Config`. Unknown fields are rejected.
+func ParseConfigYAML(r io.Reader) (*Config, error) {
+
+	var cfg Config
+
+	decoder := yaml.NewDecoder(r)
+	decoder.KnownFields(true)
+
+	if err := decoder.Decode(&cfg); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidConfig, err)
+	}
+
+	return &cfg, nil
+
+}
+
+// TokenIssuer issues and verifies symmetric tokens, e.g. `cryptoutils.Fernet` and
+// `cryptoutils.Branca`.
+type TokenIssuer interface {
+	// Encode issues a token of the _payload_.
+	Encode(payload []byte) (string, error)
+	// Decode verifies the _token_, that must not be older than _ttl_ unless zero, and returns
+	// the payload.
+	Decode(token string, ttl time.Duration) ([]byte, error)
+}
+
+// Assembly is the components of a `Config`, by name, see `FromConfig`.
+type Assembly struct {
+	KeyStores   map[string]ifcrypto.KeyStore
+	TrustStores map[string]*x509.CertPool
+	Rotation    map[string]KeyRetentionPolicy
+	// Collectors is the `KeyCollector` of each rotation whose keystore implements
+	// `ifcrypto.KeyVersionStore`.
+	Collectors map[string]*KeyCollector
+	Issuers    map[string]TokenIssuer
+	// Lifecycle is all components that implements `ifcrypto.Lifecycle`, in assembly order.
+	Lifecycle LifecycleGroup
+}
+
+// KeyStore returns the keystore _name_ that is already assembled.
+func (a *Assembly) KeyStore(name string) (ifcrypto.KeyStore, error) {
+
+	store, ok := a.KeyStores[name]
+	if !ok {
+		return nil, fmt.Errorf("%w: no keystore named %s", ErrInvalidConfig, name)
+	}
+
+	return store, nil
+
+}
+
+// ComponentKind is the kind of component a `ComponentFactory` creates.
+type ComponentKind string
+
+const (
+	// ComponentKeyStore creates a `ifcrypto.KeyStore`.
+	ComponentKeyStore ComponentKind = "keystore"
+	// ComponentTrustStore creates a `*x509.CertPool`.
+	ComponentTrustStore ComponentKind = "truststore"
+	// ComponentTokenIssuer creates a `TokenIssuer`.
+	ComponentTokenIssuer ComponentKind = "issuer"
+)
+
+// ComponentFactory creates a component from the _cfg_. Keystores are assembled first, hence
+// other components may use them from _a_.
+type ComponentFactory func(a *Assembly, cfg ComponentConfig) (interface{}, error)
+
+var (
+	componentsMu sync.RWMutex
+	components   = map[ComponentKind]map[string]ComponentFactory{
+		ComponentKeyStore: {
+			"memory": memoryKeyStoreFactory,
+			"file":   fileKeyStoreFactory,
+		},
+		ComponentTrustStore: {
+			"pem": pemTrustStoreFactory,
+		},
+		ComponentTokenIssuer: {
+			"fernet": symmetricTokenIssuerFactory(func(key []byte) (TokenIssuer, error) { return cryptoutils.NewFernet(key) }),
+			"branca": symmetricTokenIssuerFactory(func(key []byte) (TokenIssuer, error) { return cryptoutils.NewBranca(key) }),
+		},
+	}
+)
+
+// RegisterComponent registers, or replaces, the _factory_ of components of _kind_ and _typ_.
+// Providers outside of this package, e.g. cloud _KMS_ keystores, registers themselves when
+// imported.
+func RegisterComponent(kind ComponentKind, typ string, factory ComponentFactory) {
+
+	componentsMu.Lock()
+	defer componentsMu.Unlock()
+
+	if components[kind] == nil {
+		components[kind] = map[string]ComponentFactory{}
+	}
+
+	components[kind][typ] = factory
+
+}
+
+// FromConfig assembles the components of _cfg_.
+//
+// The keystores are assembled first, then the trust stores, rotation and token issuers.
+// Components of each kind are assembled in name order. Any error wraps `ErrInvalidConfig`
+// and names the failing component.
+func FromConfig(cfg Config) (*Assembly, error) {
+
+	a := &Assembly{
+		KeyStores:   map[string]ifcrypto.KeyStore{},
+		TrustStores: map[string]*x509.CertPool{},
+		Rotation:    map[string]KeyRetentionPolicy{},
+		Collectors:  map[string]*KeyCollector{},
+		Issuers:     map[string]TokenIssuer{},
+	}
+
+	err := assemble(a, ComponentKeyStore, cfg.KeyStores, func(name string, v interface{}) bool {
+
+		store, ok := v.(ifcrypto.KeyStore)
+		a.KeyStores[name] = store
+
+		return ok
+
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	err = assemble(a, ComponentTrustStore, cfg.TrustStores, func(name string, v interface{}) bool {
+
+		pool, ok := v.(*x509.CertPool)
+		a.TrustStores[name] = pool
+
+		return ok
+
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	for _, name := range sortedNames(cfg.Rotation) {
+
+		if err := a.rotation(name, cfg.Rotation[name]); err != nil {
+			return nil, err
+		}
+
+	}
+
+	err = assemble(a, ComponentTokenIssuer, cfg.Issuers, func(name string, v interface{}) bool {
+
+		issuer, ok := v.(TokenIssuer)
+		a.Issuers[name] = issuer
+
+		return ok
+
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return a, nil
+
+}
+
+// assemble creates the _configs_ of _kind_ in name order and hands them to _add_, that returns
+// `false` if the component is of the wrong type.
+func assemble(
+	a *Assembly,
+	kind ComponentKind,
+	configs map[string]ComponentConfig,
+	add func(name string, v interface{}) bool,
+) error {
+
+	for _, name := range sortedNames(configs) {
+
+		cfg := configs[name]
+
+		componentsMu.RLock()
+		factory, ok := components[kind][cfg.Type]
+		componentsMu.RUnlock()
+
+		if !ok {
+			return fmt.Errorf("%w: %s %s: unknown type %q", ErrInvalidConfig, kind, name, cfg.Type)
+		}
+
+		v, err := factory(a, cfg)
+		if err != nil {
+			return fmt.Errorf("%w: %s %s: %v", ErrInvalidConfig, kind, name, err)
+		}
+
+		if !add(name, v) {
+			return fmt.Errorf("%w: %s %s: type %s created a %T", ErrInvalidConfig, kind, name, cfg.Type, v)
+		}
+
+		if lifecycle, ok := v.(ifcrypto.Lifecycle); ok {
+			a.Lifecycle = append(a.Lifecycle, lifecycle)
+		}
+
+	}
+
+	return nil
+
+}
+
+// rotation assembles the rotation _name_.
+func (a *Assembly) rotation(name string, cfg RotationConfig) error {
+
+	store, ok := a.KeyStores[cfg.KeyStore]
+	if !ok {
+		return fmt.Errorf("%w: rotation %s: no keystore named %s", ErrInvalidConfig, name, cfg.KeyStore)
+	}
+
+	retention, err := time.ParseDuration(cfg.Retention)
+	if err != nil {
+		return fmt.Errorf("%w: rotation %s: %v", ErrInvalidConfig, name, err)
+	}
+
+	policy := KeyRetentionPolicy{Retention: retention, MinVersions: cfg.MinVersions}
+	a.Rotation[name] = policy
+
+	if versions, ok := store.(ifcrypto.KeyVersionStore); ok {
+		a.Collectors[name] = NewKeyCollector(versions, policy)
+	}
+
+	return nil
+
+}
+
+// sortedNames returns the keys of _m_, a map with string keys, sorted.
+func sortedNames(m interface{}) []string {
+
+	names := []string{}
+
+	switch v := m.(type) {
+	case map[string]ComponentConfig:
+		for name := range v {
+			names = append(names, name)
+		}
+	case map[string]RotationConfig:
+		for name := range v {
+			names = append(names, name)
+		}
+	}
+
+	sort.Strings(names)
+
+	return names
+
+}
+
+// ConfigSecret returns the value of the environment variable _env_, it fails if not set or
+// empty.
+func ConfigSecret(env string) ([]byte, error) {
+
+	if env == "" {
+		return nil, fmt.Errorf("no secret environment variable configured")
+	}
+
+	value := os.Getenv(env)
+	if value == "" {
+		return nil, fmt.Errorf("secret environment variable %s is not set", env)
+	}
+
+	return []byte(value), nil
+
+}
+
+// memoryKeyStoreFactory creates a empty `MemoryKeyStore`, mainly for tests.
+func memoryKeyStoreFactory(a *Assembly, cfg ComponentConfig) (interface{}, error) {
+
+	if err := cfg.Decode(&struct{}{}); err != nil {
+		return nil, err
+	}
+
+	return NewMemoryKeyStore()
+
+}
+
+// fileKeyStoreFactory creates a `FileKeyStore` protected by a base64 encoded _KEK_ or a
+// passphrase.
+func fileKeyStoreFactory(a *Assembly, cfg ComponentConfig) (interface{}, error) {
+
+	var options struct {
+		Dir           string `json:"dir"`
+		KEKEnv        string `json:"kek_env"`
+		PassphraseEnv string `json:"passphrase_env"`
+	}
+
+	if err := cfg.Decode(&options); err != nil {
+		return nil, err
+	}
+
+	if options.Dir == "" {
+		return nil, fmt.Errorf("dir is required")
+	}
+
+	if (options.KEKEnv == "") == (options.PassphraseEnv == "") {
+		return nil, fmt.Errorf("either kek_env or passphrase_env is required")
+	}
+
+	if options.PassphraseEnv != "" {
+
+		passphrase, err := ConfigSecret(options.PassphraseEnv)
+		if err != nil {
+			return nil, err
+		}
+
+		return NewFileKeyStoreWithPassphrase(options.Dir, passphrase)
+
+	}
+
+	encoded, err := ConfigSecret(options.KEKEnv)
+	if err != nil {
+		return nil, err
+	}
+
+	kek, err := base64.StdEncoding.DecodeString(string(encoded))
+	if err != nil {
+		return nil, fmt.Errorf("kek is not base64 encoded: %v", err)
+	}
+
+	return NewFileKeyStore(options.Dir, kek)
+
+}
+
+// pemTrustStoreFactory creates a `*x509.CertPool` of the certificates in _PEM_, or _PKCS #7_,
+// files, optionally on top of the system roots.
+func pemTrustStoreFactory(a *Assembly, cfg ComponentConfig) (interface{}, error) {
+
+	var options struct {
+		Files  []string `json:"files"`
+		System bool     `json:"system"`
+	}
+
+	if err := cfg.Decode(&options); err != nil {
+		return nil, err
+	}
+
+	pool := x509.NewCertPool()
+
+	if options.System {
+
+		system, err := x509.SystemCertPool()
+		if err != nil {
+			return nil, err
+		}
+
+		pool = system
+
+	}
+
+	for _, file := range options.Files {
+
+		data, err := ioutil.ReadFile(file)
+		if err != nil {
+			return nil, err
+		}
+
+		certs, err := cryptoutils.ParseCertificateBundle(data)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %v", file, err)
+		}
+
+		if len(certs) == 0 {
+			return nil, fmt.Errorf("%s has no certificates", file)
+		}
+
+		for _, cert := range certs {
+			pool.AddCert(cert)
+		}
+
+	}
+
+	return pool, nil
+
+}
+
+// symmetricTokenIssuerFactory returns a factory of `TokenIssuer` created by _create_ from a
+// symmetric key in a keystore.
+func symmetricTokenIssuerFactory(create func(key []byte) (TokenIssuer, error)) ComponentFactory {
+
+	return func(a *Assembly, cfg ComponentConfig) (interface{}, error) {
+
+		var options struct {
+			KeyStore string `json:"keystore"`
+			KeyID    string `json:"key_id"`
+		}
+
+		if err := cfg.Decode(&options); err != nil {
+			return nil, err
+		}
+
+		store, err := a.KeyStore(options.KeyStore)
+		if err != nil {
+			return nil, err
+		}
+
+		key, err := store.Get(context.Background(), options.KeyID)
+		if err != nil {
+			return nil, err
+		}
+
+		raw, ok := key.GetKey().([]byte)
+		if !ok || !key.IsSymmetric() {
+			return nil, fmt.Errorf("key %s is not a local symmetric key", options.KeyID)
+		}
+
+		return create(raw)
+
+	}
+
+}
@@ -0,0 +1,208 @@
+//go:build !verifyonly
+// +build !verifyonly
+
+package gocrypto
+
+import (
+	"context"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mariotoffia/goservice/interfaces/ifcrypto"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// versionedKeyStore is a `MemoryKeyStore` with versions and a background loop.
+type versionedKeyStore struct {
+	*MemoryKeyStore
+	*versionStore
+	*Background
+}
+
+// registerVersionedKeyStore registers the _test-versioned_ keystore until _t_ completes.
+func registerVersionedKeyStore(t *testing.T) {
+
+	RegisterComponent(ComponentKeyStore, "test-versioned", func(a *Assembly, cfg ComponentConfig) (interface{}, error) {
+
+		store, err := NewMemoryKeyStore()
+		if err != nil {
+			return nil, err
+		}
+
+		return &versionedKeyStore{
+			MemoryKeyStore: store,
+			versionStore:   &versionStore{},
+			Background:     NewBackground("test", func(stop, work context.Context) { <-stop.Done() }),
+		}, nil
+
+	})
+
+	t.Cleanup(func() {
+
+		componentsMu.Lock()
+		defer componentsMu.Unlock()
+
+		delete(components[ComponentKeyStore], "test-versioned")
+
+	})
+
+}
+
+// configFixture seeds a file keystore and a trust bundle in a temporary directory and returns
+// the directory and the _CA_ certificate.
+func configFixture(t *testing.T) (string, *x509.Certificate) {
+
+	dir := t.TempDir()
+	kek := make([]byte, 32)
+
+	require.NoError(t, os.Setenv("TEST_CONFIG_KEK", base64.StdEncoding.EncodeToString(kek)))
+	t.Cleanup(func() { os.Unsetenv("TEST_CONFIG_KEK") })
+
+	seed, err := NewFileKeyStore(filepath.Join(dir, "keys"), kek)
+	require.NoError(t, err)
+
+	session, err := NewSymmetricKey("session", 256, ifcrypto.KeyUsageEncrypt)
+	require.NoError(t, err)
+	require.NoError(t, seed.Put(context.Background(), session))
+
+	ca := testCertificate(t, "mesh ca")
+	caFile := filepath.Join(dir, "ca.pem")
+	require.NoError(t, ioutil.WriteFile(caFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: ca.Raw}), 0600))
+
+	registerVersionedKeyStore(t)
+
+	return dir, ca
+
+}
+
+// assertAssembly asserts the components configured by the _configFixture_ tests.
+func assertAssembly(t *testing.T, cfg *Config, ca *x509.Certificate) {
+
+	a, err := FromConfig(*cfg)
+	require.NoError(t, err)
+
+	assert.Len(t, a.KeyStores, 2)
+	assert.IsType(t, &FileKeyStore{}, a.KeyStores["local"])
+
+	assert.Equal(t, KeyRetentionPolicy{Retention: 90 * 24 * time.Hour}, a.Rotation["local"])
+	assert.Equal(t, KeyRetentionPolicy{Retention: 24 * time.Hour, MinVersions: 2}, a.Rotation["versioned"])
+	assert.NotContains(t, a.Collectors, "local")
+	assert.Contains(t, a.Collectors, "versioned")
+
+	_, err = ca.Verify(x509.VerifyOptions{Roots: a.TrustStores["mesh"]})
+	assert.NoError(t, err)
+
+	token, err := a.Issuers["session"].Encode([]byte("hello world"))
+	require.NoError(t, err)
+
+	payload, err := a.Issuers["session"].Decode(token, time.Minute)
+	require.NoError(t, err)
+	assert.Equal(t, "hello world", string(payload))
+
+	require.Len(t, a.Lifecycle, 1)
+	require.NoError(t, a.Lifecycle.Start(context.Background()))
+	assert.NoError(t, a.Lifecycle.Stop(context.Background()))
+
+}
+
+func TestFromConfigAssemblesComponents(t *testing.T) {
+
+	dir, ca := configFixture(t)
+
+	cfg, err := ParseConfig(strings.NewReader(`{
+		"keystores": {
+			"local": {"type": "file", "options": {"dir": "` + filepath.Join(dir, "keys") + `", "kek_env": "TEST_CONFIG_KEK"}},
+			"versioned": {"type": "test-versioned"}
+		},
+		"truststores": {"mesh": {"type": "pem", "options": {"files": ["` + filepath.Join(dir, "ca.pem") + `"]}}},
+		"rotation": {
+			"local": {"keystore": "local", "retention": "2160h"},
+			"versioned": {"keystore": "versioned", "retention": "24h", "min_versions": 2}
+		},
+		"issuers": {"session": {"type": "branca", "options": {"keystore": "local", "key_id": "session"}}}
+	}`))
+
+	require.NoError(t, err)
+	assertAssembly(t, cfg, ca)
+
+}
+
+func TestFromConfigAssemblesComponentsFromYAML(t *testing.T) {
+
+	dir, ca := configFixture(t)
+
+	cfg, err := ParseConfigYAML(strings.NewReader(`
+keystores:
+  local:
+    type: file
+    options:
+      dir: ` + filepath.Join(dir, "keys") + `
+      kek_env: TEST_CONFIG_KEK
+  versioned:
+    type: test-versioned
+truststores:
+  mesh:
+    type: pem
+    options:
+      files: [` + filepath.Join(dir, "ca.pem") + `]
+rotation:
+  local: {keystore: local, retention: 2160h}
+  versioned: {keystore: versioned, retention: 24h, min_versions: 2}
+issuers:
+  session:
+    type: branca
+    options: {keystore: local, key_id: session}
+`))
+
+	require.NoError(t, err)
+	assertAssembly(t, cfg, ca)
+
+	_, err = ParseConfigYAML(strings.NewReader("keystore: {}\n"))
+	assert.True(t, errors.Is(err, ErrInvalidConfig), "misspelled section")
+
+	_, err = ParseConfigYAML(strings.NewReader("rotation:\n  local: {keystore: local, retension: 1h}\n"))
+	assert.True(t, errors.Is(err, ErrInvalidConfig), "misspelled field")
+
+}
+
+func TestFromConfigRejectsInvalidConfig(t *testing.T) {
+
+	_, err := ParseConfig(strings.NewReader(`{"keystore": {}}`))
+	assert.True(t, errors.Is(err, ErrInvalidConfig), "misspelled section")
+
+	for name, cfg := range map[string]Config{
+		"unknown type": {KeyStores: map[string]ComponentConfig{"a": {Type: "nope"}}},
+		"unknown option": {KeyStores: map[string]ComponentConfig{
+			"a": {Type: "memory", Options: map[string]interface{}{"dir": "/tmp"}},
+		}},
+		"missing secret": {KeyStores: map[string]ComponentConfig{
+			"a": {Type: "file", Options: map[string]interface{}{"dir": t.TempDir(), "kek_env": "TEST_CONFIG_MISSING"}},
+		}},
+		"unknown keystore": {Rotation: map[string]RotationConfig{"a": {KeyStore: "b", Retention: "1h"}}},
+		"bad retention": {
+			KeyStores: map[string]ComponentConfig{"a": {Type: "memory"}},
+			Rotation:  map[string]RotationConfig{"a": {KeyStore: "a", Retention: "90 days"}},
+		},
+		"missing issuer key": {
+			KeyStores: map[string]ComponentConfig{"a": {Type: "memory"}},
+			Issuers: map[string]ComponentConfig{
+				"b": {Type: "fernet", Options: map[string]interface{}{"keystore": "a", "key_id": "missing"}},
+			},
+		},
+	} {
+
+		_, err := FromConfig(cfg)
+		assert.True(t, errors.Is(err, ErrInvalidConfig), name)
+
+	}
+
+}
@@ -0,0 +1,109 @@
+package cryptotest
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"testing"
+)
+
+// AssertSignatureValid checks that _sig_ is a valid signature of _digest_ by _pub_ and
+// reports a test error otherwise.
+//
+// For _RSA_ keys, a `*rsa.PSSOptions` _opts_ verifies a _PSS_ signature, otherwise _PKCS#1 v1.5_.
+// For _Ed25519_ keys the _digest_ is the message itself.
+func AssertSignatureValid(t testing.TB, pub crypto.PublicKey, digest, sig []byte, opts crypto.SignerOpts) bool {
+
+	t.Helper()
+
+	if err := verifySignature(pub, digest, sig, opts); err != "" {
+		t.Errorf("signature is not valid: %s", err)
+		return false
+	}
+
+	return true
+
+}
+
+// AssertSignatureInvalid is the inverse of `AssertSignatureValid`.
+func AssertSignatureInvalid(t testing.TB, pub crypto.PublicKey, digest, sig []byte, opts crypto.SignerOpts) bool {
+
+	t.Helper()
+
+	if verifySignature(pub, digest, sig, opts) == "" {
+		t.Errorf("signature is unexpectedly valid")
+		return false
+	}
+
+	return true
+
+}
+
+// AssertDecryptable checks that _decrypter_ decrypts _ciphertext_ into _expected_ and
+// reports a test error otherwise.
+func AssertDecryptable(
+	t testing.TB,
+	decrypter crypto.Decrypter,
+	ciphertext, expected []byte,
+	opts crypto.DecrypterOpts,
+) bool {
+
+	t.Helper()
+
+	plaintext, err := decrypter.Decrypt(rand.Reader, ciphertext, opts)
+	if err != nil {
+		t.Errorf("failed to decrypt: %v", err)
+		return false
+	}
+
+	if !bytes.Equal(plaintext, expected) {
+		t.Errorf("decrypted %q, expected %q", plaintext, expected)
+		return false
+	}
+
+	return true
+
+}
+
+// verifySignature returns a empty string if the signature is valid, otherwise the reason.
+func verifySignature(pub crypto.PublicKey, digest, sig []byte, opts crypto.SignerOpts) string {
+
+	switch key := pub.(type) {
+	case *rsa.PublicKey:
+
+		var err error
+
+		if pss, ok := opts.(*rsa.PSSOptions); ok {
+			err = rsa.VerifyPSS(key, pss.HashFunc(), digest, sig, pss)
+		} else {
+			err = rsa.VerifyPKCS1v15(key, opts.HashFunc(), digest, sig)
+		}
+
+		if err != nil {
+			return err.Error()
+		}
+
+	case *ecdsa.PublicKey:
+
+		if !ecdsa.VerifyASN1(key, digest, sig) {
+			return "ecdsa verification failed"
+		}
+
+	case ed25519.PublicKey:
+
+		if !ed25519.Verify(key, digest, sig) {
+			return "ed25519 verification failed"
+		}
+
+	default:
+
+		return "unsupported public key"
+
+	}
+
+	return ""
+
+}
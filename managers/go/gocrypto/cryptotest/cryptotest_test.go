@@ -0,0 +1,74 @@
+//go:build !verifyonly
+// +build !verifyonly
+
+package cryptotest
+
+import (
+	"crypto"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRecordingSignerAndAssertions(t *testing.T) {
+
+	p := NewSeededKeyProvider("cryptotest")
+
+	rsaKey := p.MustRSA("rsa", 1024)
+	ecKey, err := p.ECDSA("ec", elliptic.P256())
+	require.NoError(t, err)
+
+	ks := NewFakeKeyStore(rsaKey, ecKey)
+	assert.Equal(t, []string{"ec", "rsa"}, ks.IDs())
+
+	key, ok := ks.Get("rsa")
+	require.True(t, ok)
+	assert.Equal(t, rsaKey, key)
+
+	ks.Remove("rsa")
+	_, ok = ks.Get("rsa")
+	assert.False(t, ok)
+
+	digest := sha256.Sum256([]byte("payload"))
+
+	for _, signer := range []crypto.Signer{rsaKey, ecKey} {
+
+		recorder := NewRecordingSigner(signer)
+
+		sig, err := recorder.Sign(rand.Reader, digest[:], crypto.SHA256)
+		require.NoError(t, err)
+
+		require.Len(t, recorder.Records(), 1)
+		assert.Equal(t, digest[:], recorder.Records()[0].Digest)
+
+		AssertSignatureValid(t, signer.Public(), digest[:], sig, crypto.SHA256)
+
+		sig[len(sig)-1] ^= 1
+		AssertSignatureInvalid(t, signer.Public(), digest[:], sig, crypto.SHA256)
+
+		recorder.Err = fmt.Errorf("hsm offline")
+		_, err = recorder.Sign(rand.Reader, digest[:], crypto.SHA256)
+		assert.Error(t, err)
+		assert.Len(t, recorder.Records(), 2)
+
+		recorder.Reset()
+		assert.Empty(t, recorder.Records())
+
+	}
+
+	ciphertext, err := rsa.EncryptOAEP(sha256.New(), rand.Reader, rsaKey.Public().(*rsa.PublicKey), []byte("secret"), nil)
+	require.NoError(t, err)
+
+	AssertDecryptable(t, rsaKey, ciphertext, []byte("secret"), &rsa.OAEPOptions{Hash: crypto.SHA256})
+
+}
+
+func TestMustPanicsOnError(t *testing.T) {
+	assert.Panics(t, func() { MustNewRSAPrivateKeyFromPEM("x", []byte("not pem")) })
+}
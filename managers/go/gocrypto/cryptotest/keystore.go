@@ -0,0 +1,77 @@
+package cryptotest
+
+import (
+	"sort"
+	"sync"
+
+	"github.com/mariotoffia/goservice/interfaces/ifcrypto"
+)
+
+// FakeKeyStore is a in memory store of `ifcrypto.Key` indexed on key id.
+type FakeKeyStore struct {
+	mu   sync.RWMutex
+	keys map[string]ifcrypto.Key
+}
+
+// NewFakeKeyStore creates a new `FakeKeyStore` holding the _keys_.
+func NewFakeKeyStore(keys ...ifcrypto.Key) *FakeKeyStore {
+
+	ks := &FakeKeyStore{keys: map[string]ifcrypto.Key{}}
+
+	for _, key := range keys {
+		ks.Add(key)
+	}
+
+	return ks
+
+}
+
+// Add adds, or replaces, the _key_ under its id.
+func (ks *FakeKeyStore) Add(key ifcrypto.Key) {
+
+	ks.mu.Lock()
+	defer ks.mu.Unlock()
+
+	ks.keys[key.GetID()] = key
+
+}
+
+// Get returns the key with _id_.
+func (ks *FakeKeyStore) Get(id string) (ifcrypto.Key, bool) {
+
+	ks.mu.RLock()
+	defer ks.mu.RUnlock()
+
+	key, ok := ks.keys[id]
+
+	return key, ok
+
+}
+
+// Remove removes the key with _id_.
+func (ks *FakeKeyStore) Remove(id string) {
+
+	ks.mu.Lock()
+	defer ks.mu.Unlock()
+
+	delete(ks.keys, id)
+
+}
+
+// IDs returns the sorted ids of all keys.
+func (ks *FakeKeyStore) IDs() []string {
+
+	ks.mu.RLock()
+	defer ks.mu.RUnlock()
+
+	ids := make([]string, 0, len(ks.keys))
+
+	for id := range ks.keys {
+		ids = append(ids, id)
+	}
+
+	sort.Strings(ids)
+
+	return ids
+
+}
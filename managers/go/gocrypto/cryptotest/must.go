@@ -0,0 +1,83 @@
+package cryptotest
+
+import (
+	"encoding/pem"
+	"fmt"
+
+	"github.com/mariotoffia/goservice/interfaces/ifcrypto"
+	"github.com/mariotoffia/goservice/managers/go/gocrypto"
+)
+
+// MustNewRSAPrivateKey is `gocrypto.NewRSAPrivateKey` that panics on error.
+func MustNewRSAPrivateKey(id string, bits int, usage ...ifcrypto.KeyUsage) *gocrypto.RSAPrivateKey {
+
+	key, err := gocrypto.NewRSAPrivateKey(id, bits, usage...)
+	if err != nil {
+		panic(err)
+	}
+
+	return key
+
+}
+
+// MustNewECDSAPrivateKey is `gocrypto.NewECDSAPrivateKey` that panics on error.
+func MustNewECDSAPrivateKey(id string, bits int, usage ...ifcrypto.KeyUsage) *gocrypto.ECDSAPrivateKey {
+
+	key, err := gocrypto.NewECDSAPrivateKey(id, bits, usage...)
+	if err != nil {
+		panic(err)
+	}
+
+	return key
+
+}
+
+// MustNewRSAPrivateKeyFromPEM is `gocrypto.NewRSAPrivateKeyFromPEM` of the first _PEM_ block
+// in _data_ that panics on error.
+func MustNewRSAPrivateKeyFromPEM(id string, data []byte, usage ...ifcrypto.KeyUsage) *gocrypto.RSAPrivateKey {
+
+	key, err := gocrypto.NewRSAPrivateKeyFromPEM(mustDecodePEM(data), id, usage...)
+	if err != nil {
+		panic(err)
+	}
+
+	return key
+
+}
+
+// MustNewECDSAPrivateKeyFromPEM is `gocrypto.NewECDSAPrivateKeyFromPEM` of the first _PEM_ block
+// in _data_ that panics on error.
+func MustNewECDSAPrivateKeyFromPEM(id string, data []byte, usage ...ifcrypto.KeyUsage) *gocrypto.ECDSAPrivateKey {
+
+	key, err := gocrypto.NewECDSAPrivateKeyFromPEM(mustDecodePEM(data), id, usage...)
+	if err != nil {
+		panic(err)
+	}
+
+	return key
+
+}
+
+// MustRSA is `SeededKeyProvider.RSA` that panics on error.
+func (p *SeededKeyProvider) MustRSA(id string, bits int, usage ...ifcrypto.KeyUsage) *gocrypto.RSAPrivateKey {
+
+	key, err := p.RSA(id, bits, usage...)
+	if err != nil {
+		panic(err)
+	}
+
+	return key
+
+}
+
+// mustDecodePEM returns the first _PEM_ block of _data_.
+func mustDecodePEM(data []byte) pem.Block {
+
+	block, _ := pem.Decode(data)
+	if block == nil {
+		panic(fmt.Errorf("no PEM block found"))
+	}
+
+	return *block
+
+}
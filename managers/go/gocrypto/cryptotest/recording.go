@@ -0,0 +1,69 @@
+package cryptotest
+
+import (
+	"crypto"
+	"io"
+	"sync"
+)
+
+// SignRecord is a single call to a `RecordingSigner`.
+type SignRecord struct {
+	Digest []byte
+	Opts   crypto.SignerOpts
+}
+
+// RecordingSigner is a `crypto.Signer` that records all digests it is asked to sign.
+//
+// If _Err_ is set, it is returned instead of signing, the call is still recorded.
+type RecordingSigner struct {
+	Signer crypto.Signer
+	Err    error
+	mu     sync.Mutex
+	calls  []SignRecord
+}
+
+// NewRecordingSigner creates a `RecordingSigner` that signs using _signer_.
+func NewRecordingSigner(signer crypto.Signer) *RecordingSigner {
+	return &RecordingSigner{Signer: signer}
+}
+
+// Public implements the `crypto.Signer` interface.
+func (r *RecordingSigner) Public() crypto.PublicKey {
+	return r.Signer.Public()
+}
+
+// Sign implements the `crypto.Signer` interface.
+func (r *RecordingSigner) Sign(rand io.Reader, digest []byte, opts crypto.SignerOpts) ([]byte, error) {
+
+	r.mu.Lock()
+	r.calls = append(r.calls, SignRecord{Digest: append([]byte{}, digest...), Opts: opts})
+	err := r.Err
+	r.mu.Unlock()
+
+	if err != nil {
+		return nil, err
+	}
+
+	return r.Signer.Sign(rand, digest, opts)
+
+}
+
+// Records returns a copy of all recorded calls, in order.
+func (r *RecordingSigner) Records() []SignRecord {
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return append([]SignRecord{}, r.calls...)
+
+}
+
+// Reset clears the recorded calls.
+func (r *RecordingSigner) Reset() {
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.calls = nil
+
+}
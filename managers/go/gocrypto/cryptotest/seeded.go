@@ -0,0 +1,235 @@
+// Package cryptotest contains test support for code that uses the `ifcrypto` interfaces.
+//
+// WARNING: Nothing in this package is safe for production use.
+package cryptotest
+
+import (
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/hmac"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math/big"
+	"sync"
+
+	"github.com/mariotoffia/goservice/interfaces/ifcrypto"
+	"github.com/mariotoffia/goservice/managers/go/gocrypto"
+)
+
+// SeededKeyProvider generates reproducible keys from a seed.
+//
+// The same seed, key id and parameters always gives the same key, so tests get stable
+// fixtures without committing _PEM_ files. Different key ids gives unrelated keys.
+//
+// The keys are derived manually from a _HMAC-SHA256_ stream since the standard library key
+// generation ignores the random source. UNSAFE: the keys are only as secret as the seed,
+// which normally is checked in with the tests. NEVER use them outside of tests.
+type SeededKeyProvider struct {
+	seed  []byte
+	mu    sync.Mutex
+	cache map[string]*rsa.PrivateKey
+}
+
+// NewSeededKeyProvider creates a new `SeededKeyProvider` for the _seed_.
+func NewSeededKeyProvider(seed string) *SeededKeyProvider {
+	return &SeededKeyProvider{seed: []byte(seed), cache: map[string]*rsa.PrivateKey{}}
+}
+
+// Reader returns a deterministic stream of bytes for the _label_.
+func (p *SeededKeyProvider) Reader(label string) io.Reader {
+	return &seededReader{key: p.seed, label: []byte(label)}
+}
+
+// RSA returns the _RSA_ key with _id_ and _bits_ size. Generated keys are cached by the
+// provider since generating them is slow.
+func (p *SeededKeyProvider) RSA(id string, bits int, usage ...ifcrypto.KeyUsage) (*gocrypto.RSAPrivateKey, error) {
+
+	if bits < 1024 || bits%16 != 0 {
+		return nil, fmt.Errorf("RSA key size must be a multiple of 16 and at least 1024, got: %d", bits)
+	}
+
+	label := fmt.Sprintf("rsa/%d/%s", bits, id)
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	key, ok := p.cache[label]
+
+	if !ok {
+
+		var err error
+
+		if key, err = generateRSA(p.Reader(label), bits); err != nil {
+			return nil, err
+		}
+
+		p.cache[label] = key
+
+	}
+
+	return gocrypto.NewRSAPrivateKeyFromKey(id, key, usage...), nil
+
+}
+
+// ECDSA returns the _ECDSA_ key with _id_ on the _curve_.
+func (p *SeededKeyProvider) ECDSA(id string, curve elliptic.Curve, usage ...ifcrypto.KeyUsage) (*gocrypto.ECDSAPrivateKey, error) {
+
+	params := curve.Params()
+
+	// Read 64 extra bits to make the modulo bias negligible
+	buf := make([]byte, (params.N.BitLen()+7)/8+8)
+
+	if _, err := io.ReadFull(p.Reader("ecdsa/"+params.Name+"/"+id), buf); err != nil {
+		return nil, err
+	}
+
+	nMinusOne := new(big.Int).Sub(params.N, big.NewInt(1))
+
+	d := new(big.Int).SetBytes(buf)
+	d.Mod(d, nMinusOne)
+	d.Add(d, big.NewInt(1))
+
+	key := &ecdsa.PrivateKey{D: d}
+	key.Curve = curve
+	key.X, key.Y = curve.ScalarBaseMult(d.FillBytes(make([]byte, (params.N.BitLen()+7)/8)))
+
+	return gocrypto.NewECDSAPrivateKeyFromKey(id, key, usage...), nil
+
+}
+
+// Ed25519 returns the _Ed25519_ key with _id_.
+func (p *SeededKeyProvider) Ed25519(id string) ed25519.PrivateKey {
+
+	seed := make([]byte, ed25519.SeedSize)
+
+	_, _ = io.ReadFull(p.Reader("ed25519/"+id), seed)
+
+	return ed25519.NewKeyFromSeed(seed)
+
+}
+
+// generateRSA generates a _RSA_ key with public exponent 65537 reading candidate primes from _r_.
+func generateRSA(r io.Reader, bits int) (*rsa.PrivateKey, error) {
+
+	e := big.NewInt(65537)
+	one := big.NewInt(1)
+
+	var primes [2]*big.Int
+
+	for i := 0; i < 2; i++ {
+
+		for {
+
+			p, err := candidatePrime(r, bits/2)
+			if err != nil {
+				return nil, err
+			}
+
+			pMinusOne := new(big.Int).Sub(p, one)
+
+			if new(big.Int).Mod(pMinusOne, e).Sign() == 0 {
+				continue
+			}
+
+			if i == 1 && p.Cmp(primes[0]) == 0 {
+				continue
+			}
+
+			if p.ProbablyPrime(20) {
+				primes[i] = p
+				break
+			}
+
+		}
+
+	}
+
+	phi := new(big.Int).Mul(new(big.Int).Sub(primes[0], one), new(big.Int).Sub(primes[1], one))
+
+	key := &rsa.PrivateKey{
+		PublicKey: rsa.PublicKey{N: new(big.Int).Mul(primes[0], primes[1]), E: int(e.Int64())},
+		D:         new(big.Int).ModInverse(e, phi),
+		Primes:    primes[:],
+	}
+
+	if err := key.Validate(); err != nil {
+		return nil, err
+	}
+
+	key.Precompute()
+
+	return key, nil
+
+}
+
+// candidatePrime reads a odd _bits_ size number with the two top bits set, so that the
+// product of two such numbers has exactly _2 * bits_ bits.
+func candidatePrime(r io.Reader, bits int) (*big.Int, error) {
+
+	buf := make([]byte, (bits+7)/8)
+
+	if _, err := io.ReadFull(r, buf); err != nil {
+		return nil, err
+	}
+
+	if extra := len(buf)*8 - bits; extra > 0 {
+		buf[0] &= 0xFF >> extra
+	}
+
+	top := uint(bits-1) % 8
+	buf[0] |= 1 << top
+
+	if top == 0 {
+		buf[1] |= 0x80
+	} else {
+		buf[0] |= 1 << (top - 1)
+	}
+
+	buf[len(buf)-1] |= 1
+
+	return new(big.Int).SetBytes(buf), nil
+
+}
+
+// seededReader is a _HMAC-SHA256_ counter mode byte stream.
+type seededReader struct {
+	key     []byte
+	label   []byte
+	counter uint64
+	buf     []byte
+}
+
+// Read implements the `io.Reader` interface.
+func (r *seededReader) Read(p []byte) (int, error) {
+
+	n := 0
+
+	for n < len(p) {
+
+		if len(r.buf) == 0 {
+
+			var counter [8]byte
+			binary.BigEndian.PutUint64(counter[:], r.counter)
+			r.counter++
+
+			h := hmac.New(sha256.New, r.key)
+			h.Write(r.label)
+			h.Write(counter[:])
+
+			r.buf = h.Sum(nil)
+
+		}
+
+		c := copy(p[n:], r.buf)
+		r.buf = r.buf[c:]
+		n += c
+
+	}
+
+	return n, nil
+
+}
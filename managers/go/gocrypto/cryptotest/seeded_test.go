@@ -0,0 +1,64 @@
+package cryptotest
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSeededKeysAreReproducible(t *testing.T) {
+
+	p1 := NewSeededKeyProvider("fixture")
+	p2 := NewSeededKeyProvider("fixture")
+
+	r1, err := p1.RSA("signer", 1024)
+	require.NoError(t, err)
+
+	r2, err := p2.RSA("signer", 1024)
+	require.NoError(t, err)
+
+	other, err := p1.RSA("other", 1024)
+	require.NoError(t, err)
+
+	k1 := r1.GetKey().(*rsa.PrivateKey)
+	assert.Equal(t, 1024, k1.N.BitLen())
+	assert.Equal(t, 0, k1.N.Cmp(r2.GetKey().(*rsa.PrivateKey).N))
+	assert.NotEqual(t, 0, k1.N.Cmp(other.GetKey().(*rsa.PrivateKey).N))
+
+	digest := sha256.Sum256([]byte("message"))
+
+	sig, err := k1.Sign(rand.Reader, digest[:], crypto.SHA256)
+	require.NoError(t, err)
+	assert.NoError(t, rsa.VerifyPKCS1v15(&k1.PublicKey, crypto.SHA256, digest[:], sig))
+
+	for _, curve := range []elliptic.Curve{elliptic.P256(), elliptic.P384(), elliptic.P521()} {
+
+		e1, err := p1.ECDSA("signer", curve)
+		require.NoError(t, err)
+
+		e2, err := p2.ECDSA("signer", curve)
+		require.NoError(t, err)
+
+		ek := e1.GetKey().(*ecdsa.PrivateKey)
+		assert.Equal(t, 0, ek.D.Cmp(e2.GetKey().(*ecdsa.PrivateKey).D))
+		assert.True(t, curve.IsOnCurve(ek.X, ek.Y))
+
+		sig, err := ecdsa.SignASN1(rand.Reader, ek, digest[:])
+		require.NoError(t, err)
+		assert.True(t, ecdsa.VerifyASN1(&ek.PublicKey, digest[:], sig))
+
+	}
+
+	assert.Equal(t, p1.Ed25519("a"), p2.Ed25519("a"))
+	assert.NotEqual(t, p1.Ed25519("a"), p1.Ed25519("b"))
+	assert.Len(t, p1.Ed25519("a"), ed25519.PrivateKeySize)
+
+}
@@ -0,0 +1,70 @@
+package gocrypto
+
+import (
+	"bytes"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/pem"
+	"fmt"
+	"io"
+
+	"github.com/mariotoffia/goservice/interfaces/ifcrypto"
+	"github.com/mariotoffia/goservice/utils/cryptoutils"
+)
+
+// privateKeyDERWrite writes the _PKCS #8_ _DER_ of the private key of _writer_ onto _w_.
+func privateKeyDERWrite(w io.Writer, writer ifcrypto.PEMWriter) error {
+
+	var buf bytes.Buffer
+
+	if err := writer.PEMWrite(&buf, false); err != nil {
+		return err
+	}
+
+	block, _ := pem.Decode(buf.Bytes())
+	if block == nil {
+		return fmt.Errorf("key did not encode to PEM")
+	}
+
+	der, err := cryptoutils.PrivateKeyBlockToPKCS8(block)
+	if err != nil {
+		return err
+	}
+
+	_, err = w.Write(der)
+
+	return err
+
+}
+
+// publicKeyDERWrite writes the _PKIX_ _DER_ of _key_ onto _w_.
+func publicKeyDERWrite(w io.Writer, key ifcrypto.Key) error {
+
+	der, err := publicKeyDER(key)
+	if err != nil {
+		return err
+	}
+
+	_, err = w.Write(der)
+
+	return err
+
+}
+
+// privateKeyDERBlock returns _der_ as a _PRIVATE KEY_ block if it is _PKCS #8_, otherwise as a
+// _legacy_ block, e.g. _RSA PRIVATE KEY_.
+func privateKeyDERBlock(der []byte, legacy string) pem.Block {
+
+	var info struct {
+		Version    int
+		Algorithm  pkix.AlgorithmIdentifier
+		PrivateKey []byte
+	}
+
+	if rest, err := asn1.Unmarshal(der, &info); err == nil && len(rest) == 0 {
+		return pem.Block{Type: "PRIVATE KEY", Bytes: der}
+	}
+
+	return pem.Block{Type: legacy, Bytes: der}
+
+}
@@ -0,0 +1,113 @@
+//go:build !verifyonly
+// +build !verifyonly
+
+package gocrypto
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/rsa"
+	"crypto/x509"
+	"testing"
+
+	"github.com/mariotoffia/goservice/interfaces/ifcrypto"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func derOf(t *testing.T, key ifcrypto.Key) []byte {
+
+	var buf bytes.Buffer
+	require.NoError(t, key.(ifcrypto.DERWriter).DERWrite(&buf))
+
+	return buf.Bytes()
+
+}
+
+func TestDERRoundTripAllKeyTypes(t *testing.T) {
+
+	sign := ifcrypto.KeyUsageSign
+
+	rsaKey, err := NewRSAPrivateKey("rsa", 2048, sign)
+	require.NoError(t, err)
+
+	ecKey, err := NewECDSAPrivateKey("ec", 256, sign)
+	require.NoError(t, err)
+
+	edKey, err := NewED25519PrivateKey("ed", sign)
+	require.NoError(t, err)
+
+	k1Key, err := NewSecp256k1PrivateKey("k1", sign)
+	require.NoError(t, err)
+
+	xKey, err := NewX25519PrivateKey("x", ifcrypto.KeyUsageKeyAgreement)
+	require.NoError(t, err)
+
+	// Standard private keys are written as PKCS #8 that x509 can parse.
+	for _, key := range []ifcrypto.KeyPair{rsaKey, ecKey, edKey} {
+
+		_, err := x509.ParsePKCS8PrivateKey(derOf(t, key))
+		require.NoError(t, err)
+
+		_, err = x509.ParsePKIXPublicKey(derOf(t, key.GetPublic()))
+		require.NoError(t, err)
+
+	}
+
+	loaders := []struct {
+		key     ifcrypto.KeyPair
+		private func(der []byte) (ifcrypto.KeyPair, error)
+		public  func(der []byte) (ifcrypto.PublicKey, error)
+	}{
+		{
+			rsaKey,
+			func(der []byte) (ifcrypto.KeyPair, error) { return NewRSAPrivateKeyFromDER(der, "rsa") },
+			func(der []byte) (ifcrypto.PublicKey, error) { return NewRSAPublicKeyFromDER(der, "rsa") },
+		},
+		{
+			ecKey,
+			func(der []byte) (ifcrypto.KeyPair, error) { return NewECDSAPrivateKeyFromDER(der, "ec") },
+			func(der []byte) (ifcrypto.PublicKey, error) { return NewECDSAPublicKeyFromDER(der, "ec") },
+		},
+		{
+			edKey,
+			func(der []byte) (ifcrypto.KeyPair, error) { return NewED25519PrivateKeyFromDER(der, "ed") },
+			func(der []byte) (ifcrypto.PublicKey, error) { return NewED25519PublicKeyFromDER(der, "ed") },
+		},
+		{
+			k1Key,
+			func(der []byte) (ifcrypto.KeyPair, error) { return NewSecp256k1PrivateKeyFromDER(der, "k1") },
+			func(der []byte) (ifcrypto.PublicKey, error) { return NewSecp256k1PublicKeyFromDER(der, "k1") },
+		},
+		{
+			xKey,
+			func(der []byte) (ifcrypto.KeyPair, error) { return NewX25519PrivateKeyFromDER(der, "x") },
+			func(der []byte) (ifcrypto.PublicKey, error) { return NewX25519PublicKeyFromDER(der, "x") },
+		},
+	}
+
+	for _, loader := range loaders {
+
+		private, err := loader.private(derOf(t, loader.key))
+		require.NoError(t, err, loader.key.GetID())
+		assert.Equal(t, loader.key.GetPublic().GetKey(), private.GetPublic().GetKey())
+
+		public, err := loader.public(derOf(t, loader.key.GetPublic()))
+		require.NoError(t, err, loader.key.GetID())
+		assert.Equal(t, loader.key.GetPublic().GetKey(), public.GetKey())
+
+	}
+
+	// Legacy PKCS #1 and SEC 1 DER is accepted as well.
+	rsaPKCS1, err := NewRSAPrivateKeyFromDER(x509.MarshalPKCS1PrivateKey(rsaKey.GetKey().(*rsa.PrivateKey)), "rsa")
+	require.NoError(t, err)
+	assert.Equal(t, rsaKey.GetPublic().GetKey(), rsaPKCS1.GetPublic().GetKey())
+
+	hmacKey, err := NewHMACKey("hmac", crypto.SHA256, sign)
+	require.NoError(t, err)
+
+	loaded, err := NewHMACKeyFromBytes("hmac", derOf(t, hmacKey), crypto.SHA256, sign)
+	require.NoError(t, err)
+	assert.Equal(t, hmacKey.GetKey(), loaded.GetKey())
+
+}
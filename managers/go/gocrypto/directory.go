@@ -0,0 +1,547 @@
+package gocrypto
+
+import (
+	"bytes"
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mariotoffia/goservice/interfaces/ifcrypto"
+	"github.com/mariotoffia/goservice/utils/cryptoutils"
+)
+
+var (
+	// ErrDirectorySignature is returned when a directory entry is not signed by a trusted authority.
+	ErrDirectorySignature = fmt.Errorf("invalid directory entry signature")
+	// ErrDirectoryRollback is returned when a source returns a older version than already seen.
+	ErrDirectoryRollback = fmt.Errorf("directory entry version rollback")
+)
+
+// DirectoryKey is a public key of a service in a `DirectoryEntry`.
+type DirectoryKey struct {
+	KeyID string              `json:"key_id"`
+	Usage []ifcrypto.KeyUsage `json:"usage"`
+	// PublicKey is the _DER_ encoded _PKIX_ public key.
+	PublicKey []byte `json:"public_key"`
+}
+
+// DirectoryEntry is the current keys and certificates of a service.
+type DirectoryEntry struct {
+	Service string `json:"service"`
+	// Version must increase with each update of the entry.
+	Version uint64 `json:"version"`
+	// Expires is when the entry must no longer be used, even if no newer entry is available.
+	Expires time.Time      `json:"expires"`
+	Keys    []DirectoryKey `json:"keys"`
+	// Certificates is _DER_ encoded certificates, leaf first.
+	Certificates [][]byte `json:"certificates,omitempty"`
+}
+
+// SignedDirectoryEntry is a `DirectoryEntry` signed by a directory authority.
+type SignedDirectoryEntry struct {
+	// Entry is the _JSON_ encoded `DirectoryEntry` as signed.
+	Entry     json.RawMessage `json:"entry"`
+	SignerID  string          `json:"signer_id"`
+	Signature []byte          `json:"signature"`
+}
+
+// DirectorySource fetches signed directory entries, e.g. from a keystore, a _HTTP_ endpoint
+// or _DNS_.
+type DirectorySource interface {
+	// Fetch returns the current entry of _service_ or `ifcrypto.ErrServiceKeyNotFound`.
+	Fetch(c context.Context, service string) (*SignedDirectoryEntry, error)
+}
+
+// SignDirectoryEntry signs the _entry_ with the authority _signer_ identified by _signerID_.
+func SignDirectoryEntry(entry *DirectoryEntry, signerID string, signer crypto.Signer) (*SignedDirectoryEntry, error) {
+
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return nil, err
+	}
+
+	digest := sha256.Sum256(data)
+
+	signature, err := signDigest(signer, digest[:])
+	if err != nil {
+		return nil, err
+	}
+
+	return &SignedDirectoryEntry{Entry: data, SignerID: signerID, Signature: signature}, nil
+
+}
+
+// NewDirectoryKey creates a `DirectoryKey` from the _key_ and its usage.
+func NewDirectoryKey(key ifcrypto.PublicKey) (DirectoryKey, error) {
+
+	der, err := publicKeyDER(key)
+	if err != nil {
+		return DirectoryKey{}, err
+	}
+
+	return DirectoryKey{KeyID: key.GetID(), Usage: key.GetKeyUsage(), PublicKey: der}, nil
+
+}
+
+// ServiceDirectoryOption configures a `ServiceDirectory`.
+type ServiceDirectoryOption func(d *ServiceDirectory)
+
+// WithDirectoryAuthority adds a trusted authority key, identified by _id_, that signs entries.
+func WithDirectoryAuthority(id string, key crypto.PublicKey) ServiceDirectoryOption {
+	return func(d *ServiceDirectory) {
+		d.authorities[id] = key
+	}
+}
+
+// WithDirectoryTTL sets how long a fetched entry is cached before it is refreshed, default five minutes.
+func WithDirectoryTTL(ttl time.Duration) ServiceDirectoryOption {
+	return func(d *ServiceDirectory) {
+		d.ttl = ttl
+	}
+}
+
+// WithDirectoryRefreshInterval sets the interval that cached entries are refreshed when started,
+// see `ServiceDirectory.Start` (default half the _TTL_).
+func WithDirectoryRefreshInterval(interval time.Duration) ServiceDirectoryOption {
+	return func(d *ServiceDirectory) {
+		d.refresh = interval
+	}
+}
+
+// WithDirectoryClock sets the clock used for caching and expiry, default `time.Now`.
+func WithDirectoryClock(clock func() time.Time) ServiceDirectoryOption {
+	return func(d *ServiceDirectory) {
+		d.clock = clock
+	}
+}
+
+// directoryCacheEntry is a verified and parsed entry.
+type directoryCacheEntry struct {
+	entry   DirectoryEntry
+	keys    []ifcrypto.PublicKey
+	certs   []*x509.Certificate
+	fetched time.Time
+}
+
+// ServiceDirectory maps service names to their current public keys and certificates.
+//
+// Entries are fetched from a `DirectorySource`, verified against the trusted authorities
+// and cached. A entry with a lower version than already seen is rejected. If a refresh
+// fails, the cached entry is used until it expires.
+//
+// When started, see `Start`, the cached entries are refreshed in the background such that
+// lookups do not wait for the source.
+type ServiceDirectory struct {
+	source      DirectorySource
+	authorities map[string]crypto.PublicKey
+	ttl         time.Duration
+	refresh     time.Duration
+	clock       func() time.Time
+	cache       map[string]*directoryCacheEntry
+	background  *Background
+	mu          sync.Mutex
+}
+
+// NewServiceDirectory creates a new `ServiceDirectory` that fetches entries from _source_.
+//
+// At least one authority must be added using `WithDirectoryAuthority`.
+func NewServiceDirectory(source DirectorySource, opts ...ServiceDirectoryOption) (*ServiceDirectory, error) {
+
+	d := &ServiceDirectory{
+		source:      source,
+		authorities: map[string]crypto.PublicKey{},
+		ttl:         5 * time.Minute,
+		clock:       time.Now,
+		cache:       map[string]*directoryCacheEntry{},
+	}
+
+	for _, opt := range opts {
+		opt(d)
+	}
+
+	if len(d.authorities) == 0 {
+		return nil, fmt.Errorf("service directory requires at least one authority")
+	}
+
+	if d.refresh <= 0 {
+		d.refresh = d.ttl / 2
+	}
+
+	d.background = NewTickerBackground("service directory", d.refresh, func(c context.Context) {
+		_ = d.Refresh(c)
+	})
+
+	return d, nil
+
+}
+
+// Lookup implements the `ifcrypto.PublicKeyDirectory` interface.
+//
+// If several keys have the _usage_, the first in the entry is returned.
+func (d *ServiceDirectory) Lookup(c context.Context, service string, usage ifcrypto.KeyUsage) (ifcrypto.PublicKey, error) {
+
+	cached, err := d.get(c, service)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, key := range cached.keys {
+
+		for _, u := range key.GetKeyUsage() {
+
+			if u == usage {
+				return key, nil
+			}
+
+		}
+
+	}
+
+	return nil, fmt.Errorf("%w: %s has no %s key", ifcrypto.ErrServiceKeyNotFound, service, usage)
+
+}
+
+// Certificates returns the certificates of _service_, leaf first.
+func (d *ServiceDirectory) Certificates(c context.Context, service string) ([]*x509.Certificate, error) {
+
+	cached, err := d.get(c, service)
+	if err != nil {
+		return nil, err
+	}
+
+	return cached.certs, nil
+
+}
+
+// Invalidate marks the cached entry of _service_ as stale, forcing a fetch on next lookup.
+//
+// The highest seen version is kept, hence rollback protection remains.
+func (d *ServiceDirectory) Invalidate(service string) {
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if cached, ok := d.cache[service]; ok {
+		cached.fetched = time.Time{}
+	}
+
+}
+
+// Refresh fetches all cached entries. A entry that fails to refresh is kept, and used until
+// it expires, the first error is returned.
+func (d *ServiceDirectory) Refresh(c context.Context) error {
+
+	d.mu.Lock()
+
+	services := make([]string, 0, len(d.cache))
+	for service := range d.cache {
+		services = append(services, service)
+	}
+
+	d.mu.Unlock()
+
+	var first error
+
+	for _, service := range services {
+
+		d.Invalidate(service)
+
+		if _, err := d.get(c, service); err != nil && first == nil {
+			first = err
+		}
+
+	}
+
+	return first
+
+}
+
+// Start implements the `ifcrypto.Lifecycle` interface, it refreshes the cached entries
+// periodically, see `WithDirectoryRefreshInterval`, until stopped.
+func (d *ServiceDirectory) Start(c context.Context) error {
+	return d.background.Start(c)
+}
+
+// Stop implements the `ifcrypto.Lifecycle` interface. A refresh in-flight is completed.
+func (d *ServiceDirectory) Stop(c context.Context) error {
+	return d.background.Stop(c)
+}
+
+// get returns the cached entry of _service_, refreshing it if needed.
+func (d *ServiceDirectory) get(c context.Context, service string) (*directoryCacheEntry, error) {
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	now := d.clock()
+	cached := d.cache[service]
+
+	if cached != nil && now.Sub(cached.fetched) < d.ttl && now.Before(cached.entry.Expires) {
+		return cached, nil
+	}
+
+	fresh, err := d.fetch(c, service, cached)
+
+	if err != nil {
+
+		// Serve the stale, but not expired, entry when the source is unavailable
+		if cached != nil && now.Before(cached.entry.Expires) && !isDirectoryTrustError(err) {
+			return cached, nil
+		}
+
+		return nil, err
+
+	}
+
+	d.cache[service] = fresh
+
+	return fresh, nil
+
+}
+
+// fetch fetches, verifies and parses the entry of _service_.
+func (d *ServiceDirectory) fetch(
+	c context.Context,
+	service string,
+	cached *directoryCacheEntry,
+) (*directoryCacheEntry, error) {
+
+	signed, err := d.source.Fetch(c, service)
+	if err != nil {
+		return nil, err
+	}
+
+	authority, ok := d.authorities[signed.SignerID]
+	if !ok {
+		return nil, fmt.Errorf("%w: unknown authority %s", ErrDirectorySignature, signed.SignerID)
+	}
+
+	digest := sha256.Sum256(signed.Entry)
+
+	if !verifyDigest(authority, digest[:], signed.Signature) {
+		return nil, fmt.Errorf("%w: %s signed by %s", ErrDirectorySignature, service, signed.SignerID)
+	}
+
+	fresh := &directoryCacheEntry{fetched: d.clock()}
+
+	if err := json.Unmarshal(signed.Entry, &fresh.entry); err != nil {
+		return nil, err
+	}
+
+	if fresh.entry.Service != service {
+		return nil, fmt.Errorf("%w: entry is for %s not %s", ErrDirectorySignature, fresh.entry.Service, service)
+	}
+
+	if cached != nil && fresh.entry.Version < cached.entry.Version {
+		return nil, fmt.Errorf(
+			"%w: %s version %d is older than %d",
+			ErrDirectoryRollback, service, fresh.entry.Version, cached.entry.Version,
+		)
+	}
+
+	if !fresh.fetched.Before(fresh.entry.Expires) {
+		return nil, fmt.Errorf("directory entry of %s expired at %s", service, fresh.entry.Expires)
+	}
+
+	for _, k := range fresh.entry.Keys {
+
+		key, err := publicKeyFromDER(k.KeyID, k.PublicKey, k.Usage...)
+		if err != nil {
+			return nil, fmt.Errorf("%s key %s: %w", service, k.KeyID, err)
+		}
+
+		fresh.keys = append(fresh.keys, key)
+
+	}
+
+	for _, der := range fresh.entry.Certificates {
+
+		cert, err := x509.ParseCertificate(der)
+		if err != nil {
+			return nil, err
+		}
+
+		fresh.certs = append(fresh.certs, cert)
+
+	}
+
+	return fresh, nil
+
+}
+
+// isDirectoryTrustError returns `true` if _err_ means the source returned untrusted data, in
+// which case the stale entry must not hide the error.
+func isDirectoryTrustError(err error) bool {
+	return errors.Is(err, ErrDirectorySignature) || errors.Is(err, ErrDirectoryRollback)
+}
+
+// MemoryDirectorySource is a in memory `DirectorySource`, e.g. populated from the keystore
+// of the authority or in tests.
+type MemoryDirectorySource struct {
+	mu      sync.RWMutex
+	entries map[string]*SignedDirectoryEntry
+}
+
+// NewMemoryDirectorySource creates a new empty `MemoryDirectorySource`.
+func NewMemoryDirectorySource() *MemoryDirectorySource {
+	return &MemoryDirectorySource{entries: map[string]*SignedDirectoryEntry{}}
+}
+
+// Publish sets the signed entry of _service_.
+func (m *MemoryDirectorySource) Publish(service string, entry *SignedDirectoryEntry) {
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.entries[service] = entry
+
+}
+
+// Fetch implements the `DirectorySource` interface.
+func (m *MemoryDirectorySource) Fetch(c context.Context, service string) (*SignedDirectoryEntry, error) {
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	if entry, ok := m.entries[service]; ok {
+		return entry, nil
+	}
+
+	return nil, fmt.Errorf("%w: %s", ifcrypto.ErrServiceKeyNotFound, service)
+
+}
+
+// HTTPDirectorySourceOption configures a `HTTPDirectorySource`.
+type HTTPDirectorySourceOption func(s *HTTPDirectorySource)
+
+// WithDirectoryHTTPClient sets the _HTTP_ client, default `http.DefaultClient`.
+func WithDirectoryHTTPClient(client *http.Client) HTTPDirectorySourceOption {
+	return func(s *HTTPDirectorySource) {
+		s.client = client
+	}
+}
+
+// HTTPDirectorySource fetches `SignedDirectoryEntry` documents over _HTTP_.
+type HTTPDirectorySource struct {
+	endpoint string
+	client   *http.Client
+}
+
+// NewHTTPDirectorySource creates a source that fetches the entry of a service from _endpoint_
+// where _{service}_ is replaced by the path escaped service name, e.g.
+// _https://directory.example.com/services/{service}_.
+func NewHTTPDirectorySource(endpoint string, opts ...HTTPDirectorySourceOption) *HTTPDirectorySource {
+
+	s := &HTTPDirectorySource{endpoint: endpoint, client: http.DefaultClient}
+
+	for _, opt := range opts {
+		opt(s)
+	}
+
+	return s
+
+}
+
+// Fetch implements the `DirectorySource` interface.
+func (s *HTTPDirectorySource) Fetch(c context.Context, service string) (*SignedDirectoryEntry, error) {
+
+	u := strings.Replace(s.endpoint, "{service}", url.PathEscape(service), -1)
+
+	req, err := http.NewRequestWithContext(c, http.MethodGet, u, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, fmt.Errorf("%w: %s", ifcrypto.ErrServiceKeyNotFound, service)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("directory %s returned status %d", u, resp.StatusCode)
+	}
+
+	var entry SignedDirectoryEntry
+
+	if err := json.NewDecoder(resp.Body).Decode(&entry); err != nil {
+		return nil, err
+	}
+
+	return &entry, nil
+
+}
+
+// publicKeyDER returns the _DER_ encoded _PKIX_ public key of _key_.
+func publicKeyDER(key ifcrypto.Key) ([]byte, error) {
+
+	writer, ok := key.(ifcrypto.PEMWriter)
+	if !ok {
+		return nil, fmt.Errorf("key can not be encoded: %T", key)
+	}
+
+	var buf bytes.Buffer
+
+	if err := writer.PEMWrite(&buf, false); err != nil {
+		return nil, err
+	}
+
+	block, _ := pem.Decode(buf.Bytes())
+	if block == nil {
+		return nil, fmt.Errorf("key did not encode to PEM")
+	}
+
+	return block.Bytes, nil
+
+}
+
+// publicKeyFromDER parses a _DER_ encoded _PKIX_ public key into the matching `ifcrypto.PublicKey`.
+func publicKeyFromDER(id string, der []byte, usage ...ifcrypto.KeyUsage) (ifcrypto.PublicKey, error) {
+
+	if key, err := cryptoutils.ParseX25519PKIXPublicKey(der); err == nil {
+		return NewX25519PublicKeyFromKey(id, key, usage...)
+	}
+
+	if key, err := cryptoutils.ParseSecp256k1PublicKey(der); err == nil {
+		return NewSecp256k1PublicKeyFromKey(id, key, usage...)
+	}
+
+	key, err := x509.ParsePKIXPublicKey(der)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := checkLoadedKey(key); err != nil {
+		return nil, err
+	}
+
+	switch k := key.(type) {
+	case *rsa.PublicKey:
+		return NewRSAPublicKeyFromKey(id, k, usage...), nil
+	case *ecdsa.PublicKey:
+		return NewECDSAPublicKeyFromKey(id, k, usage...), nil
+	case ed25519.PublicKey:
+		return NewED25519PublicKeyFromKey(id, k, usage...), nil
+	}
+
+	return nil, fmt.Errorf("unsupported public key: %T", key)
+
+}
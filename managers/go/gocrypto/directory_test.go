@@ -0,0 +1,158 @@
+//go:build !verifyonly
+// +build !verifyonly
+
+package gocrypto
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/mariotoffia/goservice/interfaces/ifcrypto"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type failingDirectorySource struct {
+	DirectorySource
+	fail bool
+}
+
+func (s *failingDirectorySource) Fetch(c context.Context, service string) (*SignedDirectoryEntry, error) {
+
+	if s.fail {
+		return nil, errors.New("source unavailable")
+	}
+
+	return s.DirectorySource.Fetch(c, service)
+
+}
+
+func TestServiceDirectoryLookup(t *testing.T) {
+
+	authority, err := NewED25519PrivateKey("authority", ifcrypto.KeyUsageSign)
+	require.NoError(t, err)
+
+	signKey, err := NewECDSAPrivateKey("billing-sign", 256, ifcrypto.KeyUsageVerify)
+	require.NoError(t, err)
+
+	agreeKey, err := NewX25519PrivateKey("billing-x25519", ifcrypto.KeyUsageKeyAgreement)
+	require.NoError(t, err)
+
+	now := time.Date(2026, 10, 1, 12, 0, 0, 0, time.UTC)
+
+	publish := func(source *MemoryDirectorySource, version uint64, keys ...ifcrypto.KeyPair) {
+
+		entry := &DirectoryEntry{Service: "billing", Version: version, Expires: now.Add(time.Hour)}
+
+		for _, key := range keys {
+
+			dk, err := NewDirectoryKey(key.GetPublic())
+			require.NoError(t, err)
+
+			entry.Keys = append(entry.Keys, dk)
+
+		}
+
+		signed, err := SignDirectoryEntry(entry, "authority", authority)
+		require.NoError(t, err)
+
+		source.Publish("billing", signed)
+
+	}
+
+	memory := NewMemoryDirectorySource()
+	publish(memory, 2, signKey, agreeKey)
+
+	source := &failingDirectorySource{DirectorySource: memory}
+
+	directory, err := NewServiceDirectory(
+		source,
+		WithDirectoryAuthority("authority", authority.Public()),
+		WithDirectoryTTL(time.Minute),
+		WithDirectoryClock(func() time.Time { return now }),
+	)
+	require.NoError(t, err)
+
+	var _ ifcrypto.PublicKeyDirectory = directory
+
+	ctx := context.Background()
+
+	key, err := directory.Lookup(ctx, "billing", ifcrypto.KeyUsageKeyAgreement)
+	require.NoError(t, err)
+	assert.Equal(t, "billing-x25519", key.GetID())
+	assert.Equal(t, agreeKey.GetPublic().GetKey(), key.GetKey())
+
+	key, err = directory.Lookup(ctx, "billing", ifcrypto.KeyUsageVerify)
+	require.NoError(t, err)
+	assert.Equal(t, ifcrypto.KeyTypeEccNistP, key.GetKeyType())
+
+	_, err = directory.Lookup(ctx, "orders", ifcrypto.KeyUsageVerify)
+	assert.True(t, errors.Is(err, ifcrypto.ErrServiceKeyNotFound))
+
+	// Stale entry is served while the source is down
+	now = now.Add(2 * time.Minute)
+	source.fail = true
+
+	_, err = directory.Lookup(ctx, "billing", ifcrypto.KeyUsageVerify)
+	require.NoError(t, err)
+
+	// Rollback to a older version is rejected
+	source.fail = false
+	publish(memory, 1, signKey)
+	directory.Invalidate("billing")
+
+	_, err = directory.Lookup(ctx, "billing", ifcrypto.KeyUsageVerify)
+	assert.True(t, errors.Is(err, ErrDirectoryRollback))
+
+	// Entries signed by a unknown authority are rejected
+	rogue, err := NewED25519PrivateKey("rogue", ifcrypto.KeyUsageSign)
+	require.NoError(t, err)
+
+	signed, err := SignDirectoryEntry(
+		&DirectoryEntry{Service: "billing", Version: 3, Expires: now.Add(time.Hour)}, "authority", rogue,
+	)
+	require.NoError(t, err)
+	memory.Publish("billing", signed)
+
+	_, err = directory.Lookup(ctx, "billing", ifcrypto.KeyUsageVerify)
+	assert.True(t, errors.Is(err, ErrDirectorySignature))
+
+}
+
+func TestHTTPDirectorySource(t *testing.T) {
+
+	authority, err := NewED25519PrivateKey("authority", ifcrypto.KeyUsageSign)
+	require.NoError(t, err)
+
+	signed, err := SignDirectoryEntry(
+		&DirectoryEntry{Service: "billing", Version: 1, Expires: time.Now().Add(time.Hour)}, "authority", authority,
+	)
+	require.NoError(t, err)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+
+		if r.URL.Path != "/services/billing" {
+			http.NotFound(w, r)
+			return
+		}
+
+		json.NewEncoder(w).Encode(signed)
+
+	}))
+	defer server.Close()
+
+	source := NewHTTPDirectorySource(server.URL + "/services/{service}")
+
+	fetched, err := source.Fetch(context.Background(), "billing")
+	require.NoError(t, err)
+	assert.Equal(t, signed.Signature, fetched.Signature)
+
+	_, err = source.Fetch(context.Background(), "orders")
+	assert.True(t, errors.Is(err, ifcrypto.ErrServiceKeyNotFound))
+
+}
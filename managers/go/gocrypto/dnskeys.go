@@ -0,0 +1,391 @@
+package gocrypto
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/sha512"
+	"crypto/subtle"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/mariotoffia/goservice/interfaces/ifcrypto"
+)
+
+// ErrTLSAMismatch is returned when no _TLSA_ record matches the presented certificates.
+var ErrTLSAMismatch = fmt.Errorf("no TLSA record matches the certificate")
+
+// TLSA certificate usages, _RFC 6698_ section 2.1.1.
+const (
+	TLSAUsagePKIXTA uint8 = 0
+	TLSAUsagePKIXEE uint8 = 1
+	TLSAUsageDANETA uint8 = 2
+	TLSAUsageDANEEE uint8 = 3
+)
+
+// TLSA selectors, _RFC 6698_ section 2.1.2.
+const (
+	// TLSASelectorCert matches the full certificate.
+	TLSASelectorCert uint8 = 0
+	// TLSASelectorSPKI matches the subject public key info, hence survives certificate renewal
+	// as long as the key is kept.
+	TLSASelectorSPKI uint8 = 1
+)
+
+// TLSA matching types, _RFC 6698_ section 2.1.3.
+const (
+	TLSAMatchingFull   uint8 = 0
+	TLSAMatchingSHA256 uint8 = 1
+	TLSAMatchingSHA512 uint8 = 2
+)
+
+// TLSARecord is a _DANE_ _TLSA_ resource record, _RFC 6698_.
+type TLSARecord struct {
+	Usage        uint8
+	Selector     uint8
+	MatchingType uint8
+	Data         []byte
+}
+
+// NewTLSARecord creates a _TLSA_ record for the _cert_.
+//
+// The recommended record for a service certificate is _3 1 1_, i.e. `TLSAUsageDANEEE`,
+// `TLSASelectorSPKI` and `TLSAMatchingSHA256`.
+func NewTLSARecord(cert *x509.Certificate, usage, selector, matchingType uint8) (*TLSARecord, error) {
+
+	if usage > TLSAUsageDANEEE {
+		return nil, fmt.Errorf("invalid TLSA usage: %d", usage)
+	}
+
+	data, err := tlsaData(cert, selector, matchingType)
+	if err != nil {
+		return nil, err
+	}
+
+	return &TLSARecord{Usage: usage, Selector: selector, MatchingType: matchingType, Data: data}, nil
+
+}
+
+// ParseTLSARecord parses the presentation format _RDATA_ of a _TLSA_ record, e.g.
+// _3 1 1 0c72ac70..._. The hex data may be split by white space.
+func ParseTLSARecord(rdata string) (*TLSARecord, error) {
+
+	fields := strings.Fields(rdata)
+	if len(fields) < 4 {
+		return nil, fmt.Errorf("invalid TLSA record: %q", rdata)
+	}
+
+	var values [3]uint8
+
+	for i := range values {
+
+		v, err := strconv.ParseUint(fields[i], 10, 8)
+		if err != nil {
+			return nil, fmt.Errorf("invalid TLSA record: %w", err)
+		}
+
+		values[i] = uint8(v)
+
+	}
+
+	data, err := hex.DecodeString(strings.Join(fields[3:], ""))
+	if err != nil {
+		return nil, fmt.Errorf("invalid TLSA record: %w", err)
+	}
+
+	return &TLSARecord{Usage: values[0], Selector: values[1], MatchingType: values[2], Data: data}, nil
+
+}
+
+// String returns the presentation format _RDATA_ of the record.
+func (r *TLSARecord) String() string {
+	return fmt.Sprintf("%d %d %d %s", r.Usage, r.Selector, r.MatchingType, hex.EncodeToString(r.Data))
+}
+
+// Matches returns `true` if the _cert_ matches the selector and data of the record.
+func (r *TLSARecord) Matches(cert *x509.Certificate) bool {
+
+	data, err := tlsaData(cert, r.Selector, r.MatchingType)
+	if err != nil {
+		return false
+	}
+
+	return subtle.ConstantTimeCompare(data, r.Data) == 1
+
+}
+
+// TLSAName returns the owner name of the _TLSA_ record of a service, e.g. _443_, _tcp_ and
+// _api.example.com_ gives _\_443.\_tcp.api.example.com._.
+func TLSAName(port int, protocol, host string) string {
+	return fmt.Sprintf("_%d._%s.%s.", port, protocol, strings.TrimSuffix(host, "."))
+}
+
+// tlsaData selects and hashes the _cert_ data.
+func tlsaData(cert *x509.Certificate, selector, matchingType uint8) ([]byte, error) {
+
+	var data []byte
+
+	switch selector {
+	case TLSASelectorCert:
+		data = cert.Raw
+	case TLSASelectorSPKI:
+		data = cert.RawSubjectPublicKeyInfo
+	default:
+		return nil, fmt.Errorf("invalid TLSA selector: %d", selector)
+	}
+
+	switch matchingType {
+	case TLSAMatchingFull:
+		return data, nil
+	case TLSAMatchingSHA256:
+		sum := sha256.Sum256(data)
+		return sum[:], nil
+	case TLSAMatchingSHA512:
+		sum := sha512.Sum512(data)
+		return sum[:], nil
+	}
+
+	return nil, fmt.Errorf("invalid TLSA matching type: %d", matchingType)
+
+}
+
+// KeyTXTRecord creates a _DKIM_ style _TXT_ record, _RFC 6376_ section 3.6.1, that
+// publishes the public _key_, e.g. _v=DKIM1; k=ed25519; p=..._.
+//
+// Only _RSA_ and _Ed25519_ (_RFC 8463_) keys are defined for _DKIM_. Use `SplitTXT` when
+// the record is longer than 255 characters.
+func KeyTXTRecord(key ifcrypto.PublicKey) (string, error) {
+
+	switch k := key.GetKey().(type) {
+	case *rsa.PublicKey:
+
+		der, err := x509.MarshalPKIXPublicKey(k)
+		if err != nil {
+			return "", err
+		}
+
+		return "v=DKIM1; k=rsa; p=" + base64.StdEncoding.EncodeToString(der), nil
+
+	case ed25519.PublicKey:
+		return "v=DKIM1; k=ed25519; p=" + base64.StdEncoding.EncodeToString(k), nil
+	}
+
+	return "", fmt.Errorf("unsupported key for TXT record: %s", key.GetKeyType())
+
+}
+
+// ParseKeyTXTRecord parses a _DKIM_ style _TXT_ record into a public key with _id_ and _usage_.
+//
+// The _txt_ is the concatenation of all strings of the record. A empty _p_ tag means
+// that the key has been revoked.
+func ParseKeyTXTRecord(id, txt string, usage ...ifcrypto.KeyUsage) (ifcrypto.PublicKey, error) {
+
+	tags := map[string]string{"k": "rsa"}
+
+	for _, tag := range strings.Split(txt, ";") {
+
+		kv := strings.SplitN(tag, "=", 2)
+		if len(kv) != 2 {
+			continue
+		}
+
+		// White space is allowed anywhere within base64 values
+		tags[strings.TrimSpace(kv[0])] = strings.Join(strings.Fields(kv[1]), "")
+
+	}
+
+	if v, ok := tags["v"]; ok && v != "DKIM1" {
+		return nil, fmt.Errorf("unsupported key record version: %s", v)
+	}
+
+	p, ok := tags["p"]
+	if !ok {
+		return nil, fmt.Errorf("key record has no p tag")
+	}
+
+	if p == "" {
+		return nil, fmt.Errorf("key %s has been revoked", id)
+	}
+
+	data, err := base64.StdEncoding.DecodeString(p)
+	if err != nil {
+		return nil, err
+	}
+
+	switch tags["k"] {
+	case "rsa":
+
+		key, err := x509.ParsePKIXPublicKey(data)
+		if err != nil {
+			return nil, err
+		}
+
+		rsakey, ok := key.(*rsa.PublicKey)
+		if !ok {
+			return nil, fmt.Errorf("not a *rsa.PublicKey: %T", key)
+		}
+
+		if err := checkLoadedKey(rsakey); err != nil {
+			return nil, err
+		}
+
+		return NewRSAPublicKeyFromKey(id, rsakey, usage...), nil
+
+	case "ed25519":
+
+		if len(data) != ed25519.PublicKeySize {
+			return nil, fmt.Errorf("invalid ed25519 key size: %d", len(data))
+		}
+
+		return NewED25519PublicKeyFromKey(id, ed25519.PublicKey(data), usage...), nil
+
+	}
+
+	return nil, fmt.Errorf("unsupported key type in TXT record: %s", tags["k"])
+
+}
+
+// SplitTXT splits _txt_ into strings of at most 255 characters as required for a single
+// _TXT_ record.
+func SplitTXT(txt string) []string {
+
+	parts := []string{}
+
+	for len(txt) > 255 {
+		parts = append(parts, txt[:255])
+		txt = txt[255:]
+	}
+
+	return append(parts, txt)
+
+}
+
+// DNSResolver resolves _TXT_ and _TLSA_ records.
+//
+// The resolver must be a _DNSSEC_ validating resolver, or only return records that has
+// been validated, since the records are trusted as is. `TXTResolverFunc` adapts a
+// `*net.Resolver` that only resolves _TXT_ records.
+type DNSResolver interface {
+	// LookupTXT returns the _TXT_ records of _name_, one string per record.
+	LookupTXT(c context.Context, name string) ([]string, error)
+	// LookupTLSA returns the _TLSA_ records of _name_.
+	LookupTLSA(c context.Context, name string) ([]TLSARecord, error)
+}
+
+// TXTResolverFunc is a `DNSResolver` that resolves _TXT_ records only, e.g.
+// `TXTResolverFunc(net.DefaultResolver.LookupTXT)`.
+type TXTResolverFunc func(c context.Context, name string) ([]string, error)
+
+// LookupTXT implements the `DNSResolver` interface.
+func (f TXTResolverFunc) LookupTXT(c context.Context, name string) ([]string, error) {
+	return f(c, name)
+}
+
+// LookupTLSA implements the `DNSResolver` interface and always fails.
+func (f TXTResolverFunc) LookupTLSA(c context.Context, name string) ([]TLSARecord, error) {
+	return nil, fmt.Errorf("TLSA lookup not supported, resolving %s", name)
+}
+
+// DNSKeyVerifier discovers and verifies keys and certificates published in _DNS_.
+type DNSKeyVerifier struct {
+	resolver DNSResolver
+}
+
+// NewDNSKeyVerifier creates a new `DNSKeyVerifier` that uses the _resolver_.
+func NewDNSKeyVerifier(resolver DNSResolver) *DNSKeyVerifier {
+	return &DNSKeyVerifier{resolver: resolver}
+}
+
+// LookupKey resolves the key record _selector.\_domainkey.domain_ into a public key with
+// _usage_. The key id is the record name.
+func (v *DNSKeyVerifier) LookupKey(
+	c context.Context,
+	selector, domain string,
+	usage ...ifcrypto.KeyUsage,
+) (ifcrypto.PublicKey, error) {
+
+	name := selector + "._domainkey." + strings.TrimSuffix(domain, ".")
+
+	records, err := v.resolver.LookupTXT(c, name)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, txt := range records {
+
+		if strings.Contains(txt, "p=") {
+			return ParseKeyTXTRecord(name, txt, usage...)
+		}
+
+	}
+
+	return nil, fmt.Errorf("%w: no key record at %s", ifcrypto.ErrServiceKeyNotFound, name)
+
+}
+
+// VerifyCertificates verifies the presented _chain_, leaf first, of _host_ against the _TLSA_
+// records of the service at _port_ and _protocol_.
+//
+// End entity usages match the leaf only, trust anchor usages match any certificate in the
+// chain. The _PKIX_ usages also require normal path validation, which is left to the caller,
+// e.g. `crypto/tls`.
+func (v *DNSKeyVerifier) VerifyCertificates(
+	c context.Context,
+	port int,
+	protocol, host string,
+	chain []*x509.Certificate,
+) error {
+
+	if len(chain) == 0 {
+		return fmt.Errorf("no certificates presented by %s", host)
+	}
+
+	records, err := v.resolver.LookupTLSA(c, TLSAName(port, protocol, host))
+	if err != nil {
+		return err
+	}
+
+	for i := range records {
+
+		record := &records[i]
+
+		switch record.Usage {
+		case TLSAUsageDANEEE, TLSAUsagePKIXEE:
+
+			if record.Matches(chain[0]) {
+				return nil
+			}
+
+		case TLSAUsageDANETA, TLSAUsagePKIXTA:
+
+			for _, cert := range chain {
+
+				if record.Matches(cert) {
+					return nil
+				}
+
+			}
+
+		}
+
+	}
+
+	return fmt.Errorf("%w: %s", ErrTLSAMismatch, TLSAName(port, protocol, host))
+
+}
+
+// VerifyConnection returns a function to set as `tls.Config.VerifyConnection` that requires the
+// peer certificates to match the _TLSA_ records of the server name at _port_ over _tcp_.
+func (v *DNSKeyVerifier) VerifyConnection(port int) func(cs tls.ConnectionState) error {
+
+	return func(cs tls.ConnectionState) error {
+		return v.VerifyCertificates(context.Background(), port, "tcp", cs.ServerName, cs.PeerCertificates)
+	}
+
+}
@@ -0,0 +1,121 @@
+//go:build !verifyonly
+// +build !verifyonly
+
+package gocrypto
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"errors"
+	"math/big"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mariotoffia/goservice/interfaces/ifcrypto"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type testDNSResolver struct {
+	txt  map[string][]string
+	tlsa map[string][]TLSARecord
+}
+
+func (r *testDNSResolver) LookupTXT(c context.Context, name string) ([]string, error) {
+	return r.txt[name], nil
+}
+
+func (r *testDNSResolver) LookupTLSA(c context.Context, name string) ([]TLSARecord, error) {
+	return r.tlsa[name], nil
+}
+
+func testCertificate(t *testing.T, cn string) *x509.Certificate {
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: cn},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	require.NoError(t, err)
+
+	cert, err := x509.ParseCertificate(der)
+	require.NoError(t, err)
+
+	return cert
+
+}
+
+func TestTLSARecords(t *testing.T) {
+
+	cert := testCertificate(t, "api.example.com")
+
+	record, err := NewTLSARecord(cert, TLSAUsageDANEEE, TLSASelectorSPKI, TLSAMatchingSHA256)
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(record.String(), "3 1 1 "))
+
+	parsed, err := ParseTLSARecord(record.String())
+	require.NoError(t, err)
+	assert.Equal(t, record, parsed)
+	assert.True(t, parsed.Matches(cert))
+
+	name := TLSAName(443, "tcp", "api.example.com")
+	assert.Equal(t, "_443._tcp.api.example.com.", name)
+
+	verifier := NewDNSKeyVerifier(&testDNSResolver{tlsa: map[string][]TLSARecord{name: {*record}}})
+
+	require.NoError(t, verifier.VerifyCertificates(context.Background(), 443, "tcp", "api.example.com", []*x509.Certificate{cert}))
+
+	other := testCertificate(t, "api.example.com")
+	err = verifier.VerifyCertificates(context.Background(), 443, "tcp", "api.example.com", []*x509.Certificate{other})
+	assert.True(t, errors.Is(err, ErrTLSAMismatch))
+
+}
+
+func TestKeyTXTRecords(t *testing.T) {
+
+	key, err := NewED25519PrivateKey("k", ifcrypto.KeyUsageSign)
+	require.NoError(t, err)
+
+	txt, err := KeyTXTRecord(key.GetPublic())
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(txt, "v=DKIM1; k=ed25519; p="))
+
+	rsaKey, err := NewRSAPrivateKey("r", 2048, ifcrypto.KeyUsageSign)
+	require.NoError(t, err)
+
+	rsaTXT, err := KeyTXTRecord(rsaKey.GetPublic())
+	require.NoError(t, err)
+	assert.True(t, len(SplitTXT(rsaTXT)) > 1)
+
+	resolver := &testDNSResolver{txt: map[string][]string{
+		"2026._domainkey.example.com": {txt},
+		"rsa._domainkey.example.com":  {strings.Join(SplitTXT(rsaTXT), "")},
+		"gone._domainkey.example.com": {"v=DKIM1; p="},
+	}}
+
+	verifier := NewDNSKeyVerifier(resolver)
+
+	pub, err := verifier.LookupKey(context.Background(), "2026", "example.com", ifcrypto.KeyUsageVerify)
+	require.NoError(t, err)
+	assert.Equal(t, key.GetPublic().GetKey(), pub.GetKey())
+	assert.Equal(t, "2026._domainkey.example.com", pub.GetID())
+
+	pub, err = verifier.LookupKey(context.Background(), "rsa", "example.com")
+	require.NoError(t, err)
+	assert.Equal(t, rsaKey.GetPublic().GetKey(), pub.GetKey())
+
+	_, err = verifier.LookupKey(context.Background(), "gone", "example.com")
+	assert.Error(t, err)
+
+}
@@ -0,0 +1,436 @@
+package gocrypto
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"github.com/mariotoffia/goservice/interfaces/ifcrypto"
+	"github.com/mariotoffia/goservice/utils/cryptoutils"
+)
+
+// ErrInventoryKeyNotFound is returned when a key id is not part of a inventory export.
+var ErrInventoryKeyNotFound = fmt.Errorf("key not found in inventory")
+
+// InventoryKey is a `ifcrypto.Key` that only holds the metadata of a `InventoryEntry`, e.g. from
+// a production inventory export. It has no key material and is always a remote key.
+type InventoryKey struct {
+	KeyBase
+	entry InventoryEntry
+}
+
+// NewInventoryKey creates a new `InventoryKey` from the key _entry_.
+func NewInventoryKey(entry InventoryEntry) (*InventoryKey, error) {
+
+	if entry.Kind != InventoryKindKey {
+		return nil, fmt.Errorf("inventory entry %s is a %s, not a key", entry.ID, entry.Kind)
+	}
+
+	keyType := ifcrypto.KeyType(entry.Algorithm)
+
+	if _, ok := ifcrypto.KeySizes[keyType]; !ok {
+		return nil, fmt.Errorf("inventory entry %s has unknown key type: %s", entry.ID, entry.Algorithm)
+	}
+
+	return &InventoryKey{
+		KeyBase: KeyBase{
+			id:      entry.ID,
+			keyType: keyType,
+			keySize: entry.Size,
+			usage:   entry.Usage,
+		},
+		entry: entry,
+	}, nil
+
+}
+
+// Entry returns the `InventoryEntry` of the key.
+func (r *InventoryKey) Entry() InventoryEntry {
+	return r.entry
+}
+
+// GetKey returns the key id since there is no key material.
+func (r *InventoryKey) GetKey() interface{} {
+	return r.entry.ID
+}
+
+// IsSymmetric returns `true` if the key is a `KeyTypeSymmetric` or `KeyTypeHmac`.
+func (r *InventoryKey) IsSymmetric() bool {
+	return r.keyType == ifcrypto.KeyTypeSymmetric || r.keyType == ifcrypto.KeyTypeHmac
+}
+
+// IsPrivate returns the _Private_ of the entry.
+func (r *InventoryKey) IsPrivate() bool {
+	return r.entry.Private
+}
+
+// IsRemoteKey returns `true` since there is no key material in memory. Use `Entry` to get
+// whether the inventoried key is remote.
+func (r *InventoryKey) IsRemoteKey() bool {
+	return true
+}
+
+// NewInventoryKeyLookup creates a `KeyLookup` of all keys in _report_, e.g. a production
+// inventory export read by `ReadInventoryReportJSON`.
+//
+// It returns a error if a key id occurs more than once since the lookup would be ambiguous.
+func NewInventoryKeyLookup(report *InventoryReport) (KeyLookup, error) {
+
+	keys := map[string]*InventoryKey{}
+
+	for _, entry := range report.Entries {
+
+		if entry.Kind != InventoryKindKey {
+			continue
+		}
+
+		if _, ok := keys[entry.ID]; ok {
+			return nil, fmt.Errorf("duplicate key id in inventory: %s", entry.ID)
+		}
+
+		key, err := NewInventoryKey(entry)
+		if err != nil {
+			return nil, err
+		}
+
+		keys[entry.ID] = key
+
+	}
+
+	return func(c context.Context, id string) (ifcrypto.Key, error) {
+
+		if key, ok := keys[id]; ok {
+			return key, nil
+		}
+
+		return nil, fmt.Errorf("%w: %s", ErrInventoryKeyNotFound, id)
+
+	}, nil
+
+}
+
+// DryRunOperation is a operation to simulate.
+type DryRunOperation struct {
+	Operation ifcrypto.PolicyOperation `json:"operation"`
+	KeyID     string                   `json:"key_id"`
+	// Algorithm is the sign algorithm of a `ifcrypto.PolicyOperationSign` or
+	// `ifcrypto.PolicyOperationIssueCertificate`, if empty it is not checked.
+	Algorithm ifcrypto.SignAlgorithm `json:"algorithm,omitempty"`
+	// Attributes is passed on to the policy engine.
+	Attributes map[string]string `json:"attributes,omitempty"`
+}
+
+// DryRunResult is the simulated outcome of a `DryRunOperation`.
+type DryRunResult struct {
+	DryRunOperation
+	KeyType ifcrypto.KeyType `json:"key_type,omitempty"`
+	Allowed bool             `json:"allowed"`
+	// Reasons is all reasons the operation would be denied.
+	Reasons []string `json:"reasons,omitempty"`
+	// Findings is the weak crypto findings of the key.
+	Findings []cryptoutils.LintFinding `json:"findings,omitempty"`
+}
+
+// DryRunReport is the result of `DryRun.Simulate`.
+type DryRunReport struct {
+	Results []DryRunResult `json:"results"`
+	// Denied is the number of operations that would be denied.
+	Denied int `json:"denied"`
+}
+
+// Allowed returns `true` if all operations would be allowed.
+func (r *DryRunReport) Allowed() bool {
+	return r.Denied == 0
+}
+
+// WriteJSON writes the report as a indented _JSON_ document onto _w_.
+func (r *DryRunReport) WriteJSON(w io.Writer) error {
+
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+
+	return enc.Encode(r)
+
+}
+
+// DryRunOption configures a `DryRun`.
+type DryRunOption func(d *DryRun)
+
+// WithDryRunPolicy evaluates each operation with _guard_, as the `PolicyGuard` would before the
+// real operation.
+func WithDryRunPolicy(guard *PolicyGuard) DryRunOption {
+	return func(d *DryRun) {
+		d.guard = guard
+	}
+}
+
+// WithDryRunScope checks each operation against _scope_, as a `ScopedKeys` view would.
+func WithDryRunScope(scope KeyScope) DryRunOption {
+	return func(d *DryRun) {
+		d.scope = &scope
+	}
+}
+
+// WithDryRunLinter denies operations on keys with findings that _linter_ fails. By default
+// findings are only reported.
+func WithDryRunLinter(linter *cryptoutils.Linter) DryRunOption {
+	return func(d *DryRun) {
+		d.linter = linter
+	}
+}
+
+// DryRun simulates key operations. It validates the key, its usages, the algorithm
+// compatibility, the scope and the policy and reports what would happen without performing any
+// cryptography.
+//
+// Together with `NewInventoryKeyLookup` it allows CI to validate configuration changes, such
+// as policies and scopes, against the key metadata exported from production.
+type DryRun struct {
+	lookup KeyLookup
+	guard  *PolicyGuard
+	scope  *KeyScope
+	linter *cryptoutils.Linter
+}
+
+// NewDryRun creates a new `DryRun` that resolves keys using _lookup_.
+func NewDryRun(lookup KeyLookup, opts ...DryRunOption) *DryRun {
+
+	d := &DryRun{lookup: lookup}
+
+	for _, opt := range opts {
+		opt(d)
+	}
+
+	return d
+
+}
+
+// Simulate simulates all _ops_ and reports the outcome of each. All reasons to deny a operation
+// are reported, not only the first.
+func (d *DryRun) Simulate(c context.Context, ops ...DryRunOperation) *DryRunReport {
+
+	report := &DryRunReport{Results: []DryRunResult{}}
+
+	for _, op := range ops {
+
+		result := d.simulate(c, op)
+
+		if !result.Allowed {
+			report.Denied++
+		}
+
+		report.Results = append(report.Results, result)
+
+	}
+
+	return report
+
+}
+
+// simulate simulates a single _op_.
+func (d *DryRun) simulate(c context.Context, op DryRunOperation) DryRunResult {
+
+	result := DryRunResult{DryRunOperation: op}
+
+	deny := func(format string, args ...interface{}) {
+		result.Reasons = append(result.Reasons, fmt.Sprintf(format, args...))
+	}
+
+	if d.scope != nil {
+
+		if err := d.scope.permitsID(op.KeyID); err != nil {
+
+			deny("%v", err)
+			return result
+
+		}
+
+	}
+
+	key, err := d.lookup(c, op.KeyID)
+	if err != nil {
+
+		deny("%v", err)
+		return result
+
+	}
+
+	result.KeyType = key.GetKeyType()
+	result.Findings = dryRunFindings(key)
+
+	usage, keyTypes, known := dryRunRequirements(op.Operation)
+
+	if !known {
+		deny("unknown operation: %s", op.Operation)
+	}
+
+	if usage != "" && !hasKeyUsage(key, usage) {
+		deny("key %s do not have usage %s", op.KeyID, usage)
+	}
+
+	if known && len(keyTypes) > 0 && !containsKeyType(keyTypes, key.GetKeyType()) {
+		deny("key type %s can not %s", key.GetKeyType(), op.Operation)
+	}
+
+	if !key.IsPrivate() {
+		deny("key %s is not a private key", op.KeyID)
+	}
+
+	if op.Operation == ifcrypto.PolicyOperationExport && dryRunRemote(key) {
+		deny("key %s is remote and can not be exported", op.KeyID)
+	}
+
+	if op.Algorithm != "" {
+
+		if !isSignAlgorithm(op.Algorithm) {
+			deny("unknown sign algorithm: %s", op.Algorithm)
+		} else if op.Operation != ifcrypto.PolicyOperationSign &&
+			op.Operation != ifcrypto.PolicyOperationIssueCertificate {
+			deny("sign algorithm %s do not apply to %s", op.Algorithm, op.Operation)
+		} else if !key.CanSign(op.Algorithm) {
+			deny("key %s can not sign using %s", op.KeyID, op.Algorithm)
+		}
+
+	}
+
+	if d.scope != nil {
+
+		if err := d.scope.permits(key, op.Operation); err != nil {
+			deny("%v", err)
+		}
+
+	}
+
+	if d.linter != nil {
+
+		for _, finding := range result.Findings {
+
+			if d.linter.Action(finding) == cryptoutils.LintActionFail {
+				deny("key %s fails lint: %s", op.KeyID, finding.String())
+			}
+
+		}
+
+	}
+
+	if d.guard != nil {
+
+		if err := d.guard.AuthorizeKey(c, op.Operation, key, op.Attributes); err != nil {
+			deny("%v", err)
+		}
+
+	}
+
+	result.Allowed = len(result.Reasons) == 0
+
+	return result
+
+}
+
+// dryRunRequirements returns the usage and key types, empty if any, that _op_ requires. The
+// _known_ is `false` if _op_ is not a known operation.
+func dryRunRequirements(op ifcrypto.PolicyOperation) (
+	usage ifcrypto.KeyUsage,
+	keyTypes []ifcrypto.KeyType,
+	known bool,
+) {
+
+	switch op {
+	case ifcrypto.PolicyOperationSign:
+
+		return ifcrypto.KeyUsageSign, []ifcrypto.KeyType{
+			ifcrypto.KeyTypeRsa, ifcrypto.KeyTypeEccNistP, ifcrypto.KeyTypeEccSecgP256k1,
+			ifcrypto.KeyTypeEd25519, ifcrypto.KeyTypeHmac,
+		}, true
+
+	case ifcrypto.PolicyOperationIssueCertificate:
+
+		return ifcrypto.KeyUsageSign, []ifcrypto.KeyType{
+			ifcrypto.KeyTypeRsa, ifcrypto.KeyTypeEccNistP, ifcrypto.KeyTypeEd25519,
+		}, true
+
+	case ifcrypto.PolicyOperationDecrypt:
+
+		return ifcrypto.KeyUsageDecrypt, []ifcrypto.KeyType{
+			ifcrypto.KeyTypeRsa, ifcrypto.KeyTypeSymmetric,
+		}, true
+
+	case ifcrypto.PolicyOperationKeyAgreement:
+
+		return ifcrypto.KeyUsageKeyAgreement, []ifcrypto.KeyType{
+			ifcrypto.KeyTypeX25519, ifcrypto.KeyTypeEccNistP,
+		}, true
+
+	case ifcrypto.PolicyOperationExport:
+
+		return ifcrypto.KeyUsageExport, nil, true
+
+	}
+
+	return "", nil, false
+
+}
+
+// dryRunFindings returns the findings of _key_, a `InventoryKey` reports the exported findings.
+func dryRunFindings(key ifcrypto.Key) []cryptoutils.LintFinding {
+
+	if ik, ok := key.(*InventoryKey); ok {
+		return ik.entry.Findings
+	}
+
+	return keyInventoryEntry("", key).Findings
+
+}
+
+// dryRunRemote returns `true` if _key_, or the inventoried key of a `InventoryKey`, is remote.
+func dryRunRemote(key ifcrypto.Key) bool {
+
+	if ik, ok := key.(*InventoryKey); ok {
+		return ik.entry.Remote
+	}
+
+	return key.IsRemoteKey()
+
+}
+
+// containsKeyType returns `true` if _keyType_ is one of _keyTypes_.
+func containsKeyType(keyTypes []ifcrypto.KeyType, keyType ifcrypto.KeyType) bool {
+
+	for _, kt := range keyTypes {
+
+		if kt == keyType {
+			return true
+		}
+
+	}
+
+	return false
+
+}
+
+// isSignAlgorithm returns `true` if _alg_ is a known `ifcrypto.SignAlgorithm`.
+func isSignAlgorithm(alg ifcrypto.SignAlgorithm) bool {
+
+	switch alg {
+	case ifcrypto.SignAlgorithmRsaPssSha256,
+		ifcrypto.SignAlgorithmRsaPssSha384,
+		ifcrypto.SignAlgorithmRsaPssSha512,
+		ifcrypto.SignAlgorithmRsaPkcs1V15Sha256,
+		ifcrypto.SignAlgorithmRsaPkcs1V15Sha384,
+		ifcrypto.SignAlgorithmRsaPkcs1V15Sha512,
+		ifcrypto.SignAlgorithmEcdSha256,
+		ifcrypto.SignAlgorithmEcdSha384,
+		ifcrypto.SignAlgorithmEcdSha512,
+		ifcrypto.SignAlgorithmEd25519,
+		ifcrypto.SignAlgorithmHmacSha256,
+		ifcrypto.SignAlgorithmHmacSha384,
+		ifcrypto.SignAlgorithmHmacSha512:
+
+		return true
+
+	}
+
+	return false
+
+}
@@ -0,0 +1,144 @@
+//go:build !verifyonly
+// +build !verifyonly
+
+package gocrypto
+
+import (
+	"bytes"
+	"context"
+	"testing"
+	"time"
+
+	"github.com/mariotoffia/goservice/interfaces/ifcrypto"
+	"github.com/mariotoffia/goservice/utils/cryptoutils"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDryRunSimulatesOperationsOnInventoryExport(t *testing.T) {
+
+	weak, err := NewRSAPrivateKey("tokens-legacy", 1024, ifcrypto.KeyUsageSign)
+	require.NoError(t, err)
+
+	signing, err := NewED25519PrivateKey("tokens-signing", ifcrypto.KeyUsageSign)
+	require.NoError(t, err)
+
+	agreement, err := NewX25519PrivateKey("session", ifcrypto.KeyUsageKeyAgreement)
+	require.NoError(t, err)
+
+	kek, err := NewSymmetricKey("kek", 256, ifcrypto.KeyUsageDecrypt, ifcrypto.KeyUsageExport)
+	require.NoError(t, err)
+
+	var export bytes.Buffer
+
+	require.NoError(t, NewInventory(&StaticInventorySource{
+		SourceName: "production",
+		KeyList:    []ifcrypto.Key{weak, signing, agreement, kek},
+	}).Report(time.Now()).WriteJSON(&export))
+
+	report, err := ReadInventoryReportJSON(&export)
+	require.NoError(t, err)
+
+	lookup, err := NewInventoryKeyLookup(report)
+	require.NoError(t, err)
+
+	guard := NewPolicyGuard(PolicyEngineFunc(
+		func(c context.Context, input ifcrypto.PolicyInput) (ifcrypto.PolicyDecision, error) {
+
+			if input.Operation == ifcrypto.PolicyOperationExport {
+				return ifcrypto.PolicyDecision{Reason: "export is disabled"}, nil
+			}
+
+			return ifcrypto.PolicyDecision{Allow: true}, nil
+
+		}),
+	)
+
+	dryRun := NewDryRun(lookup,
+		WithDryRunPolicy(guard),
+		WithDryRunLinter(cryptoutils.NewLinter()),
+		WithDryRunScope(KeyScope{
+			KeyIDs: []string{"tokens-*", "session", "kek"},
+			Operations: []ifcrypto.PolicyOperation{
+				ifcrypto.PolicyOperationSign,
+				ifcrypto.PolicyOperationDecrypt,
+				ifcrypto.PolicyOperationExport,
+			},
+		}),
+	)
+
+	result := dryRun.Simulate(context.Background(),
+		DryRunOperation{Operation: ifcrypto.PolicyOperationSign, KeyID: "tokens-signing", Algorithm: ifcrypto.SignAlgorithmEd25519},
+		DryRunOperation{Operation: ifcrypto.PolicyOperationSign, KeyID: "tokens-signing", Algorithm: ifcrypto.SignAlgorithmEcdSha256},
+		DryRunOperation{Operation: ifcrypto.PolicyOperationSign, KeyID: "tokens-legacy"},
+		DryRunOperation{Operation: ifcrypto.PolicyOperationKeyAgreement, KeyID: "session"},
+		DryRunOperation{Operation: ifcrypto.PolicyOperationDecrypt, KeyID: "kek"},
+		DryRunOperation{Operation: ifcrypto.PolicyOperationExport, KeyID: "kek"},
+		DryRunOperation{Operation: ifcrypto.PolicyOperationSign, KeyID: "database"},
+	)
+
+	require.Len(t, result.Results, 7)
+	assert.False(t, result.Allowed())
+	assert.Equal(t, 5, result.Denied)
+
+	assert.True(t, result.Results[0].Allowed)
+	assert.Equal(t, ifcrypto.KeyTypeEd25519, result.Results[0].KeyType)
+
+	assert.False(t, result.Results[1].Allowed)
+	assert.Contains(t, result.Results[1].Reasons, "key tokens-signing can not sign using ecd-sha256")
+
+	assert.False(t, result.Results[2].Allowed)
+	require.Len(t, result.Results[2].Findings, 1)
+	assert.Equal(t, "weak-rsa-key", result.Results[2].Findings[0].Code)
+
+	assert.False(t, result.Results[3].Allowed)
+	assert.Len(t, result.Results[3].Reasons, 1)
+
+	assert.True(t, result.Results[4].Allowed)
+
+	assert.False(t, result.Results[5].Allowed)
+	require.Len(t, result.Results[5].Reasons, 1)
+	assert.Contains(t, result.Results[5].Reasons[0], "export is disabled")
+
+	assert.False(t, result.Results[6].Allowed)
+	assert.Contains(t, result.Results[6].Reasons[0], ErrKeyOutOfScope.Error())
+
+}
+
+func TestDryRunReportsIncompatibleKeys(t *testing.T) {
+
+	key, err := NewInventoryKey(InventoryEntry{
+		Kind:      InventoryKindKey,
+		ID:        "hsm-verify",
+		Algorithm: string(ifcrypto.KeyTypeEccNistP),
+		Size:      256,
+		Usage:     []ifcrypto.KeyUsage{ifcrypto.KeyUsageVerify},
+		Remote:    true,
+	})
+	require.NoError(t, err)
+
+	_, err = NewInventoryKey(InventoryEntry{Kind: InventoryKindCertificate, ID: "01"})
+	assert.Error(t, err)
+
+	lookup := func(c context.Context, id string) (ifcrypto.Key, error) {
+		return key, nil
+	}
+
+	result := NewDryRun(lookup).Simulate(context.Background(),
+		DryRunOperation{Operation: ifcrypto.PolicyOperationDecrypt, KeyID: "hsm-verify"},
+		DryRunOperation{Operation: ifcrypto.PolicyOperationSign, KeyID: "hsm-verify", Algorithm: "md5"},
+	)
+
+	assert.Equal(t, []string{
+		"key hsm-verify do not have usage decrypt",
+		"key type ecc-nist-p can not decrypt",
+		"key hsm-verify is not a private key",
+	}, result.Results[0].Reasons)
+
+	assert.Contains(t, result.Results[1].Reasons, "unknown sign algorithm: md5")
+
+	var buf bytes.Buffer
+	require.NoError(t, result.WriteJSON(&buf))
+	assert.Contains(t, buf.String(), `"denied": 2`)
+
+}
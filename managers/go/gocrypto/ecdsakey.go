@@ -4,7 +4,6 @@ import (
 	"crypto"
 	"crypto/ecdsa"
 	"crypto/elliptic"
-	"crypto/rand"
 	"crypto/x509"
 	"encoding/pem"
 	"fmt"
@@ -14,7 +13,30 @@ import (
 	"github.com/mariotoffia/goservice/utils/cryptoutils"
 )
 
-// ECDSAPrivateKey implements the `ifcrypto.KeyPair` interface for a `*rsa.PrivateKey`.
+// ECDSASignerOpts is a `crypto.SignerOpts` that selects the signature encoding of _ECDSA_ keys.
+type ECDSASignerOpts struct {
+	// Hash is the hash function used to create the digest.
+	Hash crypto.Hash
+	// Raw emits, or expects when verifying, the fixed size _r || s_ encoding instead of
+	// _ASN.1 DER_, as required by _JOSE_, _COSE_ and _WebAuthn_.
+	Raw bool
+}
+
+// HashFunc implements the `crypto.SignerOpts` interface.
+func (o *ECDSASignerOpts) HashFunc() crypto.Hash {
+	return o.Hash
+}
+
+// isRawECDSA returns `true` if _opts_ is a `*ECDSASignerOpts` that requests the raw encoding.
+func isRawECDSA(opts crypto.SignerOpts) bool {
+
+	o, ok := opts.(*ECDSASignerOpts)
+
+	return ok && o.Raw
+
+}
+
+// ECDSAPrivateKey implements the `ifcrypto.KeyPair` interface for a `*ecdsa.PrivateKey`.
 type ECDSAPrivateKey struct {
 	KeyBase
 	key    *ecdsa.PrivateKey
@@ -33,7 +55,7 @@ func NewECDSAPrivateKeyFromKey(
 	return &ECDSAPrivateKey{
 		KeyBase: KeyBase{
 			id:      id,
-			keyType: ifcrypto.KeyTypeRsa,
+			keyType: ifcrypto.KeyTypeEccNistP,
 			keySize: key.Params().BitSize,
 			usage:   usage,
 			chiper:  []ifcrypto.Chipher{},
@@ -44,66 +66,79 @@ func NewECDSAPrivateKeyFromKey(
 
 }
 
-// NewECDSAPrivateKeyFromPEM initializes a new `*ecdsa.PrivateKey` from the underlying _PEM_ block.
-func NewECDSAPrivateKeyFromPEM(
-	block pem.Block,
+// NewECDSAPrivateKeyFromDER initializes a new `ECDSAPrivateKey` from _PKCS #8_ or _SEC 1_ _DER_.
+func NewECDSAPrivateKeyFromDER(
+	der []byte,
 	id string,
 	usage ...ifcrypto.KeyUsage,
 ) (*ECDSAPrivateKey, error) {
 
-	if block.Type == "PRIVATE KEY" {
-		key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
-
-		if err != nil {
-			return nil, err
-		}
-
-		if ecdsakey, ok := key.(*ecdsa.PrivateKey); ok {
+	return NewECDSAPrivateKeyFromPEM(privateKeyDERBlock(der, "EC PRIVATE KEY"), id, usage...)
 
-			return NewECDSAPrivateKeyFromKey(id, ecdsakey, usage...), nil
-
-		}
+}
 
-		return nil, fmt.Errorf("not a *ecdsa.PrivateKey: %T", key)
+// NewECDSAPrivateKey generates a new `ECDSAPrivateKey` using the `rand.Reader`, or the `EntropyGuard` reader, as entropy.
+//
+// The _bits_ selects the _NIST_ curve, one of 224, 256, 384 or 521. Zero selects _P-256_.
+func NewECDSAPrivateKey(id string, bits int, usage ...ifcrypto.KeyUsage) (*ECDSAPrivateKey, error) {
 
+	curve, err := ECDSACurve(bits)
+	if err != nil {
+		return nil, err
 	}
 
-	if block.Type == "EC PRIVATE KEY" {
+	return NewECDSAPrivateKeyWithOptions(id, WithCurve(curve), WithKeyUsage(usage...))
+}
 
-		key, err := x509.ParseECPrivateKey(block.Bytes)
-		if err != nil {
-			return nil, err
-		}
+// ECDSAKeyOption configures the generation of a `ECDSAPrivateKey`.
+type ECDSAKeyOption func(opts *ecdsaKeyOptions)
 
-		return NewECDSAPrivateKeyFromKey(id, key, usage...), nil
+// ecdsaKeyOptions is the options used by `NewECDSAPrivateKeyWithOptions`.
+type ecdsaKeyOptions struct {
+	curve elliptic.Curve
+	usage []ifcrypto.KeyUsage
+}
 
+// WithCurve sets the curve to generate the key on, default is _P-256_.
+func WithCurve(curve elliptic.Curve) ECDSAKeyOption {
+	return func(opts *ecdsaKeyOptions) {
+		opts.curve = curve
 	}
-
-	return nil, fmt.Errorf("unsupported PEM block: %s", block.Type)
-
 }
 
-// NewECDSAPrivateKey generates a new `ECDSAPrivateKey` using the `rand.Reader` as entropy.
-func NewECDSAPrivateKey(id string, bits int, usage ...ifcrypto.KeyUsage) (*ECDSAPrivateKey, error) {
+// WithKeyUsage sets the usage of the generated key.
+func WithKeyUsage(usage ...ifcrypto.KeyUsage) ECDSAKeyOption {
+	return func(opts *ecdsaKeyOptions) {
+		opts.usage = usage
+	}
+}
 
-	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
-	if err != nil {
-		return nil, err
+// ECDSACurve returns the _NIST_ curve with the size of _bits_. Zero returns _P-256_.
+func ECDSACurve(bits int) (elliptic.Curve, error) {
+
+	switch bits {
+	case 224:
+		return elliptic.P224(), nil
+	case 0, 256:
+		return elliptic.P256(), nil
+	case 384:
+		return elliptic.P384(), nil
+	case 521:
+		return elliptic.P521(), nil
 	}
 
-	return NewECDSAPrivateKeyFromKey(id, key, usage...), nil
-}
+	return nil, fmt.Errorf("unsupported ECDSA key size: %d", bits)
 
-// Sign implements the `crypto.Signer` _interface_. The _opts_
-// arguments is not used.
-func (r *ECDSAPrivateKey) Sign(
-	rand io.Reader,
-	digest []byte,
-	opts crypto.SignerOpts,
-) ([]byte, error) {
+}
 
-	return r.key.Sign(rand, digest, opts)
+// SignMessage implements the `ifcrypto.MessageSigner` interface, see `SignMessage`.
+func (r *ECDSAPrivateKey) SignMessage(msg []byte, hash crypto.Hash) ([]byte, error) {
+	return SignMessage(r, msg, hash)
+}
 
+// Public implements the `crypto.Signer` _interface_.
+func (r *ECDSAPrivateKey) Public() crypto.PublicKey {
+	return &r.key.PublicKey
 }
 
 // GetPublic returns the public portion of the key
@@ -120,6 +155,32 @@ func (r *ECDSAPrivateKey) PEMWrite(w io.Writer, public bool) error {
 
 }
 
+// PEMWriteEncrypted implements the `ifcrypto.EncryptedPEMWriter` interface.
+func (r *ECDSAPrivateKey) PEMWriteEncrypted(w io.Writer, passphrase []byte) error {
+
+	return pemWriteEncrypted(w, r, passphrase)
+
+}
+
+// DERWrite implements the `ifcrypto.DERWriter` interface, the key is written as _PKCS #8_.
+func (r *ECDSAPrivateKey) DERWrite(w io.Writer) error {
+
+	return privateKeyDERWrite(w, r)
+
+}
+
+// ToJWK implements the `JWKMarshaler` interface. The _JWK_ includes the private key, use
+// `GetPublic` to get the public _JWK_.
+func (r *ECDSAPrivateKey) ToJWK() (*cryptoutils.JSONWebKey, error) {
+	return keyToJWK(r)
+}
+
+// Fingerprint implements the `Fingerprinter` interface and returns the fingerprint of the
+// public key, see `Fingerprint`.
+func (r *ECDSAPrivateKey) Fingerprint(opts ...FingerprintOption) (string, error) {
+	return Fingerprint(r, opts...)
+}
+
 // GetKey gets the underlying key, if any.
 //
 // Some keys are remote and not possible to fetch. In such situations the function returns a remote id,
@@ -150,10 +211,11 @@ func (r *ECDSAPrivateKey) IsRemoteKey() bool {
 	return false
 }
 
-// ECDSAPublicKey implements the `ifcrypto.PublicKey` interface for `*rsa.PublicKey`
+// ECDSAPublicKey implements the `ifcrypto.PublicKey` interface for `*ecdsa.PublicKey`
 type ECDSAPublicKey struct {
 	KeyBase
 	key *ecdsa.PublicKey
+	pem publicPEM
 }
 
 // NewECDSAPublicKeyFromKey creates a instance based on a existing public key.
@@ -166,7 +228,7 @@ func NewECDSAPublicKeyFromKey(
 	return &ECDSAPublicKey{
 		KeyBase: KeyBase{
 			id:      id,
-			keyType: ifcrypto.KeyTypeRsa,
+			keyType: ifcrypto.KeyTypeEccNistP,
 			keySize: key.Params().BitSize,
 			usage:   usage,
 		},
@@ -192,6 +254,10 @@ func NewECDSAPublicKeyFromPEM(
 
 		if ecdsakey, ok := key.(*ecdsa.PublicKey); ok {
 
+			if err := checkLoadedKey(ecdsakey); err != nil {
+				return nil, err
+			}
+
 			return NewECDSAPublicKeyFromKey(id, ecdsakey, usage...), nil
 
 		}
@@ -204,6 +270,64 @@ func NewECDSAPublicKeyFromPEM(
 
 }
 
+// NewECDSAPublicKeyFromDER initializes a new `ECDSAPublicKey` from _PKIX_ _DER_.
+func NewECDSAPublicKeyFromDER(
+	der []byte,
+	id string,
+	usage ...ifcrypto.KeyUsage,
+) (*ECDSAPublicKey, error) {
+
+	return NewECDSAPublicKeyFromPEM(pem.Block{Type: "PUBLIC KEY", Bytes: der}, id, usage...)
+
+}
+
+// VerifyMessage implements the `ifcrypto.MessageVerifier` interface, see `VerifyMessage`.
+func (r *ECDSAPublicKey) VerifyMessage(msg, signature []byte, hash crypto.Hash) error {
+	return VerifyMessage(r, msg, signature, hash)
+}
+
+// Verify implements the `ifcrypto.SignatureVerifier` interface for a _ASN.1 DER_ signature, or
+// a _r || s_ signature if _opts_ is a `*ECDSASignerOpts` with _Raw_ set.
+//
+// If _opts_ has a hash, the _digest_ must be of the hash size.
+func (r *ECDSAPublicKey) Verify(digest, signature []byte, opts crypto.SignerOpts) error {
+
+	if err := CheckAlgorithm(ifcrypto.PolicyOperationVerify, r, signerHash(opts)); err != nil {
+		return err
+	}
+
+	if opts != nil && opts.HashFunc() != 0 && len(digest) != opts.HashFunc().Size() {
+		return fmt.Errorf("digest must be %d bytes", opts.HashFunc().Size())
+	}
+
+	if isRawECDSA(opts) {
+
+		if len(signature) != 2*cryptoutils.ECDSACoordinateSize(r.key.Curve) {
+			return ifcrypto.ErrInvalidSignature
+		}
+
+		buf := cryptoutils.AcquireBuffer()
+		defer cryptoutils.ReleaseBuffer(buf)
+
+		buf.Grow(len(signature) + 9)
+
+		der, err := cryptoutils.AppendECDSASignatureFromRaw(buf.Bytes(), signature)
+		if err != nil {
+			return ifcrypto.ErrInvalidSignature
+		}
+
+		signature = der
+
+	}
+
+	if !ecdsa.VerifyASN1(r.key, digest, signature) {
+		return ifcrypto.ErrInvalidSignature
+	}
+
+	return nil
+
+}
+
 // PEMWrite will write the key onto _w_.
 //
 // Since this is a public key, it will ignore the _public_ parameter.
@@ -213,6 +337,29 @@ func (r *ECDSAPublicKey) PEMWrite(w io.Writer, public bool) error {
 
 }
 
+// DERWrite implements the `ifcrypto.DERWriter` interface, the key is written as _PKIX_.
+func (r *ECDSAPublicKey) DERWrite(w io.Writer) error {
+
+	return publicKeyDERWrite(w, r)
+
+}
+
+// WriteTo implements the `io.WriterTo` interface and writes the key as _PEM_ onto _w_. The
+// encoding is cached, hence streaming many keys do not encode them over and over again.
+func (r *ECDSAPublicKey) WriteTo(w io.Writer) (int64, error) {
+	return r.pem.writeTo(w, r)
+}
+
+// ToJWK implements the `JWKMarshaler` interface.
+func (r *ECDSAPublicKey) ToJWK() (*cryptoutils.JSONWebKey, error) {
+	return keyToJWK(r)
+}
+
+// Fingerprint implements the `Fingerprinter` interface, see `Fingerprint`.
+func (r *ECDSAPublicKey) Fingerprint(opts ...FingerprintOption) (string, error) {
+	return Fingerprint(r, opts...)
+}
+
 // GetKey gets the underlying key, if any.
 //
 // Some keys are remote and not possible to fetch. In such situations the function returns a remote id,
@@ -232,7 +379,7 @@ func (r *ECDSAPublicKey) IsSymmetric() bool {
 //
 // If `KeyTypeSymmetric` it will return `true` since all symmetric keys are considered as private.
 func (r *ECDSAPublicKey) IsPrivate() bool {
-	return true
+	return false
 }
 
 // IsRemoteKey returns `true` if the key is not present in current process memory.
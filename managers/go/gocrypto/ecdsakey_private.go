@@ -0,0 +1,160 @@
+//go:build !verifyonly
+// +build !verifyonly
+
+package gocrypto
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"io"
+
+	"github.com/mariotoffia/goservice/interfaces/ifcrypto"
+	"github.com/mariotoffia/goservice/utils/cryptoutils"
+)
+
+// NewECDSAPrivateKeyFromPEM initializes a new `*ecdsa.PrivateKey` from the underlying _PEM_ block.
+func NewECDSAPrivateKeyFromPEM(
+	block pem.Block,
+	id string,
+	usage ...ifcrypto.KeyUsage,
+) (*ECDSAPrivateKey, error) {
+
+	if IsVerifyOnly() {
+		return nil, ErrVerifyOnly
+	}
+
+	if block.Type == "PRIVATE KEY" {
+		key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+
+		if err != nil {
+			return nil, err
+		}
+
+		if ecdsakey, ok := key.(*ecdsa.PrivateKey); ok {
+
+			if err := checkLoadedKey(&ecdsakey.PublicKey); err != nil {
+				return nil, err
+			}
+
+			return NewECDSAPrivateKeyFromKey(id, ecdsakey, usage...), nil
+
+		}
+
+		return nil, fmt.Errorf("not a *ecdsa.PrivateKey: %T", key)
+
+	}
+
+	if block.Type == "EC PRIVATE KEY" {
+
+		key, err := x509.ParseECPrivateKey(block.Bytes)
+		if err != nil {
+			return nil, err
+		}
+
+		if err := checkLoadedKey(&key.PublicKey); err != nil {
+			return nil, err
+		}
+
+		return NewECDSAPrivateKeyFromKey(id, key, usage...), nil
+
+	}
+
+	return nil, fmt.Errorf("unsupported PEM block: %s", block.Type)
+
+}
+
+// NewECDSAPrivateKeyWithOptions generates a new `ECDSAPrivateKey` using the `rand.Reader`, or the `EntropyGuard` reader, as entropy.
+func NewECDSAPrivateKeyWithOptions(id string, opts ...ECDSAKeyOption) (*ECDSAPrivateKey, error) {
+
+	if IsVerifyOnly() {
+		return nil, ErrVerifyOnly
+	}
+
+	options := &ecdsaKeyOptions{curve: elliptic.P256()}
+
+	for _, opt := range opts {
+		opt(options)
+	}
+
+	if options.curve == nil {
+		return nil, fmt.Errorf("must specify a curve")
+	}
+
+	reader, err := keyGenerationReader()
+	if err != nil {
+		return nil, err
+	}
+
+	key, err := ecdsa.GenerateKey(options.curve, reader)
+	if err != nil {
+		return nil, err
+	}
+
+	return NewECDSAPrivateKeyFromKey(id, key, options.usage...), nil
+
+}
+
+// Sign implements the `crypto.Signer` _interface_. The signature is _ASN.1 DER_ encoded unless
+// _opts_ is a `*ECDSASignerOpts` with _Raw_ set, otherwise _opts_ is not used.
+func (r *ECDSAPrivateKey) Sign(
+	rand io.Reader,
+	digest []byte,
+	opts crypto.SignerOpts,
+) ([]byte, error) {
+
+	if IsVerifyOnly() {
+		return nil, ErrVerifyOnly
+	}
+
+	if err := CheckAlgorithm(ifcrypto.PolicyOperationSign, r, signerHash(opts)); err != nil {
+		return nil, err
+	}
+
+	signature, err := r.key.Sign(rand, digest, opts)
+	if err != nil || !isRawECDSA(opts) {
+		return signature, err
+	}
+
+	return cryptoutils.ECDSASignatureToRaw(signature, cryptoutils.ECDSACoordinateSize(r.key.Curve))
+
+}
+
+// DeriveSharedSecret implements the `ifcrypto.KeyAgreement` interface using _ECDH_.
+//
+// The _peer_ must be a _NIST_ curve key on the same curve as this key.
+func (r *ECDSAPrivateKey) DeriveSharedSecret(peer ifcrypto.PublicKey) ([]byte, error) {
+
+	if IsVerifyOnly() {
+		return nil, ErrVerifyOnly
+	}
+
+	if err := CheckAlgorithm(ifcrypto.PolicyOperationKeyAgreement, r, 0); err != nil {
+		return nil, err
+	}
+
+	pub, ok := peer.GetKey().(*ecdsa.PublicKey)
+	if !ok || peer.GetKeyType() != ifcrypto.KeyTypeEccNistP {
+		return nil, fmt.Errorf("peer key must be %s, got %s", ifcrypto.KeyTypeEccNistP, peer.GetKeyType())
+	}
+
+	if pub.Curve != r.key.Curve {
+		return nil, fmt.Errorf("peer key curve %s do not match %s", pub.Params().Name, r.key.Params().Name)
+	}
+
+	priv, err := r.key.ECDH()
+	if err != nil {
+		return nil, err
+	}
+
+	peerPub, err := pub.ECDH()
+	if err != nil {
+		return nil, err
+	}
+
+	return priv.ECDH(peerPub)
+
+}
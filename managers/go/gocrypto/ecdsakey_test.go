@@ -0,0 +1,104 @@
+//go:build !verifyonly
+// +build !verifyonly
+
+package gocrypto
+
+import (
+	"crypto"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha512"
+	"testing"
+
+	"github.com/mariotoffia/goservice/interfaces/ifcrypto"
+	"github.com/mariotoffia/goservice/utils/cryptoutils"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewECDSAPrivateKeySelectsCurveFromBits(t *testing.T) {
+
+	for _, bits := range []int{224, 256, 384, 521} {
+
+		key, err := NewECDSAPrivateKey("ec", bits, ifcrypto.KeyUsageSign)
+		require.NoError(t, err)
+
+		assert.Equal(t, bits, key.GetKeySize())
+		assert.Equal(t, bits, key.GetPublic().GetKeySize())
+
+	}
+
+	_, err := NewECDSAPrivateKey("ec", 512)
+	assert.Error(t, err)
+
+}
+
+func TestNewECDSAPrivateKeyWithCurve(t *testing.T) {
+
+	key, err := NewECDSAPrivateKeyWithOptions(
+		"ec", WithCurve(elliptic.P384()), WithKeyUsage(ifcrypto.KeyUsageSign),
+	)
+
+	require.NoError(t, err)
+
+	assert.Equal(t, 384, key.GetKeySize())
+	assert.True(t, key.HasUsage(ifcrypto.KeyUsageSign))
+
+}
+
+func TestECDSADeriveSharedSecret(t *testing.T) {
+
+	alice, err := NewECDSAPrivateKey("alice", 384, ifcrypto.KeyUsageKeyAgreement)
+	require.NoError(t, err)
+
+	bob, err := NewECDSAPrivateKey("bob", 384, ifcrypto.KeyUsageKeyAgreement)
+	require.NoError(t, err)
+
+	var _ ifcrypto.KeyAgreement = alice
+
+	s1, err := alice.DeriveSharedSecret(bob.GetPublic())
+	require.NoError(t, err)
+
+	s2, err := bob.DeriveSharedSecret(alice.GetPublic())
+	require.NoError(t, err)
+
+	assert.Equal(t, s1, s2)
+	assert.Len(t, s1, 48)
+
+	other, err := NewECDSAPrivateKey("other", 256)
+	require.NoError(t, err)
+
+	_, err = alice.DeriveSharedSecret(other.GetPublic())
+	assert.Error(t, err)
+
+}
+
+func TestECDSASignRawSignature(t *testing.T) {
+
+	key, err := NewECDSAPrivateKey("ec", 384, ifcrypto.KeyUsageSign)
+	require.NoError(t, err)
+
+	digest := sha512.Sum384([]byte("message"))
+	opts := &ECDSASignerOpts{Hash: crypto.SHA384, Raw: true}
+
+	signature, err := key.Sign(rand.Reader, digest[:], opts)
+	require.NoError(t, err)
+	assert.Len(t, signature, 96)
+
+	verifier := key.GetPublic().(ifcrypto.SignatureVerifier)
+
+	assert.NoError(t, verifier.Verify(digest[:], signature, opts))
+	assert.Error(t, verifier.Verify(digest[:], signature, crypto.SHA384))
+
+	der, err := cryptoutils.ECDSASignatureFromRaw(signature)
+	require.NoError(t, err)
+	assert.NoError(t, verifier.Verify(digest[:], der, crypto.SHA384))
+
+	k1, err := NewSecp256k1PrivateKey("k1", ifcrypto.KeyUsageSign)
+	require.NoError(t, err)
+
+	signature, err = k1.Sign(rand.Reader, digest[:32], &ECDSASignerOpts{Hash: crypto.SHA256, Raw: true})
+	require.NoError(t, err)
+	assert.Len(t, signature, 64)
+
+}
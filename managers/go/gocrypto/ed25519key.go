@@ -0,0 +1,302 @@
+package gocrypto
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"io"
+
+	"github.com/mariotoffia/goservice/interfaces/ifcrypto"
+	"github.com/mariotoffia/goservice/utils/cryptoutils"
+)
+
+// ED25519PrivateKey implements the `ifcrypto.KeyPair` interface for a `ed25519.PrivateKey`.
+type ED25519PrivateKey struct {
+	KeyBase
+	key    ed25519.PrivateKey
+	public *ED25519PublicKey
+}
+
+// NewED25519PrivateKeyFromKey creates a new `ED25519PrivateKey`
+//
+// The public key portion derives the same usage as the private key
+func NewED25519PrivateKeyFromKey(
+	id string,
+	key ed25519.PrivateKey,
+	usage ...ifcrypto.KeyUsage,
+) *ED25519PrivateKey {
+
+	return &ED25519PrivateKey{
+		KeyBase: KeyBase{
+			id:      id,
+			keyType: ifcrypto.KeyTypeEd25519,
+			keySize: 256,
+			usage:   usage,
+			chiper:  []ifcrypto.Chipher{},
+		},
+		key:    key,
+		public: NewED25519PublicKeyFromKey(id, key.Public().(ed25519.PublicKey), usage...),
+	}
+
+}
+
+// NewED25519PrivateKeyFromDER initializes a new `ED25519PrivateKey` from _PKCS #8_ _DER_.
+func NewED25519PrivateKeyFromDER(
+	der []byte,
+	id string,
+	usage ...ifcrypto.KeyUsage,
+) (*ED25519PrivateKey, error) {
+
+	return NewED25519PrivateKeyFromPEM(pem.Block{Type: "PRIVATE KEY", Bytes: der}, id, usage...)
+
+}
+
+// SignMessage implements the `ifcrypto.MessageSigner` interface, see `SignMessage`.
+func (r *ED25519PrivateKey) SignMessage(msg []byte, hash crypto.Hash) ([]byte, error) {
+	return SignMessage(r, msg, hash)
+}
+
+// Public implements the `crypto.Signer` _interface_.
+func (r *ED25519PrivateKey) Public() crypto.PublicKey {
+	return r.key.Public()
+}
+
+// GetPublic returns the public portion of the key
+func (r *ED25519PrivateKey) GetPublic() ifcrypto.PublicKey {
+	return r.public
+}
+
+// PEMWrite will write the key onto _w_.
+//
+// If private key, and _public_ is `true`, it will in addition write the public portion as well.
+func (r *ED25519PrivateKey) PEMWrite(w io.Writer, public bool) error {
+
+	return cryptoutils.ED25519PrivateKeyToPEM(w, r.key, public)
+
+}
+
+// PEMWriteEncrypted implements the `ifcrypto.EncryptedPEMWriter` interface.
+func (r *ED25519PrivateKey) PEMWriteEncrypted(w io.Writer, passphrase []byte) error {
+
+	return pemWriteEncrypted(w, r, passphrase)
+
+}
+
+// DERWrite implements the `ifcrypto.DERWriter` interface, the key is written as _PKCS #8_.
+func (r *ED25519PrivateKey) DERWrite(w io.Writer) error {
+
+	return privateKeyDERWrite(w, r)
+
+}
+
+// ToJWK implements the `JWKMarshaler` interface. The _JWK_ includes the private key, use
+// `GetPublic` to get the public _JWK_.
+func (r *ED25519PrivateKey) ToJWK() (*cryptoutils.JSONWebKey, error) {
+	return keyToJWK(r)
+}
+
+// Fingerprint implements the `Fingerprinter` interface and returns the fingerprint of the
+// public key, see `Fingerprint`.
+func (r *ED25519PrivateKey) Fingerprint(opts ...FingerprintOption) (string, error) {
+	return Fingerprint(r, opts...)
+}
+
+// GetKey gets the underlying key, if any.
+//
+// Some keys are remote and not possible to fetch. In such situations the function returns a remote id,
+// most often the same as GetID() returns.
+func (r *ED25519PrivateKey) GetKey() interface{} {
+	return r.key
+}
+
+// IsSymmetric returns `true` if this is a `KeyTypeSymmetric`
+//
+// This is a convenience function instead of `GetKeyType`.
+func (r *ED25519PrivateKey) IsSymmetric() bool {
+	return false
+}
+
+// IsPrivate returns `true` if this is a `KeyType` other than `KeyTypeSymmetric` and is a private key.
+//
+// If `KeyTypeSymmetric` it will return `true` since all symmetric keys are considered as private.
+func (r *ED25519PrivateKey) IsPrivate() bool {
+	return true
+}
+
+// IsRemoteKey returns `true` if the key is not present in current process memory.
+//
+// Typically hardware units or remote services will not reveal their private key. In such case, this
+// method returns `true`. If present in memory such as a `ed25519.PrivateKey` it returns `false`.
+func (r *ED25519PrivateKey) IsRemoteKey() bool {
+	return false
+}
+
+// ED25519PublicKey implements the `ifcrypto.PublicKey` interface for `ed25519.PublicKey`
+type ED25519PublicKey struct {
+	KeyBase
+	key ed25519.PublicKey
+	pem publicPEM
+}
+
+// NewED25519PublicKeyFromKey creates a instance based on a existing public key.
+func NewED25519PublicKeyFromKey(
+	id string,
+	key ed25519.PublicKey,
+	usage ...ifcrypto.KeyUsage,
+) *ED25519PublicKey {
+
+	return &ED25519PublicKey{
+		KeyBase: KeyBase{
+			id:      id,
+			keyType: ifcrypto.KeyTypeEd25519,
+			keySize: 256,
+			usage:   usage,
+		},
+		key: key,
+	}
+
+}
+
+// NewED25519PublicKeyFromPEM initializes a new `ed25519.PublicKey` from the underlying _PKIX_ _PEM_ block.
+func NewED25519PublicKeyFromPEM(
+	block pem.Block,
+	id string,
+	usage ...ifcrypto.KeyUsage,
+) (*ED25519PublicKey, error) {
+
+	if block.Type == "PUBLIC KEY" {
+
+		key, err := x509.ParsePKIXPublicKey(block.Bytes)
+		if err != nil {
+			return nil, err
+		}
+
+		if edkey, ok := key.(ed25519.PublicKey); ok {
+
+			if err := checkLoadedKey(edkey); err != nil {
+				return nil, err
+			}
+
+			return NewED25519PublicKeyFromKey(id, edkey, usage...), nil
+
+		}
+
+		return nil, fmt.Errorf("not a ed25519.PublicKey: %T", key)
+
+	}
+
+	return nil, fmt.Errorf("unsupported PEM block: %s", block.Type)
+
+}
+
+// NewED25519PublicKeyFromDER initializes a new `ED25519PublicKey` from _PKIX_ _DER_.
+func NewED25519PublicKeyFromDER(
+	der []byte,
+	id string,
+	usage ...ifcrypto.KeyUsage,
+) (*ED25519PublicKey, error) {
+
+	return NewED25519PublicKeyFromPEM(pem.Block{Type: "PUBLIC KEY", Bytes: der}, id, usage...)
+
+}
+
+// VerifyMessage implements the `ifcrypto.MessageVerifier` interface, see `VerifyMessage`.
+func (r *ED25519PublicKey) VerifyMessage(msg, signature []byte, hash crypto.Hash) error {
+	return VerifyMessage(r, msg, signature, hash)
+}
+
+// Verify implements the `ifcrypto.SignatureVerifier` interface.
+//
+// The _digest_ is the message itself unless _opts_ is a `*ed25519.Options` with _SHA-512_,
+// in which case it is the _SHA-512_ of the message, i.e. _Ed25519ph_.
+func (r *ED25519PublicKey) Verify(digest, signature []byte, opts crypto.SignerOpts) error {
+
+	if err := CheckAlgorithm(ifcrypto.PolicyOperationVerify, r, signerHash(opts)); err != nil {
+		return err
+	}
+
+	if o, ok := opts.(*ed25519.Options); ok {
+
+		if err := ed25519.VerifyWithOptions(r.key, digest, signature, o); err != nil {
+			return ifcrypto.ErrInvalidSignature
+		}
+
+		return nil
+
+	}
+
+	if opts != nil && opts.HashFunc() != 0 {
+		return fmt.Errorf("ed25519 signs the message, got hash %s", opts.HashFunc())
+	}
+
+	if !ed25519.Verify(r.key, digest, signature) {
+		return ifcrypto.ErrInvalidSignature
+	}
+
+	return nil
+
+}
+
+// PEMWrite will write the key onto _w_.
+//
+// Since this is a public key, it will ignore the _public_ parameter.
+func (r *ED25519PublicKey) PEMWrite(w io.Writer, public bool) error {
+
+	return cryptoutils.ED25519PublicKeyToPEM(w, r.key)
+
+}
+
+// DERWrite implements the `ifcrypto.DERWriter` interface, the key is written as _PKIX_.
+func (r *ED25519PublicKey) DERWrite(w io.Writer) error {
+
+	return publicKeyDERWrite(w, r)
+
+}
+
+// WriteTo implements the `io.WriterTo` interface and writes the key as _PEM_ onto _w_. The
+// encoding is cached, hence streaming many keys do not encode them over and over again.
+func (r *ED25519PublicKey) WriteTo(w io.Writer) (int64, error) {
+	return r.pem.writeTo(w, r)
+}
+
+// ToJWK implements the `JWKMarshaler` interface.
+func (r *ED25519PublicKey) ToJWK() (*cryptoutils.JSONWebKey, error) {
+	return keyToJWK(r)
+}
+
+// Fingerprint implements the `Fingerprinter` interface, see `Fingerprint`.
+func (r *ED25519PublicKey) Fingerprint(opts ...FingerprintOption) (string, error) {
+	return Fingerprint(r, opts...)
+}
+
+// GetKey gets the underlying key, if any.
+//
+// Some keys are remote and not possible to fetch. In such situations the function returns a remote id,
+// most often the same as GetID() returns.
+func (r *ED25519PublicKey) GetKey() interface{} {
+	return r.key
+}
+
+// IsSymmetric returns `true` if this is a `KeyTypeSymmetric`
+//
+// This is a convenience function instead of `GetKeyType`.
+func (r *ED25519PublicKey) IsSymmetric() bool {
+	return false
+}
+
+// IsPrivate returns `true` if this is a `KeyType` other than `KeyTypeSymmetric` and is a private key.
+//
+// If `KeyTypeSymmetric` it will return `true` since all symmetric keys are considered as private.
+func (r *ED25519PublicKey) IsPrivate() bool {
+	return false
+}
+
+// IsRemoteKey returns `true` if the key is not present in current process memory.
+//
+// Typically hardware units or remote services will not reveal their private key. In such case, this
+// method returns `true`. If present in memory such as a `ed25519.PublicKey` it returns `false`.
+func (r *ED25519PublicKey) IsRemoteKey() bool {
+	return false
+}
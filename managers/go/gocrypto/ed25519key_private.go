@@ -0,0 +1,92 @@
+//go:build !verifyonly
+// +build !verifyonly
+
+package gocrypto
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"io"
+
+	"github.com/mariotoffia/goservice/interfaces/ifcrypto"
+)
+
+// NewED25519PrivateKeyFromPEM initializes a new `ed25519.PrivateKey` from the underlying _PKCS #8_ _PEM_ block.
+func NewED25519PrivateKeyFromPEM(
+	block pem.Block,
+	id string,
+	usage ...ifcrypto.KeyUsage,
+) (*ED25519PrivateKey, error) {
+
+	if IsVerifyOnly() {
+		return nil, ErrVerifyOnly
+	}
+
+	if block.Type == "PRIVATE KEY" {
+
+		key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+		if err != nil {
+			return nil, err
+		}
+
+		if edkey, ok := key.(ed25519.PrivateKey); ok {
+
+			if err := checkLoadedKey(edkey.Public()); err != nil {
+				return nil, err
+			}
+
+			return NewED25519PrivateKeyFromKey(id, edkey, usage...), nil
+
+		}
+
+		return nil, fmt.Errorf("not a ed25519.PrivateKey: %T", key)
+
+	}
+
+	return nil, fmt.Errorf("unsupported PEM block: %s", block.Type)
+
+}
+
+// NewED25519PrivateKey generates a new `ED25519PrivateKey` using the `rand.Reader`, or the `EntropyGuard` reader, as entropy.
+func NewED25519PrivateKey(id string, usage ...ifcrypto.KeyUsage) (*ED25519PrivateKey, error) {
+
+	if IsVerifyOnly() {
+		return nil, ErrVerifyOnly
+	}
+
+	reader, err := keyGenerationReader()
+	if err != nil {
+		return nil, err
+	}
+
+	_, key, err := ed25519.GenerateKey(reader)
+	if err != nil {
+		return nil, err
+	}
+
+	return NewED25519PrivateKeyFromKey(id, key, usage...), nil
+
+}
+
+// Sign implements the `crypto.Signer` _interface_. The _message_ is signed as is, i.e. not
+// a digest, and _opts_ must be `crypto.Hash(0)`.
+func (r *ED25519PrivateKey) Sign(
+	rand io.Reader,
+	message []byte,
+	opts crypto.SignerOpts,
+) ([]byte, error) {
+
+	if IsVerifyOnly() {
+		return nil, ErrVerifyOnly
+	}
+
+	if err := CheckAlgorithm(ifcrypto.PolicyOperationSign, r, signerHash(opts)); err != nil {
+		return nil, err
+	}
+
+	return r.key.Sign(rand, message, opts)
+
+}
@@ -0,0 +1,54 @@
+//go:build !verifyonly
+// +build !verifyonly
+
+package gocrypto
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/pem"
+	"testing"
+
+	"github.com/mariotoffia/goservice/interfaces/ifcrypto"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestED25519KeyPEMRoundTrip(t *testing.T) {
+
+	key, err := NewED25519PrivateKey("token-key", ifcrypto.KeyUsageSign, ifcrypto.KeyUsageVerify)
+	require.NoError(t, err)
+
+	var _ ifcrypto.KeyPair = key
+	var _ crypto.Signer = key
+
+	assert.Equal(t, ifcrypto.KeyTypeEd25519, key.GetKeyType())
+	assert.Equal(t, 256, key.GetKeySize())
+	assert.True(t, key.CanSign(ifcrypto.SignAlgorithmEd25519))
+	assert.False(t, key.CanSign(ifcrypto.SignAlgorithmEcdSha256))
+
+	var buf bytes.Buffer
+	require.NoError(t, key.PEMWrite(&buf, true))
+
+	privBlock, rest := pem.Decode(buf.Bytes())
+	require.NotNil(t, privBlock)
+	pubBlock, _ := pem.Decode(rest)
+	require.NotNil(t, pubBlock)
+
+	loaded, err := NewED25519PrivateKeyFromPEM(*privBlock, "token-key", ifcrypto.KeyUsageSign)
+	require.NoError(t, err)
+	assert.Equal(t, key.GetKey(), loaded.GetKey())
+
+	pub, err := NewED25519PublicKeyFromPEM(*pubBlock, "token-key", ifcrypto.KeyUsageVerify)
+	require.NoError(t, err)
+
+	message := []byte("header.payload")
+	sig, err := loaded.Sign(rand.Reader, message, crypto.Hash(0))
+	require.NoError(t, err)
+
+	assert.True(t, ed25519.Verify(pub.GetKey().(ed25519.PublicKey), message, sig))
+	assert.True(t, ed25519.Verify(key.GetPublic().GetKey().(ed25519.PublicKey), message, sig))
+
+}
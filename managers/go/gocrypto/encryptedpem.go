@@ -0,0 +1,83 @@
+package gocrypto
+
+import (
+	"bytes"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/pem"
+	"fmt"
+	"io"
+
+	"github.com/mariotoffia/goservice/interfaces/ifcrypto"
+	"github.com/mariotoffia/goservice/utils/cryptoutils"
+)
+
+var (
+	oidPKCS8RSA       = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 1, 1}
+	oidPKCS8EC        = asn1.ObjectIdentifier{1, 2, 840, 10045, 2, 1}
+	oidPKCS8X25519    = asn1.ObjectIdentifier{1, 3, 101, 110}
+	oidPKCS8Ed25519   = asn1.ObjectIdentifier{1, 3, 101, 112}
+	oidPKCS8Secp256k1 = asn1.ObjectIdentifier{1, 3, 132, 0, 10}
+)
+
+// newKeyPairFromPKCS8 creates the key pair matching the algorithm of the _PKCS #8_ _PRIVATE KEY_
+// _block_.
+func newKeyPairFromPKCS8(block pem.Block, id string, usage ...ifcrypto.KeyUsage) (ifcrypto.KeyPair, error) {
+
+	var info struct {
+		Version    int
+		Algorithm  pkix.AlgorithmIdentifier
+		PrivateKey []byte
+	}
+
+	if _, err := asn1.Unmarshal(block.Bytes, &info); err != nil {
+		return nil, err
+	}
+
+	switch algorithm := info.Algorithm.Algorithm; {
+	case algorithm.Equal(oidPKCS8RSA):
+		return NewRSAPrivateKeyFromPEM(block, id, usage...)
+	case algorithm.Equal(oidPKCS8Ed25519):
+		return NewED25519PrivateKeyFromPEM(block, id, usage...)
+	case algorithm.Equal(oidPKCS8X25519):
+		return NewX25519PrivateKeyFromPEM(block, id, usage...)
+	case algorithm.Equal(oidPKCS8EC):
+
+		var curve asn1.ObjectIdentifier
+
+		if _, err := asn1.Unmarshal(info.Algorithm.Parameters.FullBytes, &curve); err == nil &&
+			curve.Equal(oidPKCS8Secp256k1) {
+
+			return NewSecp256k1PrivateKeyFromPEM(block, id, usage...)
+		}
+
+		return NewECDSAPrivateKeyFromPEM(block, id, usage...)
+
+	}
+
+	return nil, fmt.Errorf("unsupported PKCS#8 private key algorithm: %s", info.Algorithm.Algorithm)
+
+}
+
+// pemWriteEncrypted writes the private key of _writer_ encrypted with _passphrase_ onto _w_.
+func pemWriteEncrypted(w io.Writer, writer ifcrypto.PEMWriter, passphrase []byte) error {
+
+	var buf bytes.Buffer
+
+	if err := writer.PEMWrite(&buf, false); err != nil {
+		return err
+	}
+
+	block, _ := pem.Decode(buf.Bytes())
+	if block == nil {
+		return fmt.Errorf("key did not encode to PEM")
+	}
+
+	encrypted, err := cryptoutils.EncryptPrivateKeyPEM(block, passphrase, 0)
+	if err != nil {
+		return err
+	}
+
+	return pem.Encode(w, encrypted)
+
+}
@@ -0,0 +1,35 @@
+//go:build !verifyonly
+// +build !verifyonly
+
+package gocrypto
+
+import (
+	"encoding/pem"
+
+	"github.com/mariotoffia/goservice/interfaces/ifcrypto"
+	"github.com/mariotoffia/goservice/utils/cryptoutils"
+)
+
+// NewKeyPairFromEncryptedPEM decrypts the _ENCRYPTED PRIVATE KEY_ _block_ with _passphrase_
+// and creates the matching key pair, e.g. a `RSAPrivateKey` or a `ED25519PrivateKey`.
+//
+// It returns `cryptoutils.ErrIncorrectPassphrase` when the _passphrase_ is wrong.
+func NewKeyPairFromEncryptedPEM(
+	block pem.Block,
+	passphrase []byte,
+	id string,
+	usage ...ifcrypto.KeyUsage,
+) (ifcrypto.KeyPair, error) {
+
+	if IsVerifyOnly() {
+		return nil, ErrVerifyOnly
+	}
+
+	decrypted, err := cryptoutils.DecryptPrivateKeyPEM(&block, passphrase)
+	if err != nil {
+		return nil, err
+	}
+
+	return newKeyPairFromPKCS8(*decrypted, id, usage...)
+
+}
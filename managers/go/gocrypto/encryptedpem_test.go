@@ -0,0 +1,55 @@
+//go:build !verifyonly
+// +build !verifyonly
+
+package gocrypto
+
+import (
+	"bytes"
+	"encoding/pem"
+	"testing"
+
+	"github.com/mariotoffia/goservice/interfaces/ifcrypto"
+	"github.com/mariotoffia/goservice/utils/cryptoutils"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEncryptedPEMRoundTripAllKeyPairs(t *testing.T) {
+
+	rsaKey, err := NewRSAPrivateKey("rsa", 2048, ifcrypto.KeyUsageSign)
+	require.NoError(t, err)
+
+	ecKey, err := NewECDSAPrivateKey("ec", 384, ifcrypto.KeyUsageSign)
+	require.NoError(t, err)
+
+	edKey, err := NewED25519PrivateKey("ed", ifcrypto.KeyUsageSign)
+	require.NoError(t, err)
+
+	k1Key, err := NewSecp256k1PrivateKey("k1", ifcrypto.KeyUsageSign)
+	require.NoError(t, err)
+
+	xKey, err := NewX25519PrivateKey("x", ifcrypto.KeyUsageKeyAgreement)
+	require.NoError(t, err)
+
+	for _, key := range []ifcrypto.KeyPair{rsaKey, ecKey, edKey, k1Key, xKey} {
+
+		var buf bytes.Buffer
+		require.NoError(t, key.(ifcrypto.EncryptedPEMWriter).PEMWriteEncrypted(&buf, []byte("passphrase")))
+
+		block, _ := pem.Decode(buf.Bytes())
+		require.NotNil(t, block)
+		assert.Equal(t, cryptoutils.EncryptedPrivateKeyPEMType, block.Type)
+
+		loaded, err := NewKeyPairFromEncryptedPEM(*block, []byte("passphrase"), key.GetID(), key.GetKeyUsage()...)
+		require.NoError(t, err, key.GetID())
+
+		assert.IsType(t, key, loaded)
+		assert.Equal(t, key.GetKeyType(), loaded.GetKeyType())
+		assert.Equal(t, key.GetPublic().GetKey(), loaded.GetPublic().GetKey())
+
+		_, err = NewKeyPairFromEncryptedPEM(*block, []byte("wrong"), key.GetID())
+		assert.Equal(t, cryptoutils.ErrIncorrectPassphrase, err)
+
+	}
+
+}
@@ -0,0 +1,398 @@
+package gocrypto
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha512"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ErrInsufficientEntropy is returned by key generation when the `EntropyGuard` is unhealthy.
+var ErrInsufficientEntropy = fmt.Errorf("insufficient entropy")
+
+// Health test parameters of _NIST SP 800-90B_ section 4.4 for a byte source claimed to have
+// full entropy, with a false positive probability of 2^-20.
+const (
+	entropyRCTCutoff  = 4
+	entropyAPTWindow  = 512
+	entropyAPTCutoff  = 13
+	entropyTestLength = 4096
+)
+
+var (
+	entropyGuardMu sync.RWMutex
+	entropyGuard   *EntropyGuard
+)
+
+// SetEntropyGuard sets the _guard_ that gates all key generation in this package. While the
+// guard is unhealthy, key generation fails with `ErrInsufficientEntropy`.
+//
+// By default no guard is used. Set _guard_ to `nil` to disable it again.
+func SetEntropyGuard(guard *EntropyGuard) {
+
+	entropyGuardMu.Lock()
+	defer entropyGuardMu.Unlock()
+
+	entropyGuard = guard
+
+}
+
+// keyGenerationReader returns the entropy source for key generation, it fails if the
+// `EntropyGuard` is unhealthy.
+func keyGenerationReader() (io.Reader, error) {
+
+	entropyGuardMu.RLock()
+	guard := entropyGuard
+	entropyGuardMu.RUnlock()
+
+	if guard == nil {
+		return rand.Reader, nil
+	}
+
+	if err := guard.Err(); err != nil {
+		return nil, err
+	}
+
+	return guard.Reader(), nil
+
+}
+
+// EntropyHealth is the outcome of a `EntropyGuard` health check.
+type EntropyHealth struct {
+	Time time.Time
+	// Estimate is the entropy estimate of the kernel in bits, -1 if not available.
+	Estimate int
+	// Err is set when the check failed, it wraps `ErrInsufficientEntropy`.
+	Err error
+}
+
+// EntropyGuardOption configures a `EntropyGuard`.
+type EntropyGuardOption func(g *EntropyGuard)
+
+// WithMinEntropy sets the minimum kernel entropy estimate in bits (default 256).
+func WithMinEntropy(bits int) EntropyGuardOption {
+	return func(g *EntropyGuard) {
+		g.minEntropy = bits
+	}
+}
+
+// WithEntropyWait blocks a check up to _timeout_ while the kernel estimate is below the
+// minimum, e.g. on the first boot of a embedded device (default zero, fail at once).
+func WithEntropyWait(timeout time.Duration) EntropyGuardOption {
+	return func(g *EntropyGuard) {
+		g.wait = timeout
+	}
+}
+
+// WithEntropyEstimator replaces the kernel entropy estimator. It returns the estimate in bits,
+// or -1 if not available. The default reads _/proc/sys/kernel/random/entropy_avail_.
+func WithEntropyEstimator(estimator func() (int, error)) EntropyGuardOption {
+	return func(g *EntropyGuard) {
+		g.estimator = estimator
+	}
+}
+
+// WithEntropySource sets the source that is health tested (default `rand.Reader`).
+func WithEntropySource(source io.Reader) EntropyGuardOption {
+	return func(g *EntropyGuard) {
+		g.source = source
+	}
+}
+
+// WithJitterEntropy mixes a _CPU_ timing jitter collector into the entropy used for key
+// generation, such that a weak system source alone do not determine the keys.
+//
+// NOTE: The standard library generates _RSA_ and _ECDSA_ keys from its own source, hence
+// the jitter only reaches the keys generated from raw bytes, e.g. _Ed25519_ and _AES_.
+func WithJitterEntropy() EntropyGuardOption {
+	return func(g *EntropyGuard) {
+		g.jitter = true
+	}
+}
+
+// WithEntropyCheckInterval sets the interval of the periodic checks when started, see
+// `EntropyGuard.Start` (default one minute).
+func WithEntropyCheckInterval(interval time.Duration) EntropyGuardOption {
+	return func(g *EntropyGuard) {
+		g.interval = interval
+	}
+}
+
+// WithEntropyAuditor sets a function that receives the outcome of all checks.
+func WithEntropyAuditor(auditor func(health EntropyHealth)) EntropyGuardOption {
+	return func(g *EntropyGuard) {
+		g.auditor = auditor
+	}
+}
+
+// EntropyGuard checks the health of the entropy source at startup and periodically, see
+// `Start` or `Run`, and gates key generation on it, see `SetEntropyGuard`.
+//
+// A check waits for the kernel entropy estimate, if available, and runs the repetition count
+// and adaptive proportion tests of _NIST SP 800-90B_ on a sample of the source. This detects
+// a stuck or heavily biased source, it do not prove that the source is unpredictable.
+type EntropyGuard struct {
+	minEntropy int
+	wait       time.Duration
+	estimator  func() (int, error)
+	source     io.Reader
+	jitter     bool
+	auditor    func(health EntropyHealth)
+	interval   time.Duration
+	background *Background
+	mu         sync.RWMutex
+	err        error
+}
+
+// NewEntropyGuard creates a new `EntropyGuard` and runs the startup check. It fails if the
+// startup check fails.
+func NewEntropyGuard(opts ...EntropyGuardOption) (*EntropyGuard, error) {
+
+	g := &EntropyGuard{
+		minEntropy: 256,
+		estimator:  kernelEntropyEstimate,
+		source:     rand.Reader,
+		auditor:    func(EntropyHealth) {},
+		interval:   time.Minute,
+	}
+
+	for _, opt := range opts {
+		opt(g)
+	}
+
+	g.background = NewTickerBackground("entropy guard", g.interval, func(context.Context) { g.Check() })
+
+	if health := g.Check(); health.Err != nil {
+		return nil, health.Err
+	}
+
+	return g, nil
+
+}
+
+// Err returns the error of the last check, or `nil` if healthy.
+func (g *EntropyGuard) Err() error {
+
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+
+	return g.err
+
+}
+
+// Reader returns the entropy source for key generation.
+func (g *EntropyGuard) Reader() io.Reader {
+
+	if g.jitter {
+		return &mixedReader{a: g.source, b: jitterReader{}}
+	}
+
+	return g.source
+
+}
+
+// Check runs a health check now and returns its outcome.
+func (g *EntropyGuard) Check() EntropyHealth {
+
+	health := EntropyHealth{Time: time.Now().UTC(), Estimate: -1}
+
+	health.Estimate, health.Err = g.awaitEstimate()
+
+	if health.Err == nil {
+		health.Err = g.testSource()
+	}
+
+	g.mu.Lock()
+	g.err = health.Err
+	g.mu.Unlock()
+
+	g.auditor(health)
+
+	return health
+
+}
+
+// Start implements the `ifcrypto.Lifecycle` interface, it checks the health periodically, see
+// `WithEntropyCheckInterval`, until stopped.
+func (g *EntropyGuard) Start(c context.Context) error {
+	return g.background.Start(c)
+}
+
+// Stop implements the `ifcrypto.Lifecycle` interface.
+func (g *EntropyGuard) Stop(c context.Context) error {
+	return g.background.Stop(c)
+}
+
+// Run checks the health every _interval_ until _c_ is done.
+func (g *EntropyGuard) Run(c context.Context, interval time.Duration) {
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+
+		select {
+		case <-c.Done():
+			return
+		case <-ticker.C:
+			g.Check()
+		}
+
+	}
+
+}
+
+// awaitEstimate waits, up to the configured wait, for the kernel estimate to reach the minimum.
+func (g *EntropyGuard) awaitEstimate() (int, error) {
+
+	deadline := time.Now().Add(g.wait)
+
+	for {
+
+		estimate, err := g.estimator()
+		if err != nil {
+			return -1, fmt.Errorf("%w: %v", ErrInsufficientEntropy, err)
+		}
+
+		if estimate < 0 || estimate >= g.minEntropy {
+			return estimate, nil
+		}
+
+		if !time.Now().Before(deadline) {
+			return estimate, fmt.Errorf("%w: estimate %d bits, want %d", ErrInsufficientEntropy, estimate, g.minEntropy)
+		}
+
+		time.Sleep(100 * time.Millisecond)
+
+	}
+
+}
+
+// testSource runs the repetition count and adaptive proportion tests on a sample of the source.
+func (g *EntropyGuard) testSource() error {
+
+	sample := make([]byte, entropyTestLength)
+
+	if _, err := io.ReadFull(g.source, sample); err != nil {
+		return fmt.Errorf("%w: %v", ErrInsufficientEntropy, err)
+	}
+
+	repeated := 1
+
+	for i := 1; i < len(sample); i++ {
+
+		if sample[i] != sample[i-1] {
+			repeated = 1
+			continue
+		}
+
+		if repeated++; repeated >= entropyRCTCutoff {
+			return fmt.Errorf("%w: repetition count test failed at sample %d", ErrInsufficientEntropy, i)
+		}
+
+	}
+
+	for start := 0; start+entropyAPTWindow <= len(sample); start += entropyAPTWindow {
+
+		count := 0
+
+		for _, b := range sample[start : start+entropyAPTWindow] {
+
+			if b == sample[start] {
+				count++
+			}
+
+		}
+
+		if count >= entropyAPTCutoff {
+			return fmt.Errorf("%w: adaptive proportion test failed at sample %d", ErrInsufficientEntropy, start)
+		}
+
+	}
+
+	return nil
+
+}
+
+// kernelEntropyEstimate reads the _Linux_ kernel entropy estimate, or -1 if not available.
+func kernelEntropyEstimate() (int, error) {
+
+	data, err := ioutil.ReadFile("/proc/sys/kernel/random/entropy_avail")
+	if err != nil {
+		return -1, nil
+	}
+
+	return strconv.Atoi(strings.TrimSpace(string(data)))
+
+}
+
+// mixedReader reads the XOR of _a_ and _b_, it is as strong as the strongest of them.
+type mixedReader struct {
+	a, b io.Reader
+}
+
+func (m *mixedReader) Read(p []byte) (int, error) {
+
+	if _, err := io.ReadFull(m.a, p); err != nil {
+		return 0, err
+	}
+
+	mix := make([]byte, len(p))
+
+	if _, err := io.ReadFull(m.b, mix); err != nil {
+		return 0, err
+	}
+
+	for i := range p {
+		p[i] ^= mix[i]
+	}
+
+	return len(p), nil
+
+}
+
+// jitterReader collects entropy from the timing jitter of memory accesses, conditioned by
+// _SHA-512_. It is slow and only meant to be mixed with another source.
+type jitterReader struct{}
+
+func (jitterReader) Read(p []byte) (int, error) {
+
+	var (
+		memory [4096]byte
+		stamp  [8]byte
+	)
+
+	n := 0
+
+	for n < len(p) {
+
+		h := sha512.New()
+
+		// Oversample, 64 timing deltas per output byte
+		for i := 0; i < 64*sha512.Size; i++ {
+
+			start := time.Now()
+
+			for j := 0; j < len(memory); j += 61 {
+				memory[(uint64(j)*uint64(start.UnixNano()))%uint64(len(memory))]++
+			}
+
+			binary.LittleEndian.PutUint64(stamp[:], uint64(time.Since(start)))
+			h.Write(stamp[:])
+
+		}
+
+		n += copy(p[n:], h.Sum(nil))
+
+	}
+
+	return n, nil
+
+}
@@ -0,0 +1,173 @@
+//go:build !verifyonly
+// +build !verifyonly
+
+package gocrypto
+
+import (
+	"bytes"
+	"crypto/rand"
+	"errors"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// stuckReader returns the same byte forever, as a broken hardware source would.
+type stuckReader struct{}
+
+func (stuckReader) Read(p []byte) (int, error) {
+
+	for i := range p {
+		p[i] = 0x42
+	}
+
+	return len(p), nil
+
+}
+
+func TestEntropyGuardHealthy(t *testing.T) {
+
+	var audited []EntropyHealth
+
+	guard, err := NewEntropyGuard(
+		WithEntropyEstimator(func() (int, error) { return 256, nil }),
+		WithEntropyAuditor(func(health EntropyHealth) { audited = append(audited, health) }),
+	)
+	require.NoError(t, err)
+	assert.NoError(t, guard.Err())
+
+	require.Len(t, audited, 1)
+	assert.Equal(t, 256, audited[0].Estimate)
+	assert.NoError(t, audited[0].Err)
+
+	SetEntropyGuard(guard)
+	defer SetEntropyGuard(nil)
+
+	_, err = NewED25519PrivateKey("ed")
+	assert.NoError(t, err)
+
+}
+
+func TestEntropyGuardRejectsLowEstimate(t *testing.T) {
+
+	_, err := NewEntropyGuard(WithEntropyEstimator(func() (int, error) { return 40, nil }))
+	assert.True(t, errors.Is(err, ErrInsufficientEntropy))
+
+}
+
+func TestEntropyGuardWaitsForEstimate(t *testing.T) {
+
+	estimate := 0
+
+	guard, err := NewEntropyGuard(
+		WithEntropyWait(5*time.Second),
+		WithEntropyEstimator(func() (int, error) {
+			estimate += 100
+			return estimate, nil
+		}),
+	)
+	require.NoError(t, err)
+	assert.NoError(t, guard.Err())
+	assert.Equal(t, 300, estimate)
+
+}
+
+func TestEntropyGuardUnknownEstimatePasses(t *testing.T) {
+
+	_, err := NewEntropyGuard(WithEntropyEstimator(func() (int, error) { return -1, nil }))
+	assert.NoError(t, err)
+
+}
+
+func TestEntropyGuardDetectsStuckSource(t *testing.T) {
+
+	_, err := NewEntropyGuard(
+		WithEntropyEstimator(func() (int, error) { return -1, nil }),
+		WithEntropySource(stuckReader{}),
+	)
+	assert.True(t, errors.Is(err, ErrInsufficientEntropy))
+
+}
+
+func TestEntropyGuardDetectsBiasedSource(t *testing.T) {
+
+	// Every fourth byte is zero, which passes the repetition count but not the proportion test
+	biased := bytes.Repeat([]byte{0, 1, 2, 3}, entropyTestLength/4)
+
+	_, err := NewEntropyGuard(
+		WithEntropyEstimator(func() (int, error) { return -1, nil }),
+		WithEntropySource(bytes.NewReader(biased)),
+	)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "adaptive proportion")
+
+}
+
+func TestEntropyGuardGatesKeyGeneration(t *testing.T) {
+
+	source := &switchReader{}
+
+	guard, err := NewEntropyGuard(
+		WithEntropyEstimator(func() (int, error) { return -1, nil }),
+		WithEntropySource(source),
+	)
+	require.NoError(t, err)
+
+	SetEntropyGuard(guard)
+	defer SetEntropyGuard(nil)
+
+	source.stuck = true
+	assert.True(t, errors.Is(guard.Check().Err, ErrInsufficientEntropy))
+
+	_, err = NewSymmetricKey("aes", 256)
+	assert.True(t, errors.Is(err, ErrInsufficientEntropy))
+
+	_, err = NewECDSAPrivateKey("ec", 256)
+	assert.True(t, errors.Is(err, ErrInsufficientEntropy))
+
+	source.stuck = false
+	assert.NoError(t, guard.Check().Err)
+
+	_, err = NewSymmetricKey("aes", 256)
+	assert.NoError(t, err)
+
+}
+
+func TestEntropyGuardJitterReader(t *testing.T) {
+
+	guard, err := NewEntropyGuard(
+		WithEntropyEstimator(func() (int, error) { return -1, nil }),
+		WithJitterEntropy(),
+	)
+	require.NoError(t, err)
+
+	a := make([]byte, 32)
+	b := make([]byte, 32)
+
+	_, err = io.ReadFull(guard.Reader(), a)
+	require.NoError(t, err)
+
+	_, err = io.ReadFull(guard.Reader(), b)
+	require.NoError(t, err)
+
+	assert.NotEqual(t, a, b)
+
+}
+
+// switchReader is `rand.Reader` or a `stuckReader` when _stuck_.
+type switchReader struct {
+	stuck bool
+}
+
+func (s *switchReader) Read(p []byte) (int, error) {
+
+	if s.stuck {
+		return stuckReader{}.Read(p)
+	}
+
+	return io.ReadFull(rand.Reader, p)
+
+}
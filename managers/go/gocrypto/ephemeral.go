@@ -0,0 +1,216 @@
+package gocrypto
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mariotoffia/goservice/interfaces/ifcrypto"
+)
+
+var (
+	// ErrEphemeralKeyNotFound is returned when a ephemeral key is unknown, expired or already taken.
+	ErrEphemeralKeyNotFound = fmt.Errorf("ephemeral key not found")
+	// ErrEphemeralCapacity is returned when the `EphemeralKeyManager` holds its maximum number
+	// of unexpired keys.
+	ErrEphemeralCapacity = fmt.Errorf("ephemeral key capacity exceeded")
+)
+
+// EphemeralKeyIDPrefix is the prefix of all ids generated by a `EphemeralKeyManager`.
+const EphemeralKeyIDPrefix = "ephemeral-"
+
+// IsEphemeralKey returns `true` if _key_ was generated by a `EphemeralKeyManager`. Such keys are
+// excluded from the `Inventory`.
+func IsEphemeralKey(key ifcrypto.Key) bool {
+	return strings.HasPrefix(key.GetID(), EphemeralKeyIDPrefix)
+}
+
+// EphemeralKeyGenerator generates a ephemeral key pair with _id_.
+type EphemeralKeyGenerator func(id string) (ifcrypto.KeyPair, error)
+
+// EphemeralX25519 generates _X25519_ key agreement keys, e.g. for _ECDHE_ session keys.
+func EphemeralX25519(id string) (ifcrypto.KeyPair, error) {
+	return NewX25519PrivateKey(id, ifcrypto.KeyUsageKeyAgreement)
+}
+
+// EphemeralP256 generates _P-256_ keys usable for both key agreement and signing.
+func EphemeralP256(id string) (ifcrypto.KeyPair, error) {
+	return NewECDSAPrivateKey(id, 256, ifcrypto.KeyUsageKeyAgreement, ifcrypto.KeyUsageSign)
+}
+
+// EphemeralEd25519 generates _Ed25519_ one-time signing keys.
+func EphemeralEd25519(id string) (ifcrypto.KeyPair, error) {
+	return NewED25519PrivateKey(id, ifcrypto.KeyUsageSign)
+}
+
+// EphemeralKeyManagerOption configures a `EphemeralKeyManager`.
+type EphemeralKeyManagerOption func(m *EphemeralKeyManager)
+
+// WithEphemeralTTL sets how long a key lives, default five minutes.
+func WithEphemeralTTL(ttl time.Duration) EphemeralKeyManagerOption {
+	return func(m *EphemeralKeyManager) {
+		m.ttl = ttl
+	}
+}
+
+// WithEphemeralCapacity sets the maximum number of unexpired keys, default 1024.
+func WithEphemeralCapacity(capacity int) EphemeralKeyManagerOption {
+	return func(m *EphemeralKeyManager) {
+		m.capacity = capacity
+	}
+}
+
+// WithEphemeralClock sets the clock, default `time.Now`.
+func WithEphemeralClock(clock func() time.Time) EphemeralKeyManagerOption {
+	return func(m *EphemeralKeyManager) {
+		m.clock = clock
+	}
+}
+
+type ephemeralEntry struct {
+	key     ifcrypto.KeyPair
+	expires time.Time
+}
+
+// EphemeralKeyManager holds short-lived keys, such as _ECDHE_ session keys and one-time signing
+// keys, in memory only.
+//
+// Keys expire after the _TTL_ and are then forgotten, expired keys are purged on each
+// `Generate`. The number of unexpired keys is bounded by the capacity. Keys are never persisted,
+// the manager is not a `InventorySource` and `IsEphemeralKey` lets other machinery, such as
+// rotation, skip them.
+type EphemeralKeyManager struct {
+	mu       sync.Mutex
+	keys     map[string]ephemeralEntry
+	ttl      time.Duration
+	capacity int
+	clock    func() time.Time
+}
+
+// NewEphemeralKeyManager creates a new `EphemeralKeyManager`.
+func NewEphemeralKeyManager(opts ...EphemeralKeyManagerOption) *EphemeralKeyManager {
+
+	m := &EphemeralKeyManager{
+		keys:     map[string]ephemeralEntry{},
+		ttl:      5 * time.Minute,
+		capacity: 1024,
+		clock:    time.Now,
+	}
+
+	for _, opt := range opts {
+		opt(m)
+	}
+
+	return m
+
+}
+
+// Generate generates a new key using _generator_, e.g. `EphemeralX25519`. The key id is random
+// and prefixed with `EphemeralKeyIDPrefix`.
+//
+// It returns `ErrEphemeralCapacity` if the manager is full after expired keys are purged.
+func (m *EphemeralKeyManager) Generate(generator EphemeralKeyGenerator) (ifcrypto.KeyPair, error) {
+
+	nonce := make([]byte, 16)
+
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return nil, err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := m.clock()
+
+	m.purge(now)
+
+	if len(m.keys) >= m.capacity {
+		return nil, ErrEphemeralCapacity
+	}
+
+	key, err := generator(EphemeralKeyIDPrefix + hex.EncodeToString(nonce))
+	if err != nil {
+		return nil, err
+	}
+
+	m.keys[key.GetID()] = ephemeralEntry{key: key, expires: now.Add(m.ttl)}
+
+	return key, nil
+
+}
+
+// Get returns the unexpired key with _id_.
+func (m *EphemeralKeyManager) Get(id string) (ifcrypto.KeyPair, error) {
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	entry, ok := m.keys[id]
+
+	if !ok || !m.clock().Before(entry.expires) {
+
+		delete(m.keys, id)
+		return nil, fmt.Errorf("%w: %s", ErrEphemeralKeyNotFound, id)
+
+	}
+
+	return entry.key, nil
+
+}
+
+// Take returns the unexpired key with _id_ and removes it, hence a one-time key can only be
+// used once.
+func (m *EphemeralKeyManager) Take(id string) (ifcrypto.KeyPair, error) {
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	entry, ok := m.keys[id]
+	delete(m.keys, id)
+
+	if !ok || !m.clock().Before(entry.expires) {
+		return nil, fmt.Errorf("%w: %s", ErrEphemeralKeyNotFound, id)
+	}
+
+	return entry.key, nil
+
+}
+
+// Delete removes the key with _id_, e.g. when the session ends.
+func (m *EphemeralKeyManager) Delete(id string) {
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	delete(m.keys, id)
+
+}
+
+// Len returns the number of unexpired keys.
+func (m *EphemeralKeyManager) Len() int {
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.purge(m.clock())
+
+	return len(m.keys)
+
+}
+
+// purge removes all keys that expired at _now_.
+func (m *EphemeralKeyManager) purge(now time.Time) {
+
+	for id, entry := range m.keys {
+
+		if !now.Before(entry.expires) {
+			delete(m.keys, id)
+		}
+
+	}
+
+}
@@ -0,0 +1,68 @@
+//go:build !verifyonly
+// +build !verifyonly
+
+package gocrypto
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/mariotoffia/goservice/interfaces/ifcrypto"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEphemeralKeyManagerExpiryAndCapacity(t *testing.T) {
+
+	now := time.Now()
+
+	m := NewEphemeralKeyManager(
+		WithEphemeralTTL(time.Minute),
+		WithEphemeralCapacity(2),
+		WithEphemeralClock(func() time.Time { return now }),
+	)
+
+	alice, err := m.Generate(EphemeralX25519)
+	require.NoError(t, err)
+	assert.True(t, IsEphemeralKey(alice))
+
+	bob, err := m.Generate(EphemeralX25519)
+	require.NoError(t, err)
+
+	_, err = m.Generate(EphemeralEd25519)
+	assert.True(t, errors.Is(err, ErrEphemeralCapacity))
+
+	key, err := m.Get(alice.GetID())
+	require.NoError(t, err)
+
+	secret, err := key.(ifcrypto.KeyAgreement).DeriveSharedSecret(bob.GetPublic())
+	require.NoError(t, err)
+
+	peer, err := bob.(ifcrypto.KeyAgreement).DeriveSharedSecret(alice.GetPublic())
+	require.NoError(t, err)
+	assert.Equal(t, secret, peer)
+
+	now = now.Add(time.Minute)
+
+	_, err = m.Get(alice.GetID())
+	assert.True(t, errors.Is(err, ErrEphemeralKeyNotFound))
+	assert.Equal(t, 0, m.Len())
+
+	signer, err := m.Generate(EphemeralEd25519)
+	require.NoError(t, err)
+
+	_, err = m.Take(signer.GetID())
+	require.NoError(t, err)
+
+	_, err = m.Take(signer.GetID())
+	assert.True(t, errors.Is(err, ErrEphemeralKeyNotFound))
+
+	report := NewInventory(&StaticInventorySource{
+		SourceName: "mixed",
+		KeyList:    []ifcrypto.Key{signer, alice.GetPublic()},
+	}).Report(now)
+
+	assert.Empty(t, report.Entries)
+
+}
@@ -0,0 +1,389 @@
+package gocrypto
+
+import (
+	"bytes"
+	"context"
+	"crypto"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/mariotoffia/goservice/interfaces/ifcrypto"
+)
+
+// ErrEscrowChainBroken is returned by `VerifyEscrowChain` when the records have been tampered with.
+var ErrEscrowChainBroken = fmt.Errorf("escrow chain is broken")
+
+// KeyEscrowOption configures a `KeyEscrow`.
+type KeyEscrowOption func(e *KeyEscrow)
+
+// WithEscrowClock sets the clock used to timestamp records, default `time.Now`.
+func WithEscrowClock(clock func() time.Time) KeyEscrowOption {
+	return func(e *KeyEscrow) {
+		e.clock = clock
+	}
+}
+
+// KeyEscrow writes a copy of private keys, wrapped to a escrow public key, to a `ifcrypto.EscrowSink`.
+//
+// Use the _NewXXXPrivateKey_ functions to generate keys that are escrowed before they are
+// returned, or `Deposit` to escrow a existing key. Only the holder of the escrow private key
+// may recover a key, see `RecoverEscrowedKey`.
+type KeyEscrow struct {
+	escrowKeyID string
+	escrowKey   *rsa.PublicKey
+	sink        ifcrypto.EscrowSink
+	clock       func() time.Time
+	mu          sync.Mutex
+}
+
+// NewKeyEscrow creates a new `KeyEscrow` that wraps keys to the _escrowKey_ identified by
+// _escrowKeyID_ and appends the records to _sink_.
+func NewKeyEscrow(
+	escrowKeyID string,
+	escrowKey *rsa.PublicKey,
+	sink ifcrypto.EscrowSink,
+	opts ...KeyEscrowOption,
+) (*KeyEscrow, error) {
+
+	if escrowKey == nil || escrowKey.N.BitLen() < 2048 {
+		return nil, fmt.Errorf("escrow key must be a RSA key of at least 2048 bits")
+	}
+
+	e := &KeyEscrow{
+		escrowKeyID: escrowKeyID,
+		escrowKey:   escrowKey,
+		sink:        sink,
+		clock:       time.Now,
+	}
+
+	for _, opt := range opts {
+		opt(e)
+	}
+
+	return e, nil
+
+}
+
+// NewRSAPrivateKey generates and escrows a new `RSAPrivateKey`.
+func (e *KeyEscrow) NewRSAPrivateKey(
+	c context.Context,
+	id string,
+	bits int,
+	usage ...ifcrypto.KeyUsage,
+) (*RSAPrivateKey, error) {
+
+	key, err := NewRSAPrivateKey(id, bits, usage...)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := e.Deposit(c, key); err != nil {
+		return nil, err
+	}
+
+	return key, nil
+
+}
+
+// NewECDSAPrivateKey generates and escrows a new `ECDSAPrivateKey`.
+func (e *KeyEscrow) NewECDSAPrivateKey(
+	c context.Context,
+	id string,
+	bits int,
+	usage ...ifcrypto.KeyUsage,
+) (*ECDSAPrivateKey, error) {
+
+	key, err := NewECDSAPrivateKey(id, bits, usage...)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := e.Deposit(c, key); err != nil {
+		return nil, err
+	}
+
+	return key, nil
+
+}
+
+// NewED25519PrivateKey generates and escrows a new `ED25519PrivateKey`.
+func (e *KeyEscrow) NewED25519PrivateKey(
+	c context.Context,
+	id string,
+	usage ...ifcrypto.KeyUsage,
+) (*ED25519PrivateKey, error) {
+
+	key, err := NewED25519PrivateKey(id, usage...)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := e.Deposit(c, key); err != nil {
+		return nil, err
+	}
+
+	return key, nil
+
+}
+
+// Deposit escrows the private _key_ and returns the appended record.
+//
+// Remote keys can not be escrowed since the private key is not available.
+func (e *KeyEscrow) Deposit(c context.Context, key ifcrypto.KeyPair) (*ifcrypto.EscrowRecord, error) {
+
+	if key.IsRemoteKey() {
+		return nil, fmt.Errorf("remote key %s can not be escrowed", key.GetID())
+	}
+
+	der, err := x509.MarshalPKCS8PrivateKey(key.GetKey())
+	if err != nil {
+		return nil, err
+	}
+
+	cek := make([]byte, 32)
+
+	if _, err := io.ReadFull(rand.Reader, cek); err != nil {
+		return nil, err
+	}
+
+	wrapped, err := rsa.EncryptOAEP(sha256.New(), rand.Reader, e.escrowKey, cek, []byte(e.escrowKeyID))
+	if err != nil {
+		return nil, err
+	}
+
+	aead, err := escrowAEAD(cek)
+	if err != nil {
+		return nil, err
+	}
+
+	nonce := make([]byte, aead.NonceSize())
+
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return nil, err
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	last, err := e.sink.Last(c)
+	if err != nil {
+		return nil, err
+	}
+
+	record := ifcrypto.EscrowRecord{
+		Sequence:    1,
+		KeyID:       key.GetID(),
+		KeyType:     key.GetKeyType(),
+		Created:     e.clock().UTC(),
+		EscrowKeyID: e.escrowKeyID,
+		WrappedKey:  wrapped,
+		Ciphertext:  aead.Seal(nonce, nonce, der, []byte(key.GetID())),
+	}
+
+	if last != nil {
+		record.Sequence = last.Sequence + 1
+		record.PrevHash = last.Hash
+	}
+
+	record.Hash = escrowRecordHash(&record)
+
+	if err := e.sink.Append(c, record); err != nil {
+		return nil, err
+	}
+
+	return &record, nil
+
+}
+
+// RecoverEscrowedKey decrypts the private key in _record_ using the escrow private key.
+//
+// The returned key is a `*rsa.PrivateKey`, `*ecdsa.PrivateKey` or `ed25519.PrivateKey`.
+func RecoverEscrowedKey(record *ifcrypto.EscrowRecord, escrowKey crypto.Decrypter) (crypto.PrivateKey, error) {
+
+	if !bytes.Equal(record.Hash, escrowRecordHash(record)) {
+		return nil, ErrEscrowChainBroken
+	}
+
+	cek, err := escrowKey.Decrypt(
+		rand.Reader, record.WrappedKey, &rsa.OAEPOptions{Hash: crypto.SHA256, Label: []byte(record.EscrowKeyID)},
+	)
+
+	if err != nil {
+		return nil, err
+	}
+
+	aead, err := escrowAEAD(cek)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(record.Ciphertext) < aead.NonceSize() {
+		return nil, fmt.Errorf("escrow ciphertext too short")
+	}
+
+	der, err := aead.Open(
+		nil,
+		record.Ciphertext[:aead.NonceSize()],
+		record.Ciphertext[aead.NonceSize():],
+		[]byte(record.KeyID),
+	)
+
+	if err != nil {
+		return nil, err
+	}
+
+	key, err := x509.ParsePKCS8PrivateKey(der)
+	if err != nil {
+		return nil, err
+	}
+
+	switch key.(type) {
+	case *rsa.PrivateKey, *ecdsa.PrivateKey, ed25519.PrivateKey:
+		return key, nil
+	}
+
+	return nil, fmt.Errorf("unsupported escrowed key type: %T", key)
+
+}
+
+// VerifyEscrowChain verifies that the _records_, in sequence order starting with the first
+// record, forms a unbroken hash chain.
+func VerifyEscrowChain(records []ifcrypto.EscrowRecord) error {
+
+	var prev []byte
+
+	for i := range records {
+
+		record := &records[i]
+
+		if record.Sequence != uint64(i+1) {
+			return fmt.Errorf("%w: expected sequence %d got %d", ErrEscrowChainBroken, i+1, record.Sequence)
+		}
+
+		if !bytes.Equal(record.PrevHash, prev) {
+			return fmt.Errorf("%w: previous hash mismatch at sequence %d", ErrEscrowChainBroken, record.Sequence)
+		}
+
+		if !bytes.Equal(record.Hash, escrowRecordHash(record)) {
+			return fmt.Errorf("%w: hash mismatch at sequence %d", ErrEscrowChainBroken, record.Sequence)
+		}
+
+		prev = record.Hash
+
+	}
+
+	return nil
+
+}
+
+// escrowAEAD creates the _AES-256-GCM_ used to encrypt the escrowed key.
+func escrowAEAD(cek []byte) (cipher.AEAD, error) {
+
+	block, err := aes.NewCipher(cek)
+	if err != nil {
+		return nil, err
+	}
+
+	return cipher.NewGCM(block)
+
+}
+
+// escrowRecordHash calculates the _SHA-256_ over all fields of the _record_ except the hash.
+//
+// Each variable length field is length prefixed to make the encoding unambiguous.
+func escrowRecordHash(record *ifcrypto.EscrowRecord) []byte {
+
+	h := sha256.New()
+
+	field := func(b []byte) {
+
+		var n [8]byte
+		binary.BigEndian.PutUint64(n[:], uint64(len(b)))
+
+		h.Write(n[:])
+		h.Write(b)
+
+	}
+
+	var n [8]byte
+
+	binary.BigEndian.PutUint64(n[:], record.Sequence)
+	h.Write(n[:])
+
+	binary.BigEndian.PutUint64(n[:], uint64(record.Created.UnixNano()))
+	h.Write(n[:])
+
+	field([]byte(record.KeyID))
+	field([]byte(record.KeyType))
+	field([]byte(record.EscrowKeyID))
+	field(record.WrappedKey)
+	field(record.Ciphertext)
+	field(record.PrevHash)
+
+	return h.Sum(nil)
+
+}
+
+// MemoryEscrowSink is a in memory `ifcrypto.EscrowSink`, mainly for tests.
+type MemoryEscrowSink struct {
+	mu      sync.Mutex
+	records []ifcrypto.EscrowRecord
+}
+
+// NewMemoryEscrowSink creates a new empty `MemoryEscrowSink`.
+func NewMemoryEscrowSink() *MemoryEscrowSink {
+	return &MemoryEscrowSink{}
+}
+
+// Append implements the `ifcrypto.EscrowSink` interface.
+func (s *MemoryEscrowSink) Append(c context.Context, record ifcrypto.EscrowRecord) error {
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if record.Sequence != uint64(len(s.records)+1) {
+		return fmt.Errorf("escrow record sequence %d do not follow %d", record.Sequence, len(s.records))
+	}
+
+	s.records = append(s.records, record)
+
+	return nil
+
+}
+
+// Last implements the `ifcrypto.EscrowSink` interface.
+func (s *MemoryEscrowSink) Last(c context.Context) (*ifcrypto.EscrowRecord, error) {
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if len(s.records) == 0 {
+		return nil, nil
+	}
+
+	record := s.records[len(s.records)-1]
+
+	return &record, nil
+
+}
+
+// Records returns a copy of all records in sequence order.
+func (s *MemoryEscrowSink) Records() []ifcrypto.EscrowRecord {
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return append([]ifcrypto.EscrowRecord{}, s.records...)
+
+}
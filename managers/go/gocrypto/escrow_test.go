@@ -0,0 +1,53 @@
+//go:build !verifyonly
+// +build !verifyonly
+
+package gocrypto
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"errors"
+	"testing"
+
+	"github.com/mariotoffia/goservice/interfaces/ifcrypto"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestKeyEscrowDepositAndRecover(t *testing.T) {
+
+	escrowKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+
+	sink := NewMemoryEscrowSink()
+
+	escrow, err := NewKeyEscrow("escrow-2026", &escrowKey.PublicKey, sink)
+	require.NoError(t, err)
+
+	ctx := context.Background()
+
+	ecKey, err := escrow.NewECDSAPrivateKey(ctx, "ec-key", 256, ifcrypto.KeyUsageSign)
+	require.NoError(t, err)
+
+	edKey, err := escrow.NewED25519PrivateKey(ctx, "ed-key", ifcrypto.KeyUsageSign)
+	require.NoError(t, err)
+
+	records := sink.Records()
+	require.Len(t, records, 2)
+	require.NoError(t, VerifyEscrowChain(records))
+
+	recovered, err := RecoverEscrowedKey(&records[0], escrowKey)
+	require.NoError(t, err)
+	assert.Equal(t, ecKey.GetKey(), recovered)
+
+	recovered, err = RecoverEscrowedKey(&records[1], escrowKey)
+	require.NoError(t, err)
+	assert.Equal(t, edKey.GetKey(), recovered)
+
+	records[0].KeyID = "other"
+	assert.True(t, errors.Is(VerifyEscrowChain(records), ErrEscrowChainBroken))
+
+	assert.True(t, errors.Is(VerifyEscrowChain(sink.Records()[1:]), ErrEscrowChainBroken))
+
+}
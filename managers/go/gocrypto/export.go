@@ -0,0 +1,249 @@
+package gocrypto
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/mariotoffia/goservice/interfaces/ifcrypto"
+)
+
+// ErrKeyNotExportable is returned when exporting a key that was not created with
+// `ifcrypto.KeyUsageExport`.
+var ErrKeyNotExportable = fmt.Errorf("key is not exportable")
+
+// ExportedKey is a private key wrapped to a approved recipient.
+//
+// The key is _PKCS #8_ encoded and encrypted with a one time _AES-256-GCM_ key that in turn is
+// wrapped, using _RSA-OAEP_ with _SHA-256_, to the recipient public key.
+type ExportedKey struct {
+	KeyID       string           `json:"key_id"`
+	KeyType     ifcrypto.KeyType `json:"key_type"`
+	RecipientID string           `json:"recipient_id"`
+	Exported    time.Time        `json:"exported"`
+	WrappedKey  []byte           `json:"wrapped_key"`
+	Ciphertext  []byte           `json:"ciphertext"`
+}
+
+// ExportEvent is a audit event emitted by the `KeyExporter` for each export attempt.
+type ExportEvent struct {
+	Time        time.Time
+	KeyID       string
+	RecipientID string
+	Principal   string
+	// Err is set when the export was rejected or failed.
+	Err error
+	// Context is the `ifcrypto.CryptoContext` of the export.
+	Context ifcrypto.CryptoContext
+}
+
+// KeyExporterOption configures a `KeyExporter`.
+type KeyExporterOption func(e *KeyExporter)
+
+// WithExportRecipient approves the _recipient_, a _RSA_ public key of at least 2048 bits, that
+// keys may be wrapped to.
+func WithExportRecipient(recipient ifcrypto.PublicKey) KeyExporterOption {
+	return func(e *KeyExporter) {
+		e.recipients[recipient.GetID()] = recipient
+	}
+}
+
+// WithExportAuditor sets a function that receives all audit events.
+func WithExportAuditor(auditor func(event ExportEvent)) KeyExporterOption {
+	return func(e *KeyExporter) {
+		e.auditor = auditor
+	}
+}
+
+// WithExportClock sets the clock, default `time.Now`.
+func WithExportClock(clock func() time.Time) KeyExporterOption {
+	return func(e *KeyExporter) {
+		e.clock = clock
+	}
+}
+
+// KeyExporter exports private keys wrapped to approved recipients.
+//
+// An export requires that the key was created with `ifcrypto.KeyUsageExport`, that the
+// recipient is approved using `WithExportRecipient` and that the `PolicyGuard` authorizes a
+// `ifcrypto.PolicyOperationExport` with the _recipient_ attribute. All attempts, successful or
+// not, are audited.
+type KeyExporter struct {
+	guard      *PolicyGuard
+	recipients map[string]ifcrypto.PublicKey
+	auditor    func(event ExportEvent)
+	clock      func() time.Time
+}
+
+// NewKeyExporter creates a new `KeyExporter` that authorizes exports with _guard_.
+func NewKeyExporter(guard *PolicyGuard, opts ...KeyExporterOption) (*KeyExporter, error) {
+
+	e := &KeyExporter{
+		guard:      guard,
+		recipients: map[string]ifcrypto.PublicKey{},
+		auditor:    func(ExportEvent) {},
+		clock:      time.Now,
+	}
+
+	for _, opt := range opts {
+		opt(e)
+	}
+
+	for id, recipient := range e.recipients {
+
+		if pub, ok := recipient.GetKey().(*rsa.PublicKey); !ok || pub.N.BitLen() < 2048 {
+			return nil, fmt.Errorf("export recipient %s must be a RSA key of at least 2048 bits", id)
+		}
+
+	}
+
+	return e, nil
+
+}
+
+// Export wraps the private _key_ to the recipient with _recipientID_.
+func (e *KeyExporter) Export(c context.Context, key ifcrypto.KeyPair, recipientID string) (*ExportedKey, error) {
+
+	exported, err := e.export(c, key, recipientID)
+
+	e.auditor(ExportEvent{
+		Time:        e.clock().UTC(),
+		KeyID:       key.GetID(),
+		RecipientID: recipientID,
+		Principal:   ifcrypto.PolicyPrincipal(c),
+		Err:         err,
+		Context:     ifcrypto.CryptoContextFrom(c),
+	})
+
+	return exported, err
+
+}
+
+func (e *KeyExporter) export(c context.Context, key ifcrypto.KeyPair, recipientID string) (*ExportedKey, error) {
+
+	if !hasKeyUsage(key, ifcrypto.KeyUsageExport) {
+		return nil, fmt.Errorf("%w: %s", ErrKeyNotExportable, key.GetID())
+	}
+
+	if key.IsRemoteKey() {
+		return nil, fmt.Errorf("%w: remote key %s", ErrKeyNotExportable, key.GetID())
+	}
+
+	recipient, ok := e.recipients[recipientID]
+	if !ok {
+		return nil, fmt.Errorf("%w: recipient %s is not approved", ifcrypto.ErrPolicyDenied, recipientID)
+	}
+
+	if err := e.guard.AuthorizeKey(
+		c, ifcrypto.PolicyOperationExport, key, map[string]string{"recipient": recipientID},
+	); err != nil {
+		return nil, err
+	}
+
+	der, err := x509.MarshalPKCS8PrivateKey(key.GetKey())
+	if err != nil {
+		return nil, err
+	}
+
+	cek := make([]byte, 32)
+
+	if _, err := io.ReadFull(rand.Reader, cek); err != nil {
+		return nil, err
+	}
+
+	wrapped, err := rsa.EncryptOAEP(
+		sha256.New(), rand.Reader, recipient.GetKey().(*rsa.PublicKey), cek, []byte(recipientID),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	aead, err := escrowAEAD(cek)
+	if err != nil {
+		return nil, err
+	}
+
+	nonce := make([]byte, aead.NonceSize())
+
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return nil, err
+	}
+
+	return &ExportedKey{
+		KeyID:       key.GetID(),
+		KeyType:     key.GetKeyType(),
+		RecipientID: recipientID,
+		Exported:    e.clock().UTC(),
+		WrappedKey:  wrapped,
+		Ciphertext:  aead.Seal(nonce, nonce, der, []byte(key.GetID())),
+	}, nil
+
+}
+
+// UnwrapExportedKey decrypts the private key in _exported_ using the recipient private key.
+//
+// The returned key is a `*rsa.PrivateKey`, `*ecdsa.PrivateKey` or `ed25519.PrivateKey`.
+func UnwrapExportedKey(exported *ExportedKey, recipientKey crypto.Decrypter) (crypto.PrivateKey, error) {
+
+	cek, err := recipientKey.Decrypt(
+		rand.Reader, exported.WrappedKey, &rsa.OAEPOptions{Hash: crypto.SHA256, Label: []byte(exported.RecipientID)},
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	aead, err := escrowAEAD(cek)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(exported.Ciphertext) < aead.NonceSize() {
+		return nil, fmt.Errorf("exported key ciphertext too short")
+	}
+
+	der, err := aead.Open(
+		nil,
+		exported.Ciphertext[:aead.NonceSize()],
+		exported.Ciphertext[aead.NonceSize():],
+		[]byte(exported.KeyID),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	key, err := x509.ParsePKCS8PrivateKey(der)
+	if err != nil {
+		return nil, err
+	}
+
+	switch key.(type) {
+	case *rsa.PrivateKey, *ecdsa.PrivateKey, ed25519.PrivateKey:
+		return key, nil
+	}
+
+	return nil, fmt.Errorf("unsupported exported key type: %T", key)
+
+}
+
+// hasKeyUsage returns `true` if _key_ has the _usage_.
+func hasKeyUsage(key ifcrypto.Key, usage ifcrypto.KeyUsage) bool {
+
+	for _, u := range key.GetKeyUsage() {
+
+		if u == usage {
+			return true
+		}
+
+	}
+
+	return false
+
+}
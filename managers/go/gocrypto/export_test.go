@@ -0,0 +1,76 @@
+//go:build !verifyonly
+// +build !verifyonly
+
+package gocrypto
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"errors"
+	"testing"
+
+	"github.com/mariotoffia/goservice/interfaces/ifcrypto"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestKeyExporter(t *testing.T) {
+
+	recipient, err := NewRSAPrivateKey("backup-hsm", 2048, ifcrypto.KeyUsageDecrypt)
+	require.NoError(t, err)
+
+	events := []ExportEvent{}
+
+	guard := NewPolicyGuard(PolicyEngineFunc(
+		func(c context.Context, input ifcrypto.PolicyInput) (ifcrypto.PolicyDecision, error) {
+			return ifcrypto.PolicyDecision{Allow: input.Principal == "security-officer"}, nil
+		},
+	))
+
+	exporter, err := NewKeyExporter(
+		guard,
+		WithExportRecipient(recipient.GetPublic()),
+		WithExportAuditor(func(event ExportEvent) { events = append(events, event) }),
+	)
+	require.NoError(t, err)
+
+	exportable, err := NewECDSAPrivateKey("exportable", 256, ifcrypto.KeyUsageSign, ifcrypto.KeyUsageExport)
+	require.NoError(t, err)
+
+	locked, err := NewECDSAPrivateKey("locked", 256, ifcrypto.KeyUsageSign)
+	require.NoError(t, err)
+
+	officer := ifcrypto.WithPolicyPrincipal(context.Background(), "security-officer")
+
+	exported, err := exporter.Export(officer, exportable, "backup-hsm")
+	require.NoError(t, err)
+
+	key, err := UnwrapExportedKey(exported, recipient)
+	require.NoError(t, err)
+	assert.True(t, key.(*ecdsa.PrivateKey).Equal(exportable.GetKey()))
+
+	_, err = exporter.Export(officer, locked, "backup-hsm")
+	assert.True(t, errors.Is(err, ErrKeyNotExportable))
+
+	_, err = exporter.Export(officer, exportable, "unknown")
+	assert.True(t, errors.Is(err, ifcrypto.ErrPolicyDenied))
+
+	_, err = exporter.Export(context.Background(), exportable, "backup-hsm")
+	assert.True(t, errors.Is(err, ifcrypto.ErrPolicyDenied))
+
+	require.Len(t, events, 4)
+	assert.NoError(t, events[0].Err)
+	assert.Equal(t, "security-officer", events[0].Principal)
+
+	for _, event := range events[1:] {
+		assert.Error(t, event.Err)
+	}
+
+	// a small recipient key is rejected
+	small, err := NewECDSAPrivateKey("small", 256, ifcrypto.KeyUsageDecrypt)
+	require.NoError(t, err)
+
+	_, err = NewKeyExporter(guard, WithExportRecipient(small.GetPublic()))
+	assert.Error(t, err)
+
+}
@@ -0,0 +1,214 @@
+package gocrypto
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// counterNameRegexp is the allowed format of a counter name, it is used as file name.
+var counterNameRegexp = regexp.MustCompile(`^[A-Za-z0-9._-]{1,128}$`)
+
+// fileCounter is the in memory state of a single counter.
+type fileCounter struct {
+	// current is the last value handed out.
+	current uint64
+	// high is the value persisted on disk, all values up to _high_ are reserved.
+	high uint64
+}
+
+// FileCounterStore implements `ifcrypto.CounterStore` using one file per counter in a directory.
+//
+// Each update is written to a temporary file that is synced and atomically renamed over the
+// existing one, hence a crash will either see the old or the new value.
+//
+// To reduce disk writes it is possible to reserve blocks of values, see `NewFileCounterStore`.
+// A crash will then skip the remainder of the block, but never reuse any value.
+//
+// NOTE: The store is safe for concurrent use within a process, but only one process may
+// use the same directory at the time.
+type FileCounterStore struct {
+	dir       string
+	blockSize uint64
+	mu        sync.Mutex
+	counters  map[string]*fileCounter
+}
+
+// NewFileCounterStore creates a new `FileCounterStore` that stores its counters in _dir_.
+//
+// The _blockSize_ is the number of values persisted ahead in each write. Use one to persist
+// every single increment.
+func NewFileCounterStore(dir string, blockSize uint64) (*FileCounterStore, error) {
+
+	if blockSize == 0 {
+		blockSize = 1
+	}
+
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, err
+	}
+
+	return &FileCounterStore{
+		dir:       dir,
+		blockSize: blockSize,
+		counters:  map[string]*fileCounter{},
+	}, nil
+
+}
+
+// Next implements the `ifcrypto.CounterStore` interface.
+func (s *FileCounterStore) Next(c context.Context, name string) (uint64, error) {
+	return s.Reserve(c, name, 1)
+}
+
+// Reserve implements the `ifcrypto.CounterStore` interface.
+func (s *FileCounterStore) Reserve(c context.Context, name string, n uint64) (uint64, error) {
+
+	if n == 0 {
+		return 0, fmt.Errorf("must reserve at least one value")
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	counter, err := s.load(name)
+	if err != nil {
+		return 0, err
+	}
+
+	if counter.current+n < counter.current {
+		return 0, fmt.Errorf("counter %s would overflow", name)
+	}
+
+	first := counter.current + 1
+	last := counter.current + n
+
+	if last > counter.high {
+
+		high := last + s.blockSize - 1
+
+		if high < last {
+			high = last
+		}
+
+		if err := s.persist(name, high); err != nil {
+			return 0, err
+		}
+
+		counter.high = high
+
+	}
+
+	counter.current = last
+
+	return first, nil
+
+}
+
+// Current implements the `ifcrypto.CounterStore` interface.
+//
+// After a restart, this is the persisted value, i.e. the end of the last reserved block.
+func (s *FileCounterStore) Current(c context.Context, name string) (uint64, error) {
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	counter, err := s.load(name)
+	if err != nil {
+		return 0, err
+	}
+
+	return counter.current, nil
+
+}
+
+// load returns the in memory counter and reads it from disk if not already loaded.
+//
+// A counter loaded from disk continues after the persisted value since all values up to
+// that value may have been handed out before a restart.
+func (s *FileCounterStore) load(name string) (*fileCounter, error) {
+
+	if counter, ok := s.counters[name]; ok {
+		return counter, nil
+	}
+
+	if !counterNameRegexp.MatchString(name) {
+		return nil, fmt.Errorf("invalid counter name: %s", name)
+	}
+
+	data, err := ioutil.ReadFile(filepath.Join(s.dir, name))
+
+	counter := &fileCounter{}
+
+	if err == nil {
+
+		value, err := strconv.ParseUint(strings.TrimSpace(string(data)), 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("corrupt counter file %s: %v", name, err)
+		}
+
+		counter.current = value
+		counter.high = value
+
+	} else if !os.IsNotExist(err) {
+
+		return nil, err
+
+	}
+
+	s.counters[name] = counter
+
+	return counter, nil
+
+}
+
+// persist writes the _value_ of the counter _name_ durably to disk.
+func (s *FileCounterStore) persist(name string, value uint64) error {
+	return writeFileAtomic(s.dir, name, []byte(strconv.FormatUint(value, 10)+"\n"))
+}
+
+// writeFileAtomic writes _data_ to the file _name_ in _dir_ by writing a temporary file that
+// is synced and atomically renamed over the existing one, hence a crash will either see the
+// old or the new content.
+func writeFileAtomic(dir, name string, data []byte) error {
+
+	tmp, err := ioutil.TempFile(dir, "."+name+".*")
+	if err != nil {
+		return err
+	}
+
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+
+	if err := os.Rename(tmp.Name(), filepath.Join(dir, name)); err != nil {
+		return err
+	}
+
+	// Make the rename durable, not all platforms supports syncing a directory
+	if d, err := os.Open(dir); err == nil {
+		_ = d.Sync()
+		d.Close()
+	}
+
+	return nil
+
+}
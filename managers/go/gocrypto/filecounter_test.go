@@ -0,0 +1,42 @@
+package gocrypto
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFileCounterStoreNeverReusesAfterRestart(t *testing.T) {
+
+	dir := t.TempDir()
+	ctx := context.Background()
+
+	store, err := NewFileCounterStore(dir, 10)
+	require.NoError(t, err)
+
+	for i := uint64(1); i <= 3; i++ {
+
+		v, err := store.Next(ctx, "nonce")
+		require.NoError(t, err)
+
+		assert.Equal(t, i, v)
+
+	}
+
+	first, err := store.Reserve(ctx, "nonce", 5)
+	require.NoError(t, err)
+	assert.Equal(t, uint64(4), first)
+
+	// Simulate a crash, i.e. the rest of the reserved block is skipped
+	restarted, err := NewFileCounterStore(dir, 10)
+	require.NoError(t, err)
+
+	v, err := restarted.Next(ctx, "nonce")
+	require.NoError(t, err)
+	assert.Equal(t, uint64(11), v)
+
+	_, err = restarted.Next(ctx, "../escape")
+	assert.Error(t, err)
+}
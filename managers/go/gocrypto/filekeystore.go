@@ -0,0 +1,610 @@
+package gocrypto
+
+import (
+	"bytes"
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mariotoffia/goservice/interfaces/ifcrypto"
+	"golang.org/x/crypto/argon2"
+)
+
+// ErrKeystoreMasterKey is returned when opening a `FileKeyStore` with another passphrase, or
+// _KEK_, than it was created with.
+var ErrKeystoreMasterKey = fmt.Errorf("wrong keystore master key")
+
+// fileKeystoreManifest is the name of the manifest file in the keystore directory.
+const fileKeystoreManifest = "keystore.json"
+
+// fileKeystoreCheck is encrypted into the manifest to detect a wrong master key.
+const fileKeystoreCheck = "goservice file keystore"
+
+// fileKeystoreManifestData is the manifest of a `FileKeyStore`.
+type fileKeystoreManifestData struct {
+	// KDF is _argon2id_ when the master key is derived from a passphrase, otherwise _kek_.
+	KDF     string `json:"kdf"`
+	Salt    []byte `json:"salt,omitempty"`
+	Time    uint32 `json:"time,omitempty"`
+	Memory  uint32 `json:"memory,omitempty"`
+	Threads uint8  `json:"threads,omitempty"`
+	// Check is the encrypted `fileKeystoreCheck`.
+	Check []byte `json:"check"`
+}
+
+// fileKeyMeta is the plaintext metadata of a key version, it is bound to the ciphertext.
+type fileKeyMeta struct {
+	KeyID   string              `json:"key_id"`
+	Version int                 `json:"version"`
+	KeyType ifcrypto.KeyType    `json:"key_type"`
+	Usage   []ifcrypto.KeyUsage `json:"usage"`
+	Created time.Time           `json:"created"`
+}
+
+// fileKeyRecord is the file of a single key version.
+type fileKeyRecord struct {
+	fileKeyMeta
+	Ciphertext []byte `json:"ciphertext"`
+}
+
+// fileKeyEntry is the in memory state of a key, its key is loaded on first use.
+type fileKeyEntry struct {
+	versions []fileKeyMeta
+	key      ifcrypto.Key
+}
+
+// FileKeyStoreOption configures a `FileKeyStore`.
+type FileKeyStoreOption func(s *FileKeyStore)
+
+// WithArgon2idParams sets the _Argon2id_ parameters used when a new keystore is created from a
+// passphrase (default time 3, 64 MiB memory and 4 threads). A existing keystore uses the
+// parameters it was created with.
+func WithArgon2idParams(time, memoryKiB uint32, threads uint8) FileKeyStoreOption {
+	return func(s *FileKeyStore) {
+		s.time, s.memory, s.threads = time, memoryKiB, threads
+	}
+}
+
+// FileKeyStore is a `ifcrypto.KeyStore` that persists keys in a directory encrypted with a
+// master key, i.e. a lightweight keyring on disk for edge deployments.
+//
+// The master key is either a _KEK_ or derived from a passphrase using _Argon2id_. Each key
+// version is a file with its metadata, id, version, type and usage, in plaintext and the key
+// _PEM_ encrypted using _AES-256-GCM_ with the metadata as additional data. Files are written
+// atomically, see `FileCounterStore`.
+//
+// Keys are versioned, `Rotate` adds a new version that `Get` returns from then on. Keys are
+// loaded lazily, only the metadata is read when the store is opened and a key is decrypted
+// the first time it is used.
+//
+// NOTE: The store is safe for concurrent use within a process, but only one process may
+// use the same directory at the time.
+type FileKeyStore struct {
+	dir     string
+	aead    cipher.AEAD
+	time    uint32
+	memory  uint32
+	threads uint8
+	mu      sync.RWMutex
+	entries map[string]*fileKeyEntry
+}
+
+// NewFileKeyStore opens, or creates, the keystore in _dir_ using the 16, 24 or 32 byte _kek_.
+func NewFileKeyStore(dir string, kek []byte, opts ...FileKeyStoreOption) (*FileKeyStore, error) {
+
+	return openFileKeyStore(dir, opts, func(s *FileKeyStore, m *fileKeystoreManifestData) ([]byte, error) {
+
+		if m.KDF == "" {
+			m.KDF = "kek"
+		}
+
+		if m.KDF != "kek" {
+			return nil, fmt.Errorf("%w: keystore is protected by a passphrase", ErrKeystoreMasterKey)
+		}
+
+		return kek, nil
+
+	})
+
+}
+
+// NewFileKeyStoreWithPassphrase opens, or creates, the keystore in _dir_ using a master key
+// derived from _passphrase_ using _Argon2id_.
+func NewFileKeyStoreWithPassphrase(dir string, passphrase []byte, opts ...FileKeyStoreOption) (*FileKeyStore, error) {
+
+	return openFileKeyStore(dir, opts, func(s *FileKeyStore, m *fileKeystoreManifestData) ([]byte, error) {
+
+		if m.KDF == "" {
+
+			m.KDF = "argon2id"
+			m.Time, m.Memory, m.Threads = s.time, s.memory, s.threads
+			m.Salt = make([]byte, 16)
+
+			if _, err := io.ReadFull(rand.Reader, m.Salt); err != nil {
+				return nil, err
+			}
+
+		}
+
+		if m.KDF != "argon2id" {
+			return nil, fmt.Errorf("%w: keystore is protected by a KEK", ErrKeystoreMasterKey)
+		}
+
+		return argon2.IDKey(passphrase, m.Salt, m.Time, m.Memory, m.Threads, 32), nil
+
+	})
+
+}
+
+// openFileKeyStore opens the keystore in _dir_ with the master key returned by _master_. The
+// _master_ gets a empty manifest when a new keystore is created.
+func openFileKeyStore(
+	dir string,
+	opts []FileKeyStoreOption,
+	master func(s *FileKeyStore, m *fileKeystoreManifestData) ([]byte, error),
+) (*FileKeyStore, error) {
+
+	s := &FileKeyStore{
+		dir:     dir,
+		time:    3,
+		memory:  64 * 1024,
+		threads: 4,
+		entries: map[string]*fileKeyEntry{},
+	}
+
+	for _, opt := range opts {
+		opt(s)
+	}
+
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, err
+	}
+
+	var manifest fileKeystoreManifestData
+
+	data, err := ioutil.ReadFile(filepath.Join(dir, fileKeystoreManifest))
+
+	exists := err == nil
+
+	if exists {
+
+		if err := json.Unmarshal(data, &manifest); err != nil {
+			return nil, fmt.Errorf("corrupt keystore manifest: %v", err)
+		}
+
+	} else if !os.IsNotExist(err) {
+		return nil, err
+	}
+
+	key, err := master(s, &manifest)
+	if err != nil {
+		return nil, err
+	}
+
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+
+	if s.aead, err = cipher.NewGCM(block); err != nil {
+		return nil, err
+	}
+
+	if !exists {
+
+		if manifest.Check, err = s.seal([]byte(fileKeystoreCheck), []byte(fileKeystoreManifest)); err != nil {
+			return nil, err
+		}
+
+		data, err := json.MarshalIndent(&manifest, "", "  ")
+		if err != nil {
+			return nil, err
+		}
+
+		if err := writeFileAtomic(dir, fileKeystoreManifest, data); err != nil {
+			return nil, err
+		}
+
+	} else if check, err := s.open(manifest.Check, []byte(fileKeystoreManifest)); err != nil ||
+		string(check) != fileKeystoreCheck {
+
+		return nil, ErrKeystoreMasterKey
+
+	}
+
+	if err := s.scan(); err != nil {
+		return nil, err
+	}
+
+	return s, nil
+
+}
+
+// Get implements the `ifcrypto.KeyStore` interface and returns the latest version of the key.
+func (s *FileKeyStore) Get(c context.Context, id string) (ifcrypto.Key, error) {
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	entry, ok := s.entries[id]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ifcrypto.ErrKeyNotFound, id)
+	}
+
+	return s.load(entry)
+
+}
+
+// GetVersion returns the _version_ of the key with _id_.
+func (s *FileKeyStore) GetVersion(c context.Context, id string, version int) (ifcrypto.Key, error) {
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if entry, ok := s.entries[id]; ok {
+
+		for _, meta := range entry.versions {
+
+			if meta.Version == version {
+				return s.read(meta)
+			}
+
+		}
+
+	}
+
+	return nil, fmt.Errorf("%w: %s version %d", ifcrypto.ErrKeyNotFound, id, version)
+
+}
+
+// Versions returns the versions of the key with _id_, oldest first.
+func (s *FileKeyStore) Versions(id string) ([]int, error) {
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	entry, ok := s.entries[id]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ifcrypto.ErrKeyNotFound, id)
+	}
+
+	versions := make([]int, 0, len(entry.versions))
+	for _, meta := range entry.versions {
+		versions = append(versions, meta.Version)
+	}
+
+	return versions, nil
+
+}
+
+// Put implements the `ifcrypto.KeyStore` interface and persists _key_ as version one.
+func (s *FileKeyStore) Put(c context.Context, key ifcrypto.Key) error {
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.entries[key.GetID()]; ok {
+		return fmt.Errorf("%w: %s", ifcrypto.ErrKeyExists, key.GetID())
+	}
+
+	_, err := s.write(key, 1)
+
+	return err
+
+}
+
+// Rotate persists _key_ as a new version of the key with the same id, or as version one if it
+// do not exist. It returns the new version.
+func (s *FileKeyStore) Rotate(c context.Context, key ifcrypto.Key) (int, error) {
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	version := 1
+
+	if entry, ok := s.entries[key.GetID()]; ok {
+		version = entry.versions[len(entry.versions)-1].Version + 1
+	}
+
+	return s.write(key, version)
+
+}
+
+// Delete implements the `ifcrypto.KeyStore` interface and removes all versions of the keys.
+func (s *FileKeyStore) Delete(c context.Context, ids ...string) error {
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, id := range ids {
+
+		entry, ok := s.entries[id]
+		if !ok {
+			continue
+		}
+
+		for _, meta := range entry.versions {
+
+			if err := os.Remove(filepath.Join(s.dir, fileKeyName(id, meta.Version))); err != nil && !os.IsNotExist(err) {
+				return err
+			}
+
+		}
+
+		delete(s.entries, id)
+
+	}
+
+	return nil
+
+}
+
+// List implements the `ifcrypto.KeyStore` interface. The filter is matched against the
+// metadata, only the selected keys are loaded.
+func (s *FileKeyStore) List(c context.Context, filter ifcrypto.KeyFilter) ([]ifcrypto.Key, error) {
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	ids := make([]string, 0, len(s.entries))
+	for id := range s.entries {
+		ids = append(ids, id)
+	}
+
+	sort.Strings(ids)
+
+	keys := []ifcrypto.Key{}
+
+	for _, id := range ids {
+
+		entry := s.entries[id]
+
+		if !filter.Match(&keyMetadata{meta: entry.versions[len(entry.versions)-1]}) {
+			continue
+		}
+
+		key, err := s.load(entry)
+		if err != nil {
+			return nil, err
+		}
+
+		keys = append(keys, key)
+
+	}
+
+	return keys, nil
+
+}
+
+// load returns the latest version of _entry_, decrypting it on first use. The caller must hold
+// the write lock.
+func (s *FileKeyStore) load(entry *fileKeyEntry) (ifcrypto.Key, error) {
+
+	if entry.key != nil {
+		return entry.key, nil
+	}
+
+	key, err := s.read(entry.versions[len(entry.versions)-1])
+	if err != nil {
+		return nil, err
+	}
+
+	entry.key = key
+
+	return key, nil
+
+}
+
+// read reads and decrypts the key version of _meta_.
+func (s *FileKeyStore) read(meta fileKeyMeta) (ifcrypto.Key, error) {
+
+	data, err := ioutil.ReadFile(filepath.Join(s.dir, fileKeyName(meta.KeyID, meta.Version)))
+	if err != nil {
+		return nil, err
+	}
+
+	var record fileKeyRecord
+
+	if err := json.Unmarshal(data, &record); err != nil {
+		return nil, fmt.Errorf("corrupt key file %s: %v", meta.KeyID, err)
+	}
+
+	aad, err := json.Marshal(&record.fileKeyMeta)
+	if err != nil {
+		return nil, err
+	}
+
+	plaintext, err := s.open(record.Ciphertext, aad)
+	if err != nil {
+		return nil, fmt.Errorf("key %s version %d: %w", meta.KeyID, meta.Version, err)
+	}
+
+	defer zeroBytes(plaintext)
+
+	return LoadKey(bytes.NewReader(plaintext), record.KeyID, record.Usage...)
+
+}
+
+// write encrypts and persists _key_ as _version_. The caller must hold the write lock.
+func (s *FileKeyStore) write(key ifcrypto.Key, version int) (int, error) {
+
+	if key.IsRemoteKey() {
+		return 0, fmt.Errorf("key %s is a remote key", key.GetID())
+	}
+
+	writer, ok := key.(ifcrypto.PEMWriter)
+	if !ok {
+		return 0, fmt.Errorf("key %s can not be written as PEM", key.GetID())
+	}
+
+	// A key pair is written without, and a public key with, its public portion
+	_, pair := key.(ifcrypto.KeyPair)
+
+	var buf bytes.Buffer
+
+	if err := writer.PEMWrite(&buf, !pair); err != nil {
+		return 0, err
+	}
+
+	defer zeroBytes(buf.Bytes())
+
+	record := fileKeyRecord{fileKeyMeta: fileKeyMeta{
+		KeyID:   key.GetID(),
+		Version: version,
+		KeyType: key.GetKeyType(),
+		Usage:   key.GetKeyUsage(),
+		Created: time.Now().UTC().Truncate(time.Second),
+	}}
+
+	aad, err := json.Marshal(&record.fileKeyMeta)
+	if err != nil {
+		return 0, err
+	}
+
+	if record.Ciphertext, err = s.seal(buf.Bytes(), aad); err != nil {
+		return 0, err
+	}
+
+	data, err := json.MarshalIndent(&record, "", "  ")
+	if err != nil {
+		return 0, err
+	}
+
+	if err := writeFileAtomic(s.dir, fileKeyName(key.GetID(), version), data); err != nil {
+		return 0, err
+	}
+
+	entry, ok := s.entries[key.GetID()]
+	if !ok {
+		entry = &fileKeyEntry{}
+		s.entries[key.GetID()] = entry
+	}
+
+	entry.versions = append(entry.versions, record.fileKeyMeta)
+	entry.key = key
+
+	return version, nil
+
+}
+
+// scan reads the metadata of all key files in the directory.
+func (s *FileKeyStore) scan() error {
+
+	files, err := ioutil.ReadDir(s.dir)
+	if err != nil {
+		return err
+	}
+
+	for _, file := range files {
+
+		if file.IsDir() || !strings.HasSuffix(file.Name(), ".key") || strings.HasPrefix(file.Name(), ".") {
+			continue
+		}
+
+		data, err := ioutil.ReadFile(filepath.Join(s.dir, file.Name()))
+		if err != nil {
+			return err
+		}
+
+		var record fileKeyRecord
+
+		if err := json.Unmarshal(data, &record); err != nil {
+			return fmt.Errorf("corrupt key file %s: %v", file.Name(), err)
+		}
+
+		if file.Name() != fileKeyName(record.KeyID, record.Version) {
+			return fmt.Errorf("key file %s do not match key %s version %d", file.Name(), record.KeyID, record.Version)
+		}
+
+		entry, ok := s.entries[record.KeyID]
+		if !ok {
+			entry = &fileKeyEntry{}
+			s.entries[record.KeyID] = entry
+		}
+
+		entry.versions = append(entry.versions, record.fileKeyMeta)
+
+	}
+
+	for _, entry := range s.entries {
+
+		versions := entry.versions
+		sort.Slice(versions, func(i, j int) bool { return versions[i].Version < versions[j].Version })
+
+	}
+
+	return nil
+
+}
+
+// seal encrypts _plaintext_ with the master key and prepends the nonce.
+func (s *FileKeyStore) seal(plaintext, aad []byte) ([]byte, error) {
+
+	nonce := make([]byte, s.aead.NonceSize())
+
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return nil, err
+	}
+
+	return s.aead.Seal(nonce, nonce, plaintext, aad), nil
+
+}
+
+// open decrypts the _ciphertext_ of `seal`.
+func (s *FileKeyStore) open(ciphertext, aad []byte) ([]byte, error) {
+
+	if len(ciphertext) < s.aead.NonceSize() {
+		return nil, fmt.Errorf("ciphertext too short")
+	}
+
+	return s.aead.Open(nil, ciphertext[:s.aead.NonceSize()], ciphertext[s.aead.NonceSize():], aad)
+
+}
+
+// fileKeyName returns the file name of the _version_ of the key _id_. The id is hashed since it
+// may contain characters, such as _/_ in a _ARN_, that are not allowed in a file name.
+func fileKeyName(id string, version int) string {
+
+	sum := sha256.Sum256([]byte(id))
+
+	return hex.EncodeToString(sum[:16]) + "." + strconv.Itoa(version) + ".key"
+
+}
+
+// zeroBytes overwrites _b_ with zeros.
+func zeroBytes(b []byte) {
+
+	for i := range b {
+		b[i] = 0
+	}
+
+}
+
+// keyMetadata is a `ifcrypto.Key` of only the metadata, used to match a `ifcrypto.KeyFilter`
+// without loading the key.
+type keyMetadata struct {
+	ifcrypto.Key
+	meta fileKeyMeta
+}
+
+func (k *keyMetadata) GetKeyType() ifcrypto.KeyType {
+	return k.meta.KeyType
+}
+
+func (k *keyMetadata) GetKeyUsage() []ifcrypto.KeyUsage {
+	return k.meta.Usage
+}
@@ -0,0 +1,147 @@
+//go:build !verifyonly
+// +build !verifyonly
+
+package gocrypto
+
+import (
+	"bytes"
+	"context"
+	"crypto"
+	"errors"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	"github.com/mariotoffia/goservice/interfaces/ifcrypto"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// testArgon2id is cheap Argon2id parameters for tests.
+var testArgon2id = WithArgon2idParams(1, 1024, 1)
+
+func TestFileKeyStorePersistsEncryptedVersionedKeys(t *testing.T) {
+
+	dir := t.TempDir()
+	c := context.Background()
+
+	store, err := NewFileKeyStoreWithPassphrase(dir, []byte("correct horse"), testArgon2id)
+	require.NoError(t, err)
+
+	signer, err := NewED25519PrivateKey("arn:aws:kms:eu-west-1:1:key/signer", ifcrypto.KeyUsageSign)
+	require.NoError(t, err)
+
+	data, err := NewSymmetricKey("data", 256, ifcrypto.KeyUsageEncrypt)
+	require.NoError(t, err)
+
+	peer, err := NewECDSAPrivateKey("peer", 256, ifcrypto.KeyUsageVerify)
+	require.NoError(t, err)
+
+	require.NoError(t, store.Put(c, signer))
+	require.NoError(t, store.Put(c, data))
+	require.NoError(t, store.Put(c, peer.GetPublic()))
+	assert.True(t, errors.Is(store.Put(c, data), ifcrypto.ErrKeyExists))
+
+	rotated, err := NewED25519PrivateKey(signer.GetID(), ifcrypto.KeyUsageSign)
+	require.NoError(t, err)
+
+	version, err := store.Rotate(c, rotated)
+	require.NoError(t, err)
+	assert.Equal(t, 2, version)
+
+	// The key material is not stored in plaintext.
+	files, err := filepath.Glob(filepath.Join(dir, "*.key"))
+	require.NoError(t, err)
+	require.Len(t, files, 4)
+
+	for _, file := range files {
+
+		content, err := ioutil.ReadFile(file)
+		require.NoError(t, err)
+		assert.NotContains(t, string(content), "PRIVATE KEY")
+
+	}
+
+	// Reopen, keys are loaded lazily.
+	store, err = NewFileKeyStoreWithPassphrase(dir, []byte("correct horse"), testArgon2id)
+	require.NoError(t, err)
+
+	key, err := store.Get(c, signer.GetID())
+	require.NoError(t, err)
+	assert.Equal(t, rotated.GetPublic().GetKey(), key.(ifcrypto.KeyPair).GetPublic().GetKey())
+	assert.Equal(t, []ifcrypto.KeyUsage{ifcrypto.KeyUsageSign}, key.GetKeyUsage())
+
+	old, err := store.GetVersion(c, signer.GetID(), 1)
+	require.NoError(t, err)
+	assert.Equal(t, signer.GetPublic().GetKey(), old.(ifcrypto.KeyPair).GetPublic().GetKey())
+
+	versions, err := store.Versions(signer.GetID())
+	require.NoError(t, err)
+	assert.Equal(t, []int{1, 2}, versions)
+
+	keys, err := store.List(c, ifcrypto.KeyFilter{Usages: []ifcrypto.KeyUsage{ifcrypto.KeyUsageVerify}})
+	require.NoError(t, err)
+	require.Len(t, keys, 1)
+	assert.IsType(t, &ECDSAPublicKey{}, keys[0])
+
+	keys, err = store.List(c, ifcrypto.KeyFilter{})
+	require.NoError(t, err)
+	require.Len(t, keys, 3)
+	assert.IsType(t, &SymmetricKey{}, keys[1])
+
+	require.NoError(t, store.Delete(c, signer.GetID()))
+
+	_, err = store.Get(c, signer.GetID())
+	assert.True(t, errors.Is(err, ifcrypto.ErrKeyNotFound))
+
+	files, err = filepath.Glob(filepath.Join(dir, "*.key"))
+	require.NoError(t, err)
+	assert.Len(t, files, 2)
+
+	_, err = NewFileKeyStoreWithPassphrase(dir, []byte("wrong"), testArgon2id)
+	assert.True(t, errors.Is(err, ErrKeystoreMasterKey))
+
+	_, err = NewFileKeyStore(dir, make([]byte, 32))
+	assert.True(t, errors.Is(err, ErrKeystoreMasterKey))
+
+}
+
+func TestFileKeyStoreWithKEKDetectsTampering(t *testing.T) {
+
+	dir := t.TempDir()
+	c := context.Background()
+	kek := make([]byte, 32)
+
+	store, err := NewFileKeyStore(dir, kek)
+	require.NoError(t, err)
+
+	key, err := NewHMACKey("webhook", crypto.SHA256, ifcrypto.KeyUsageSign)
+	require.NoError(t, err)
+
+	require.NoError(t, store.Put(c, key))
+
+	file := filepath.Join(dir, fileKeyName("webhook", 1))
+
+	content, err := ioutil.ReadFile(file)
+	require.NoError(t, err)
+
+	// Granting the key another usage breaks the binding to the ciphertext.
+	tampered := bytes.Replace(content, []byte(`"sign"`), []byte(`"verify"`), 1)
+	require.NoError(t, ioutil.WriteFile(file, tampered, 0600))
+
+	store, err = NewFileKeyStore(dir, kek)
+	require.NoError(t, err)
+
+	_, err = store.Get(c, "webhook")
+	assert.Error(t, err)
+
+	require.NoError(t, ioutil.WriteFile(file, content, 0600))
+
+	store, err = NewFileKeyStore(dir, kek)
+	require.NoError(t, err)
+
+	loaded, err := store.Get(c, "webhook")
+	require.NoError(t, err)
+	assert.Equal(t, ifcrypto.SignAlgorithmHmacSha256, loaded.(*HMACKey).Algorithm())
+
+}
@@ -0,0 +1,139 @@
+package gocrypto
+
+import (
+	"crypto"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+
+	"github.com/mariotoffia/goservice/interfaces/ifcrypto"
+)
+
+// FingerprintMethod is what a `Fingerprint` is computed from.
+type FingerprintMethod string
+
+const (
+	// FingerprintSPKI is the _SHA-256_ of the _DER_ encoded _PKIX_ public key, e.g. as used in
+	// pinning configurations.
+	FingerprintSPKI FingerprintMethod = "spki"
+	// FingerprintJWKThumbprint is the _RFC 7638_ _SHA-256_ _JWK_ thumbprint, e.g. as used as _kid_.
+	FingerprintJWKThumbprint FingerprintMethod = "jwk-thumbprint"
+)
+
+// FingerprintEncoding is the text encoding of a `Fingerprint`.
+type FingerprintEncoding string
+
+const (
+	// FingerprintHex is lower case hex.
+	FingerprintHex FingerprintEncoding = "hex"
+	// FingerprintBase64URL is base64url without padding.
+	FingerprintBase64URL FingerprintEncoding = "base64url"
+)
+
+// Fingerprinter is implemented by all asymmetric keys in this package.
+type Fingerprinter interface {
+	// Fingerprint returns the fingerprint of the public key, see `Fingerprint`.
+	Fingerprint(opts ...FingerprintOption) (string, error)
+}
+
+// FingerprintOption configures a `Fingerprint`.
+type FingerprintOption func(f *fingerprintOptions)
+
+type fingerprintOptions struct {
+	method   FingerprintMethod
+	encoding FingerprintEncoding
+}
+
+// WithFingerprintMethod sets the _method_ (default `FingerprintSPKI`).
+func WithFingerprintMethod(method FingerprintMethod) FingerprintOption {
+	return func(f *fingerprintOptions) {
+		f.method = method
+	}
+}
+
+// WithFingerprintEncoding sets the _encoding_ (default `FingerprintHex`).
+func WithFingerprintEncoding(encoding FingerprintEncoding) FingerprintOption {
+	return func(f *fingerprintOptions) {
+		f.encoding = encoding
+	}
+}
+
+// Fingerprint returns the fingerprint of the public portion of _key_, such that the key can be
+// cross-referenced in logs, pinning configurations and _kid_ headers without exporting it.
+//
+// A key pair and its public key have the same fingerprint. Symmetric keys have no public
+// portion and are rejected.
+func Fingerprint(key ifcrypto.Key, opts ...FingerprintOption) (string, error) {
+
+	f := fingerprintOptions{method: FingerprintSPKI, encoding: FingerprintHex}
+
+	for _, opt := range opts {
+		opt(&f)
+	}
+
+	if key.IsSymmetric() || key.GetKeyType() == ifcrypto.KeyTypeHmac {
+		return "", fmt.Errorf("symmetric key %s has no fingerprint", key.GetID())
+	}
+
+	var public ifcrypto.Key = key
+
+	if kp, ok := key.(ifcrypto.KeyPair); ok {
+
+		if kp.GetPublic() == nil {
+			return "", fmt.Errorf("key %s has no public key", key.GetID())
+		}
+
+		public = kp.GetPublic()
+
+	}
+
+	var (
+		sum []byte
+		err error
+	)
+
+	switch f.method {
+	case FingerprintSPKI:
+
+		var der []byte
+
+		if der, err = publicKeyDER(public); err == nil {
+
+			digest := sha256.Sum256(der)
+			sum = digest[:]
+
+		}
+
+	case FingerprintJWKThumbprint:
+
+		marshaler, ok := public.(JWKMarshaler)
+		if !ok {
+			return "", fmt.Errorf("key %s can not be encoded as JWK", key.GetID())
+		}
+
+		jwk, jwkErr := marshaler.ToJWK()
+		if jwkErr != nil {
+			return "", jwkErr
+		}
+
+		sum, err = jwk.Thumbprint(crypto.SHA256)
+
+	default:
+		return "", fmt.Errorf("unsupported fingerprint method: %s", f.method)
+	}
+
+	if err != nil {
+		return "", err
+	}
+
+	switch f.encoding {
+	case FingerprintHex:
+		return hex.EncodeToString(sum), nil
+	case FingerprintBase64URL:
+		return base64.RawURLEncoding.EncodeToString(sum), nil
+	}
+
+	return "", fmt.Errorf("unsupported fingerprint encoding: %s", f.encoding)
+
+}
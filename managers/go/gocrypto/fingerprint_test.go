@@ -0,0 +1,80 @@
+//go:build !verifyonly
+// +build !verifyonly
+
+package gocrypto
+
+import (
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/hex"
+	"testing"
+
+	"github.com/mariotoffia/goservice/interfaces/ifcrypto"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFingerprintSPKI(t *testing.T) {
+
+	key, err := NewECDSAPrivateKey("ec", 256, ifcrypto.KeyUsageSign)
+	require.NoError(t, err)
+
+	der, err := x509.MarshalPKIXPublicKey(key.Public())
+	require.NoError(t, err)
+
+	sum := sha256.Sum256(der)
+
+	fingerprint, err := key.Fingerprint()
+	require.NoError(t, err)
+	assert.Equal(t, hex.EncodeToString(sum[:]), fingerprint)
+
+	public, err := key.GetPublic().(Fingerprinter).Fingerprint()
+	require.NoError(t, err)
+	assert.Equal(t, fingerprint, public)
+
+	b64, err := Fingerprint(key, WithFingerprintEncoding(FingerprintBase64URL))
+	require.NoError(t, err)
+	assert.Len(t, b64, 43)
+
+}
+
+func TestFingerprintJWKThumbprint(t *testing.T) {
+
+	// RFC 8037 appendix A.3
+	key, err := NewKeyFromJWK([]byte(`{"kty":"OKP","crv":"Ed25519",
+		"d":"nWGxne_9WmC6hEr0kuwsxERJxWl7MmkZcDusAxyuf2A",
+		"x":"11qYAYKxCrfVS_7TyWQHOg7hcvPapiMlrwIaaPcHURo"}`), ifcrypto.KeyUsageSign)
+	require.NoError(t, err)
+
+	thumbprint, err := Fingerprint(key,
+		WithFingerprintMethod(FingerprintJWKThumbprint),
+		WithFingerprintEncoding(FingerprintBase64URL),
+	)
+	require.NoError(t, err)
+	assert.Equal(t, "kPrK_qmxVWaYVA9wwBF6Iuo3vVzz7TxHCTwXBygrS4k", thumbprint)
+
+	for _, generate := range []func() (ifcrypto.KeyPair, error){
+		func() (ifcrypto.KeyPair, error) { return NewRSAPrivateKey("rsa", 2048, ifcrypto.KeyUsageSign) },
+		func() (ifcrypto.KeyPair, error) { return NewSecp256k1PrivateKey("k1", ifcrypto.KeyUsageSign) },
+		func() (ifcrypto.KeyPair, error) { return NewX25519PrivateKey("x", ifcrypto.KeyUsageKeyAgreement) },
+	} {
+
+		pair, err := generate()
+		require.NoError(t, err)
+
+		private, err := Fingerprint(pair, WithFingerprintMethod(FingerprintJWKThumbprint))
+		require.NoError(t, err)
+
+		public, err := Fingerprint(pair.GetPublic(), WithFingerprintMethod(FingerprintJWKThumbprint))
+		require.NoError(t, err)
+		assert.Equal(t, private, public)
+
+	}
+
+	symmetric, err := NewSymmetricKey("aes", 256, ifcrypto.KeyUsageEncrypt)
+	require.NoError(t, err)
+
+	_, err = Fingerprint(symmetric)
+	assert.Error(t, err)
+
+}
@@ -0,0 +1,194 @@
+package gocrypto
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/mariotoffia/goservice/interfaces/ifcrypto"
+)
+
+// ErrVariableTime is returned by `NewHardenedKey` when the key would use a variable-time
+// implementation, e.g. the generic curve arithmetic of _secp256k1_.
+var ErrVariableTime = fmt.Errorf("key operation is not constant-time")
+
+// ErrFaultDetected is returned by a `HardenedKey` when a signature do not verify after
+// signing, i.e. the computation was faulty. The faulty signature is never returned since it
+// may leak the private key.
+var ErrFaultDetected = fmt.Errorf("signature fault detected")
+
+// HardenedFault is a audit event emitted by the `HardenedKey` when a fault is detected.
+type HardenedFault struct {
+	Time  time.Time
+	KeyID string
+	Err   error
+}
+
+// HardenedKeyOption configures a `HardenedKey`.
+type HardenedKeyOption func(h *HardenedKey)
+
+// WithFaultAuditor sets a function that receives all detected faults.
+func WithFaultAuditor(auditor func(fault HardenedFault)) HardenedKeyOption {
+	return func(h *HardenedKey) {
+		h.auditor = auditor
+	}
+}
+
+// HardenedKey is a side-channel hardened high-value software key.
+//
+// It only accepts keys whose operations run on constant-time implementations, i.e. _RSA_,
+// _ECDSA_ on the _NIST_ curves of the standard library and _Ed25519_. Keys that would fall back
+// to variable-time arithmetic, such as _secp256k1_, are rejected with `ErrVariableTime`.
+//
+// NOTE: The standard library _RSA_ is only constant-time as of _Go 1.20_, the minimum version
+// of this module. Building with an older toolchain voids the guarantee for _RSA_ keys.
+//
+// Each signature is verified with the public key before it is returned, such that a fault
+// induced during signing, e.g. a _RSA-CRT_ fault, is detected instead of leaking the key.
+//
+// .Example
+// [source,go]
+// ----
+// key, err := NewHardenedKey(issuer, WithFaultAuditor(alert))
+//
+// signature, err := key.SignMessage(token, crypto.SHA256)
+// ----
+type HardenedKey struct {
+	ifcrypto.KeyPair
+	signer   crypto.Signer
+	verifier ifcrypto.SignatureVerifier
+	auditor  func(fault HardenedFault)
+}
+
+// NewHardenedKey creates a new `HardenedKey` of _key_. The _key_ must be a in process
+// `crypto.Signer` with a public key that implements `ifcrypto.SignatureVerifier`.
+func NewHardenedKey(key ifcrypto.KeyPair, opts ...HardenedKeyOption) (*HardenedKey, error) {
+
+	if key.IsRemoteKey() {
+		return nil, fmt.Errorf("key %s is a remote key", key.GetID())
+	}
+
+	signer, ok := key.(crypto.Signer)
+	if !ok {
+		return nil, fmt.Errorf("key %s is not a crypto.Signer", key.GetID())
+	}
+
+	verifier, ok := key.GetPublic().(ifcrypto.SignatureVerifier)
+	if !ok {
+		return nil, fmt.Errorf("key %s do not support signature verification", key.GetID())
+	}
+
+	if err := checkConstantTime(key); err != nil {
+		return nil, err
+	}
+
+	h := &HardenedKey{
+		KeyPair:  key,
+		signer:   signer,
+		verifier: verifier,
+		auditor:  func(HardenedFault) {},
+	}
+
+	for _, opt := range opts {
+		opt(h)
+	}
+
+	return h, nil
+
+}
+
+// Unwrap returns the hardened key.
+func (h *HardenedKey) Unwrap() ifcrypto.KeyPair {
+	return h.KeyPair
+}
+
+// Public implements the `crypto.Signer` _interface_.
+func (h *HardenedKey) Public() crypto.PublicKey {
+	return h.signer.Public()
+}
+
+// Sign implements the `crypto.Signer` _interface_. The signature is verified before it is
+// returned, if it do not verify `ErrFaultDetected` is returned.
+func (h *HardenedKey) Sign(rand io.Reader, digest []byte, opts crypto.SignerOpts) ([]byte, error) {
+
+	// The opts select the algorithm, e.g. crypto.Hash(0) for Ed25519
+	if opts == nil {
+		return nil, fmt.Errorf("key %s requires signer opts", h.GetID())
+	}
+
+	signature, err := h.signer.Sign(rand, digest, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := h.verifier.Verify(digest, signature, opts); err != nil {
+
+		for i := range signature {
+			signature[i] = 0
+		}
+
+		fault := fmt.Errorf("%w: %s: %v", ErrFaultDetected, h.GetID(), err)
+
+		h.auditor(HardenedFault{Time: time.Now().UTC(), KeyID: h.GetID(), Err: fault})
+
+		return nil, fault
+
+	}
+
+	return signature, nil
+
+}
+
+// SignMessage implements the `ifcrypto.MessageSigner` interface, see `SignMessage`.
+func (h *HardenedKey) SignMessage(msg []byte, hash crypto.Hash) ([]byte, error) {
+	return SignMessage(h, msg, hash)
+}
+
+// Decrypt implements the `crypto.Decrypter` _interface_ if the hardened key does, e.g. a
+// _RSA_ key, otherwise it fails.
+func (h *HardenedKey) Decrypt(rand io.Reader, msg []byte, opts crypto.DecrypterOpts) ([]byte, error) {
+
+	decrypter, ok := h.KeyPair.(crypto.Decrypter)
+	if !ok {
+		return nil, fmt.Errorf("key %s is not a crypto.Decrypter", h.GetID())
+	}
+
+	return decrypter.Decrypt(rand, msg, opts)
+
+}
+
+// checkConstantTime returns `ErrVariableTime` unless the operations of _key_ are constant-time.
+func checkConstantTime(key ifcrypto.Key) error {
+
+	switch k := key.GetKey().(type) {
+	case *rsa.PrivateKey:
+
+		// Multi-prime keys are not handled by the constant-time CRT implementation
+		if len(k.Primes) != 2 {
+			return fmt.Errorf("%w: %s is a multi-prime RSA key", ErrVariableTime, key.GetID())
+		}
+
+		return nil
+
+	case *ecdsa.PrivateKey:
+
+		switch k.Curve {
+		case elliptic.P224(), elliptic.P256(), elliptic.P384(), elliptic.P521():
+			return nil
+		}
+
+		return fmt.Errorf("%w: %s uses curve %s", ErrVariableTime, key.GetID(), k.Params().Name)
+
+	}
+
+	if key.GetKeyType() == ifcrypto.KeyTypeEd25519 {
+		return nil
+	}
+
+	return fmt.Errorf("%w: %s is a %s key", ErrVariableTime, key.GetID(), key.GetKeyType())
+
+}
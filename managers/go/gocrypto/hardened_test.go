@@ -0,0 +1,113 @@
+//go:build !verifyonly
+// +build !verifyonly
+
+package gocrypto
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"errors"
+	"io"
+	"testing"
+
+	"github.com/mariotoffia/goservice/interfaces/ifcrypto"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// faultyECDSAKey flips a bit in each signature, as a induced fault would.
+type faultyECDSAKey struct {
+	*ECDSAPrivateKey
+}
+
+func (k *faultyECDSAKey) Sign(rand io.Reader, digest []byte, opts crypto.SignerOpts) ([]byte, error) {
+
+	signature, err := k.ECDSAPrivateKey.Sign(rand, digest, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	signature[len(signature)-1] ^= 0x01
+
+	return signature, nil
+
+}
+
+func TestHardenedKeySignsAndVerifies(t *testing.T) {
+
+	rsaKey, err := NewRSAPrivateKey("rsa", 2048, ifcrypto.KeyUsageSign, ifcrypto.KeyUsageDecrypt)
+	require.NoError(t, err)
+
+	ecKey, err := NewECDSAPrivateKey("ec", 384, ifcrypto.KeyUsageSign)
+	require.NoError(t, err)
+
+	edKey, err := NewED25519PrivateKey("ed", ifcrypto.KeyUsageSign)
+	require.NoError(t, err)
+
+	for _, key := range []ifcrypto.KeyPair{rsaKey, ecKey, edKey} {
+
+		hardened, err := NewHardenedKey(key)
+		require.NoError(t, err, key.GetID())
+		assert.Equal(t, key.GetID(), hardened.GetID())
+
+		signature, err := hardened.SignMessage([]byte("message"), crypto.SHA256)
+		require.NoError(t, err, key.GetID())
+		require.NoError(t, VerifyMessage(key.GetPublic(), []byte("message"), signature, crypto.SHA256))
+
+	}
+
+	hardened, err := NewHardenedKey(rsaKey)
+	require.NoError(t, err)
+
+	digest := sha256.Sum256([]byte("message"))
+
+	assert.NotPanics(t, func() {
+		_, err = hardened.Sign(rand.Reader, digest[:], nil)
+	})
+
+	assert.Error(t, err, "nil opts")
+
+	ciphertext, err := rsa.EncryptOAEP(sha256.New(), rand.Reader, rsaKey.GetPublic().GetKey().(*rsa.PublicKey), []byte("secret"), nil)
+	require.NoError(t, err)
+
+	plaintext, err := hardened.Decrypt(rand.Reader, ciphertext, &rsa.OAEPOptions{Hash: crypto.SHA256})
+	require.NoError(t, err)
+	assert.Equal(t, "secret", string(plaintext))
+
+	hardened, err = NewHardenedKey(edKey)
+	require.NoError(t, err)
+
+	_, err = hardened.Decrypt(rand.Reader, ciphertext, nil)
+	assert.Error(t, err)
+
+}
+
+func TestHardenedKeyRejectsVariableTimeAndDetectsFaults(t *testing.T) {
+
+	k1Key, err := NewSecp256k1PrivateKey("k1", ifcrypto.KeyUsageSign)
+	require.NoError(t, err)
+
+	_, err = NewHardenedKey(k1Key)
+	assert.True(t, errors.Is(err, ErrVariableTime))
+
+	ecKey, err := NewECDSAPrivateKey("ec", 256, ifcrypto.KeyUsageSign)
+	require.NoError(t, err)
+
+	var faults []HardenedFault
+
+	hardened, err := NewHardenedKey(&faultyECDSAKey{ecKey}, WithFaultAuditor(func(fault HardenedFault) {
+		faults = append(faults, fault)
+	}))
+	require.NoError(t, err)
+
+	digest := sha256.Sum256([]byte("message"))
+
+	signature, err := hardened.Sign(rand.Reader, digest[:], crypto.SHA256)
+	assert.True(t, errors.Is(err, ErrFaultDetected))
+	assert.Nil(t, signature)
+	require.Len(t, faults, 1)
+	assert.Equal(t, "ec", faults[0].KeyID)
+
+}
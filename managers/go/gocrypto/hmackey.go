@@ -0,0 +1,185 @@
+package gocrypto
+
+import (
+	"crypto"
+	"crypto/hmac"
+	"encoding/pem"
+	"fmt"
+	"io"
+
+	"github.com/mariotoffia/goservice/interfaces/ifcrypto"
+	"github.com/mariotoffia/goservice/utils/cryptoutils"
+)
+
+// HMACKeyPEMType is the _PEM_ block type used to armor a `HMACKey`.
+const HMACKeyPEMType = "HMAC KEY"
+
+// ErrInvalidMAC is returned by `HMACKey.VerifyMAC` when the _MAC_ do not match.
+var ErrInvalidMAC = fmt.Errorf("invalid MAC")
+
+// hmacAlgorithms maps the supported hashes to their `ifcrypto.SignAlgorithm`.
+var hmacAlgorithms = map[crypto.Hash]ifcrypto.SignAlgorithm{
+	crypto.SHA256: ifcrypto.SignAlgorithmHmacSha256,
+	crypto.SHA384: ifcrypto.SignAlgorithmHmacSha384,
+	crypto.SHA512: ifcrypto.SignAlgorithmHmacSha512,
+}
+
+// HMACKey implements the `ifcrypto.KeyPair` interface for a _HMAC_ key bound to a hash,
+// e.g. for _JWT HS256_ or webhook signatures.
+//
+// The key implements `crypto.Signer`, but in contrast to asymmetric keys `Sign` is passed
+// the message, not a digest. A _HMAC_ key has no public portion, hence `GetPublic` returns
+// `nil` and the same key is used to `Verify`.
+type HMACKey struct {
+	KeyBase
+	key  []byte
+	hash crypto.Hash
+}
+
+// NewHMACKeyFromPEM initializes a new `HMACKey` from the underlying _PEM_ block.
+func NewHMACKeyFromPEM(
+	block pem.Block,
+	id string,
+	hash crypto.Hash,
+	usage ...ifcrypto.KeyUsage,
+) (*HMACKey, error) {
+
+	if block.Type == HMACKeyPEMType {
+		return NewHMACKeyFromBytes(id, block.Bytes, hash, usage...)
+	}
+
+	return nil, fmt.Errorf("unsupported PEM block: %s", block.Type)
+
+}
+
+// NewHMACKey generates a new `HMACKey`, of the same size as the _hash_ output, using the
+// `rand.Reader`, or the `EntropyGuard` reader, as entropy.
+func NewHMACKey(id string, hash crypto.Hash, usage ...ifcrypto.KeyUsage) (*HMACKey, error) {
+
+	if _, ok := hmacAlgorithms[hash]; !ok {
+		return nil, fmt.Errorf("unsupported HMAC hash: %s", hash)
+	}
+
+	reader, err := keyGenerationReader()
+	if err != nil {
+		return nil, err
+	}
+
+	key := make([]byte, hash.Size())
+
+	if _, err := io.ReadFull(reader, key); err != nil {
+		return nil, err
+	}
+
+	return NewHMACKeyFromBytes(id, key, hash, usage...)
+
+}
+
+// Algorithm returns the `ifcrypto.SignAlgorithm` of the key.
+func (r *HMACKey) Algorithm() ifcrypto.SignAlgorithm {
+	return hmacAlgorithms[r.hash]
+}
+
+// CanSign checks if the key may sign with _alg_, that must be the algorithm of the hash the
+// key is bound to.
+func (r *HMACKey) CanSign(alg ifcrypto.SignAlgorithm) bool {
+	return r.KeyBase.CanSign(alg) && alg == r.Algorithm()
+}
+
+// CanVerify checks if the key may verify with _alg_, that must be the algorithm of the hash
+// the key is bound to.
+func (r *HMACKey) CanVerify(alg ifcrypto.SignAlgorithm) bool {
+	return r.KeyBase.CanVerify(alg) && alg == r.Algorithm()
+}
+
+// VerifyMAC verifies, in constant time, the _mac_ of _message_. It returns `ErrInvalidMAC` if
+// the _mac_ do not match.
+//
+// It is not named _Verify_ since it takes the message, not a digest, and hence do not
+// implement `ifcrypto.SignatureVerifier`.
+func (r *HMACKey) VerifyMAC(message, mac []byte) error {
+
+	if err := CheckAlgorithm(ifcrypto.PolicyOperationVerify, r, r.hash); err != nil {
+		return err
+	}
+
+	if !hmac.Equal(r.sum(message), mac) {
+		return ErrInvalidMAC
+	}
+
+	return nil
+
+}
+
+// sum returns the _MAC_ of _message_.
+func (r *HMACKey) sum(message []byte) []byte {
+
+	mac := hmac.New(r.hash.New, r.key)
+	mac.Write(message)
+
+	return mac.Sum(nil)
+
+}
+
+// Public implements the `crypto.Signer` _interface_. It returns `nil` since there is no public key.
+func (r *HMACKey) Public() crypto.PublicKey {
+	return nil
+}
+
+// GetPublic returns `nil` since a _HMAC_ key has no public portion.
+func (r *HMACKey) GetPublic() ifcrypto.PublicKey {
+	return nil
+}
+
+// PEMWrite will write the key onto _w_ as a _HMAC KEY_ PEM block.
+//
+// Since there is no public portion, the _public_ parameter is ignored.
+func (r *HMACKey) PEMWrite(w io.Writer, public bool) error {
+
+	return pem.Encode(w, &pem.Block{Type: HMACKeyPEMType, Bytes: r.key})
+
+}
+
+// DERWrite implements the `ifcrypto.DERWriter` interface, the raw key bytes are written. Use
+// `NewHMACKeyFromBytes` to read them.
+func (r *HMACKey) DERWrite(w io.Writer) error {
+
+	_, err := w.Write(r.key)
+
+	return err
+
+}
+
+// ToJWK implements the `JWKMarshaler` interface and returns a _oct_ _JWK_ with the secret key.
+func (r *HMACKey) ToJWK() (*cryptoutils.JSONWebKey, error) {
+	return keyToJWK(r)
+}
+
+// GetKey gets the underlying key, the raw key bytes.
+//
+// Some keys are remote and not possible to fetch. In such situations the function returns a remote id,
+// most often the same as GetID() returns.
+func (r *HMACKey) GetKey() interface{} {
+	return r.key
+}
+
+// IsSymmetric returns `true` since a _HMAC_ key is a symmetric key, even though its
+// `KeyType` is `KeyTypeHmac`.
+func (r *HMACKey) IsSymmetric() bool {
+	return true
+}
+
+// IsPrivate returns `true` if this is a `KeyType` other than `KeyTypeSymmetric` and is a private key.
+//
+// If `KeyTypeSymmetric` it will return `true` since all symmetric keys are considered as private.
+func (r *HMACKey) IsPrivate() bool {
+	return true
+}
+
+// IsRemoteKey returns `true` if the key is not present in current process memory.
+//
+// Typically hardware units or remote services will not reveal their private key. In such case, this
+// method returns `true`. If present in memory such as a `*rsa.PrivateKey` it returns `false`.
+func (r *HMACKey) IsRemoteKey() bool {
+	return false
+}
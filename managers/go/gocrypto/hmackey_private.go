@@ -0,0 +1,73 @@
+//go:build !verifyonly
+// +build !verifyonly
+
+package gocrypto
+
+import (
+	"crypto"
+	"fmt"
+	"io"
+
+	"github.com/mariotoffia/goservice/interfaces/ifcrypto"
+)
+
+// NewHMACKeyFromBytes creates a new `HMACKey` using _hash_ (_SHA-256_, _SHA-384_ or _SHA-512_).
+//
+// As of _RFC 7518_ section 3.2, the _key_ must be at least as long as the _hash_ output.
+func NewHMACKeyFromBytes(
+	id string,
+	key []byte,
+	hash crypto.Hash,
+	usage ...ifcrypto.KeyUsage,
+) (*HMACKey, error) {
+
+	if IsVerifyOnly() {
+		return nil, ErrVerifyOnly
+	}
+
+	if _, ok := hmacAlgorithms[hash]; !ok {
+		return nil, fmt.Errorf("unsupported HMAC hash: %s", hash)
+	}
+
+	if len(key) < hash.Size() {
+		return nil, fmt.Errorf("HMAC key must be at least %d bytes for %s", hash.Size(), hash)
+	}
+
+	return &HMACKey{
+		KeyBase: KeyBase{
+			id:      id,
+			keyType: ifcrypto.KeyTypeHmac,
+			keySize: len(key) * 8,
+			usage:   usage,
+			chiper:  []ifcrypto.Chipher{},
+		},
+		key:  append([]byte{}, key...),
+		hash: hash,
+	}, nil
+
+}
+
+// Sign implements the `crypto.Signer` _interface_ and returns the _MAC_ of the _message_.
+//
+// The _opts_ may be `nil`, or its hash must be zero or the hash of the key.
+func (r *HMACKey) Sign(
+	rand io.Reader,
+	message []byte,
+	opts crypto.SignerOpts,
+) ([]byte, error) {
+
+	if IsVerifyOnly() {
+		return nil, ErrVerifyOnly
+	}
+
+	if opts != nil && opts.HashFunc() != 0 && opts.HashFunc() != r.hash {
+		return nil, fmt.Errorf("HMAC key %s is bound to %s, not %s", r.id, r.hash, opts.HashFunc())
+	}
+
+	if err := CheckAlgorithm(ifcrypto.PolicyOperationSign, r, r.hash); err != nil {
+		return nil, err
+	}
+
+	return r.sum(message), nil
+
+}
@@ -0,0 +1,52 @@
+//go:build !verifyonly
+// +build !verifyonly
+
+package gocrypto
+
+import (
+	"crypto"
+	"encoding/hex"
+	"testing"
+
+	"github.com/mariotoffia/goservice/interfaces/ifcrypto"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHMACKeySignAndVerify(t *testing.T) {
+
+	key, err := NewHMACKeyFromBytes("webhook", []byte("0123456789abcdef0123456789abcdef"), crypto.SHA256,
+		ifcrypto.KeyUsageSign, ifcrypto.KeyUsageVerify,
+	)
+
+	require.NoError(t, err)
+
+	var _ ifcrypto.KeyPair = key
+	var _ crypto.Signer = key
+
+	assert.True(t, key.CanSign(ifcrypto.SignAlgorithmHmacSha256))
+	assert.False(t, key.CanSign(ifcrypto.SignAlgorithmHmacSha512))
+	assert.False(t, key.CanSign(ifcrypto.SignAlgorithmEcdSha256))
+	assert.True(t, key.CanVerify(ifcrypto.SignAlgorithmHmacSha256))
+	assert.False(t, key.CanVerify(ifcrypto.SignAlgorithmHmacSha384))
+	assert.Equal(t, ifcrypto.SignAlgorithmHmacSha256, key.Algorithm())
+
+	mac, err := key.Sign(nil, []byte("payload"), crypto.SHA256)
+	require.NoError(t, err)
+
+	assert.Equal(t, "1884c50fba58f58371a23a9f6424571bb9b9b0437aa6402e25c928f65a188952", hex.EncodeToString(mac))
+
+	assert.NoError(t, key.VerifyMAC([]byte("payload"), mac))
+	assert.Equal(t, ErrInvalidMAC, key.VerifyMAC([]byte("tampered"), mac))
+
+	_, err = key.Sign(nil, []byte("payload"), crypto.SHA512)
+	assert.Error(t, err)
+
+	_, err = NewHMACKeyFromBytes("short", []byte("secret"), crypto.SHA256)
+	assert.Error(t, err)
+
+	generated, err := NewHMACKey("hs512", crypto.SHA512)
+	require.NoError(t, err)
+	assert.Equal(t, 512, generated.GetKeySize())
+
+}
@@ -0,0 +1,348 @@
+package gocrypto
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mariotoffia/goservice/interfaces/ifcrypto"
+	"github.com/mariotoffia/goservice/utils/cryptoutils"
+)
+
+// InventoryKind is the kind of material in a `InventoryEntry`.
+type InventoryKind string
+
+const (
+	// InventoryKindKey is a `ifcrypto.Key`.
+	InventoryKindKey InventoryKind = "key"
+	// InventoryKindCertificate is a `*x509.Certificate`.
+	InventoryKindCertificate InventoryKind = "certificate"
+)
+
+// InventorySource is a set of keys and certificates to include in a inventory,
+// for example a keystore or a trust store.
+type InventorySource interface {
+	// Name returns the name of the source, it is reported as _source_ on each entry.
+	Name() string
+	// Keys returns all keys in the source.
+	Keys() ([]ifcrypto.Key, error)
+	// Certificates returns all certificates in the source.
+	Certificates() ([]*x509.Certificate, error)
+}
+
+// StaticInventorySource is a `InventorySource` with a fixed set of keys and certificates.
+type StaticInventorySource struct {
+	// SourceName is returned by `Name`.
+	SourceName string
+	// KeyList is returned by `Keys`.
+	KeyList []ifcrypto.Key
+	// CertificateList is returned by `Certificates`.
+	CertificateList []*x509.Certificate
+}
+
+// Name implements the `InventorySource` interface.
+func (s *StaticInventorySource) Name() string {
+	return s.SourceName
+}
+
+// Keys implements the `InventorySource` interface.
+func (s *StaticInventorySource) Keys() ([]ifcrypto.Key, error) {
+	return s.KeyList, nil
+}
+
+// Certificates implements the `InventorySource` interface.
+func (s *StaticInventorySource) Certificates() ([]*x509.Certificate, error) {
+	return s.CertificateList, nil
+}
+
+// InventoryEntry is a single key or certificate in a `InventoryReport`.
+type InventoryEntry struct {
+	Source    string        `json:"source"`
+	Kind      InventoryKind `json:"kind"`
+	ID        string        `json:"id"`
+	Algorithm string        `json:"algorithm"`
+	Size      int           `json:"size"`
+	// Usage is only set on keys.
+	Usage []ifcrypto.KeyUsage `json:"usage,omitempty"`
+	// Private is only set on keys.
+	Private bool `json:"private,omitempty"`
+	// Remote is only set on keys.
+	Remote bool `json:"remote,omitempty"`
+	// Subject is only set on certificates.
+	Subject string `json:"subject,omitempty"`
+	// Issuer is only set on certificates.
+	Issuer string `json:"issuer,omitempty"`
+	// SignatureAlgorithm is only set on certificates.
+	SignatureAlgorithm string `json:"signature_algorithm,omitempty"`
+	// NotBefore is only set on certificates.
+	NotBefore *time.Time `json:"not_before,omitempty"`
+	// NotAfter is only set on certificates.
+	NotAfter *time.Time `json:"not_after,omitempty"`
+	// Findings is the weak crypto findings, if any.
+	Findings []cryptoutils.LintFinding `json:"findings"`
+}
+
+// InventoryReport is the result of `Inventory.Report`.
+type InventoryReport struct {
+	// GeneratedAt is when the report was generated.
+	GeneratedAt time.Time `json:"generated_at"`
+	// Entries is all keys and certificates sorted by source, kind and id.
+	Entries []InventoryEntry `json:"entries"`
+	// Errors is the sources that failed to be read.
+	Errors []string `json:"errors,omitempty"`
+}
+
+// Inventory walks a set of `InventorySource` and produces a `InventoryReport`.
+type Inventory struct {
+	mu      sync.Mutex
+	sources []InventorySource
+}
+
+// NewInventory creates a new `Inventory` of the _sources_.
+func NewInventory(sources ...InventorySource) *Inventory {
+	return &Inventory{sources: sources}
+}
+
+// AddSource adds a _source_ to the inventory.
+func (inv *Inventory) AddSource(source InventorySource) *Inventory {
+
+	inv.mu.Lock()
+	defer inv.mu.Unlock()
+
+	inv.sources = append(inv.sources, source)
+
+	return inv
+
+}
+
+// Report walks all sources and reports each key and certificate with its findings at _now_.
+// Ephemeral keys, see `IsEphemeralKey`, are not reported.
+//
+// A failing source is reported in `InventoryReport.Errors` and do not stop the report.
+func (inv *Inventory) Report(now time.Time) *InventoryReport {
+
+	inv.mu.Lock()
+	sources := append([]InventorySource{}, inv.sources...)
+	inv.mu.Unlock()
+
+	report := &InventoryReport{
+		GeneratedAt: now.UTC(),
+		Entries:     []InventoryEntry{},
+	}
+
+	for _, source := range sources {
+
+		keys, err := source.Keys()
+		if err != nil {
+			report.Errors = append(report.Errors, fmt.Sprintf("%s: %v", source.Name(), err))
+		}
+
+		for _, key := range keys {
+
+			if IsEphemeralKey(key) {
+				continue
+			}
+
+			report.Entries = append(report.Entries, keyInventoryEntry(source.Name(), key))
+
+		}
+
+		certs, err := source.Certificates()
+		if err != nil {
+			report.Errors = append(report.Errors, fmt.Sprintf("%s: %v", source.Name(), err))
+		}
+
+		for _, cert := range certs {
+			report.Entries = append(report.Entries, certificateInventoryEntry(source.Name(), cert, now))
+		}
+
+	}
+
+	sort.SliceStable(report.Entries, func(i, j int) bool {
+
+		a, b := report.Entries[i], report.Entries[j]
+
+		if a.Source != b.Source {
+			return a.Source < b.Source
+		}
+
+		if a.Kind != b.Kind {
+			return a.Kind < b.Kind
+		}
+
+		return a.ID < b.ID
+
+	})
+
+	return report
+
+}
+
+// WriteJSON writes the report as a indented _JSON_ document onto _w_.
+func (r *InventoryReport) WriteJSON(w io.Writer) error {
+
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+
+	return enc.Encode(r)
+
+}
+
+// ReadInventoryReportJSON reads a report written by `InventoryReport.WriteJSON` from _r_.
+func ReadInventoryReportJSON(r io.Reader) (*InventoryReport, error) {
+
+	var report InventoryReport
+
+	if err := json.NewDecoder(r).Decode(&report); err != nil {
+		return nil, err
+	}
+
+	return &report, nil
+
+}
+
+// WriteCSV writes the report entries as _CSV_ with a header row onto _w_.
+//
+// Multi valued columns such as usage and findings are separated by a semicolon.
+func (r *InventoryReport) WriteCSV(w io.Writer) error {
+
+	cw := csv.NewWriter(w)
+
+	if err := cw.Write([]string{
+		"source", "kind", "id", "algorithm", "size", "usage", "private", "remote",
+		"subject", "issuer", "signature_algorithm", "not_before", "not_after", "findings",
+	}); err != nil {
+		return err
+	}
+
+	for _, e := range r.Entries {
+
+		usage := make([]string, len(e.Usage))
+		for i := range e.Usage {
+			usage[i] = string(e.Usage[i])
+		}
+
+		findings := make([]string, len(e.Findings))
+		for i := range e.Findings {
+			findings[i] = e.Findings[i].String()
+		}
+
+		if err := cw.Write([]string{
+			e.Source,
+			string(e.Kind),
+			e.ID,
+			e.Algorithm,
+			strconv.Itoa(e.Size),
+			strings.Join(usage, ";"),
+			strconv.FormatBool(e.Private),
+			strconv.FormatBool(e.Remote),
+			e.Subject,
+			e.Issuer,
+			e.SignatureAlgorithm,
+			formatInventoryTime(e.NotBefore),
+			formatInventoryTime(e.NotAfter),
+			strings.Join(findings, ";"),
+		}); err != nil {
+			return err
+		}
+
+	}
+
+	cw.Flush()
+
+	return cw.Error()
+
+}
+
+// keyInventoryEntry creates a entry for the _key_.
+//
+// Remote keys are not linted since their material is not available.
+func keyInventoryEntry(source string, key ifcrypto.Key) InventoryEntry {
+
+	entry := InventoryEntry{
+		Source:    source,
+		Kind:      InventoryKindKey,
+		ID:        key.GetID(),
+		Algorithm: string(key.GetKeyType()),
+		Size:      key.GetKeySize(),
+		Usage:     key.GetKeyUsage(),
+		Private:   key.IsPrivate(),
+		Remote:    key.IsRemoteKey(),
+		Findings:  []cryptoutils.LintFinding{},
+	}
+
+	if key.IsRemoteKey() || key.IsSymmetric() {
+		return entry
+	}
+
+	public := key.GetKey()
+
+	if kp, ok := key.(ifcrypto.KeyPair); ok {
+		public = kp.GetPublic().GetKey()
+	}
+
+	entry.Findings = append(entry.Findings, cryptoutils.LintPublicKey(public)...)
+
+	return entry
+
+}
+
+// certificateInventoryEntry creates a entry for the _cert_.
+func certificateInventoryEntry(source string, cert *x509.Certificate, now time.Time) InventoryEntry {
+
+	notBefore := cert.NotBefore.UTC()
+	notAfter := cert.NotAfter.UTC()
+
+	return InventoryEntry{
+		Source:             source,
+		Kind:               InventoryKindCertificate,
+		ID:                 cert.SerialNumber.Text(16),
+		Algorithm:          cert.PublicKeyAlgorithm.String(),
+		Size:               publicKeySize(cert.PublicKey),
+		Subject:            cert.Subject.String(),
+		Issuer:             cert.Issuer.String(),
+		SignatureAlgorithm: cert.SignatureAlgorithm.String(),
+		NotBefore:          &notBefore,
+		NotAfter:           &notAfter,
+		Findings:           cryptoutils.LintCertificate(cert, now),
+	}
+
+}
+
+// formatInventoryTime formats _t_ as _RFC 3339_ or empty string if `nil`.
+func formatInventoryTime(t *time.Time) string {
+
+	if t == nil {
+		return ""
+	}
+
+	return t.Format(time.RFC3339)
+
+}
+
+// publicKeySize returns the size in bits of the _pub_ key or zero if unknown.
+func publicKeySize(pub crypto.PublicKey) int {
+
+	switch key := pub.(type) {
+	case *rsa.PublicKey:
+		return key.N.BitLen()
+	case *ecdsa.PublicKey:
+		return key.Params().BitSize
+	case ed25519.PublicKey:
+		return 256
+	}
+
+	return 0
+
+}
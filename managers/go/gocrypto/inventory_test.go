@@ -0,0 +1,45 @@
+//go:build !verifyonly
+// +build !verifyonly
+
+package gocrypto
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mariotoffia/goservice/interfaces/ifcrypto"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestInventoryReportsWeakKeys(t *testing.T) {
+
+	weak, err := NewRSAPrivateKey("weak", 1024, ifcrypto.KeyUsageSign)
+	require.NoError(t, err)
+
+	strong, err := NewRSAPrivateKey("strong", 2048, ifcrypto.KeyUsageSign)
+	require.NoError(t, err)
+
+	report := NewInventory(&StaticInventorySource{
+		SourceName: "test",
+		KeyList:    []ifcrypto.Key{weak, strong, strong.GetPublic()},
+	}).Report(time.Now())
+
+	require.Len(t, report.Entries, 3)
+
+	assert.Equal(t, "strong", report.Entries[0].ID)
+	assert.Equal(t, 2048, report.Entries[0].Size)
+	assert.Empty(t, report.Entries[0].Findings)
+	assert.False(t, report.Entries[1].Private)
+
+	assert.Equal(t, "weak", report.Entries[2].ID)
+	require.Len(t, report.Entries[2].Findings, 1)
+	assert.Equal(t, "weak-rsa-key", report.Entries[2].Findings[0].Code)
+
+	var buf bytes.Buffer
+	require.NoError(t, report.WriteCSV(&buf))
+
+	assert.Equal(t, 4, strings.Count(buf.String(), "\n"))
+}
@@ -0,0 +1,328 @@
+package gocrypto
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"encoding/json"
+	"fmt"
+
+	"github.com/mariotoffia/goservice/interfaces/ifcrypto"
+	"github.com/mariotoffia/goservice/utils/cryptoutils"
+)
+
+// JWKMarshaler is implemented by all keys in this package.
+type JWKMarshaler interface {
+	// ToJWK returns the _RFC 7517_ _JWK_ with _kid_, _use_, _alg_ and _key_ops_ set from the key.
+	ToJWK() (*cryptoutils.JSONWebKey, error)
+}
+
+// jwkKeyOps maps `ifcrypto.KeyUsage` to _JWK_ _key_ops_.
+var jwkKeyOps = map[ifcrypto.KeyUsage]string{
+	ifcrypto.KeyUsageSign:         "sign",
+	ifcrypto.KeyUsageVerify:       "verify",
+	ifcrypto.KeyUsageEncrypt:      "encrypt",
+	ifcrypto.KeyUsageDecrypt:      "decrypt",
+	ifcrypto.KeyUsageKeyAgreement: "deriveBits",
+}
+
+// jwkUsages maps _JWK_ _key_ops_ to `ifcrypto.KeyUsage`.
+var jwkUsages = map[string]ifcrypto.KeyUsage{
+	"sign":       ifcrypto.KeyUsageSign,
+	"verify":     ifcrypto.KeyUsageVerify,
+	"encrypt":    ifcrypto.KeyUsageEncrypt,
+	"wrapKey":    ifcrypto.KeyUsageEncrypt,
+	"decrypt":    ifcrypto.KeyUsageDecrypt,
+	"unwrapKey":  ifcrypto.KeyUsageDecrypt,
+	"deriveKey":  ifcrypto.KeyUsageKeyAgreement,
+	"deriveBits": ifcrypto.KeyUsageKeyAgreement,
+}
+
+// NewKeyFromJWK parses a _RFC 7517_ _JWK_ and returns the key.
+//
+// A private _JWK_ returns a `ifcrypto.KeyPair`, e.g. `*RSAPrivateKey`, a public _JWK_ returns
+// a `ifcrypto.PublicKey` and a _oct_ _JWK_ returns a `*HMACKey` if _alg_ is _HS256_, _HS384_
+// or _HS512_, a `*ChaChaKey` if _alg_ is _C20P_ or _XC20P_ and a `*SymmetricKey` otherwise.
+//
+// The _kid_ becomes the key id. If no _usage_ is passed, it is derived from _key_ops_ or _use_.
+func NewKeyFromJWK(data []byte, usage ...ifcrypto.KeyUsage) (ifcrypto.Key, error) {
+
+	var jwk cryptoutils.JSONWebKey
+
+	if err := json.Unmarshal(data, &jwk); err != nil {
+		return nil, err
+	}
+
+	return NewKeyFromJSONWebKey(&jwk, usage...)
+
+}
+
+// NewKeyFromJSONWebKey is the same as `NewKeyFromJWK` but for a already parsed _jwk_.
+func NewKeyFromJSONWebKey(jwk *cryptoutils.JSONWebKey, usage ...ifcrypto.KeyUsage) (ifcrypto.Key, error) {
+
+	if len(usage) == 0 {
+
+		var err error
+
+		if usage, err = jwkKeyUsage(jwk); err != nil {
+			return nil, err
+		}
+
+	}
+
+	id := jwk.KeyID
+
+	if (jwk.KeyType == "oct" || jwk.IsPrivate()) && IsVerifyOnly() {
+		return nil, ErrVerifyOnly
+	}
+
+	if jwk.KeyType == "oct" {
+
+		key, err := jwk.SymmetricKey()
+		if err != nil {
+			return nil, err
+		}
+
+		switch jwk.Algorithm {
+		case "HS256":
+			return NewHMACKeyFromBytes(id, key, crypto.SHA256, usage...)
+		case "HS384":
+			return NewHMACKeyFromBytes(id, key, crypto.SHA384, usage...)
+		case "HS512":
+			return NewHMACKeyFromBytes(id, key, crypto.SHA512, usage...)
+		case "C20P", "XC20P":
+			return NewChaChaKeyFromBytes(id, key, usage...)
+		}
+
+		return NewSymmetricKeyFromBytes(id, key, usage...)
+
+	}
+
+	if !jwk.IsPrivate() {
+
+		public, err := jwk.PublicKey()
+		if err != nil {
+			return nil, err
+		}
+
+		switch key := public.(type) {
+		case *rsa.PublicKey:
+			return NewRSAPublicKeyFromKey(id, key, usage...), nil
+		case *ecdsa.PublicKey:
+
+			if jwk.Curve == "secp256k1" {
+				return NewSecp256k1PublicKeyFromKey(id, key, usage...)
+			}
+
+			return NewECDSAPublicKeyFromKey(id, key, usage...), nil
+
+		case ed25519.PublicKey:
+			return NewED25519PublicKeyFromKey(id, key, usage...), nil
+		case []byte:
+			return NewX25519PublicKeyFromKey(id, key, usage...)
+		}
+
+		return nil, fmt.Errorf("unsupported JWK public key: %T", public)
+
+	}
+
+	private, err := jwk.PrivateKey()
+	if err != nil {
+		return nil, err
+	}
+
+	switch key := private.(type) {
+	case *rsa.PrivateKey:
+		return NewRSAPrivateKeyFromKey(id, key, usage...), nil
+	case *ecdsa.PrivateKey:
+
+		if jwk.Curve == "secp256k1" {
+			return NewSecp256k1PrivateKeyFromKey(id, key, usage...)
+		}
+
+		return NewECDSAPrivateKeyFromKey(id, key, usage...), nil
+
+	case ed25519.PrivateKey:
+		return NewED25519PrivateKeyFromKey(id, key, usage...), nil
+	case []byte:
+
+		pair, err := NewX25519PrivateKeyFromKey(id, key, usage...)
+		if err != nil {
+			return nil, err
+		}
+
+		if x, _ := jwk.PublicKey(); !bytes.Equal(x.([]byte), pair.GetPublic().GetKey().([]byte)) {
+			return nil, fmt.Errorf("JWK private key do not match the public key")
+		}
+
+		return pair, nil
+
+	}
+
+	return nil, fmt.Errorf("unsupported JWK private key: %T", private)
+
+}
+
+// keyToJWK creates the _JWK_ of _key_, see `JWKMarshaler`.
+func keyToJWK(key ifcrypto.Key) (*cryptoutils.JSONWebKey, error) {
+
+	var (
+		jwk *cryptoutils.JSONWebKey
+		err error
+		alg string
+	)
+
+	switch k := key.(type) {
+	case *RSAPrivateKey, *ECDSAPrivateKey, *ED25519PrivateKey, *Secp256k1PrivateKey:
+		jwk, err = cryptoutils.PrivateKeyToJWK(k.GetKey())
+	case *RSAPublicKey, *ECDSAPublicKey, *ED25519PublicKey, *Secp256k1PublicKey:
+		jwk, err = cryptoutils.PublicKeyToJWK(k.GetKey())
+	case *X25519PrivateKey:
+		jwk = cryptoutils.OctetKeyPairToJWK("X25519", k.public.key, k.key)
+	case *X25519PublicKey:
+		jwk = cryptoutils.OctetKeyPairToJWK("X25519", k.key, nil)
+	case *HMACKey:
+		jwk = cryptoutils.SymmetricKeyToJWK(k.key)
+		alg = "HS" + fmt.Sprint(k.hash.Size()*8)
+	case *ChaChaKey:
+		jwk = cryptoutils.SymmetricKeyToJWK(k.key)
+		alg = "XC20P"
+	case *SymmetricKey:
+		jwk = cryptoutils.SymmetricKeyToJWK(k.key)
+		alg = fmt.Sprintf("A%dGCM", k.GetKeySize())
+	default:
+		return nil, fmt.Errorf("unsupported JWK key: %T", key)
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	if alg == "" {
+
+		switch jwk.KeyType {
+		case "RSA":
+			alg = "RS256"
+		default:
+			alg = cryptoutils.JWKCurveAlgorithm(jwk.Curve)
+		}
+
+	}
+
+	jwk.KeyID = key.GetID()
+	jwk.Algorithm = alg
+	jwk.Use = jwkUse(key.GetKeyUsage())
+
+	for _, usage := range key.GetKeyUsage() {
+
+		if op, ok := jwkKeyOps[usage]; ok {
+			jwk.KeyOps = append(jwk.KeyOps, op)
+		}
+
+	}
+
+	return jwk, nil
+
+}
+
+// jwkUse returns _sig_ if all _usage_ is sign or verify, _enc_ if all is encryption or
+// key agreement, otherwise a empty string.
+func jwkUse(usage []ifcrypto.KeyUsage) string {
+
+	use := ""
+
+	for _, u := range usage {
+
+		var current string
+
+		switch u {
+		case ifcrypto.KeyUsageSign, ifcrypto.KeyUsageVerify:
+			current = "sig"
+		case ifcrypto.KeyUsageEncrypt, ifcrypto.KeyUsageDecrypt, ifcrypto.KeyUsageKeyAgreement:
+			current = "enc"
+		default:
+			continue
+		}
+
+		if use != "" && use != current {
+			return ""
+		}
+
+		use = current
+
+	}
+
+	return use
+
+}
+
+// jwkKeyUsage derives the `ifcrypto.KeyUsage` from _key_ops_, or _use_ if not set.
+func jwkKeyUsage(jwk *cryptoutils.JSONWebKey) ([]ifcrypto.KeyUsage, error) {
+
+	usage := []ifcrypto.KeyUsage{}
+
+	add := func(u ifcrypto.KeyUsage) {
+
+		for _, existing := range usage {
+
+			if existing == u {
+				return
+			}
+
+		}
+
+		usage = append(usage, u)
+
+	}
+
+	if len(jwk.KeyOps) > 0 {
+
+		for _, op := range jwk.KeyOps {
+
+			u, ok := jwkUsages[op]
+			if !ok {
+				return nil, fmt.Errorf("unsupported JWK key_ops: %s", op)
+			}
+
+			add(u)
+
+		}
+
+		return usage, nil
+
+	}
+
+	private := jwk.IsPrivate()
+
+	switch jwk.Use {
+	case "sig":
+
+		if private {
+			add(ifcrypto.KeyUsageSign)
+		}
+
+		add(ifcrypto.KeyUsageVerify)
+
+	case "enc":
+
+		if jwk.Curve == "X25519" {
+
+			add(ifcrypto.KeyUsageKeyAgreement)
+
+		} else {
+
+			add(ifcrypto.KeyUsageEncrypt)
+
+			if private {
+				add(ifcrypto.KeyUsageDecrypt)
+			}
+
+		}
+
+	}
+
+	return usage, nil
+
+}
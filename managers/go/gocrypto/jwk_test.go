@@ -0,0 +1,119 @@
+//go:build !verifyonly
+// +build !verifyonly
+
+package gocrypto
+
+import (
+	"crypto"
+	"encoding/json"
+	"testing"
+
+	"github.com/mariotoffia/goservice/interfaces/ifcrypto"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestJWKRoundTripAllKeyTypes(t *testing.T) {
+
+	sign := []ifcrypto.KeyUsage{ifcrypto.KeyUsageSign, ifcrypto.KeyUsageVerify}
+	enc := []ifcrypto.KeyUsage{ifcrypto.KeyUsageEncrypt, ifcrypto.KeyUsageDecrypt}
+
+	rsaKey, err := NewRSAPrivateKey("rsa", 2048, enc...)
+	require.NoError(t, err)
+
+	ecKey, err := NewECDSAPrivateKey("ec", 521, sign...)
+	require.NoError(t, err)
+
+	edKey, err := NewED25519PrivateKey("ed", sign...)
+	require.NoError(t, err)
+
+	k1Key, err := NewSecp256k1PrivateKey("k1", sign...)
+	require.NoError(t, err)
+
+	xKey, err := NewX25519PrivateKey("x", ifcrypto.KeyUsageKeyAgreement)
+	require.NoError(t, err)
+
+	hmacKey, err := NewHMACKey("hmac", crypto.SHA384, sign...)
+	require.NoError(t, err)
+
+	aesKey, err := NewSymmetricKey("aes", 192, enc...)
+	require.NoError(t, err)
+
+	chachaKey, err := NewChaChaKey("chacha", enc...)
+	require.NoError(t, err)
+
+	tests := []struct {
+		key ifcrypto.Key
+		kty string
+		alg string
+		use string
+	}{
+		{rsaKey, "RSA", "RS256", "enc"},
+		{rsaKey.GetPublic(), "RSA", "RS256", "enc"},
+		{ecKey, "EC", "ES512", "sig"},
+		{ecKey.GetPublic(), "EC", "ES512", "sig"},
+		{edKey, "OKP", "EdDSA", "sig"},
+		{edKey.GetPublic(), "OKP", "EdDSA", "sig"},
+		{k1Key, "EC", "ES256K", "sig"},
+		{k1Key.GetPublic(), "EC", "ES256K", "sig"},
+		{xKey, "OKP", "ECDH-ES", "enc"},
+		{xKey.GetPublic(), "OKP", "ECDH-ES", "enc"},
+		{hmacKey, "oct", "HS384", "sig"},
+		{aesKey, "oct", "A192GCM", "enc"},
+		{chachaKey, "oct", "XC20P", "enc"},
+	}
+
+	for _, tt := range tests {
+
+		t.Run(tt.key.GetID(), func(t *testing.T) {
+
+			jwk, err := tt.key.(JWKMarshaler).ToJWK()
+			require.NoError(t, err)
+
+			assert.Equal(t, tt.key.GetID(), jwk.KeyID)
+			assert.Equal(t, tt.kty, jwk.KeyType)
+			assert.Equal(t, tt.alg, jwk.Algorithm)
+			assert.Equal(t, tt.use, jwk.Use)
+			assert.Equal(t, tt.key.IsPrivate() || tt.key.IsSymmetric(), jwk.IsPrivate())
+			assert.Len(t, jwk.KeyOps, len(tt.key.GetKeyUsage()))
+
+			data, err := json.Marshal(jwk)
+			require.NoError(t, err)
+
+			key, err := NewKeyFromJWK(data)
+			require.NoError(t, err)
+
+			assert.IsType(t, tt.key, key)
+			assert.Equal(t, tt.key.GetID(), key.GetID())
+			assert.Equal(t, tt.key.GetKeyType(), key.GetKeyType())
+			assert.Equal(t, tt.key.GetKeySize(), key.GetKeySize())
+			assert.ElementsMatch(t, tt.key.GetKeyUsage(), key.GetKeyUsage())
+			assert.Equal(t, tt.key.GetKey(), key.GetKey())
+
+		})
+
+	}
+
+}
+
+func TestNewKeyFromJWKDerivesUsageFromUse(t *testing.T) {
+
+	key, err := NewKeyFromJWK([]byte(`{"kty":"OKP","crv":"Ed25519","use":"sig",
+		"x":"11qYAYKxCrfVS_7TyWQHOg7hcvPapiMlrwIaaPcHURo"}`))
+	require.NoError(t, err)
+
+	assert.IsType(t, &ED25519PublicKey{}, key)
+	assert.Equal(t, []ifcrypto.KeyUsage{ifcrypto.KeyUsageVerify}, key.GetKeyUsage())
+
+	key, err = NewKeyFromJWK([]byte(`{"kty":"OKP","crv":"Ed25519","use":"sig",
+		"d":"nWGxne_9WmC6hEr0kuwsxERJxWl7MmkZcDusAxyuf2A",
+		"x":"11qYAYKxCrfVS_7TyWQHOg7hcvPapiMlrwIaaPcHURo"}`), ifcrypto.KeyUsageSign)
+	require.NoError(t, err)
+
+	assert.IsType(t, &ED25519PrivateKey{}, key)
+	assert.Equal(t, []ifcrypto.KeyUsage{ifcrypto.KeyUsageSign}, key.GetKeyUsage())
+
+	_, err = NewKeyFromJWK([]byte(`{"kty":"oct","k":"AAAA","key_ops":["fly"]}`))
+	assert.Error(t, err)
+
+}
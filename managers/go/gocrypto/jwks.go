@@ -0,0 +1,294 @@
+package gocrypto
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/mariotoffia/goservice/interfaces/ifcrypto"
+	"github.com/mariotoffia/goservice/utils/cryptoutils"
+)
+
+// ErrJWKNotFound is returned when a _kid_ is not in a _JWKS_.
+var ErrJWKNotFound = fmt.Errorf("JWK not found")
+
+// JWKSet is a _RFC 7517_ _JWKS_ document.
+type JWKSet struct {
+	Keys []cryptoutils.JSONWebKey `json:"keys"`
+}
+
+// NewJWKSet creates a `JWKSet` of the public portion of _keys_.
+//
+// Symmetric keys are never published and hence skipped. A `ifcrypto.KeyPair` is published
+// using `GetPublic`.
+func NewJWKSet(keys ...ifcrypto.Key) (*JWKSet, error) {
+
+	set := &JWKSet{Keys: []cryptoutils.JSONWebKey{}}
+
+	for _, key := range keys {
+
+		if key.IsSymmetric() {
+			continue
+		}
+
+		if pair, ok := key.(ifcrypto.KeyPair); ok && key.IsPrivate() {
+			key = pair.GetPublic()
+		}
+
+		marshaler, ok := key.(JWKMarshaler)
+		if !ok {
+			return nil, fmt.Errorf("key %s can not be marshaled as JWK", key.GetID())
+		}
+
+		jwk, err := marshaler.ToJWK()
+		if err != nil {
+			return nil, err
+		}
+
+		set.Keys = append(set.Keys, *jwk.Public())
+
+	}
+
+	return set, nil
+
+}
+
+// Key returns the _JWK_ with _kid_ or `nil` if not found.
+func (s *JWKSet) Key(kid string) *cryptoutils.JSONWebKey {
+
+	for i := range s.Keys {
+
+		if s.Keys[i].KeyID == kid {
+			return &s.Keys[i]
+		}
+
+	}
+
+	return nil
+
+}
+
+// PublicKey returns the public key with _kid_, see `NewKeyFromJSONWebKey`.
+func (s *JWKSet) PublicKey(kid string) (ifcrypto.PublicKey, error) {
+
+	jwk := s.Key(kid)
+	if jwk == nil {
+		return nil, fmt.Errorf("%w: %s", ErrJWKNotFound, kid)
+	}
+
+	key, err := NewKeyFromJSONWebKey(jwk.Public())
+	if err != nil {
+		return nil, err
+	}
+
+	public, ok := key.(ifcrypto.PublicKey)
+	if !ok {
+		return nil, fmt.Errorf("JWK %s is not a public key", kid)
+	}
+
+	return public, nil
+
+}
+
+// JWKSetSource returns the keys to publish, e.g. all active and not yet retired keys of a
+// keystore.
+type JWKSetSource func(c context.Context) ([]ifcrypto.Key, error)
+
+// NewJWKSetHandler creates a `http.Handler` that serves the `JWKSet` of the keys from _source_,
+// e.g. on _/.well-known/jwks.json_, and allows clients to cache it for _maxAge_.
+func NewJWKSetHandler(source JWKSetSource, maxAge time.Duration) http.Handler {
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+
+		keys, err := source(r.Context())
+		if err != nil {
+			http.Error(w, "failed to read keys", http.StatusInternalServerError)
+			return
+		}
+
+		set, err := NewJWKSet(keys...)
+		if err != nil {
+			http.Error(w, "failed to create JWKS", http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/jwk-set+json")
+		w.Header().Set("Cache-Control", fmt.Sprintf("public, max-age=%d", int(maxAge/time.Second)))
+
+		_ = json.NewEncoder(w).Encode(set)
+
+	})
+
+}
+
+// RemoteJWKSOption configures a `RemoteJWKS`.
+type RemoteJWKSOption func(r *RemoteJWKS)
+
+// WithJWKSHTTPClient sets the _HTTP_ client, default `http.DefaultClient`.
+func WithJWKSHTTPClient(client *http.Client) RemoteJWKSOption {
+	return func(r *RemoteJWKS) {
+		r.client = client
+	}
+}
+
+// WithJWKSRefresh sets how long the fetched _JWKS_ is cached, default one hour, and the
+// minimum time between refreshes caused by a unknown _kid_, default 30 seconds.
+func WithJWKSRefresh(ttl, minInterval time.Duration) RemoteJWKSOption {
+	return func(r *RemoteJWKS) {
+		r.ttl = ttl
+		r.minInterval = minInterval
+	}
+}
+
+// WithJWKSClock sets the clock, default `time.Now`.
+func WithJWKSClock(clock func() time.Time) RemoteJWKSOption {
+	return func(r *RemoteJWKS) {
+		r.clock = clock
+	}
+}
+
+// RemoteJWKS fetches and caches a remote _JWKS_.
+//
+// A lookup of a unknown _kid_ refreshes the cache, at most once per minimum interval, hence a
+// key rotated in at the remote end is picked up without waiting for the cache to expire. If a
+// refresh fails the cached keys are used until a refresh succeeds.
+type RemoteJWKS struct {
+	url         string
+	client      *http.Client
+	ttl         time.Duration
+	minInterval time.Duration
+	clock       func() time.Time
+	mu          sync.Mutex
+	set         *JWKSet
+	keys        map[string]ifcrypto.PublicKey
+	fetched     time.Time
+}
+
+// NewRemoteJWKS creates a `RemoteJWKS` that fetches the _JWKS_ at _url_.
+func NewRemoteJWKS(url string, opts ...RemoteJWKSOption) *RemoteJWKS {
+
+	r := &RemoteJWKS{
+		url:         url,
+		client:      http.DefaultClient,
+		ttl:         time.Hour,
+		minInterval: 30 * time.Second,
+		clock:       time.Now,
+		keys:        map[string]ifcrypto.PublicKey{},
+	}
+
+	for _, opt := range opts {
+		opt(r)
+	}
+
+	return r
+
+}
+
+// Lookup returns the public key with _kid_. It returns `ErrJWKNotFound` if not found, even
+// after a refresh.
+func (r *RemoteJWKS) Lookup(c context.Context, kid string) (ifcrypto.PublicKey, error) {
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := r.clock()
+
+	if r.set == nil || now.Sub(r.fetched) >= r.ttl {
+
+		if err := r.refresh(c, now); err != nil && r.set == nil {
+			return nil, err
+		}
+
+	}
+
+	if key, ok := r.keys[kid]; ok {
+		return key, nil
+	}
+
+	if now.Sub(r.fetched) >= r.minInterval {
+
+		if err := r.refresh(c, now); err != nil {
+			return nil, err
+		}
+
+		if key, ok := r.keys[kid]; ok {
+			return key, nil
+		}
+
+	}
+
+	return nil, fmt.Errorf("%w: %s", ErrJWKNotFound, kid)
+
+}
+
+// Keys returns the cached `JWKSet`, fetching it if not yet fetched or expired.
+func (r *RemoteJWKS) Keys(c context.Context) (*JWKSet, error) {
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := r.clock()
+
+	if r.set == nil || now.Sub(r.fetched) >= r.ttl {
+
+		if err := r.refresh(c, now); err != nil && r.set == nil {
+			return nil, err
+		}
+
+	}
+
+	return r.set, nil
+
+}
+
+// refresh fetches the _JWKS_. Keys that can not be parsed are skipped.
+func (r *RemoteJWKS) refresh(c context.Context, now time.Time) error {
+
+	req, err := http.NewRequestWithContext(c, http.MethodGet, r.url, nil)
+	if err != nil {
+		return err
+	}
+
+	req.Header.Set("Accept", "application/jwk-set+json, application/json")
+
+	resp, err := r.client.Do(req)
+	if err != nil {
+		return err
+	}
+
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("JWKS %s returned status %d", r.url, resp.StatusCode)
+	}
+
+	var set JWKSet
+
+	if err := json.NewDecoder(resp.Body).Decode(&set); err != nil {
+		return err
+	}
+
+	keys := map[string]ifcrypto.PublicKey{}
+
+	for _, jwk := range set.Keys {
+
+		if jwk.KeyID == "" {
+			continue
+		}
+
+		if key, err := set.PublicKey(jwk.KeyID); err == nil {
+			keys[jwk.KeyID] = key
+		}
+
+	}
+
+	r.set = &set
+	r.keys = keys
+	r.fetched = now
+
+	return nil
+
+}
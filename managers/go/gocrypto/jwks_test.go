@@ -0,0 +1,109 @@
+//go:build !verifyonly
+// +build !verifyonly
+
+package gocrypto
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/mariotoffia/goservice/interfaces/ifcrypto"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestJWKSetPublishesOnlyPublicKeys(t *testing.T) {
+
+	edKey, err := NewED25519PrivateKey("ed", ifcrypto.KeyUsageSign)
+	require.NoError(t, err)
+
+	ecKey, err := NewECDSAPrivateKey("ec", 256, ifcrypto.KeyUsageSign)
+	require.NoError(t, err)
+
+	aesKey, err := NewSymmetricKey("aes", 256, ifcrypto.KeyUsageEncrypt)
+	require.NoError(t, err)
+
+	set, err := NewJWKSet(edKey, ecKey.GetPublic(), aesKey)
+	require.NoError(t, err)
+	require.Len(t, set.Keys, 2)
+
+	for _, jwk := range set.Keys {
+		assert.False(t, jwk.IsPrivate())
+	}
+
+	assert.Nil(t, set.Key("aes"))
+
+	key, err := set.PublicKey("ed")
+	require.NoError(t, err)
+	assert.False(t, key.IsPrivate())
+
+	_, err = set.PublicKey("missing")
+	assert.True(t, errors.Is(err, ErrJWKNotFound))
+
+}
+
+func TestRemoteJWKSRefreshesOnUnknownKid(t *testing.T) {
+
+	first, err := NewED25519PrivateKey("first", ifcrypto.KeyUsageSign)
+	require.NoError(t, err)
+
+	second, err := NewED25519PrivateKey("second", ifcrypto.KeyUsageSign)
+	require.NoError(t, err)
+
+	keys := []ifcrypto.Key{first}
+	var fetches int32
+
+	handler := NewJWKSetHandler(func(c context.Context) ([]ifcrypto.Key, error) {
+		atomic.AddInt32(&fetches, 1)
+		return keys, nil
+	}, time.Minute)
+
+	server := httptest.NewServer(handler)
+	defer server.Close()
+
+	resp, err := http.Get(server.URL)
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, "application/jwk-set+json", resp.Header.Get("Content-Type"))
+	assert.Equal(t, "public, max-age=60", resp.Header.Get("Cache-Control"))
+
+	now := time.Now()
+	remote := NewRemoteJWKS(
+		server.URL,
+		WithJWKSRefresh(time.Hour, 30*time.Second),
+		WithJWKSClock(func() time.Time { return now }),
+	)
+
+	key, err := remote.Lookup(context.Background(), "first")
+	require.NoError(t, err)
+	assert.Equal(t, "first", key.GetID())
+	assert.Equal(t, int32(2), atomic.LoadInt32(&fetches))
+
+	// Rotated in remotely, not visible until the rate limit allows a refresh.
+	keys = []ifcrypto.Key{first, second}
+
+	_, err = remote.Lookup(context.Background(), "second")
+	assert.True(t, errors.Is(err, ErrJWKNotFound))
+	assert.Equal(t, int32(2), atomic.LoadInt32(&fetches))
+
+	now = now.Add(time.Minute)
+
+	key, err = remote.Lookup(context.Background(), "second")
+	require.NoError(t, err)
+	assert.Equal(t, "second", key.GetID())
+	assert.Equal(t, int32(3), atomic.LoadInt32(&fetches))
+
+	set, err := remote.Keys(context.Background())
+	require.NoError(t, err)
+
+	data, err := json.Marshal(set)
+	require.NoError(t, err)
+	assert.Contains(t, string(data), `"kid":"second"`)
+
+}
@@ -18,6 +18,19 @@ type KeyBase struct {
 	keySize int
 }
 
+// NewKeyBase creates a new `KeyBase`, e.g. for keys implemented outside of this package such
+// as remote keys.
+func NewKeyBase(
+	id string,
+	keyType ifcrypto.KeyType,
+	keySize int,
+	usage ...ifcrypto.KeyUsage,
+) KeyBase {
+
+	return KeyBase{id: id, keyType: keyType, keySize: keySize, usage: usage}
+
+}
+
 // GetID returns a id of the key.
 //
 // This is always specific of the backing _KMS_ system. For example, in _AWS_ this is a _ARN_ to
@@ -44,7 +57,7 @@ func (b *KeyBase) GetSupportedChiphers() []ifcrypto.Chipher {
 // CanSign checks if the current _Key_ may participate in _alg_ `SignAlgorithm` to do sign operations with.
 func (b *KeyBase) CanSign(alg ifcrypto.SignAlgorithm) bool {
 
-	if !b.HasUsage(ifcrypto.KeyUsageVerify) {
+	if !b.HasUsage(ifcrypto.KeyUsageSign) {
 		return false
 	}
 
@@ -102,6 +115,16 @@ func (b *KeyBase) matchSignAlgForKey(alg ifcrypto.SignAlgorithm) bool {
 
 		return b.keyType == ifcrypto.KeyTypeEccNistP ||
 			b.keyType == ifcrypto.KeyTypeEccSecgP256k1
+
+	case ifcrypto.SignAlgorithmEd25519:
+
+		return b.keyType == ifcrypto.KeyTypeEd25519
+
+	case ifcrypto.SignAlgorithmHmacSha256,
+		ifcrypto.SignAlgorithmHmacSha384,
+		ifcrypto.SignAlgorithmHmacSha512:
+
+		return b.keyType == ifcrypto.KeyTypeHmac
 	}
 
 	panic(
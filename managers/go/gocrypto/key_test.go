@@ -0,0 +1,40 @@
+//go:build !verifyonly
+// +build !verifyonly
+
+package gocrypto
+
+import (
+	"testing"
+
+	"github.com/mariotoffia/goservice/interfaces/ifcrypto"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestKeyBaseCanSignRequiresSignUsage(t *testing.T) {
+
+	rsaKey, err := NewRSAPrivateKey("rsa", 2048, ifcrypto.KeyUsageSign)
+	require.NoError(t, err)
+
+	ecKey, err := NewECDSAPrivateKey("ec", 256, ifcrypto.KeyUsageVerify)
+	require.NoError(t, err)
+
+	edKey, err := NewED25519PrivateKey("ed", ifcrypto.KeyUsageSign, ifcrypto.KeyUsageVerify)
+	require.NoError(t, err)
+
+	// Signing requires KeyUsageSign, not KeyUsageVerify
+	assert.True(t, rsaKey.CanSign(ifcrypto.SignAlgorithmRsaPssSha256))
+	assert.False(t, rsaKey.CanVerify(ifcrypto.SignAlgorithmRsaPssSha256))
+
+	assert.False(t, ecKey.CanSign(ifcrypto.SignAlgorithmEcdSha256))
+	assert.True(t, ecKey.CanVerify(ifcrypto.SignAlgorithmEcdSha256))
+
+	assert.True(t, edKey.CanSign(ifcrypto.SignAlgorithmEd25519))
+	assert.True(t, edKey.CanVerify(ifcrypto.SignAlgorithmEd25519))
+
+	// The algorithm must match the key type
+	assert.False(t, rsaKey.CanSign(ifcrypto.SignAlgorithmEcdSha256))
+	assert.False(t, ecKey.CanVerify(ifcrypto.SignAlgorithmRsaPkcs1V15Sha256))
+	assert.False(t, edKey.CanSign(ifcrypto.SignAlgorithmHmacSha256))
+
+}
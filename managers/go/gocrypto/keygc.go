@@ -0,0 +1,231 @@
+package gocrypto
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/mariotoffia/goservice/interfaces/ifcrypto"
+)
+
+// KeyCollectionAction is the outcome for a key version in a `KeyCollectionEvent`.
+type KeyCollectionAction string
+
+const (
+	// KeyCollectionDestroyed is when the version was destroyed.
+	KeyCollectionDestroyed KeyCollectionAction = "destroyed"
+	// KeyCollectionWouldDestroy is when the version would have been destroyed, but dry run is enabled.
+	KeyCollectionWouldDestroy KeyCollectionAction = "would-destroy"
+	// KeyCollectionReferenced is when the version is past retention, but still referenced.
+	KeyCollectionReferenced KeyCollectionAction = "referenced"
+	// KeyCollectionFailed is when a reference check, or the destruction, failed.
+	KeyCollectionFailed KeyCollectionAction = "failed"
+)
+
+// KeyReferenceChecker checks if anything still references a key _version_, for example
+// ciphertext that has not been re-wrapped or tokens that have not expired.
+type KeyReferenceChecker func(c context.Context, version ifcrypto.KeyVersion) (referenced bool, err error)
+
+// KeyRetentionPolicy decides when a retired key version may be destroyed.
+type KeyRetentionPolicy struct {
+	// Retention is the minimum time after retirement before a version may be destroyed.
+	Retention time.Duration
+	// MinVersions is the number of most recent versions of each key that are always kept.
+	MinVersions int
+}
+
+// KeyCollectionEvent is a audit event emitted by the `KeyCollector`.
+type KeyCollectionEvent struct {
+	Time    time.Time           `json:"time"`
+	Version ifcrypto.KeyVersion `json:"version"`
+	Action  KeyCollectionAction `json:"action"`
+	// Reason is the reference checker that reported a reference, or the error.
+	Reason string `json:"reason,omitempty"`
+}
+
+// KeyCollectionReport is the result of `KeyCollector.Collect`.
+type KeyCollectionReport struct {
+	DryRun bool                 `json:"dry_run"`
+	Events []KeyCollectionEvent `json:"events"`
+	// Retained is the number of versions not yet eligible for destruction.
+	Retained int `json:"retained"`
+}
+
+// KeyCollectorOption configures a `KeyCollector`.
+type KeyCollectorOption func(k *KeyCollector)
+
+// WithReferenceChecker adds a named reference checker. All checkers must report the version
+// as unreferenced before it is destroyed.
+func WithReferenceChecker(name string, checker KeyReferenceChecker) KeyCollectorOption {
+	return func(k *KeyCollector) {
+		k.checkers = append(k.checkers, namedChecker{name: name, check: checker})
+	}
+}
+
+// WithDryRun reports what would be destroyed without destroying anything.
+func WithDryRun() KeyCollectorOption {
+	return func(k *KeyCollector) {
+		k.dryRun = true
+	}
+}
+
+// WithCollectorAuditor sets a function that receives all audit events.
+func WithCollectorAuditor(auditor func(event KeyCollectionEvent)) KeyCollectorOption {
+	return func(k *KeyCollector) {
+		k.auditor = auditor
+	}
+}
+
+// WithCollectorClock sets the clock used to evaluate the retention.
+func WithCollectorClock(clock func() time.Time) KeyCollectorOption {
+	return func(k *KeyCollector) {
+		k.clock = clock
+	}
+}
+
+// namedChecker is a `KeyReferenceChecker` with a name used in audit events.
+type namedChecker struct {
+	name  string
+	check KeyReferenceChecker
+}
+
+// KeyCollector destroys key versions that are past their retention and no longer referenced.
+type KeyCollector struct {
+	store    ifcrypto.KeyVersionStore
+	policy   KeyRetentionPolicy
+	checkers []namedChecker
+	dryRun   bool
+	auditor  func(event KeyCollectionEvent)
+	clock    func() time.Time
+}
+
+// NewKeyCollector creates a new `KeyCollector` of the versions in _store_ using _policy_.
+func NewKeyCollector(
+	store ifcrypto.KeyVersionStore,
+	policy KeyRetentionPolicy,
+	opts ...KeyCollectorOption,
+) *KeyCollector {
+
+	k := &KeyCollector{
+		store:   store,
+		policy:  policy,
+		auditor: func(KeyCollectionEvent) {},
+		clock:   time.Now,
+	}
+
+	for _, opt := range opts {
+		opt(k)
+	}
+
+	return k
+
+}
+
+// Collect evaluates all key versions and destroys those that are eligible.
+//
+// A version is eligible when it has been retired for at least the retention, is not one
+// of the _MinVersions_ most recent versions of its key and no reference checker reports it
+// as referenced. A failing check, or destruction, is reported as a event and the
+// collection continues with the next version.
+func (k *KeyCollector) Collect(c context.Context) (*KeyCollectionReport, error) {
+
+	versions, err := k.store.ListVersions(c)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &KeyCollectionReport{DryRun: k.dryRun, Events: []KeyCollectionEvent{}}
+
+	for _, version := range k.candidates(versions, report) {
+
+		if err := c.Err(); err != nil {
+			return report, err
+		}
+
+		action, reason := k.collect(c, version)
+
+		event := KeyCollectionEvent{Time: k.clock().UTC(), Version: version, Action: action, Reason: reason}
+
+		report.Events = append(report.Events, event)
+		k.auditor(event)
+
+	}
+
+	return report, nil
+
+}
+
+// candidates returns the versions that are past retention and not protected by _MinVersions_.
+func (k *KeyCollector) candidates(versions []ifcrypto.KeyVersion, report *KeyCollectionReport) []ifcrypto.KeyVersion {
+
+	byKey := map[string][]ifcrypto.KeyVersion{}
+	keyIDs := []string{}
+
+	for _, v := range versions {
+
+		if _, ok := byKey[v.KeyID]; !ok {
+			keyIDs = append(keyIDs, v.KeyID)
+		}
+
+		byKey[v.KeyID] = append(byKey[v.KeyID], v)
+
+	}
+
+	sort.Strings(keyIDs)
+
+	now := k.clock()
+	candidates := []ifcrypto.KeyVersion{}
+
+	for _, keyID := range keyIDs {
+
+		list := byKey[keyID]
+
+		// Most recent first
+		sort.SliceStable(list, func(i, j int) bool { return list[i].Created.After(list[j].Created) })
+
+		for i, v := range list {
+
+			if i < k.policy.MinVersions || v.Retired.IsZero() || now.Sub(v.Retired) < k.policy.Retention {
+				report.Retained++
+				continue
+			}
+
+			candidates = append(candidates, v)
+
+		}
+
+	}
+
+	return candidates
+
+}
+
+// collect checks the references of _version_ and destroys it if unreferenced.
+func (k *KeyCollector) collect(c context.Context, version ifcrypto.KeyVersion) (KeyCollectionAction, string) {
+
+	for _, checker := range k.checkers {
+
+		referenced, err := checker.check(c, version)
+
+		if err != nil {
+			return KeyCollectionFailed, fmt.Sprintf("%s: %v", checker.name, err)
+		}
+
+		if referenced {
+			return KeyCollectionReferenced, checker.name
+		}
+
+	}
+
+	if k.dryRun {
+		return KeyCollectionWouldDestroy, ""
+	}
+
+	if err := k.store.DestroyVersion(c, version); err != nil {
+		return KeyCollectionFailed, err.Error()
+	}
+
+	return KeyCollectionDestroyed, ""
+
+}
@@ -0,0 +1,75 @@
+package gocrypto
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/mariotoffia/goservice/interfaces/ifcrypto"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// versionStore is a in memory `ifcrypto.KeyVersionStore`.
+type versionStore struct {
+	versions  []ifcrypto.KeyVersion
+	destroyed []string
+}
+
+func (s *versionStore) ListVersions(c context.Context) ([]ifcrypto.KeyVersion, error) {
+	return s.versions, nil
+}
+
+func (s *versionStore) DestroyVersion(c context.Context, v ifcrypto.KeyVersion) error {
+
+	s.destroyed = append(s.destroyed, v.KeyID+"/"+v.Version)
+	return nil
+
+}
+
+func TestKeyCollectorDestroysUnreferencedExpiredVersions(t *testing.T) {
+
+	now := time.Unix(1700000000, 0)
+	day := 24 * time.Hour
+
+	store := &versionStore{versions: []ifcrypto.KeyVersion{
+		{KeyID: "signing", Version: "1", Created: now.Add(-300 * day), Retired: now.Add(-200 * day)},
+		{KeyID: "signing", Version: "2", Created: now.Add(-200 * day), Retired: now.Add(-100 * day)},
+		{KeyID: "signing", Version: "3", Created: now.Add(-100 * day), Retired: now.Add(-10 * day)},
+		{KeyID: "signing", Version: "4", Created: now.Add(-10 * day)},
+		{KeyID: "kek", Version: "1", Created: now.Add(-300 * day), Retired: now.Add(-200 * day)},
+		{KeyID: "kek", Version: "2", Created: now.Add(-200 * day)},
+	}}
+
+	events := []KeyCollectionEvent{}
+
+	opts := []KeyCollectorOption{
+		WithCollectorClock(func() time.Time { return now }),
+		WithCollectorAuditor(func(e KeyCollectionEvent) { events = append(events, e) }),
+		WithReferenceChecker("wrapped-deks", func(c context.Context, v ifcrypto.KeyVersion) (bool, error) {
+			return v.KeyID == "kek", nil
+		}),
+	}
+
+	policy := KeyRetentionPolicy{Retention: 30 * day, MinVersions: 1}
+
+	report, err := NewKeyCollector(store, policy, append(opts, WithDryRun())...).Collect(context.Background())
+	require.NoError(t, err)
+
+	assert.True(t, report.DryRun)
+	assert.Empty(t, store.destroyed)
+	assert.Equal(t, 3, report.Retained)
+	require.Len(t, report.Events, 3)
+	assert.Equal(t, KeyCollectionReferenced, report.Events[0].Action)
+	assert.Equal(t, "wrapped-deks", report.Events[0].Reason)
+	assert.Equal(t, KeyCollectionWouldDestroy, report.Events[1].Action)
+
+	events = events[:0]
+
+	_, err = NewKeyCollector(store, policy, opts...).Collect(context.Background())
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{"signing/2", "signing/1"}, store.destroyed)
+	assert.Len(t, events, 3)
+
+}
@@ -0,0 +1,106 @@
+package gocrypto
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/mariotoffia/goservice/interfaces/ifcrypto"
+)
+
+// MemoryKeyStore is a threadsafe in memory `ifcrypto.KeyStore`, i.e. a keyring where a service
+// registers all its keys at startup and resolves them by id when signing.
+type MemoryKeyStore struct {
+	mu   sync.RWMutex
+	keys map[string]ifcrypto.Key
+}
+
+// NewMemoryKeyStore creates a new `MemoryKeyStore` holding the _keys_. It fails with
+// `ifcrypto.ErrKeyExists` if two keys have the same id.
+func NewMemoryKeyStore(keys ...ifcrypto.Key) (*MemoryKeyStore, error) {
+
+	m := &MemoryKeyStore{keys: map[string]ifcrypto.Key{}}
+
+	for _, key := range keys {
+
+		if err := m.Put(context.Background(), key); err != nil {
+			return nil, err
+		}
+
+	}
+
+	return m, nil
+
+}
+
+// Get implements the `ifcrypto.KeyStore` interface.
+func (m *MemoryKeyStore) Get(c context.Context, id string) (ifcrypto.Key, error) {
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	if key, ok := m.keys[id]; ok {
+		return key, nil
+	}
+
+	return nil, fmt.Errorf("%w: %s", ifcrypto.ErrKeyNotFound, id)
+
+}
+
+// Put implements the `ifcrypto.KeyStore` interface.
+func (m *MemoryKeyStore) Put(c context.Context, key ifcrypto.Key) error {
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.keys[key.GetID()]; ok {
+		return fmt.Errorf("%w: %s", ifcrypto.ErrKeyExists, key.GetID())
+	}
+
+	m.keys[key.GetID()] = key
+
+	return nil
+
+}
+
+// Delete implements the `ifcrypto.KeyStore` interface.
+func (m *MemoryKeyStore) Delete(c context.Context, ids ...string) error {
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, id := range ids {
+		delete(m.keys, id)
+	}
+
+	return nil
+
+}
+
+// List implements the `ifcrypto.KeyStore` interface.
+func (m *MemoryKeyStore) List(c context.Context, filter ifcrypto.KeyFilter) ([]ifcrypto.Key, error) {
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	keys := make([]ifcrypto.Key, 0, len(m.keys))
+
+	for _, key := range m.keys {
+
+		if filter.Match(key) {
+			keys = append(keys, key)
+		}
+
+	}
+
+	sort.Slice(keys, func(i, j int) bool { return keys[i].GetID() < keys[j].GetID() })
+
+	return keys, nil
+
+}
+
+// KeyStoreLookup returns a `KeyLookup` that resolves keys from _store_, e.g. for `NewScopedKeys`.
+func KeyStoreLookup(store ifcrypto.KeyStore) KeyLookup {
+	return store.Get
+}
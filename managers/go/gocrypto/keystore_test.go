@@ -0,0 +1,110 @@
+//go:build !verifyonly
+// +build !verifyonly
+
+package gocrypto
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/mariotoffia/goservice/interfaces/ifcrypto"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMemoryKeyStore(t *testing.T) {
+
+	signer, err := NewED25519PrivateKey("signer", ifcrypto.KeyUsageSign)
+	require.NoError(t, err)
+
+	agreement, err := NewX25519PrivateKey("agreement", ifcrypto.KeyUsageKeyAgreement)
+	require.NoError(t, err)
+
+	data, err := NewSymmetricKey("data", 256, ifcrypto.KeyUsageEncrypt, ifcrypto.KeyUsageDecrypt)
+	require.NoError(t, err)
+
+	var store ifcrypto.KeyStore
+
+	store, err = NewMemoryKeyStore(signer, agreement, data)
+	require.NoError(t, err)
+
+	c := context.Background()
+
+	key, err := store.Get(c, "signer")
+	require.NoError(t, err)
+	assert.Equal(t, signer, key)
+
+	_, err = store.Get(c, "missing")
+	assert.True(t, errors.Is(err, ifcrypto.ErrKeyNotFound))
+
+	assert.True(t, errors.Is(store.Put(c, signer), ifcrypto.ErrKeyExists))
+
+	keys, err := store.List(c, ifcrypto.KeyFilter{})
+	require.NoError(t, err)
+	require.Len(t, keys, 3)
+	assert.Equal(t, "agreement", keys[0].GetID())
+	assert.Equal(t, "data", keys[1].GetID())
+
+	keys, err = store.List(c, ifcrypto.KeyFilter{Usages: []ifcrypto.KeyUsage{ifcrypto.KeyUsageSign, ifcrypto.KeyUsageDecrypt}})
+	require.NoError(t, err)
+	require.Len(t, keys, 2)
+	assert.Equal(t, "data", keys[0].GetID())
+	assert.Equal(t, "signer", keys[1].GetID())
+
+	keys, err = store.List(c, ifcrypto.KeyFilter{
+		KeyTypes: []ifcrypto.KeyType{ifcrypto.KeyTypeX25519},
+		Usages:   []ifcrypto.KeyUsage{ifcrypto.KeyUsageSign},
+	})
+	require.NoError(t, err)
+	assert.Empty(t, keys)
+
+	require.NoError(t, store.Delete(c, "signer", "missing"))
+
+	_, err = KeyStoreLookup(store)(c, "signer")
+	assert.True(t, errors.Is(err, ifcrypto.ErrKeyNotFound))
+
+	_, err = NewMemoryKeyStore(signer, signer)
+	assert.True(t, errors.Is(err, ifcrypto.ErrKeyExists))
+
+}
+
+func TestMemoryKeyStoreConcurrent(t *testing.T) {
+
+	store, err := NewMemoryKeyStore()
+	require.NoError(t, err)
+
+	c := context.Background()
+
+	var wg sync.WaitGroup
+
+	for i := 0; i < 16; i++ {
+
+		wg.Add(1)
+
+		go func(i int) {
+
+			defer wg.Done()
+
+			key, err := NewSymmetricKey(fmt.Sprintf("key-%d", i), 128, ifcrypto.KeyUsageEncrypt)
+			if err != nil {
+				return
+			}
+
+			_ = store.Put(c, key)
+			_, _ = store.Get(c, key.GetID())
+			_, _ = store.List(c, ifcrypto.KeyFilter{})
+
+		}(i)
+
+	}
+
+	wg.Wait()
+
+	keys, err := store.List(c, ifcrypto.KeyFilter{})
+	require.NoError(t, err)
+	assert.Len(t, keys, 16)
+
+}
@@ -0,0 +1,299 @@
+package gocrypto
+
+import (
+	"context"
+	"crypto"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+
+	"github.com/mariotoffia/goservice/interfaces/ifcrypto"
+)
+
+// ErrKeySyncConflict is set on a `KeySyncResult` when the target already holds a different key
+// under the same id.
+var ErrKeySyncConflict = fmt.Errorf("key sync conflict")
+
+// KeySyncAction is what a `KeySync` did, or would do in dry-run mode, with a key and a target.
+type KeySyncAction string
+
+const (
+	// KeySyncCreate mirrors the key onto the target.
+	KeySyncCreate KeySyncAction = "create"
+	// KeySyncUnchanged is a key that the target already holds.
+	KeySyncUnchanged KeySyncAction = "unchanged"
+	// KeySyncConflict is a key where the target holds a different key with the same id. The
+	// target key is never overwritten.
+	KeySyncConflict KeySyncAction = "conflict"
+	// KeySyncSkip is a key that can not be mirrored, e.g. a secret key, or that failed.
+	KeySyncSkip KeySyncAction = "skip"
+)
+
+// KeySyncResult is the outcome of a key and a target.
+type KeySyncResult struct {
+	KeyID  string
+	Target string
+	Action KeySyncAction
+	// Private is `true` when the private key is, or would be, mirrored wrapped to the target.
+	Private bool
+	// Err is set on conflicts, skipped keys and failures.
+	Err error
+}
+
+// KeySyncReport is the outcome of a `KeySync.Sync`.
+type KeySyncReport struct {
+	DryRun  bool
+	Results []KeySyncResult
+}
+
+// Conflicts returns the results with `KeySyncConflict`.
+func (r *KeySyncReport) Conflicts() []KeySyncResult {
+
+	conflicts := []KeySyncResult{}
+
+	for _, result := range r.Results {
+
+		if result.Action == KeySyncConflict {
+			conflicts = append(conflicts, result)
+		}
+
+	}
+
+	return conflicts
+
+}
+
+// WrappedKeyStore is a `ifcrypto.KeyStore` that accepts private keys wrapped, by a
+// `KeyExporter`, to its recipient key.
+type WrappedKeyStore interface {
+	ifcrypto.KeyStore
+	// RecipientID returns the id of the recipient key, see `WithExportRecipient`.
+	RecipientID() string
+	// PutWrapped unwraps the _exported_ private key and stores it with the _usage_.
+	PutWrapped(c context.Context, exported *ExportedKey, usage ...ifcrypto.KeyUsage) error
+}
+
+// RecipientKeyStore is a `WrappedKeyStore` that unwraps the private keys with a recipient key
+// and stores them in a underlying keystore.
+type RecipientKeyStore struct {
+	ifcrypto.KeyStore
+	recipient ifcrypto.KeyPair
+}
+
+// NewRecipientKeyStore creates a new `RecipientKeyStore` that unwraps with the _recipient_
+// _RSA_ key and stores the keys in _store_.
+func NewRecipientKeyStore(store ifcrypto.KeyStore, recipient ifcrypto.KeyPair) (*RecipientKeyStore, error) {
+
+	if _, ok := recipient.(crypto.Decrypter); !ok || recipient.GetKeyType() != ifcrypto.KeyTypeRsa {
+		return nil, fmt.Errorf("recipient %s must be a RSA private key", recipient.GetID())
+	}
+
+	return &RecipientKeyStore{KeyStore: store, recipient: recipient}, nil
+
+}
+
+// RecipientID implements the `WrappedKeyStore` interface.
+func (s *RecipientKeyStore) RecipientID() string {
+	return s.recipient.GetID()
+}
+
+// PutWrapped implements the `WrappedKeyStore` interface.
+func (s *RecipientKeyStore) PutWrapped(c context.Context, exported *ExportedKey, usage ...ifcrypto.KeyUsage) error {
+
+	key, err := UnwrapExportedKey(exported, s.recipient.(crypto.Decrypter))
+	if err != nil {
+		return err
+	}
+
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		return err
+	}
+
+	pair, err := newKeyPairFromPKCS8(pem.Block{Type: "PRIVATE KEY", Bytes: der}, exported.KeyID, usage...)
+	if err != nil {
+		return err
+	}
+
+	return s.Put(c, pair)
+
+}
+
+// KeySyncOption configures a `KeySync`.
+type KeySyncOption func(s *KeySync)
+
+// WithKeySyncTarget adds the _target_ keystore under _name_.
+func WithKeySyncTarget(name string, target ifcrypto.KeyStore) KeySyncOption {
+	return func(s *KeySync) {
+		s.targets = append(s.targets, keySyncTarget{name: name, store: target})
+	}
+}
+
+// WithKeySyncFilter selects the source keys to mirror, default all.
+func WithKeySyncFilter(filter ifcrypto.KeyFilter) KeySyncOption {
+	return func(s *KeySync) {
+		s.filter = filter
+	}
+}
+
+// WithKeySyncExporter mirrors the private keys, that have `ifcrypto.KeyUsageExport`, wrapped
+// by the _exporter_ to targets that implements `WrappedKeyStore`. The recipient of each
+// target must be approved by the _exporter_.
+//
+// Without a exporter, or for other targets and keys, only the public keys are mirrored.
+func WithKeySyncExporter(exporter *KeyExporter) KeySyncOption {
+	return func(s *KeySync) {
+		s.exporter = exporter
+	}
+}
+
+// WithKeySyncDryRun reports what would be mirrored without writing to the targets or
+// exporting any keys.
+func WithKeySyncDryRun() KeySyncOption {
+	return func(s *KeySync) {
+		s.dryRun = true
+	}
+}
+
+type keySyncTarget struct {
+	name  string
+	store ifcrypto.KeyStore
+}
+
+// KeySync mirrors keys from a source keystore to one or more targets, e.g. when promoting keys
+// between environments.
+//
+// Public keys are mirrored as is, and key pairs by their public key unless the private key is
+// mirrored wrapped, see `WithKeySyncExporter`. Secret keys, i.e. symmetric and _HMAC_ keys, are
+// never mirrored. A target key with the same id is compared by its `Fingerprint` and a
+// different key is reported as a conflict, it is never overwritten.
+type KeySync struct {
+	source   ifcrypto.KeyStore
+	targets  []keySyncTarget
+	filter   ifcrypto.KeyFilter
+	exporter *KeyExporter
+	dryRun   bool
+}
+
+// NewKeySync creates a new `KeySync` that mirrors the keys of _source_ to the targets, see
+// `WithKeySyncTarget`.
+func NewKeySync(source ifcrypto.KeyStore, opts ...KeySyncOption) (*KeySync, error) {
+
+	s := &KeySync{source: source}
+
+	for _, opt := range opts {
+		opt(s)
+	}
+
+	if len(s.targets) == 0 {
+		return nil, fmt.Errorf("key sync requires at least one target")
+	}
+
+	return s, nil
+
+}
+
+// Sync mirrors the selected source keys to all targets and reports the outcome of each key
+// and target. It only fails when the source keys can not be listed.
+func (s *KeySync) Sync(c context.Context) (*KeySyncReport, error) {
+
+	keys, err := s.source.List(c, s.filter)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &KeySyncReport{DryRun: s.dryRun}
+
+	for _, key := range keys {
+
+		for _, target := range s.targets {
+			report.Results = append(report.Results, s.sync(c, key, target))
+		}
+
+	}
+
+	return report, nil
+
+}
+
+// sync mirrors _key_ onto _target_.
+func (s *KeySync) sync(c context.Context, key ifcrypto.Key, target keySyncTarget) KeySyncResult {
+
+	result := KeySyncResult{KeyID: key.GetID(), Target: target.name, Action: KeySyncSkip}
+
+	if key.IsSymmetric() || key.GetKeyType() == ifcrypto.KeyTypeHmac {
+		result.Err = fmt.Errorf("secret key %s is never mirrored", key.GetID())
+		return result
+	}
+
+	fingerprint, err := Fingerprint(key)
+	if err != nil {
+		result.Err = err
+		return result
+	}
+
+	pair, isPair := key.(ifcrypto.KeyPair)
+	wrapped, isWrapped := target.store.(WrappedKeyStore)
+
+	result.Private = s.exporter != nil && isPair && isWrapped &&
+		!key.IsRemoteKey() && hasKeyUsage(key, ifcrypto.KeyUsageExport)
+
+	existing, err := target.store.Get(c, key.GetID())
+
+	switch {
+	case err == nil:
+
+		if other, err := Fingerprint(existing); err == nil && other == fingerprint {
+			result.Action = KeySyncUnchanged
+			return result
+		}
+
+		result.Action = KeySyncConflict
+		result.Err = fmt.Errorf("%w: %s in %s", ErrKeySyncConflict, key.GetID(), target.name)
+
+		return result
+
+	case !errors.Is(err, ifcrypto.ErrKeyNotFound):
+
+		result.Err = err
+		return result
+
+	}
+
+	if s.dryRun {
+		result.Action = KeySyncCreate
+		return result
+	}
+
+	switch {
+	case result.Private:
+
+		exported, err := s.exporter.Export(c, pair, wrapped.RecipientID())
+		if err == nil {
+			err = wrapped.PutWrapped(c, exported, key.GetKeyUsage()...)
+		}
+
+		result.Err = err
+
+	case isPair:
+
+		if pair.GetPublic() == nil {
+			result.Err = fmt.Errorf("key %s has no public key", key.GetID())
+		} else {
+			result.Err = target.store.Put(c, pair.GetPublic())
+		}
+
+	case key.IsPrivate():
+		result.Err = fmt.Errorf("private key %s has no public key", key.GetID())
+	default:
+		result.Err = target.store.Put(c, key)
+	}
+
+	if result.Err == nil {
+		result.Action = KeySyncCreate
+	}
+
+	return result
+
+}
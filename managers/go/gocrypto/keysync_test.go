@@ -0,0 +1,142 @@
+//go:build !verifyonly
+// +build !verifyonly
+
+package gocrypto
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/mariotoffia/goservice/interfaces/ifcrypto"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestKeySyncMirrorsPublicAndWrappedKeys(t *testing.T) {
+
+	c := ifcrypto.WithPolicyPrincipal(context.Background(), "release-manager")
+
+	signer, err := NewECDSAPrivateKey("signer", 256, ifcrypto.KeyUsageSign, ifcrypto.KeyUsageExport)
+	require.NoError(t, err)
+
+	identity, err := NewED25519PrivateKey("identity", ifcrypto.KeyUsageSign)
+	require.NoError(t, err)
+
+	session, err := NewSymmetricKey("session", 256, ifcrypto.KeyUsageEncrypt)
+	require.NoError(t, err)
+
+	source, err := NewMemoryKeyStore(signer, identity, session)
+	require.NoError(t, err)
+
+	// The staging target already has a different identity key
+	other, err := NewED25519PrivateKey("identity", ifcrypto.KeyUsageSign)
+	require.NoError(t, err)
+
+	staging, err := NewMemoryKeyStore(other.GetPublic())
+	require.NoError(t, err)
+
+	recipient, err := NewRSAPrivateKey("prod-hsm", 2048, ifcrypto.KeyUsageDecrypt)
+	require.NoError(t, err)
+
+	prodStore, err := NewMemoryKeyStore()
+	require.NoError(t, err)
+
+	prod, err := NewRecipientKeyStore(prodStore, recipient)
+	require.NoError(t, err)
+
+	exporter, err := NewKeyExporter(
+		NewPolicyGuard(PolicyEngineFunc(
+			func(c context.Context, input ifcrypto.PolicyInput) (ifcrypto.PolicyDecision, error) {
+				return ifcrypto.PolicyDecision{Allow: input.Principal == "release-manager"}, nil
+			},
+		)),
+		WithExportRecipient(recipient.GetPublic()),
+	)
+	require.NoError(t, err)
+
+	opts := []KeySyncOption{
+		WithKeySyncTarget("staging", staging),
+		WithKeySyncTarget("prod", prod),
+		WithKeySyncExporter(exporter),
+	}
+
+	actions := func(report *KeySyncReport) map[string]KeySyncAction {
+
+		actions := map[string]KeySyncAction{}
+		for _, result := range report.Results {
+			actions[result.KeyID+"@"+result.Target] = result.Action
+		}
+
+		return actions
+
+	}
+
+	dryRun, err := NewKeySync(source, append(opts, WithKeySyncDryRun())...)
+	require.NoError(t, err)
+
+	report, err := dryRun.Sync(c)
+	require.NoError(t, err)
+	assert.True(t, report.DryRun)
+
+	expected := map[string]KeySyncAction{
+		"identity@staging": KeySyncConflict,
+		"identity@prod":    KeySyncCreate,
+		"session@staging":  KeySyncSkip,
+		"session@prod":     KeySyncSkip,
+		"signer@staging":   KeySyncCreate,
+		"signer@prod":      KeySyncCreate,
+	}
+
+	assert.Equal(t, expected, actions(report))
+
+	keys, err := prodStore.List(c, ifcrypto.KeyFilter{})
+	require.NoError(t, err)
+	assert.Empty(t, keys, "dry-run do not write")
+
+	sync, err := NewKeySync(source, opts...)
+	require.NoError(t, err)
+
+	report, err = sync.Sync(c)
+	require.NoError(t, err)
+	assert.Equal(t, expected, actions(report))
+
+	conflicts := report.Conflicts()
+	require.Len(t, conflicts, 1)
+	assert.True(t, errors.Is(conflicts[0].Err, ErrKeySyncConflict))
+
+	// The exportable key is mirrored wrapped to prod and public to staging
+	mirrored, err := prodStore.Get(c, "signer")
+	require.NoError(t, err)
+	assert.True(t, mirrored.IsPrivate())
+
+	fingerprint, err := Fingerprint(signer)
+	require.NoError(t, err)
+
+	mirroredFingerprint, err := Fingerprint(mirrored)
+	require.NoError(t, err)
+	assert.Equal(t, fingerprint, mirroredFingerprint)
+
+	public, err := staging.Get(c, "signer")
+	require.NoError(t, err)
+	assert.False(t, public.IsPrivate())
+
+	// The non-exportable key is mirrored public only
+	identityKey, err := prodStore.Get(c, "identity")
+	require.NoError(t, err)
+	assert.False(t, identityKey.IsPrivate())
+
+	// A second sync finds everything in place
+	report, err = sync.Sync(c)
+	require.NoError(t, err)
+
+	expected["identity@prod"] = KeySyncUnchanged
+	expected["signer@staging"] = KeySyncUnchanged
+	expected["signer@prod"] = KeySyncUnchanged
+
+	assert.Equal(t, expected, actions(report))
+
+	_, err = NewKeySync(source)
+	assert.Error(t, err, "no targets")
+
+}
@@ -0,0 +1,206 @@
+package gocrypto
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/mariotoffia/goservice/interfaces/ifcrypto"
+)
+
+// BackgroundLoop is run by a `Background`. It must return when _stop_ is done and perform
+// its operations with _work_, that is only canceled when `Background.Stop` gives up draining.
+type BackgroundLoop func(stop, work context.Context)
+
+// Background implements `ifcrypto.Lifecycle` for a `BackgroundLoop`.
+//
+// Stopping is graceful, the loop is signalled to stop and the operation in-flight is allowed
+// to complete until the context of `Stop` is done, then it is canceled.
+type Background struct {
+	name   string
+	loop   BackgroundLoop
+	mu     sync.Mutex
+	cancel context.CancelFunc
+	abort  context.CancelFunc
+	done   chan struct{}
+}
+
+// NewBackground creates a new `Background` of _loop_, the _name_ is used in errors.
+func NewBackground(name string, loop BackgroundLoop) *Background {
+	return &Background{name: name, loop: loop, done: make(chan struct{})}
+}
+
+// NewTickerBackground creates a new `Background` that calls _fn_ every _interval_.
+func NewTickerBackground(name string, interval time.Duration, fn func(c context.Context)) *Background {
+
+	return NewBackground(name, func(stop, work context.Context) {
+
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+
+			select {
+			case <-stop.Done():
+				return
+			case <-ticker.C:
+				fn(work)
+			}
+
+		}
+
+	})
+
+}
+
+// Start implements the `ifcrypto.Lifecycle` interface. The loop runs until `Stop` is called
+// or _c_ is done. The values of _c_, e.g. a `ifcrypto.CryptoContext`, are visible to the loop.
+//
+// A `Background` can only be started once, otherwise `ifcrypto.ErrAlreadyStarted` is returned.
+func (b *Background) Start(c context.Context) error {
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.abort != nil {
+		return fmt.Errorf("%w: %s", ifcrypto.ErrAlreadyStarted, b.name)
+	}
+
+	work, abort := context.WithCancel(detachedContext{c})
+	stop, cancel := context.WithCancel(work)
+
+	b.cancel, b.abort = cancel, abort
+
+	// Stop when c is done, work continues to drain
+	go func() {
+
+		select {
+		case <-c.Done():
+			cancel()
+		case <-stop.Done():
+		}
+
+	}()
+
+	go func() {
+
+		defer close(b.done)
+		defer abort()
+
+		b.loop(stop, work)
+
+	}()
+
+	return nil
+
+}
+
+// Stop implements the `ifcrypto.Lifecycle` interface. Stopping a not started `Background` do
+// nothing.
+func (b *Background) Stop(c context.Context) error {
+
+	b.mu.Lock()
+	cancel, abort := b.cancel, b.abort
+	b.mu.Unlock()
+
+	if cancel == nil {
+		return nil
+	}
+
+	cancel()
+
+	select {
+	case <-b.done:
+		return nil
+	case <-c.Done():
+	}
+
+	abort()
+	<-b.done
+
+	return fmt.Errorf("%w: %s: %v", ifcrypto.ErrDrainTimeout, b.name, c.Err())
+
+}
+
+// Done is closed when a started loop has returned.
+func (b *Background) Done() <-chan struct{} {
+	return b.done
+}
+
+// detachedContext has the values, but not the cancellation, of the parent context.
+type detachedContext struct {
+	parent context.Context
+}
+
+func (d detachedContext) Deadline() (time.Time, bool) {
+	return time.Time{}, false
+}
+
+func (d detachedContext) Done() <-chan struct{} {
+	return nil
+}
+
+func (d detachedContext) Err() error {
+	return nil
+}
+
+func (d detachedContext) Value(key interface{}) interface{} {
+	return d.parent.Value(key)
+}
+
+// LifecycleGroup starts components in order and stops them in reverse order, e.g. the
+// background components of a service.
+//
+// .Example
+// [source,go]
+// ----
+// group := LifecycleGroup{entropy, snapshotter, directory, queue}
+//
+// err := group.Start(c)
+// // ...
+// <-shutdown
+//
+// c, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+// defer cancel()
+//
+// return group.Stop(c)
+// ----
+type LifecycleGroup []ifcrypto.Lifecycle
+
+// Start implements the `ifcrypto.Lifecycle` interface. If a component fails to start, the
+// already started components are stopped and the error is returned.
+func (g LifecycleGroup) Start(c context.Context) error {
+
+	for i, component := range g {
+
+		if err := component.Start(c); err != nil {
+
+			_ = g[:i].Stop(c)
+			return err
+
+		}
+
+	}
+
+	return nil
+
+}
+
+// Stop implements the `ifcrypto.Lifecycle` interface. All components are stopped, in reverse
+// order, with the same _c_ and the first error is returned.
+func (g LifecycleGroup) Stop(c context.Context) error {
+
+	var first error
+
+	for i := len(g) - 1; i >= 0; i-- {
+
+		if err := g[i].Stop(c); err != nil && first == nil {
+			first = err
+		}
+
+	}
+
+	return first
+
+}
@@ -0,0 +1,141 @@
+package gocrypto
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/mariotoffia/goservice/interfaces/ifcrypto"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type testLifecycleKey struct{}
+
+func TestBackgroundDrainsInFlightOperation(t *testing.T) {
+
+	started := make(chan struct{})
+	release := make(chan struct{})
+	completed := make(chan string, 1)
+
+	b := NewBackground("test", func(stop, work context.Context) {
+
+		close(started)
+		<-release
+
+		// The operation completes even though stop is done
+		completed <- work.Value(testLifecycleKey{}).(string)
+		<-stop.Done()
+
+	})
+
+	c, cancel := context.WithCancel(context.WithValue(context.Background(), testLifecycleKey{}, "value"))
+	defer cancel()
+
+	require.NoError(t, b.Start(c))
+	assert.True(t, errors.Is(b.Start(c), ifcrypto.ErrAlreadyStarted))
+
+	<-started
+
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		close(release)
+	}()
+
+	require.NoError(t, b.Stop(context.Background()))
+	assert.Equal(t, "value", <-completed)
+
+	<-b.Done()
+
+}
+
+func TestBackgroundCancelsWhenDrainTimesOut(t *testing.T) {
+
+	b := NewBackground("stuck", func(stop, work context.Context) {
+		<-work.Done()
+	})
+
+	require.NoError(t, b.Start(context.Background()))
+
+	c, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	err := b.Stop(c)
+	assert.True(t, errors.Is(err, ifcrypto.ErrDrainTimeout))
+	assert.Contains(t, err.Error(), "stuck")
+
+	<-b.Done()
+
+}
+
+func TestBackgroundStopsWhenStartContextIsDone(t *testing.T) {
+
+	ticks := make(chan struct{}, 1)
+
+	b := NewTickerBackground("ticker", time.Millisecond, func(c context.Context) {
+
+		select {
+		case ticks <- struct{}{}:
+		default:
+		}
+
+	})
+
+	c, cancel := context.WithCancel(context.Background())
+
+	require.NoError(t, b.Start(c))
+	<-ticks
+
+	cancel()
+	<-b.Done()
+
+	assert.NoError(t, b.Stop(context.Background()))
+
+}
+
+type recordingLifecycle struct {
+	name   string
+	events *[]string
+	err    error
+}
+
+func (r *recordingLifecycle) Start(c context.Context) error {
+
+	*r.events = append(*r.events, "start "+r.name)
+	return r.err
+
+}
+
+func (r *recordingLifecycle) Stop(c context.Context) error {
+
+	*r.events = append(*r.events, "stop "+r.name)
+	return nil
+
+}
+
+func TestLifecycleGroupOrder(t *testing.T) {
+
+	events := []string{}
+
+	group := LifecycleGroup{
+		&recordingLifecycle{name: "a", events: &events},
+		&recordingLifecycle{name: "b", events: &events},
+	}
+
+	require.NoError(t, group.Start(context.Background()))
+	require.NoError(t, group.Stop(context.Background()))
+
+	assert.Equal(t, []string{"start a", "start b", "stop b", "stop a"}, events)
+
+	events = events[:0]
+	failing := LifecycleGroup{
+		&recordingLifecycle{name: "a", events: &events},
+		&recordingLifecycle{name: "b", events: &events, err: errors.New("failed")},
+		&recordingLifecycle{name: "c", events: &events},
+	}
+
+	assert.Error(t, failing.Start(context.Background()))
+	assert.Equal(t, []string{"start a", "start b", "stop a"}, events)
+
+}
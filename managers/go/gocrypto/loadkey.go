@@ -0,0 +1,197 @@
+package gocrypto
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/pem"
+	"fmt"
+	"io"
+
+	"github.com/mariotoffia/goservice/interfaces/ifcrypto"
+	"github.com/mariotoffia/goservice/utils/cryptoutils"
+)
+
+// ErrNoKey is returned by `LoadKey` when the input do not contain any key.
+var ErrNoKey = fmt.Errorf("no key found")
+
+// sec1PrivateKey is the _SEC 1_ _ECPrivateKey_ structure, used to sniff the curve.
+type sec1PrivateKey struct {
+	Version       int
+	PrivateKey    []byte
+	NamedCurveOID asn1.ObjectIdentifier `asn1:"optional,explicit,tag:0"`
+	PublicKey     asn1.BitString        `asn1:"optional,explicit,tag:1"`
+}
+
+// LoadKey reads _r_ and returns the key as the matching concrete type, e.g. a `*RSAPrivateKey`,
+// a `*Secp256k1PrivateKey` or a `*ED25519PublicKey`.
+//
+// The input is one or more _PEM_ blocks or a single _DER_ encoded _PKCS #8_, _PKIX_,
+// _PKCS #1_ or _SEC 1_ key. The algorithm, and whether it is public or private, is sniffed from
+// the block type and the key structure. Of several keys the first private key is returned, or
+// else the first public key, hence the output of `PEMWrite` with the public portion loads as
+// the private key. Other blocks, such as certificates, are skipped.
+//
+// Symmetric keys are only detected in their _PEM_ armor. A _HMAC KEY_ do not carry its hash, it
+// is bound to the hash of the same size as the key, as generated by `NewHMACKey`, or else
+// _SHA-256_. Encrypted keys must be loaded using `NewKeyPairFromEncryptedPEM`.
+func LoadKey(r io.Reader, id string, usage ...ifcrypto.KeyUsage) (ifcrypto.Key, error) {
+
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+
+	if !bytes.Contains(data, []byte("-----BEGIN ")) {
+		return loadDERKey(data, id, usage...)
+	}
+
+	var public ifcrypto.Key
+
+	for {
+
+		var block *pem.Block
+
+		block, data = pem.Decode(data)
+		if block == nil {
+			break
+		}
+
+		key, err := keyFromPEMBlock(block, id, usage...)
+		if err != nil {
+			return nil, err
+		}
+
+		if key == nil {
+			continue
+		}
+
+		if _, ok := key.(ifcrypto.KeyPair); ok {
+			return key, nil
+		}
+
+		if public == nil {
+			public = key
+		}
+
+	}
+
+	if public == nil {
+		return nil, ErrNoKey
+	}
+
+	return public, nil
+
+}
+
+// keyFromPEMBlock creates the key of the _block_, or returns `nil` when the _block_ is not a key,
+// e.g. a certificate.
+func keyFromPEMBlock(block *pem.Block, id string, usage ...ifcrypto.KeyUsage) (ifcrypto.Key, error) {
+
+	if _, ok := block.Headers["Proc-Type"]; ok || block.Type == cryptoutils.EncryptedPrivateKeyPEMType {
+		return nil, fmt.Errorf("%s is encrypted, use NewKeyPairFromEncryptedPEM", block.Type)
+	}
+
+	switch block.Type {
+	case "PRIVATE KEY":
+		return newKeyPairFromPKCS8(*block, id, usage...)
+	case "RSA PRIVATE KEY":
+		return NewRSAPrivateKeyFromPEM(*block, id, usage...)
+	case "EC PRIVATE KEY":
+		return newKeyPairFromSEC1(*block, id, usage...)
+	case SymmetricKeyPEMType:
+		return NewSymmetricKeyFromPEM(*block, id, usage...)
+	case HMACKeyPEMType:
+		return NewHMACKeyFromPEM(*block, id, hmacHashForKey(block.Bytes), usage...)
+	case ChaChaKeyPEMType:
+		return NewChaChaKeyFromPEM(*block, id, usage...)
+	case "PUBLIC KEY", "RSA PUBLIC KEY", "EC PUBLIC KEY":
+		return loadPublicKey(block.Bytes, id, usage...)
+	}
+
+	return nil, nil
+
+}
+
+// hmacHashForKey returns the hash with a output of the same size as _key_, or else _SHA-256_.
+func hmacHashForKey(key []byte) crypto.Hash {
+
+	for hash := range hmacAlgorithms {
+
+		if hash.Size() == len(key) {
+			return hash
+		}
+
+	}
+
+	return crypto.SHA256
+
+}
+
+// loadDERKey sniffs the format of the _der_ key.
+func loadDERKey(der []byte, id string, usage ...ifcrypto.KeyUsage) (ifcrypto.Key, error) {
+
+	var pkcs8 struct {
+		Version    int
+		Algorithm  pkix.AlgorithmIdentifier
+		PrivateKey []byte
+	}
+
+	if rest, err := asn1.Unmarshal(der, &pkcs8); err == nil && len(rest) == 0 {
+		return newKeyPairFromPKCS8(pem.Block{Type: "PRIVATE KEY", Bytes: der}, id, usage...)
+	}
+
+	if _, err := x509.ParsePKCS1PrivateKey(der); err == nil {
+		return NewRSAPrivateKeyFromPEM(pem.Block{Type: "RSA PRIVATE KEY", Bytes: der}, id, usage...)
+	}
+
+	var sec1 sec1PrivateKey
+
+	if rest, err := asn1.Unmarshal(der, &sec1); err == nil && len(rest) == 0 && len(sec1.NamedCurveOID) > 0 {
+		return newKeyPairFromSEC1(pem.Block{Type: "EC PRIVATE KEY", Bytes: der}, id, usage...)
+	}
+
+	if key, err := loadPublicKey(der, id, usage...); err == nil {
+		return key, nil
+	}
+
+	return nil, fmt.Errorf("%w: unrecognized DER", ErrNoKey)
+
+}
+
+// newKeyPairFromSEC1 creates a `ECDSAPrivateKey` or, if the curve is _secp256k1_, a
+// `Secp256k1PrivateKey` from the _EC PRIVATE KEY_ _block_.
+func newKeyPairFromSEC1(block pem.Block, id string, usage ...ifcrypto.KeyUsage) (ifcrypto.KeyPair, error) {
+
+	var sec1 sec1PrivateKey
+
+	if _, err := asn1.Unmarshal(block.Bytes, &sec1); err == nil && sec1.NamedCurveOID.Equal(oidPKCS8Secp256k1) {
+		return NewSecp256k1PrivateKeyFromPEM(block, id, usage...)
+	}
+
+	return NewECDSAPrivateKeyFromPEM(block, id, usage...)
+
+}
+
+// loadPublicKey creates the public key of the _PKIX_, or _PKCS #1_ _RSA_, _der_.
+func loadPublicKey(der []byte, id string, usage ...ifcrypto.KeyUsage) (ifcrypto.PublicKey, error) {
+
+	key, err := publicKeyFromDER(id, der, usage...)
+	if err == nil {
+		return key, nil
+	}
+
+	rsakey, pkcs1Err := x509.ParsePKCS1PublicKey(der)
+	if pkcs1Err != nil {
+		return nil, err
+	}
+
+	if err := checkLoadedKey(rsakey); err != nil {
+		return nil, err
+	}
+
+	return NewRSAPublicKeyFromKey(id, rsakey, usage...), nil
+
+}
@@ -0,0 +1,114 @@
+//go:build !verifyonly
+// +build !verifyonly
+
+package gocrypto
+
+import (
+	"bytes"
+	"crypto"
+	"encoding/pem"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/mariotoffia/goservice/interfaces/ifcrypto"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadKeySniffsPEMAndDER(t *testing.T) {
+
+	rsaKey, err := NewRSAPrivateKey("rsa", 2048, ifcrypto.KeyUsageSign)
+	require.NoError(t, err)
+
+	ecKey, err := NewECDSAPrivateKey("ec", 256, ifcrypto.KeyUsageSign)
+	require.NoError(t, err)
+
+	edKey, err := NewED25519PrivateKey("ed", ifcrypto.KeyUsageSign)
+	require.NoError(t, err)
+
+	xKey, err := NewX25519PrivateKey("x", ifcrypto.KeyUsageKeyAgreement)
+	require.NoError(t, err)
+
+	k1Key, err := NewSecp256k1PrivateKey("k1", ifcrypto.KeyUsageSign)
+	require.NoError(t, err)
+
+	tests := []struct {
+		key  ifcrypto.KeyPair
+		want interface{}
+	}{
+		{rsaKey, &RSAPrivateKey{}},
+		{ecKey, &ECDSAPrivateKey{}},
+		{edKey, &ED25519PrivateKey{}},
+		{xKey, &X25519PrivateKey{}},
+		{k1Key, &Secp256k1PrivateKey{}},
+	}
+
+	for _, tt := range tests {
+
+		var pemData bytes.Buffer
+		require.NoError(t, tt.key.(ifcrypto.PEMWriter).PEMWrite(&pemData, true))
+
+		key, err := LoadKey(&pemData, "loaded", ifcrypto.KeyUsageSign)
+		require.NoError(t, err, tt.key.GetID())
+		assert.IsType(t, tt.want, key, tt.key.GetID())
+		assert.Equal(t, "loaded", key.GetID())
+		assert.Equal(t, tt.key.GetKeyType(), key.GetKeyType())
+
+		var der bytes.Buffer
+		require.NoError(t, tt.key.(ifcrypto.DERWriter).DERWrite(&der))
+
+		key, err = LoadKey(&der, "loaded")
+		require.NoError(t, err, tt.key.GetID())
+		assert.IsType(t, tt.want, key, tt.key.GetID())
+
+		var pubData bytes.Buffer
+		require.NoError(t, tt.key.GetPublic().(ifcrypto.PEMWriter).PEMWrite(&pubData, true))
+
+		key, err = LoadKey(&pubData, "loaded")
+		require.NoError(t, err, tt.key.GetID())
+		assert.Equal(t, tt.key.GetKeyType(), key.GetKeyType())
+		_, private := key.(ifcrypto.KeyPair)
+		assert.False(t, private, tt.key.GetID())
+
+	}
+
+}
+
+func TestLoadKeyBundleSymmetricAndErrors(t *testing.T) {
+
+	edKey, err := NewED25519PrivateKey("ed", ifcrypto.KeyUsageSign)
+	require.NoError(t, err)
+
+	var bundle bytes.Buffer
+	require.NoError(t, pem.Encode(&bundle, &pem.Block{Type: "CERTIFICATE", Bytes: []byte{0x30, 0x00}}))
+	require.NoError(t, edKey.GetPublic().(ifcrypto.PEMWriter).PEMWrite(&bundle, true))
+	require.NoError(t, edKey.PEMWrite(&bundle, false))
+
+	key, err := LoadKey(&bundle, "bundle")
+	require.NoError(t, err)
+	assert.IsType(t, &ED25519PrivateKey{}, key)
+
+	hmacKey, err := NewHMACKey("hmac", crypto.SHA384, ifcrypto.KeyUsageSign)
+	require.NoError(t, err)
+
+	var hmacData bytes.Buffer
+	require.NoError(t, hmacKey.PEMWrite(&hmacData, false))
+
+	key, err = LoadKey(&hmacData, "hmac")
+	require.NoError(t, err)
+	assert.Equal(t, ifcrypto.SignAlgorithmHmacSha384, key.(*HMACKey).Algorithm())
+
+	var encrypted bytes.Buffer
+	require.NoError(t, edKey.PEMWriteEncrypted(&encrypted, []byte("secret")))
+
+	_, err = LoadKey(&encrypted, "enc")
+	assert.Error(t, err)
+
+	_, err = LoadKey(strings.NewReader(""), "empty")
+	assert.True(t, errors.Is(err, ErrNoKey))
+
+	_, err = LoadKey(bytes.NewReader([]byte{0x01, 0x02}), "garbage")
+	assert.True(t, errors.Is(err, ErrNoKey))
+
+}
@@ -0,0 +1,42 @@
+package gocrypto
+
+import (
+	"crypto"
+	"sync"
+
+	"github.com/mariotoffia/goservice/utils/cryptoutils"
+)
+
+var (
+	loadLinterMu sync.RWMutex
+	loadLinter   *cryptoutils.Linter
+)
+
+// SetLoadLinter sets the _linter_ that validates all keys loaded from _PEM_ by the
+// `NewXxxFromPEM` functions. If the linter fails a key, the load fails with a
+// `*cryptoutils.LintFailure`.
+//
+// By default no linting is done. Set _linter_ to `nil` to disable it again.
+func SetLoadLinter(linter *cryptoutils.Linter) {
+
+	loadLinterMu.Lock()
+	defer loadLinterMu.Unlock()
+
+	loadLinter = linter
+
+}
+
+// checkLoadedKey validates the _pub_ key using the load linter, if any.
+func checkLoadedKey(pub crypto.PublicKey) error {
+
+	loadLinterMu.RLock()
+	linter := loadLinter
+	loadLinterMu.RUnlock()
+
+	if linter == nil {
+		return nil
+	}
+
+	return linter.CheckPublicKey(pub)
+
+}
@@ -0,0 +1,88 @@
+package gocrypto
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rand"
+	"fmt"
+
+	"github.com/mariotoffia/goservice/interfaces/ifcrypto"
+	"github.com/mariotoffia/goservice/utils/cryptoutils"
+)
+
+// SignMessage hashes the _msg_ using _hash_ and signs the digest with _signer_.
+//
+// A _Ed25519_ signer signs the _msg_ as is, since it must not be pre-hashed, and ignores _hash_.
+// A zero _hash_ defaults to _SHA-256_. _RSA_ keys uses _PKCS #1 v1.5_.
+func SignMessage(signer crypto.Signer, msg []byte, hash crypto.Hash) ([]byte, error) {
+
+	if _, ok := signer.Public().(ed25519.PublicKey); ok {
+		return signer.Sign(rand.Reader, msg, crypto.Hash(0))
+	}
+
+	buf := cryptoutils.AcquireDigest()
+	defer cryptoutils.ReleaseDigest(buf)
+
+	digest, err := messageDigest(buf, msg, hash)
+	if err != nil {
+		return nil, err
+	}
+
+	return signer.Sign(rand.Reader, digest, messageHash(hash))
+
+}
+
+// VerifyMessage verifies the _signature_ of the _msg_ created by `SignMessage` with the same
+// _hash_. The _key_ must implement `ifcrypto.SignatureVerifier`.
+func VerifyMessage(key ifcrypto.PublicKey, msg, signature []byte, hash crypto.Hash) error {
+
+	verifier, ok := key.(ifcrypto.SignatureVerifier)
+	if !ok {
+		return fmt.Errorf("key %s do not support signature verification", key.GetID())
+	}
+
+	if _, ok := key.GetKey().(ed25519.PublicKey); ok {
+		return verifier.Verify(msg, signature, crypto.Hash(0))
+	}
+
+	buf := cryptoutils.AcquireDigest()
+	defer cryptoutils.ReleaseDigest(buf)
+
+	digest, err := messageDigest(buf, msg, hash)
+	if err != nil {
+		return err
+	}
+
+	return verifier.Verify(digest, signature, messageHash(hash))
+
+}
+
+// messageHash returns _hash_ or _SHA-256_ if zero.
+func messageHash(hash crypto.Hash) crypto.Hash {
+
+	if hash == 0 {
+		return crypto.SHA256
+	}
+
+	return hash
+
+}
+
+// messageDigest hashes _msg_ with _hash_, see `messageHash`, into _buf_ using a pooled hash
+// state. The digest is only valid until _buf_ is released.
+func messageDigest(buf *[64]byte, msg []byte, hash crypto.Hash) ([]byte, error) {
+
+	hash = messageHash(hash)
+
+	if !hash.Available() {
+		return nil, fmt.Errorf("hash function %d is not available", hash)
+	}
+
+	h := cryptoutils.AcquireHash(hash)
+	defer cryptoutils.ReleaseHash(hash, h)
+
+	h.Write(msg)
+
+	return h.Sum(buf[:0]), nil
+
+}
@@ -0,0 +1,152 @@
+//go:build !verifyonly
+// +build !verifyonly
+
+package gocrypto
+
+import (
+	"crypto"
+	"errors"
+	"testing"
+
+	"github.com/mariotoffia/goservice/interfaces/ifcrypto"
+	"github.com/mariotoffia/goservice/utils/cryptoutils"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSignAndVerifyMessage(t *testing.T) {
+
+	rsaKey, err := NewRSAPrivateKey("rsa", 2048, ifcrypto.KeyUsageSign)
+	require.NoError(t, err)
+
+	ecKey, err := NewECDSAPrivateKey("ec", 384, ifcrypto.KeyUsageSign)
+	require.NoError(t, err)
+
+	edKey, err := NewED25519PrivateKey("ed", ifcrypto.KeyUsageSign)
+	require.NoError(t, err)
+
+	k1Key, err := NewSecp256k1PrivateKey("k1", ifcrypto.KeyUsageSign)
+	require.NoError(t, err)
+
+	keys := []ifcrypto.KeyPair{rsaKey, ecKey, edKey, k1Key}
+	msg := []byte("a message that is not pre-hashed")
+
+	for _, key := range keys {
+
+		for _, hash := range []crypto.Hash{0, crypto.SHA256, crypto.SHA512} {
+
+			signer := key.(ifcrypto.MessageSigner)
+			verifier := key.GetPublic().(ifcrypto.MessageVerifier)
+
+			signature, err := signer.SignMessage(msg, hash)
+			require.NoError(t, err, key.GetID())
+
+			assert.NoError(t, verifier.VerifyMessage(msg, signature, hash), key.GetID())
+
+			err = verifier.VerifyMessage([]byte("another message"), signature, hash)
+			assert.True(t, errors.Is(err, ifcrypto.ErrInvalidSignature), key.GetID())
+
+		}
+
+	}
+
+}
+
+func TestSignMessageEd25519IsNotPreHashed(t *testing.T) {
+
+	key, err := NewED25519PrivateKey("ed", ifcrypto.KeyUsageSign)
+	require.NoError(t, err)
+
+	msg := []byte("message")
+
+	signature, err := SignMessage(key, msg, crypto.SHA256)
+	require.NoError(t, err)
+
+	assert.NoError(t, key.GetPublic().(ifcrypto.SignatureVerifier).Verify(msg, signature, crypto.Hash(0)))
+
+}
+
+func TestSignMessageUnavailableHash(t *testing.T) {
+
+	key, err := NewECDSAPrivateKey("ec", 256, ifcrypto.KeyUsageSign)
+	require.NoError(t, err)
+
+	_, err = SignMessage(key, []byte("message"), crypto.MD4)
+	assert.Error(t, err)
+
+}
+
+func TestMessageDigestDoNotAllocate(t *testing.T) {
+
+	msg := make([]byte, 1024)
+
+	allocs := testing.AllocsPerRun(100, func() {
+
+		buf := cryptoutils.AcquireDigest()
+		_, _ = messageDigest(buf, msg, crypto.SHA256)
+		cryptoutils.ReleaseDigest(buf)
+
+	})
+
+	assert.Zero(t, allocs)
+
+}
+
+func BenchmarkMessageDigest(b *testing.B) {
+
+	msg := make([]byte, 1024)
+
+	b.Run("unpooled", func(b *testing.B) {
+
+		b.ReportAllocs()
+
+		for i := 0; i < b.N; i++ {
+
+			h := crypto.SHA256.New()
+			h.Write(msg)
+			_ = h.Sum(nil)
+
+		}
+
+	})
+
+	b.Run("pooled", func(b *testing.B) {
+
+		b.ReportAllocs()
+
+		for i := 0; i < b.N; i++ {
+
+			buf := cryptoutils.AcquireDigest()
+			_, _ = messageDigest(buf, msg, crypto.SHA256)
+			cryptoutils.ReleaseDigest(buf)
+
+		}
+
+	})
+
+}
+
+func BenchmarkVerifyMessage(b *testing.B) {
+
+	key, err := NewECDSAPrivateKey("ec", 256, ifcrypto.KeyUsageSign)
+	require.NoError(b, err)
+
+	msg := make([]byte, 1024)
+
+	signature, err := key.SignMessage(msg, crypto.SHA256)
+	require.NoError(b, err)
+
+	public := key.GetPublic()
+
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+
+		if err := VerifyMessage(public, msg, signature, crypto.SHA256); err != nil {
+			b.Fatal(err)
+		}
+
+	}
+
+}
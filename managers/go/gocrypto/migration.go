@@ -0,0 +1,300 @@
+package gocrypto
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/mariotoffia/goservice/interfaces/ifcrypto"
+)
+
+// MigrationPhase is the phase of a `KeyMigration`.
+type MigrationPhase string
+
+const (
+	// MigrationPhasePending is before the successor key has been generated.
+	MigrationPhasePending MigrationPhase = "pending"
+	// MigrationPhaseValidating is when both keys are published and the successor is being validated.
+	MigrationPhaseValidating MigrationPhase = "validating"
+	// MigrationPhaseRetired is when the old key has been retired and only the successor is published.
+	MigrationPhaseRetired MigrationPhase = "retired"
+)
+
+// MigrationTarget is the algorithm of the successor key.
+type MigrationTarget struct {
+	// KeyType is one of `ifcrypto.KeyTypeRsa`, `ifcrypto.KeyTypeEccNistP` or `ifcrypto.KeyTypeEd25519`.
+	KeyType ifcrypto.KeyType
+	// Bits is the key size, ignored for _Ed25519_.
+	Bits int
+}
+
+// KeyPublisher publishes the set of public keys that verifiers should trust, for example
+// by updating a _JWKS_ document or a trust bundle.
+type KeyPublisher func(c context.Context, keys []ifcrypto.PublicKey) error
+
+// KeyRewrapper unwraps a data encryption key with the _from_ key and wraps it with the _to_ key.
+type KeyRewrapper func(c context.Context, from, to ifcrypto.KeyPair, wrapped []byte) ([]byte, error)
+
+// KeyMigrationOption configures a `KeyMigration`.
+type KeyMigrationOption func(m *KeyMigration)
+
+// WithMigrationPublisher sets the function that publishes the public keys.
+func WithMigrationPublisher(publish KeyPublisher) KeyMigrationOption {
+	return func(m *KeyMigration) {
+		m.publish = publish
+	}
+}
+
+// WithMigrationValidator sets a function that must succeed on the successor key before the
+// old key may be retired, for example checking that no verifier still rejects it.
+func WithMigrationValidator(validate func(c context.Context, successor ifcrypto.KeyPair) error) KeyMigrationOption {
+	return func(m *KeyMigration) {
+		m.validate = validate
+	}
+}
+
+// WithMigrationClock sets the clock used for the validation period.
+func WithMigrationClock(clock func() time.Time) KeyMigrationOption {
+	return func(m *KeyMigration) {
+		m.clock = clock
+	}
+}
+
+// KeyMigration guides the migration of a key to a successor key of a new algorithm, e.g.
+// _RSA_ to _ECDSA_ or _ECDSA_ to _Ed25519_.
+//
+// The migration is done in steps:
+//
+// . `Start` generates the successor and publishes both public keys.
+// . `Rewrap` re-wraps existing data encryption keys with the successor.
+// . `Retire` publishes only the successor once the validation period has passed.
+type KeyMigration struct {
+	current          ifcrypto.KeyPair
+	successor        ifcrypto.KeyPair
+	successorID      string
+	target           MigrationTarget
+	validationPeriod time.Duration
+	phase            MigrationPhase
+	started          time.Time
+	publish          KeyPublisher
+	validate         func(c context.Context, successor ifcrypto.KeyPair) error
+	clock            func() time.Time
+	mu               sync.Mutex
+}
+
+// NewKeyMigration creates a new `KeyMigration` of the _current_ key to a successor with
+// _successorID_ of the _target_ algorithm. The old key is kept published for at least the
+// _validationPeriod_.
+func NewKeyMigration(
+	current ifcrypto.KeyPair,
+	successorID string,
+	target MigrationTarget,
+	validationPeriod time.Duration,
+	opts ...KeyMigrationOption,
+) (*KeyMigration, error) {
+
+	if successorID == "" || successorID == current.GetID() {
+		return nil, fmt.Errorf("successor must have a new id")
+	}
+
+	switch target.KeyType {
+	case ifcrypto.KeyTypeRsa, ifcrypto.KeyTypeEccNistP, ifcrypto.KeyTypeEd25519:
+	default:
+		return nil, fmt.Errorf("unsupported migration target: %s", target.KeyType)
+	}
+
+	m := &KeyMigration{
+		current:          current,
+		successorID:      successorID,
+		target:           target,
+		validationPeriod: validationPeriod,
+		phase:            MigrationPhasePending,
+		publish:          func(context.Context, []ifcrypto.PublicKey) error { return nil },
+		validate:         func(context.Context, ifcrypto.KeyPair) error { return nil },
+		clock:            time.Now,
+	}
+
+	for _, opt := range opts {
+		opt(m)
+	}
+
+	return m, nil
+
+}
+
+// Phase returns the current `MigrationPhase`.
+func (m *KeyMigration) Phase() MigrationPhase {
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.phase
+
+}
+
+// Successor returns the successor key or `nil` if the migration has not been started.
+func (m *KeyMigration) Successor() ifcrypto.KeyPair {
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.successor
+
+}
+
+// Start generates the successor key, with the same usage as the current key, and publishes
+// both the current and the successor public keys.
+func (m *KeyMigration) Start(c context.Context) (ifcrypto.KeyPair, error) {
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.phase != MigrationPhasePending {
+		return nil, fmt.Errorf("migration of %s already started", m.current.GetID())
+	}
+
+	successor, err := m.generate()
+	if err != nil {
+		return nil, err
+	}
+
+	if err := m.publish(c, []ifcrypto.PublicKey{m.current.GetPublic(), successor.GetPublic()}); err != nil {
+		return nil, err
+	}
+
+	m.successor = successor
+	m.started = m.clock()
+	m.phase = MigrationPhaseValidating
+
+	return successor, nil
+
+}
+
+// Rewrap re-wraps the data encryption keys with _ids_ in _store_ from the current key to the
+// successor using _rewrap_.
+//
+// Since `ifcrypto.DataKeyStore` do not support update, each key is deleted and put. If the
+// put fails, the original wrapped key is restored. Keys that do not exist are skipped.
+func (m *KeyMigration) Rewrap(
+	c context.Context,
+	store ifcrypto.DataKeyStore,
+	rewrap KeyRewrapper,
+	ids ...string,
+) error {
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.phase != MigrationPhaseValidating {
+		return fmt.Errorf("data keys can only be re-wrapped while validating, phase is %s", m.phase)
+	}
+
+	for _, id := range ids {
+
+		wrapped, err := store.Get(c, id)
+		if errors.Is(err, ifcrypto.ErrDataKeyNotFound) {
+			continue
+		}
+
+		if err != nil {
+			return err
+		}
+
+		rewrapped, err := rewrap(c, m.current, m.successor, wrapped)
+		if err != nil {
+			return fmt.Errorf("failed to re-wrap data key %s: %w", id, err)
+		}
+
+		if err := store.Delete(c, id); err != nil {
+			return err
+		}
+
+		if err := store.Put(c, id, rewrapped); err != nil {
+
+			if restoreErr := store.Put(c, id, wrapped); restoreErr != nil {
+				return fmt.Errorf("failed to store data key %s: %v, restore failed: %v", id, err, restoreErr)
+			}
+
+			return err
+
+		}
+
+	}
+
+	return nil
+
+}
+
+// Retire retires the current key and publishes only the successor.
+//
+// It fails if the validation period has not passed or the validator rejects the successor.
+func (m *KeyMigration) Retire(c context.Context) error {
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.phase != MigrationPhaseValidating {
+		return fmt.Errorf("can only retire while validating, phase is %s", m.phase)
+	}
+
+	if remaining := m.started.Add(m.validationPeriod).Sub(m.clock()); remaining > 0 {
+		return fmt.Errorf("validation period has %s remaining", remaining)
+	}
+
+	if err := m.validate(c, m.successor); err != nil {
+		return fmt.Errorf("successor %s failed validation: %w", m.successor.GetID(), err)
+	}
+
+	if err := m.publish(c, []ifcrypto.PublicKey{m.successor.GetPublic()}); err != nil {
+		return err
+	}
+
+	m.phase = MigrationPhaseRetired
+
+	return nil
+
+}
+
+// generate generates the successor key.
+func (m *KeyMigration) generate() (ifcrypto.KeyPair, error) {
+
+	usage := m.current.GetKeyUsage()
+
+	switch m.target.KeyType {
+	case ifcrypto.KeyTypeRsa:
+		return NewRSAPrivateKey(m.successorID, m.target.Bits, usage...)
+	case ifcrypto.KeyTypeEccNistP:
+		return NewECDSAPrivateKey(m.successorID, m.target.Bits, usage...)
+	}
+
+	return NewED25519PrivateKey(m.successorID, usage...)
+
+}
+
+// RewrapRSAOAEP is a `KeyRewrapper` for data keys wrapped using _RSA-OAEP_ with _SHA-256_
+// where both keys are local _RSA_ keys.
+func RewrapRSAOAEP(c context.Context, from, to ifcrypto.KeyPair, wrapped []byte) ([]byte, error) {
+
+	priv, ok := from.GetKey().(*rsa.PrivateKey)
+	if !ok {
+		return nil, fmt.Errorf("from key %s is not a local RSA key", from.GetID())
+	}
+
+	pub, ok := to.GetPublic().GetKey().(*rsa.PublicKey)
+	if !ok {
+		return nil, fmt.Errorf("to key %s is not a RSA key", to.GetID())
+	}
+
+	dek, err := rsa.DecryptOAEP(sha256.New(), rand.Reader, priv, wrapped, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	return rsa.EncryptOAEP(sha256.New(), rand.Reader, pub, dek, nil)
+
+}
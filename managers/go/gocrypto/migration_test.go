@@ -0,0 +1,93 @@
+//go:build !verifyonly
+// +build !verifyonly
+
+package gocrypto
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"testing"
+	"time"
+
+	"github.com/mariotoffia/goservice/interfaces/ifcrypto"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestKeyMigrationToNewRSAKey(t *testing.T) {
+
+	ctx := context.Background()
+
+	current, err := NewRSAPrivateKey("key-v1", 2048, ifcrypto.KeyUsageEncrypt, ifcrypto.KeyUsageDecrypt)
+	require.NoError(t, err)
+
+	published := [][]string{}
+	now := time.Unix(1700000000, 0)
+
+	migration, err := NewKeyMigration(current, "key-v2", MigrationTarget{KeyType: ifcrypto.KeyTypeRsa, Bits: 3072}, 24*time.Hour,
+		WithMigrationClock(func() time.Time { return now }),
+		WithMigrationPublisher(func(c context.Context, keys []ifcrypto.PublicKey) error {
+
+			ids := []string{}
+			for _, k := range keys {
+				ids = append(ids, k.GetID())
+			}
+
+			published = append(published, ids)
+			return nil
+
+		}),
+	)
+
+	require.NoError(t, err)
+
+	successor, err := migration.Start(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 3072, successor.GetKeySize())
+	assert.Equal(t, MigrationPhaseValidating, migration.Phase())
+
+	dek := []byte("0123456789abcdef0123456789abcdef")
+	wrapped, err := rsa.EncryptOAEP(sha256.New(), rand.Reader, &current.key.PublicKey, dek, nil)
+	require.NoError(t, err)
+
+	store := NewMemoryDataKeyStore()
+	require.NoError(t, store.Put(ctx, "record-1", wrapped))
+
+	require.NoError(t, migration.Rewrap(ctx, store, RewrapRSAOAEP, "record-1", "record-missing"))
+
+	rewrapped, err := store.Get(ctx, "record-1")
+	require.NoError(t, err)
+
+	plain, err := rsa.DecryptOAEP(sha256.New(), rand.Reader, successor.GetKey().(*rsa.PrivateKey), rewrapped, nil)
+	require.NoError(t, err)
+	assert.Equal(t, dek, plain)
+
+	assert.Error(t, migration.Retire(ctx))
+
+	now = now.Add(25 * time.Hour)
+	require.NoError(t, migration.Retire(ctx))
+
+	assert.Equal(t, MigrationPhaseRetired, migration.Phase())
+	assert.Equal(t, [][]string{{"key-v1", "key-v2"}, {"key-v2"}}, published)
+
+}
+
+func TestKeyMigrationToEd25519(t *testing.T) {
+
+	current, err := NewECDSAPrivateKey("sig-v1", 256, ifcrypto.KeyUsageSign)
+	require.NoError(t, err)
+
+	migration, err := NewKeyMigration(current, "sig-v2", MigrationTarget{KeyType: ifcrypto.KeyTypeEd25519}, 0)
+	require.NoError(t, err)
+
+	successor, err := migration.Start(context.Background())
+	require.NoError(t, err)
+
+	assert.Equal(t, ifcrypto.KeyTypeEd25519, successor.GetKeyType())
+	assert.True(t, successor.CanSign(ifcrypto.SignAlgorithmEd25519))
+
+	require.NoError(t, migration.Retire(context.Background()))
+
+}
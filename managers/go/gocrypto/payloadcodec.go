@@ -0,0 +1,566 @@
+package gocrypto
+
+import (
+	"bytes"
+	"context"
+	"crypto"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/ecdsa"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/binary"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"time"
+
+	"github.com/mariotoffia/goservice/interfaces/ifcrypto"
+	"github.com/mariotoffia/goservice/utils/cryptoutils"
+	"golang.org/x/crypto/hkdf"
+)
+
+var (
+	// ErrPayloadExpired is returned when a sealed payload is older than the maximum age.
+	ErrPayloadExpired = fmt.Errorf("sealed payload has expired")
+	// ErrPayloadSignature is returned when the signature of a sealed payload do not verify.
+	ErrPayloadSignature = fmt.Errorf("invalid sealed payload signature")
+)
+
+// PayloadContentType is the _HTTP_ content type of a `SealedPayload`.
+const PayloadContentType = "application/vnd.goservice.sealed+json"
+
+// SealedPayload is a signed, and optionally encrypted, message between two services.
+//
+// The signature covers all fields, hence the payload can not be replayed to another recipient.
+// When encrypted, the content key is derived from a ephemeral key agreement with the
+// recipient key and the _Payload_ is _AES-256-GCM_ encrypted.
+type SealedPayload struct {
+	Sender         string    `json:"sender"`
+	SenderKeyID    string    `json:"sender_key_id"`
+	Recipient      string    `json:"recipient,omitempty"`
+	RecipientKeyID string    `json:"recipient_key_id,omitempty"`
+	Created        time.Time `json:"created"`
+	// EphemeralKey is the _DER_ encoded ephemeral public key, only set when encrypted.
+	EphemeralKey []byte `json:"epk,omitempty"`
+	// Payload is the plaintext, or the nonce and ciphertext when encrypted.
+	Payload []byte `json:"payload"`
+	// Context is the `ifcrypto.CryptoContext` the payload was sealed in, if any. It is signed
+	// but never encrypted.
+	Context   *ifcrypto.CryptoContext `json:"context,omitempty"`
+	Signature []byte                  `json:"signature"`
+}
+
+// PayloadCodecOption configures a `PayloadCodec`.
+type PayloadCodecOption func(p *PayloadCodec)
+
+// WithPayloadRecipient sets the service that `Marshal` seals payloads to. This is needed when
+// the codec is used as a _gRPC_ codec since it has no other way to learn the recipient.
+func WithPayloadRecipient(recipient string) PayloadCodecOption {
+	return func(p *PayloadCodec) {
+		p.recipient = recipient
+	}
+}
+
+// WithPayloadEncryption enables, or disables, encryption of sealed payloads. Default is enabled.
+func WithPayloadEncryption(encrypt bool) PayloadCodecOption {
+	return func(p *PayloadCodec) {
+		p.encrypt = encrypt
+	}
+}
+
+// WithPayloadDecryptionKey sets the key of this service used to decrypt payloads sent to it.
+// The key must implement `ifcrypto.KeyAgreement`.
+func WithPayloadDecryptionKey(key ifcrypto.KeyPair) PayloadCodecOption {
+	return func(p *PayloadCodec) {
+		p.decryptionKey = key
+	}
+}
+
+// WithPayloadMarshaler sets the functions that (un)marshal the messages, default is _JSON_.
+func WithPayloadMarshaler(
+	marshal func(v interface{}) ([]byte, error),
+	unmarshal func(data []byte, v interface{}) error,
+) PayloadCodecOption {
+	return func(p *PayloadCodec) {
+		p.marshal = marshal
+		p.unmarshal = unmarshal
+	}
+}
+
+// WithPayloadMaxAge sets the maximum age of a received payload, zero disables the check.
+// Default is five minutes.
+func WithPayloadMaxAge(maxAge time.Duration) PayloadCodecOption {
+	return func(p *PayloadCodec) {
+		p.maxAge = maxAge
+	}
+}
+
+// WithPayloadClock sets the clock used to timestamp and check payloads, default `time.Now`.
+func WithPayloadClock(clock func() time.Time) PayloadCodecOption {
+	return func(p *PayloadCodec) {
+		p.clock = clock
+	}
+}
+
+// PayloadCodec signs, and optionally encrypts, message payloads end-to-end between services
+// independent of the transport security.
+//
+// The sender signs with its own key pair and encrypts to the recipient key found in the
+// `ifcrypto.PublicKeyDirectory` with usage `ifcrypto.KeyUsageKeyAgreement`. The recipient
+// verifies using the sender key found with usage `ifcrypto.KeyUsageVerify`. Recipient keys
+// may be _X25519_ or _NIST_ curve keys.
+//
+// The codec implements the _gRPC_ `encoding.Codec` interface, see `Marshal`, and may wrap
+// _HTTP_ handlers, see `Handler`.
+type PayloadCodec struct {
+	service       string
+	signer        ifcrypto.KeyPair
+	directory     ifcrypto.PublicKeyDirectory
+	recipient     string
+	encrypt       bool
+	decryptionKey ifcrypto.KeyPair
+	marshal       func(v interface{}) ([]byte, error)
+	unmarshal     func(data []byte, v interface{}) error
+	maxAge        time.Duration
+	clock         func() time.Time
+}
+
+// NewPayloadCodec creates a new `PayloadCodec` for _service_ that signs with _signer_ and
+// resolves the keys of other services in _directory_. The _signer_ must implement `crypto.Signer`
+// and may be `nil` if the codec only opens payloads.
+func NewPayloadCodec(
+	service string,
+	signer ifcrypto.KeyPair,
+	directory ifcrypto.PublicKeyDirectory,
+	opts ...PayloadCodecOption,
+) *PayloadCodec {
+
+	p := &PayloadCodec{
+		service:   service,
+		signer:    signer,
+		directory: directory,
+		encrypt:   true,
+		marshal:   json.Marshal,
+		unmarshal: json.Unmarshal,
+		maxAge:    5 * time.Minute,
+		clock:     time.Now,
+	}
+
+	for _, opt := range opts {
+		opt(p)
+	}
+
+	return p
+
+}
+
+// Name implements the _gRPC_ `encoding.Codec` interface.
+func (p *PayloadCodec) Name() string {
+	return "sealed"
+}
+
+// Marshal implements the _gRPC_ `encoding.Codec` interface and seals _v_ to the recipient
+// set by `WithPayloadRecipient`.
+func (p *PayloadCodec) Marshal(v interface{}) ([]byte, error) {
+	return p.Seal(context.Background(), p.recipient, v)
+}
+
+// Unmarshal implements the _gRPC_ `encoding.Codec` interface.
+func (p *PayloadCodec) Unmarshal(data []byte, v interface{}) error {
+
+	_, err := p.Open(context.Background(), data, v)
+	return err
+
+}
+
+// Seal marshals, signs and, if encryption is enabled, encrypts _v_ to the _recipient_ service.
+// The `ifcrypto.CryptoContext` of _c_, if any, is embedded in the `SealedPayload`.
+func (p *PayloadCodec) Seal(c context.Context, recipient string, v interface{}) ([]byte, error) {
+
+	plaintext, err := p.marshal(v)
+	if err != nil {
+		return nil, err
+	}
+
+	sealed := SealedPayload{
+		Sender:      p.service,
+		SenderKeyID: p.signer.GetID(),
+		Recipient:   recipient,
+		Created:     p.clock().UTC(),
+		Payload:     plaintext,
+	}
+
+	if cc := ifcrypto.CryptoContextFrom(c); !cc.IsZero() {
+		sealed.Context = &cc
+	}
+
+	if p.encrypt {
+
+		if recipient == "" {
+			return nil, fmt.Errorf("recipient is required to encrypt payload")
+		}
+
+		if err := p.encryptPayload(c, &sealed); err != nil {
+			return nil, err
+		}
+
+	}
+
+	signer, ok := p.signer.(crypto.Signer)
+	if !ok {
+		return nil, fmt.Errorf("key %s is not a crypto.Signer", p.signer.GetID())
+	}
+
+	if sealed.Signature, err = signDigest(signer, sealedPayloadDigest(&sealed)); err != nil {
+		return nil, err
+	}
+
+	return json.Marshal(&sealed)
+
+}
+
+// Open verifies and, if encrypted, decrypts the sealed _data_ and unmarshals it into _v_.
+//
+// It returns the sender service.
+func (p *PayloadCodec) Open(c context.Context, data []byte, v interface{}) (string, error) {
+
+	sealed, err := p.open(c, data, v)
+	if err != nil {
+		return "", err
+	}
+
+	return sealed.Sender, nil
+
+}
+
+// OpenContext is `Open` but returns a copy of _c_ that carries the sender, see `PayloadSender`,
+// and the `ifcrypto.CryptoContext` the payload was sealed in, if any. This lets the recipient
+// act, and audit, on behalf of the same tenant, request and principal as the sender.
+func (p *PayloadCodec) OpenContext(c context.Context, data []byte, v interface{}) (context.Context, error) {
+
+	sealed, err := p.open(c, data, v)
+	if err != nil {
+		return nil, err
+	}
+
+	c = context.WithValue(c, payloadSenderKey{}, sealed.Sender)
+
+	if sealed.Context != nil {
+		c = ifcrypto.WithCryptoContext(c, *sealed.Context)
+	}
+
+	return c, nil
+
+}
+
+// open verifies and, if encrypted, decrypts the sealed _data_ and unmarshals it into _v_.
+func (p *PayloadCodec) open(c context.Context, data []byte, v interface{}) (*SealedPayload, error) {
+
+	var sealed SealedPayload
+
+	if err := json.Unmarshal(data, &sealed); err != nil {
+		return nil, err
+	}
+
+	if sealed.Recipient != "" && sealed.Recipient != p.service {
+		return nil, fmt.Errorf("payload is sealed to %s not %s", sealed.Recipient, p.service)
+	}
+
+	if p.maxAge > 0 && p.clock().Sub(sealed.Created) > p.maxAge {
+		return nil, fmt.Errorf("%w: created %s", ErrPayloadExpired, sealed.Created)
+	}
+
+	sender, err := p.directory.Lookup(c, sealed.Sender, ifcrypto.KeyUsageVerify)
+	if err != nil {
+		return nil, err
+	}
+
+	if !verifyDigest(sender.GetKey(), sealedPayloadDigest(&sealed), sealed.Signature) {
+		return nil, fmt.Errorf("%w: payload from %s key %s", ErrPayloadSignature, sealed.Sender, sealed.SenderKeyID)
+	}
+
+	plaintext := sealed.Payload
+
+	if len(sealed.EphemeralKey) > 0 {
+
+		if plaintext, err = p.decryptPayload(&sealed); err != nil {
+			return nil, err
+		}
+
+	} else if p.encrypt {
+		return nil, fmt.Errorf("payload from %s is not encrypted", sealed.Sender)
+	}
+
+	if err := p.unmarshal(plaintext, v); err != nil {
+		return nil, err
+	}
+
+	return &sealed, nil
+
+}
+
+// payloadSenderKey is the context key of the sender set by `PayloadCodec.Handler`.
+type payloadSenderKey struct{}
+
+// PayloadSender returns the verified sender service of a request opened by `PayloadCodec.Handler`.
+func PayloadSender(c context.Context) string {
+
+	sender, _ := c.Value(payloadSenderKey{}).(string)
+	return sender
+
+}
+
+// Handler returns a _HTTP_ handler that opens sealed request bodies before calling _next_.
+//
+// The body is replaced with the plaintext and the request context is the one of `OpenContext`.
+// Requests that do not open are rejected with _400 Bad Request_.
+func (p *PayloadCodec) Handler(next http.Handler) http.Handler {
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+
+		data, err := ioutil.ReadAll(r.Body)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		var plaintext []byte
+
+		// Keep the plaintext as is, it is up to _next_ to unmarshal it
+		opener := *p
+		opener.unmarshal = func(data []byte, v interface{}) error {
+			plaintext = data
+			return nil
+		}
+
+		c, err := opener.OpenContext(r.Context(), data, nil)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		r = r.WithContext(c)
+		r.Body = ioutil.NopCloser(bytes.NewReader(plaintext))
+		r.ContentLength = int64(len(plaintext))
+
+		next.ServeHTTP(w, r)
+
+	})
+
+}
+
+// NewRequest creates a _HTTP_ request with _v_ sealed to the _recipient_ as body.
+func (p *PayloadCodec) NewRequest(
+	c context.Context,
+	method, url, recipient string,
+	v interface{},
+) (*http.Request, error) {
+
+	data, err := p.Seal(c, recipient, v)
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequestWithContext(c, method, url, bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+
+	req.Header.Set("Content-Type", PayloadContentType)
+
+	return req, nil
+
+}
+
+// encryptPayload encrypts the plaintext _Payload_ of _sealed_ to the recipient key.
+func (p *PayloadCodec) encryptPayload(c context.Context, sealed *SealedPayload) error {
+
+	recipient, err := p.directory.Lookup(c, sealed.Recipient, ifcrypto.KeyUsageKeyAgreement)
+	if err != nil {
+		return err
+	}
+
+	var ephemeral ifcrypto.KeyPair
+
+	switch recipient.GetKeyType() {
+	case ifcrypto.KeyTypeX25519:
+		ephemeral, err = NewX25519PrivateKey("ephemeral")
+	case ifcrypto.KeyTypeEccNistP:
+		ephemeral, err = NewECDSAPrivateKey("ephemeral", recipient.GetKeySize())
+	default:
+		return fmt.Errorf("unsupported recipient key type: %s", recipient.GetKeyType())
+	}
+
+	if err != nil {
+		return err
+	}
+
+	shared, err := ephemeral.(ifcrypto.KeyAgreement).DeriveSharedSecret(recipient)
+	if err != nil {
+		return err
+	}
+
+	var buf bytes.Buffer
+
+	if err := ephemeral.GetPublic().(ifcrypto.PEMWriter).PEMWrite(&buf, false); err != nil {
+		return err
+	}
+
+	block, _ := pem.Decode(buf.Bytes())
+
+	sealed.RecipientKeyID = recipient.GetID()
+	sealed.EphemeralKey = block.Bytes
+
+	aead, err := payloadAEAD(shared, sealed)
+	if err != nil {
+		return err
+	}
+
+	nonce := make([]byte, aead.NonceSize())
+
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return err
+	}
+
+	sealed.Payload = aead.Seal(nonce, nonce, sealed.Payload, []byte(sealed.Sender))
+
+	return nil
+
+}
+
+// decryptPayload decrypts the _Payload_ of _sealed_ using the decryption key.
+func (p *PayloadCodec) decryptPayload(sealed *SealedPayload) ([]byte, error) {
+
+	if p.decryptionKey == nil {
+		return nil, fmt.Errorf("no decryption key configured for %s", p.service)
+	}
+
+	agreement, ok := p.decryptionKey.(ifcrypto.KeyAgreement)
+	if !ok {
+		return nil, fmt.Errorf("decryption key %s do not support key agreement", p.decryptionKey.GetID())
+	}
+
+	var (
+		ephemeral ifcrypto.PublicKey
+		err       error
+	)
+
+	switch p.decryptionKey.GetKeyType() {
+	case ifcrypto.KeyTypeX25519:
+
+		var key []byte
+
+		if key, err = cryptoutils.ParseX25519PKIXPublicKey(sealed.EphemeralKey); err == nil {
+			ephemeral, err = NewX25519PublicKeyFromKey("ephemeral", key)
+		}
+
+	case ifcrypto.KeyTypeEccNistP:
+
+		var key interface{}
+
+		if key, err = x509.ParsePKIXPublicKey(sealed.EphemeralKey); err == nil {
+
+			if ecdsakey, ok := key.(*ecdsa.PublicKey); ok {
+				ephemeral = NewECDSAPublicKeyFromKey("ephemeral", ecdsakey)
+			} else {
+				err = fmt.Errorf("not a *ecdsa.PublicKey: %T", key)
+			}
+
+		}
+
+	default:
+		err = fmt.Errorf("unsupported decryption key type: %s", p.decryptionKey.GetKeyType())
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	shared, err := agreement.DeriveSharedSecret(ephemeral)
+	if err != nil {
+		return nil, err
+	}
+
+	aead, err := payloadAEAD(shared, sealed)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(sealed.Payload) < aead.NonceSize() {
+		return nil, fmt.Errorf("sealed payload too short")
+	}
+
+	return aead.Open(
+		nil,
+		sealed.Payload[:aead.NonceSize()],
+		sealed.Payload[aead.NonceSize():],
+		[]byte(sealed.Sender),
+	)
+
+}
+
+// payloadAEAD derives the content key from the _shared_ secret bound to the sender, recipient
+// and ephemeral key.
+func payloadAEAD(shared []byte, sealed *SealedPayload) (cipher.AEAD, error) {
+
+	info := []byte("goservice sealed payload\x00" + sealed.Sender + "\x00" + sealed.Recipient)
+
+	key := make([]byte, 32)
+
+	if _, err := io.ReadFull(hkdf.New(sha256.New, shared, sealed.EphemeralKey, info), key); err != nil {
+		return nil, err
+	}
+
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+
+	return cipher.NewGCM(block)
+
+}
+
+// sealedPayloadDigest calculates the _SHA-256_ over all fields of _sealed_ except the signature.
+//
+// Each variable length field is length prefixed to make the encoding unambiguous. The _JSON_
+// encoded _Context_ is only included when set, such that payloads without it keep their digest.
+func sealedPayloadDigest(sealed *SealedPayload) []byte {
+
+	h := sha256.New()
+
+	var n [8]byte
+
+	field := func(b []byte) {
+
+		binary.BigEndian.PutUint64(n[:], uint64(len(b)))
+		h.Write(n[:])
+		h.Write(b)
+
+	}
+
+	field([]byte(sealed.Sender))
+	field([]byte(sealed.SenderKeyID))
+	field([]byte(sealed.Recipient))
+	field([]byte(sealed.RecipientKeyID))
+
+	binary.BigEndian.PutUint64(n[:], uint64(sealed.Created.UnixNano()))
+	h.Write(n[:])
+
+	field(sealed.EphemeralKey)
+	field(sealed.Payload)
+
+	if sealed.Context != nil {
+
+		data, _ := json.Marshal(sealed.Context)
+		field(data)
+
+	}
+
+	return h.Sum(nil)
+
+}
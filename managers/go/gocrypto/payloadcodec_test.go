@@ -0,0 +1,175 @@
+//go:build !verifyonly
+// +build !verifyonly
+
+package gocrypto
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/mariotoffia/goservice/interfaces/ifcrypto"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type testDirectory map[string]ifcrypto.PublicKey
+
+func (d testDirectory) Lookup(c context.Context, service string, usage ifcrypto.KeyUsage) (ifcrypto.PublicKey, error) {
+
+	if key, ok := d[service+"/"+string(usage)]; ok {
+		return key, nil
+	}
+
+	return nil, ifcrypto.ErrServiceKeyNotFound
+
+}
+
+type testOrder struct {
+	ID     string `json:"id"`
+	Amount int    `json:"amount"`
+}
+
+func TestPayloadCodecSealAndOpen(t *testing.T) {
+
+	ordersSigner, err := NewED25519PrivateKey("orders-sign", ifcrypto.KeyUsageSign)
+	require.NoError(t, err)
+
+	billingSigner, err := NewECDSAPrivateKey("billing-sign", 256, ifcrypto.KeyUsageSign)
+	require.NoError(t, err)
+
+	billingX25519, err := NewX25519PrivateKey("billing-x25519", ifcrypto.KeyUsageKeyAgreement)
+	require.NoError(t, err)
+
+	billingEC, err := NewECDSAPrivateKey("billing-ec", 384, ifcrypto.KeyUsageKeyAgreement)
+	require.NoError(t, err)
+
+	for _, decryptionKey := range []ifcrypto.KeyPair{billingX25519, billingEC} {
+
+		directory := testDirectory{
+			"orders/verify":         ordersSigner.GetPublic(),
+			"billing/verify":        billingSigner.GetPublic(),
+			"billing/key-agreement": decryptionKey.GetPublic(),
+		}
+
+		orders := NewPayloadCodec("orders", ordersSigner, directory, WithPayloadRecipient("billing"))
+		billing := NewPayloadCodec("billing", billingSigner, directory, WithPayloadDecryptionKey(decryptionKey))
+
+		data, err := orders.Marshal(&testOrder{ID: "o-1", Amount: 42})
+		require.NoError(t, err)
+		assert.NotContains(t, string(data), "o-1")
+
+		var order testOrder
+		sender, err := billing.Open(context.Background(), data, &order)
+		require.NoError(t, err)
+		assert.Equal(t, "orders", sender)
+		assert.Equal(t, testOrder{ID: "o-1", Amount: 42}, order)
+
+		var sealed SealedPayload
+		require.NoError(t, json.Unmarshal(data, &sealed))
+		sealed.Recipient = ""
+		tampered, _ := json.Marshal(&sealed)
+
+		_, err = billing.Open(context.Background(), tampered, &order)
+		assert.True(t, errors.Is(err, ErrPayloadSignature))
+
+	}
+
+}
+
+func TestPayloadCodecHTTPHandler(t *testing.T) {
+
+	ordersSigner, err := NewED25519PrivateKey("orders-sign", ifcrypto.KeyUsageSign)
+	require.NoError(t, err)
+
+	billingKey, err := NewX25519PrivateKey("billing-x25519", ifcrypto.KeyUsageKeyAgreement)
+	require.NoError(t, err)
+
+	directory := testDirectory{
+		"orders/verify":         ordersSigner.GetPublic(),
+		"billing/key-agreement": billingKey.GetPublic(),
+	}
+
+	orders := NewPayloadCodec("orders", ordersSigner, directory)
+	billing := NewPayloadCodec("billing", nil, directory, WithPayloadDecryptionKey(billingKey))
+
+	server := httptest.NewServer(billing.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+
+		body, _ := ioutil.ReadAll(r.Body)
+		w.Write([]byte(PayloadSender(r.Context()) + ":" + string(body)))
+
+	})))
+	defer server.Close()
+
+	req, err := orders.NewRequest(context.Background(), http.MethodPost, server.URL, "billing", &testOrder{ID: "o-2"})
+	require.NoError(t, err)
+
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+
+	body, _ := ioutil.ReadAll(resp.Body)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, `orders:{"id":"o-2","amount":0}`, string(body))
+
+}
+
+func TestPayloadCodecPropagatesCryptoContext(t *testing.T) {
+
+	ordersSigner, err := NewED25519PrivateKey("orders/sign", ifcrypto.KeyUsageSign)
+	require.NoError(t, err)
+
+	directory := testDirectory{"orders/verify": ordersSigner.GetPublic()}
+
+	orders := NewPayloadCodec("orders", ordersSigner, directory, WithPayloadEncryption(false))
+	billing := NewPayloadCodec("billing", nil, directory, WithPayloadEncryption(false))
+
+	events := []PolicyEvent{}
+
+	guard := NewPolicyGuard(PolicyEngineFunc(
+		func(c context.Context, input ifcrypto.PolicyInput) (ifcrypto.PolicyDecision, error) {
+			return ifcrypto.PolicyDecision{Allow: input.Principal == "alice"}, nil
+		}),
+		WithPolicyAuditor(func(event PolicyEvent) { events = append(events, event) }),
+	)
+
+	c := ifcrypto.WithCryptoContext(context.Background(), ifcrypto.CryptoContext{
+		Tenant: "acme", RequestID: "r-1", Principal: "alice",
+	})
+
+	require.NoError(t, guard.AuthorizeKey(c, ifcrypto.PolicyOperationSign, ordersSigner, nil))
+
+	data, err := orders.Seal(c, "billing", &testOrder{ID: "o-3"})
+	require.NoError(t, err)
+
+	var order testOrder
+	opened, err := billing.OpenContext(context.Background(), data, &order)
+	require.NoError(t, err)
+
+	cc := ifcrypto.CryptoContextFrom(opened)
+	assert.Equal(t, "orders", PayloadSender(opened))
+	assert.Equal(t, "acme", cc.Tenant)
+	assert.Equal(t, "r-1", cc.RequestID)
+	assert.Equal(t, "alice", ifcrypto.PolicyPrincipal(opened))
+	assert.Equal(t, []ifcrypto.CryptoDecision{
+		{Operation: ifcrypto.PolicyOperationSign, KeyID: "orders/sign", Allow: true},
+	}, cc.Decisions)
+
+	require.Len(t, events, 1)
+	assert.Equal(t, "alice", events[0].Input.Principal)
+	assert.Equal(t, cc.Decisions, events[0].Context.Decisions)
+
+	// The context is signed
+	var sealed SealedPayload
+	require.NoError(t, json.Unmarshal(data, &sealed))
+	sealed.Context.Tenant = "other"
+	tampered, _ := json.Marshal(&sealed)
+
+	_, err = billing.OpenContext(context.Background(), tampered, &order)
+	assert.True(t, errors.Is(err, ErrPayloadSignature))
+
+}
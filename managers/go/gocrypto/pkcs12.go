@@ -0,0 +1,37 @@
+package gocrypto
+
+import (
+	"crypto/x509"
+	"fmt"
+
+	"github.com/mariotoffia/goservice/interfaces/ifcrypto"
+	"github.com/mariotoffia/goservice/utils/cryptoutils"
+)
+
+// KeyPairToPKCS12 encodes the _key_ and its certificate _chain_, leaf first, as a _PKCS #12_
+// file protected by _password_.
+//
+// Since the private key leaves the process the _key_ must have `ifcrypto.KeyUsageExport`,
+// otherwise `ErrKeyNotExportable` is returned.
+func KeyPairToPKCS12(
+	key ifcrypto.KeyPair,
+	chain []*x509.Certificate,
+	password string,
+	opts ...cryptoutils.PKCS12Option,
+) ([]byte, error) {
+
+	if !hasKeyUsage(key, ifcrypto.KeyUsageExport) {
+		return nil, fmt.Errorf("%w: %s", ErrKeyNotExportable, key.GetID())
+	}
+
+	if key.IsRemoteKey() {
+		return nil, fmt.Errorf("%w: %s is a remote key", ErrKeyNotExportable, key.GetID())
+	}
+
+	if len(chain) == 0 {
+		return nil, fmt.Errorf("PKCS#12 requires the certificate of key %s", key.GetID())
+	}
+
+	return cryptoutils.EncodePKCS12(key.GetKey(), chain[0], chain[1:], password, opts...)
+
+}
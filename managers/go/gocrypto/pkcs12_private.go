@@ -0,0 +1,69 @@
+//go:build !verifyonly
+// +build !verifyonly
+
+package gocrypto
+
+import (
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/x509"
+	"fmt"
+
+	"github.com/mariotoffia/goservice/interfaces/ifcrypto"
+	"github.com/mariotoffia/goservice/utils/cryptoutils"
+)
+
+// NewKeyPairFromPKCS12 loads the key and certificate chain of a _PKCS #12_ (_.p12_, _.pfx_)
+// file protected by _password_.
+//
+// The returned chain starts with the certificate of the key, if present in the file, followed
+// by the issuers towards the root. The _usage_ is set on the key pair.
+func NewKeyPairFromPKCS12(
+	id string,
+	data []byte,
+	password string,
+	usage ...ifcrypto.KeyUsage,
+) (ifcrypto.KeyPair, []*x509.Certificate, error) {
+
+	if IsVerifyOnly() {
+		return nil, nil, ErrVerifyOnly
+	}
+
+	bundle, err := cryptoutils.DecodePKCS12(data, password)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var pair ifcrypto.KeyPair
+
+	switch key := bundle.PrivateKey.(type) {
+	case *rsa.PrivateKey:
+		pair = NewRSAPrivateKeyFromKey(id, key, usage...)
+	case *ecdsa.PrivateKey:
+		pair = NewECDSAPrivateKeyFromKey(id, key, usage...)
+	case ed25519.PrivateKey:
+		pair = NewED25519PrivateKeyFromKey(id, key, usage...)
+	default:
+		return nil, nil, fmt.Errorf("unsupported PKCS#12 private key: %T", bundle.PrivateKey)
+	}
+
+	if err := checkLoadedKey(pair.GetPublic().GetKey()); err != nil {
+		return nil, nil, err
+	}
+
+	chain := bundle.CACerts
+
+	if bundle.Certificate != nil {
+
+		chain = append([]*x509.Certificate{bundle.Certificate}, bundle.CACerts...)
+
+		if sorted, err := cryptoutils.SortCertificateChain(chain); err == nil {
+			chain = sorted
+		}
+
+	}
+
+	return pair, chain, nil
+
+}
@@ -0,0 +1,51 @@
+//go:build !verifyonly
+// +build !verifyonly
+
+package gocrypto
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"errors"
+	"testing"
+
+	"github.com/mariotoffia/goservice/interfaces/ifcrypto"
+	"github.com/mariotoffia/goservice/utils/cryptoutils"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPKCS12KeyPairRoundTrip(t *testing.T) {
+
+	key, err := NewRSAPrivateKey("rsa", 2048, ifcrypto.KeyUsageSign, ifcrypto.KeyUsageExport)
+	require.NoError(t, err)
+
+	tmpl, _, err := cryptoutils.NewCertificateTemplate(
+		cryptoutils.CertificateProfileCodeSigning,
+		cryptoutils.CertificateSubject{Subject: pkix.Name{CommonName: "rsa"}},
+	)
+	require.NoError(t, err)
+
+	cert, err := cryptoutils.CreateSelfSignedCertificate(rand.Reader, tmpl, key)
+	require.NoError(t, err)
+
+	data, err := KeyPairToPKCS12(key, []*x509.Certificate{cert}, "secret")
+	require.NoError(t, err)
+
+	pair, chain, err := NewKeyPairFromPKCS12("loaded", data, "secret", ifcrypto.KeyUsageSign)
+	require.NoError(t, err)
+
+	assert.Equal(t, "loaded", pair.GetID())
+	require.Len(t, chain, 1)
+	assert.Equal(t, cert.Raw, chain[0].Raw)
+
+	sig, err := SignMessage(pair.(crypto.Signer), []byte("hello"), 0)
+	require.NoError(t, err)
+	require.NoError(t, VerifyMessage(key.GetPublic(), []byte("hello"), sig, 0))
+
+	_, err = KeyPairToPKCS12(pair, chain, "secret")
+	assert.True(t, errors.Is(err, ErrKeyNotExportable))
+
+}
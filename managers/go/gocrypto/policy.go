@@ -0,0 +1,232 @@
+package gocrypto
+
+import (
+	"context"
+	"crypto"
+	"crypto/x509"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/mariotoffia/goservice/interfaces/ifcrypto"
+)
+
+// PolicyEngineFunc adapts a function to the `ifcrypto.PolicyEngine` interface.
+type PolicyEngineFunc func(c context.Context, input ifcrypto.PolicyInput) (ifcrypto.PolicyDecision, error)
+
+// Evaluate implements the `ifcrypto.PolicyEngine` interface.
+func (f PolicyEngineFunc) Evaluate(c context.Context, input ifcrypto.PolicyInput) (ifcrypto.PolicyDecision, error) {
+	return f(c, input)
+}
+
+// PolicyEvent is a audit event emitted by the `PolicyGuard` for each evaluation.
+type PolicyEvent struct {
+	Time     time.Time
+	Input    ifcrypto.PolicyInput
+	Decision ifcrypto.PolicyDecision
+	// Err is set when the engine failed, the operation is then denied.
+	Err error
+	// Context is the `ifcrypto.CryptoContext` of the evaluation, including this decision.
+	Context ifcrypto.CryptoContext
+}
+
+// PolicyGuardOption configures a `PolicyGuard`.
+type PolicyGuardOption func(g *PolicyGuard)
+
+// WithPolicyAuditor sets a function that receives all audit events.
+func WithPolicyAuditor(auditor func(event PolicyEvent)) PolicyGuardOption {
+	return func(g *PolicyGuard) {
+		g.auditor = auditor
+	}
+}
+
+// PolicyGuard evaluates a `ifcrypto.PolicyEngine` before key operations and certificate
+// issuance. It fails closed, i.e. a engine error denies the operation.
+type PolicyGuard struct {
+	engine  ifcrypto.PolicyEngine
+	auditor func(event PolicyEvent)
+}
+
+// NewPolicyGuard creates a new `PolicyGuard` that asks _engine_.
+func NewPolicyGuard(engine ifcrypto.PolicyEngine, opts ...PolicyGuardOption) *PolicyGuard {
+
+	g := &PolicyGuard{engine: engine, auditor: func(PolicyEvent) {}}
+
+	for _, opt := range opts {
+		opt(g)
+	}
+
+	return g
+
+}
+
+// Authorize evaluates the _input_. The _Principal_ is taken from _c_, see
+// `ifcrypto.WithPolicyPrincipal`, unless already set. The decision is recorded in the
+// `ifcrypto.CryptoContext` of _c_, if any.
+//
+// It returns a error wrapping `ifcrypto.ErrPolicyDenied` when denied.
+func (g *PolicyGuard) Authorize(c context.Context, input ifcrypto.PolicyInput) error {
+
+	if input.Principal == "" {
+		input.Principal = ifcrypto.PolicyPrincipal(c)
+	}
+
+	decision, err := g.engine.Evaluate(c, input)
+
+	record := ifcrypto.CryptoDecision{
+		Operation: input.Operation,
+		KeyID:     input.KeyID,
+		Allow:     decision.Allow && err == nil,
+		Reason:    decision.Reason,
+	}
+
+	if err != nil {
+		record.Reason = err.Error()
+	}
+
+	ifcrypto.RecordCryptoDecision(c, record)
+
+	g.auditor(PolicyEvent{
+		Time:     time.Now().UTC(),
+		Input:    input,
+		Decision: decision,
+		Err:      err,
+		Context:  ifcrypto.CryptoContextFrom(c),
+	})
+
+	if err != nil {
+		return fmt.Errorf("%w: %s %s: %v", ifcrypto.ErrPolicyDenied, input.Operation, input.KeyID, err)
+	}
+
+	if !decision.Allow {
+
+		if decision.Reason != "" {
+			return fmt.Errorf("%w: %s %s: %s", ifcrypto.ErrPolicyDenied, input.Operation, input.KeyID, decision.Reason)
+		}
+
+		return fmt.Errorf("%w: %s %s", ifcrypto.ErrPolicyDenied, input.Operation, input.KeyID)
+
+	}
+
+	return nil
+
+}
+
+// AuthorizeKey evaluates _op_ on _key_ with optional _attributes_.
+func (g *PolicyGuard) AuthorizeKey(
+	c context.Context,
+	op ifcrypto.PolicyOperation,
+	key ifcrypto.Key,
+	attributes map[string]string,
+) error {
+
+	return g.Authorize(c, ifcrypto.PolicyInput{
+		Operation:  op,
+		KeyID:      key.GetID(),
+		KeyType:    key.GetKeyType(),
+		KeyUsage:   key.GetKeyUsage(),
+		Attributes: attributes,
+	})
+
+}
+
+// AuthorizeIssuance evaluates the issuance of _tmpl_ by _issuer_.
+func (g *PolicyGuard) AuthorizeIssuance(c context.Context, issuer, tmpl *x509.Certificate) error {
+
+	cert := &ifcrypto.PolicyCertificate{
+		Subject:  tmpl.Subject.String(),
+		DNSNames: tmpl.DNSNames,
+		Emails:   tmpl.EmailAddresses,
+		Issuer:   issuer.Subject.String(),
+		Validity: int64(tmpl.NotAfter.Sub(tmpl.NotBefore) / time.Second),
+		IsCA:     tmpl.IsCA,
+	}
+
+	for _, ip := range tmpl.IPAddresses {
+		cert.IPAddresses = append(cert.IPAddresses, ip.String())
+	}
+
+	for _, uri := range tmpl.URIs {
+		cert.URIs = append(cert.URIs, uri.String())
+	}
+
+	return g.Authorize(c, ifcrypto.PolicyInput{
+		Operation:   ifcrypto.PolicyOperationIssueCertificate,
+		Certificate: cert,
+	})
+
+}
+
+// Signer returns a `crypto.Signer` that authorizes each sign operation, in the scope of _c_,
+// before it is forwarded to _key_. The _key_ must implement `crypto.Signer`.
+func (g *PolicyGuard) Signer(c context.Context, key ifcrypto.KeyPair) (crypto.Signer, error) {
+
+	signer, ok := key.(crypto.Signer)
+	if !ok {
+		return nil, fmt.Errorf("key %s is not a crypto.Signer", key.GetID())
+	}
+
+	return &policySigner{c: c, guard: g, key: key, signer: signer}, nil
+
+}
+
+// Decrypter returns a `crypto.Decrypter` that authorizes each decrypt operation, in the scope
+// of _c_, before it is forwarded to _key_. The _key_ must implement `crypto.Decrypter`.
+func (g *PolicyGuard) Decrypter(c context.Context, key ifcrypto.KeyPair) (crypto.Decrypter, error) {
+
+	decrypter, ok := key.(crypto.Decrypter)
+	if !ok {
+		return nil, fmt.Errorf("key %s is not a crypto.Decrypter", key.GetID())
+	}
+
+	return &policyDecrypter{c: c, guard: g, key: key, decrypter: decrypter}, nil
+
+}
+
+type policySigner struct {
+	c      context.Context
+	guard  *PolicyGuard
+	key    ifcrypto.KeyPair
+	signer crypto.Signer
+}
+
+func (s *policySigner) Public() crypto.PublicKey {
+	return s.signer.Public()
+}
+
+func (s *policySigner) Sign(rand io.Reader, digest []byte, opts crypto.SignerOpts) ([]byte, error) {
+
+	attributes := map[string]string{}
+
+	if opts != nil && opts.HashFunc() != 0 {
+		attributes["hash"] = opts.HashFunc().String()
+	}
+
+	if err := s.guard.AuthorizeKey(s.c, ifcrypto.PolicyOperationSign, s.key, attributes); err != nil {
+		return nil, err
+	}
+
+	return s.signer.Sign(rand, digest, opts)
+
+}
+
+type policyDecrypter struct {
+	c         context.Context
+	guard     *PolicyGuard
+	key       ifcrypto.KeyPair
+	decrypter crypto.Decrypter
+}
+
+func (d *policyDecrypter) Public() crypto.PublicKey {
+	return d.decrypter.Public()
+}
+
+func (d *policyDecrypter) Decrypt(rand io.Reader, msg []byte, opts crypto.DecrypterOpts) ([]byte, error) {
+
+	if err := d.guard.AuthorizeKey(d.c, ifcrypto.PolicyOperationDecrypt, d.key, nil); err != nil {
+		return nil, err
+	}
+
+	return d.decrypter.Decrypt(rand, msg, opts)
+
+}
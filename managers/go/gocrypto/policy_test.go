@@ -0,0 +1,133 @@
+//go:build !verifyonly
+// +build !verifyonly
+
+package gocrypto
+
+import (
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mariotoffia/goservice/interfaces/ifcrypto"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPolicyGuardSigner(t *testing.T) {
+
+	key, err := NewECDSAPrivateKey("orders/signing", 256, ifcrypto.KeyUsageSign)
+	require.NoError(t, err)
+
+	events := []PolicyEvent{}
+
+	guard := NewPolicyGuard(PolicyEngineFunc(
+		func(c context.Context, input ifcrypto.PolicyInput) (ifcrypto.PolicyDecision, error) {
+
+			if input.Principal == "" {
+				return ifcrypto.PolicyDecision{}, errors.New("no principal")
+			}
+
+			if strings.HasPrefix(input.KeyID, input.Principal+"/") {
+				return ifcrypto.PolicyDecision{Allow: true}, nil
+			}
+
+			return ifcrypto.PolicyDecision{Reason: "not owner"}, nil
+
+		}),
+		WithPolicyAuditor(func(event PolicyEvent) { events = append(events, event) }),
+	)
+
+	digest := sha256.Sum256([]byte("message"))
+
+	signer, err := guard.Signer(ifcrypto.WithPolicyPrincipal(context.Background(), "orders"), key)
+	require.NoError(t, err)
+
+	_, err = signer.Sign(rand.Reader, digest[:], crypto.SHA256)
+	require.NoError(t, err)
+
+	signer, err = guard.Signer(ifcrypto.WithPolicyPrincipal(context.Background(), "billing"), key)
+	require.NoError(t, err)
+
+	_, err = signer.Sign(rand.Reader, digest[:], crypto.SHA256)
+	assert.True(t, errors.Is(err, ifcrypto.ErrPolicyDenied))
+	assert.Contains(t, err.Error(), "not owner")
+
+	// engine errors fail closed
+	signer, err = guard.Signer(context.Background(), key)
+	require.NoError(t, err)
+
+	_, err = signer.Sign(rand.Reader, digest[:], crypto.SHA256)
+	assert.True(t, errors.Is(err, ifcrypto.ErrPolicyDenied))
+
+	require.Len(t, events, 3)
+	assert.Equal(t, ifcrypto.PolicyOperationSign, events[0].Input.Operation)
+	assert.Equal(t, "SHA-256", events[0].Input.Attributes["hash"])
+	assert.True(t, events[0].Decision.Allow)
+	assert.Equal(t, "billing", events[1].Input.Principal)
+	assert.Error(t, events[2].Err)
+
+}
+
+func TestOPAPolicyEngine(t *testing.T) {
+
+	results := map[string]string{
+		"/v1/data/bool":      `{"result": true}`,
+		"/v1/data/object":    `{"result": {"allow": false, "reason": "CA certificates are not allowed"}}`,
+		"/v1/data/undefined": `{}`,
+	}
+
+	var received ifcrypto.PolicyInput
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+
+		var body struct {
+			Input ifcrypto.PolicyInput `json:"input"`
+		}
+
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+		received = body.Input
+
+		assert.Equal(t, "Bearer secret", r.Header.Get("Authorization"))
+
+		_, _ = w.Write([]byte(results[r.URL.Path]))
+
+	}))
+	defer server.Close()
+
+	tmpl := &x509.Certificate{
+		Subject:   pkix.Name{CommonName: "intermediate"},
+		NotBefore: time.Now(),
+		NotAfter:  time.Now().Add(time.Hour),
+		IsCA:      true,
+	}
+
+	issuer := &x509.Certificate{Subject: pkix.Name{CommonName: "root"}}
+
+	guard := NewPolicyGuard(NewOPAPolicyEngine(server.URL+"/v1/data/bool", WithOPAToken("secret")))
+	require.NoError(t, guard.AuthorizeIssuance(context.Background(), issuer, tmpl))
+
+	assert.Equal(t, ifcrypto.PolicyOperationIssueCertificate, received.Operation)
+	assert.Equal(t, "CN=intermediate", received.Certificate.Subject)
+	assert.Equal(t, int64(3600), received.Certificate.Validity)
+	assert.True(t, received.Certificate.IsCA)
+
+	guard = NewPolicyGuard(NewOPAPolicyEngine(server.URL+"/v1/data/object", WithOPAToken("secret")))
+	err := guard.AuthorizeIssuance(context.Background(), issuer, tmpl)
+	assert.True(t, errors.Is(err, ifcrypto.ErrPolicyDenied))
+	assert.Contains(t, err.Error(), "CA certificates are not allowed")
+
+	guard = NewPolicyGuard(NewOPAPolicyEngine(server.URL+"/v1/data/undefined", WithOPAToken("secret")))
+	err = guard.AuthorizeIssuance(context.Background(), issuer, tmpl)
+	assert.True(t, errors.Is(err, ifcrypto.ErrPolicyDenied))
+
+}
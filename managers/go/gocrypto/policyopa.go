@@ -0,0 +1,121 @@
+package gocrypto
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/mariotoffia/goservice/interfaces/ifcrypto"
+)
+
+// OPAPolicyEngineOption configures a `OPAPolicyEngine`.
+type OPAPolicyEngineOption func(e *OPAPolicyEngine)
+
+// WithOPAHTTPClient sets the _HTTP_ client, default `http.DefaultClient`.
+func WithOPAHTTPClient(client *http.Client) OPAPolicyEngineOption {
+	return func(e *OPAPolicyEngine) {
+		e.client = client
+	}
+}
+
+// WithOPAToken sets a bearer token sent to the _OPA_ server.
+func WithOPAToken(token string) OPAPolicyEngineOption {
+	return func(e *OPAPolicyEngine) {
+		e.token = token
+	}
+}
+
+// OPAPolicyEngine is a `ifcrypto.PolicyEngine` that queries the _Open Policy Agent_ data _API_.
+//
+// The `ifcrypto.PolicyInput` is posted as _input_ to the rule and the result may either be a
+// boolean or a object with _allow_ and optional _reason_, e.g.
+//
+//	package goservice.crypto
+//
+//	default allow = false
+//
+//	allow {
+//		input.operation == "sign"
+//		startswith(input.key_id, concat("", [input.principal, "/"]))
+//	}
+//
+// An undefined rule, i.e. no _result_, denies the operation.
+type OPAPolicyEngine struct {
+	endpoint string
+	client   *http.Client
+	token    string
+}
+
+// NewOPAPolicyEngine creates a engine that evaluates the rule at _endpoint_, e.g.
+// _http://localhost:8181/v1/data/goservice/crypto/allow_.
+func NewOPAPolicyEngine(endpoint string, opts ...OPAPolicyEngineOption) *OPAPolicyEngine {
+
+	e := &OPAPolicyEngine{endpoint: endpoint, client: http.DefaultClient}
+
+	for _, opt := range opts {
+		opt(e)
+	}
+
+	return e
+
+}
+
+// Evaluate implements the `ifcrypto.PolicyEngine` interface.
+func (e *OPAPolicyEngine) Evaluate(c context.Context, input ifcrypto.PolicyInput) (ifcrypto.PolicyDecision, error) {
+
+	body, err := json.Marshal(map[string]interface{}{"input": input})
+	if err != nil {
+		return ifcrypto.PolicyDecision{}, err
+	}
+
+	req, err := http.NewRequestWithContext(c, http.MethodPost, e.endpoint, bytes.NewReader(body))
+	if err != nil {
+		return ifcrypto.PolicyDecision{}, err
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+
+	if e.token != "" {
+		req.Header.Set("Authorization", "Bearer "+e.token)
+	}
+
+	resp, err := e.client.Do(req)
+	if err != nil {
+		return ifcrypto.PolicyDecision{}, err
+	}
+
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return ifcrypto.PolicyDecision{}, fmt.Errorf("OPA %s returned status %d", e.endpoint, resp.StatusCode)
+	}
+
+	var result struct {
+		Result json.RawMessage `json:"result"`
+	}
+
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return ifcrypto.PolicyDecision{}, err
+	}
+
+	if len(result.Result) == 0 {
+		return ifcrypto.PolicyDecision{Reason: "policy is undefined"}, nil
+	}
+
+	var allow bool
+
+	if err := json.Unmarshal(result.Result, &allow); err == nil {
+		return ifcrypto.PolicyDecision{Allow: allow}, nil
+	}
+
+	var decision ifcrypto.PolicyDecision
+
+	if err := json.Unmarshal(result.Result, &decision); err != nil {
+		return ifcrypto.PolicyDecision{}, fmt.Errorf("unexpected OPA result: %s", result.Result)
+	}
+
+	return decision, nil
+
+}
@@ -0,0 +1,264 @@
+package gocrypto
+
+import (
+	"context"
+	"crypto"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/mariotoffia/goservice/interfaces/ifcrypto"
+)
+
+// ErrPossessionNotProven is returned when a imported key fails, or lacks, its proof-of-possession.
+var ErrPossessionNotProven = fmt.Errorf("proof-of-possession not proven")
+
+// PolicyAttributePossessionVerified is the `ifcrypto.PolicyInput` attribute that is set to
+// _true_ for keys that passed the proof-of-possession, see `ImportedKey.PolicyAttributes`.
+const PolicyAttributePossessionVerified = "pop_verified"
+
+// possessionContext is the domain separation prefix of the message signed as proof.
+const possessionContext = "goservice proof-of-possession v1\x00"
+
+// PossessionProof is the partner signature of a challenge from `KeyImporter.Challenge`.
+type PossessionProof struct {
+	// Challenge is the challenge as returned by `KeyImporter.Challenge`.
+	Challenge []byte `json:"challenge"`
+	// Signature is the signature of `PossessionMessage` as created by `SignMessage`.
+	Signature []byte `json:"signature"`
+	// Hash is the hash used with `SignMessage`, zero is _SHA-256_.
+	Hash crypto.Hash `json:"hash,omitempty"`
+}
+
+// PossessionMessage returns the message that the partner must sign, using `SignMessage`, to
+// prove that it holds the private key of the imported key.
+func PossessionMessage(challenge []byte) []byte {
+	return append([]byte(possessionContext), challenge...)
+}
+
+// ImportedKey is a partner public key accepted by `KeyImporter`.
+type ImportedKey struct {
+	// Key is the imported public key.
+	Key ifcrypto.PublicKey
+	// Certificate is set when imported using `KeyImporter.ImportCertificate`.
+	Certificate *x509.Certificate
+	// PossessionVerified is `true` when the partner proved possession of the private key.
+	PossessionVerified bool
+	// Imported is when the key was imported.
+	Imported time.Time
+}
+
+// PolicyAttributes returns the attributes to pass to `PolicyGuard.AuthorizeKey` such that a
+// policy can require `PolicyAttributePossessionVerified`.
+func (k *ImportedKey) PolicyAttributes() map[string]string {
+
+	return map[string]string{
+		PolicyAttributePossessionVerified: fmt.Sprintf("%t", k.PossessionVerified),
+	}
+
+}
+
+// KeyImporterOption configures a `KeyImporter`.
+type KeyImporterOption func(i *KeyImporter)
+
+// WithImportChallenge sets the key that authenticates challenges and how long a challenge is
+// valid, default is a random key and ten minutes. All instances behind a load balancer must
+// share the _key_ and the replay cache.
+func WithImportChallenge(key []byte, ttl time.Duration) KeyImporterOption {
+	return func(i *KeyImporter) {
+		i.challengeKey = key
+		i.challengeTTL = ttl
+	}
+}
+
+// WithImportReplayCache sets the cache that makes challenges single use, default
+// `NewMemoryReplayCache`.
+func WithImportReplayCache(cache ifcrypto.ReplayCache) KeyImporterOption {
+	return func(i *KeyImporter) {
+		i.replay = cache
+	}
+}
+
+// WithImportRequirePossession rejects imports without a `PossessionProof`. Default is to import
+// such keys as not verified.
+func WithImportRequirePossession() KeyImporterOption {
+	return func(i *KeyImporter) {
+		i.require = true
+	}
+}
+
+// WithImportClock sets the clock, default `time.Now`.
+func WithImportClock(clock func() time.Time) KeyImporterOption {
+	return func(i *KeyImporter) {
+		i.clock = clock
+	}
+}
+
+// KeyImporter imports partner public keys and certificates with a optional proof-of-possession.
+//
+// The flow is that the partner fetches a challenge bound to the key id using `Challenge`, signs
+// the `PossessionMessage` of it with the private key and hands over the public key, or
+// certificate, together with the `PossessionProof`. A challenge is single use.
+type KeyImporter struct {
+	challengeKey []byte
+	challengeTTL time.Duration
+	replay       ifcrypto.ReplayCache
+	require      bool
+	clock        func() time.Time
+}
+
+// NewKeyImporter creates a new `KeyImporter`.
+func NewKeyImporter(opts ...KeyImporterOption) (*KeyImporter, error) {
+
+	i := &KeyImporter{
+		challengeTTL: 10 * time.Minute,
+		clock:        time.Now,
+	}
+
+	for _, opt := range opts {
+		opt(i)
+	}
+
+	if i.challengeKey == nil {
+
+		i.challengeKey = make([]byte, 32)
+
+		if _, err := io.ReadFull(rand.Reader, i.challengeKey); err != nil {
+			return nil, err
+		}
+
+	}
+
+	if i.replay == nil {
+		i.replay = NewMemoryReplayCache(WithReplayCacheClock(i.clock))
+	}
+
+	return i, nil
+
+}
+
+// Challenge returns a new single use challenge for the key with _keyID_.
+//
+// The challenge is stateless, it holds a random nonce, the expiry and a _HMAC_ over both and
+// the _keyID_.
+func (i *KeyImporter) Challenge(c context.Context, keyID string) ([]byte, error) {
+
+	challenge := make([]byte, 24, 24+sha256.Size)
+
+	if _, err := io.ReadFull(rand.Reader, challenge[:16]); err != nil {
+		return nil, err
+	}
+
+	binary.BigEndian.PutUint64(challenge[16:], uint64(i.clock().Add(i.challengeTTL).Unix()))
+
+	return i.mac(keyID, challenge), nil
+
+}
+
+// ImportPublicKey imports the _key_. When _proof_ is `nil` the key is imported as not verified,
+// or rejected if `WithImportRequirePossession` is set.
+func (i *KeyImporter) ImportPublicKey(
+	c context.Context,
+	key ifcrypto.PublicKey,
+	proof *PossessionProof,
+) (*ImportedKey, error) {
+
+	imported := &ImportedKey{Key: key, Imported: i.clock().UTC()}
+
+	if proof == nil {
+
+		if i.require {
+			return nil, fmt.Errorf("%w: %s has no proof", ErrPossessionNotProven, key.GetID())
+		}
+
+		return imported, nil
+
+	}
+
+	if err := i.verify(c, key, proof); err != nil {
+		return nil, err
+	}
+
+	imported.PossessionVerified = true
+
+	return imported, nil
+
+}
+
+// ImportCertificate imports the public key of _cert_ as _keyID_ with _usage_, see
+// `ImportPublicKey`. The _cert_ must be validated by the caller.
+func (i *KeyImporter) ImportCertificate(
+	c context.Context,
+	keyID string,
+	cert *x509.Certificate,
+	proof *PossessionProof,
+	usage ...ifcrypto.KeyUsage,
+) (*ImportedKey, error) {
+
+	key, err := publicKeyFromDER(keyID, cert.RawSubjectPublicKeyInfo, usage...)
+	if err != nil {
+		return nil, err
+	}
+
+	imported, err := i.ImportPublicKey(c, key, proof)
+	if err != nil {
+		return nil, err
+	}
+
+	imported.Certificate = cert
+
+	return imported, nil
+
+}
+
+// verify verifies the _proof_ of _key_ and marks the challenge as used.
+func (i *KeyImporter) verify(c context.Context, key ifcrypto.PublicKey, proof *PossessionProof) error {
+
+	challenge := proof.Challenge
+
+	if len(challenge) != 24+sha256.Size ||
+		!hmac.Equal(i.mac(key.GetID(), challenge[:24:24])[24:], challenge[24:]) {
+
+		return fmt.Errorf("%w: invalid challenge", ErrPossessionNotProven)
+	}
+
+	expires := time.Unix(int64(binary.BigEndian.Uint64(challenge[16:24])), 0)
+
+	if !i.clock().Before(expires) {
+		return fmt.Errorf("%w: challenge expired", ErrPossessionNotProven)
+	}
+
+	if err := VerifyMessage(key, PossessionMessage(challenge), proof.Signature, proof.Hash); err != nil {
+		return fmt.Errorf("%w: %v", ErrPossessionNotProven, err)
+	}
+
+	replayed, err := i.replay.Use(c, "gocrypto-pop", hex.EncodeToString(challenge[:16]), expires)
+	if err != nil {
+		return err
+	}
+
+	if replayed {
+		return fmt.Errorf("%w: challenge already used", ErrPossessionNotProven)
+	}
+
+	return nil
+
+}
+
+// mac appends the _HMAC_ of _keyID_ and _challenge_ to _challenge_.
+func (i *KeyImporter) mac(keyID string, challenge []byte) []byte {
+
+	mac := hmac.New(sha256.New, i.challengeKey)
+	mac.Write([]byte(keyID))
+	mac.Write([]byte{0})
+	mac.Write(challenge)
+
+	return mac.Sum(challenge)
+
+}
@@ -0,0 +1,115 @@
+//go:build !verifyonly
+// +build !verifyonly
+
+package gocrypto
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/x509/pkix"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/mariotoffia/goservice/interfaces/ifcrypto"
+	"github.com/mariotoffia/goservice/utils/cryptoutils"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestKeyImporterVerifiesPossession(t *testing.T) {
+
+	ctx := context.Background()
+
+	importer, err := NewKeyImporter()
+	require.NoError(t, err)
+
+	partner, err := NewECDSAPrivateKey("partner", 256, ifcrypto.KeyUsageSign)
+	require.NoError(t, err)
+
+	challenge, err := importer.Challenge(ctx, "partner")
+	require.NoError(t, err)
+
+	signature, err := SignMessage(partner, PossessionMessage(challenge), 0)
+	require.NoError(t, err)
+
+	proof := &PossessionProof{Challenge: challenge, Signature: signature}
+
+	imported, err := importer.ImportPublicKey(ctx, partner.GetPublic(), proof)
+	require.NoError(t, err)
+	assert.True(t, imported.PossessionVerified)
+	assert.Equal(t, "true", imported.PolicyAttributes()[PolicyAttributePossessionVerified])
+
+	// Single use.
+	_, err = importer.ImportPublicKey(ctx, partner.GetPublic(), proof)
+	assert.True(t, errors.Is(err, ErrPossessionNotProven))
+
+	// Challenge is bound to the key id.
+	other, err := NewECDSAPrivateKey("other", 256, ifcrypto.KeyUsageSign)
+	require.NoError(t, err)
+
+	challenge, err = importer.Challenge(ctx, "partner")
+	require.NoError(t, err)
+
+	signature, err = SignMessage(other, PossessionMessage(challenge), 0)
+	require.NoError(t, err)
+
+	_, err = importer.ImportPublicKey(ctx, other.GetPublic(), &PossessionProof{Challenge: challenge, Signature: signature})
+	assert.True(t, errors.Is(err, ErrPossessionNotProven))
+
+	// Wrong signer for the key.
+	_, err = importer.ImportPublicKey(ctx, partner.GetPublic(), &PossessionProof{Challenge: challenge, Signature: signature})
+	assert.True(t, errors.Is(err, ErrPossessionNotProven))
+
+}
+
+func TestKeyImporterCertificateAndRequirePossession(t *testing.T) {
+
+	ctx := context.Background()
+	now := time.Now()
+
+	importer, err := NewKeyImporter(
+		WithImportRequirePossession(),
+		WithImportClock(func() time.Time { return now }),
+	)
+	require.NoError(t, err)
+
+	partner, err := NewED25519PrivateKey("partner", ifcrypto.KeyUsageSign)
+	require.NoError(t, err)
+
+	tmpl, _, err := cryptoutils.NewCertificateTemplate(
+		cryptoutils.CertificateProfileCodeSigning,
+		cryptoutils.CertificateSubject{Subject: pkix.Name{CommonName: "partner"}},
+	)
+	require.NoError(t, err)
+
+	cert, err := cryptoutils.CreateSelfSignedCertificate(rand.Reader, tmpl, partner)
+	require.NoError(t, err)
+
+	_, err = importer.ImportCertificate(ctx, "partner", cert, nil, ifcrypto.KeyUsageVerify)
+	assert.True(t, errors.Is(err, ErrPossessionNotProven))
+
+	challenge, err := importer.Challenge(ctx, "partner")
+	require.NoError(t, err)
+
+	signature, err := SignMessage(partner, PossessionMessage(challenge), 0)
+	require.NoError(t, err)
+
+	now = now.Add(time.Hour)
+
+	_, err = importer.ImportCertificate(ctx, "partner", cert, &PossessionProof{Challenge: challenge, Signature: signature}, ifcrypto.KeyUsageVerify)
+	assert.True(t, errors.Is(err, ErrPossessionNotProven))
+
+	challenge, err = importer.Challenge(ctx, "partner")
+	require.NoError(t, err)
+
+	signature, err = SignMessage(partner, PossessionMessage(challenge), 0)
+	require.NoError(t, err)
+
+	imported, err := importer.ImportCertificate(ctx, "partner", cert, &PossessionProof{Challenge: challenge, Signature: signature}, ifcrypto.KeyUsageVerify)
+	require.NoError(t, err)
+	assert.True(t, imported.PossessionVerified)
+	assert.Equal(t, cert, imported.Certificate)
+	assert.Equal(t, "partner", imported.Key.GetID())
+
+}
@@ -0,0 +1,174 @@
+package gocrypto
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/pem"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+
+	"github.com/mariotoffia/goservice/interfaces/ifcrypto"
+)
+
+// publicPEM caches the _PEM_ encoding of a public key. Public keys are immutable, hence
+// `WriteTo` encodes once and then writes the same bytes without encoding or allocating.
+type publicPEM struct {
+	once sync.Once
+	data []byte
+	err  error
+}
+
+// writeTo writes the, possibly cached, _PEM_ of _key_ onto _w_.
+func (p *publicPEM) writeTo(w io.Writer, key ifcrypto.PEMWriter) (int64, error) {
+
+	p.once.Do(func() {
+
+		var buf bytes.Buffer
+
+		p.err = key.PEMWrite(&buf, true)
+		p.data = buf.Bytes()
+
+	})
+
+	if p.err != nil {
+		return 0, p.err
+	}
+
+	n, err := w.Write(p.data)
+
+	return int64(n), err
+
+}
+
+// PublicKeySet is a set of public keys that is streamed as concatenated _PEM_ blocks, e.g. by
+// a export endpoint.
+//
+// It implements `io.WriterTo` and `io.ReaderFrom`.
+type PublicKeySet struct {
+	Keys []ifcrypto.PublicKey
+	// Usage is the usage of keys read by `ReadFrom`.
+	Usage []ifcrypto.KeyUsage
+	// KeyID returns the id of a key read by `ReadFrom` from its _PKIX_ _DER_. If `nil` the id is
+	// the hex encoded _SHA-256_ of the _DER_.
+	KeyID func(der []byte) string
+}
+
+// WriteTo implements the `io.WriterTo` interface and writes all keys as _PEM_ onto _w_.
+//
+// Keys that implements `io.WriterTo`, as all public keys in this package do, are written
+// from their cached encoding.
+func (s *PublicKeySet) WriteTo(w io.Writer) (int64, error) {
+
+	var total int64
+
+	for _, key := range s.Keys {
+
+		var (
+			n   int64
+			err error
+		)
+
+		switch k := key.(type) {
+		case io.WriterTo:
+			n, err = k.WriteTo(w)
+		case ifcrypto.PEMWriter:
+
+			cw := &countingWriter{w: w}
+			err = k.PEMWrite(cw, true)
+			n = cw.n
+
+		default:
+			err = fmt.Errorf("key %s can not be written as PEM", key.GetID())
+		}
+
+		total += n
+
+		if err != nil {
+			return total, err
+		}
+
+	}
+
+	return total, nil
+
+}
+
+// ReadFrom implements the `io.ReaderFrom` interface and appends all _PUBLIC KEY_ blocks read
+// from _r_ to the set. Other blocks are skipped.
+//
+// A `*bytes.Buffer` is consumed in place without copying.
+func (s *PublicKeySet) ReadFrom(r io.Reader) (int64, error) {
+
+	var data []byte
+
+	if buf, ok := r.(*bytes.Buffer); ok {
+		data = buf.Next(buf.Len())
+	} else {
+
+		var buf bytes.Buffer
+
+		if _, err := buf.ReadFrom(r); err != nil {
+			return int64(buf.Len()), err
+		}
+
+		data = buf.Bytes()
+
+	}
+
+	keyID := s.KeyID
+	if keyID == nil {
+		keyID = publicKeySetID
+	}
+
+	for rest := data; ; {
+
+		var block *pem.Block
+
+		block, rest = pem.Decode(rest)
+		if block == nil {
+			break
+		}
+
+		if !strings.HasSuffix(block.Type, "PUBLIC KEY") {
+			continue
+		}
+
+		key, err := publicKeyFromDER(keyID(block.Bytes), block.Bytes, s.Usage...)
+		if err != nil {
+			return int64(len(data)), err
+		}
+
+		s.Keys = append(s.Keys, key)
+
+	}
+
+	return int64(len(data)), nil
+
+}
+
+// publicKeySetID is the default `PublicKeySet.KeyID`.
+func publicKeySetID(der []byte) string {
+
+	sum := sha256.Sum256(der)
+
+	return hex.EncodeToString(sum[:])
+
+}
+
+// countingWriter counts the bytes written onto _w_.
+type countingWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+
+	n, err := c.w.Write(p)
+	c.n += int64(n)
+
+	return n, err
+
+}
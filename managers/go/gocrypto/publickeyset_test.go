@@ -0,0 +1,67 @@
+//go:build !verifyonly
+// +build !verifyonly
+
+package gocrypto
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/mariotoffia/goservice/interfaces/ifcrypto"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPublicKeySetWriteToReadFrom(t *testing.T) {
+
+	rsaKey, err := NewRSAPrivateKey("rsa", 2048, ifcrypto.KeyUsageSign)
+	require.NoError(t, err)
+
+	ecKey, err := NewECDSAPrivateKey("ec", 256, ifcrypto.KeyUsageSign)
+	require.NoError(t, err)
+
+	edKey, err := NewED25519PrivateKey("ed", ifcrypto.KeyUsageSign)
+	require.NoError(t, err)
+
+	xKey, err := NewX25519PrivateKey("x", ifcrypto.KeyUsageKeyAgreement)
+	require.NoError(t, err)
+
+	set := &PublicKeySet{Keys: []ifcrypto.PublicKey{
+		rsaKey.GetPublic(), ecKey.GetPublic(), edKey.GetPublic(), xKey.GetPublic(),
+	}}
+
+	var buf bytes.Buffer
+
+	n, err := set.WriteTo(&buf)
+	require.NoError(t, err)
+	assert.Equal(t, int64(buf.Len()), n)
+
+	// The second write uses the cached encodings and must be identical.
+	var again bytes.Buffer
+
+	_, err = set.WriteTo(&again)
+	require.NoError(t, err)
+	assert.Equal(t, buf.Bytes(), again.Bytes())
+
+	allocs := testing.AllocsPerRun(10, func() {
+
+		again.Reset()
+		_, _ = set.WriteTo(&again)
+
+	})
+	assert.Zero(t, allocs)
+
+	read := &PublicKeySet{Usage: []ifcrypto.KeyUsage{ifcrypto.KeyUsageVerify}}
+
+	_, err = read.ReadFrom(&buf)
+	require.NoError(t, err)
+	require.Len(t, read.Keys, 4)
+
+	assert.Equal(t, ifcrypto.KeyTypeRsa, read.Keys[0].GetKeyType())
+	assert.Equal(t, ifcrypto.KeyTypeEccNistP, read.Keys[1].GetKeyType())
+	assert.Equal(t, ifcrypto.KeyTypeEd25519, read.Keys[2].GetKeyType())
+	assert.Equal(t, ifcrypto.KeyTypeX25519, read.Keys[3].GetKeyType())
+	assert.Len(t, read.Keys[0].GetID(), 64)
+	assert.Equal(t, []ifcrypto.KeyUsage{ifcrypto.KeyUsageVerify}, read.Keys[0].GetKeyUsage())
+
+}
@@ -0,0 +1,174 @@
+package gocrypto
+
+import (
+	"container/heap"
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// MemoryReplayCacheOption configures a `MemoryReplayCache`.
+type MemoryReplayCacheOption func(rc *MemoryReplayCache)
+
+// WithReplayCacheMaxEntries limits the number of remembered identifiers (default 100 000).
+//
+// When the cache is full, and no identifier has expired, `Use` returns an error rather
+// than forgetting an identifier that still may be replayed.
+func WithReplayCacheMaxEntries(max int) MemoryReplayCacheOption {
+	return func(rc *MemoryReplayCache) {
+		if max > 0 {
+			rc.maxEntries = max
+		}
+	}
+}
+
+// WithReplayCacheMaxTTL caps how long an identifier is remembered (default 24h). An identifier
+// with a later expiry is remembered for _ttl_ only, hence verifiers must not accept tokens
+// with a longer lifetime than this.
+func WithReplayCacheMaxTTL(ttl time.Duration) MemoryReplayCacheOption {
+	return func(rc *MemoryReplayCache) {
+		if ttl > 0 {
+			rc.maxTTL = ttl
+		}
+	}
+}
+
+// WithReplayCacheClock sets the clock used to expire identifiers.
+func WithReplayCacheClock(now func() time.Time) MemoryReplayCacheOption {
+	return func(rc *MemoryReplayCache) {
+		rc.now = now
+	}
+}
+
+// MemoryReplayCache is an in process implementation of `ifcrypto.ReplayCache`.
+//
+// It only detects replays within a single process, use a shared backend when a token may be
+// presented to several instances of a service.
+//
+// Identifiers are kept in expiry order, hence expired identifiers are forgotten in logarithmic
+// time as they expire, also when the cache is full.
+type MemoryReplayCache struct {
+	mu         sync.Mutex
+	entries    map[string]time.Time
+	expiry     replayExpiry
+	maxEntries int
+	maxTTL     time.Duration
+	now        func() time.Time
+}
+
+// replayEntry is a remembered identifier.
+type replayEntry struct {
+	key       string
+	expiresAt time.Time
+}
+
+// replayExpiry is a `heap.Interface` of the remembered identifiers, earliest expiry first.
+type replayExpiry []replayEntry
+
+func (e replayExpiry) Len() int {
+	return len(e)
+}
+
+func (e replayExpiry) Less(i, j int) bool {
+	return e[i].expiresAt.Before(e[j].expiresAt)
+}
+
+func (e replayExpiry) Swap(i, j int) {
+	e[i], e[j] = e[j], e[i]
+}
+
+func (e *replayExpiry) Push(x interface{}) {
+	*e = append(*e, x.(replayEntry))
+}
+
+func (e *replayExpiry) Pop() interface{} {
+
+	old := *e
+	entry := old[len(old)-1]
+	*e = old[:len(old)-1]
+
+	return entry
+
+}
+
+// NewMemoryReplayCache creates a new `MemoryReplayCache`.
+func NewMemoryReplayCache(opts ...MemoryReplayCacheOption) *MemoryReplayCache {
+
+	rc := &MemoryReplayCache{
+		entries:    map[string]time.Time{},
+		maxEntries: 100000,
+		maxTTL:     24 * time.Hour,
+		now:        time.Now,
+	}
+
+	for _, opt := range opts {
+		opt(rc)
+	}
+
+	return rc
+
+}
+
+// Use implements the `ifcrypto.ReplayCache` interface.
+func (rc *MemoryReplayCache) Use(c context.Context, scope, id string, expiresAt time.Time) (bool, error) {
+
+	if id == "" {
+		return false, fmt.Errorf("empty replay identifier")
+	}
+
+	now := rc.now()
+
+	if max := now.Add(rc.maxTTL); expiresAt.After(max) {
+		expiresAt = max
+	}
+
+	// Length prefix the scope so that scope and id can not be shifted into each other
+	key := fmt.Sprintf("%d:%s%s", len(scope), scope, id)
+
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+
+	rc.expire(now)
+
+	if _, ok := rc.entries[key]; ok {
+		return true, nil
+	}
+
+	if !now.Before(expiresAt) {
+		// Already expired, the token is rejected anyway
+		return false, nil
+	}
+
+	if len(rc.entries) >= rc.maxEntries {
+		return false, fmt.Errorf("replay cache is full (%d entries)", rc.maxEntries)
+	}
+
+	rc.entries[key] = expiresAt
+	heap.Push(&rc.expiry, replayEntry{key: key, expiresAt: expiresAt})
+
+	return false, nil
+
+}
+
+// Len returns the number of remembered identifiers, including expired not yet forgotten.
+func (rc *MemoryReplayCache) Len() int {
+
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+
+	return len(rc.entries)
+
+}
+
+// expire forgets the expired identifiers, must be called with the lock held.
+func (rc *MemoryReplayCache) expire(now time.Time) {
+
+	for len(rc.expiry) > 0 && !now.Before(rc.expiry[0].expiresAt) {
+
+		entry := heap.Pop(&rc.expiry).(replayEntry)
+		delete(rc.entries, entry.key)
+
+	}
+
+}
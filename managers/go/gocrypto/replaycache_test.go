@@ -0,0 +1,78 @@
+package gocrypto
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/mariotoffia/goservice/interfaces/ifcrypto"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMemoryReplayCacheDetectsReplayUntilExpiry(t *testing.T) {
+
+	ctx := context.Background()
+	now := time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	var cache ifcrypto.ReplayCache = NewMemoryReplayCache(
+		WithReplayCacheMaxEntries(2),
+		WithReplayCacheClock(func() time.Time { return now }),
+	)
+
+	replayed, err := cache.Use(ctx, "issuer", "jti-1", now.Add(time.Minute))
+	require.NoError(t, err)
+	assert.False(t, replayed)
+
+	replayed, err = cache.Use(ctx, "issuer", "jti-1", now.Add(time.Minute))
+	require.NoError(t, err)
+	assert.True(t, replayed)
+
+	replayed, err = cache.Use(ctx, "other", "jti-1", now.Add(time.Minute))
+	require.NoError(t, err)
+	assert.False(t, replayed, "scopes are separate")
+
+	_, err = cache.Use(ctx, "issuer", "jti-2", now.Add(time.Minute))
+	assert.Error(t, err, "cache is full")
+
+	now = now.Add(2 * time.Minute)
+
+	replayed, err = cache.Use(ctx, "issuer", "jti-1", now.Add(time.Minute))
+	require.NoError(t, err)
+	assert.False(t, replayed, "expired identifiers are forgotten")
+
+}
+
+func TestMemoryReplayCacheForgetsInExpiryOrder(t *testing.T) {
+
+	ctx := context.Background()
+	now := time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	cache := NewMemoryReplayCache(
+		WithReplayCacheMaxEntries(3),
+		WithReplayCacheClock(func() time.Time { return now }),
+	)
+
+	for i, ttl := range []time.Duration{3 * time.Minute, time.Minute, 2 * time.Minute} {
+
+		_, err := cache.Use(ctx, "issuer", fmt.Sprintf("jti-%d", i), now.Add(ttl))
+		require.NoError(t, err)
+
+	}
+
+	_, err := cache.Use(ctx, "issuer", "jti-3", now.Add(time.Minute))
+	assert.Error(t, err, "cache is full")
+
+	now = now.Add(90 * time.Second)
+
+	replayed, err := cache.Use(ctx, "issuer", "jti-3", now.Add(time.Minute))
+	require.NoError(t, err)
+	assert.False(t, replayed)
+	assert.Equal(t, 3, cache.Len(), "only jti-1 expired")
+
+	replayed, err = cache.Use(ctx, "issuer", "jti-0", now.Add(time.Minute))
+	require.NoError(t, err)
+	assert.True(t, replayed)
+
+}
@@ -0,0 +1,205 @@
+package gocrypto
+
+import (
+	"context"
+	"crypto/aes"
+	"fmt"
+	"io"
+
+	"github.com/mariotoffia/goservice/interfaces/ifcrypto"
+	"github.com/mariotoffia/goservice/utils/cryptoutils"
+)
+
+// WrappedKey is a data encryption key, wrapped with _AES_ key wrap, e.g. as stored by the `Shredder`.
+type WrappedKey struct {
+	ID      string
+	Wrapped []byte
+}
+
+// WrappedKeyIterator iterates wrapped keys. `Next` returns `io.EOF` when done.
+//
+// To be able to resume a interrupted run, the iterator must return the keys in a stable order.
+type WrappedKeyIterator interface {
+	Next(c context.Context) (*WrappedKey, error)
+}
+
+// WrappedKeySink receives a re-wrapped key.
+type WrappedKeySink func(c context.Context, id string, wrapped []byte) error
+
+// RewrapProgress is the progress of a `KEKRewrapper` run.
+type RewrapProgress struct {
+	// Rewrapped is the number of keys that have been re-wrapped.
+	Rewrapped int `json:"rewrapped"`
+	// AlreadyRewrapped is the number of keys that already were wrapped with the new _KEK_.
+	AlreadyRewrapped int `json:"already_rewrapped"`
+	// Skipped is the number of keys skipped when resuming.
+	Skipped int `json:"skipped"`
+	// LastID is the id of the last processed key, use it to resume.
+	LastID string `json:"last_id"`
+}
+
+// KEKRewrapperOption configures a `KEKRewrapper`.
+type KEKRewrapperOption func(r *KEKRewrapper)
+
+// WithRewrapProgress sets a function that is called with the progress every _every_ keys and
+// when the run ends.
+func WithRewrapProgress(every int, progress func(p RewrapProgress)) KEKRewrapperOption {
+	return func(r *KEKRewrapper) {
+		r.every = every
+		r.progress = progress
+	}
+}
+
+// WithRewrapResumeAfter skips all keys up to, and including, the key with _id_. Use the
+// _LastID_ of a previous `RewrapProgress` to resume a interrupted run.
+func WithRewrapResumeAfter(id string) KEKRewrapperOption {
+	return func(r *KEKRewrapper) {
+		r.resumeAfter = id
+	}
+}
+
+// KEKRewrapper re-wraps data encryption keys from a old key encryption key to a new one.
+//
+// Only the wrapped keys are touched, data encrypted with the data keys is left as is. A key
+// that already unwraps with the new _KEK_ is not re-wrapped, hence a interrupted run may
+// also be restarted from the beginning.
+type KEKRewrapper struct {
+	oldKEK      []byte
+	newKEK      []byte
+	every       int
+	progress    func(p RewrapProgress)
+	resumeAfter string
+}
+
+// NewKEKRewrapper creates a new `KEKRewrapper` from _oldKEK_ to _newKEK_.
+func NewKEKRewrapper(oldKEK, newKEK []byte, opts ...KEKRewrapperOption) (*KEKRewrapper, error) {
+
+	for _, kek := range [][]byte{oldKEK, newKEK} {
+
+		if _, err := aes.NewCipher(kek); err != nil {
+			return nil, err
+		}
+
+	}
+
+	r := &KEKRewrapper{
+		oldKEK:   append([]byte{}, oldKEK...),
+		newKEK:   append([]byte{}, newKEK...),
+		progress: func(RewrapProgress) {},
+	}
+
+	for _, opt := range opts {
+		opt(r)
+	}
+
+	return r, nil
+
+}
+
+// Run re-wraps all keys from _source_ and writes them to _sink_.
+//
+// It stops on the first error and returns the progress so far. The _LastID_ is then the
+// last key that was successfully processed.
+func (r *KEKRewrapper) Run(c context.Context, source WrappedKeyIterator, sink WrappedKeySink) (RewrapProgress, error) {
+
+	progress := RewrapProgress{}
+	resuming := r.resumeAfter != ""
+	processed := 0
+
+	for {
+
+		if err := c.Err(); err != nil {
+			r.progress(progress)
+			return progress, err
+		}
+
+		key, err := source.Next(c)
+
+		if err == io.EOF {
+			break
+		}
+
+		if err != nil {
+			r.progress(progress)
+			return progress, err
+		}
+
+		if resuming {
+
+			progress.Skipped++
+
+			if key.ID == r.resumeAfter {
+				resuming = false
+			}
+
+			continue
+
+		}
+
+		if _, err := cryptoutils.AESKeyUnwrap(r.newKEK, key.Wrapped); err == nil {
+
+			progress.AlreadyRewrapped++
+
+		} else {
+
+			if err := r.rewrap(c, key, sink); err != nil {
+				r.progress(progress)
+				return progress, err
+			}
+
+			progress.Rewrapped++
+
+		}
+
+		progress.LastID = key.ID
+		processed++
+
+		if r.every > 0 && processed%r.every == 0 {
+			r.progress(progress)
+		}
+
+	}
+
+	if resuming {
+		return progress, fmt.Errorf("resume key %s not found", r.resumeAfter)
+	}
+
+	r.progress(progress)
+
+	return progress, nil
+
+}
+
+// rewrap unwraps _key_ with the old _KEK_, wraps it with the new and writes it to _sink_.
+func (r *KEKRewrapper) rewrap(c context.Context, key *WrappedKey, sink WrappedKeySink) error {
+
+	dek, err := cryptoutils.AESKeyUnwrap(r.oldKEK, key.Wrapped)
+	if err != nil {
+		return fmt.Errorf("failed to unwrap key %s: %w", key.ID, err)
+	}
+
+	wrapped, err := cryptoutils.AESKeyWrap(r.newKEK, dek)
+	if err != nil {
+		return err
+	}
+
+	return sink(c, key.ID, wrapped)
+
+}
+
+// DataKeyStoreSink returns a `WrappedKeySink` that replaces the keys in _store_.
+//
+// Since `ifcrypto.DataKeyStore` do not support update, each key is deleted and put.
+func DataKeyStoreSink(store ifcrypto.DataKeyStore) WrappedKeySink {
+
+	return func(c context.Context, id string, wrapped []byte) error {
+
+		if err := store.Delete(c, id); err != nil {
+			return err
+		}
+
+		return store.Put(c, id, wrapped)
+
+	}
+
+}
@@ -0,0 +1,117 @@
+package gocrypto
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// sliceIterator is a `WrappedKeyIterator` over a slice.
+type sliceIterator struct {
+	keys []WrappedKey
+	pos  int
+}
+
+func (it *sliceIterator) Next(c context.Context) (*WrappedKey, error) {
+
+	if it.pos >= len(it.keys) {
+		return nil, io.EOF
+	}
+
+	it.pos++
+
+	return &it.keys[it.pos-1], nil
+
+}
+
+func TestKEKRewrapperRewrapsShredderKeys(t *testing.T) {
+
+	ctx := context.Background()
+	oldKEK := bytes.Repeat([]byte{1}, 32)
+	newKEK := bytes.Repeat([]byte{2}, 32)
+
+	store := NewMemoryDataKeyStore()
+
+	shredder, err := NewShredder(store, oldKEK)
+	require.NoError(t, err)
+
+	ciphertexts := map[string][]byte{}
+	keys := []WrappedKey{}
+
+	for i := 0; i < 5; i++ {
+
+		id := fmt.Sprintf("user-%d", i)
+
+		ciphertexts[id], err = shredder.Encrypt(ctx, id, []byte("secret of "+id))
+		require.NoError(t, err)
+
+		wrapped, err := store.Get(ctx, id)
+		require.NoError(t, err)
+
+		keys = append(keys, WrappedKey{ID: id, Wrapped: wrapped})
+
+	}
+
+	failing := func(c context.Context, id string, wrapped []byte) error {
+
+		if id == "user-3" {
+			return fmt.Errorf("store unavailable")
+		}
+
+		return DataKeyStoreSink(store)(c, id, wrapped)
+
+	}
+
+	rewrapper, err := NewKEKRewrapper(oldKEK, newKEK)
+	require.NoError(t, err)
+
+	progress, err := rewrapper.Run(ctx, &sliceIterator{keys: keys}, failing)
+	require.Error(t, err)
+	assert.Equal(t, 3, progress.Rewrapped)
+	assert.Equal(t, "user-2", progress.LastID)
+
+	reports := []RewrapProgress{}
+
+	rewrapper, err = NewKEKRewrapper(oldKEK, newKEK,
+		WithRewrapResumeAfter(progress.LastID),
+		WithRewrapProgress(1, func(p RewrapProgress) { reports = append(reports, p) }),
+	)
+
+	require.NoError(t, err)
+
+	progress, err = rewrapper.Run(ctx, &sliceIterator{keys: keys}, DataKeyStoreSink(store))
+	require.NoError(t, err)
+	assert.Equal(t, RewrapProgress{Rewrapped: 2, Skipped: 3, LastID: "user-4"}, progress)
+	assert.Len(t, reports, 3)
+
+	rewrapped, err := NewShredder(store, newKEK)
+	require.NoError(t, err)
+
+	for id, ciphertext := range ciphertexts {
+
+		plain, err := rewrapped.Decrypt(ctx, id, ciphertext)
+		require.NoError(t, err)
+		assert.Equal(t, "secret of "+id, string(plain))
+
+	}
+
+	rewrapper, err = NewKEKRewrapper(oldKEK, newKEK)
+	require.NoError(t, err)
+
+	// The iterator still returns the keys wrapped with the old KEK, the store has the new ones
+	current := []WrappedKey{}
+	for _, k := range keys {
+		wrapped, _ := store.Get(ctx, k.ID)
+		current = append(current, WrappedKey{ID: k.ID, Wrapped: wrapped})
+	}
+
+	progress, err = rewrapper.Run(ctx, &sliceIterator{keys: current}, DataKeyStoreSink(store))
+	require.NoError(t, err)
+	assert.Equal(t, 5, progress.AlreadyRewrapped)
+
+}
@@ -2,7 +2,6 @@ package gocrypto
 
 import (
 	"crypto"
-	"crypto/rand"
 	"crypto/rsa"
 	"crypto/x509"
 	"encoding/pem"
@@ -13,6 +12,10 @@ import (
 	"github.com/mariotoffia/goservice/utils/cryptoutils"
 )
 
+// errRSANilSignerOpts is returned when signing or verifying without _opts_, the hash, or the
+// _PSS_ options, selects the _RSA_ algorithm.
+var errRSANilSignerOpts = fmt.Errorf("rsa requires signer opts")
+
 // RSAPrivateKey implements the `ifcrypto.KeyPair` interface for a `*rsa.PrivateKey`.
 type RSAPrivateKey struct {
 	KeyBase
@@ -33,7 +36,7 @@ func NewRSAPrivateKeyFromKey(
 		KeyBase: KeyBase{
 			id:      id,
 			keyType: ifcrypto.KeyTypeRsa,
-			keySize: key.Size(),
+			keySize: key.N.BitLen(),
 			usage:   usage,
 			chiper:  []ifcrypto.Chipher{},
 		},
@@ -43,94 +46,65 @@ func NewRSAPrivateKeyFromKey(
 
 }
 
-// NewRSAPrivateKeyFromPEM initializes a new `*rsa.PrivateKey` from the underlying _PEM_ block.
-func NewRSAPrivateKeyFromPEM(
-	block pem.Block,
+// NewRSAPrivateKeyFromDER initializes a new `RSAPrivateKey` from _PKCS #8_ or _PKCS #1_ _DER_.
+func NewRSAPrivateKeyFromDER(
+	der []byte,
 	id string,
 	usage ...ifcrypto.KeyUsage,
 ) (*RSAPrivateKey, error) {
 
-	if block.Type == "PRIVATE KEY" {
-		key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
-
-		if err != nil {
-			return nil, err
-		}
-
-		if rsakey, ok := key.(*rsa.PrivateKey); ok {
-
-			return NewRSAPrivateKeyFromKey(id, rsakey, usage...), nil
-
-		}
-
-		return nil, fmt.Errorf("not a rsa.PrivateKey: %T", key)
-
-	}
-
-	if block.Type == "RSA PRIVATE KEY" {
-
-		key, err := x509.ParsePKCS1PrivateKey(block.Bytes)
-		if err != nil {
-			return nil, err
-		}
+	return NewRSAPrivateKeyFromPEM(privateKeyDERBlock(der, "RSA PRIVATE KEY"), id, usage...)
 
-		return NewRSAPrivateKeyFromKey(id, key, usage...), nil
+}
 
-	}
+// SignMessage implements the `ifcrypto.MessageSigner` interface, see `SignMessage`.
+func (r *RSAPrivateKey) SignMessage(msg []byte, hash crypto.Hash) ([]byte, error) {
+	return SignMessage(r, msg, hash)
+}
 
-	return nil, fmt.Errorf("unsupported PEM block: %s", block.Type)
+// Public implements the `crypto.Signer` _interface_.
+func (r *RSAPrivateKey) Public() crypto.PublicKey {
+	return &r.key.PublicKey
+}
 
+// GetPublic returns the public portion of the key
+func (r *RSAPrivateKey) GetPublic() ifcrypto.PublicKey {
+	return r.public
 }
 
-// NewRSAPrivateKey generates a new `RSAPrivateKey` using the `rand.Reader` as entropy.
-func NewRSAPrivateKey(id string, bits int, usage ...ifcrypto.KeyUsage) (*RSAPrivateKey, error) {
+// PEMWrite will write the key onto _w_.
+//
+// If private key, and _public_ is `true`, it will in addition write the public portion as well.
+func (r *RSAPrivateKey) PEMWrite(w io.Writer, public bool) error {
 
-	key, err := rsa.GenerateKey(rand.Reader, bits)
-	if err != nil {
-		return nil, err
-	}
+	return cryptoutils.RSAPrivateKeyToPEM(w, r.key, public)
 
-	return NewRSAPrivateKeyFromKey(id, key, usage...), nil
 }
 
-// Sign implements the `crypto.Signer` _interface_.If opts is a
-// *PSSOptions then the PSS algorithm will be used, otherwise
-// PKCS #1 v1.5 will be used.
-func (r *RSAPrivateKey) Sign(
-	rand io.Reader,
-	digest []byte,
-	opts crypto.SignerOpts,
-) ([]byte, error) {
+// PEMWriteEncrypted implements the `ifcrypto.EncryptedPEMWriter` interface.
+func (r *RSAPrivateKey) PEMWriteEncrypted(w io.Writer, passphrase []byte) error {
 
-	return r.key.Sign(rand, digest, opts)
+	return pemWriteEncrypted(w, r, passphrase)
 
 }
 
-// Decrypt implements the `crypto.Decrypter` _interface_. If opts is `nil` or of type
-// `*PKCS1v15DecryptOptions` then _PKCS #1 v1.5_ decryption is performed. Otherwise
-// opts must have type `*OAEPOptions` and _OAEP_ decryption is done.
-func (r *RSAPrivateKey) Decrypt(
-	rand io.Reader,
-	msg []byte,
-	opts crypto.DecrypterOpts,
-) (plaintext []byte, err error) {
+// DERWrite implements the `ifcrypto.DERWriter` interface, the key is written as _PKCS #8_.
+func (r *RSAPrivateKey) DERWrite(w io.Writer) error {
 
-	return r.key.Decrypt(rand, msg, opts)
+	return privateKeyDERWrite(w, r)
 
 }
 
-// GetPublic returns the public portion of the key
-func (r *RSAPrivateKey) GetPublic() ifcrypto.PublicKey {
-	return r.public
+// ToJWK implements the `JWKMarshaler` interface. The _JWK_ includes the private key, use
+// `GetPublic` to get the public _JWK_.
+func (r *RSAPrivateKey) ToJWK() (*cryptoutils.JSONWebKey, error) {
+	return keyToJWK(r)
 }
 
-// PEMWrite will write the key onto _w_.
-//
-// If private key, and _public_ is `true`, it will in addition write the public portion as well.
-func (r *RSAPrivateKey) PEMWrite(w io.Writer, public bool) error {
-
-	return cryptoutils.RSAPrivateKeyToPEM(w, r.key, public)
-
+// Fingerprint implements the `Fingerprinter` interface and returns the fingerprint of the
+// public key, see `Fingerprint`.
+func (r *RSAPrivateKey) Fingerprint(opts ...FingerprintOption) (string, error) {
+	return Fingerprint(r, opts...)
 }
 
 // GetKey gets the underlying key, if any.
@@ -167,6 +141,7 @@ func (r *RSAPrivateKey) IsRemoteKey() bool {
 type RSAPublicKey struct {
 	KeyBase
 	key *rsa.PublicKey
+	pem publicPEM
 }
 
 // NewRSAPublicKeyFromKey creates a instance based on a existing public key.
@@ -180,7 +155,7 @@ func NewRSAPublicKeyFromKey(
 		KeyBase: KeyBase{
 			id:      id,
 			keyType: ifcrypto.KeyTypeRsa,
-			keySize: key.Size(),
+			keySize: key.N.BitLen(),
 			usage:   usage,
 		},
 		key: key,
@@ -205,6 +180,10 @@ func NewRSAPublicKeyFromPEM(
 
 		if rsakey, ok := key.(*rsa.PublicKey); ok {
 
+			if err := checkLoadedKey(rsakey); err != nil {
+				return nil, err
+			}
+
 			return NewRSAPublicKeyFromKey(id, rsakey, usage...), nil
 
 		}
@@ -217,6 +196,50 @@ func NewRSAPublicKeyFromPEM(
 
 }
 
+// NewRSAPublicKeyFromDER initializes a new `RSAPublicKey` from _PKIX_ _DER_.
+func NewRSAPublicKeyFromDER(
+	der []byte,
+	id string,
+	usage ...ifcrypto.KeyUsage,
+) (*RSAPublicKey, error) {
+
+	return NewRSAPublicKeyFromPEM(pem.Block{Type: "PUBLIC KEY", Bytes: der}, id, usage...)
+
+}
+
+// VerifyMessage implements the `ifcrypto.MessageVerifier` interface, see `VerifyMessage`.
+func (r *RSAPublicKey) VerifyMessage(msg, signature []byte, hash crypto.Hash) error {
+	return VerifyMessage(r, msg, signature, hash)
+}
+
+// Verify implements the `ifcrypto.SignatureVerifier` interface. If _opts_ is a
+// `*rsa.PSSOptions` the _PSS_ algorithm is used, otherwise _PKCS #1 v1.5_.
+func (r *RSAPublicKey) Verify(digest, signature []byte, opts crypto.SignerOpts) error {
+
+	if opts == nil {
+		return errRSANilSignerOpts
+	}
+
+	if err := CheckAlgorithm(ifcrypto.PolicyOperationVerify, r, signerHash(opts)); err != nil {
+		return err
+	}
+
+	var err error
+
+	if pss, ok := opts.(*rsa.PSSOptions); ok {
+		err = rsa.VerifyPSS(r.key, pss.Hash, digest, signature, pss)
+	} else {
+		err = rsa.VerifyPKCS1v15(r.key, opts.HashFunc(), digest, signature)
+	}
+
+	if err != nil {
+		return ifcrypto.ErrInvalidSignature
+	}
+
+	return nil
+
+}
+
 // PEMWrite will write the key onto _w_.
 //
 // Since this is a public key, it will ignore the _public_ parameter.
@@ -226,6 +249,29 @@ func (r *RSAPublicKey) PEMWrite(w io.Writer, public bool) error {
 
 }
 
+// DERWrite implements the `ifcrypto.DERWriter` interface, the key is written as _PKIX_.
+func (r *RSAPublicKey) DERWrite(w io.Writer) error {
+
+	return publicKeyDERWrite(w, r)
+
+}
+
+// WriteTo implements the `io.WriterTo` interface and writes the key as _PEM_ onto _w_. The
+// encoding is cached, hence streaming many keys do not encode them over and over again.
+func (r *RSAPublicKey) WriteTo(w io.Writer) (int64, error) {
+	return r.pem.writeTo(w, r)
+}
+
+// ToJWK implements the `JWKMarshaler` interface.
+func (r *RSAPublicKey) ToJWK() (*cryptoutils.JSONWebKey, error) {
+	return keyToJWK(r)
+}
+
+// Fingerprint implements the `Fingerprinter` interface, see `Fingerprint`.
+func (r *RSAPublicKey) Fingerprint(opts ...FingerprintOption) (string, error) {
+	return Fingerprint(r, opts...)
+}
+
 // GetKey gets the underlying key, if any.
 //
 // Some keys are remote and not possible to fetch. In such situations the function returns a remote id,
@@ -245,7 +291,7 @@ func (r *RSAPublicKey) IsSymmetric() bool {
 //
 // If `KeyTypeSymmetric` it will return `true` since all symmetric keys are considered as private.
 func (r *RSAPublicKey) IsPrivate() bool {
-	return true
+	return false
 }
 
 // IsRemoteKey returns `true` if the key is not present in current process memory.
@@ -0,0 +1,132 @@
+//go:build !verifyonly
+// +build !verifyonly
+
+package gocrypto
+
+import (
+	"crypto"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"io"
+
+	"github.com/mariotoffia/goservice/interfaces/ifcrypto"
+)
+
+// NewRSAPrivateKeyFromPEM initializes a new `*rsa.PrivateKey` from the underlying _PEM_ block.
+func NewRSAPrivateKeyFromPEM(
+	block pem.Block,
+	id string,
+	usage ...ifcrypto.KeyUsage,
+) (*RSAPrivateKey, error) {
+
+	if IsVerifyOnly() {
+		return nil, ErrVerifyOnly
+	}
+
+	if block.Type == "PRIVATE KEY" {
+		key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+
+		if err != nil {
+			return nil, err
+		}
+
+		if rsakey, ok := key.(*rsa.PrivateKey); ok {
+
+			if err := checkLoadedKey(&rsakey.PublicKey); err != nil {
+				return nil, err
+			}
+
+			return NewRSAPrivateKeyFromKey(id, rsakey, usage...), nil
+
+		}
+
+		return nil, fmt.Errorf("not a rsa.PrivateKey: %T", key)
+
+	}
+
+	if block.Type == "RSA PRIVATE KEY" {
+
+		key, err := x509.ParsePKCS1PrivateKey(block.Bytes)
+		if err != nil {
+			return nil, err
+		}
+
+		if err := checkLoadedKey(&key.PublicKey); err != nil {
+			return nil, err
+		}
+
+		return NewRSAPrivateKeyFromKey(id, key, usage...), nil
+
+	}
+
+	return nil, fmt.Errorf("unsupported PEM block: %s", block.Type)
+
+}
+
+// NewRSAPrivateKey generates a new `RSAPrivateKey` using the `rand.Reader`, or the `EntropyGuard` reader, as entropy.
+func NewRSAPrivateKey(id string, bits int, usage ...ifcrypto.KeyUsage) (*RSAPrivateKey, error) {
+
+	if IsVerifyOnly() {
+		return nil, ErrVerifyOnly
+	}
+
+	reader, err := keyGenerationReader()
+	if err != nil {
+		return nil, err
+	}
+
+	key, err := rsa.GenerateKey(reader, bits)
+	if err != nil {
+		return nil, err
+	}
+
+	return NewRSAPrivateKeyFromKey(id, key, usage...), nil
+}
+
+// Sign implements the `crypto.Signer` _interface_.If opts is a
+// *PSSOptions then the PSS algorithm will be used, otherwise
+// PKCS #1 v1.5 will be used.
+func (r *RSAPrivateKey) Sign(
+	rand io.Reader,
+	digest []byte,
+	opts crypto.SignerOpts,
+) ([]byte, error) {
+
+	if IsVerifyOnly() {
+		return nil, ErrVerifyOnly
+	}
+
+	if opts == nil {
+		return nil, errRSANilSignerOpts
+	}
+
+	if err := CheckAlgorithm(ifcrypto.PolicyOperationSign, r, signerHash(opts)); err != nil {
+		return nil, err
+	}
+
+	return r.key.Sign(rand, digest, opts)
+
+}
+
+// Decrypt implements the `crypto.Decrypter` _interface_. If opts is `nil` or of type
+// `*PKCS1v15DecryptOptions` then _PKCS #1 v1.5_ decryption is performed. Otherwise
+// opts must have type `*OAEPOptions` and _OAEP_ decryption is done.
+func (r *RSAPrivateKey) Decrypt(
+	rand io.Reader,
+	msg []byte,
+	opts crypto.DecrypterOpts,
+) (plaintext []byte, err error) {
+
+	if IsVerifyOnly() {
+		return nil, ErrVerifyOnly
+	}
+
+	if err := CheckAlgorithm(ifcrypto.PolicyOperationDecrypt, r, decrypterHash(opts)); err != nil {
+		return nil, err
+	}
+
+	return r.key.Decrypt(rand, msg, opts)
+
+}
@@ -0,0 +1,299 @@
+package gocrypto
+
+import (
+	"context"
+	"crypto"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/mariotoffia/goservice/interfaces/ifcrypto"
+)
+
+// ErrKeyOutOfScope is returned when a `ScopedKeys` view do not permit the key or operation.
+var ErrKeyOutOfScope = fmt.Errorf("key out of scope")
+
+// KeyLookup returns the key with _id_, e.g. from a keystore.
+type KeyLookup func(c context.Context, id string) (ifcrypto.Key, error)
+
+// KeyScope is what a `ScopedKeys` view permits. A empty scope permits nothing.
+type KeyScope struct {
+	// KeyIDs is the permitted key ids. A id that ends with _*_ permits all ids with that prefix.
+	KeyIDs []string
+	// Usages, when set, only permits keys that have at least one of the usages.
+	Usages []ifcrypto.KeyUsage
+	// Operations is the permitted operations, `ifcrypto.PolicyOperationSign`,
+	// `ifcrypto.PolicyOperationDecrypt` and `ifcrypto.PolicyOperationKeyAgreement`. Public
+	// keys of keys in scope are always accessible.
+	Operations []ifcrypto.PolicyOperation
+}
+
+// permits returns `nil` if _key_ and _op_ is permitted, _op_ is empty for public key access.
+func (s *KeyScope) permits(key ifcrypto.Key, op ifcrypto.PolicyOperation) error {
+
+	id := key.GetID()
+
+	if err := s.permitsID(id); err != nil {
+		return err
+	}
+
+	if len(s.Usages) > 0 {
+
+		permitted := false
+
+		for _, usage := range s.Usages {
+
+			if hasKeyUsage(key, usage) {
+				permitted = true
+				break
+			}
+
+		}
+
+		if !permitted {
+			return fmt.Errorf("%w: %s has no permitted usage", ErrKeyOutOfScope, id)
+		}
+
+	}
+
+	if op == "" {
+		return nil
+	}
+
+	for _, scoped := range s.Operations {
+
+		if scoped == op {
+			return nil
+		}
+
+	}
+
+	return fmt.Errorf("%w: %s %s", ErrKeyOutOfScope, op, id)
+
+}
+
+// permitsID returns `nil` if the _KeyIDs_ permits _id_.
+func (s *KeyScope) permitsID(id string) error {
+
+	for _, scoped := range s.KeyIDs {
+
+		if scoped == id || (strings.HasSuffix(scoped, "*") && strings.HasPrefix(id, scoped[:len(scoped)-1])) {
+			return nil
+		}
+
+	}
+
+	return fmt.Errorf("%w: %s", ErrKeyOutOfScope, id)
+
+}
+
+// ScopeEvent is a audit event emitted by `ScopedKeys` for each denied access.
+type ScopeEvent struct {
+	Time      time.Time
+	Scope     string
+	KeyID     string
+	Operation ifcrypto.PolicyOperation
+	Err       error
+	// Context is the `ifcrypto.CryptoContext` of the access.
+	Context ifcrypto.CryptoContext
+}
+
+// ScopedKeysOption configures a `ScopedKeys`.
+type ScopedKeysOption func(s *ScopedKeys)
+
+// WithScopeAuditor sets a function that receives all denied accesses.
+func WithScopeAuditor(auditor func(event ScopeEvent)) ScopedKeysOption {
+	return func(s *ScopedKeys) {
+		s.auditor = auditor
+	}
+}
+
+// ScopedKeys is a restricted view of a keystore that is handed to a component.
+//
+// The view never hands out a `ifcrypto.Key`, only the public key and wrappers that performs
+// the permitted operations. Hence a token signing component that gets a view of the token keys
+// can not reach the private key material or any other key, e.g. the database encryption keys.
+type ScopedKeys struct {
+	name    string
+	lookup  KeyLookup
+	scope   KeyScope
+	parent  *ScopedKeys
+	auditor func(event ScopeEvent)
+}
+
+// NewScopedKeys creates a new `ScopedKeys` view, named _name_ in audit events, that resolves
+// keys using _lookup_ and permits what _scope_ permits.
+func NewScopedKeys(name string, lookup KeyLookup, scope KeyScope, opts ...ScopedKeysOption) *ScopedKeys {
+
+	s := &ScopedKeys{name: name, lookup: lookup, scope: scope, auditor: func(ScopeEvent) {}}
+
+	for _, opt := range opts {
+		opt(s)
+	}
+
+	return s
+
+}
+
+// Restrict creates a sub view that permits what both this view and _scope_ permits, e.g. to
+// hand a sub component a narrower view.
+func (s *ScopedKeys) Restrict(name string, scope KeyScope) *ScopedKeys {
+
+	return &ScopedKeys{
+		name:    name,
+		lookup:  s.lookup,
+		scope:   scope,
+		parent:  s,
+		auditor: s.auditor,
+	}
+
+}
+
+// PublicKey returns the public key of the asymmetric key with _id_.
+func (s *ScopedKeys) PublicKey(c context.Context, id string) (ifcrypto.PublicKey, error) {
+
+	key, err := s.key(c, id, "")
+	if err != nil {
+		return nil, err
+	}
+
+	if key.IsSymmetric() {
+		return nil, fmt.Errorf("key %s is symmetric and has no public key", id)
+	}
+
+	if pair, ok := key.(ifcrypto.KeyPair); ok && key.IsPrivate() {
+		return pair.GetPublic(), nil
+	}
+
+	public, ok := key.(ifcrypto.PublicKey)
+	if !ok || key.IsPrivate() {
+		return nil, fmt.Errorf("key %s has no public key", id)
+	}
+
+	return public, nil
+
+}
+
+// Signer returns a `crypto.Signer` of the key with _id_. The key must have
+// `ifcrypto.KeyUsageSign`.
+func (s *ScopedKeys) Signer(c context.Context, id string) (crypto.Signer, error) {
+
+	key, err := s.key(c, id, ifcrypto.PolicyOperationSign)
+	if err != nil {
+		return nil, err
+	}
+
+	signer, ok := key.(crypto.Signer)
+	if !ok || !hasKeyUsage(key, ifcrypto.KeyUsageSign) {
+		return nil, fmt.Errorf("key %s can not sign", id)
+	}
+
+	return &scopedSigner{signer: signer}, nil
+
+}
+
+// Decrypter returns a `crypto.Decrypter` of the key with _id_. The key must have
+// `ifcrypto.KeyUsageDecrypt`.
+func (s *ScopedKeys) Decrypter(c context.Context, id string) (crypto.Decrypter, error) {
+
+	key, err := s.key(c, id, ifcrypto.PolicyOperationDecrypt)
+	if err != nil {
+		return nil, err
+	}
+
+	decrypter, ok := key.(crypto.Decrypter)
+	if !ok || !hasKeyUsage(key, ifcrypto.KeyUsageDecrypt) {
+		return nil, fmt.Errorf("key %s can not decrypt", id)
+	}
+
+	return &scopedDecrypter{decrypter: decrypter}, nil
+
+}
+
+// DeriveSharedSecret derives the shared secret of the key with _id_ and _peer_. The key must
+// have `ifcrypto.KeyUsageKeyAgreement`.
+func (s *ScopedKeys) DeriveSharedSecret(c context.Context, id string, peer ifcrypto.PublicKey) ([]byte, error) {
+
+	key, err := s.key(c, id, ifcrypto.PolicyOperationKeyAgreement)
+	if err != nil {
+		return nil, err
+	}
+
+	agreement, ok := key.(ifcrypto.KeyAgreement)
+	if !ok || !hasKeyUsage(key, ifcrypto.KeyUsageKeyAgreement) {
+		return nil, fmt.Errorf("key %s can not do key agreement", id)
+	}
+
+	return agreement.DeriveSharedSecret(peer)
+
+}
+
+// key resolves _id_ and checks that it, and _op_, is permitted by this view and all parents.
+func (s *ScopedKeys) key(c context.Context, id string, op ifcrypto.PolicyOperation) (ifcrypto.Key, error) {
+
+	// Check the id before the lookup so that out of scope keys are never loaded.
+	for view := s; view != nil; view = view.parent {
+
+		if err := view.scope.permitsID(id); err != nil {
+			s.audit(c, id, op, err)
+			return nil, err
+		}
+
+	}
+
+	key, err := s.lookup(c, id)
+	if err != nil {
+		return nil, err
+	}
+
+	for view := s; view != nil; view = view.parent {
+
+		if err := view.scope.permits(key, op); err != nil {
+			s.audit(c, id, op, err)
+			return nil, err
+		}
+
+	}
+
+	return key, nil
+
+}
+
+// audit emits a `ScopeEvent`.
+func (s *ScopedKeys) audit(c context.Context, id string, op ifcrypto.PolicyOperation, err error) {
+
+	s.auditor(ScopeEvent{
+		Time:      time.Now().UTC(),
+		Scope:     s.name,
+		KeyID:     id,
+		Operation: op,
+		Err:       err,
+		Context:   ifcrypto.CryptoContextFrom(c),
+	})
+
+}
+
+type scopedSigner struct {
+	signer crypto.Signer
+}
+
+func (s *scopedSigner) Public() crypto.PublicKey {
+	return s.signer.Public()
+}
+
+func (s *scopedSigner) Sign(rand io.Reader, digest []byte, opts crypto.SignerOpts) ([]byte, error) {
+	return s.signer.Sign(rand, digest, opts)
+}
+
+type scopedDecrypter struct {
+	decrypter crypto.Decrypter
+}
+
+func (d *scopedDecrypter) Public() crypto.PublicKey {
+	return d.decrypter.Public()
+}
+
+func (d *scopedDecrypter) Decrypt(rand io.Reader, msg []byte, opts crypto.DecrypterOpts) ([]byte, error) {
+	return d.decrypter.Decrypt(rand, msg, opts)
+}
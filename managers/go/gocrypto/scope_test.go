@@ -0,0 +1,88 @@
+//go:build !verifyonly
+// +build !verifyonly
+
+package gocrypto
+
+import (
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/sha256"
+	"errors"
+	"testing"
+
+	"github.com/mariotoffia/goservice/interfaces/ifcrypto"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestScopedKeysRestrictsKeysAndOperations(t *testing.T) {
+
+	ctx := context.Background()
+
+	tokenKey, err := NewECDSAPrivateKey("token-1", 256, ifcrypto.KeyUsageSign)
+	require.NoError(t, err)
+
+	dbKey, err := NewRSAPrivateKey("db", 2048, ifcrypto.KeyUsageEncrypt, ifcrypto.KeyUsageDecrypt)
+	require.NoError(t, err)
+
+	keys := map[string]ifcrypto.Key{"token-1": tokenKey, "db": dbKey}
+	lookups := 0
+
+	lookup := func(c context.Context, id string) (ifcrypto.Key, error) {
+
+		lookups++
+
+		if key, ok := keys[id]; ok {
+			return key, nil
+		}
+
+		return nil, ifcrypto.ErrServiceKeyNotFound
+
+	}
+
+	var events []ScopeEvent
+
+	view := NewScopedKeys("tokens", lookup, KeyScope{
+		KeyIDs:     []string{"token-*"},
+		Operations: []ifcrypto.PolicyOperation{ifcrypto.PolicyOperationSign},
+	}, WithScopeAuditor(func(event ScopeEvent) { events = append(events, event) }))
+
+	signer, err := view.Signer(ctx, "token-1")
+	require.NoError(t, err)
+
+	_, isKey := signer.(ifcrypto.Key)
+	assert.False(t, isKey)
+
+	digest := sha256.Sum256([]byte("hello"))
+	sig, err := signer.Sign(rand.Reader, digest[:], crypto.SHA256)
+	require.NoError(t, err)
+
+	public, err := view.PublicKey(ctx, "token-1")
+	require.NoError(t, err)
+	require.NoError(t, public.(ifcrypto.SignatureVerifier).Verify(digest[:], sig, crypto.SHA256))
+
+	_, err = view.Decrypter(ctx, "token-1")
+	assert.True(t, errors.Is(err, ErrKeyOutOfScope))
+
+	before := lookups
+	_, err = view.Decrypter(ctx, "db")
+	assert.True(t, errors.Is(err, ErrKeyOutOfScope))
+	assert.Equal(t, before, lookups, "out of scope key must not be looked up")
+
+	require.Len(t, events, 2)
+	assert.Equal(t, "tokens", events[1].Scope)
+	assert.Equal(t, "db", events[1].KeyID)
+
+	narrow := view.Restrict("verify-only", KeyScope{KeyIDs: []string{"token-1", "db"}})
+
+	_, err = narrow.PublicKey(ctx, "token-1")
+	require.NoError(t, err)
+
+	_, err = narrow.Signer(ctx, "token-1")
+	assert.True(t, errors.Is(err, ErrKeyOutOfScope))
+
+	_, err = narrow.PublicKey(ctx, "db")
+	assert.True(t, errors.Is(err, ErrKeyOutOfScope))
+
+}
@@ -0,0 +1,305 @@
+package gocrypto
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"encoding/pem"
+	"fmt"
+	"io"
+
+	"github.com/mariotoffia/goservice/interfaces/ifcrypto"
+	"github.com/mariotoffia/goservice/utils/cryptoutils"
+)
+
+// Secp256k1PrivateKey implements the `ifcrypto.KeyPair` interface for a _secp256k1_ `*ecdsa.PrivateKey`.
+//
+// Signatures use deterministic _RFC 6979_ nonces and low _s_ normalization, as required by
+// _Bitcoin_ and _Ethereum_.
+//
+// NOTE: The curve arithmetic is not constant time, see `cryptoutils.Secp256k1`.
+type Secp256k1PrivateKey struct {
+	KeyBase
+	key    *ecdsa.PrivateKey
+	public *Secp256k1PublicKey
+}
+
+// NewSecp256k1PrivateKeyFromKey creates a new `Secp256k1PrivateKey`
+//
+// The public key portion derives the same usage as the private key
+func NewSecp256k1PrivateKeyFromKey(
+	id string,
+	key *ecdsa.PrivateKey,
+	usage ...ifcrypto.KeyUsage,
+) (*Secp256k1PrivateKey, error) {
+
+	if key.Curve != cryptoutils.Secp256k1() {
+		return nil, fmt.Errorf("not a secp256k1 key: %s", key.Params().Name)
+	}
+
+	public, err := NewSecp256k1PublicKeyFromKey(id, &key.PublicKey, usage...)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Secp256k1PrivateKey{
+		KeyBase: KeyBase{
+			id:      id,
+			keyType: ifcrypto.KeyTypeEccSecgP256k1,
+			keySize: 256,
+			usage:   usage,
+			chiper:  []ifcrypto.Chipher{},
+		},
+		key:    key,
+		public: public,
+	}, nil
+
+}
+
+// NewSecp256k1PrivateKeyFromDER initializes a new `Secp256k1PrivateKey` from _PKCS #8_ or _SEC 1_ _DER_.
+func NewSecp256k1PrivateKeyFromDER(
+	der []byte,
+	id string,
+	usage ...ifcrypto.KeyUsage,
+) (*Secp256k1PrivateKey, error) {
+
+	return NewSecp256k1PrivateKeyFromPEM(privateKeyDERBlock(der, "EC PRIVATE KEY"), id, usage...)
+
+}
+
+// SignMessage implements the `ifcrypto.MessageSigner` interface, see `SignMessage`.
+func (r *Secp256k1PrivateKey) SignMessage(msg []byte, hash crypto.Hash) ([]byte, error) {
+	return SignMessage(r, msg, hash)
+}
+
+// Public implements the `crypto.Signer` _interface_.
+func (r *Secp256k1PrivateKey) Public() crypto.PublicKey {
+	return &r.key.PublicKey
+}
+
+// GetPublic returns the public portion of the key
+func (r *Secp256k1PrivateKey) GetPublic() ifcrypto.PublicKey {
+	return r.public
+}
+
+// PEMWrite will write the key onto _w_.
+//
+// If private key, and _public_ is `true`, it will in addition write the public portion as well.
+func (r *Secp256k1PrivateKey) PEMWrite(w io.Writer, public bool) error {
+
+	return cryptoutils.Secp256k1PrivateKeyToPEM(w, r.key, public)
+
+}
+
+// PEMWriteEncrypted implements the `ifcrypto.EncryptedPEMWriter` interface.
+func (r *Secp256k1PrivateKey) PEMWriteEncrypted(w io.Writer, passphrase []byte) error {
+
+	return pemWriteEncrypted(w, r, passphrase)
+
+}
+
+// DERWrite implements the `ifcrypto.DERWriter` interface, the key is written as _PKCS #8_.
+func (r *Secp256k1PrivateKey) DERWrite(w io.Writer) error {
+
+	return privateKeyDERWrite(w, r)
+
+}
+
+// ToJWK implements the `JWKMarshaler` interface. The _JWK_ includes the private key, use
+// `GetPublic` to get the public _JWK_.
+func (r *Secp256k1PrivateKey) ToJWK() (*cryptoutils.JSONWebKey, error) {
+	return keyToJWK(r)
+}
+
+// Fingerprint implements the `Fingerprinter` interface and returns the fingerprint of the
+// public key, see `Fingerprint`.
+func (r *Secp256k1PrivateKey) Fingerprint(opts ...FingerprintOption) (string, error) {
+	return Fingerprint(r, opts...)
+}
+
+// GetKey gets the underlying key, if any.
+//
+// Some keys are remote and not possible to fetch. In such situations the function returns a remote id,
+// most often the same as GetID() returns.
+func (r *Secp256k1PrivateKey) GetKey() interface{} {
+	return r.key
+}
+
+// IsSymmetric returns `true` if this is a `KeyTypeSymmetric`
+//
+// This is a convenience function instead of `GetKeyType`.
+func (r *Secp256k1PrivateKey) IsSymmetric() bool {
+	return false
+}
+
+// IsPrivate returns `true` if this is a `KeyType` other than `KeyTypeSymmetric` and is a private key.
+//
+// If `KeyTypeSymmetric` it will return `true` since all symmetric keys are considered as private.
+func (r *Secp256k1PrivateKey) IsPrivate() bool {
+	return true
+}
+
+// IsRemoteKey returns `true` if the key is not present in current process memory.
+//
+// Typically hardware units or remote services will not reveal their private key. In such case, this
+// method returns `true`. If present in memory such as a `*rsa.PrivateKey` it returns `false`.
+func (r *Secp256k1PrivateKey) IsRemoteKey() bool {
+	return false
+}
+
+// Secp256k1PublicKey implements the `ifcrypto.PublicKey` interface for a _secp256k1_ `*ecdsa.PublicKey`
+type Secp256k1PublicKey struct {
+	KeyBase
+	key *ecdsa.PublicKey
+	pem publicPEM
+}
+
+// NewSecp256k1PublicKeyFromKey creates a instance based on a existing public key.
+func NewSecp256k1PublicKeyFromKey(
+	id string,
+	key *ecdsa.PublicKey,
+	usage ...ifcrypto.KeyUsage,
+) (*Secp256k1PublicKey, error) {
+
+	if key.Curve != cryptoutils.Secp256k1() {
+		return nil, fmt.Errorf("not a secp256k1 key: %s", key.Params().Name)
+	}
+
+	return &Secp256k1PublicKey{
+		KeyBase: KeyBase{
+			id:      id,
+			keyType: ifcrypto.KeyTypeEccSecgP256k1,
+			keySize: 256,
+			usage:   usage,
+		},
+		key: key,
+	}, nil
+
+}
+
+// NewSecp256k1PublicKeyFromBytes creates a instance from a compressed or uncompressed _SEC 1_ point.
+func NewSecp256k1PublicKeyFromBytes(id string, data []byte, usage ...ifcrypto.KeyUsage) (*Secp256k1PublicKey, error) {
+
+	key, err := cryptoutils.Secp256k1PublicKeyFromBytes(data)
+	if err != nil {
+		return nil, err
+	}
+
+	return NewSecp256k1PublicKeyFromKey(id, key, usage...)
+
+}
+
+// NewSecp256k1PublicKeyFromPEM initializes a new `Secp256k1PublicKey` from the underlying _PKIX_ _PEM_ block.
+func NewSecp256k1PublicKeyFromPEM(
+	block pem.Block,
+	id string,
+	usage ...ifcrypto.KeyUsage,
+) (*Secp256k1PublicKey, error) {
+
+	if block.Type == "PUBLIC KEY" || block.Type == "EC PUBLIC KEY" {
+
+		key, err := cryptoutils.ParseSecp256k1PublicKey(block.Bytes)
+		if err != nil {
+			return nil, err
+		}
+
+		return NewSecp256k1PublicKeyFromKey(id, key, usage...)
+
+	}
+
+	return nil, fmt.Errorf("unsupported PEM block: %s", block.Type)
+
+}
+
+// NewSecp256k1PublicKeyFromDER initializes a new `Secp256k1PublicKey` from _PKIX_ _DER_.
+func NewSecp256k1PublicKeyFromDER(
+	der []byte,
+	id string,
+	usage ...ifcrypto.KeyUsage,
+) (*Secp256k1PublicKey, error) {
+
+	return NewSecp256k1PublicKeyFromPEM(pem.Block{Type: "PUBLIC KEY", Bytes: der}, id, usage...)
+
+}
+
+// VerifyMessage implements the `ifcrypto.MessageVerifier` interface, see `VerifyMessage`.
+func (r *Secp256k1PublicKey) VerifyMessage(msg, signature []byte, hash crypto.Hash) error {
+	return VerifyMessage(r, msg, signature, hash)
+}
+
+// Verify implements the `ifcrypto.SignatureVerifier` interface for a _ASN.1 DER_, or 64 byte
+// _r || s_, _signature_. High _s_ signatures are rejected.
+func (r *Secp256k1PublicKey) Verify(digest, signature []byte, opts crypto.SignerOpts) error {
+
+	if err := CheckAlgorithm(ifcrypto.PolicyOperationVerify, r, signerHash(opts)); err != nil {
+		return err
+	}
+
+	if !cryptoutils.VerifySecp256k1(r.key, digest, signature) {
+		return ifcrypto.ErrInvalidSignature
+	}
+
+	return nil
+
+}
+
+// PEMWrite will write the key onto _w_.
+//
+// Since this is a public key, it will ignore the _public_ parameter.
+func (r *Secp256k1PublicKey) PEMWrite(w io.Writer, public bool) error {
+
+	return cryptoutils.Secp256k1PublicKeyToPEM(w, r.key)
+
+}
+
+// DERWrite implements the `ifcrypto.DERWriter` interface, the key is written as _PKIX_.
+func (r *Secp256k1PublicKey) DERWrite(w io.Writer) error {
+
+	return publicKeyDERWrite(w, r)
+
+}
+
+// WriteTo implements the `io.WriterTo` interface and writes the key as _PEM_ onto _w_. The
+// encoding is cached, hence streaming many keys do not encode them over and over again.
+func (r *Secp256k1PublicKey) WriteTo(w io.Writer) (int64, error) {
+	return r.pem.writeTo(w, r)
+}
+
+// ToJWK implements the `JWKMarshaler` interface.
+func (r *Secp256k1PublicKey) ToJWK() (*cryptoutils.JSONWebKey, error) {
+	return keyToJWK(r)
+}
+
+// Fingerprint implements the `Fingerprinter` interface, see `Fingerprint`.
+func (r *Secp256k1PublicKey) Fingerprint(opts ...FingerprintOption) (string, error) {
+	return Fingerprint(r, opts...)
+}
+
+// GetKey gets the underlying key, if any.
+//
+// Some keys are remote and not possible to fetch. In such situations the function returns a remote id,
+// most often the same as GetID() returns.
+func (r *Secp256k1PublicKey) GetKey() interface{} {
+	return r.key
+}
+
+// IsSymmetric returns `true` if this is a `KeyTypeSymmetric`
+//
+// This is a convenience function instead of `GetKeyType`.
+func (r *Secp256k1PublicKey) IsSymmetric() bool {
+	return false
+}
+
+// IsPrivate returns `true` if this is a `KeyType` other than `KeyTypeSymmetric` and is a private key.
+//
+// If `KeyTypeSymmetric` it will return `true` since all symmetric keys are considered as private.
+func (r *Secp256k1PublicKey) IsPrivate() bool {
+	return false
+}
+
+// IsRemoteKey returns `true` if the key is not present in current process memory.
+//
+// Typically hardware units or remote services will not reveal their private key. In such case, this
+// method returns `true`. If present in memory such as a `*rsa.PrivateKey` it returns `false`.
+func (r *Secp256k1PublicKey) IsRemoteKey() bool {
+	return false
+}
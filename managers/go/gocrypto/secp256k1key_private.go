@@ -0,0 +1,126 @@
+//go:build !verifyonly
+// +build !verifyonly
+
+package gocrypto
+
+import (
+	"crypto"
+	"encoding/pem"
+	"fmt"
+	"io"
+
+	"github.com/mariotoffia/goservice/interfaces/ifcrypto"
+	"github.com/mariotoffia/goservice/utils/cryptoutils"
+)
+
+// NewSecp256k1PrivateKeyFromBytes creates a new `Secp256k1PrivateKey` from the raw 32 byte scalar.
+func NewSecp256k1PrivateKeyFromBytes(id string, d []byte, usage ...ifcrypto.KeyUsage) (*Secp256k1PrivateKey, error) {
+
+	if IsVerifyOnly() {
+		return nil, ErrVerifyOnly
+	}
+
+	key, err := cryptoutils.Secp256k1PrivateKeyFromBytes(d)
+	if err != nil {
+		return nil, err
+	}
+
+	return NewSecp256k1PrivateKeyFromKey(id, key, usage...)
+
+}
+
+// NewSecp256k1PrivateKeyFromPEM initializes a new `Secp256k1PrivateKey` from the underlying
+// _SEC 1_ or _PKCS #8_ _PEM_ block.
+func NewSecp256k1PrivateKeyFromPEM(
+	block pem.Block,
+	id string,
+	usage ...ifcrypto.KeyUsage,
+) (*Secp256k1PrivateKey, error) {
+
+	if IsVerifyOnly() {
+		return nil, ErrVerifyOnly
+	}
+
+	if block.Type == "EC PRIVATE KEY" || block.Type == "PRIVATE KEY" {
+
+		key, err := cryptoutils.ParseSecp256k1PrivateKey(block.Bytes)
+		if err != nil {
+			return nil, err
+		}
+
+		return NewSecp256k1PrivateKeyFromKey(id, key, usage...)
+
+	}
+
+	return nil, fmt.Errorf("unsupported PEM block: %s", block.Type)
+
+}
+
+// NewSecp256k1PrivateKey generates a new `Secp256k1PrivateKey` using the `rand.Reader`, or the `EntropyGuard` reader, as entropy.
+func NewSecp256k1PrivateKey(id string, usage ...ifcrypto.KeyUsage) (*Secp256k1PrivateKey, error) {
+
+	if IsVerifyOnly() {
+		return nil, ErrVerifyOnly
+	}
+
+	reader, err := keyGenerationReader()
+	if err != nil {
+		return nil, err
+	}
+
+	d := make([]byte, 32)
+
+	for {
+
+		if _, err := io.ReadFull(reader, d); err != nil {
+			return nil, err
+		}
+
+		// Retry the negligible chance of a scalar outside [1, n-1]
+		if key, err := cryptoutils.Secp256k1PrivateKeyFromBytes(d); err == nil {
+			return NewSecp256k1PrivateKeyFromKey(id, key, usage...)
+		}
+
+	}
+
+}
+
+// Sign implements the `crypto.Signer` _interface_ and returns a _ASN.1 DER_ signature, or the
+// 64 byte _r || s_ signature if _opts_ is a `*ECDSASignerOpts` with _Raw_ set. The _rand_
+// argument is not used since the nonce is deterministic.
+func (r *Secp256k1PrivateKey) Sign(
+	rand io.Reader,
+	digest []byte,
+	opts crypto.SignerOpts,
+) ([]byte, error) {
+
+	if IsVerifyOnly() {
+		return nil, ErrVerifyOnly
+	}
+
+	if err := CheckAlgorithm(ifcrypto.PolicyOperationSign, r, signerHash(opts)); err != nil {
+		return nil, err
+	}
+
+	if isRawECDSA(opts) {
+		return cryptoutils.SignSecp256k1Raw(r.key, digest)
+	}
+
+	return cryptoutils.SignSecp256k1(r.key, digest)
+
+}
+
+// SignRaw signs the _digest_ and returns the 64 byte _r || s_ signature.
+func (r *Secp256k1PrivateKey) SignRaw(digest []byte) ([]byte, error) {
+
+	if IsVerifyOnly() {
+		return nil, ErrVerifyOnly
+	}
+
+	if err := CheckAlgorithm(ifcrypto.PolicyOperationSign, r, 0); err != nil {
+		return nil, err
+	}
+
+	return cryptoutils.SignSecp256k1Raw(r.key, digest)
+
+}
@@ -0,0 +1,52 @@
+//go:build !verifyonly
+// +build !verifyonly
+
+package gocrypto
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/pem"
+	"testing"
+
+	"github.com/mariotoffia/goservice/interfaces/ifcrypto"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSecp256k1Key(t *testing.T) {
+
+	key, err := NewSecp256k1PrivateKey("wallet", ifcrypto.KeyUsageSign, ifcrypto.KeyUsageVerify)
+	require.NoError(t, err)
+
+	var _ ifcrypto.KeyPair = key
+	var _ crypto.Signer = key
+
+	assert.Equal(t, ifcrypto.KeyTypeEccSecgP256k1, key.GetKeyType())
+	assert.True(t, key.CanSign(ifcrypto.SignAlgorithmEcdSha256))
+
+	digest := sha256.Sum256([]byte("transaction"))
+
+	sig, err := key.Sign(rand.Reader, digest[:], crypto.SHA256)
+	require.NoError(t, err)
+
+	var buf bytes.Buffer
+	require.NoError(t, key.PEMWrite(&buf, true))
+
+	_, rest := pem.Decode(buf.Bytes())
+	pubBlock, _ := pem.Decode(rest)
+	require.NotNil(t, pubBlock)
+
+	pub, err := NewSecp256k1PublicKeyFromPEM(*pubBlock, "wallet")
+	require.NoError(t, err)
+
+	assert.NoError(t, pub.Verify(digest[:], sig, crypto.SHA256))
+
+	raw, err := key.SignRaw(digest[:])
+	require.NoError(t, err)
+	assert.Len(t, raw, 64)
+	assert.NoError(t, pub.Verify(digest[:], raw, crypto.SHA256))
+
+}
@@ -0,0 +1,272 @@
+package gocrypto
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/mariotoffia/goservice/interfaces/ifcrypto"
+	"github.com/mariotoffia/goservice/utils/cryptoutils"
+)
+
+// ErrDataShredded is returned when decrypting data whose key has been shredded.
+var ErrDataShredded = fmt.Errorf("data key has been shredded")
+
+// ShredOperation is the operation of a `ShredEvent`.
+type ShredOperation string
+
+const (
+	// ShredOperationCreate is when a new data key is created for a id.
+	ShredOperationCreate ShredOperation = "create"
+	// ShredOperationShred is when data keys are deleted.
+	ShredOperationShred ShredOperation = "shred"
+)
+
+// ShredEvent is a audit event emitted by the `Shredder`.
+type ShredEvent struct {
+	Time      time.Time
+	Operation ShredOperation
+	IDs       []string
+	// Reason is the caller supplied reason, e.g. a erasure request reference.
+	Reason string
+	// Context is the `ifcrypto.CryptoContext` of the operation.
+	Context ifcrypto.CryptoContext
+}
+
+// ShredderOption configures a `Shredder`.
+type ShredderOption func(s *Shredder)
+
+// WithShredAuditor sets a function that receives all audit events.
+func WithShredAuditor(auditor func(event ShredEvent)) ShredderOption {
+	return func(s *Shredder) {
+		s.auditor = auditor
+	}
+}
+
+// Shredder encrypts data with per-record data encryption keys so that the data of a record
+// can be erased by deleting its key, i.e. crypto-shredding.
+//
+// The data keys are _AES-256_ keys, wrapped with the key encryption key using _AES_ key wrap
+// before they are handed to the `ifcrypto.DataKeyStore`. The data is encrypted using
+// _AES-256-GCM_ and the id is bound as additional data, hence ciphertext can not be moved
+// between records.
+type Shredder struct {
+	store   ifcrypto.DataKeyStore
+	kek     []byte
+	auditor func(event ShredEvent)
+	mu      sync.Mutex
+}
+
+// NewShredder creates a new `Shredder` that stores the data keys in _store_ wrapped by _kek_.
+func NewShredder(store ifcrypto.DataKeyStore, kek []byte, opts ...ShredderOption) (*Shredder, error) {
+
+	if _, err := aes.NewCipher(kek); err != nil {
+		return nil, err
+	}
+
+	s := &Shredder{store: store, kek: append([]byte{}, kek...), auditor: func(ShredEvent) {}}
+
+	for _, opt := range opts {
+		opt(s)
+	}
+
+	return s, nil
+
+}
+
+// Encrypt encrypts the _plaintext_ of the record _id_. A data key is created for the id if
+// it do not exist.
+func (s *Shredder) Encrypt(c context.Context, id string, plaintext []byte) ([]byte, error) {
+
+	aead, err := s.aead(c, id, true)
+	if err != nil {
+		return nil, err
+	}
+
+	nonce := make([]byte, aead.NonceSize())
+
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return nil, err
+	}
+
+	return aead.Seal(nonce, nonce, plaintext, []byte(id)), nil
+
+}
+
+// Decrypt decrypts the _ciphertext_ of the record _id_. If the key has been shredded,
+// `ErrDataShredded` is returned.
+func (s *Shredder) Decrypt(c context.Context, id string, ciphertext []byte) ([]byte, error) {
+
+	aead, err := s.aead(c, id, false)
+	if err != nil {
+		return nil, err
+	}
+
+	return shredderOpen(aead, id, ciphertext)
+
+}
+
+// shredderOpen decrypts the _ciphertext_ of the record _id_ using _aead_.
+func shredderOpen(aead cipher.AEAD, id string, ciphertext []byte) ([]byte, error) {
+
+	if len(ciphertext) < aead.NonceSize() {
+		return nil, fmt.Errorf("ciphertext too short")
+	}
+
+	return aead.Open(nil, ciphertext[:aead.NonceSize()], ciphertext[aead.NonceSize():], []byte(id))
+
+}
+
+// Shred deletes the data keys of all _ids_, after which their data can not be decrypted.
+func (s *Shredder) Shred(c context.Context, reason string, ids ...string) error {
+
+	if len(ids) == 0 {
+		return nil
+	}
+
+	if err := s.store.Delete(c, ids...); err != nil {
+		return err
+	}
+
+	s.auditor(ShredEvent{
+		Time:      time.Now().UTC(),
+		Operation: ShredOperationShred,
+		IDs:       append([]string{}, ids...),
+		Reason:    reason,
+		Context:   ifcrypto.CryptoContextFrom(c),
+	})
+
+	return nil
+
+}
+
+// aead returns the cipher for the data key of _id_, optionally creating the key.
+func (s *Shredder) aead(c context.Context, id string, create bool) (cipher.AEAD, error) {
+
+	wrapped, err := s.store.Get(c, id)
+
+	if errors.Is(err, ifcrypto.ErrDataKeyNotFound) {
+
+		if !create {
+			return nil, ErrDataShredded
+		}
+
+		wrapped, err = s.createKey(c, id)
+
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	key, err := cryptoutils.AESKeyUnwrap(s.kek, wrapped)
+	if err != nil {
+		return nil, err
+	}
+
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+
+	return cipher.NewGCM(block)
+
+}
+
+// createKey creates, wraps and stores a new data key for _id_.
+func (s *Shredder) createKey(c context.Context, id string) ([]byte, error) {
+
+	// Serialize creation so that concurrent first writes do not race on the same id
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if wrapped, err := s.store.Get(c, id); err == nil {
+		return wrapped, nil
+	}
+
+	key := make([]byte, 32)
+
+	if _, err := io.ReadFull(rand.Reader, key); err != nil {
+		return nil, err
+	}
+
+	wrapped, err := cryptoutils.AESKeyWrap(s.kek, key)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.store.Put(c, id, wrapped); err != nil {
+		return nil, err
+	}
+
+	s.auditor(ShredEvent{
+		Time:      time.Now().UTC(),
+		Operation: ShredOperationCreate,
+		IDs:       []string{id},
+		Context:   ifcrypto.CryptoContextFrom(c),
+	})
+
+	return wrapped, nil
+
+}
+
+// MemoryDataKeyStore is a in memory `ifcrypto.DataKeyStore`, mainly for tests.
+type MemoryDataKeyStore struct {
+	mu   sync.RWMutex
+	keys map[string][]byte
+}
+
+// NewMemoryDataKeyStore creates a new empty `MemoryDataKeyStore`.
+func NewMemoryDataKeyStore() *MemoryDataKeyStore {
+	return &MemoryDataKeyStore{keys: map[string][]byte{}}
+}
+
+// Put implements the `ifcrypto.DataKeyStore` interface.
+func (m *MemoryDataKeyStore) Put(c context.Context, id string, wrapped []byte) error {
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.keys[id]; ok {
+		return fmt.Errorf("data key %s already exists", id)
+	}
+
+	m.keys[id] = append([]byte{}, wrapped...)
+
+	return nil
+
+}
+
+// Get implements the `ifcrypto.DataKeyStore` interface.
+func (m *MemoryDataKeyStore) Get(c context.Context, id string) ([]byte, error) {
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	if wrapped, ok := m.keys[id]; ok {
+		return wrapped, nil
+	}
+
+	return nil, ifcrypto.ErrDataKeyNotFound
+
+}
+
+// Delete implements the `ifcrypto.DataKeyStore` interface.
+func (m *MemoryDataKeyStore) Delete(c context.Context, ids ...string) error {
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, id := range ids {
+		delete(m.keys, id)
+	}
+
+	return nil
+
+}
@@ -0,0 +1,48 @@
+package gocrypto
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestShredderMakesDataUnrecoverable(t *testing.T) {
+
+	ctx := context.Background()
+	events := []ShredEvent{}
+
+	shredder, err := NewShredder(NewMemoryDataKeyStore(), make([]byte, 32),
+		WithShredAuditor(func(event ShredEvent) { events = append(events, event) }),
+	)
+	require.NoError(t, err)
+
+	alice, err := shredder.Encrypt(ctx, "user-alice", []byte("alice@example.com"))
+	require.NoError(t, err)
+
+	bob, err := shredder.Encrypt(ctx, "user-bob", []byte("bob@example.com"))
+	require.NoError(t, err)
+
+	plaintext, err := shredder.Decrypt(ctx, "user-alice", alice)
+	require.NoError(t, err)
+	assert.Equal(t, "alice@example.com", string(plaintext))
+
+	_, err = shredder.Decrypt(ctx, "user-bob", alice)
+	assert.Error(t, err, "ciphertext is bound to its record")
+
+	require.NoError(t, shredder.Shred(ctx, "gdpr-4711", "user-alice"))
+
+	_, err = shredder.Decrypt(ctx, "user-alice", alice)
+	assert.Equal(t, ErrDataShredded, err)
+
+	plaintext, err = shredder.Decrypt(ctx, "user-bob", bob)
+	require.NoError(t, err)
+	assert.Equal(t, "bob@example.com", string(plaintext))
+
+	require.Len(t, events, 3)
+	assert.Equal(t, ShredOperationShred, events[2].Operation)
+	assert.Equal(t, []string{"user-alice"}, events[2].IDs)
+	assert.Equal(t, "gdpr-4711", events[2].Reason)
+
+}
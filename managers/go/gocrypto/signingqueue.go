@@ -0,0 +1,547 @@
+package gocrypto
+
+import (
+	"context"
+	"crypto"
+	"crypto/rand"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/mariotoffia/goservice/interfaces/ifcrypto"
+)
+
+// ErrSigningQueueClosed is returned when signing on a closed `SigningQueue`.
+var ErrSigningQueueClosed = fmt.Errorf("signing queue is closed")
+
+// SigningQueueOption configures a `SigningQueue`.
+type SigningQueueOption func(q *SigningQueue)
+
+// WithMaxBatchSize sets the maximum number of requests sent in a single batch (default 32).
+func WithMaxBatchSize(size int) SigningQueueOption {
+	return func(q *SigningQueue) {
+		if size > 0 {
+			q.maxBatch = size
+		}
+	}
+}
+
+// WithMaxLinger sets how long the queue waits for a batch to fill up before it is
+// sent anyway (default 2ms). Setting it to zero sends whatever is queued immediately.
+func WithMaxLinger(linger time.Duration) SigningQueueOption {
+	return func(q *SigningQueue) {
+		if linger >= 0 {
+			q.linger = linger
+		}
+	}
+}
+
+// WithMaxInFlight sets the maximum number of batches that may be outstanding towards
+// the provider at the same time (default 1, i.e. a single connection).
+func WithMaxInFlight(inflight int) SigningQueueOption {
+	return func(q *SigningQueue) {
+		if inflight > 0 {
+			q.inflight = inflight
+		}
+	}
+}
+
+// WithMaxPerTenant caps how many requests a single tenant may contribute to one batch
+// (default unlimited). Batches are always filled round-robin across tenants, the cap
+// ensures that one busy tenant can not monopolize a batch when there is contention.
+func WithMaxPerTenant(max int) SigningQueueOption {
+	return func(q *SigningQueue) {
+		if max > 0 {
+			q.perTenant = max
+		}
+	}
+}
+
+// pendingSign is a single queued request.
+type pendingSign struct {
+	ctx    context.Context
+	req    ifcrypto.SignRequest
+	result chan ifcrypto.SignResult
+}
+
+// SigningQueue coalesces concurrent sign requests into batches that are handed to a
+// `ifcrypto.BatchSigner`.
+//
+// This is useful when the signing is done by a remote provider where each round trip is
+// expensive. Requests are queued per tenant and batches are filled in a round-robin
+// fashion so that each tenant gets a fair share of the provider throughput.
+//
+// .Example
+// [source,go]
+// ----
+// q := NewSigningQueue(hsm, WithMaxBatchSize(64), WithMaxInFlight(2))
+// defer q.Close()
+//
+// signature, err := q.Sign(ctx, "token-issuer", digest, crypto.SHA256)
+// ----
+type SigningQueue struct {
+	signer    ifcrypto.BatchSigner
+	maxBatch  int
+	linger    time.Duration
+	inflight  int
+	perTenant int
+
+	mu       sync.Mutex
+	queues   map[string][]*pendingSign
+	tenants  []string
+	size     int
+	closed   bool
+	draining bool
+
+	notify chan struct{}
+	full   chan struct{}
+	empty  chan struct{}
+	stop   chan struct{}
+	done   chan struct{}
+	slots  chan struct{}
+	wg     sync.WaitGroup
+}
+
+// NewSigningQueue creates and starts a new `SigningQueue` in front of _signer_.
+//
+// Make sure to `Close` the queue when done to release the dispatcher.
+func NewSigningQueue(signer ifcrypto.BatchSigner, opts ...SigningQueueOption) *SigningQueue {
+
+	q := &SigningQueue{
+		signer:   signer,
+		maxBatch: 32,
+		linger:   2 * time.Millisecond,
+		inflight: 1,
+		queues:   map[string][]*pendingSign{},
+		notify:   make(chan struct{}, 1),
+		full:     make(chan struct{}, 1),
+		empty:    make(chan struct{}, 1),
+		stop:     make(chan struct{}),
+		done:     make(chan struct{}),
+	}
+
+	for _, opt := range opts {
+		opt(q)
+	}
+
+	q.slots = make(chan struct{}, q.inflight)
+
+	go q.run()
+
+	return q
+
+}
+
+// Sign queues the _digest_ for signing on behalf of _tenant_ and blocks until it has
+// been signed, the _ctx_ is done or the queue is closed.
+func (q *SigningQueue) Sign(
+	ctx context.Context,
+	tenant string,
+	digest []byte,
+	opts crypto.SignerOpts,
+) ([]byte, error) {
+
+	p := &pendingSign{
+		ctx:    ctx,
+		req:    ifcrypto.SignRequest{Digest: digest, Opts: opts},
+		result: make(chan ifcrypto.SignResult, 1),
+	}
+
+	q.mu.Lock()
+
+	if q.closed || q.draining {
+		q.mu.Unlock()
+		return nil, ErrSigningQueueClosed
+	}
+
+	if _, ok := q.queues[tenant]; !ok {
+		q.tenants = append(q.tenants, tenant)
+	}
+
+	q.queues[tenant] = append(q.queues[tenant], p)
+	q.size++
+
+	isFull := q.size >= q.maxBatch
+
+	q.mu.Unlock()
+
+	signal(q.notify)
+
+	if isFull {
+		signal(q.full)
+	}
+
+	select {
+	case res := <-p.result:
+		return res.Signature, res.Err
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+
+}
+
+// Signer returns a `crypto.Signer` that signs through the queue on behalf of _tenant_.
+//
+// The _public_ key is returned by the `crypto.Signer.Public` function.
+func (q *SigningQueue) Signer(tenant string, public crypto.PublicKey) crypto.Signer {
+	return &queuedSigner{queue: q, tenant: tenant, public: public}
+}
+
+// Close stops the queue. All requests not yet sent to the provider fails with
+// `ErrSigningQueueClosed` and it blocks until all in-flight batches has completed.
+func (q *SigningQueue) Close() {
+
+	q.mu.Lock()
+
+	if q.closed {
+		q.mu.Unlock()
+		return
+	}
+
+	q.closed = true
+	q.mu.Unlock()
+
+	close(q.stop)
+	<-q.done
+
+	q.wg.Wait()
+
+}
+
+// Start implements the `ifcrypto.Lifecycle` interface. The queue is already started by
+// `NewSigningQueue`, hence it do nothing.
+func (q *SigningQueue) Start(c context.Context) error {
+	return nil
+}
+
+// Stop implements the `ifcrypto.Lifecycle` interface. Unlike `Close` it drains the queue, new
+// requests fail with `ErrSigningQueueClosed` while the queued requests are sent to the provider.
+// When _c_ is done before the queue is empty the remaining requests fail as in `Close`.
+//
+// In-flight batches can not be canceled, if _c_ is done before they complete it returns
+// without waiting for them.
+func (q *SigningQueue) Stop(c context.Context) error {
+
+	q.mu.Lock()
+	q.draining = true
+	empty := q.size == 0
+	q.mu.Unlock()
+
+	if !empty {
+
+		signal(q.full)
+
+		select {
+		case <-q.empty:
+		case <-c.Done():
+		}
+
+	}
+
+	closed := make(chan struct{})
+
+	go func() {
+		q.Close()
+		close(closed)
+	}()
+
+	select {
+	case <-closed:
+		return nil
+	case <-c.Done():
+		return fmt.Errorf("%w: signing queue: %v", ifcrypto.ErrDrainTimeout, c.Err())
+	}
+
+}
+
+// run is the dispatcher loop.
+func (q *SigningQueue) run() {
+
+	defer close(q.done)
+
+	for {
+
+		select {
+		case <-q.notify:
+		case <-q.stop:
+			q.failPending()
+			return
+		}
+
+		if q.linger > 0 && q.pending() < q.maxBatch {
+
+			timer := time.NewTimer(q.linger)
+
+			select {
+			case <-timer.C:
+			case <-q.full:
+				timer.Stop()
+			case <-q.stop:
+				timer.Stop()
+				q.failPending()
+				return
+			}
+
+		}
+
+		for {
+
+			batch := q.take()
+
+			if len(batch) == 0 {
+				break
+			}
+
+			select {
+			case q.slots <- struct{}{}:
+			case <-q.stop:
+				failAll(batch)
+				q.failPending()
+				return
+			}
+
+			q.wg.Add(1)
+
+			go q.execute(batch)
+
+			if q.isDrained() {
+				signal(q.empty)
+			}
+
+		}
+
+	}
+
+}
+
+// execute sends the _batch_ to the provider and dispatches the results.
+func (q *SigningQueue) execute(batch []*pendingSign) {
+
+	defer func() {
+		<-q.slots
+		q.wg.Done()
+	}()
+
+	live := make([]*pendingSign, 0, len(batch))
+	requests := make([]ifcrypto.SignRequest, 0, len(batch))
+
+	for _, p := range batch {
+
+		// Caller has already given up
+		if p.ctx.Err() != nil {
+			continue
+		}
+
+		live = append(live, p)
+		requests = append(requests, p.req)
+
+	}
+
+	if len(live) == 0 {
+		return
+	}
+
+	results := q.signer.SignBatch(context.Background(), requests)
+
+	for i, p := range live {
+
+		if i < len(results) {
+			p.result <- results[i]
+		} else {
+			p.result <- ifcrypto.SignResult{
+				Err: fmt.Errorf("batch signer returned %d results for %d requests", len(results), len(live)),
+			}
+		}
+
+	}
+
+}
+
+// isDrained returns `true` if `Stop` drains the queue and all requests has been dispatched.
+func (q *SigningQueue) isDrained() bool {
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	return q.draining && q.size == 0
+
+}
+
+// take removes up to _maxBatch_ requests, round-robin across tenants.
+func (q *SigningQueue) take() []*pendingSign {
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	batch := make([]*pendingSign, 0, q.maxBatch)
+	taken := map[string]int{}
+
+	for len(batch) < q.maxBatch && q.size > 0 {
+
+		progress := false
+
+		for _, tenant := range q.tenants {
+
+			if len(batch) == q.maxBatch {
+				break
+			}
+
+			queue := q.queues[tenant]
+
+			if len(queue) == 0 || (q.perTenant > 0 && taken[tenant] >= q.perTenant) {
+				continue
+			}
+
+			batch = append(batch, queue[0])
+			q.queues[tenant] = queue[1:]
+			q.size--
+			taken[tenant]++
+			progress = true
+
+		}
+
+		if !progress {
+			break
+		}
+
+	}
+
+	// Rotate so the next batch starts with the next tenant and drop idle tenants
+	tenants := make([]string, 0, len(q.tenants))
+
+	if len(q.tenants) > 1 {
+		q.tenants = append(q.tenants[1:], q.tenants[0])
+	}
+
+	for _, tenant := range q.tenants {
+
+		if len(q.queues[tenant]) == 0 {
+			delete(q.queues, tenant)
+			continue
+		}
+
+		tenants = append(tenants, tenant)
+
+	}
+
+	q.tenants = tenants
+
+	if q.size > 0 {
+		signal(q.notify)
+	}
+
+	return batch
+
+}
+
+// pending returns the number of queued requests.
+func (q *SigningQueue) pending() int {
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	return q.size
+
+}
+
+// failPending fails all queued requests with `ErrSigningQueueClosed`.
+func (q *SigningQueue) failPending() {
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	for _, queue := range q.queues {
+		failAll(queue)
+	}
+
+	q.queues = map[string][]*pendingSign{}
+	q.tenants = nil
+	q.size = 0
+
+}
+
+// failAll fails all _batch_ requests with `ErrSigningQueueClosed`.
+func failAll(batch []*pendingSign) {
+
+	for _, p := range batch {
+		p.result <- ifcrypto.SignResult{Err: ErrSigningQueueClosed}
+	}
+
+}
+
+// signal does a non blocking send on _ch_.
+func signal(ch chan struct{}) {
+
+	select {
+	case ch <- struct{}{}:
+	default:
+	}
+
+}
+
+// queuedSigner is a `crypto.Signer` that signs through a `SigningQueue`.
+type queuedSigner struct {
+	queue  *SigningQueue
+	tenant string
+	public crypto.PublicKey
+}
+
+// Public implements the `crypto.Signer` _interface_.
+func (s *queuedSigner) Public() crypto.PublicKey {
+	return s.public
+}
+
+// Sign implements the `crypto.Signer` _interface_. The _rand_ parameter is not used
+// since the signing is done by the provider.
+func (s *queuedSigner) Sign(rand io.Reader, digest []byte, opts crypto.SignerOpts) ([]byte, error) {
+	return s.queue.Sign(context.Background(), s.tenant, digest, opts)
+}
+
+// SequentialBatchSigner adapts a plain `crypto.Signer` to a `ifcrypto.BatchSigner` by
+// signing each request in sequence.
+//
+// This is mainly useful for providers that do not support pipelining but still benefits
+// from the concurrency limiting of a `SigningQueue`.
+type SequentialBatchSigner struct {
+	// Signer is the signer to sign each request with.
+	Signer crypto.Signer
+	// Rand is the entropy to use when signing, if `nil`, `rand.Reader` is used.
+	Rand io.Reader
+}
+
+// SignBatch implements the `ifcrypto.BatchSigner` interface.
+func (s *SequentialBatchSigner) SignBatch(
+	c context.Context,
+	requests []ifcrypto.SignRequest,
+) []ifcrypto.SignResult {
+
+	results := make([]ifcrypto.SignResult, len(requests))
+
+	for i := range requests {
+
+		if err := c.Err(); err != nil {
+			results[i].Err = err
+			continue
+		}
+
+		results[i].Signature, results[i].Err = s.Signer.Sign(
+			randOrDefault(s.Rand), requests[i].Digest, requests[i].Opts,
+		)
+
+	}
+
+	return results
+
+}
+
+// randOrDefault returns _r_ or `rand.Reader` if _r_ is `nil`.
+func randOrDefault(r io.Reader) io.Reader {
+
+	if r == nil {
+		return rand.Reader
+	}
+
+	return r
+
+}
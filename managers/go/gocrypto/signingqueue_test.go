@@ -0,0 +1,150 @@
+package gocrypto
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/mariotoffia/goservice/interfaces/ifcrypto"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type recordingBatchSigner struct {
+	SequentialBatchSigner
+	mu      sync.Mutex
+	batches []int
+}
+
+func (r *recordingBatchSigner) SignBatch(
+	c context.Context,
+	requests []ifcrypto.SignRequest,
+) []ifcrypto.SignResult {
+
+	r.mu.Lock()
+	r.batches = append(r.batches, len(requests))
+	r.mu.Unlock()
+
+	time.Sleep(time.Millisecond)
+
+	return r.SequentialBatchSigner.SignBatch(c, requests)
+}
+
+func TestSigningQueueCoalescesConcurrentRequests(t *testing.T) {
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	signer := &recordingBatchSigner{SequentialBatchSigner: SequentialBatchSigner{Signer: key}}
+
+	q := NewSigningQueue(signer, WithMaxBatchSize(16), WithMaxLinger(5*time.Millisecond))
+	defer q.Close()
+
+	var wg sync.WaitGroup
+
+	for i := 0; i < 64; i++ {
+
+		wg.Add(1)
+
+		go func(i int) {
+			defer wg.Done()
+
+			digest := sha256.Sum256([]byte(fmt.Sprintf("msg-%d", i)))
+
+			sig, err := q.Sign(context.Background(), fmt.Sprintf("tenant-%d", i%3), digest[:], crypto.SHA256)
+
+			assert.NoError(t, err)
+			assert.True(t, ecdsa.VerifyASN1(&key.PublicKey, digest[:], sig))
+		}(i)
+
+	}
+
+	wg.Wait()
+
+	signer.mu.Lock()
+	defer signer.mu.Unlock()
+
+	total := 0
+
+	for _, size := range signer.batches {
+		assert.LessOrEqual(t, size, 16)
+		total += size
+	}
+
+	assert.Equal(t, 64, total)
+	assert.Less(t, len(signer.batches), 64)
+}
+
+func TestSigningQueueClosedFails(t *testing.T) {
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	q := NewSigningQueue(&SequentialBatchSigner{Signer: key})
+	q.Close()
+
+	_, err = q.Signer("tenant", key.Public()).Sign(nil, make([]byte, 32), crypto.SHA256)
+	assert.Equal(t, ErrSigningQueueClosed, err)
+}
+
+type gatedBatchSigner struct {
+	SequentialBatchSigner
+	gate chan struct{}
+}
+
+func (g *gatedBatchSigner) SignBatch(
+	c context.Context,
+	requests []ifcrypto.SignRequest,
+) []ifcrypto.SignResult {
+
+	<-g.gate
+	return g.SequentialBatchSigner.SignBatch(c, requests)
+
+}
+
+func TestSigningQueueStopDrains(t *testing.T) {
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	signer := &gatedBatchSigner{SequentialBatchSigner: SequentialBatchSigner{Signer: key}, gate: make(chan struct{})}
+	q := NewSigningQueue(signer, WithMaxLinger(time.Hour))
+
+	errs := make(chan error, 3)
+
+	for i := 0; i < 3; i++ {
+
+		go func() {
+			_, err := q.Sign(context.Background(), "tenant", make([]byte, 32), crypto.SHA256)
+			errs <- err
+		}()
+
+	}
+
+	// All lingers in the queue until stopped
+	require.Eventually(t, func() bool { return q.pending() == 3 }, 5*time.Second, time.Millisecond)
+
+	stopped := make(chan error, 1)
+	go func() { stopped <- q.Stop(context.Background()) }()
+
+	require.Eventually(t, func() bool {
+		_, err := q.Sign(context.Background(), "tenant", make([]byte, 32), crypto.SHA256)
+		return err == ErrSigningQueueClosed
+	}, 5*time.Second, time.Millisecond)
+
+	close(signer.gate)
+
+	for i := 0; i < 3; i++ {
+		assert.NoError(t, <-errs)
+	}
+
+	assert.NoError(t, <-stopped)
+
+}
@@ -0,0 +1,232 @@
+package gocrypto
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rand"
+	"fmt"
+	"hash"
+	"io"
+	"io/ioutil"
+
+	"github.com/mariotoffia/goservice/interfaces/ifcrypto"
+)
+
+// StreamOption configures a `SigningWriter` or `VerifyingReader`.
+type StreamOption func(s *streamConfig)
+
+type streamConfig struct {
+	hash crypto.Hash
+	out  io.Writer
+}
+
+// WithStreamHash sets the hash function, default _SHA-256_. It is ignored by _Ed25519_ keys
+// since those always uses _Ed25519ph_ with _SHA-512_.
+func WithStreamHash(hash crypto.Hash) StreamOption {
+	return func(s *streamConfig) {
+		s.hash = hash
+	}
+}
+
+// WithStreamOutput makes the `SigningWriter` write all data onto _w_ while hashing it, e.g. to
+// sign a artifact while it is uploaded. It is not used by the `VerifyingReader`.
+func WithStreamOutput(w io.Writer) StreamOption {
+	return func(s *streamConfig) {
+		s.out = w
+	}
+}
+
+// newStreamConfig applies the _opts_ and returns the hash and `crypto.SignerOpts` for _public_.
+func newStreamConfig(public crypto.PublicKey, opts []StreamOption) (*streamConfig, crypto.SignerOpts, error) {
+
+	cfg := &streamConfig{hash: crypto.SHA256, out: ioutil.Discard}
+
+	for _, opt := range opts {
+		opt(cfg)
+	}
+
+	var signerOpts crypto.SignerOpts = cfg.hash
+
+	if _, ok := public.(ed25519.PublicKey); ok {
+
+		cfg.hash = crypto.SHA512
+		signerOpts = &ed25519.Options{Hash: crypto.SHA512}
+
+	}
+
+	if !cfg.hash.Available() {
+		return nil, nil, fmt.Errorf("hash function %d is not available", cfg.hash)
+	}
+
+	return cfg, signerOpts, nil
+
+}
+
+// SigningWriter is a `io.WriteCloser` that hashes all data written to it and signs the digest on
+// `Close`, hence multi gigabyte artifacts may be signed without loading them into memory.
+//
+// _Ed25519_ keys can not sign a digest, the signature is therefore _Ed25519ph_, i.e. a
+// `*ed25519.Options` with _SHA-512_ must be used when verified.
+type SigningWriter struct {
+	signer    crypto.Signer
+	opts      crypto.SignerOpts
+	hash      hash.Hash
+	out       io.Writer
+	signature []byte
+	closed    bool
+}
+
+// NewSigningWriter creates a new `SigningWriter` that signs with _signer_.
+func NewSigningWriter(signer crypto.Signer, opts ...StreamOption) (*SigningWriter, error) {
+
+	cfg, signerOpts, err := newStreamConfig(signer.Public(), opts)
+	if err != nil {
+		return nil, err
+	}
+
+	return &SigningWriter{
+		signer: signer,
+		opts:   signerOpts,
+		hash:   cfg.hash.New(),
+		out:    cfg.out,
+	}, nil
+
+}
+
+// Write implements the `io.Writer` interface.
+func (w *SigningWriter) Write(p []byte) (int, error) {
+
+	if w.closed {
+		return 0, fmt.Errorf("write on closed signing writer")
+	}
+
+	n, err := w.out.Write(p)
+	w.hash.Write(p[:n])
+
+	return n, err
+
+}
+
+// Close signs the digest of all written data. The signature is available from `Signature`.
+func (w *SigningWriter) Close() error {
+
+	if w.closed {
+		return nil
+	}
+
+	w.closed = true
+
+	signature, err := w.signer.Sign(rand.Reader, w.hash.Sum(nil), w.opts)
+	if err != nil {
+		return err
+	}
+
+	w.signature = signature
+
+	return nil
+
+}
+
+// Signature returns the signature, it is `nil` until `Close` has succeeded.
+func (w *SigningWriter) Signature() []byte {
+	return w.signature
+}
+
+// SignerOpts returns the _opts_ that the signature must be verified with.
+func (w *SigningWriter) SignerOpts() crypto.SignerOpts {
+	return w.opts
+}
+
+// VerifyingReader is a `io.ReadCloser` that hashes all data read through it and verifies the
+// signature when the underlying reader is exhausted or on `Close`.
+//
+// When the end of data is reached and the signature do not verify, _Read_ returns
+// `ifcrypto.ErrInvalidSignature` instead of `io.EOF`. Consumers must not trust the data until
+// the end has been reached or `Close` returned `nil`.
+type VerifyingReader struct {
+	r         io.Reader
+	verifier  ifcrypto.SignatureVerifier
+	opts      crypto.SignerOpts
+	hash      hash.Hash
+	signature []byte
+	verified  bool
+	err       error
+}
+
+// NewVerifyingReader creates a new `VerifyingReader` that reads from _r_ and verifies the
+// _signature_ using _public_, which must implement `ifcrypto.SignatureVerifier`.
+func NewVerifyingReader(
+	r io.Reader,
+	public ifcrypto.PublicKey,
+	signature []byte,
+	opts ...StreamOption,
+) (*VerifyingReader, error) {
+
+	verifier, ok := public.(ifcrypto.SignatureVerifier)
+	if !ok {
+		return nil, fmt.Errorf("key %s do not support signature verification", public.GetID())
+	}
+
+	cfg, signerOpts, err := newStreamConfig(public.GetKey(), opts)
+	if err != nil {
+		return nil, err
+	}
+
+	return &VerifyingReader{
+		r:         r,
+		verifier:  verifier,
+		opts:      signerOpts,
+		hash:      cfg.hash.New(),
+		signature: signature,
+	}, nil
+
+}
+
+// Read implements the `io.Reader` interface.
+func (r *VerifyingReader) Read(p []byte) (int, error) {
+
+	n, err := r.r.Read(p)
+	r.hash.Write(p[:n])
+
+	if err == io.EOF {
+
+		if verr := r.verify(); verr != nil {
+			return n, verr
+		}
+
+	}
+
+	return n, err
+
+}
+
+// Close verifies the signature of the data read so far, unless already verified, and closes the
+// underlying reader if it is a `io.Closer`.
+func (r *VerifyingReader) Close() error {
+
+	err := r.verify()
+
+	if closer, ok := r.r.(io.Closer); ok {
+
+		if cerr := closer.Close(); err == nil {
+			err = cerr
+		}
+
+	}
+
+	return err
+
+}
+
+func (r *VerifyingReader) verify() error {
+
+	if !r.verified {
+
+		r.verified = true
+		r.err = r.verifier.Verify(r.hash.Sum(nil), r.signature, r.opts)
+
+	}
+
+	return r.err
+
+}
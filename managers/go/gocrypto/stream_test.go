@@ -0,0 +1,95 @@
+//go:build !verifyonly
+// +build !verifyonly
+
+package gocrypto
+
+import (
+	"bytes"
+	"crypto"
+	"errors"
+	"io"
+	"io/ioutil"
+	"testing"
+
+	"github.com/mariotoffia/goservice/interfaces/ifcrypto"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStreamingSignAndVerify(t *testing.T) {
+
+	rsaKey, err := NewRSAPrivateKey("rsa", 2048, ifcrypto.KeyUsageSign)
+	require.NoError(t, err)
+
+	ecKey, err := NewECDSAPrivateKey("ec", 256, ifcrypto.KeyUsageSign)
+	require.NoError(t, err)
+
+	edKey, err := NewED25519PrivateKey("ed", ifcrypto.KeyUsageSign)
+	require.NoError(t, err)
+
+	k1Key, err := NewSecp256k1PrivateKey("k1", ifcrypto.KeyUsageSign)
+	require.NoError(t, err)
+
+	payload := bytes.Repeat([]byte("0123456789abcdef"), 64*1024)
+
+	for _, key := range []ifcrypto.KeyPair{rsaKey, ecKey, edKey, k1Key} {
+
+		t.Run(key.GetID(), func(t *testing.T) {
+
+			var out bytes.Buffer
+
+			w, err := NewSigningWriter(key.(crypto.Signer), WithStreamOutput(&out), WithStreamHash(crypto.SHA384))
+			require.NoError(t, err)
+
+			_, err = io.Copy(w, bytes.NewReader(payload))
+			require.NoError(t, err)
+			require.NoError(t, w.Close())
+
+			assert.Equal(t, payload, out.Bytes())
+			require.NotEmpty(t, w.Signature())
+
+			r, err := NewVerifyingReader(&out, key.GetPublic(), w.Signature(), WithStreamHash(crypto.SHA384))
+			require.NoError(t, err)
+
+			data, err := ioutil.ReadAll(r)
+			require.NoError(t, err)
+			assert.Equal(t, payload, data)
+			assert.NoError(t, r.Close())
+
+			tampered := append([]byte{}, payload...)
+			tampered[len(tampered)/2] ^= 1
+
+			r, err = NewVerifyingReader(bytes.NewReader(tampered), key.GetPublic(), w.Signature(), WithStreamHash(crypto.SHA384))
+			require.NoError(t, err)
+
+			_, err = ioutil.ReadAll(r)
+			assert.True(t, errors.Is(err, ifcrypto.ErrInvalidSignature))
+			assert.True(t, errors.Is(r.Close(), ifcrypto.ErrInvalidSignature))
+
+		})
+
+	}
+
+}
+
+func TestVerifyingReaderCloseBeforeEOF(t *testing.T) {
+
+	key, err := NewECDSAPrivateKey("ec", 256, ifcrypto.KeyUsageSign)
+	require.NoError(t, err)
+
+	w, err := NewSigningWriter(key)
+	require.NoError(t, err)
+
+	_, err = w.Write([]byte("a payload"))
+	require.NoError(t, err)
+	require.NoError(t, w.Close())
+
+	r, err := NewVerifyingReader(bytes.NewReader([]byte("a payload")), key.GetPublic(), w.Signature())
+	require.NoError(t, err)
+
+	_, err = r.Read(make([]byte, 2))
+	require.NoError(t, err)
+
+	assert.True(t, errors.Is(r.Close(), ifcrypto.ErrInvalidSignature))
+
+}
@@ -0,0 +1,183 @@
+package gocrypto
+
+import (
+	"crypto/cipher"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/pem"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/mariotoffia/goservice/interfaces/ifcrypto"
+	"github.com/mariotoffia/goservice/utils/cryptoutils"
+)
+
+// SymmetricKeyPEMType is the _PEM_ block type used to armor a `SymmetricKey`.
+const SymmetricKeyPEMType = "AES KEY"
+
+// SymmetricKey implements the `ifcrypto.KeyPair` interface for a raw _AES-128_, _AES-192_
+// or _AES-256_ key.
+//
+// A symmetric key has no public portion, hence `GetPublic` returns `nil`.
+type SymmetricKey struct {
+	KeyBase
+	key []byte
+}
+
+// NewSymmetricKeyFromHex creates a new `SymmetricKey` from a hex encoded key.
+func NewSymmetricKeyFromHex(id, key string, usage ...ifcrypto.KeyUsage) (*SymmetricKey, error) {
+
+	data, err := hex.DecodeString(strings.TrimSpace(key))
+	if err != nil {
+		return nil, err
+	}
+
+	return NewSymmetricKeyFromBytes(id, data, usage...)
+
+}
+
+// NewSymmetricKeyFromBase64 creates a new `SymmetricKey` from a standard, or _URL_ safe,
+// base64 encoded key with or without padding.
+func NewSymmetricKeyFromBase64(id, key string, usage ...ifcrypto.KeyUsage) (*SymmetricKey, error) {
+
+	key = strings.TrimRight(strings.TrimSpace(key), "=")
+	key = strings.NewReplacer("-", "+", "_", "/").Replace(key)
+
+	data, err := base64.RawStdEncoding.DecodeString(key)
+	if err != nil {
+		return nil, err
+	}
+
+	return NewSymmetricKeyFromBytes(id, data, usage...)
+
+}
+
+// NewSymmetricKeyFromPEM initializes a new `SymmetricKey` from the underlying _PEM_ block.
+func NewSymmetricKeyFromPEM(
+	block pem.Block,
+	id string,
+	usage ...ifcrypto.KeyUsage,
+) (*SymmetricKey, error) {
+
+	if block.Type == SymmetricKeyPEMType {
+		return NewSymmetricKeyFromBytes(id, block.Bytes, usage...)
+	}
+
+	return nil, fmt.Errorf("unsupported PEM block: %s", block.Type)
+
+}
+
+// NewSymmetricKey generates a new `SymmetricKey` of _bits_ (128, 192 or 256) using the
+// `rand.Reader`, or the `EntropyGuard` reader, as entropy.
+func NewSymmetricKey(id string, bits int, usage ...ifcrypto.KeyUsage) (*SymmetricKey, error) {
+
+	if bits != 128 && bits != 192 && bits != 256 {
+		return nil, fmt.Errorf("invalid AES key size: %d bits", bits)
+	}
+
+	reader, err := keyGenerationReader()
+	if err != nil {
+		return nil, err
+	}
+
+	key := make([]byte, bits/8)
+
+	if _, err := io.ReadFull(reader, key); err != nil {
+		return nil, err
+	}
+
+	return NewSymmetricKeyFromBytes(id, key, usage...)
+
+}
+
+// GetPublic returns `nil` since a symmetric key has no public portion.
+func (r *SymmetricKey) GetPublic() ifcrypto.PublicKey {
+	return nil
+}
+
+// AEAD returns the _AES-GCM_ _chiper_ keyed with this key. The _chiper_ must match the key
+// size, e.g. `ifcrypto.ChiperAES256` for a 256 bit key.
+func (r *SymmetricKey) AEAD(chiper ifcrypto.Chipher) (cipher.AEAD, error) {
+
+	if len(r.chiper) == 0 || chiper != r.chiper[0] {
+		return nil, fmt.Errorf("unsupported chiper for %d bit AES key: %s", r.GetKeySize(), chiper)
+	}
+
+	return NewAEAD(chiper, r.key)
+
+}
+
+// Encrypt encrypts and authenticates the _plaintext_ and authenticates the _additionalData_
+// using the _chiper_. The random nonce is prepended to the returned ciphertext.
+//
+// The 96 bit _GCM_ nonce must not be randomly generated for more than 2^32 messages with the
+// same key.
+func (r *SymmetricKey) Encrypt(chiper ifcrypto.Chipher, plaintext, additionalData []byte) ([]byte, error) {
+
+	if err := CheckAlgorithm(ifcrypto.PolicyOperationEncrypt, r, 0); err != nil {
+		return nil, err
+	}
+
+	aead, err := r.AEAD(chiper)
+	if err != nil {
+		return nil, err
+	}
+
+	return sealAEAD(aead, plaintext, additionalData)
+
+}
+
+// PEMWrite will write the key onto _w_ as a _AES KEY_ PEM block.
+//
+// Since there is no public portion, the _public_ parameter is ignored.
+func (r *SymmetricKey) PEMWrite(w io.Writer, public bool) error {
+
+	return pem.Encode(w, &pem.Block{Type: SymmetricKeyPEMType, Bytes: r.key})
+
+}
+
+// DERWrite implements the `ifcrypto.DERWriter` interface, the raw key bytes are written. Use
+// `NewSymmetricKeyFromBytes` to read them.
+func (r *SymmetricKey) DERWrite(w io.Writer) error {
+
+	_, err := w.Write(r.key)
+
+	return err
+
+}
+
+// ToJWK implements the `JWKMarshaler` interface and returns a _oct_ _JWK_ with the secret key.
+func (r *SymmetricKey) ToJWK() (*cryptoutils.JSONWebKey, error) {
+	return keyToJWK(r)
+}
+
+// GetKey gets the underlying key, the raw key bytes.
+//
+// Some keys are remote and not possible to fetch. In such situations the function returns a remote id,
+// most often the same as GetID() returns.
+func (r *SymmetricKey) GetKey() interface{} {
+	return r.key
+}
+
+// IsSymmetric returns `true` if this is a `KeyTypeSymmetric`
+//
+// This is a convenience function instead of `GetKeyType`.
+func (r *SymmetricKey) IsSymmetric() bool {
+	return true
+}
+
+// IsPrivate returns `true` if this is a `KeyType` other than `KeyTypeSymmetric` and is a private key.
+//
+// If `KeyTypeSymmetric` it will return `true` since all symmetric keys are considered as private.
+func (r *SymmetricKey) IsPrivate() bool {
+	return true
+}
+
+// IsRemoteKey returns `true` if the key is not present in current process memory.
+//
+// Typically hardware units or remote services will not reveal their private key. In such case, this
+// method returns `true`. If present in memory such as a `*rsa.PrivateKey` it returns `false`.
+func (r *SymmetricKey) IsRemoteKey() bool {
+	return false
+}
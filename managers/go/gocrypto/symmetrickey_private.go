@@ -0,0 +1,70 @@
+//go:build !verifyonly
+// +build !verifyonly
+
+package gocrypto
+
+import (
+	"fmt"
+
+	"github.com/mariotoffia/goservice/interfaces/ifcrypto"
+)
+
+// NewSymmetricKeyFromBytes creates a new `SymmetricKey` from a 16, 24 or 32 byte _key_.
+//
+// The _key_ is copied.
+func NewSymmetricKeyFromBytes(
+	id string,
+	key []byte,
+	usage ...ifcrypto.KeyUsage,
+) (*SymmetricKey, error) {
+
+	if IsVerifyOnly() {
+		return nil, ErrVerifyOnly
+	}
+
+	var chiper ifcrypto.Chipher
+
+	switch len(key) {
+	case 16:
+		chiper = ifcrypto.ChiperAES128
+	case 24:
+		chiper = ifcrypto.ChiperAES192
+	case 32:
+		chiper = ifcrypto.ChiperAES256
+	default:
+		return nil, fmt.Errorf("invalid AES key size: %d bytes", len(key))
+	}
+
+	return &SymmetricKey{
+		KeyBase: KeyBase{
+			id:      id,
+			keyType: ifcrypto.KeyTypeSymmetric,
+			keySize: len(key) * 8,
+			usage:   usage,
+			chiper:  []ifcrypto.Chipher{chiper},
+		},
+		key: append([]byte{}, key...),
+	}, nil
+
+}
+
+// Decrypt decrypts and authenticates _ciphertext_, created by `Encrypt`, and authenticates
+// the _additionalData_ using the _chiper_.
+func (r *SymmetricKey) Decrypt(chiper ifcrypto.Chipher, ciphertext, additionalData []byte) ([]byte, error) {
+
+	if IsVerifyOnly() {
+		return nil, ErrVerifyOnly
+	}
+
+	if err := CheckAlgorithm(ifcrypto.PolicyOperationDecrypt, r, 0); err != nil {
+		return nil, err
+	}
+
+	aead, err := r.AEAD(chiper)
+	if err != nil {
+		return nil, err
+	}
+
+	return openAEAD(aead, ciphertext, additionalData)
+
+}
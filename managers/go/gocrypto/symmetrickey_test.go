@@ -0,0 +1,53 @@
+//go:build !verifyonly
+// +build !verifyonly
+
+package gocrypto
+
+import (
+	"bytes"
+	"encoding/pem"
+	"testing"
+
+	"github.com/mariotoffia/goservice/interfaces/ifcrypto"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSymmetricKey(t *testing.T) {
+
+	key, err := NewSymmetricKey("dek", 256, ifcrypto.KeyUsageEncrypt, ifcrypto.KeyUsageDecrypt)
+	require.NoError(t, err)
+
+	var _ ifcrypto.KeyPair = key
+
+	assert.True(t, key.IsSymmetric())
+	assert.True(t, key.IsPrivate())
+	assert.Nil(t, key.GetPublic())
+	assert.Equal(t, 256, key.GetKeySize())
+	assert.Equal(t, []ifcrypto.Chipher{ifcrypto.ChiperAES256}, key.GetSupportedChiphers())
+
+	var buf bytes.Buffer
+	require.NoError(t, key.PEMWrite(&buf, true))
+
+	block, _ := pem.Decode(buf.Bytes())
+	require.NotNil(t, block)
+
+	loaded, err := NewSymmetricKeyFromPEM(*block, "dek")
+	require.NoError(t, err)
+	assert.Equal(t, key.GetKey(), loaded.GetKey())
+
+	fromHex, err := NewSymmetricKeyFromHex("k", "000102030405060708090a0b0c0d0e0f")
+	require.NoError(t, err)
+	assert.Equal(t, 128, fromHex.GetKeySize())
+
+	fromB64, err := NewSymmetricKeyFromBase64("k", "AAECAwQFBgcICQoLDA0ODw==")
+	require.NoError(t, err)
+	assert.Equal(t, fromHex.GetKey(), fromB64.GetKey())
+
+	_, err = NewSymmetricKeyFromHex("k", "0001")
+	assert.Error(t, err)
+
+	_, err = NewSymmetricKey("k", 512)
+	assert.Error(t, err)
+
+}
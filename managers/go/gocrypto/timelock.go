@@ -0,0 +1,254 @@
+package gocrypto
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+
+	"github.com/mariotoffia/goservice/interfaces/ifcrypto"
+	"golang.org/x/crypto/curve25519"
+	"golang.org/x/crypto/hkdf"
+)
+
+// timeLockInfo is the _HKDF_ info used to derive the content key.
+const timeLockInfo = "goservice timelock v1"
+
+// TimeLocked is data encrypted with `TimeLock.Encrypt`.
+type TimeLocked struct {
+	// Epoch is the id of the release epoch.
+	Epoch string `json:"epoch"`
+	// ReleaseAt is when the data may be decrypted.
+	ReleaseAt time.Time `json:"release_at"`
+	// EphemeralKey is the ephemeral _X25519_ public key.
+	EphemeralKey []byte `json:"ephemeral_key"`
+	// Ciphertext is the _AES-256-GCM_ nonce and ciphertext.
+	Ciphertext []byte `json:"ciphertext"`
+}
+
+// TimeLock encrypts data such that it can only be decrypted after a given time, for example
+// embargoed releases and delayed disclosures.
+//
+// The data is encrypted using _ECIES_ to the _X25519_ public key of the release epoch of a
+// `ifcrypto.KeyReleaseService`, hence decryption requires the epoch private key that the
+// service releases at the release time.
+type TimeLock struct {
+	service ifcrypto.KeyReleaseService
+}
+
+// NewTimeLock creates a new `TimeLock` backed by the _service_.
+func NewTimeLock(service ifcrypto.KeyReleaseService) *TimeLock {
+	return &TimeLock{service: service}
+}
+
+// Encrypt encrypts the _plaintext_ so that it can be decrypted at, or after, _releaseAt_.
+//
+// The actual release time is the first epoch of the service at or after _releaseAt_.
+func (t *TimeLock) Encrypt(c context.Context, releaseAt time.Time, plaintext []byte) (*TimeLocked, error) {
+
+	epoch, err := t.service.Epoch(c, releaseAt)
+	if err != nil {
+		return nil, err
+	}
+
+	if epoch.ReleaseAt.Before(releaseAt) {
+		return nil, fmt.Errorf("epoch %s releases before %s", epoch.ID, releaseAt)
+	}
+
+	ephemeral := make([]byte, curve25519.ScalarSize)
+
+	if _, err := io.ReadFull(rand.Reader, ephemeral); err != nil {
+		return nil, err
+	}
+
+	ephemeralPub, err := curve25519.X25519(ephemeral, curve25519.Basepoint)
+	if err != nil {
+		return nil, err
+	}
+
+	shared, err := curve25519.X25519(ephemeral, epoch.PublicKey)
+	if err != nil {
+		return nil, err
+	}
+
+	locked := &TimeLocked{Epoch: epoch.ID, ReleaseAt: epoch.ReleaseAt.UTC(), EphemeralKey: ephemeralPub}
+
+	aead, err := timeLockAEAD(shared, locked)
+	if err != nil {
+		return nil, err
+	}
+
+	nonce := make([]byte, aead.NonceSize())
+
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return nil, err
+	}
+
+	locked.Ciphertext = aead.Seal(nonce, nonce, plaintext, timeLockAAD(locked))
+
+	return locked, nil
+
+}
+
+// Decrypt decrypts the _locked_ data. It returns `ifcrypto.ErrNotYetReleased` if the
+// release time has not been reached.
+func (t *TimeLock) Decrypt(c context.Context, locked *TimeLocked) ([]byte, error) {
+
+	priv, err := t.service.PrivateKey(c, locked.Epoch)
+	if err != nil {
+		return nil, err
+	}
+
+	shared, err := curve25519.X25519(priv, locked.EphemeralKey)
+	if err != nil {
+		return nil, err
+	}
+
+	aead, err := timeLockAEAD(shared, locked)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(locked.Ciphertext) < aead.NonceSize() {
+		return nil, fmt.Errorf("time locked ciphertext too short")
+	}
+
+	return aead.Open(
+		nil,
+		locked.Ciphertext[:aead.NonceSize()],
+		locked.Ciphertext[aead.NonceSize():],
+		timeLockAAD(locked),
+	)
+
+}
+
+// timeLockAEAD derives the content key from the _shared_ secret and the ephemeral key.
+func timeLockAEAD(shared []byte, locked *TimeLocked) (cipher.AEAD, error) {
+
+	key := make([]byte, 32)
+
+	if _, err := io.ReadFull(hkdf.New(sha256.New, shared, locked.EphemeralKey, []byte(timeLockInfo)), key); err != nil {
+		return nil, err
+	}
+
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+
+	return cipher.NewGCM(block)
+
+}
+
+// timeLockAAD binds the epoch and release time to the ciphertext.
+func timeLockAAD(locked *TimeLocked) []byte {
+
+	aad, _ := json.Marshal([]string{locked.Epoch, strconv.FormatInt(locked.ReleaseAt.Unix(), 10)})
+	return aad
+
+}
+
+// KeyReleaseServiceOption configures a `LocalKeyReleaseService`.
+type KeyReleaseServiceOption func(s *LocalKeyReleaseService)
+
+// WithReleaseClock sets the clock used to decide if a epoch has been released.
+func WithReleaseClock(clock func() time.Time) KeyReleaseServiceOption {
+	return func(s *LocalKeyReleaseService) {
+		s.clock = clock
+	}
+}
+
+// LocalKeyReleaseService is a `ifcrypto.KeyReleaseService` with fixed length epochs where
+// the epoch keys are derived from a master secret.
+//
+// It is intended to be hosted by a trusted party, or used in tests, since anyone holding
+// the master secret can derive all epoch private keys.
+type LocalKeyReleaseService struct {
+	master []byte
+	period time.Duration
+	clock  func() time.Time
+}
+
+// NewLocalKeyReleaseService creates a new `LocalKeyReleaseService` with epochs of _period_
+// length, starting at the _UNIX_ epoch. The _master_ secret must be at least 32 bytes.
+func NewLocalKeyReleaseService(
+	master []byte,
+	period time.Duration,
+	opts ...KeyReleaseServiceOption,
+) (*LocalKeyReleaseService, error) {
+
+	if len(master) < 32 {
+		return nil, fmt.Errorf("master secret must be at least 32 bytes")
+	}
+
+	if period < time.Second {
+		return nil, fmt.Errorf("epoch period must be at least one second")
+	}
+
+	s := &LocalKeyReleaseService{master: append([]byte{}, master...), period: period, clock: time.Now}
+
+	for _, opt := range opts {
+		opt(s)
+	}
+
+	return s, nil
+
+}
+
+// Epoch implements the `ifcrypto.KeyReleaseService` interface.
+func (s *LocalKeyReleaseService) Epoch(c context.Context, at time.Time) (*ifcrypto.TimeLockEpoch, error) {
+
+	if at.Unix() < 0 {
+		return nil, fmt.Errorf("release time before the UNIX epoch")
+	}
+
+	seconds := int64(s.period / time.Second)
+	number := (at.Unix() + seconds - 1) / seconds
+
+	id := strconv.FormatInt(number, 10)
+
+	pub, err := curve25519.X25519(s.epochKey(id), curve25519.Basepoint)
+	if err != nil {
+		return nil, err
+	}
+
+	return &ifcrypto.TimeLockEpoch{
+		ID:        id,
+		ReleaseAt: time.Unix(number*seconds, 0).UTC(),
+		PublicKey: pub,
+	}, nil
+
+}
+
+// PrivateKey implements the `ifcrypto.KeyReleaseService` interface.
+func (s *LocalKeyReleaseService) PrivateKey(c context.Context, id string) ([]byte, error) {
+
+	number, err := strconv.ParseInt(id, 10, 64)
+	if err != nil || number < 0 {
+		return nil, fmt.Errorf("invalid epoch: %s", id)
+	}
+
+	if s.clock().Before(time.Unix(number*int64(s.period/time.Second), 0)) {
+		return nil, ifcrypto.ErrNotYetReleased
+	}
+
+	return s.epochKey(id), nil
+
+}
+
+// epochKey derives the private key of the epoch _id_.
+func (s *LocalKeyReleaseService) epochKey(id string) []byte {
+
+	mac := hmac.New(sha256.New, s.master)
+	mac.Write([]byte("timelock epoch " + id))
+
+	return mac.Sum(nil)
+
+}
@@ -0,0 +1,47 @@
+package gocrypto
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/mariotoffia/goservice/interfaces/ifcrypto"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTimeLock(t *testing.T) {
+
+	now := time.Unix(1700000000, 0)
+
+	service, err := NewLocalKeyReleaseService(bytes.Repeat([]byte{7}, 32), time.Hour,
+		WithReleaseClock(func() time.Time { return now }),
+	)
+
+	require.NoError(t, err)
+
+	lock := NewTimeLock(service)
+	ctx := context.Background()
+
+	locked, err := lock.Encrypt(ctx, now.Add(90*time.Minute), []byte("embargoed"))
+	require.NoError(t, err)
+	assert.False(t, locked.ReleaseAt.Before(now.Add(90*time.Minute)))
+	assert.True(t, locked.ReleaseAt.Before(now.Add(150*time.Minute)))
+
+	_, err = lock.Decrypt(ctx, locked)
+	assert.True(t, errors.Is(err, ifcrypto.ErrNotYetReleased))
+
+	now = locked.ReleaseAt
+
+	plain, err := lock.Decrypt(ctx, locked)
+	require.NoError(t, err)
+	assert.Equal(t, "embargoed", string(plain))
+
+	// Moving the data to a earlier epoch must not decrypt
+	locked.Epoch = "1"
+	_, err = lock.Decrypt(ctx, locked)
+	assert.Error(t, err)
+
+}
@@ -0,0 +1,1055 @@
+package gocrypto
+
+import (
+	"bytes"
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"strconv"
+	"strings"
+
+	"github.com/mariotoffia/goservice/interfaces/ifcrypto"
+)
+
+// tinkTypeURLPrefix prefixes all _Tink_ key type _URL_.
+const tinkTypeURLPrefix = "type.googleapis.com/google.crypto.tink."
+
+// The _Tink_ key types that maps to the key types of this package.
+const (
+	tinkAesGcmKey            = tinkTypeURLPrefix + "AesGcmKey"
+	tinkChaCha20Poly1305Key  = tinkTypeURLPrefix + "ChaCha20Poly1305Key"
+	tinkXChaCha20Poly1305Key = tinkTypeURLPrefix + "XChaCha20Poly1305Key"
+	tinkHmacKey              = tinkTypeURLPrefix + "HmacKey"
+	tinkEd25519PrivateKey    = tinkTypeURLPrefix + "Ed25519PrivateKey"
+	tinkEd25519PublicKey     = tinkTypeURLPrefix + "Ed25519PublicKey"
+	tinkEcdsaPrivateKey      = tinkTypeURLPrefix + "EcdsaPrivateKey"
+	tinkEcdsaPublicKey       = tinkTypeURLPrefix + "EcdsaPublicKey"
+)
+
+// The _Tink_ _KeyMaterialType_ and _EcdsaSignatureEncoding_ enums.
+const (
+	tinkMaterialSymmetric     = 1
+	tinkMaterialPrivate       = 2
+	tinkMaterialPublic        = 3
+	tinkEcdsaEncodingIEEE1363 = 1
+	tinkEcdsaEncodingDER      = 2
+)
+
+// tinkMaterialTypes maps the _Tink_ _KeyMaterialType_ to and from its _JSON_ name.
+var tinkMaterialTypes = map[uint64]string{
+	tinkMaterialSymmetric: "SYMMETRIC",
+	tinkMaterialPrivate:   "ASYMMETRIC_PRIVATE",
+	tinkMaterialPublic:    "ASYMMETRIC_PUBLIC",
+	4:                     "REMOTE",
+}
+
+// tinkHashes maps the _Tink_ _HashType_ to the `crypto.Hash`.
+var tinkHashes = map[uint64]crypto.Hash{
+	1: crypto.SHA1,
+	2: crypto.SHA384,
+	3: crypto.SHA256,
+	4: crypto.SHA512,
+	5: crypto.SHA224,
+}
+
+// tinkCurves maps the _Tink_ _EllipticCurveType_ to the _NIST_ curve and its hash.
+var tinkCurves = map[uint64]struct {
+	curve elliptic.Curve
+	hash  uint64
+}{
+	2: {elliptic.P256(), 3},
+	3: {elliptic.P384(), 2},
+	4: {elliptic.P521(), 4},
+}
+
+// TinkKeyStatus is the status of a key in a _Tink_ keyset.
+type TinkKeyStatus uint32
+
+const (
+	// TinkKeyEnabled is a key that can be used.
+	TinkKeyEnabled TinkKeyStatus = 1
+	// TinkKeyDisabled is a key that is kept in the keyset but must not be used.
+	TinkKeyDisabled TinkKeyStatus = 2
+	// TinkKeyDestroyed is a key without key material.
+	TinkKeyDestroyed TinkKeyStatus = 3
+)
+
+// TinkOutputPrefix is how _Tink_ prefixes the signatures, _MAC_ and ciphertexts of a key.
+type TinkOutputPrefix uint32
+
+const (
+	// TinkPrefixTink prefixes with _0x01_ and the key id.
+	TinkPrefixTink TinkOutputPrefix = 1
+	// TinkPrefixLegacy prefixes with _0x00_ and the key id, and signs the message with a
+	// trailing zero byte.
+	TinkPrefixLegacy TinkOutputPrefix = 2
+	// TinkPrefixRaw do not prefix.
+	TinkPrefixRaw TinkOutputPrefix = 3
+	// TinkPrefixCrunchy prefixes with _0x00_ and the key id.
+	TinkPrefixCrunchy TinkOutputPrefix = 4
+)
+
+// tinkStatusNames and tinkPrefixNames are the _JSON_ names of the enums.
+var (
+	tinkStatusNames = map[TinkKeyStatus]string{
+		TinkKeyEnabled:   "ENABLED",
+		TinkKeyDisabled:  "DISABLED",
+		TinkKeyDestroyed: "DESTROYED",
+	}
+	tinkPrefixNames = map[TinkOutputPrefix]string{
+		TinkPrefixTink:    "TINK",
+		TinkPrefixLegacy:  "LEGACY",
+		TinkPrefixRaw:     "RAW",
+		TinkPrefixCrunchy: "CRUNCHY",
+	}
+)
+
+// TinkKey is a key in a `TinkKeyset`.
+type TinkKey struct {
+	// Key is the key, its id is the decimal _Tink_ key id.
+	Key          ifcrypto.Key
+	KeyID        uint32
+	Status       TinkKeyStatus
+	OutputPrefix TinkOutputPrefix
+}
+
+// Prefix returns the bytes that _Tink_ prepends to the signatures, _MAC_ and ciphertexts of
+// the key, or `nil` for `TinkPrefixRaw`.
+//
+// The keys of this package never prefix, hence prepend, or strip, the prefix to interoperate
+// with _Tink_.
+func (k *TinkKey) Prefix() []byte {
+
+	var prefix []byte
+
+	switch k.OutputPrefix {
+	case TinkPrefixTink:
+		prefix = []byte{1, 0, 0, 0, 0}
+	case TinkPrefixLegacy, TinkPrefixCrunchy:
+		prefix = []byte{0, 0, 0, 0, 0}
+	default:
+		return nil
+	}
+
+	binary.BigEndian.PutUint32(prefix[1:], k.KeyID)
+
+	return prefix
+
+}
+
+// TinkKEK is the key encryption key of a encrypted _Tink_ keyset, e.g. a `*SymmetricKey` or a
+// `*ChaChaKey`.
+//
+// The first supported chiper of the key is used, its output, the nonce followed by the
+// ciphertext and tag, is the same as a _RAW_ _Tink_ _AES-GCM_ or _XChaCha20-Poly1305_ _AEAD_.
+type TinkKEK interface {
+	ifcrypto.Key
+	Encrypt(chiper ifcrypto.Chipher, plaintext, additionalData []byte) ([]byte, error)
+	Decrypt(chiper ifcrypto.Chipher, ciphertext, additionalData []byte) ([]byte, error)
+}
+
+// TinkKeyset is a _Google Tink_ keyset mapped to the key types of this package, for teams that
+// migrates from _Tink_ and wants to keep their keysets.
+//
+// The _AES-GCM_, _ChaCha20-Poly1305_, _XChaCha20-Poly1305_, _HMAC_, _Ed25519_ and _ECDSA_
+// (_P-256_, _P-384_ and _P-521_ with _DER_ signatures) keys are supported. Other key types,
+// e.g. _RSA_ or _AES-GCM-SIV_, fails the parse.
+//
+// .Example
+// [source,go]
+// ----
+// keyset, err := ParseEncryptedTinkKeyset(data, kek, nil)
+//
+// err = keyset.Import(c, store)
+// ----
+type TinkKeyset struct {
+	PrimaryKeyID uint32
+	Keys         []TinkKey
+}
+
+// NewTinkKeyset creates a new `TinkKeyset`, with the first key as primary, from _keys_.
+//
+// The keys are enabled and has the _RAW_ output prefix, thus are used the same way by _Tink_
+// as by this package. A key id that is a decimal `uint32` is kept, otherwise a random _Tink_
+// key id is assigned.
+func NewTinkKeyset(keys ...ifcrypto.Key) (*TinkKeyset, error) {
+
+	if len(keys) == 0 {
+		return nil, fmt.Errorf("a Tink keyset requires at least one key")
+	}
+
+	keyset := &TinkKeyset{}
+	used := map[uint32]bool{}
+
+	for _, key := range keys {
+
+		if _, err := tinkKeyData(key); err != nil {
+			return nil, err
+		}
+
+		id, err := tinkKeyID(key.GetID(), used)
+		if err != nil {
+			return nil, err
+		}
+
+		keyset.Keys = append(keyset.Keys, TinkKey{
+			Key:          key,
+			KeyID:        id,
+			Status:       TinkKeyEnabled,
+			OutputPrefix: TinkPrefixRaw,
+		})
+
+	}
+
+	keyset.PrimaryKeyID = keyset.Keys[0].KeyID
+
+	return keyset, nil
+
+}
+
+// ParseTinkKeyset parses a cleartext _Tink_ keyset in the binary or _JSON_ format.
+//
+// Destroyed keys are left out since they have no key material. Secret and private keys fails
+// with `ErrVerifyOnly` in verify-only mode.
+func ParseTinkKeyset(data []byte) (*TinkKeyset, error) {
+
+	if isTinkJSON(data) {
+
+		var keyset tinkJSONKeyset
+
+		if err := json.Unmarshal(data, &keyset); err != nil {
+			return nil, err
+		}
+
+		return keyset.keyset()
+
+	}
+
+	return parseTinkKeyset(data)
+
+}
+
+// ParseEncryptedTinkKeyset parses a _Tink_ keyset encrypted by the _kek_, with the
+// _associatedData_, in the binary or _JSON_ format, see `ParseTinkKeyset`.
+func ParseEncryptedTinkKeyset(data []byte, kek TinkKEK, associatedData []byte) (*TinkKeyset, error) {
+
+	var encrypted []byte
+
+	if isTinkJSON(data) {
+
+		var keyset struct {
+			EncryptedKeyset []byte `json:"encryptedKeyset"`
+		}
+
+		if err := json.Unmarshal(data, &keyset); err != nil {
+			return nil, err
+		}
+
+		encrypted = keyset.EncryptedKeyset
+
+	} else {
+
+		fields, err := readProtoFields(data)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, field := range fields {
+
+			if field.num == 2 {
+				encrypted = field.bytes
+			}
+
+		}
+
+	}
+
+	if len(encrypted) == 0 {
+		return nil, fmt.Errorf("Tink keyset is not encrypted")
+	}
+
+	chiper, err := tinkKEKChiper(kek)
+	if err != nil {
+		return nil, err
+	}
+
+	plaintext, err := kek.Decrypt(chiper, encrypted, associatedData)
+	if err != nil {
+		return nil, fmt.Errorf("decrypt Tink keyset with %s: %w", kek.GetID(), err)
+	}
+
+	return parseTinkKeyset(plaintext)
+
+}
+
+// Primary returns the primary key, or `nil` if not in the keyset.
+func (k *TinkKeyset) Primary() *TinkKey {
+
+	for i := range k.Keys {
+
+		if k.Keys[i].KeyID == k.PrimaryKeyID {
+			return &k.Keys[i]
+		}
+
+	}
+
+	return nil
+
+}
+
+// Import puts the enabled keys into the _store_.
+func (k *TinkKeyset) Import(c context.Context, store ifcrypto.KeyStore) error {
+
+	for _, key := range k.Keys {
+
+		if key.Status != TinkKeyEnabled {
+			continue
+		}
+
+		if err := store.Put(c, key.Key); err != nil {
+			return err
+		}
+
+	}
+
+	return nil
+
+}
+
+// Marshal returns the cleartext keyset in the binary _Tink_ format.
+func (k *TinkKeyset) Marshal() ([]byte, error) {
+
+	buf := appendProtoVarint(nil, 1, uint64(k.PrimaryKeyID))
+
+	for _, key := range k.Keys {
+
+		data, err := tinkKeyData(key.Key)
+		if err != nil {
+			return nil, err
+		}
+
+		var msg []byte
+
+		msg = appendProtoField(msg, 1, data.marshal())
+		msg = appendProtoVarint(msg, 2, uint64(key.Status))
+		msg = appendProtoVarint(msg, 3, uint64(key.KeyID))
+		msg = appendProtoVarint(msg, 4, uint64(key.OutputPrefix))
+
+		buf = appendProtoField(buf, 2, msg)
+
+	}
+
+	return buf, nil
+
+}
+
+// MarshalJSON implements the `json.Marshaler` interface and returns the cleartext keyset in
+// the _JSON_ _Tink_ format.
+func (k *TinkKeyset) MarshalJSON() ([]byte, error) {
+
+	keyset := tinkJSONKeyset{PrimaryKeyID: k.PrimaryKeyID, Key: []tinkJSONKey{}}
+
+	for _, key := range k.Keys {
+
+		data, err := tinkKeyData(key.Key)
+		if err != nil {
+			return nil, err
+		}
+
+		var entry tinkJSONKey
+
+		entry.KeyData.TypeURL = data.typeURL
+		entry.KeyData.Value = data.value
+		entry.KeyData.KeyMaterialType = tinkMaterialTypes[data.material]
+		entry.Status = tinkStatusNames[key.Status]
+		entry.KeyID = key.KeyID
+		entry.OutputPrefixType = tinkPrefixNames[key.OutputPrefix]
+
+		keyset.Key = append(keyset.Key, entry)
+
+	}
+
+	return json.Marshal(&keyset)
+
+}
+
+// Encrypt returns the keyset encrypted by the _kek_, with the _associatedData_, in the binary
+// _Tink_ format.
+func (k *TinkKeyset) Encrypt(kek TinkKEK, associatedData []byte) ([]byte, error) {
+
+	plaintext, err := k.Marshal()
+	if err != nil {
+		return nil, err
+	}
+
+	chiper, err := tinkKEKChiper(kek)
+	if err != nil {
+		return nil, err
+	}
+
+	encrypted, err := kek.Encrypt(chiper, plaintext, associatedData)
+	if err != nil {
+		return nil, err
+	}
+
+	return appendProtoField(nil, 2, encrypted), nil
+
+}
+
+// tinkKEKChiper returns the first supported chiper of the _kek_.
+func tinkKEKChiper(kek TinkKEK) (ifcrypto.Chipher, error) {
+
+	if chipers := kek.GetSupportedChiphers(); len(chipers) > 0 {
+		return chipers[0], nil
+	}
+
+	return "", fmt.Errorf("key %s do not support any chiper", kek.GetID())
+
+}
+
+// tinkKeyID returns _id_ as a _Tink_ key id, or a random one, that is not _used_.
+func tinkKeyID(id string, used map[uint32]bool) (uint32, error) {
+
+	if parsed, err := strconv.ParseUint(id, 10, 32); err == nil && !used[uint32(parsed)] {
+
+		used[uint32(parsed)] = true
+		return uint32(parsed), nil
+
+	}
+
+	var random [4]byte
+
+	for {
+
+		if _, err := rand.Read(random[:]); err != nil {
+			return 0, err
+		}
+
+		if id := binary.BigEndian.Uint32(random[:]); id != 0 && !used[id] {
+
+			used[id] = true
+			return id, nil
+
+		}
+
+	}
+
+}
+
+// isTinkJSON returns `true` if _data_ is a _JSON_ object.
+func isTinkJSON(data []byte) bool {
+	return strings.HasPrefix(strings.TrimSpace(string(data)), "{")
+}
+
+// tinkJSONKeyset is the _JSON_ _Tink_ keyset.
+type tinkJSONKeyset struct {
+	PrimaryKeyID uint32        `json:"primaryKeyId"`
+	Key          []tinkJSONKey `json:"key"`
+}
+
+// tinkJSONKey is a key in the _JSON_ _Tink_ keyset.
+type tinkJSONKey struct {
+	KeyData struct {
+		TypeURL         string `json:"typeUrl"`
+		Value           []byte `json:"value"`
+		KeyMaterialType string `json:"keyMaterialType"`
+	} `json:"keyData"`
+	Status           string `json:"status"`
+	KeyID            uint32 `json:"keyId"`
+	OutputPrefixType string `json:"outputPrefixType"`
+}
+
+// keyset maps the _JSON_ enum names and parses the keys.
+func (k *tinkJSONKeyset) keyset() (*TinkKeyset, error) {
+
+	keyset := &TinkKeyset{PrimaryKeyID: k.PrimaryKeyID}
+
+	for _, key := range k.Key {
+
+		entry := TinkKey{KeyID: key.KeyID}
+		data := tinkKeyDataValue{typeURL: key.KeyData.TypeURL, value: key.KeyData.Value}
+
+		for status, name := range tinkStatusNames {
+
+			if name == key.Status {
+				entry.Status = status
+			}
+
+		}
+
+		for prefix, name := range tinkPrefixNames {
+
+			if name == key.OutputPrefixType {
+				entry.OutputPrefix = prefix
+			}
+
+		}
+
+		for material, name := range tinkMaterialTypes {
+
+			if name == key.KeyData.KeyMaterialType {
+				data.material = material
+			}
+
+		}
+
+		if err := keyset.add(entry, data); err != nil {
+			return nil, err
+		}
+
+	}
+
+	return keyset, nil
+
+}
+
+// parseTinkKeyset parses a binary cleartext _Tink_ keyset.
+func parseTinkKeyset(data []byte) (*TinkKeyset, error) {
+
+	fields, err := readProtoFields(data)
+	if err != nil {
+		return nil, err
+	}
+
+	keyset := &TinkKeyset{}
+
+	for _, field := range fields {
+
+		switch field.num {
+		case 1:
+			keyset.PrimaryKeyID = uint32(field.varint)
+		case 2:
+
+			key, err := readProtoFields(field.bytes)
+			if err != nil {
+				return nil, err
+			}
+
+			var (
+				entry TinkKey
+				data  tinkKeyDataValue
+			)
+
+			for _, field := range key {
+
+				switch field.num {
+				case 1:
+
+					if data, err = parseTinkKeyData(field.bytes); err != nil {
+						return nil, err
+					}
+
+				case 2:
+					entry.Status = TinkKeyStatus(field.varint)
+				case 3:
+					entry.KeyID = uint32(field.varint)
+				case 4:
+					entry.OutputPrefix = TinkOutputPrefix(field.varint)
+				}
+
+			}
+
+			if err := keyset.add(entry, data); err != nil {
+				return nil, err
+			}
+
+		}
+
+	}
+
+	return keyset, nil
+
+}
+
+// add parses the key material in _data_ and adds the _entry_, unless it is destroyed.
+func (k *TinkKeyset) add(entry TinkKey, data tinkKeyDataValue) error {
+
+	if entry.Status == TinkKeyDestroyed {
+		return nil
+	}
+
+	if _, ok := tinkStatusNames[entry.Status]; !ok {
+		return fmt.Errorf("Tink key %d has a unknown status", entry.KeyID)
+	}
+
+	if _, ok := tinkPrefixNames[entry.OutputPrefix]; !ok {
+		return fmt.Errorf("Tink key %d has a unknown output prefix", entry.KeyID)
+	}
+
+	key, err := data.key(strconv.FormatUint(uint64(entry.KeyID), 10))
+	if err != nil {
+		return fmt.Errorf("Tink key %d: %w", entry.KeyID, err)
+	}
+
+	entry.Key = key
+	k.Keys = append(k.Keys, entry)
+
+	return nil
+
+}
+
+// tinkKeyDataValue is the _Tink_ _KeyData_, i.e. the type, serialized key and material type.
+type tinkKeyDataValue struct {
+	typeURL  string
+	value    []byte
+	material uint64
+}
+
+// parseTinkKeyData parses a binary _KeyData_.
+func parseTinkKeyData(data []byte) (tinkKeyDataValue, error) {
+
+	var value tinkKeyDataValue
+
+	fields, err := readProtoFields(data)
+	if err != nil {
+		return value, err
+	}
+
+	for _, field := range fields {
+
+		switch field.num {
+		case 1:
+			value.typeURL = string(field.bytes)
+		case 2:
+			value.value = field.bytes
+		case 3:
+			value.material = field.varint
+		}
+
+	}
+
+	return value, nil
+
+}
+
+// marshal returns the binary _KeyData_.
+func (d tinkKeyDataValue) marshal() []byte {
+
+	var buf []byte
+
+	buf = appendProtoBytes(buf, 1, []byte(d.typeURL))
+	buf = appendProtoBytes(buf, 2, d.value)
+
+	return appendProtoVarint(buf, 3, d.material)
+
+}
+
+// key parses the serialized key into a key of this package with _id_.
+func (d tinkKeyDataValue) key(id string) (ifcrypto.Key, error) {
+
+	if (d.material == tinkMaterialSymmetric || d.material == tinkMaterialPrivate) && IsVerifyOnly() {
+		return nil, ErrVerifyOnly
+	}
+
+	fields, err := readProtoFields(d.value)
+	if err != nil {
+		return nil, err
+	}
+
+	value := func(num uint64) []byte {
+
+		for _, field := range fields {
+
+			if field.num == num {
+				return field.bytes
+			}
+
+		}
+
+		return nil
+
+	}
+
+	switch d.typeURL {
+	case tinkAesGcmKey:
+
+		return NewSymmetricKeyFromBytes(id, value(3), ifcrypto.KeyUsageEncrypt, ifcrypto.KeyUsageDecrypt)
+
+	case tinkChaCha20Poly1305Key:
+
+		return NewChaChaKeyFromBytes(id, value(2), ifcrypto.KeyUsageEncrypt, ifcrypto.KeyUsageDecrypt)
+
+	case tinkXChaCha20Poly1305Key:
+
+		return NewChaChaKeyFromBytes(id, value(3), ifcrypto.KeyUsageEncrypt, ifcrypto.KeyUsageDecrypt)
+
+	case tinkHmacKey:
+
+		params, err := readProtoFields(value(2))
+		if err != nil {
+			return nil, err
+		}
+
+		var hash crypto.Hash
+
+		tagSize := uint64(0)
+
+		for _, field := range params {
+
+			switch field.num {
+			case 1:
+				hash = tinkHashes[field.varint]
+			case 2:
+				tagSize = field.varint
+			}
+
+		}
+
+		if hash == 0 || tagSize != uint64(hash.Size()) {
+			return nil, fmt.Errorf("only untruncated HMAC SHA-256, SHA-384 and SHA-512 tags are supported")
+		}
+
+		return NewHMACKeyFromBytes(id, value(3), hash, ifcrypto.KeyUsageSign, ifcrypto.KeyUsageVerify)
+
+	case tinkEd25519PrivateKey:
+
+		seed := value(2)
+		if len(seed) != ed25519.SeedSize {
+			return nil, fmt.Errorf("invalid Ed25519 seed size: %d bytes", len(seed))
+		}
+
+		private := ed25519.NewKeyFromSeed(seed)
+
+		public, err := readProtoFields(value(3))
+		if err != nil {
+			return nil, err
+		}
+
+		for _, field := range public {
+
+			if field.num == 2 && !bytes.Equal(field.bytes, private.Public().(ed25519.PublicKey)) {
+				return nil, fmt.Errorf("Ed25519 private key do not match the public key")
+			}
+
+		}
+
+		return NewED25519PrivateKeyFromKey(id, private, ifcrypto.KeyUsageSign, ifcrypto.KeyUsageVerify), nil
+
+	case tinkEd25519PublicKey:
+
+		public := value(2)
+		if len(public) != ed25519.PublicKeySize {
+			return nil, fmt.Errorf("invalid Ed25519 public key size: %d bytes", len(public))
+		}
+
+		return NewED25519PublicKeyFromKey(
+			id, ed25519.PublicKey(append([]byte{}, public...)), ifcrypto.KeyUsageVerify,
+		), nil
+
+	case tinkEcdsaPrivateKey:
+
+		public, err := parseTinkEcdsaPublicKey(value(2))
+		if err != nil {
+			return nil, err
+		}
+
+		private := &ecdsa.PrivateKey{PublicKey: *public, D: new(big.Int).SetBytes(value(3))}
+
+		x, y := public.Curve.ScalarBaseMult(private.D.Bytes())
+		if x.Cmp(public.X) != 0 || y.Cmp(public.Y) != 0 {
+			return nil, fmt.Errorf("ECDSA private key do not match the public key")
+		}
+
+		return NewECDSAPrivateKeyFromKey(id, private, ifcrypto.KeyUsageSign, ifcrypto.KeyUsageVerify), nil
+
+	case tinkEcdsaPublicKey:
+
+		public, err := parseTinkEcdsaPublicKey(d.value)
+		if err != nil {
+			return nil, err
+		}
+
+		return NewECDSAPublicKeyFromKey(id, public, ifcrypto.KeyUsageVerify), nil
+
+	}
+
+	return nil, fmt.Errorf("unsupported Tink key type: %s", d.typeURL)
+
+}
+
+// parseTinkEcdsaPublicKey parses a binary _EcdsaPublicKey_.
+func parseTinkEcdsaPublicKey(data []byte) (*ecdsa.PublicKey, error) {
+
+	fields, err := readProtoFields(data)
+	if err != nil {
+		return nil, err
+	}
+
+	public := &ecdsa.PublicKey{}
+	encoding := uint64(0)
+
+	for _, field := range fields {
+
+		switch field.num {
+		case 2:
+
+			params, err := readProtoFields(field.bytes)
+			if err != nil {
+				return nil, err
+			}
+
+			for _, param := range params {
+
+				switch param.num {
+				case 2:
+					public.Curve = tinkCurves[param.varint].curve
+				case 3:
+					encoding = param.varint
+				}
+
+			}
+
+		case 3:
+			public.X = new(big.Int).SetBytes(field.bytes)
+		case 4:
+			public.Y = new(big.Int).SetBytes(field.bytes)
+		}
+
+	}
+
+	switch {
+	case public.Curve == nil:
+		return nil, fmt.Errorf("only ECDSA P-256, P-384 and P-521 are supported")
+	case encoding == tinkEcdsaEncodingIEEE1363:
+		return nil, fmt.Errorf("IEEE P1363 encoded ECDSA signatures are not supported")
+	case public.X == nil || public.Y == nil || !public.Curve.IsOnCurve(public.X, public.Y):
+		return nil, fmt.Errorf("invalid ECDSA public key")
+	}
+
+	return public, nil
+
+}
+
+// tinkKeyData returns the _Tink_ _KeyData_ of _key_.
+func tinkKeyData(key ifcrypto.Key) (tinkKeyDataValue, error) {
+
+	switch k := key.(type) {
+	case *SymmetricKey:
+
+		if len(k.key) == 24 {
+			return tinkKeyDataValue{}, fmt.Errorf("Tink do not support AES-192 for key %s", k.GetID())
+		}
+
+		return tinkKeyDataValue{
+			typeURL:  tinkAesGcmKey,
+			value:    appendProtoBytes(nil, 3, k.key),
+			material: tinkMaterialSymmetric,
+		}, nil
+
+	case *ChaChaKey:
+
+		return tinkKeyDataValue{
+			typeURL:  tinkXChaCha20Poly1305Key,
+			value:    appendProtoBytes(nil, 3, k.key),
+			material: tinkMaterialSymmetric,
+		}, nil
+
+	case *HMACKey:
+
+		var hash uint64
+
+		for tinkHash, h := range tinkHashes {
+
+			if h == k.hash {
+				hash = tinkHash
+			}
+
+		}
+
+		params := appendProtoVarint(nil, 1, hash)
+		params = appendProtoVarint(params, 2, uint64(k.hash.Size()))
+
+		return tinkKeyDataValue{
+			typeURL:  tinkHmacKey,
+			value:    appendProtoBytes(appendProtoField(nil, 2, params), 3, k.key),
+			material: tinkMaterialSymmetric,
+		}, nil
+
+	case *ED25519PrivateKey:
+
+		public := appendProtoBytes(nil, 2, k.public.key)
+
+		return tinkKeyDataValue{
+			typeURL:  tinkEd25519PrivateKey,
+			value:    appendProtoField(appendProtoBytes(nil, 2, k.key.Seed()), 3, public),
+			material: tinkMaterialPrivate,
+		}, nil
+
+	case *ED25519PublicKey:
+
+		return tinkKeyDataValue{
+			typeURL:  tinkEd25519PublicKey,
+			value:    appendProtoBytes(nil, 2, k.key),
+			material: tinkMaterialPublic,
+		}, nil
+
+	case *ECDSAPrivateKey:
+
+		public, err := marshalTinkEcdsaPublicKey(&k.key.PublicKey)
+		if err != nil {
+			return tinkKeyDataValue{}, err
+		}
+
+		d := k.key.D.FillBytes(make([]byte, (k.key.Params().BitSize+7)/8))
+
+		return tinkKeyDataValue{
+			typeURL:  tinkEcdsaPrivateKey,
+			value:    appendProtoBytes(appendProtoField(nil, 2, public), 3, d),
+			material: tinkMaterialPrivate,
+		}, nil
+
+	case *ECDSAPublicKey:
+
+		public, err := marshalTinkEcdsaPublicKey(k.key)
+		if err != nil {
+			return tinkKeyDataValue{}, err
+		}
+
+		return tinkKeyDataValue{
+			typeURL:  tinkEcdsaPublicKey,
+			value:    public,
+			material: tinkMaterialPublic,
+		}, nil
+
+	}
+
+	return tinkKeyDataValue{}, fmt.Errorf("unsupported Tink key: %T", key)
+
+}
+
+// marshalTinkEcdsaPublicKey returns the binary _EcdsaPublicKey_ with _DER_ signatures.
+func marshalTinkEcdsaPublicKey(public *ecdsa.PublicKey) ([]byte, error) {
+
+	for curve, params := range tinkCurves {
+
+		if params.curve != public.Curve {
+			continue
+		}
+
+		size := (public.Params().BitSize + 7) / 8
+
+		msg := appendProtoVarint(nil, 1, params.hash)
+		msg = appendProtoVarint(msg, 2, curve)
+		msg = appendProtoVarint(msg, 3, tinkEcdsaEncodingDER)
+
+		buf := appendProtoField(nil, 2, msg)
+		buf = appendProtoBytes(buf, 3, public.X.FillBytes(make([]byte, size)))
+
+		return appendProtoBytes(buf, 4, public.Y.FillBytes(make([]byte, size))), nil
+
+	}
+
+	return nil, fmt.Errorf("unsupported Tink ECDSA curve: %s", public.Params().Name)
+
+}
+
+// protoField is a decoded protobuf field, either a varint or length delimited.
+type protoField struct {
+	num    uint64
+	varint uint64
+	bytes  []byte
+}
+
+// readProtoFields decodes the varint and length delimited fields of the message in _data_.
+// Fixed size fields are skipped.
+func readProtoFields(data []byte) ([]protoField, error) {
+
+	fields := []protoField{}
+
+	for len(data) > 0 {
+
+		key, n := binary.Uvarint(data)
+		if n <= 0 {
+			return nil, fmt.Errorf("invalid protobuf field key")
+		}
+
+		data = data[n:]
+		field := protoField{num: key >> 3}
+
+		switch key & 7 {
+		case 0:
+
+			if field.varint, n = binary.Uvarint(data); n <= 0 {
+				return nil, fmt.Errorf("invalid protobuf varint")
+			}
+
+			data = data[n:]
+
+		case 1, 5:
+
+			size := 8
+			if key&7 == 5 {
+				size = 4
+			}
+
+			if len(data) < size {
+				return nil, fmt.Errorf("unexpected end of protobuf data")
+			}
+
+			data = data[size:]
+
+			continue
+
+		case 2:
+
+			length, n := binary.Uvarint(data)
+			if n <= 0 || uint64(len(data)-n) < length {
+				return nil, fmt.Errorf("invalid protobuf length")
+			}
+
+			field.bytes = data[n : n+int(length)]
+			data = data[n+int(length):]
+
+		default:
+			return nil, fmt.Errorf("unsupported protobuf wire type: %d", key&7)
+		}
+
+		fields = append(fields, field)
+
+	}
+
+	return fields, nil
+
+}
+
+// appendProtoVarint appends a varint _field_, unless _value_ is zero which is the default
+// value in _proto3_.
+func appendProtoVarint(buf []byte, field, value uint64) []byte {
+
+	if value == 0 {
+		return buf
+	}
+
+	buf = binary.AppendUvarint(buf, field<<3)
+
+	return binary.AppendUvarint(buf, value)
+
+}
+
+// appendProtoBytes appends a length delimited _field_, unless _value_ is empty.
+func appendProtoBytes(buf []byte, field uint64, value []byte) []byte {
+
+	if len(value) == 0 {
+		return buf
+	}
+
+	return appendProtoField(buf, field, value)
+
+}
+
+// appendProtoField appends a length delimited _field_.
+func appendProtoField(buf []byte, field uint64, value []byte) []byte {
+
+	buf = binary.AppendUvarint(buf, field<<3|2)
+	buf = binary.AppendUvarint(buf, uint64(len(value)))
+
+	return append(buf, value...)
+
+}
@@ -0,0 +1,175 @@
+//go:build !verifyonly
+// +build !verifyonly
+
+package gocrypto
+
+import (
+	"context"
+	"crypto"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/mariotoffia/goservice/interfaces/ifcrypto"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// tinkAESKeyset is a AES-128-GCM keyset written by Tink.
+var tinkAESKeyset = []byte(`{
+	"primaryKeyId": 42818733,
+	"key": [{
+		"keyData": {
+			"typeUrl": "type.googleapis.com/google.crypto.tink.AesGcmKey",
+			"value": "GhCC74uJ+2f4qlpaHwR4ylNQ",
+			"keyMaterialType": "SYMMETRIC"
+		},
+		"status": "ENABLED",
+		"keyId": 42818733,
+		"outputPrefixType": "TINK"
+	}]
+}`)
+
+func TestTinkKeysetParsesJSON(t *testing.T) {
+
+	keyset, err := ParseTinkKeyset(tinkAESKeyset)
+	require.NoError(t, err)
+	require.Len(t, keyset.Keys, 1)
+
+	primary := keyset.Primary()
+	require.NotNil(t, primary)
+
+	assert.Equal(t, "42818733", primary.Key.GetID())
+	assert.Equal(t, ifcrypto.KeyTypeSymmetric, primary.Key.GetKeyType())
+	assert.Equal(t, 128, primary.Key.GetKeySize())
+	assert.Equal(t, TinkKeyEnabled, primary.Status)
+	assert.Equal(t, []byte{0x01, 0x02, 0x8d, 0x5c, 0xad}, primary.Prefix())
+
+	out, err := json.Marshal(keyset)
+	require.NoError(t, err)
+
+	again, err := ParseTinkKeyset(out)
+	require.NoError(t, err)
+	assert.Equal(t, primary.Key.GetKey(), again.Primary().Key.GetKey())
+
+}
+
+func TestTinkKeysetRoundTrip(t *testing.T) {
+
+	sign := []ifcrypto.KeyUsage{ifcrypto.KeyUsageSign, ifcrypto.KeyUsageVerify}
+	enc := []ifcrypto.KeyUsage{ifcrypto.KeyUsageEncrypt, ifcrypto.KeyUsageDecrypt}
+
+	edKey, err := NewED25519PrivateKey("ed", sign...)
+	require.NoError(t, err)
+
+	ecKey, err := NewECDSAPrivateKey("1234", 384, sign...)
+	require.NoError(t, err)
+
+	hmacKey, err := NewHMACKey("hmac", crypto.SHA512, sign...)
+	require.NoError(t, err)
+
+	aesKey, err := NewSymmetricKey("aes", 256, enc...)
+	require.NoError(t, err)
+
+	chachaKey, err := NewChaChaKey("chacha", enc...)
+	require.NoError(t, err)
+
+	keys := []ifcrypto.Key{edKey, ecKey, hmacKey, aesKey, chachaKey, ecKey.GetPublic(), edKey.GetPublic()}
+
+	_, err = NewTinkKeyset(keys...)
+	require.NoError(t, err, "duplicate ids are assigned random key ids")
+
+	keys = keys[:5]
+
+	keyset, err := NewTinkKeyset(keys...)
+	require.NoError(t, err)
+	assert.Equal(t, keyset.Keys[0].KeyID, keyset.PrimaryKeyID)
+	assert.Equal(t, uint32(1234), keyset.Keys[1].KeyID)
+	assert.Nil(t, keyset.Keys[0].Prefix())
+
+	kek, err := NewSymmetricKey("kek", 256, enc...)
+	require.NoError(t, err)
+
+	encrypted, err := keyset.Encrypt(kek, []byte("keyset"))
+	require.NoError(t, err)
+
+	_, err = ParseEncryptedTinkKeyset(encrypted, kek, []byte("other"))
+	assert.Error(t, err, "associated data do not match")
+
+	parsed, err := ParseEncryptedTinkKeyset(encrypted, kek, []byte("keyset"))
+	require.NoError(t, err)
+	require.Len(t, parsed.Keys, len(keys))
+
+	for i, key := range keys {
+
+		assert.Equal(t, key.GetKeyType(), parsed.Keys[i].Key.GetKeyType())
+		assert.Equal(t, key.GetKey(), parsed.Keys[i].Key.GetKey())
+
+	}
+
+	// A signature of the original key verifies with the imported public key
+	signature, err := edKey.SignMessage([]byte("message"), crypto.Hash(0))
+	require.NoError(t, err)
+
+	public := parsed.Keys[0].Key.(ifcrypto.KeyPair).GetPublic().(ifcrypto.MessageVerifier)
+	require.NoError(t, public.VerifyMessage([]byte("message"), signature, crypto.Hash(0)))
+
+	// Disabled keys are kept in the keyset but not imported
+	parsed.Keys[2].Status = TinkKeyDisabled
+
+	cleartext, err := parsed.Marshal()
+	require.NoError(t, err)
+
+	parsed, err = ParseTinkKeyset(cleartext)
+	require.NoError(t, err)
+	assert.Equal(t, TinkKeyDisabled, parsed.Keys[2].Status)
+
+	store, err := NewMemoryKeyStore()
+	require.NoError(t, err)
+
+	require.NoError(t, parsed.Import(context.Background(), store))
+
+	imported, err := store.List(context.Background(), ifcrypto.KeyFilter{})
+	require.NoError(t, err)
+	assert.Len(t, imported, len(keys)-1)
+
+}
+
+func TestTinkKeysetRejectsUnsupportedKeys(t *testing.T) {
+
+	aesKey, err := NewSymmetricKey("aes", 192, ifcrypto.KeyUsageEncrypt)
+	require.NoError(t, err)
+
+	_, err = NewTinkKeyset(aesKey)
+	assert.Error(t, err, "Tink do not support AES-192")
+
+	rsaKey, err := NewRSAPrivateKey("rsa", 2048, ifcrypto.KeyUsageSign)
+	require.NoError(t, err)
+
+	_, err = NewTinkKeyset(rsaKey)
+	assert.Error(t, err)
+
+	data := []byte(`{
+		"primaryKeyId": 1,
+		"key": [{
+			"keyData": {
+				"typeUrl": "type.googleapis.com/google.crypto.tink.AesGcmSivKey",
+				"value": "GhCC74uJ+2f4qlpaHwR4ylNQ",
+				"keyMaterialType": "SYMMETRIC"
+			},
+			"status": "ENABLED",
+			"keyId": 1,
+			"outputPrefixType": "RAW"
+		}]
+	}`)
+
+	_, err = ParseTinkKeyset(data)
+	assert.Error(t, err)
+
+	SetVerifyOnly(true)
+	defer SetVerifyOnly(false)
+
+	_, err = ParseTinkKeyset(tinkAESKeyset)
+	assert.True(t, errors.Is(err, ErrVerifyOnly))
+
+}
@@ -0,0 +1,100 @@
+//go:build !verifyonly
+// +build !verifyonly
+
+package gocrypto
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"errors"
+	"testing"
+
+	"github.com/mariotoffia/goservice/interfaces/ifcrypto"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPublicKeySignatureVerifier(t *testing.T) {
+
+	rsaKey, err := NewRSAPrivateKey("rsa", 2048, ifcrypto.KeyUsageSign)
+	require.NoError(t, err)
+
+	ecKey, err := NewECDSAPrivateKey("ec", 256, ifcrypto.KeyUsageSign)
+	require.NoError(t, err)
+
+	edKey, err := NewED25519PrivateKey("ed", ifcrypto.KeyUsageSign)
+	require.NoError(t, err)
+
+	k1Key, err := NewSecp256k1PrivateKey("k1", ifcrypto.KeyUsageSign)
+	require.NoError(t, err)
+
+	digest := sha256.Sum256([]byte("message"))
+
+	tests := []struct {
+		name    string
+		signer  crypto.Signer
+		public  ifcrypto.PublicKey
+		message []byte
+		opts    crypto.SignerOpts
+	}{
+		{"rsa-pkcs1", rsaKey, rsaKey.GetPublic(), digest[:], crypto.SHA256},
+		{"rsa-pss", rsaKey, rsaKey.GetPublic(), digest[:], &rsa.PSSOptions{Hash: crypto.SHA256}},
+		{"ecdsa", ecKey, ecKey.GetPublic(), digest[:], crypto.SHA256},
+		{"ed25519", edKey, edKey.GetPublic(), []byte("message"), crypto.Hash(0)},
+		{"ed25519ph", edKey, edKey.GetPublic(), sha512Sum("message"), &ed25519.Options{Hash: crypto.SHA512}},
+		{"secp256k1", k1Key, k1Key.GetPublic(), digest[:], crypto.SHA256},
+	}
+
+	for _, tt := range tests {
+
+		t.Run(tt.name, func(t *testing.T) {
+
+			signature, err := tt.signer.Sign(rand.Reader, tt.message, tt.opts)
+			require.NoError(t, err)
+
+			verifier, ok := tt.public.(ifcrypto.SignatureVerifier)
+			require.True(t, ok)
+
+			assert.NoError(t, verifier.Verify(tt.message, signature, tt.opts))
+
+			signature[len(signature)-1] ^= 0xff
+			assert.True(t, errors.Is(verifier.Verify(tt.message, signature, tt.opts), ifcrypto.ErrInvalidSignature))
+
+		})
+
+	}
+
+}
+
+func TestRSAPublicKeyVerifyNilOpts(t *testing.T) {
+
+	key, err := NewRSAPrivateKey("rsa", 2048, ifcrypto.KeyUsageSign)
+	require.NoError(t, err)
+
+	digest := sha256.Sum256([]byte("message"))
+
+	_, err = key.Sign(rand.Reader, digest[:], nil)
+	assert.Error(t, err)
+
+	signature, err := key.Sign(rand.Reader, digest[:], crypto.SHA256)
+	require.NoError(t, err)
+
+	verifier := key.GetPublic().(ifcrypto.SignatureVerifier)
+
+	assert.NotPanics(t, func() {
+		assert.Error(t, verifier.Verify(digest[:], signature, nil))
+	})
+
+}
+
+func sha512Sum(message string) []byte {
+
+	h := crypto.SHA512.New()
+	h.Write([]byte(message))
+
+	return h.Sum(nil)
+
+}
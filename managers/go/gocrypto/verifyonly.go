@@ -0,0 +1,40 @@
+package gocrypto
+
+import (
+	"fmt"
+	"sync/atomic"
+)
+
+// ErrVerifyOnly is returned by private key operations when verify-only mode is active.
+var ErrVerifyOnly = fmt.Errorf("private key operations are disabled in verify-only mode")
+
+// verifyOnly is set to one when verify-only mode is enabled at runtime.
+var verifyOnly int32
+
+// SetVerifyOnly enables, or disables, verify-only mode at runtime.
+//
+// In verify-only mode, private and secret keys, i.e. asymmetric private keys, _HMAC_, _ChaCha_
+// and _AES_ keys, can not be generated, loaded from _PEM_, _DER_, _JWK_ or _PKCS #12_ or used
+// to sign or decrypt. Use this in deployments, such as edge verifiers, that never should sign.
+//
+// When built with the _verifyonly_ build tag, the mode is always active and can not be
+// disabled. The private and secret key constructors and operations, i.e. the _*_private.go_
+// files, are then excluded from the build and replaced by stubs that returns `ErrVerifyOnly`,
+// e.g.
+//
+// go build -tags verifyonly ./...
+func SetVerifyOnly(enabled bool) {
+
+	if enabled {
+		atomic.StoreInt32(&verifyOnly, 1)
+	} else {
+		atomic.StoreInt32(&verifyOnly, 0)
+	}
+
+}
+
+// IsVerifyOnly returns `true` if verify-only mode is active, either by the _verifyonly_
+// build tag or by `SetVerifyOnly`.
+func IsVerifyOnly() bool {
+	return verifyOnlyBuild || atomic.LoadInt32(&verifyOnly) == 1
+}
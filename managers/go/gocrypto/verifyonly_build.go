@@ -0,0 +1,217 @@
+//go:build verifyonly
+// +build verifyonly
+
+package gocrypto
+
+import (
+	"crypto"
+	"crypto/x509"
+	"encoding/pem"
+	"io"
+
+	"github.com/mariotoffia/goservice/interfaces/ifcrypto"
+)
+
+// verifyOnlyBuild is `true` when built with the _verifyonly_ build tag.
+const verifyOnlyBuild = true
+
+// The private and secret key constructors and operations below replace the ones in the
+// _*_private.go_ files, hence no private key code paths are compiled into verify-only builds.
+
+// NewRSAPrivateKeyFromPEM always returns `ErrVerifyOnly` in verify-only builds.
+func NewRSAPrivateKeyFromPEM(
+	block pem.Block,
+	id string,
+	usage ...ifcrypto.KeyUsage,
+) (*RSAPrivateKey, error) {
+	return nil, ErrVerifyOnly
+}
+
+// NewRSAPrivateKey always returns `ErrVerifyOnly` in verify-only builds.
+func NewRSAPrivateKey(id string, bits int, usage ...ifcrypto.KeyUsage) (*RSAPrivateKey, error) {
+	return nil, ErrVerifyOnly
+}
+
+// Sign always returns `ErrVerifyOnly` in verify-only builds.
+func (r *RSAPrivateKey) Sign(
+	rand io.Reader,
+	digest []byte,
+	opts crypto.SignerOpts,
+) ([]byte, error) {
+	return nil, ErrVerifyOnly
+}
+
+// Decrypt always returns `ErrVerifyOnly` in verify-only builds.
+func (r *RSAPrivateKey) Decrypt(
+	rand io.Reader,
+	msg []byte,
+	opts crypto.DecrypterOpts,
+) (plaintext []byte, err error) {
+	return nil, ErrVerifyOnly
+}
+
+// NewECDSAPrivateKeyFromPEM always returns `ErrVerifyOnly` in verify-only builds.
+func NewECDSAPrivateKeyFromPEM(
+	block pem.Block,
+	id string,
+	usage ...ifcrypto.KeyUsage,
+) (*ECDSAPrivateKey, error) {
+	return nil, ErrVerifyOnly
+}
+
+// NewECDSAPrivateKeyWithOptions always returns `ErrVerifyOnly` in verify-only builds.
+func NewECDSAPrivateKeyWithOptions(id string, opts ...ECDSAKeyOption) (*ECDSAPrivateKey, error) {
+	return nil, ErrVerifyOnly
+}
+
+// Sign always returns `ErrVerifyOnly` in verify-only builds.
+func (r *ECDSAPrivateKey) Sign(
+	rand io.Reader,
+	digest []byte,
+	opts crypto.SignerOpts,
+) ([]byte, error) {
+	return nil, ErrVerifyOnly
+}
+
+// DeriveSharedSecret always returns `ErrVerifyOnly` in verify-only builds.
+func (r *ECDSAPrivateKey) DeriveSharedSecret(peer ifcrypto.PublicKey) ([]byte, error) {
+	return nil, ErrVerifyOnly
+}
+
+// NewED25519PrivateKeyFromPEM always returns `ErrVerifyOnly` in verify-only builds.
+func NewED25519PrivateKeyFromPEM(
+	block pem.Block,
+	id string,
+	usage ...ifcrypto.KeyUsage,
+) (*ED25519PrivateKey, error) {
+	return nil, ErrVerifyOnly
+}
+
+// NewED25519PrivateKey always returns `ErrVerifyOnly` in verify-only builds.
+func NewED25519PrivateKey(id string, usage ...ifcrypto.KeyUsage) (*ED25519PrivateKey, error) {
+	return nil, ErrVerifyOnly
+}
+
+// Sign always returns `ErrVerifyOnly` in verify-only builds.
+func (r *ED25519PrivateKey) Sign(
+	rand io.Reader,
+	message []byte,
+	opts crypto.SignerOpts,
+) ([]byte, error) {
+	return nil, ErrVerifyOnly
+}
+
+// NewX25519PrivateKeyFromPEM always returns `ErrVerifyOnly` in verify-only builds.
+func NewX25519PrivateKeyFromPEM(
+	block pem.Block,
+	id string,
+	usage ...ifcrypto.KeyUsage,
+) (*X25519PrivateKey, error) {
+	return nil, ErrVerifyOnly
+}
+
+// NewX25519PrivateKey always returns `ErrVerifyOnly` in verify-only builds.
+func NewX25519PrivateKey(id string, usage ...ifcrypto.KeyUsage) (*X25519PrivateKey, error) {
+	return nil, ErrVerifyOnly
+}
+
+// DeriveSharedSecret always returns `ErrVerifyOnly` in verify-only builds.
+func (r *X25519PrivateKey) DeriveSharedSecret(peer ifcrypto.PublicKey) ([]byte, error) {
+	return nil, ErrVerifyOnly
+}
+
+// NewSecp256k1PrivateKeyFromBytes always returns `ErrVerifyOnly` in verify-only builds.
+func NewSecp256k1PrivateKeyFromBytes(id string, d []byte, usage ...ifcrypto.KeyUsage) (*Secp256k1PrivateKey, error) {
+	return nil, ErrVerifyOnly
+}
+
+// NewSecp256k1PrivateKeyFromPEM always returns `ErrVerifyOnly` in verify-only builds.
+func NewSecp256k1PrivateKeyFromPEM(
+	block pem.Block,
+	id string,
+	usage ...ifcrypto.KeyUsage,
+) (*Secp256k1PrivateKey, error) {
+	return nil, ErrVerifyOnly
+}
+
+// NewSecp256k1PrivateKey always returns `ErrVerifyOnly` in verify-only builds.
+func NewSecp256k1PrivateKey(id string, usage ...ifcrypto.KeyUsage) (*Secp256k1PrivateKey, error) {
+	return nil, ErrVerifyOnly
+}
+
+// Sign always returns `ErrVerifyOnly` in verify-only builds.
+func (r *Secp256k1PrivateKey) Sign(
+	rand io.Reader,
+	digest []byte,
+	opts crypto.SignerOpts,
+) ([]byte, error) {
+	return nil, ErrVerifyOnly
+}
+
+// SignRaw always returns `ErrVerifyOnly` in verify-only builds.
+func (r *Secp256k1PrivateKey) SignRaw(digest []byte) ([]byte, error) {
+	return nil, ErrVerifyOnly
+}
+
+// NewHMACKeyFromBytes always returns `ErrVerifyOnly` in verify-only builds.
+func NewHMACKeyFromBytes(
+	id string,
+	key []byte,
+	hash crypto.Hash,
+	usage ...ifcrypto.KeyUsage,
+) (*HMACKey, error) {
+	return nil, ErrVerifyOnly
+}
+
+// Sign always returns `ErrVerifyOnly` in verify-only builds.
+func (r *HMACKey) Sign(
+	rand io.Reader,
+	message []byte,
+	opts crypto.SignerOpts,
+) ([]byte, error) {
+	return nil, ErrVerifyOnly
+}
+
+// NewChaChaKeyFromBytes always returns `ErrVerifyOnly` in verify-only builds.
+func NewChaChaKeyFromBytes(id string, key []byte, usage ...ifcrypto.KeyUsage) (*ChaChaKey, error) {
+	return nil, ErrVerifyOnly
+}
+
+// Decrypt always returns `ErrVerifyOnly` in verify-only builds.
+func (r *ChaChaKey) Decrypt(chiper ifcrypto.Chipher, ciphertext, additionalData []byte) ([]byte, error) {
+	return nil, ErrVerifyOnly
+}
+
+// NewSymmetricKeyFromBytes always returns `ErrVerifyOnly` in verify-only builds.
+func NewSymmetricKeyFromBytes(
+	id string,
+	key []byte,
+	usage ...ifcrypto.KeyUsage,
+) (*SymmetricKey, error) {
+	return nil, ErrVerifyOnly
+}
+
+// NewKeyPairFromEncryptedPEM always returns `ErrVerifyOnly` in verify-only builds.
+func NewKeyPairFromEncryptedPEM(
+	block pem.Block,
+	passphrase []byte,
+	id string,
+	usage ...ifcrypto.KeyUsage,
+) (ifcrypto.KeyPair, error) {
+	return nil, ErrVerifyOnly
+}
+
+// NewKeyPairFromPKCS12 always returns `ErrVerifyOnly` in verify-only builds.
+func NewKeyPairFromPKCS12(
+	id string,
+	data []byte,
+	password string,
+	usage ...ifcrypto.KeyUsage,
+) (ifcrypto.KeyPair, []*x509.Certificate, error) {
+	return nil, nil, ErrVerifyOnly
+}
+
+// Decrypt always returns `ErrVerifyOnly` in verify-only builds.
+func (r *SymmetricKey) Decrypt(chiper ifcrypto.Chipher, ciphertext, additionalData []byte) ([]byte, error) {
+	return nil, ErrVerifyOnly
+}
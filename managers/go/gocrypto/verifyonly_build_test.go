@@ -0,0 +1,56 @@
+//go:build verifyonly
+// +build verifyonly
+
+package gocrypto
+
+import (
+	"crypto"
+	"crypto/rand"
+	"encoding/pem"
+	"testing"
+
+	"github.com/mariotoffia/goservice/interfaces/ifcrypto"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestVerifyOnlyBuildExcludesPrivateKeys(t *testing.T) {
+
+	SetVerifyOnly(false)
+	assert.True(t, IsVerifyOnly(), "the build tag can not be overridden at runtime")
+
+	secret := make([]byte, 32)
+	block := pem.Block{Type: "PRIVATE KEY"}
+
+	constructors := map[string]func() error{
+		"rsa":             func() error { _, err := NewRSAPrivateKey("rsa", 2048); return err },
+		"rsa pem":         func() error { _, err := NewRSAPrivateKeyFromPEM(block, "rsa"); return err },
+		"ecdsa":           func() error { _, err := NewECDSAPrivateKey("ec", 256, ifcrypto.KeyUsageSign); return err },
+		"ecdsa pem":       func() error { _, err := NewECDSAPrivateKeyFromPEM(block, "ec"); return err },
+		"ed25519":         func() error { _, err := NewED25519PrivateKey("ed"); return err },
+		"ed25519 pem":     func() error { _, err := NewED25519PrivateKeyFromPEM(block, "ed"); return err },
+		"x25519":          func() error { _, err := NewX25519PrivateKey("x"); return err },
+		"x25519 pem":      func() error { _, err := NewX25519PrivateKeyFromPEM(block, "x"); return err },
+		"secp256k1":       func() error { _, err := NewSecp256k1PrivateKey("k1"); return err },
+		"secp256k1 bytes": func() error { _, err := NewSecp256k1PrivateKeyFromBytes("k1", secret); return err },
+		"hmac":            func() error { _, err := NewHMACKey("hmac", crypto.SHA256); return err },
+		"hmac bytes":      func() error { _, err := NewHMACKeyFromBytes("hmac", secret, crypto.SHA256); return err },
+		"aes":             func() error { _, err := NewSymmetricKey("aes", 256); return err },
+		"aes bytes":       func() error { _, err := NewSymmetricKeyFromBytes("aes", secret); return err },
+		"chacha":          func() error { _, err := NewChaChaKey("chacha"); return err },
+		"chacha bytes":    func() error { _, err := NewChaChaKeyFromBytes("chacha", secret); return err },
+		"encrypted pem":   func() error { _, err := NewKeyPairFromEncryptedPEM(block, secret, "pem"); return err },
+		"pkcs12":          func() error { _, _, err := NewKeyPairFromPKCS12("p12", nil, ""); return err },
+	}
+
+	for name, constructor := range constructors {
+		assert.Equal(t, ErrVerifyOnly, constructor(), name)
+	}
+
+	// Keys that are not created by a constructor can not sign either.
+	_, err := (&ECDSAPrivateKey{}).Sign(rand.Reader, secret, crypto.SHA256)
+	assert.Equal(t, ErrVerifyOnly, err)
+
+	_, err = (&ED25519PrivateKey{}).Sign(rand.Reader, secret, crypto.Hash(0))
+	assert.Equal(t, ErrVerifyOnly, err)
+
+}
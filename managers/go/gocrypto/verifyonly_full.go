@@ -0,0 +1,7 @@
+//go:build !verifyonly
+// +build !verifyonly
+
+package gocrypto
+
+// verifyOnlyBuild is `true` when built with the _verifyonly_ build tag.
+const verifyOnlyBuild = false
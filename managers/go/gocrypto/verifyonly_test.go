@@ -0,0 +1,110 @@
+//go:build !verifyonly
+// +build !verifyonly
+
+package gocrypto
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/sha256"
+	"testing"
+
+	"github.com/mariotoffia/goservice/interfaces/ifcrypto"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestVerifyOnlyDisablesPrivateKeyOperations(t *testing.T) {
+
+	key, err := NewECDSAPrivateKey("ec", 256, ifcrypto.KeyUsageSign)
+	require.NoError(t, err)
+
+	SetVerifyOnly(true)
+	defer SetVerifyOnly(false)
+
+	assert.True(t, IsVerifyOnly())
+
+	_, err = NewRSAPrivateKey("rsa", 2048)
+	assert.Equal(t, ErrVerifyOnly, err)
+
+	_, err = NewED25519PrivateKey("ed")
+	assert.Equal(t, ErrVerifyOnly, err)
+
+	digest := sha256.Sum256([]byte("message"))
+
+	_, err = key.Sign(rand.Reader, digest[:], crypto.SHA256)
+	assert.Equal(t, ErrVerifyOnly, err)
+
+	SetVerifyOnly(false)
+
+	_, err = key.Sign(rand.Reader, digest[:], crypto.SHA256)
+	assert.NoError(t, err)
+
+}
+
+func TestVerifyOnlyDisablesSecretKeys(t *testing.T) {
+
+	secret := make([]byte, 32)
+
+	mac, err := NewHMACKeyFromBytes("hmac", secret, crypto.SHA256, ifcrypto.KeyUsageSign)
+	require.NoError(t, err)
+
+	chacha, err := NewChaChaKeyFromBytes("chacha", secret, ifcrypto.KeyUsageEncrypt)
+	require.NoError(t, err)
+
+	ciphertext, err := chacha.Encrypt(ifcrypto.ChiperXChaCha20Poly1305, []byte("message"), nil)
+	require.NoError(t, err)
+
+	jwk, err := mac.ToJWK()
+	require.NoError(t, err)
+
+	SetVerifyOnly(true)
+	defer SetVerifyOnly(false)
+
+	_, err = NewHMACKey("hmac", crypto.SHA256)
+	assert.Equal(t, ErrVerifyOnly, err)
+
+	_, err = NewHMACKeyFromBytes("hmac", secret, crypto.SHA256)
+	assert.Equal(t, ErrVerifyOnly, err)
+
+	_, err = mac.Sign(rand.Reader, []byte("message"), nil)
+	assert.Equal(t, ErrVerifyOnly, err)
+
+	_, err = NewSymmetricKey("aes", 256)
+	assert.Equal(t, ErrVerifyOnly, err)
+
+	_, err = NewSymmetricKeyFromBytes("aes", secret)
+	assert.Equal(t, ErrVerifyOnly, err)
+
+	_, err = NewChaChaKey("chacha")
+	assert.Equal(t, ErrVerifyOnly, err)
+
+	_, err = chacha.Decrypt(ifcrypto.ChiperXChaCha20Poly1305, ciphertext, nil)
+	assert.Equal(t, ErrVerifyOnly, err)
+
+	_, err = NewKeyFromJSONWebKey(jwk)
+	assert.Equal(t, ErrVerifyOnly, err)
+
+}
+
+func TestVerifyOnlyDisablesPrivateKeyImport(t *testing.T) {
+
+	key, err := NewECDSAPrivateKey("ec", 256, ifcrypto.KeyUsageSign)
+	require.NoError(t, err)
+
+	private, err := key.ToJWK()
+	require.NoError(t, err)
+
+	public, err := key.GetPublic().(JWKMarshaler).ToJWK()
+	require.NoError(t, err)
+
+	SetVerifyOnly(true)
+	defer SetVerifyOnly(false)
+
+	_, err = NewKeyFromJSONWebKey(private)
+	assert.Equal(t, ErrVerifyOnly, err)
+
+	_, err = NewKeyFromJSONWebKey(public)
+	assert.NoError(t, err)
+
+}
@@ -0,0 +1,614 @@
+package gocrypto
+
+import (
+	"bytes"
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/mariotoffia/goservice/interfaces/ifcrypto"
+)
+
+var (
+	// ErrSnapshotChainBroken is returned when snapshots do not form a unbroken hash chain.
+	ErrSnapshotChainBroken = fmt.Errorf("keystore snapshot chain is broken")
+	// ErrSnapshotSignature is returned when a snapshot, or a cosignature, do not verify.
+	ErrSnapshotSignature = fmt.Errorf("invalid keystore snapshot signature")
+	// ErrSnapshotQuorum is returned when too few witnesses have countersigned a snapshot.
+	ErrSnapshotQuorum = fmt.Errorf("keystore snapshot witness quorum not reached")
+)
+
+// KeystoreSource returns the current entries of a keystore.
+type KeystoreSource func(c context.Context) ([]ifcrypto.KeystoreEntry, error)
+
+// KeystoreSnapshotterOption configures a `KeystoreSnapshotter`.
+type KeystoreSnapshotterOption func(s *KeystoreSnapshotter)
+
+// WithSnapshotWitnesses sets the _witnesses_ to collect cosignatures from and the _quorum_
+// of cosignatures required. Zero quorum requires all witnesses.
+func WithSnapshotWitnesses(quorum int, witnesses ...ifcrypto.SnapshotWitness) KeystoreSnapshotterOption {
+	return func(s *KeystoreSnapshotter) {
+		s.witnesses = witnesses
+		s.quorum = quorum
+	}
+}
+
+// WithSnapshotPublisher sets a function that receives each snapshot, e.g. to write it to a
+// append only log. A failing publisher fails the snapshot.
+func WithSnapshotPublisher(publisher func(c context.Context, snapshot ifcrypto.KeystoreSnapshot) error) KeystoreSnapshotterOption {
+	return func(s *KeystoreSnapshotter) {
+		s.publisher = publisher
+	}
+}
+
+// WithSnapshotPrevious sets the last published snapshot, so that a restarted snapshotter
+// continues the chain.
+func WithSnapshotPrevious(snapshot *ifcrypto.KeystoreSnapshot) KeystoreSnapshotterOption {
+	return func(s *KeystoreSnapshotter) {
+		s.last = snapshot
+	}
+}
+
+// WithSnapshotClock sets the clock used to timestamp snapshots, default `time.Now`.
+func WithSnapshotClock(clock func() time.Time) KeystoreSnapshotterOption {
+	return func(s *KeystoreSnapshotter) {
+		s.clock = clock
+	}
+}
+
+// WithSnapshotInterval sets the interval of the snapshots when started, see
+// `KeystoreSnapshotter.Start` (default one hour).
+func WithSnapshotInterval(interval time.Duration) KeystoreSnapshotterOption {
+	return func(s *KeystoreSnapshotter) {
+		s.interval = interval
+	}
+}
+
+// WithSnapshotErrorHandler sets a function that receives failed snapshots when started.
+func WithSnapshotErrorHandler(onError func(err error)) KeystoreSnapshotterOption {
+	return func(s *KeystoreSnapshotter) {
+		s.onError = onError
+	}
+}
+
+// KeystoreSnapshotter takes signed, hash chained, snapshots of keystore metadata and has them
+// countersigned by external witnesses.
+//
+// Auditors compare consecutive snapshots, see `DiffKeystoreSnapshots`, to detect keys that
+// have been inserted or silently rotated. Since the witnesses refuse to countersign a
+// snapshot that do not extend the chain they have seen, the keystore can not rewrite history
+// without the witnesses noticing.
+type KeystoreSnapshotter struct {
+	signerID   string
+	signer     crypto.Signer
+	source     KeystoreSource
+	witnesses  []ifcrypto.SnapshotWitness
+	quorum     int
+	publisher  func(c context.Context, snapshot ifcrypto.KeystoreSnapshot) error
+	clock      func() time.Time
+	last       *ifcrypto.KeystoreSnapshot
+	interval   time.Duration
+	onError    func(err error)
+	background *Background
+	mu         sync.Mutex
+}
+
+// NewKeystoreSnapshotter creates a new `KeystoreSnapshotter` that reads the entries from _source_
+// and signs the snapshots with _signer_ identified by _signerID_.
+func NewKeystoreSnapshotter(
+	signerID string,
+	signer crypto.Signer,
+	source KeystoreSource,
+	opts ...KeystoreSnapshotterOption,
+) *KeystoreSnapshotter {
+
+	s := &KeystoreSnapshotter{
+		signerID:  signerID,
+		signer:    signer,
+		source:    source,
+		publisher: func(context.Context, ifcrypto.KeystoreSnapshot) error { return nil },
+		clock:     time.Now,
+		interval:  time.Hour,
+	}
+
+	for _, opt := range opts {
+		opt(s)
+	}
+
+	s.background = NewTickerBackground("keystore snapshotter", s.interval, func(c context.Context) {
+
+		if _, err := s.Snapshot(c); err != nil && s.onError != nil {
+			s.onError(err)
+		}
+
+	})
+
+	return s
+
+}
+
+// Snapshot takes, signs, countersigns and publishes a new snapshot.
+//
+// If fewer witnesses than the quorum countersign, `ErrSnapshotQuorum` is returned and the
+// snapshot is neither published nor becomes part of the chain.
+func (s *KeystoreSnapshotter) Snapshot(c context.Context) (*ifcrypto.KeystoreSnapshot, error) {
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	entries, err := s.source(c)
+	if err != nil {
+		return nil, err
+	}
+
+	entries = append([]ifcrypto.KeystoreEntry{}, entries...)
+
+	sort.SliceStable(entries, func(i, j int) bool {
+
+		if entries[i].KeyID != entries[j].KeyID {
+			return entries[i].KeyID < entries[j].KeyID
+		}
+
+		return entries[i].Version < entries[j].Version
+
+	})
+
+	snapshot := ifcrypto.KeystoreSnapshot{
+		Sequence: 1,
+		Created:  s.clock().UTC(),
+		SignerID: s.signerID,
+		Entries:  entries,
+	}
+
+	if s.last != nil {
+		snapshot.Sequence = s.last.Sequence + 1
+		snapshot.PrevHash = s.last.Hash
+	}
+
+	snapshot.Hash = keystoreSnapshotHash(&snapshot)
+
+	if snapshot.Signature, err = signDigest(s.signer, snapshot.Hash); err != nil {
+		return nil, err
+	}
+
+	var errs []string
+
+	for _, witness := range s.witnesses {
+
+		cosig, err := witness.Cosign(c, snapshot)
+		if err != nil {
+			errs = append(errs, fmt.Sprintf("%s: %v", witness.WitnessID(), err))
+			continue
+		}
+
+		snapshot.Cosignatures = append(snapshot.Cosignatures, *cosig)
+
+	}
+
+	if len(snapshot.Cosignatures) < s.requiredCosignatures() {
+		return nil, fmt.Errorf("%w: %d of %d %v", ErrSnapshotQuorum, len(snapshot.Cosignatures), s.requiredCosignatures(), errs)
+	}
+
+	if err := s.publisher(c, snapshot); err != nil {
+		return nil, err
+	}
+
+	s.last = &snapshot
+
+	return &snapshot, nil
+
+}
+
+// Start implements the `ifcrypto.Lifecycle` interface, it takes a snapshot periodically, see
+// `WithSnapshotInterval`, until stopped. Failed snapshots are reported to the
+// `WithSnapshotErrorHandler` and do not stop the loop.
+func (s *KeystoreSnapshotter) Start(c context.Context) error {
+	return s.background.Start(c)
+}
+
+// Stop implements the `ifcrypto.Lifecycle` interface. A snapshot in-flight is completed.
+func (s *KeystoreSnapshotter) Stop(c context.Context) error {
+	return s.background.Stop(c)
+}
+
+// Run takes a snapshot every _interval_ until _c_ is done. Failed snapshots are reported to
+// _onError_, if not `nil`, and do not stop the loop.
+func (s *KeystoreSnapshotter) Run(c context.Context, interval time.Duration, onError func(err error)) {
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+
+		select {
+		case <-c.Done():
+			return
+		case <-ticker.C:
+		}
+
+		if _, err := s.Snapshot(c); err != nil && onError != nil {
+			onError(err)
+		}
+
+	}
+
+}
+
+// requiredCosignatures returns the number of cosignatures required for a snapshot.
+func (s *KeystoreSnapshotter) requiredCosignatures() int {
+
+	if s.quorum <= 0 || s.quorum > len(s.witnesses) {
+		return len(s.witnesses)
+	}
+
+	return s.quorum
+
+}
+
+// LocalWitness is a in process `ifcrypto.SnapshotWitness`.
+//
+// It verifies the keystore signature and that each snapshot directly follows the previous
+// snapshot it has countersigned. It is typically run by a auditor in a separate service.
+type LocalWitness struct {
+	id       string
+	signer   crypto.Signer
+	keystore crypto.PublicKey
+	last     *ifcrypto.KeystoreSnapshot
+	clock    func() time.Time
+	mu       sync.Mutex
+}
+
+// NewLocalWitness creates a witness with _id_ that countersigns with _signer_ snapshots
+// signed by the _keystore_ public key.
+func NewLocalWitness(id string, signer crypto.Signer, keystore crypto.PublicKey) *LocalWitness {
+	return &LocalWitness{id: id, signer: signer, keystore: keystore, clock: time.Now}
+}
+
+// WitnessID implements the `ifcrypto.SnapshotWitness` interface.
+func (w *LocalWitness) WitnessID() string {
+	return w.id
+}
+
+// Cosign implements the `ifcrypto.SnapshotWitness` interface.
+func (w *LocalWitness) Cosign(c context.Context, snapshot ifcrypto.KeystoreSnapshot) (*ifcrypto.SnapshotCosignature, error) {
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if err := verifySnapshotSignature(w.keystore, &snapshot); err != nil {
+		return nil, err
+	}
+
+	if w.last != nil {
+
+		if snapshot.Sequence != w.last.Sequence+1 || !bytes.Equal(snapshot.PrevHash, w.last.Hash) {
+			return nil, fmt.Errorf(
+				"%w: sequence %d do not follow witnessed sequence %d",
+				ErrSnapshotChainBroken, snapshot.Sequence, w.last.Sequence,
+			)
+		}
+
+	}
+
+	signature, err := signDigest(w.signer, snapshot.Hash)
+	if err != nil {
+		return nil, err
+	}
+
+	w.last = &snapshot
+
+	return &ifcrypto.SnapshotCosignature{
+		WitnessID: w.id,
+		Created:   w.clock().UTC(),
+		Signature: signature,
+	}, nil
+
+}
+
+// VerifyKeystoreSnapshot verifies the hash and signature of the _snapshot_ using the _keystore_
+// public key, and that at least _quorum_ of the _witnesses_, by witness id, have countersigned it.
+//
+// Cosignatures from unknown witnesses are ignored.
+func VerifyKeystoreSnapshot(
+	snapshot *ifcrypto.KeystoreSnapshot,
+	keystore crypto.PublicKey,
+	witnesses map[string]crypto.PublicKey,
+	quorum int,
+) error {
+
+	if err := verifySnapshotSignature(keystore, snapshot); err != nil {
+		return err
+	}
+
+	valid := map[string]bool{}
+
+	for _, cosig := range snapshot.Cosignatures {
+
+		pub, ok := witnesses[cosig.WitnessID]
+		if !ok {
+			continue
+		}
+
+		if verifyDigest(pub, snapshot.Hash, cosig.Signature) {
+			valid[cosig.WitnessID] = true
+		}
+
+	}
+
+	if len(valid) < quorum {
+		return fmt.Errorf("%w: %d of %d", ErrSnapshotQuorum, len(valid), quorum)
+	}
+
+	return nil
+
+}
+
+// VerifyKeystoreSnapshotChain verifies that the _snapshots_, in sequence order, forms a unbroken
+// hash chain. The first snapshot may be any snapshot in the chain.
+func VerifyKeystoreSnapshotChain(snapshots []ifcrypto.KeystoreSnapshot) error {
+
+	for i := range snapshots {
+
+		snapshot := &snapshots[i]
+
+		if !bytes.Equal(snapshot.Hash, keystoreSnapshotHash(snapshot)) {
+			return fmt.Errorf("%w: hash mismatch at sequence %d", ErrSnapshotChainBroken, snapshot.Sequence)
+		}
+
+		if i == 0 {
+			continue
+		}
+
+		prev := &snapshots[i-1]
+
+		if snapshot.Sequence != prev.Sequence+1 {
+			return fmt.Errorf(
+				"%w: expected sequence %d got %d", ErrSnapshotChainBroken, prev.Sequence+1, snapshot.Sequence,
+			)
+		}
+
+		if !bytes.Equal(snapshot.PrevHash, prev.Hash) {
+			return fmt.Errorf("%w: previous hash mismatch at sequence %d", ErrSnapshotChainBroken, snapshot.Sequence)
+		}
+
+	}
+
+	return nil
+
+}
+
+// KeystoreChangeKind is the kind of a `KeystoreChange`.
+type KeystoreChangeKind string
+
+const (
+	// KeystoreChangeAdded is when a key, or key version, is present only in the newer snapshot.
+	KeystoreChangeAdded KeystoreChangeKind = "added"
+	// KeystoreChangeRemoved is when a key, or key version, is present only in the older snapshot.
+	KeystoreChangeRemoved KeystoreChangeKind = "removed"
+	// KeystoreChangeRotated is when the fingerprint, or type, changed without a new version.
+	KeystoreChangeRotated KeystoreChangeKind = "rotated"
+	// KeystoreChangeState is when only the state has changed.
+	KeystoreChangeState KeystoreChangeKind = "state"
+)
+
+// KeystoreChange is a difference between two snapshots.
+type KeystoreChange struct {
+	Kind KeystoreChangeKind `json:"kind"`
+	// Before is the entry in the older snapshot, `nil` if added.
+	Before *ifcrypto.KeystoreEntry `json:"before,omitempty"`
+	// After is the entry in the newer snapshot, `nil` if removed.
+	After *ifcrypto.KeystoreEntry `json:"after,omitempty"`
+}
+
+// DiffKeystoreSnapshots returns the changes from _before_ to _after_ sorted by key id and version.
+//
+// A _rotated_ change is a key whose material changed under the same id and version, which
+// always should be investigated.
+func DiffKeystoreSnapshots(before, after *ifcrypto.KeystoreSnapshot) []KeystoreChange {
+
+	key := func(e *ifcrypto.KeystoreEntry) string {
+		return e.KeyID + "\x00" + e.Version
+	}
+
+	old := map[string]*ifcrypto.KeystoreEntry{}
+
+	for i := range before.Entries {
+		old[key(&before.Entries[i])] = &before.Entries[i]
+	}
+
+	changes := []KeystoreChange{}
+
+	for i := range after.Entries {
+
+		entry := &after.Entries[i]
+		prev, ok := old[key(entry)]
+
+		delete(old, key(entry))
+
+		switch {
+		case !ok:
+			changes = append(changes, KeystoreChange{Kind: KeystoreChangeAdded, After: entry})
+		case prev.Fingerprint != entry.Fingerprint || prev.KeyType != entry.KeyType:
+			changes = append(changes, KeystoreChange{Kind: KeystoreChangeRotated, Before: prev, After: entry})
+		case prev.State != entry.State:
+			changes = append(changes, KeystoreChange{Kind: KeystoreChangeState, Before: prev, After: entry})
+		}
+
+	}
+
+	for _, prev := range old {
+		changes = append(changes, KeystoreChange{Kind: KeystoreChangeRemoved, Before: prev})
+	}
+
+	sort.SliceStable(changes, func(i, j int) bool {
+
+		a, b := changes[i].After, changes[j].After
+
+		if a == nil {
+			a = changes[i].Before
+		}
+
+		if b == nil {
+			b = changes[j].Before
+		}
+
+		if a.KeyID != b.KeyID {
+			return a.KeyID < b.KeyID
+		}
+
+		return a.Version < b.Version
+
+	})
+
+	return changes
+
+}
+
+// KeystoreEntriesFromKeys creates a entry, with _state_, for each of the _keys_.
+//
+// The fingerprint is the _SHA-256_ of the _DER_ encoded public key. Symmetric keys are
+// fingerprinted using a domain separated hash of the key. Remote keys have no fingerprint.
+func KeystoreEntriesFromKeys(state string, keys ...ifcrypto.Key) ([]ifcrypto.KeystoreEntry, error) {
+
+	entries := make([]ifcrypto.KeystoreEntry, 0, len(keys))
+
+	for _, key := range keys {
+
+		fingerprint, err := keyFingerprint(key)
+		if err != nil {
+			return nil, fmt.Errorf("key %s: %w", key.GetID(), err)
+		}
+
+		entries = append(entries, ifcrypto.KeystoreEntry{
+			KeyID:       key.GetID(),
+			KeyType:     key.GetKeyType(),
+			Fingerprint: fingerprint,
+			State:       state,
+		})
+
+	}
+
+	return entries, nil
+
+}
+
+// keyFingerprint returns the hex encoded _SHA-256_ fingerprint of the _key_.
+func keyFingerprint(key ifcrypto.Key) (string, error) {
+
+	if key.IsRemoteKey() {
+		return "", nil
+	}
+
+	if key.IsSymmetric() || key.GetKeyType() == ifcrypto.KeyTypeHmac {
+
+		material, ok := key.GetKey().([]byte)
+		if !ok {
+			return "", fmt.Errorf("unsupported symmetric key: %T", key.GetKey())
+		}
+
+		sum := sha256.Sum256(append([]byte("goservice keystore fingerprint\x00"), material...))
+
+		return hex.EncodeToString(sum[:]), nil
+
+	}
+
+	return Fingerprint(key)
+
+}
+
+// signDigest signs the _SHA-256_ _digest_. _Ed25519_ signs the digest as message.
+func signDigest(signer crypto.Signer, digest []byte) ([]byte, error) {
+
+	if _, ok := signer.Public().(ed25519.PublicKey); ok {
+		return signer.Sign(rand.Reader, digest, crypto.Hash(0))
+	}
+
+	return signer.Sign(rand.Reader, digest, crypto.SHA256)
+
+}
+
+// verifyDigest verifies a signature created by `signDigest`. _RSA_ signatures
+// must be _PKCS #1 v1.5_.
+func verifyDigest(pub crypto.PublicKey, digest, signature []byte) bool {
+
+	switch key := pub.(type) {
+	case ed25519.PublicKey:
+		return ed25519.Verify(key, digest, signature)
+	case *ecdsa.PublicKey:
+		return ecdsa.VerifyASN1(key, digest, signature)
+	case *rsa.PublicKey:
+		return rsa.VerifyPKCS1v15(key, crypto.SHA256, digest, signature) == nil
+	}
+
+	return false
+
+}
+
+// verifySnapshotSignature verifies the hash and the keystore signature of the _snapshot_.
+func verifySnapshotSignature(keystore crypto.PublicKey, snapshot *ifcrypto.KeystoreSnapshot) error {
+
+	if !bytes.Equal(snapshot.Hash, keystoreSnapshotHash(snapshot)) {
+		return fmt.Errorf("%w: hash mismatch at sequence %d", ErrSnapshotChainBroken, snapshot.Sequence)
+	}
+
+	if !verifyDigest(keystore, snapshot.Hash, snapshot.Signature) {
+		return fmt.Errorf("%w: sequence %d", ErrSnapshotSignature, snapshot.Sequence)
+	}
+
+	return nil
+
+}
+
+// keystoreSnapshotHash calculates the _SHA-256_ over all fields of the _snapshot_ except the
+// hash, signature and cosignatures.
+//
+// Each variable length field is length prefixed to make the encoding unambiguous.
+func keystoreSnapshotHash(snapshot *ifcrypto.KeystoreSnapshot) []byte {
+
+	h := sha256.New()
+
+	var n [8]byte
+
+	number := func(v uint64) {
+
+		binary.BigEndian.PutUint64(n[:], v)
+		h.Write(n[:])
+
+	}
+
+	field := func(b []byte) {
+
+		number(uint64(len(b)))
+		h.Write(b)
+
+	}
+
+	number(snapshot.Sequence)
+	number(uint64(snapshot.Created.UnixNano()))
+	field([]byte(snapshot.SignerID))
+	field(snapshot.PrevHash)
+	number(uint64(len(snapshot.Entries)))
+
+	for _, e := range snapshot.Entries {
+
+		field([]byte(e.KeyID))
+		field([]byte(e.Version))
+		field([]byte(e.KeyType))
+		field([]byte(e.Fingerprint))
+		field([]byte(e.State))
+
+	}
+
+	return h.Sum(nil)
+
+}
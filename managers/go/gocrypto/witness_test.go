@@ -0,0 +1,77 @@
+//go:build !verifyonly
+// +build !verifyonly
+
+package gocrypto
+
+import (
+	"context"
+	"crypto"
+	"errors"
+	"testing"
+
+	"github.com/mariotoffia/goservice/interfaces/ifcrypto"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestKeystoreSnapshotWitnessing(t *testing.T) {
+
+	signingKey, err := NewED25519PrivateKey("keystore-signer", ifcrypto.KeyUsageSign)
+	require.NoError(t, err)
+
+	witnessKey, err := NewECDSAPrivateKey("witness-key", 256, ifcrypto.KeyUsageSign)
+	require.NoError(t, err)
+
+	dataKey, err := NewSymmetricKey("db", 256)
+	require.NoError(t, err)
+
+	keys := []ifcrypto.Key{signingKey, dataKey}
+
+	source := func(c context.Context) ([]ifcrypto.KeystoreEntry, error) {
+		return KeystoreEntriesFromKeys("active", keys...)
+	}
+
+	witness := NewLocalWitness("auditor", witnessKey, signingKey.Public())
+
+	snapshotter := NewKeystoreSnapshotter(
+		"keystore-signer", signingKey, source, WithSnapshotWitnesses(1, witness),
+	)
+
+	ctx := context.Background()
+
+	first, err := snapshotter.Snapshot(ctx)
+	require.NoError(t, err)
+
+	witnesses := map[string]crypto.PublicKey{"auditor": witnessKey.Public()}
+	require.NoError(t, VerifyKeystoreSnapshot(first, signingKey.Public(), witnesses, 1))
+
+	// Silently rotate the data key under the same id
+	rotated, err := NewSymmetricKey("db", 256)
+	require.NoError(t, err)
+
+	keys = []ifcrypto.Key{signingKey, rotated}
+
+	second, err := snapshotter.Snapshot(ctx)
+	require.NoError(t, err)
+
+	require.NoError(t, VerifyKeystoreSnapshotChain([]ifcrypto.KeystoreSnapshot{*first, *second}))
+
+	changes := DiffKeystoreSnapshots(first, second)
+	require.Len(t, changes, 1)
+	assert.Equal(t, KeystoreChangeRotated, changes[0].Kind)
+	assert.Equal(t, "db", changes[0].After.KeyID)
+
+	// A forked keystore that restarts from the first snapshot is rejected by the witness
+	fork := NewKeystoreSnapshotter(
+		"keystore-signer", signingKey, source,
+		WithSnapshotWitnesses(1, witness), WithSnapshotPrevious(first),
+	)
+
+	_, err = fork.Snapshot(ctx)
+	assert.True(t, errors.Is(err, ErrSnapshotQuorum))
+
+	tampered := *second
+	tampered.Entries = tampered.Entries[:1]
+	assert.True(t, errors.Is(VerifyKeystoreSnapshot(&tampered, signingKey.Public(), witnesses, 1), ErrSnapshotChainBroken))
+
+}
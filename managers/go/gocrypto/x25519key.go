@@ -0,0 +1,268 @@
+package gocrypto
+
+import (
+	"crypto"
+	"encoding/pem"
+	"fmt"
+	"io"
+
+	"github.com/mariotoffia/goservice/interfaces/ifcrypto"
+	"github.com/mariotoffia/goservice/utils/cryptoutils"
+	"golang.org/x/crypto/curve25519"
+)
+
+// X25519PrivateKey implements the `ifcrypto.KeyPair` and `ifcrypto.KeyAgreement` interfaces
+// for a 32 byte _X25519_ private key.
+type X25519PrivateKey struct {
+	KeyBase
+	key    []byte
+	public *X25519PublicKey
+}
+
+// NewX25519PrivateKeyFromKey creates a new `X25519PrivateKey` from the 32 byte private _key_.
+//
+// The public key portion derives the same usage as the private key
+func NewX25519PrivateKeyFromKey(
+	id string,
+	key []byte,
+	usage ...ifcrypto.KeyUsage,
+) (*X25519PrivateKey, error) {
+
+	if len(key) != curve25519.ScalarSize {
+		return nil, fmt.Errorf("x25519 private key must be %d bytes", curve25519.ScalarSize)
+	}
+
+	pub, err := curve25519.X25519(key, curve25519.Basepoint)
+	if err != nil {
+		return nil, err
+	}
+
+	public, err := NewX25519PublicKeyFromKey(id, pub, usage...)
+	if err != nil {
+		return nil, err
+	}
+
+	return &X25519PrivateKey{
+		KeyBase: KeyBase{
+			id:      id,
+			keyType: ifcrypto.KeyTypeX25519,
+			keySize: 256,
+			usage:   usage,
+			chiper:  []ifcrypto.Chipher{},
+		},
+		key:    append([]byte{}, key...),
+		public: public,
+	}, nil
+
+}
+
+// NewX25519PrivateKeyFromDER initializes a new `X25519PrivateKey` from _PKCS #8_ _DER_.
+func NewX25519PrivateKeyFromDER(
+	der []byte,
+	id string,
+	usage ...ifcrypto.KeyUsage,
+) (*X25519PrivateKey, error) {
+
+	return NewX25519PrivateKeyFromPEM(pem.Block{Type: "PRIVATE KEY", Bytes: der}, id, usage...)
+
+}
+
+// Public returns the public key as a 32 byte slice.
+func (r *X25519PrivateKey) Public() crypto.PublicKey {
+	return r.public.key
+}
+
+// GetPublic returns the public portion of the key
+func (r *X25519PrivateKey) GetPublic() ifcrypto.PublicKey {
+	return r.public
+}
+
+// PEMWrite will write the key onto _w_.
+//
+// If private key, and _public_ is `true`, it will in addition write the public portion as well.
+func (r *X25519PrivateKey) PEMWrite(w io.Writer, public bool) error {
+
+	return cryptoutils.X25519PrivateKeyToPEM(w, r.key, public)
+
+}
+
+// PEMWriteEncrypted implements the `ifcrypto.EncryptedPEMWriter` interface.
+func (r *X25519PrivateKey) PEMWriteEncrypted(w io.Writer, passphrase []byte) error {
+
+	return pemWriteEncrypted(w, r, passphrase)
+
+}
+
+// DERWrite implements the `ifcrypto.DERWriter` interface, the key is written as _PKCS #8_.
+func (r *X25519PrivateKey) DERWrite(w io.Writer) error {
+
+	return privateKeyDERWrite(w, r)
+
+}
+
+// ToJWK implements the `JWKMarshaler` interface. The _JWK_ includes the private key, use
+// `GetPublic` to get the public _JWK_.
+func (r *X25519PrivateKey) ToJWK() (*cryptoutils.JSONWebKey, error) {
+	return keyToJWK(r)
+}
+
+// Fingerprint implements the `Fingerprinter` interface and returns the fingerprint of the
+// public key, see `Fingerprint`.
+func (r *X25519PrivateKey) Fingerprint(opts ...FingerprintOption) (string, error) {
+	return Fingerprint(r, opts...)
+}
+
+// GetKey gets the underlying key, if any.
+//
+// Some keys are remote and not possible to fetch. In such situations the function returns a remote id,
+// most often the same as GetID() returns.
+func (r *X25519PrivateKey) GetKey() interface{} {
+	return r.key
+}
+
+// IsSymmetric returns `true` if this is a `KeyTypeSymmetric`
+//
+// This is a convenience function instead of `GetKeyType`.
+func (r *X25519PrivateKey) IsSymmetric() bool {
+	return false
+}
+
+// IsPrivate returns `true` if this is a `KeyType` other than `KeyTypeSymmetric` and is a private key.
+//
+// If `KeyTypeSymmetric` it will return `true` since all symmetric keys are considered as private.
+func (r *X25519PrivateKey) IsPrivate() bool {
+	return true
+}
+
+// IsRemoteKey returns `true` if the key is not present in current process memory.
+//
+// Typically hardware units or remote services will not reveal their private key. In such case, this
+// method returns `true`. If present in memory such as a `*rsa.PrivateKey` it returns `false`.
+func (r *X25519PrivateKey) IsRemoteKey() bool {
+	return false
+}
+
+// X25519PublicKey implements the `ifcrypto.PublicKey` interface for a 32 byte _X25519_ public key.
+type X25519PublicKey struct {
+	KeyBase
+	key []byte
+	pem publicPEM
+}
+
+// NewX25519PublicKeyFromKey creates a instance based on a existing 32 byte public key.
+func NewX25519PublicKeyFromKey(
+	id string,
+	key []byte,
+	usage ...ifcrypto.KeyUsage,
+) (*X25519PublicKey, error) {
+
+	if len(key) != curve25519.PointSize {
+		return nil, fmt.Errorf("x25519 public key must be %d bytes", curve25519.PointSize)
+	}
+
+	return &X25519PublicKey{
+		KeyBase: KeyBase{
+			id:      id,
+			keyType: ifcrypto.KeyTypeX25519,
+			keySize: 256,
+			usage:   usage,
+		},
+		key: append([]byte{}, key...),
+	}, nil
+
+}
+
+// NewX25519PublicKeyFromPEM initializes a new `X25519PublicKey` from the underlying _PKIX_ _PEM_ block.
+func NewX25519PublicKeyFromPEM(
+	block pem.Block,
+	id string,
+	usage ...ifcrypto.KeyUsage,
+) (*X25519PublicKey, error) {
+
+	if block.Type == "PUBLIC KEY" {
+
+		key, err := cryptoutils.ParseX25519PKIXPublicKey(block.Bytes)
+		if err != nil {
+			return nil, err
+		}
+
+		return NewX25519PublicKeyFromKey(id, key, usage...)
+
+	}
+
+	return nil, fmt.Errorf("unsupported PEM block: %s", block.Type)
+
+}
+
+// NewX25519PublicKeyFromDER initializes a new `X25519PublicKey` from _PKIX_ _DER_.
+func NewX25519PublicKeyFromDER(
+	der []byte,
+	id string,
+	usage ...ifcrypto.KeyUsage,
+) (*X25519PublicKey, error) {
+
+	return NewX25519PublicKeyFromPEM(pem.Block{Type: "PUBLIC KEY", Bytes: der}, id, usage...)
+
+}
+
+// PEMWrite will write the key onto _w_.
+//
+// Since this is a public key, it will ignore the _public_ parameter.
+func (r *X25519PublicKey) PEMWrite(w io.Writer, public bool) error {
+
+	return cryptoutils.X25519PublicKeyToPEM(w, r.key)
+
+}
+
+// DERWrite implements the `ifcrypto.DERWriter` interface, the key is written as _PKIX_.
+func (r *X25519PublicKey) DERWrite(w io.Writer) error {
+
+	return publicKeyDERWrite(w, r)
+
+}
+
+// WriteTo implements the `io.WriterTo` interface and writes the key as _PEM_ onto _w_. The
+// encoding is cached, hence streaming many keys do not encode them over and over again.
+func (r *X25519PublicKey) WriteTo(w io.Writer) (int64, error) {
+	return r.pem.writeTo(w, r)
+}
+
+// ToJWK implements the `JWKMarshaler` interface.
+func (r *X25519PublicKey) ToJWK() (*cryptoutils.JSONWebKey, error) {
+	return keyToJWK(r)
+}
+
+// Fingerprint implements the `Fingerprinter` interface, see `Fingerprint`.
+func (r *X25519PublicKey) Fingerprint(opts ...FingerprintOption) (string, error) {
+	return Fingerprint(r, opts...)
+}
+
+// GetKey gets the underlying key, if any.
+//
+// Some keys are remote and not possible to fetch. In such situations the function returns a remote id,
+// most often the same as GetID() returns.
+func (r *X25519PublicKey) GetKey() interface{} {
+	return r.key
+}
+
+// IsSymmetric returns `true` if this is a `KeyTypeSymmetric`
+//
+// This is a convenience function instead of `GetKeyType`.
+func (r *X25519PublicKey) IsSymmetric() bool {
+	return false
+}
+
+// IsPrivate returns `true` if this is a `KeyType` other than `KeyTypeSymmetric` and is a private key.
+//
+// If `KeyTypeSymmetric` it will return `true` since all symmetric keys are considered as private.
+func (r *X25519PublicKey) IsPrivate() bool {
+	return false
+}
+
+// IsRemoteKey returns `true` if the key is not present in current process memory.
+//
+// Typically hardware units or remote services will not reveal their private key. In such case, this
+// method returns `true`. If present in memory such as a `*rsa.PrivateKey` it returns `false`.
+func (r *X25519PublicKey) IsRemoteKey() bool {
+	return false
+}
@@ -0,0 +1,99 @@
+//go:build !verifyonly
+// +build !verifyonly
+
+package gocrypto
+
+import (
+	"crypto/subtle"
+	"encoding/pem"
+	"fmt"
+	"io"
+
+	"github.com/mariotoffia/goservice/interfaces/ifcrypto"
+	"github.com/mariotoffia/goservice/utils/cryptoutils"
+	"golang.org/x/crypto/curve25519"
+)
+
+// NewX25519PrivateKeyFromPEM initializes a new `X25519PrivateKey` from the underlying _PKCS #8_ _PEM_ block.
+func NewX25519PrivateKeyFromPEM(
+	block pem.Block,
+	id string,
+	usage ...ifcrypto.KeyUsage,
+) (*X25519PrivateKey, error) {
+
+	if IsVerifyOnly() {
+		return nil, ErrVerifyOnly
+	}
+
+	if block.Type == "PRIVATE KEY" {
+
+		key, err := cryptoutils.ParseX25519PKCS8PrivateKey(block.Bytes)
+		if err != nil {
+			return nil, err
+		}
+
+		return NewX25519PrivateKeyFromKey(id, key, usage...)
+
+	}
+
+	return nil, fmt.Errorf("unsupported PEM block: %s", block.Type)
+
+}
+
+// NewX25519PrivateKey generates a new `X25519PrivateKey` using the `rand.Reader`, or the `EntropyGuard` reader, as entropy.
+func NewX25519PrivateKey(id string, usage ...ifcrypto.KeyUsage) (*X25519PrivateKey, error) {
+
+	if IsVerifyOnly() {
+		return nil, ErrVerifyOnly
+	}
+
+	reader, err := keyGenerationReader()
+	if err != nil {
+		return nil, err
+	}
+
+	key := make([]byte, curve25519.ScalarSize)
+
+	if _, err := io.ReadFull(reader, key); err != nil {
+		return nil, err
+	}
+
+	return NewX25519PrivateKeyFromKey(id, key, usage...)
+
+}
+
+// DeriveSharedSecret implements the `ifcrypto.KeyAgreement` interface.
+//
+// The _peer_ must be a _X25519_ public key. A low order _peer_ key, resulting in a all zero
+// shared secret, is rejected.
+func (r *X25519PrivateKey) DeriveSharedSecret(peer ifcrypto.PublicKey) ([]byte, error) {
+
+	if IsVerifyOnly() {
+		return nil, ErrVerifyOnly
+	}
+
+	if err := CheckAlgorithm(ifcrypto.PolicyOperationKeyAgreement, r, 0); err != nil {
+		return nil, err
+	}
+
+	if peer.GetKeyType() != ifcrypto.KeyTypeX25519 {
+		return nil, fmt.Errorf("peer key must be x25519, got %s", peer.GetKeyType())
+	}
+
+	pub, ok := peer.GetKey().([]byte)
+	if !ok || len(pub) != curve25519.PointSize {
+		return nil, fmt.Errorf("peer key is not a x25519 public key")
+	}
+
+	secret, err := curve25519.X25519(r.key, pub)
+	if err != nil {
+		return nil, err
+	}
+
+	if subtle.ConstantTimeCompare(secret, make([]byte, len(secret))) == 1 {
+		return nil, fmt.Errorf("x25519 shared secret is zero")
+	}
+
+	return secret, nil
+
+}
@@ -0,0 +1,75 @@
+//go:build !verifyonly
+// +build !verifyonly
+
+package gocrypto
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/pem"
+	"testing"
+
+	"github.com/mariotoffia/goservice/interfaces/ifcrypto"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestX25519SharedSecret(t *testing.T) {
+
+	alice, err := NewX25519PrivateKey("alice", ifcrypto.KeyUsageKeyAgreement)
+	require.NoError(t, err)
+
+	bob, err := NewX25519PrivateKey("bob", ifcrypto.KeyUsageKeyAgreement)
+	require.NoError(t, err)
+
+	var _ ifcrypto.KeyPair = alice
+	var _ ifcrypto.KeyAgreement = alice
+
+	s1, err := alice.DeriveSharedSecret(bob.GetPublic())
+	require.NoError(t, err)
+
+	s2, err := bob.DeriveSharedSecret(alice.GetPublic())
+	require.NoError(t, err)
+
+	assert.Equal(t, s1, s2)
+
+	ec, err := NewECDSAPrivateKey("ec", 256)
+	require.NoError(t, err)
+
+	_, err = alice.DeriveSharedSecret(ec.GetPublic())
+	assert.Error(t, err)
+
+	zero, err := NewX25519PublicKeyFromKey("zero", make([]byte, 32))
+	require.NoError(t, err)
+
+	_, err = alice.DeriveSharedSecret(zero)
+	assert.Error(t, err)
+
+}
+
+func TestX25519PEMRoundTrip(t *testing.T) {
+
+	key, err := NewX25519PrivateKey("x", ifcrypto.KeyUsageKeyAgreement)
+	require.NoError(t, err)
+
+	var buf bytes.Buffer
+	require.NoError(t, key.PEMWrite(&buf, true))
+
+	privBlock, rest := pem.Decode(buf.Bytes())
+	require.NotNil(t, privBlock)
+	pubBlock, _ := pem.Decode(rest)
+	require.NotNil(t, pubBlock)
+
+	loaded, err := NewX25519PrivateKeyFromPEM(*privBlock, "x")
+	require.NoError(t, err)
+	assert.Equal(t, key.GetKey(), loaded.GetKey())
+
+	pub, err := NewX25519PublicKeyFromPEM(*pubBlock, "x")
+	require.NoError(t, err)
+	assert.Equal(t, key.Public(), pub.GetKey())
+
+	// RFC 8410 encodings, as produced by e.g. openssl genpkey -algorithm X25519
+	assert.Equal(t, "302e020100300506032b656e04220420", hex.EncodeToString(privBlock.Bytes[:16]))
+	assert.Equal(t, "302a300506032b656e032100", hex.EncodeToString(pubBlock.Bytes[:12]))
+
+}
@@ -0,0 +1,212 @@
+package godkim
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+)
+
+// Canonicalization is a _DKIM_ canonicalization algorithm as of _RFC 6376_ section 3.4.
+type Canonicalization string
+
+const (
+	// CanonicalizationSimple tolerates almost no modification of the message.
+	CanonicalizationSimple Canonicalization = "simple"
+	// CanonicalizationRelaxed tolerates whitespace changes and header folding.
+	CanonicalizationRelaxed Canonicalization = "relaxed"
+)
+
+// header is a single header field of a message, _raw_ is the field including the
+// terminating _CRLF_.
+type header struct {
+	name string
+	raw  string
+}
+
+// splitMessage splits the _message_ into its header fields and body. Bare line feeds
+// are converted to _CRLF_ first, since messages handed to a program often use local
+// line endings.
+func splitMessage(message []byte) ([]header, []byte, error) {
+
+	message = normalizeLineEndings(message)
+
+	var raw, body []byte
+
+	if bytes.HasPrefix(message, []byte("\r\n")) {
+		body = message[2:]
+	} else if idx := bytes.Index(message, []byte("\r\n\r\n")); idx >= 0 {
+		raw, body = message[:idx+2], message[idx+4:]
+	} else {
+		raw = message
+	}
+
+	var headers []header
+
+	for _, line := range strings.SplitAfter(string(raw), "\r\n") {
+
+		if line == "" {
+			continue
+		}
+
+		if line[0] == ' ' || line[0] == '\t' {
+
+			if len(headers) == 0 {
+				return nil, nil, fmt.Errorf("message starts with a continuation line")
+			}
+
+			headers[len(headers)-1].raw += line
+			continue
+
+		}
+
+		idx := strings.IndexByte(line, ':')
+		if idx <= 0 {
+			return nil, nil, fmt.Errorf("malformed header line: %q", strings.TrimSpace(line))
+		}
+
+		headers = append(headers, header{name: strings.TrimRight(line[:idx], " \t"), raw: line})
+
+	}
+
+	return headers, body, nil
+
+}
+
+// normalizeLineEndings converts bare _LF_ into _CRLF_.
+func normalizeLineEndings(message []byte) []byte {
+
+	if !bytes.Contains(message, []byte("\n")) {
+		return message
+	}
+
+	out := make([]byte, 0, len(message)+len(message)/40)
+
+	for i, b := range message {
+
+		if b == '\n' && (i == 0 || message[i-1] != '\r') {
+			out = append(out, '\r')
+		}
+
+		out = append(out, b)
+
+	}
+
+	return out
+
+}
+
+// canonicalHeader canonicalizes a header field including the _CRLF_.
+func canonicalHeader(c Canonicalization, raw string) string {
+
+	if c == CanonicalizationSimple {
+		return raw
+	}
+
+	idx := strings.IndexByte(raw, ':')
+
+	name := strings.ToLower(strings.TrimRight(raw[:idx], " \t"))
+	value := strings.NewReplacer("\r\n", "").Replace(raw[idx+1:])
+
+	return name + ":" + strings.TrimSpace(collapseWhitespace(value)) + "\r\n"
+
+}
+
+// canonicalBody canonicalizes the _body_.
+func canonicalBody(c Canonicalization, body []byte) []byte {
+
+	if c == CanonicalizationRelaxed {
+
+		lines := strings.SplitAfter(string(body), "\r\n")
+
+		var sb strings.Builder
+
+		for _, line := range lines {
+
+			crlf := strings.HasSuffix(line, "\r\n")
+			line = strings.TrimRight(collapseWhitespace(strings.TrimSuffix(line, "\r\n")), " ")
+
+			sb.WriteString(line)
+
+			if crlf {
+				sb.WriteString("\r\n")
+			}
+
+		}
+
+		body = []byte(sb.String())
+
+	}
+
+	for bytes.HasSuffix(body, []byte("\r\n")) {
+		body = body[:len(body)-2]
+	}
+
+	if len(body) == 0 {
+
+		if c == CanonicalizationRelaxed {
+			return body
+		}
+
+		return []byte("\r\n")
+
+	}
+
+	return append(body[:len(body):len(body)], '\r', '\n')
+
+}
+
+// collapseWhitespace replaces each sequence of spaces and tabs with a single space.
+func collapseWhitespace(s string) string {
+
+	var sb strings.Builder
+
+	space := false
+
+	for _, r := range s {
+
+		if r == ' ' || r == '\t' {
+			space = true
+			continue
+		}
+
+		if space {
+			sb.WriteByte(' ')
+			space = false
+		}
+
+		sb.WriteRune(r)
+
+	}
+
+	if space {
+		sb.WriteByte(' ')
+	}
+
+	return sb.String()
+
+}
+
+// selectHeaders returns the header fields named in _names_, each name picks the last not
+// yet picked instance as of _RFC 6376_ section 5.4.2. Names without a instance are skipped.
+func selectHeaders(headers []header, names []string) []header {
+
+	used := make([]bool, len(headers))
+	selected := []header{}
+
+	for _, name := range names {
+
+		for i := len(headers) - 1; i >= 0; i-- {
+
+			if !used[i] && strings.EqualFold(headers[i].name, name) {
+				used[i] = true
+				selected = append(selected, headers[i])
+				break
+			}
+
+		}
+
+	}
+
+	return selected
+
+}
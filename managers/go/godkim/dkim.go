@@ -0,0 +1,583 @@
+package godkim
+
+import (
+	"context"
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DefaultSignedHeaders is the header fields signed when none are configured.
+var DefaultSignedHeaders = []string{
+	"From", "Reply-To", "Subject", "Date", "To", "Cc", "Message-ID",
+	"In-Reply-To", "References", "MIME-Version", "Content-Type", "Content-Transfer-Encoding",
+}
+
+// SignerOption configures a `Signer`.
+type SignerOption func(s *Signer)
+
+// WithSignedHeaders sets the header fields to sign, _From_ is always signed.
+func WithSignedHeaders(names ...string) SignerOption {
+	return func(s *Signer) {
+		s.headers = names
+	}
+}
+
+// WithCanonicalization sets the header and body canonicalization (default relaxed/relaxed).
+func WithCanonicalization(header, body Canonicalization) SignerOption {
+	return func(s *Signer) {
+		s.headerCanon = header
+		s.bodyCanon = body
+	}
+}
+
+// WithExpiration sets how long the signature is valid, the _x=_ tag. Zero omits it.
+func WithExpiration(validity time.Duration) SignerOption {
+	return func(s *Signer) {
+		s.validity = validity
+	}
+}
+
+// WithSignerClock sets the clock used for the _t=_ and _x=_ tags.
+func WithSignerClock(now func() time.Time) SignerOption {
+	return func(s *Signer) {
+		s.now = now
+	}
+}
+
+// Signer signs outgoing messages as of _RFC 6376_ using _rsa-sha256_ or, as of
+// _RFC 8463_, _ed25519-sha256_.
+type Signer struct {
+	domain      string
+	selector    string
+	signer      crypto.Signer
+	algorithm   string
+	headers     []string
+	headerCanon Canonicalization
+	bodyCanon   Canonicalization
+	validity    time.Duration
+	now         func() time.Time
+}
+
+// NewSigner creates a new `Signer` for the signing _domain_ (_d=_) and _selector_ (_s=_).
+//
+// The _signer_ must be backed by a _RSA_ or _Ed25519_ key, the public key is published using
+// the record from `DNSRecord`.
+func NewSigner(domain, selector string, signer crypto.Signer, opts ...SignerOption) (*Signer, error) {
+
+	s := &Signer{
+		domain:      domain,
+		selector:    selector,
+		signer:      signer,
+		headers:     DefaultSignedHeaders,
+		headerCanon: CanonicalizationRelaxed,
+		bodyCanon:   CanonicalizationRelaxed,
+		now:         time.Now,
+	}
+
+	switch key := signer.Public().(type) {
+	case *rsa.PublicKey:
+
+		if key.N.BitLen() < 1024 {
+			return nil, fmt.Errorf("DKIM RSA keys must be at least 1024 bits, got: %d", key.N.BitLen())
+		}
+
+		s.algorithm = "rsa-sha256"
+
+	case ed25519.PublicKey:
+
+		s.algorithm = "ed25519-sha256"
+
+	default:
+
+		return nil, fmt.Errorf("unsupported DKIM key: %T", key)
+
+	}
+
+	for _, opt := range opts {
+		opt(s)
+	}
+
+	return s, nil
+
+}
+
+// Sign returns the _DKIM-Signature_ header field, including the terminating _CRLF_, to
+// prepend to the _message_.
+func (s *Signer) Sign(message []byte) (string, error) {
+
+	headers, body, err := splitMessage(message)
+	if err != nil {
+		return "", err
+	}
+
+	names := s.headers
+	if !containsFold(names, "From") {
+		names = append([]string{"From"}, names...)
+	}
+
+	selected := selectHeaders(headers, names)
+
+	signedNames := make([]string, len(selected))
+	for i := range selected {
+		signedNames[i] = selected[i].name
+	}
+
+	bodyHash := sha256.Sum256(canonicalBody(s.bodyCanon, body))
+	now := s.now()
+
+	tags := []string{
+		"v=1",
+		"a=" + s.algorithm,
+		fmt.Sprintf("c=%s/%s", s.headerCanon, s.bodyCanon),
+		"d=" + s.domain,
+		"s=" + s.selector,
+		"t=" + strconv.FormatInt(now.Unix(), 10),
+	}
+
+	if s.validity > 0 {
+		tags = append(tags, "x="+strconv.FormatInt(now.Add(s.validity).Unix(), 10))
+	}
+
+	tags = append(tags,
+		"h="+strings.Join(signedNames, ":"),
+		"bh="+base64.StdEncoding.EncodeToString(bodyHash[:]),
+		"b=",
+	)
+
+	field := "DKIM-Signature: " + strings.Join(tags, ";\r\n\t")
+
+	digest := headerHash(s.headerCanon, selected, field)
+
+	var signature []byte
+
+	if s.algorithm == "ed25519-sha256" {
+		signature, err = s.signer.Sign(rand.Reader, digest, crypto.Hash(0))
+	} else {
+		signature, err = s.signer.Sign(rand.Reader, digest, crypto.SHA256)
+	}
+
+	if err != nil {
+		return "", err
+	}
+
+	return field + base64.StdEncoding.EncodeToString(signature) + "\r\n", nil
+
+}
+
+// SignMessage returns the _message_ with the _DKIM-Signature_ header field prepended.
+func (s *Signer) SignMessage(message []byte) ([]byte, error) {
+
+	field, err := s.Sign(message)
+	if err != nil {
+		return nil, err
+	}
+
+	return append([]byte(field), normalizeLineEndings(message)...), nil
+
+}
+
+// DNSRecord returns the _TXT_ record value to publish at _selector._domainkey.domain_.
+func DNSRecord(pub crypto.PublicKey) (string, error) {
+
+	switch key := pub.(type) {
+	case *rsa.PublicKey:
+
+		der, err := x509.MarshalPKIXPublicKey(key)
+		if err != nil {
+			return "", err
+		}
+
+		return "v=DKIM1; k=rsa; p=" + base64.StdEncoding.EncodeToString(der), nil
+
+	case ed25519.PublicKey:
+
+		return "v=DKIM1; k=ed25519; p=" + base64.StdEncoding.EncodeToString(key), nil
+
+	}
+
+	return "", fmt.Errorf("unsupported DKIM key: %T", pub)
+
+}
+
+// DNSRecordName returns the name of the _TXT_ record for _selector_ and _domain_.
+func DNSRecordName(selector, domain string) string {
+	return selector + "._domainkey." + domain
+}
+
+// VerificationResult is the outcome of verifying a single _DKIM-Signature_.
+type VerificationResult struct {
+	// Domain is the signing domain, _d=_.
+	Domain string
+	// Selector is the key selector, _s=_.
+	Selector string
+	// Err is `nil` if the signature is valid.
+	Err error
+}
+
+// VerifierOption configures a `Verifier`.
+type VerifierOption func(v *Verifier)
+
+// WithTXTLookup sets the function used to resolve the public key records, default is
+// `net.DefaultResolver.LookupTXT`.
+func WithTXTLookup(lookup func(c context.Context, name string) ([]string, error)) VerifierOption {
+	return func(v *Verifier) {
+		v.lookupTXT = lookup
+	}
+}
+
+// WithVerifierClock sets the clock used to check the _x=_ tag.
+func WithVerifierClock(now func() time.Time) VerifierOption {
+	return func(v *Verifier) {
+		v.now = now
+	}
+}
+
+// Verifier verifies the _DKIM_ signatures of incoming messages.
+type Verifier struct {
+	lookupTXT func(c context.Context, name string) ([]string, error)
+	now       func() time.Time
+}
+
+// NewVerifier creates a new `Verifier`.
+func NewVerifier(opts ...VerifierOption) *Verifier {
+
+	v := &Verifier{lookupTXT: net.DefaultResolver.LookupTXT, now: time.Now}
+
+	for _, opt := range opts {
+		opt(v)
+	}
+
+	return v
+
+}
+
+// Verify verifies all _DKIM-Signature_ header fields of the _message_ and returns one
+// result per signature. A message without signatures returns an empty slice.
+func (v *Verifier) Verify(c context.Context, message []byte) ([]VerificationResult, error) {
+
+	headers, body, err := splitMessage(message)
+	if err != nil {
+		return nil, err
+	}
+
+	results := []VerificationResult{}
+
+	for _, h := range headers {
+
+		if !strings.EqualFold(h.name, "DKIM-Signature") {
+			continue
+		}
+
+		tags, err := parseTags(h.raw[strings.IndexByte(h.raw, ':')+1:])
+
+		result := VerificationResult{Domain: tags["d"], Selector: tags["s"]}
+
+		if err == nil {
+			err = v.verify(c, headers, body, h, tags)
+		}
+
+		result.Err = err
+		results = append(results, result)
+
+	}
+
+	return results, nil
+
+}
+
+// verify verifies a single signature header _sig_ with the parsed _tags_.
+func (v *Verifier) verify(c context.Context, headers []header, body []byte, sig header, tags map[string]string) error {
+
+	if tags["v"] != "1" {
+		return fmt.Errorf("unsupported DKIM version: %q", tags["v"])
+	}
+
+	for _, required := range []string{"a", "b", "bh", "d", "h", "s"} {
+
+		if _, ok := tags[required]; !ok {
+			return fmt.Errorf("DKIM signature is missing the %s= tag", required)
+		}
+
+	}
+
+	names := strings.Split(tags["h"], ":")
+	for i := range names {
+		names[i] = strings.TrimSpace(names[i])
+	}
+
+	if !containsFold(names, "From") {
+		return fmt.Errorf("DKIM signature does not sign the From header")
+	}
+
+	if x, ok := tags["x"]; ok {
+
+		expires, err := strconv.ParseInt(x, 10, 64)
+		if err != nil {
+			return fmt.Errorf("invalid DKIM x= tag: %q", x)
+		}
+
+		if v.now().Unix() > expires {
+			return fmt.Errorf("DKIM signature expired")
+		}
+
+	}
+
+	headerCanon, bodyCanon, err := parseCanonicalization(tags["c"])
+	if err != nil {
+		return err
+	}
+
+	canonical := canonicalBody(bodyCanon, body)
+
+	if l, ok := tags["l"]; ok {
+
+		length, err := strconv.Atoi(l)
+		if err != nil || length < 0 || length > len(canonical) {
+			return fmt.Errorf("invalid DKIM l= tag: %q", l)
+		}
+
+		canonical = canonical[:length]
+
+	}
+
+	bodyHash := sha256.Sum256(canonical)
+
+	if base64.StdEncoding.EncodeToString(bodyHash[:]) != stripWhitespace(tags["bh"]) {
+		return fmt.Errorf("DKIM body hash mismatch")
+	}
+
+	pub, err := v.lookupKey(c, tags["s"], tags["d"])
+	if err != nil {
+		return err
+	}
+
+	signature, err := base64.StdEncoding.DecodeString(stripWhitespace(tags["b"]))
+	if err != nil {
+		return fmt.Errorf("invalid DKIM b= tag: %v", err)
+	}
+
+	digest := headerHash(headerCanon, selectHeaders(headers, names), strings.TrimSuffix(stripSignature(sig.raw), "\r\n"))
+
+	switch tags["a"] {
+	case "rsa-sha256":
+
+		key, ok := pub.(*rsa.PublicKey)
+		if !ok {
+			return fmt.Errorf("DKIM key type does not match algorithm %s", tags["a"])
+		}
+
+		if err := rsa.VerifyPKCS1v15(key, crypto.SHA256, digest, signature); err != nil {
+			return fmt.Errorf("DKIM signature verification failed")
+		}
+
+	case "ed25519-sha256":
+
+		key, ok := pub.(ed25519.PublicKey)
+		if !ok {
+			return fmt.Errorf("DKIM key type does not match algorithm %s", tags["a"])
+		}
+
+		if !ed25519.Verify(key, digest, signature) {
+			return fmt.Errorf("DKIM signature verification failed")
+		}
+
+	default:
+
+		return fmt.Errorf("unsupported DKIM algorithm: %s", tags["a"])
+
+	}
+
+	return nil
+
+}
+
+// lookupKey resolves the public key for _selector_ and _domain_.
+func (v *Verifier) lookupKey(c context.Context, selector, domain string) (crypto.PublicKey, error) {
+
+	records, err := v.lookupTXT(c, DNSRecordName(selector, domain))
+	if err != nil {
+		return nil, err
+	}
+
+	if len(records) == 0 {
+		return nil, fmt.Errorf("no DKIM key record for %s", DNSRecordName(selector, domain))
+	}
+
+	tags, err := parseTags(strings.Join(records, ""))
+	if err != nil {
+		return nil, err
+	}
+
+	p := stripWhitespace(tags["p"])
+	if p == "" {
+		return nil, fmt.Errorf("DKIM key for %s is revoked", DNSRecordName(selector, domain))
+	}
+
+	raw, err := base64.StdEncoding.DecodeString(p)
+	if err != nil {
+		return nil, err
+	}
+
+	switch tags["k"] {
+	case "", "rsa":
+
+		pub, err := x509.ParsePKIXPublicKey(raw)
+		if err != nil {
+
+			// Some records carry a bare PKCS#1 key
+			return x509.ParsePKCS1PublicKey(raw)
+
+		}
+
+		return pub, nil
+
+	case "ed25519":
+
+		if len(raw) != ed25519.PublicKeySize {
+			return nil, fmt.Errorf("invalid DKIM ed25519 key size: %d", len(raw))
+		}
+
+		return ed25519.PublicKey(raw), nil
+
+	}
+
+	return nil, fmt.Errorf("unsupported DKIM key type: %s", tags["k"])
+
+}
+
+// headerHash computes the _SHA-256_ of the canonicalized _selected_ headers followed by the
+// signature header _field_ with an empty _b=_ value and without trailing _CRLF_.
+func headerHash(c Canonicalization, selected []header, field string) []byte {
+
+	h := sha256.New()
+
+	for _, hdr := range selected {
+		h.Write([]byte(canonicalHeader(c, hdr.raw)))
+	}
+
+	h.Write([]byte(strings.TrimSuffix(canonicalHeader(c, field+"\r\n"), "\r\n")))
+
+	return h.Sum(nil)
+
+}
+
+// parseTags parses a _DKIM_ tag list, e.g. _v=1; a=rsa-sha256_.
+func parseTags(list string) (map[string]string, error) {
+
+	tags := map[string]string{}
+
+	for _, spec := range strings.Split(list, ";") {
+
+		spec = strings.TrimSpace(spec)
+		if spec == "" {
+			continue
+		}
+
+		idx := strings.IndexByte(spec, '=')
+		if idx <= 0 {
+			return nil, fmt.Errorf("malformed DKIM tag: %q", spec)
+		}
+
+		name := strings.TrimSpace(spec[:idx])
+
+		if _, ok := tags[name]; ok {
+			return nil, fmt.Errorf("duplicate DKIM tag: %s", name)
+		}
+
+		tags[name] = strings.TrimSpace(spec[idx+1:])
+
+	}
+
+	return tags, nil
+
+}
+
+// parseCanonicalization parses the _c=_ tag value.
+func parseCanonicalization(c string) (Canonicalization, Canonicalization, error) {
+
+	if c == "" {
+		return CanonicalizationSimple, CanonicalizationSimple, nil
+	}
+
+	parts := strings.SplitN(c, "/", 2)
+	if len(parts) == 1 {
+		parts = append(parts, string(CanonicalizationSimple))
+	}
+
+	result := make([]Canonicalization, 2)
+
+	for i, part := range parts {
+
+		switch Canonicalization(part) {
+		case CanonicalizationSimple, CanonicalizationRelaxed:
+			result[i] = Canonicalization(part)
+		default:
+			return "", "", fmt.Errorf("unsupported DKIM canonicalization: %s", c)
+		}
+
+	}
+
+	return result[0], result[1], nil
+
+}
+
+// stripSignature removes the value of the _b=_ tag from the raw signature header field.
+func stripSignature(raw string) string {
+
+	idx := strings.IndexByte(raw, ':')
+	specs := strings.Split(raw[idx+1:], ";")
+
+	for i, spec := range specs {
+
+		eq := strings.IndexByte(spec, '=')
+
+		if eq > 0 && strings.TrimSpace(spec[:eq]) == "b" {
+
+			specs[i] = spec[:eq+1]
+
+			if strings.HasSuffix(spec, "\r\n") && i == len(specs)-1 {
+				specs[i] += "\r\n"
+			}
+
+		}
+
+	}
+
+	return raw[:idx+1] + strings.Join(specs, ";")
+
+}
+
+// stripWhitespace removes all folding whitespace from a tag value.
+func stripWhitespace(s string) string {
+	return strings.Map(func(r rune) rune {
+		if r == ' ' || r == '\t' || r == '\r' || r == '\n' {
+			return -1
+		}
+		return r
+	}, s)
+}
+
+// containsFold returns `true` if _names_ contains _name_, ignoring case.
+func containsFold(names []string, name string) bool {
+
+	for _, n := range names {
+
+		if strings.EqualFold(n, name) {
+			return true
+		}
+
+	}
+
+	return false
+
+}
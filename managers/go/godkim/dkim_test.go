@@ -0,0 +1,123 @@
+package godkim
+
+import (
+	"context"
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testMessage = "From: Joe SixPack <joe@football.example.com>\n" +
+	"To: Suzie Q <suzie@shopping.example.net>\n" +
+	"Subject:  Is dinner   ready?\n" +
+	"Date: Fri, 11 Jul 2003 21:00:37 -0700 (PDT)\n" +
+	"Message-ID: <20030712040037.46341.5F8J@football.example.com>\n" +
+	"\n" +
+	"Hi.\n" +
+	"\n" +
+	"We lost the game.  Are you hungry yet?\n" +
+	"\n" +
+	"Joe.\n" +
+	"\n"
+
+func TestSignAndVerify(t *testing.T) {
+
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+
+	_, edKey, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+
+	for _, key := range []crypto.Signer{rsaKey, edKey} {
+
+		for _, canon := range []Canonicalization{CanonicalizationSimple, CanonicalizationRelaxed} {
+
+			record, err := DNSRecord(key.Public())
+			require.NoError(t, err)
+
+			lookup := func(c context.Context, name string) ([]string, error) {
+
+				if name != "brisbane._domainkey.football.example.com" {
+					return nil, fmt.Errorf("no such record: %s", name)
+				}
+
+				return []string{record}, nil
+
+			}
+
+			signer, err := NewSigner("football.example.com", "brisbane", key,
+				WithCanonicalization(canon, canon), WithExpiration(time.Hour),
+			)
+			require.NoError(t, err)
+
+			signed, err := signer.SignMessage([]byte(testMessage))
+			require.NoError(t, err)
+
+			verifier := NewVerifier(WithTXTLookup(lookup))
+
+			results, err := verifier.Verify(context.Background(), signed)
+			require.NoError(t, err)
+			require.Len(t, results, 1)
+
+			assert.NoError(t, results[0].Err, "%T %s", key, canon)
+			assert.Equal(t, "football.example.com", results[0].Domain)
+
+			tampered := strings.Replace(string(signed), "hungry", "thirsty", 1)
+
+			results, err = verifier.Verify(context.Background(), []byte(tampered))
+			require.NoError(t, err)
+			assert.Error(t, results[0].Err)
+
+			tampered = strings.Replace(string(signed), "Is dinner", "Is lunch", 1)
+
+			results, err = verifier.Verify(context.Background(), []byte(tampered))
+			require.NoError(t, err)
+			assert.Error(t, results[0].Err)
+
+		}
+
+	}
+
+}
+
+func TestRelaxedToleratesWhitespaceChanges(t *testing.T) {
+
+	_, key, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+
+	record, _ := DNSRecord(key.Public())
+
+	signer, err := NewSigner("example.com", "s1", key)
+	require.NoError(t, err)
+
+	signed, err := signer.SignMessage([]byte(testMessage))
+	require.NoError(t, err)
+
+	modified := strings.Replace(string(signed), "Subject:  Is dinner   ready?", "subject: Is dinner\r\n ready?", 1)
+	modified = strings.Replace(modified, "game.  Are", "game. Are  ", 1)
+
+	verifier := NewVerifier(WithTXTLookup(func(c context.Context, name string) ([]string, error) {
+		return []string{record}, nil
+	}))
+
+	results, err := verifier.Verify(context.Background(), []byte(modified))
+	require.NoError(t, err)
+	assert.NoError(t, results[0].Err)
+
+}
+
+func TestCanonicalBody(t *testing.T) {
+
+	assert.Equal(t, "\r\n", string(canonicalBody(CanonicalizationSimple, nil)))
+	assert.Equal(t, "", string(canonicalBody(CanonicalizationRelaxed, []byte("\r\n\r\n"))))
+	assert.Equal(t, " C\r\nD E\r\n", string(canonicalBody(CanonicalizationRelaxed, []byte(" C \r\nD \t E\r\n\r\n\r\n"))))
+
+}
@@ -0,0 +1,349 @@
+package godsse
+
+import (
+	"encoding/binary"
+	"fmt"
+)
+
+// CBOR major types, see _RFC 8949_.
+const (
+	cborBytes = 2
+	cborText  = 3
+	cborArray = 4
+	cborMap   = 5
+	cborTag   = 6
+)
+
+// cborMaxDepth is the maximum nesting depth of a skipped item.
+const cborMaxDepth = 64
+
+// cborSelfDescribe is the encoded tag 55799 that may prefix any _CBOR_ item.
+var cborSelfDescribe = []byte{0xd9, 0xd9, 0xf7}
+
+// cborCodec is the `FormatCBOR` codec.
+//
+// The envelope is a map of _payload_ (bytes), _payloadType_ (text) and _signatures_, a array
+// of maps of _sig_ (bytes) and the optional _keyid_ (text). It is encoded deterministically,
+// i.e. with definite lengths and sorted keys as of _RFC 8949_ section 4.2.1.
+type cborCodec struct{}
+
+func (cborCodec) Format() Format {
+	return FormatCBOR
+}
+
+func (cborCodec) Detect(data []byte) bool {
+	return len(data) > 0 && (data[0]>>5 == cborMap || data[0] == cborSelfDescribe[0])
+}
+
+func (cborCodec) Encode(envelope *Envelope) ([]byte, error) {
+
+	buf := make([]byte, 0, len(envelope.Payload)+len(envelope.PayloadType)+64)
+
+	// Keys in bytewise order of their encoding, i.e. shorter keys first
+	buf = appendCBORHead(buf, cborMap, 3)
+	buf = appendCBORText(buf, "payload")
+	buf = appendCBORHead(buf, cborBytes, uint64(len(envelope.Payload)))
+	buf = append(buf, envelope.Payload...)
+	buf = appendCBORText(buf, "signatures")
+	buf = appendCBORHead(buf, cborArray, uint64(len(envelope.Signatures)))
+
+	for _, sig := range envelope.Signatures {
+
+		if sig.KeyID == "" {
+			buf = appendCBORHead(buf, cborMap, 1)
+		} else {
+			buf = appendCBORHead(buf, cborMap, 2)
+		}
+
+		buf = appendCBORText(buf, "sig")
+		buf = appendCBORHead(buf, cborBytes, uint64(len(sig.Sig)))
+		buf = append(buf, sig.Sig...)
+
+		if sig.KeyID != "" {
+			buf = appendCBORText(buf, "keyid")
+			buf = appendCBORText(buf, sig.KeyID)
+		}
+
+	}
+
+	buf = appendCBORText(buf, "payloadType")
+	buf = appendCBORText(buf, envelope.PayloadType)
+
+	return buf, nil
+
+}
+
+func (cborCodec) Decode(data []byte) (*Envelope, error) {
+
+	d := &cborDecoder{data: data}
+
+	if len(data) >= len(cborSelfDescribe) && string(data[:3]) == string(cborSelfDescribe) {
+		d.pos = len(cborSelfDescribe)
+	}
+
+	envelope := &Envelope{Signatures: []Signature{}}
+
+	err := d.readMap(func(key string) error {
+
+		var err error
+
+		switch key {
+		case "payload":
+			envelope.Payload, err = d.readString(cborBytes)
+		case "payloadType":
+
+			var text []byte
+
+			text, err = d.readString(cborText)
+			envelope.PayloadType = string(text)
+
+		case "signatures":
+
+			var n uint64
+
+			if n, err = d.readHead(cborArray); err != nil {
+				return err
+			}
+
+			for i := uint64(0); i < n && err == nil; i++ {
+
+				var sig Signature
+
+				err = d.readMap(func(key string) error {
+
+					switch key {
+					case "sig":
+
+						var err error
+
+						sig.Sig, err = d.readString(cborBytes)
+						return err
+
+					case "keyid":
+
+						text, err := d.readString(cborText)
+						sig.KeyID = string(text)
+
+						return err
+
+					}
+
+					return d.skip()
+
+				})
+
+				envelope.Signatures = append(envelope.Signatures, sig)
+
+			}
+
+		default:
+			err = d.skip()
+		}
+
+		return err
+
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	if d.pos != len(data) {
+		return nil, fmt.Errorf("trailing data after CBOR envelope")
+	}
+
+	return envelope, nil
+
+}
+
+// appendCBORHead appends the head of a item of _major_ type with the argument _n_.
+func appendCBORHead(buf []byte, major byte, n uint64) []byte {
+
+	major <<= 5
+
+	switch {
+	case n < 24:
+		return append(buf, major|byte(n))
+	case n <= 0xff:
+		return append(buf, major|24, byte(n))
+	case n <= 0xffff:
+		return append(buf, major|25, byte(n>>8), byte(n))
+	case n <= 0xffffffff:
+		return append(buf, major|26, byte(n>>24), byte(n>>16), byte(n>>8), byte(n))
+	}
+
+	buf = append(buf, major|27)
+
+	var scratch [8]byte
+
+	binary.BigEndian.PutUint64(scratch[:], n)
+
+	return append(buf, scratch[:]...)
+
+}
+
+// appendCBORText appends _s_ as a text string.
+func appendCBORText(buf []byte, s string) []byte {
+	return append(appendCBORHead(buf, cborText, uint64(len(s))), s...)
+}
+
+// cborDecoder decodes the subset of _CBOR_ used by the envelope, i.e. definite length items.
+type cborDecoder struct {
+	data []byte
+	pos  int
+}
+
+// next reads the head of the next item and returns its major type and argument.
+func (d *cborDecoder) next() (byte, uint64, error) {
+
+	if d.pos >= len(d.data) {
+		return 0, 0, fmt.Errorf("unexpected end of CBOR data")
+	}
+
+	major, info := d.data[d.pos]>>5, d.data[d.pos]&0x1f
+	d.pos++
+
+	if info < 24 {
+		return major, uint64(info), nil
+	}
+
+	if info > 27 {
+		return 0, 0, fmt.Errorf("unsupported CBOR additional info: %d", info)
+	}
+
+	size := 1 << (info - 24)
+
+	if len(d.data)-d.pos < size {
+		return 0, 0, fmt.Errorf("unexpected end of CBOR data")
+	}
+
+	var n uint64
+
+	for _, b := range d.data[d.pos : d.pos+size] {
+		n = n<<8 | uint64(b)
+	}
+
+	d.pos += size
+
+	return major, n, nil
+
+}
+
+// readHead reads the head of a item that must be of _major_ type.
+func (d *cborDecoder) readHead(major byte) (uint64, error) {
+
+	got, n, err := d.next()
+	if err != nil {
+		return 0, err
+	}
+
+	if got != major {
+		return 0, fmt.Errorf("expected CBOR major type %d, got %d", major, got)
+	}
+
+	return n, nil
+
+}
+
+// readString reads a byte or text string of _major_ type.
+func (d *cborDecoder) readString(major byte) ([]byte, error) {
+
+	n, err := d.readHead(major)
+	if err != nil {
+		return nil, err
+	}
+
+	if uint64(len(d.data)-d.pos) < n {
+		return nil, fmt.Errorf("unexpected end of CBOR data")
+	}
+
+	s := append([]byte{}, d.data[d.pos:d.pos+int(n)]...)
+	d.pos += int(n)
+
+	return s, nil
+
+}
+
+// readMap reads a map with text keys and calls _value_ to read the value of each key.
+func (d *cborDecoder) readMap(value func(key string) error) error {
+
+	n, err := d.readHead(cborMap)
+	if err != nil {
+		return err
+	}
+
+	for i := uint64(0); i < n; i++ {
+
+		key, err := d.readString(cborText)
+		if err != nil {
+			return err
+		}
+
+		if err := value(string(key)); err != nil {
+			return err
+		}
+
+	}
+
+	return nil
+
+}
+
+// skip skips the next item, e.g. the value of a unknown key.
+func (d *cborDecoder) skip() error {
+	return d.skipDepth(0)
+}
+
+// skipDepth skips the next item nested at _depth_. Nesting deeper than `cborMaxDepth` is
+// rejected to not exhaust the stack on untrusted input.
+func (d *cborDecoder) skipDepth(depth int) error {
+
+	if depth > cborMaxDepth {
+		return fmt.Errorf("CBOR nesting too deep at offset %d", d.pos)
+	}
+
+	major, n, err := d.next()
+	if err != nil {
+		return err
+	}
+
+	switch major {
+	case cborBytes, cborText:
+
+		if uint64(len(d.data)-d.pos) < n {
+			return fmt.Errorf("unexpected end of CBOR data")
+		}
+
+		d.pos += int(n)
+
+	case cborArray, cborMap:
+
+		// Each item is at least one byte, this also keeps the map item count from overflowing
+		items := uint64(len(d.data) - d.pos)
+		if major == cborMap {
+			items /= 2
+		}
+
+		if n > items {
+			return fmt.Errorf("CBOR item count %d exceeds the remaining data", n)
+		}
+
+		if major == cborMap {
+			n *= 2
+		}
+
+		for i := uint64(0); i < n; i++ {
+
+			if err := d.skipDepth(depth + 1); err != nil {
+				return err
+			}
+
+		}
+
+	case cborTag:
+		return d.skipDepth(depth + 1)
+	}
+
+	return nil
+
+}
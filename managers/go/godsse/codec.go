@@ -0,0 +1,156 @@
+package godsse
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"sync"
+)
+
+// Format is the wire format of a encoded `Envelope`.
+type Format string
+
+const (
+	// FormatJSON is the standard _DSSE_ _JSON_ envelope.
+	FormatJSON Format = "json"
+	// FormatCBOR is a deterministic _CBOR_ map with the same field names as the _JSON_ envelope,
+	// e.g. for constrained devices.
+	FormatCBOR Format = "cbor"
+	// FormatProtobuf is the _DSSE_ _protobuf_ `Envelope` message.
+	FormatProtobuf Format = "protobuf"
+)
+
+// Codec encodes and decodes a `Envelope` in a `Format`.
+type Codec interface {
+	// Format returns the format of the codec.
+	Format() Format
+	// Detect returns `true` if _data_ looks like this format. It is only a hint, the _data_ is
+	// decoded to confirm.
+	Detect(data []byte) bool
+	// Encode encodes the _envelope_.
+	Encode(envelope *Envelope) ([]byte, error)
+	// Decode decodes a envelope from _data_.
+	Decode(data []byte) (*Envelope, error)
+}
+
+var (
+	codecsMu sync.RWMutex
+	codecs   = []Codec{jsonCodec{}, cborCodec{}, protobufCodec{}}
+)
+
+// RegisterCodec registers the _codec_, replacing any codec of the same format. Registered
+// codecs take part in the format detection of `Unmarshal`, in registration order.
+func RegisterCodec(codec Codec) {
+
+	codecsMu.Lock()
+	defer codecsMu.Unlock()
+
+	for i := range codecs {
+
+		if codecs[i].Format() == codec.Format() {
+			codecs[i] = codec
+			return
+		}
+
+	}
+
+	codecs = append(codecs, codec)
+
+}
+
+// CodecFor returns the registered `Codec` of _format_.
+func CodecFor(format Format) (Codec, error) {
+
+	codecsMu.RLock()
+	defer codecsMu.RUnlock()
+
+	for _, codec := range codecs {
+
+		if codec.Format() == format {
+			return codec, nil
+		}
+
+	}
+
+	return nil, fmt.Errorf("unsupported envelope format: %s", format)
+
+}
+
+// MarshalFormat returns the encoding of the envelope in _format_.
+func (e *Envelope) MarshalFormat(format Format) ([]byte, error) {
+
+	codec, err := CodecFor(format)
+	if err != nil {
+		return nil, err
+	}
+
+	return codec.Encode(e)
+
+}
+
+// UnmarshalFormat decodes a envelope in any registered format and returns the detected format.
+//
+// The codecs that detects the _data_ are tried in registration order, the first that decodes
+// it wins.
+func UnmarshalFormat(data []byte) (*Envelope, Format, error) {
+
+	codecsMu.RLock()
+	candidates := append([]Codec{}, codecs...)
+	codecsMu.RUnlock()
+
+	var first error
+
+	for _, codec := range candidates {
+
+		if !codec.Detect(data) {
+			continue
+		}
+
+		envelope, err := codec.Decode(data)
+		if err == nil {
+			return envelope, codec.Format(), nil
+		}
+
+		if first == nil {
+			first = fmt.Errorf("invalid %s envelope: %v", codec.Format(), err)
+		}
+
+	}
+
+	if first == nil {
+		first = fmt.Errorf("unknown envelope format")
+	}
+
+	return nil, "", first
+
+}
+
+// jsonCodec is the `FormatJSON` codec.
+type jsonCodec struct{}
+
+func (jsonCodec) Format() Format {
+	return FormatJSON
+}
+
+func (jsonCodec) Detect(data []byte) bool {
+
+	trimmed := bytes.TrimLeft(data, " \t\r\n")
+	return len(trimmed) > 0 && trimmed[0] == '{'
+
+}
+
+func (jsonCodec) Encode(envelope *Envelope) ([]byte, error) {
+	return json.Marshal(envelope)
+}
+
+func (jsonCodec) Decode(data []byte) (*Envelope, error) {
+
+	var envelope Envelope
+
+	if err := json.Unmarshal(data, &envelope); err != nil {
+		return nil, err
+	}
+
+	return &envelope, nil
+
+}
@@ -0,0 +1,125 @@
+package godsse
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"encoding/hex"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEnvelopeFormatsRoundTripWithDetection(t *testing.T) {
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	envelope, err := Sign("application/json", []byte(`{"release":"1.0.0"}`), KeySigner{KeyID: "ci", Signer: key})
+	require.NoError(t, err)
+
+	// A signature without key id
+	require.NoError(t, envelope.AddSignature(KeySigner{Signer: key}))
+
+	keys := map[string]crypto.PublicKey{"ci": key.Public()}
+
+	for _, format := range []Format{FormatJSON, FormatCBOR, FormatProtobuf} {
+
+		data, err := envelope.MarshalFormat(format)
+		require.NoError(t, err, format)
+
+		decoded, detected, err := UnmarshalFormat(data)
+		require.NoError(t, err, format)
+		assert.Equal(t, format, detected)
+		assert.Equal(t, envelope, decoded, format)
+
+		var read Envelope
+
+		_, err = read.ReadFrom(bytes.NewReader(data))
+		require.NoError(t, err, format)
+
+		_, err = read.Verify(keys, 1)
+		assert.NoError(t, err, format)
+
+	}
+
+}
+
+func TestCBOREnvelopeIsDeterministic(t *testing.T) {
+
+	envelope := &Envelope{
+		PayloadType: "t",
+		Payload:     []byte("p"),
+		Signatures:  []Signature{{KeyID: "k", Sig: []byte("s")}},
+	}
+
+	data, err := envelope.MarshalFormat(FormatCBOR)
+	require.NoError(t, err)
+
+	assert.Equal(t,
+		"a3"+"677061796c6f6164"+"4170"+
+			"6a7369676e617475726573"+"81"+"a2"+"63736967"+"4173"+"656b65796964"+"616b"+
+			"6b7061796c6f616454797065"+"6174",
+		hex.EncodeToString(data),
+	)
+
+}
+
+func TestProtobufEnvelopeWireFormat(t *testing.T) {
+
+	envelope := &Envelope{
+		PayloadType: "t",
+		Payload:     []byte("p"),
+		Signatures:  []Signature{{KeyID: "k", Sig: []byte("s")}},
+	}
+
+	data, err := envelope.MarshalFormat(FormatProtobuf)
+	require.NoError(t, err)
+
+	assert.Equal(t, "0a0170"+"120174"+"1a06"+"0a0173"+"12016b", hex.EncodeToString(data))
+
+	// Unknown fields are skipped
+	decoded, err := Unmarshal(append(data, 0x20, 0x01, 0x2d, 1, 2, 3, 4))
+	require.NoError(t, err)
+	assert.Equal(t, envelope, decoded)
+
+}
+
+func TestUnmarshalRejectsUnknownFormat(t *testing.T) {
+
+	_, _, err := UnmarshalFormat([]byte("not an envelope"))
+	assert.Error(t, err)
+
+	_, _, err = UnmarshalFormat([]byte{0xa3, 0x67})
+	assert.Error(t, err)
+
+	_, err = (&Envelope{}).MarshalFormat("xml")
+	assert.Error(t, err)
+
+}
+
+func TestUnmarshalCBORRejectsHostileNesting(t *testing.T) {
+
+	// {"x": [[[[...]]]]} nested far deeper than the limit
+	deep := append([]byte{0xa1, 0x61, 'x'}, bytes.Repeat([]byte{0x81}, 1<<20)...)
+	deep = append(deep, 0x00)
+
+	_, _, err := UnmarshalFormat(deep)
+	assert.Error(t, err)
+
+	// {"x": {2^63 entries}} must not overflow to a empty map
+	huge := []byte{0xa1, 0x61, 'x', 0xbb, 0x80, 0, 0, 0, 0, 0, 0, 0}
+
+	_, _, err = UnmarshalFormat(huge)
+	assert.Error(t, err)
+
+	// {"x": [2^32 items]} with a few bytes left
+	long := []byte{0xa1, 0x61, 'x', 0x9a, 0xff, 0xff, 0xff, 0xff, 0x00}
+
+	_, _, err = UnmarshalFormat(long)
+	assert.Error(t, err)
+
+}
@@ -0,0 +1,310 @@
+package godsse
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+
+	"github.com/mariotoffia/goservice/utils/cryptoutils"
+)
+
+// Envelope is a _DSSE_ (Dead Simple Signing Envelope) as of the
+// https://github.com/secure-systems-lab/dsse[DSSE specification].
+//
+// The _JSON_ encoding of the envelope is the standard _DSSE_ _JSON_ envelope, see `Format` for
+// the other encodings.
+type Envelope struct {
+	PayloadType string      `json:"payloadType"`
+	Payload     []byte      `json:"payload"`
+	Signatures  []Signature `json:"signatures"`
+}
+
+// Signature is a single signature of a `Envelope`.
+type Signature struct {
+	KeyID string `json:"keyid,omitempty"`
+	Sig   []byte `json:"sig"`
+}
+
+// KeySigner is a `crypto.Signer` along with the key id recorded in the signature.
+type KeySigner struct {
+	KeyID  string
+	Signer crypto.Signer
+}
+
+// Sign creates a `Envelope` for the _payload_ signed by all _signers_.
+//
+// _RSA_ keys signs using _PKCS#1 v1.5_ and _SHA-256_, _ECDSA_ keys with the hash matching
+// the curve and _Ed25519_ keys signs the pre-authentication encoding directly.
+func Sign(payloadType string, payload []byte, signers ...KeySigner) (*Envelope, error) {
+
+	if len(signers) == 0 {
+		return nil, fmt.Errorf("at least one signer is required")
+	}
+
+	envelope := &Envelope{PayloadType: payloadType, Payload: payload, Signatures: []Signature{}}
+
+	for _, signer := range signers {
+
+		if err := envelope.AddSignature(signer); err != nil {
+			return nil, err
+		}
+
+	}
+
+	return envelope, nil
+
+}
+
+// AddSignature adds a signature by _signer_, e.g. when several parties co-sign a envelope.
+func (e *Envelope) AddSignature(signer KeySigner) error {
+
+	buf := cryptoutils.AcquireBuffer()
+	defer cryptoutils.ReleaseBuffer(buf)
+
+	sig, err := sign(signer.Signer, appendPAE(buf, e.PayloadType, e.Payload))
+	if err != nil {
+		return err
+	}
+
+	e.Signatures = append(e.Signatures, Signature{KeyID: signer.KeyID, Sig: sig})
+
+	return nil
+
+}
+
+// Verify verifies the envelope signatures and returns the key ids of the valid signatures.
+//
+// The _keys_ maps key ids to trusted public keys. Signatures by unknown keys, or that are
+// invalid, are not counted. At least _threshold_ distinct trusted keys must have signed, a
+// _threshold_ below one is treated as one. Keys are distinct by their public key, not their
+// key id, hence a key trusted under several key ids is counted once.
+func (e *Envelope) Verify(keys map[string]crypto.PublicKey, threshold int) ([]string, error) {
+
+	if threshold < 1 {
+		threshold = 1
+	}
+
+	buf := cryptoutils.AcquireBuffer()
+	defer cryptoutils.ReleaseBuffer(buf)
+
+	message := appendPAE(buf, e.PayloadType, e.Payload)
+	verified := []string{}
+	seen := map[string]bool{}
+
+	for _, sig := range e.Signatures {
+
+		pub, ok := keys[sig.KeyID]
+		if !ok {
+			continue
+		}
+
+		spki, err := x509.MarshalPKIXPublicKey(pub)
+		if err != nil || seen[string(spki)] {
+			continue
+		}
+
+		if verify(pub, message, sig.Sig) == nil {
+			seen[string(spki)] = true
+			verified = append(verified, sig.KeyID)
+		}
+
+	}
+
+	if len(verified) < threshold {
+
+		return verified, fmt.Errorf(
+			"envelope has %d valid signatures by trusted keys, %d required", len(verified), threshold,
+		)
+
+	}
+
+	return verified, nil
+
+}
+
+// Marshal returns the _JSON_ encoding of the envelope.
+func (e *Envelope) Marshal() ([]byte, error) {
+	return json.Marshal(e)
+}
+
+// Unmarshal decodes a envelope in any registered `Format`, e.g. _JSON_, _CBOR_ or _protobuf_,
+// see `UnmarshalFormat`.
+func Unmarshal(data []byte) (*Envelope, error) {
+
+	envelope, _, err := UnmarshalFormat(data)
+
+	return envelope, err
+
+}
+
+// WriteTo implements the `io.WriterTo` interface and writes the _JSON_ encoding onto _w_.
+func (e *Envelope) WriteTo(w io.Writer) (int64, error) {
+
+	data, err := e.Marshal()
+	if err != nil {
+		return 0, err
+	}
+
+	n, err := w.Write(data)
+
+	return int64(n), err
+
+}
+
+// ReadFrom implements the `io.ReaderFrom` interface and decodes a envelope in any registered
+// `Format` from all of _r_ into _e_.
+func (e *Envelope) ReadFrom(r io.Reader) (int64, error) {
+
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return int64(len(data)), err
+	}
+
+	envelope, err := Unmarshal(data)
+	if err != nil {
+		return int64(len(data)), err
+	}
+
+	*e = *envelope
+
+	return int64(len(data)), nil
+
+}
+
+// PAE is the _DSSE_ pre-authentication encoding of the _payload_, i.e. the bytes that are signed.
+func PAE(payloadType string, payload []byte) []byte {
+
+	var buf bytes.Buffer
+
+	return appendPAE(&buf, payloadType, payload)
+
+}
+
+// appendPAE writes the `PAE` onto _buf_, typically a pooled scratch buffer, and returns it.
+func appendPAE(buf *bytes.Buffer, payloadType string, payload []byte) []byte {
+
+	var scratch [20]byte
+
+	buf.Grow(len(payloadType) + len(payload) + 32)
+
+	buf.WriteString("DSSEv1 ")
+	buf.Write(strconv.AppendInt(scratch[:0], int64(len(payloadType)), 10))
+	buf.WriteByte(' ')
+	buf.WriteString(payloadType)
+	buf.WriteByte(' ')
+	buf.Write(strconv.AppendInt(scratch[:0], int64(len(payload)), 10))
+	buf.WriteByte(' ')
+	buf.Write(payload)
+
+	return buf.Bytes()
+
+}
+
+// hashFor returns the hash used to sign with _pub_.
+func hashFor(pub crypto.PublicKey) (crypto.Hash, error) {
+
+	switch key := pub.(type) {
+	case *rsa.PublicKey:
+		return crypto.SHA256, nil
+	case *ecdsa.PublicKey:
+
+		switch key.Curve {
+		case elliptic.P384():
+			return crypto.SHA384, nil
+		case elliptic.P521():
+			return crypto.SHA512, nil
+		}
+
+		return crypto.SHA256, nil
+
+	case ed25519.PublicKey:
+		return crypto.Hash(0), nil
+	}
+
+	return 0, fmt.Errorf("unsupported signing key: %T", pub)
+
+}
+
+// sign signs the _message_ with _signer_.
+func sign(signer crypto.Signer, message []byte) ([]byte, error) {
+
+	h, err := hashFor(signer.Public())
+	if err != nil {
+		return nil, err
+	}
+
+	if h == crypto.Hash(0) {
+		return signer.Sign(rand.Reader, message, h)
+	}
+
+	digest := cryptoutils.AcquireDigest()
+	defer cryptoutils.ReleaseDigest(digest)
+
+	return signer.Sign(rand.Reader, sum(h, message, digest), h)
+
+}
+
+// verify verifies a signature made by `sign`.
+func verify(pub crypto.PublicKey, message, sig []byte) error {
+
+	h, err := hashFor(pub)
+	if err != nil {
+		return err
+	}
+
+	var digest []byte
+
+	if h != crypto.Hash(0) {
+
+		buf := cryptoutils.AcquireDigest()
+		defer cryptoutils.ReleaseDigest(buf)
+
+		digest = sum(h, message, buf)
+
+	}
+
+	switch key := pub.(type) {
+	case *rsa.PublicKey:
+
+		if rsa.VerifyPKCS1v15(key, h, digest, sig) == nil {
+			return nil
+		}
+
+	case *ecdsa.PublicKey:
+
+		if ecdsa.VerifyASN1(key, digest, sig) {
+			return nil
+		}
+
+	case ed25519.PublicKey:
+
+		if ed25519.Verify(key, message, sig) {
+			return nil
+		}
+
+	}
+
+	return fmt.Errorf("invalid signature")
+
+}
+
+// sum hashes _message_ with _h_ into _buf_ using a pooled hash state.
+func sum(h crypto.Hash, message []byte, buf *[64]byte) []byte {
+
+	hsh := cryptoutils.AcquireHash(h)
+	defer cryptoutils.ReleaseHash(h, hsh)
+
+	hsh.Write(message)
+
+	return hsh.Sum(buf[:0])
+
+}
@@ -0,0 +1,144 @@
+package godsse
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPAE(t *testing.T) {
+	assert.Equal(t, "DSSEv1 29 http://example.com/HelloWorld 11 hello world",
+		string(PAE("http://example.com/HelloWorld", []byte("hello world"))),
+	)
+}
+
+func TestMultiSignatureThreshold(t *testing.T) {
+
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	_, edKey, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+
+	envelope, err := Sign("application/json", []byte(`{"release":"1.0.0"}`),
+		KeySigner{KeyID: "alice", Signer: rsaKey},
+		KeySigner{KeyID: "bob", Signer: ecKey},
+	)
+	require.NoError(t, err)
+
+	data, err := envelope.Marshal()
+	require.NoError(t, err)
+
+	decoded, err := Unmarshal(data)
+	require.NoError(t, err)
+
+	keys := map[string]crypto.PublicKey{
+		"alice": rsaKey.Public(), "bob": ecKey.Public(), "carol": edKey.Public(),
+	}
+
+	verified, err := decoded.Verify(keys, 2)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"alice", "bob"}, verified)
+
+	_, err = decoded.Verify(keys, 3)
+	assert.Error(t, err)
+
+	require.NoError(t, decoded.AddSignature(KeySigner{KeyID: "carol", Signer: edKey}))
+
+	verified, err = decoded.Verify(keys, 3)
+	require.NoError(t, err)
+	assert.Len(t, verified, 3)
+
+	// A duplicated signature do not count twice
+	decoded.Signatures = append(decoded.Signatures, decoded.Signatures[0])
+	_, err = decoded.Verify(keys, 4)
+	assert.Error(t, err)
+
+	decoded.PayloadType = "text/plain"
+	verified, err = decoded.Verify(keys, 1)
+	assert.Error(t, err)
+	assert.Empty(t, verified)
+
+}
+
+func TestThresholdCountsKeysNotKeyIDs(t *testing.T) {
+
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	envelope, err := Sign("application/json", []byte(`{"release":"1.0.0"}`),
+		KeySigner{KeyID: "alice", Signer: ecKey},
+		KeySigner{KeyID: "alice-2", Signer: ecKey},
+	)
+	require.NoError(t, err)
+
+	// The same key trusted under two key ids is one signer
+	keys := map[string]crypto.PublicKey{"alice": ecKey.Public(), "alice-2": ecKey.Public()}
+
+	verified, err := envelope.Verify(keys, 2)
+	assert.Error(t, err)
+	assert.Equal(t, []string{"alice"}, verified)
+
+	verified, err = envelope.Verify(keys, 1)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"alice"}, verified)
+
+}
+
+func TestEnvelopeWriteToReadFrom(t *testing.T) {
+
+	_, key, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+
+	envelope, err := Sign("text/plain", []byte("hello"), KeySigner{KeyID: "alice", Signer: key})
+	require.NoError(t, err)
+
+	var buf bytes.Buffer
+
+	n, err := envelope.WriteTo(&buf)
+	require.NoError(t, err)
+	assert.Equal(t, int64(buf.Len()), n)
+
+	var decoded Envelope
+
+	_, err = decoded.ReadFrom(&buf)
+	require.NoError(t, err)
+
+	_, err = decoded.Verify(map[string]crypto.PublicKey{"alice": key.Public()}, 1)
+	assert.NoError(t, err)
+
+}
+
+func BenchmarkEnvelopeVerify(b *testing.B) {
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(b, err)
+
+	envelope, err := Sign("application/vnd.in-toto+json", make([]byte, 4096), KeySigner{KeyID: "ci", Signer: key})
+	require.NoError(b, err)
+
+	keys := map[string]crypto.PublicKey{"ci": key.Public()}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+
+		if _, err := envelope.Verify(keys, 1); err != nil {
+			b.Fatal(err)
+		}
+
+	}
+
+}
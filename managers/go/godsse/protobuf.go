@@ -0,0 +1,189 @@
+package godsse
+
+import (
+	"encoding/binary"
+	"fmt"
+)
+
+// Protobuf wire types.
+const (
+	protoVarint  = 0
+	protoFixed64 = 1
+	protoBytes   = 2
+	protoFixed32 = 5
+)
+
+// protobufCodec is the `FormatProtobuf` codec of the _DSSE_ messages, i.e. a _Envelope_ of
+// _payload_ (1, bytes), _payloadType_ (2, string) and _signatures_ (3, repeated _Signature_),
+// where a _Signature_ has _sig_ (1, bytes) and _keyid_ (2, string).
+type protobufCodec struct{}
+
+func (protobufCodec) Format() Format {
+	return FormatProtobuf
+}
+
+func (protobufCodec) Detect(data []byte) bool {
+
+	// The first field is one of the length delimited fields 1 to 3
+	return len(data) > 0 && (data[0] == 1<<3|protoBytes || data[0] == 2<<3|protoBytes || data[0] == 3<<3|protoBytes)
+
+}
+
+func (protobufCodec) Encode(envelope *Envelope) ([]byte, error) {
+
+	buf := make([]byte, 0, len(envelope.Payload)+len(envelope.PayloadType)+64)
+
+	buf = appendProtoBytes(buf, 1, envelope.Payload)
+	buf = appendProtoBytes(buf, 2, []byte(envelope.PayloadType))
+
+	for _, sig := range envelope.Signatures {
+
+		var msg []byte
+
+		msg = appendProtoBytes(msg, 1, sig.Sig)
+		msg = appendProtoBytes(msg, 2, []byte(sig.KeyID))
+
+		buf = appendProtoField(buf, 3, msg)
+
+	}
+
+	return buf, nil
+
+}
+
+func (protobufCodec) Decode(data []byte) (*Envelope, error) {
+
+	envelope := &Envelope{Signatures: []Signature{}}
+
+	err := readProtoFields(data, func(field uint64, value []byte) error {
+
+		switch field {
+		case 1:
+			envelope.Payload = append([]byte{}, value...)
+		case 2:
+			envelope.PayloadType = string(value)
+		case 3:
+
+			var sig Signature
+
+			err := readProtoFields(value, func(field uint64, value []byte) error {
+
+				switch field {
+				case 1:
+					sig.Sig = append([]byte{}, value...)
+				case 2:
+					sig.KeyID = string(value)
+				}
+
+				return nil
+
+			})
+
+			if err != nil {
+				return err
+			}
+
+			envelope.Signatures = append(envelope.Signatures, sig)
+
+		}
+
+		return nil
+
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return envelope, nil
+
+}
+
+// appendProtoBytes appends a length delimited _field_, unless _value_ is empty which is the
+// default value in _proto3_.
+func appendProtoBytes(buf []byte, field uint64, value []byte) []byte {
+
+	if len(value) == 0 {
+		return buf
+	}
+
+	return appendProtoField(buf, field, value)
+
+}
+
+// appendProtoField appends a length delimited _field_.
+func appendProtoField(buf []byte, field uint64, value []byte) []byte {
+
+	buf = appendUvarint(buf, field<<3|protoBytes)
+	buf = appendUvarint(buf, uint64(len(value)))
+
+	return append(buf, value...)
+
+}
+
+// readProtoFields calls _fn_ with each length delimited field of the message in _data_. Other
+// wire types are skipped.
+func readProtoFields(data []byte, fn func(field uint64, value []byte) error) error {
+
+	for len(data) > 0 {
+
+		key, n := binary.Uvarint(data)
+		if n <= 0 {
+			return fmt.Errorf("invalid protobuf field key")
+		}
+
+		data = data[n:]
+
+		switch key & 7 {
+		case protoVarint:
+
+			if _, n = binary.Uvarint(data); n <= 0 {
+				return fmt.Errorf("invalid protobuf varint")
+			}
+
+			data = data[n:]
+
+		case protoFixed64, protoFixed32:
+
+			size := 8
+			if key&7 == protoFixed32 {
+				size = 4
+			}
+
+			if len(data) < size {
+				return fmt.Errorf("unexpected end of protobuf data")
+			}
+
+			data = data[size:]
+
+		case protoBytes:
+
+			length, n := binary.Uvarint(data)
+			if n <= 0 || uint64(len(data)-n) < length {
+				return fmt.Errorf("invalid protobuf length")
+			}
+
+			if err := fn(key>>3, data[n:n+int(length)]); err != nil {
+				return err
+			}
+
+			data = data[n+int(length):]
+
+		default:
+			return fmt.Errorf("unsupported protobuf wire type: %d", key&7)
+		}
+
+	}
+
+	return nil
+
+}
+
+// appendUvarint appends _v_ as a varint.
+func appendUvarint(buf []byte, v uint64) []byte {
+
+	var scratch [binary.MaxVarintLen64]byte
+
+	return append(buf, scratch[:binary.PutUvarint(scratch[:], v)]...)
+
+}
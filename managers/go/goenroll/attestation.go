@@ -0,0 +1,171 @@
+package goenroll
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// AttestationFormatX509Chain is the format of `ChainAttestation`.
+const AttestationFormatX509Chain = "x509-chain"
+
+// ChainAttestation is a attestation by a secure element that holds a factory provisioned
+// identity key and certificate chain issued by the manufacturer.
+//
+// The identity key signs the _SHA-256_ of the challenge followed by the _DER_ encoded public
+// key of the _CSR_, hence proves that the new key was created by the same element.
+type ChainAttestation struct {
+	// Chain is the _DER_ encoded identity certificate chain, leaf first.
+	Chain [][]byte `json:"chain"`
+	// Signature is the identity key signature.
+	Signature []byte `json:"signature"`
+}
+
+// NewChainAttestation creates the _JSON_ encoded `ChainAttestation` on the device, where
+// _identity_ is the identity key and _chain_ its certificate chain.
+func NewChainAttestation(challenge, csr []byte, identity crypto.Signer, chain [][]byte) ([]byte, error) {
+
+	parsed, err := x509.ParseCertificateRequest(csr)
+	if err != nil {
+		return nil, err
+	}
+
+	digest := chainAttestationDigest(challenge, parsed)
+
+	var opts crypto.SignerOpts = crypto.SHA256
+
+	if _, ok := identity.Public().(ed25519.PublicKey); ok {
+		opts = crypto.Hash(0)
+	}
+
+	signature, err := identity.Sign(rand.Reader, digest, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	return json.Marshal(&ChainAttestation{Chain: chain, Signature: signature})
+
+}
+
+// ChainAttestationVerifier verifies `ChainAttestation` statements against the manufacturer
+// root certificates.
+//
+// The device id is the subject serial number of the identity certificate, or the common name
+// if not set.
+type ChainAttestationVerifier struct {
+	roots *x509.CertPool
+	now   func() time.Time
+}
+
+// NewChainAttestationVerifier creates a verifier that trusts identity certificates issued by _roots_.
+func NewChainAttestationVerifier(roots *x509.CertPool) *ChainAttestationVerifier {
+	return &ChainAttestationVerifier{roots: roots, now: time.Now}
+}
+
+// Verify implements the `AttestationVerifier` interface.
+func (v *ChainAttestationVerifier) Verify(
+	c context.Context,
+	req *EnrollmentRequest,
+	csr *x509.CertificateRequest,
+) (*AttestationResult, error) {
+
+	var attestation ChainAttestation
+
+	if err := json.Unmarshal(req.Attestation, &attestation); err != nil {
+		return nil, err
+	}
+
+	if len(attestation.Chain) == 0 {
+		return nil, fmt.Errorf("attestation has no certificates")
+	}
+
+	certs := make([]*x509.Certificate, len(attestation.Chain))
+
+	for i, der := range attestation.Chain {
+
+		cert, err := x509.ParseCertificate(der)
+		if err != nil {
+			return nil, err
+		}
+
+		certs[i] = cert
+
+	}
+
+	intermediates := x509.NewCertPool()
+
+	for _, cert := range certs[1:] {
+		intermediates.AddCert(cert)
+	}
+
+	if _, err := certs[0].Verify(x509.VerifyOptions{
+		Roots:         v.roots,
+		Intermediates: intermediates,
+		CurrentTime:   v.now(),
+		KeyUsages:     []x509.ExtKeyUsage{x509.ExtKeyUsageAny},
+	}); err != nil {
+		return nil, err
+	}
+
+	digest := chainAttestationDigest(req.Challenge, csr)
+
+	if err := checkIdentitySignature(certs[0].PublicKey, digest, attestation.Signature); err != nil {
+		return nil, err
+	}
+
+	deviceID := certs[0].Subject.SerialNumber
+	if deviceID == "" {
+		deviceID = certs[0].Subject.CommonName
+	}
+
+	return &AttestationResult{
+		DeviceID: deviceID,
+		Claims: map[string]string{
+			"manufacturer": certs[len(certs)-1].Issuer.String(),
+		},
+	}, nil
+
+}
+
+// chainAttestationDigest is the _SHA-256_ of the _challenge_ and the public key of _csr_.
+func chainAttestationDigest(challenge []byte, csr *x509.CertificateRequest) []byte {
+
+	h := sha256.New()
+	h.Write(challenge)
+	h.Write(csr.RawSubjectPublicKeyInfo)
+
+	return h.Sum(nil)
+
+}
+
+// checkIdentitySignature verifies the identity key _signature_ of the _digest_.
+func checkIdentitySignature(pub crypto.PublicKey, digest, signature []byte) error {
+
+	valid := false
+
+	switch key := pub.(type) {
+	case *ecdsa.PublicKey:
+		valid = ecdsa.VerifyASN1(key, digest, signature)
+	case *rsa.PublicKey:
+		valid = rsa.VerifyPKCS1v15(key, crypto.SHA256, digest, signature) == nil
+	case ed25519.PublicKey:
+		valid = ed25519.Verify(key, digest, signature)
+	default:
+		return fmt.Errorf("unsupported identity key: %T", pub)
+	}
+
+	if !valid {
+		return fmt.Errorf("invalid identity signature")
+	}
+
+	return nil
+
+}
@@ -0,0 +1,339 @@
+// Package goenroll enrolls devices that hold their key in hardware, e.g. a _TPM_ or a secure
+// element.
+//
+// The device fetches a challenge, generates a key in its hardware, creates a _CSR_ and a
+// attestation that proves that the key is hardware protected and bound to the challenge. The
+// `Enroller` validates the attestation, issues a device certificate and registers the device
+// key in a `DeviceRegistry`. The `Handler` exposes the enroller over _HTTP_.
+package goenroll
+
+import (
+	"context"
+	"crypto"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/mariotoffia/goservice/interfaces/ifcrypto"
+	"github.com/mariotoffia/goservice/managers/go/gocrypto"
+	"github.com/mariotoffia/goservice/utils/cryptoutils"
+)
+
+var (
+	// ErrInvalidChallenge is returned when the challenge is unknown, expired or already used.
+	ErrInvalidChallenge = fmt.Errorf("invalid enrollment challenge")
+	// ErrUnsupportedAttestation is returned when there is no verifier for the attestation format.
+	ErrUnsupportedAttestation = fmt.Errorf("unsupported attestation format")
+	// ErrAttestationFailed is returned when the attestation do not verify.
+	ErrAttestationFailed = fmt.Errorf("attestation failed")
+)
+
+// EnrollmentRequest is sent by a device to enroll.
+//
+// All binary fields are standard base64 encoded in _JSON_.
+type EnrollmentRequest struct {
+	DeviceID string `json:"device_id"`
+	// Challenge is the challenge returned by `Enroller.Challenge`.
+	Challenge []byte `json:"challenge"`
+	// CSR is the _DER_ encoded _PKCS #10_ request, signed by the hardware key.
+	CSR []byte `json:"csr"`
+	// AttestationFormat selects the `AttestationVerifier`, e.g. `AttestationFormatX509Chain`.
+	AttestationFormat string `json:"attestation_format"`
+	// Attestation is the format specific attestation statement.
+	Attestation []byte `json:"attestation"`
+}
+
+// AttestationResult is the outcome of a successful attestation.
+type AttestationResult struct {
+	// DeviceID is the device identity proven by the attestation.
+	DeviceID string `json:"device_id"`
+	// Claims is format specific claims, e.g. the hardware model or firmware version.
+	Claims map[string]string `json:"claims,omitempty"`
+}
+
+// AttestationVerifier verifies a attestation format.
+//
+// It must verify that the key of the _csr_ is hardware protected, that the attestation is
+// bound to the _Challenge_ of the _req_ and return the proven device identity.
+type AttestationVerifier interface {
+	Verify(c context.Context, req *EnrollmentRequest, csr *x509.CertificateRequest) (*AttestationResult, error)
+}
+
+// DeviceRecord is a enrolled device.
+type DeviceRecord struct {
+	DeviceID string
+	// KeyID is the hex encoded _SHA-256_ of the device public key _DER_.
+	KeyID       string
+	PublicKey   crypto.PublicKey
+	Certificate *x509.Certificate
+	Attestation *AttestationResult
+	Enrolled    time.Time
+}
+
+// DeviceRegistry stores enrolled devices, typically backed by the keystore.
+type DeviceRegistry interface {
+	// Register stores the _record_, replacing any previous record of the device.
+	Register(c context.Context, record DeviceRecord) error
+}
+
+// EnrollerOption configures a `Enroller`.
+type EnrollerOption func(e *Enroller)
+
+// WithAttestationVerifier registers the _verifier_ of the attestation _format_.
+func WithAttestationVerifier(format string, verifier AttestationVerifier) EnrollerOption {
+	return func(e *Enroller) {
+		e.verifiers[format] = verifier
+	}
+}
+
+// WithDeviceCertificateValidity sets the validity of issued certificates, default is the
+// `cryptoutils.CertificateProfileIoTDevice` default.
+func WithDeviceCertificateValidity(validity time.Duration) EnrollerOption {
+	return func(e *Enroller) {
+		e.validity = validity
+	}
+}
+
+// WithEnrollmentPolicy sets a issuance policy that each certificate must comply with.
+func WithEnrollmentPolicy(policy *cryptoutils.IssuancePolicy) EnrollerOption {
+	return func(e *Enroller) {
+		e.policy = policy
+	}
+}
+
+// WithEnrollmentPolicyGuard sets a guard that must authorize each certificate before it is issued.
+func WithEnrollmentPolicyGuard(guard *gocrypto.PolicyGuard) EnrollerOption {
+	return func(e *Enroller) {
+		e.guard = guard
+	}
+}
+
+// WithChallenge sets the key that authenticates challenges and how long a challenge is valid,
+// default is a random key and five minutes. All instances behind a load balancer must share
+// the _key_ and the replay cache.
+func WithChallenge(key []byte, ttl time.Duration) EnrollerOption {
+	return func(e *Enroller) {
+		e.challengeKey = key
+		e.challengeTTL = ttl
+	}
+}
+
+// WithEnrollmentReplayCache sets the cache that makes challenges single use, default
+// `gocrypto.NewMemoryReplayCache`.
+func WithEnrollmentReplayCache(cache ifcrypto.ReplayCache) EnrollerOption {
+	return func(e *Enroller) {
+		e.replay = cache
+	}
+}
+
+// WithEnrollmentClock sets the clock, default `time.Now`.
+func WithEnrollmentClock(clock func() time.Time) EnrollerOption {
+	return func(e *Enroller) {
+		e.clock = clock
+	}
+}
+
+// Enroller validates device enrollments and issues device certificates.
+type Enroller struct {
+	caCert       *x509.Certificate
+	caKey        crypto.Signer
+	registry     DeviceRegistry
+	verifiers    map[string]AttestationVerifier
+	validity     time.Duration
+	policy       *cryptoutils.IssuancePolicy
+	guard        *gocrypto.PolicyGuard
+	challengeKey []byte
+	challengeTTL time.Duration
+	replay       ifcrypto.ReplayCache
+	clock        func() time.Time
+}
+
+// NewEnroller creates a new `Enroller` that issues certificates with the _caKey_ of _caCert_
+// and registers the devices in _registry_.
+//
+// At least one `AttestationVerifier` must be registered using `WithAttestationVerifier`.
+func NewEnroller(
+	caCert *x509.Certificate,
+	caKey crypto.Signer,
+	registry DeviceRegistry,
+	opts ...EnrollerOption,
+) (*Enroller, error) {
+
+	e := &Enroller{
+		caCert:       caCert,
+		caKey:        caKey,
+		registry:     registry,
+		verifiers:    map[string]AttestationVerifier{},
+		challengeTTL: 5 * time.Minute,
+		clock:        time.Now,
+	}
+
+	for _, opt := range opts {
+		opt(e)
+	}
+
+	if len(e.verifiers) == 0 {
+		return nil, fmt.Errorf("enroller requires at least one attestation verifier")
+	}
+
+	if e.challengeKey == nil {
+
+		e.challengeKey = make([]byte, 32)
+
+		if _, err := io.ReadFull(rand.Reader, e.challengeKey); err != nil {
+			return nil, err
+		}
+
+	}
+
+	if e.replay == nil {
+		e.replay = gocrypto.NewMemoryReplayCache(gocrypto.WithReplayCacheClock(e.clock))
+	}
+
+	return e, nil
+
+}
+
+// Challenge returns a new single use challenge that the device must bind its attestation to.
+//
+// The challenge is stateless, it holds a random nonce, the expiry and a _HMAC_ over both.
+func (e *Enroller) Challenge(c context.Context) ([]byte, error) {
+
+	challenge := make([]byte, 24, 24+sha256.Size)
+
+	if _, err := io.ReadFull(rand.Reader, challenge[:16]); err != nil {
+		return nil, err
+	}
+
+	binary.BigEndian.PutUint64(challenge[16:], uint64(e.clock().Add(e.challengeTTL).Unix()))
+
+	mac := hmac.New(sha256.New, e.challengeKey)
+	mac.Write(challenge)
+
+	return mac.Sum(challenge), nil
+
+}
+
+// Enroll validates the _req_, issues the device certificate and registers the device.
+func (e *Enroller) Enroll(c context.Context, req *EnrollmentRequest) (*x509.Certificate, error) {
+
+	if err := e.useChallenge(c, req.Challenge); err != nil {
+		return nil, err
+	}
+
+	csr, err := x509.ParseCertificateRequest(req.CSR)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := csr.CheckSignature(); err != nil {
+		return nil, err
+	}
+
+	verifier, ok := e.verifiers[req.AttestationFormat]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrUnsupportedAttestation, req.AttestationFormat)
+	}
+
+	result, err := verifier.Verify(c, req, csr)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrAttestationFailed, err)
+	}
+
+	if result.DeviceID != req.DeviceID {
+		return nil, fmt.Errorf("%w: attested device %s is not %s", ErrAttestationFailed, result.DeviceID, req.DeviceID)
+	}
+
+	tmpl, _, err := cryptoutils.NewCertificateTemplate(
+		cryptoutils.CertificateProfileIoTDevice,
+		cryptoutils.CertificateSubject{
+			Subject:  pkix.Name{CommonName: req.DeviceID, SerialNumber: req.DeviceID},
+			Validity: e.validity,
+		},
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	if e.policy != nil {
+
+		if err := cryptoutils.ValidateIssuance(e.caCert, tmpl, e.policy); err != nil {
+			return nil, err
+		}
+
+	}
+
+	if e.guard != nil {
+
+		if err := e.guard.AuthorizeIssuance(c, e.caCert, tmpl); err != nil {
+			return nil, err
+		}
+
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, e.caCert, csr.PublicKey, e.caKey)
+	if err != nil {
+		return nil, err
+	}
+
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		return nil, err
+	}
+
+	keyID := sha256.Sum256(csr.RawSubjectPublicKeyInfo)
+
+	if err := e.registry.Register(c, DeviceRecord{
+		DeviceID:    req.DeviceID,
+		KeyID:       hex.EncodeToString(keyID[:]),
+		PublicKey:   csr.PublicKey,
+		Certificate: cert,
+		Attestation: result,
+		Enrolled:    e.clock().UTC(),
+	}); err != nil {
+		return nil, err
+	}
+
+	return cert, nil
+
+}
+
+// useChallenge verifies the _challenge_ and marks it as used.
+func (e *Enroller) useChallenge(c context.Context, challenge []byte) error {
+
+	if len(challenge) != 24+sha256.Size {
+		return ErrInvalidChallenge
+	}
+
+	mac := hmac.New(sha256.New, e.challengeKey)
+	mac.Write(challenge[:24])
+
+	if !hmac.Equal(mac.Sum(nil), challenge[24:]) {
+		return ErrInvalidChallenge
+	}
+
+	expires := time.Unix(int64(binary.BigEndian.Uint64(challenge[16:24])), 0)
+
+	if !e.clock().Before(expires) {
+		return fmt.Errorf("%w: expired", ErrInvalidChallenge)
+	}
+
+	replayed, err := e.replay.Use(c, "goenroll", hex.EncodeToString(challenge[:16]), expires)
+	if err != nil {
+		return err
+	}
+
+	if replayed {
+		return fmt.Errorf("%w: already used", ErrInvalidChallenge)
+	}
+
+	return nil
+
+}
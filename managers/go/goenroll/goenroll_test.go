@@ -0,0 +1,268 @@
+package goenroll
+
+import (
+	"bytes"
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testCA(t *testing.T, cn string, parent *x509.Certificate, parentKey crypto.Signer) (*x509.Certificate, *ecdsa.PrivateKey) {
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	tmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(time.Now().UnixNano()),
+		Subject:               pkix.Name{CommonName: cn},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageDigitalSignature,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+
+	if parent == nil {
+		parent, parentKey = tmpl, key
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, parent, &key.PublicKey, parentKey)
+	require.NoError(t, err)
+
+	cert, err := x509.ParseCertificate(der)
+	require.NoError(t, err)
+
+	return cert, key
+
+}
+
+type testDevice struct {
+	id       string
+	identity *ecdsa.PrivateKey
+	chain    [][]byte
+}
+
+func newTestDevice(t *testing.T, id string, mfr *x509.Certificate, mfrKey crypto.Signer) *testDevice {
+
+	identity, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	der, err := x509.CreateCertificate(rand.Reader, &x509.Certificate{
+		SerialNumber: big.NewInt(42),
+		Subject:      pkix.Name{CommonName: "secure element", SerialNumber: id},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+	}, mfr, &identity.PublicKey, mfrKey)
+	require.NoError(t, err)
+
+	return &testDevice{id: id, identity: identity, chain: [][]byte{der}}
+
+}
+
+func (d *testDevice) request(t *testing.T, challenge []byte) *EnrollmentRequest {
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	csr, err := x509.CreateCertificateRequest(rand.Reader, &x509.CertificateRequest{
+		Subject: pkix.Name{CommonName: d.id},
+	}, key)
+	require.NoError(t, err)
+
+	attestation, err := NewChainAttestation(challenge, csr, d.identity, d.chain)
+	require.NoError(t, err)
+
+	return &EnrollmentRequest{
+		DeviceID:          d.id,
+		Challenge:         challenge,
+		CSR:               csr,
+		AttestationFormat: AttestationFormatX509Chain,
+		Attestation:       attestation,
+	}
+
+}
+
+func newTestEnroller(t *testing.T) (*Enroller, *MemoryDeviceRegistry, *x509.Certificate, crypto.Signer) {
+
+	mfr, mfrKey := testCA(t, "manufacturer", nil, nil)
+	ca, caKey := testCA(t, "device ca", nil, nil)
+
+	roots := x509.NewCertPool()
+	roots.AddCert(mfr)
+
+	registry := NewMemoryDeviceRegistry()
+
+	enroller, err := NewEnroller(
+		ca, caKey, registry,
+		WithAttestationVerifier(AttestationFormatX509Chain, NewChainAttestationVerifier(roots)),
+	)
+	require.NoError(t, err)
+
+	return enroller, registry, mfr, mfrKey
+
+}
+
+func TestEnrollIssuesCertificateAndRegistersDevice(t *testing.T) {
+
+	enroller, registry, mfr, mfrKey := newTestEnroller(t)
+	device := newTestDevice(t, "dev-1", mfr, mfrKey)
+
+	challenge, err := enroller.Challenge(context.Background())
+	require.NoError(t, err)
+
+	cert, err := enroller.Enroll(context.Background(), device.request(t, challenge))
+	require.NoError(t, err)
+
+	assert.Equal(t, "dev-1", cert.Subject.CommonName)
+	assert.Contains(t, cert.ExtKeyUsage, x509.ExtKeyUsageClientAuth)
+	require.NoError(t, cert.CheckSignatureFrom(enroller.caCert))
+
+	record, ok := registry.Device("dev-1")
+	require.True(t, ok)
+	assert.Equal(t, cert, record.Certificate)
+	assert.Len(t, record.KeyID, 64)
+	assert.Equal(t, "dev-1", record.Attestation.DeviceID)
+
+}
+
+func TestEnrollRejectsReplayedChallenge(t *testing.T) {
+
+	enroller, _, mfr, mfrKey := newTestEnroller(t)
+	device := newTestDevice(t, "dev-1", mfr, mfrKey)
+
+	challenge, err := enroller.Challenge(context.Background())
+	require.NoError(t, err)
+
+	_, err = enroller.Enroll(context.Background(), device.request(t, challenge))
+	require.NoError(t, err)
+
+	_, err = enroller.Enroll(context.Background(), device.request(t, challenge))
+	assert.True(t, errors.Is(err, ErrInvalidChallenge))
+
+	forged := append([]byte{}, challenge...)
+	forged[0] ^= 1
+
+	_, err = enroller.Enroll(context.Background(), device.request(t, forged))
+	assert.True(t, errors.Is(err, ErrInvalidChallenge))
+
+}
+
+func TestEnrollRejectsExpiredChallenge(t *testing.T) {
+
+	enroller, _, mfr, mfrKey := newTestEnroller(t)
+	device := newTestDevice(t, "dev-1", mfr, mfrKey)
+
+	challenge, err := enroller.Challenge(context.Background())
+	require.NoError(t, err)
+
+	enroller.clock = func() time.Time { return time.Now().Add(time.Hour) }
+
+	_, err = enroller.Enroll(context.Background(), device.request(t, challenge))
+	assert.True(t, errors.Is(err, ErrInvalidChallenge))
+
+}
+
+func TestEnrollRejectsUntrustedOrMismatchingAttestation(t *testing.T) {
+
+	enroller, _, mfr, mfrKey := newTestEnroller(t)
+
+	other, otherKey := testCA(t, "other manufacturer", nil, nil)
+	untrusted := newTestDevice(t, "dev-1", other, otherKey)
+
+	challenge, err := enroller.Challenge(context.Background())
+	require.NoError(t, err)
+
+	_, err = enroller.Enroll(context.Background(), untrusted.request(t, challenge))
+	assert.True(t, errors.Is(err, ErrAttestationFailed))
+
+	device := newTestDevice(t, "dev-1", mfr, mfrKey)
+
+	challenge, err = enroller.Challenge(context.Background())
+	require.NoError(t, err)
+
+	req := device.request(t, challenge)
+	req.DeviceID = "dev-2"
+
+	_, err = enroller.Enroll(context.Background(), req)
+	assert.True(t, errors.Is(err, ErrAttestationFailed))
+
+	// attestation bound to another challenge
+	challenge, err = enroller.Challenge(context.Background())
+	require.NoError(t, err)
+
+	previous, err := enroller.Challenge(context.Background())
+	require.NoError(t, err)
+
+	req = device.request(t, previous)
+	req.Challenge = challenge
+
+	_, err = enroller.Enroll(context.Background(), req)
+	assert.True(t, errors.Is(err, ErrAttestationFailed))
+
+	req.AttestationFormat = "tpm2-quote"
+
+	challenge, err = enroller.Challenge(context.Background())
+	require.NoError(t, err)
+
+	req.Challenge = challenge
+
+	_, err = enroller.Enroll(context.Background(), req)
+	assert.True(t, errors.Is(err, ErrUnsupportedAttestation))
+
+}
+
+func TestHandlerEnrollsDevice(t *testing.T) {
+
+	enroller, _, mfr, mfrKey := newTestEnroller(t)
+	device := newTestDevice(t, "dev-1", mfr, mfrKey)
+
+	server := httptest.NewServer(NewHandler(enroller))
+	defer server.Close()
+
+	resp, err := http.Post(server.URL+"/v1/challenge", "application/json", nil)
+	require.NoError(t, err)
+
+	var challenge ChallengeResponse
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&challenge))
+	resp.Body.Close()
+
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+
+	body, err := json.Marshal(device.request(t, challenge.Challenge))
+	require.NoError(t, err)
+
+	resp, err = http.Post(server.URL+"/v1/enroll", "application/json", bytes.NewReader(body))
+	require.NoError(t, err)
+
+	var enrolled EnrollmentResponse
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&enrolled))
+	resp.Body.Close()
+
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+
+	cert, err := x509.ParseCertificate(enrolled.Certificate)
+	require.NoError(t, err)
+	assert.Equal(t, "dev-1", cert.Subject.CommonName)
+
+	resp, err = http.Post(server.URL+"/v1/enroll", "application/json", bytes.NewReader(body))
+	require.NoError(t, err)
+	resp.Body.Close()
+
+	assert.Equal(t, http.StatusForbidden, resp.StatusCode)
+
+}
@@ -0,0 +1,123 @@
+package goenroll
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// maxRequestSize is the maximum size of a enrollment request body.
+const maxRequestSize = 1024 * 1024
+
+// ChallengeResponse is the response of the challenge endpoint.
+type ChallengeResponse struct {
+	Challenge []byte `json:"challenge,omitempty"`
+	Error     string `json:"error,omitempty"`
+}
+
+// EnrollmentResponse is the response of the enroll endpoint.
+type EnrollmentResponse struct {
+	// Certificate is the _DER_ encoded device certificate.
+	Certificate []byte `json:"certificate,omitempty"`
+	// Chain is the _DER_ encoded issuer certificate.
+	Chain [][]byte `json:"chain,omitempty"`
+	Error string   `json:"error,omitempty"`
+}
+
+// Handler is a `http.Handler` that exposes a `Enroller`.
+//
+// A _POST_ to a path that ends with _/challenge_ responds with a `ChallengeResponse`. A _POST_
+// with a _JSON_ `EnrollmentRequest` to a path that ends with _/enroll_ responds with a
+// `EnrollmentResponse`. Invalid challenges and failed attestations are _403_, malformed
+// requests are _400_ and all other failures are _500_.
+type Handler struct {
+	enroller *Enroller
+}
+
+// NewHandler creates a new `Handler` for the _enroller_.
+func NewHandler(enroller *Enroller) *Handler {
+	return &Handler{enroller: enroller}
+}
+
+// ServeHTTP implements the `http.Handler` interface.
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+
+	if r.Method != http.MethodPost {
+
+		w.Header().Set("Allow", http.MethodPost)
+		writeJSON(w, http.StatusMethodNotAllowed, EnrollmentResponse{Error: "method not allowed"})
+
+		return
+
+	}
+
+	switch {
+	case strings.HasSuffix(r.URL.Path, "/challenge"):
+		h.challenge(w, r)
+	case strings.HasSuffix(r.URL.Path, "/enroll"):
+		h.enroll(w, r)
+	default:
+		writeJSON(w, http.StatusNotFound, EnrollmentResponse{Error: "not found"})
+	}
+
+}
+
+func (h *Handler) challenge(w http.ResponseWriter, r *http.Request) {
+
+	challenge, err := h.enroller.Challenge(r.Context())
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, ChallengeResponse{Error: "failed to create challenge"})
+		return
+	}
+
+	writeJSON(w, http.StatusOK, ChallengeResponse{Challenge: challenge})
+
+}
+
+func (h *Handler) enroll(w http.ResponseWriter, r *http.Request) {
+
+	var req EnrollmentRequest
+
+	if err := json.NewDecoder(io.LimitReader(r.Body, maxRequestSize)).Decode(&req); err != nil {
+		writeJSON(w, http.StatusBadRequest, EnrollmentResponse{Error: "malformed request"})
+		return
+	}
+
+	if req.DeviceID == "" || len(req.CSR) == 0 {
+		writeJSON(w, http.StatusBadRequest, EnrollmentResponse{Error: "device_id and csr are required"})
+		return
+	}
+
+	cert, err := h.enroller.Enroll(r.Context(), &req)
+
+	switch {
+	case err == nil:
+	case errors.Is(err, ErrInvalidChallenge), errors.Is(err, ErrAttestationFailed):
+		writeJSON(w, http.StatusForbidden, EnrollmentResponse{Error: err.Error()})
+		return
+	case errors.Is(err, ErrUnsupportedAttestation):
+		writeJSON(w, http.StatusBadRequest, EnrollmentResponse{Error: err.Error()})
+		return
+	default:
+		writeJSON(w, http.StatusInternalServerError, EnrollmentResponse{Error: "enrollment failed"})
+		return
+	}
+
+	writeJSON(w, http.StatusOK, EnrollmentResponse{
+		Certificate: cert.Raw,
+		Chain:       [][]byte{h.enroller.caCert.Raw},
+	})
+
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(status)
+
+	_ = json.NewEncoder(w).Encode(v)
+
+}
@@ -0,0 +1,41 @@
+package goenroll
+
+import (
+	"context"
+	"sync"
+)
+
+// MemoryDeviceRegistry is a in memory `DeviceRegistry`.
+type MemoryDeviceRegistry struct {
+	mu      sync.RWMutex
+	devices map[string]DeviceRecord
+}
+
+// NewMemoryDeviceRegistry creates a new empty `MemoryDeviceRegistry`.
+func NewMemoryDeviceRegistry() *MemoryDeviceRegistry {
+	return &MemoryDeviceRegistry{devices: map[string]DeviceRecord{}}
+}
+
+// Register implements the `DeviceRegistry` interface.
+func (r *MemoryDeviceRegistry) Register(c context.Context, record DeviceRecord) error {
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.devices[record.DeviceID] = record
+
+	return nil
+
+}
+
+// Device returns the record of _deviceID_ and `true` if enrolled.
+func (r *MemoryDeviceRegistry) Device(deviceID string) (DeviceRecord, bool) {
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	record, ok := r.devices[deviceID]
+
+	return record, ok
+
+}
@@ -0,0 +1,223 @@
+//go:build !verifyonly
+// +build !verifyonly
+
+package goidentity
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/mariotoffia/goservice/interfaces/ifcrypto"
+	"github.com/mariotoffia/goservice/managers/go/gocrypto"
+	"github.com/mariotoffia/goservice/utils/cryptoutils"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testIssuer(t *testing.T, opts ...LocalIssuerOption) *LocalIssuer {
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	tmpl, _, err := cryptoutils.NewCertificateTemplate(cryptoutils.CertificateProfileCA, cryptoutils.CertificateSubject{
+		Subject: pkix.Name{CommonName: "mesh ca"},
+	})
+	require.NoError(t, err)
+
+	ca, err := cryptoutils.CreateSelfSignedCertificate(rand.Reader, tmpl, key)
+	require.NoError(t, err)
+
+	return NewLocalIssuer(ca, key, opts...)
+
+}
+
+func TestBootstrapMutualTLS(t *testing.T) {
+
+	issuer := testIssuer(t)
+
+	server, err := Bootstrap(context.Background(), Config{
+		Service:         "orders",
+		DNSNames:        []string{"localhost"},
+		Issuer:          issuer,
+		DisableRotation: true,
+	})
+	require.NoError(t, err)
+
+	client, err := Bootstrap(context.Background(), Config{
+		Service:         "billing",
+		DNSNames:        []string{"billing"},
+		Issuer:          issuer,
+		DisableRotation: true,
+	})
+	require.NoError(t, err)
+
+	ts := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(r.TLS.PeerCertificates[0].Subject.CommonName))
+	}))
+	ts.TLS = server.ServerTLSConfig()
+	ts.StartTLS()
+	defer ts.Close()
+
+	cfg := client.ClientTLSConfig()
+	cfg.ServerName = "localhost"
+
+	httpClient := &http.Client{Transport: &http.Transport{TLSClientConfig: cfg}}
+
+	resp, err := httpClient.Get(ts.URL)
+	require.NoError(t, err)
+
+	body, err := ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+	require.NoError(t, err)
+
+	assert.Equal(t, "billing", string(body))
+
+	// a client without certificate is rejected
+	anonymous := &http.Client{Transport: &http.Transport{TLSClientConfig: &tls.Config{
+		RootCAs:    server.Roots(),
+		ServerName: "localhost",
+	}}}
+
+	_, err = anonymous.Get(ts.URL)
+	assert.Error(t, err)
+
+}
+
+func TestRotateKeepsPreviousKeyInJWKS(t *testing.T) {
+
+	rotations := 0
+
+	id, err := Bootstrap(context.Background(), Config{
+		Service:         "orders",
+		DNSNames:        []string{"orders"},
+		Issuer:          testIssuer(t),
+		DisableRotation: true,
+		OnRotate:        func(*Identity) { rotations++ },
+	})
+	require.NoError(t, err)
+
+	first := id.Certificate()[0]
+
+	require.NoError(t, id.Rotate(context.Background()))
+	assert.NotEqual(t, first.SerialNumber, id.Certificate()[0].SerialNumber)
+	assert.Equal(t, "orders-2", id.Key().GetID())
+	assert.Equal(t, 2, rotations)
+
+	ts := httptest.NewServer(id.JWKSHandler())
+	defer ts.Close()
+
+	resp, err := http.Get(ts.URL)
+	require.NoError(t, err)
+
+	var set gocrypto.JWKSet
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&set))
+	resp.Body.Close()
+
+	require.Len(t, set.Keys, 2)
+	assert.Equal(t, "EC", set.Keys[0].KeyType)
+	assert.Equal(t, "P-256", set.Keys[0].Curve)
+	assert.Equal(t, thumbprint(id.Certificate()[0]), set.Keys[0].KeyID)
+	assert.Equal(t, thumbprint(first), set.Keys[1].KeyID)
+
+	cert, err := x509.ParseCertificate(set.Keys[1].X5C[0])
+	require.NoError(t, err)
+	assert.Equal(t, first.SerialNumber, cert.SerialNumber)
+
+	// previous keys are dropped once their certificates have expired
+	id.cfg.Clock = func() time.Time { return first.NotAfter.Add(time.Second) }
+
+	require.NoError(t, id.Rotate(context.Background()))
+
+	set2, err := id.JWKS()
+	require.NoError(t, err)
+	assert.Len(t, set2.Keys, 1)
+
+}
+
+func TestBootstrapRotatesInBackground(t *testing.T) {
+
+	c, cancel := context.WithCancel(context.Background())
+
+	rotated := make(chan struct{}, 1)
+
+	id, err := Bootstrap(c, Config{
+		Service:  "orders",
+		DNSNames: []string{"orders"},
+		Issuer:   testIssuer(t, WithIssuerValidity(10*time.Minute)),
+		RenewAt:  0.5,
+		OnRotate: func(*Identity) {
+			select {
+			case rotated <- struct{}{}:
+			default:
+			}
+		},
+	})
+	require.NoError(t, err)
+
+	<-rotated
+
+	select {
+	case <-rotated:
+	case <-time.After(5 * time.Second):
+		t.Fatal("identity was not rotated")
+	}
+
+	cancel()
+	<-id.Done()
+
+}
+
+func TestIdentityStartAndStop(t *testing.T) {
+
+	id, err := Bootstrap(context.Background(), Config{
+		Service:         "orders",
+		DNSNames:        []string{"orders"},
+		Issuer:          testIssuer(t),
+		DisableRotation: true,
+	})
+	require.NoError(t, err)
+
+	require.NoError(t, id.Start(context.Background()))
+	assert.True(t, errors.Is(id.Start(context.Background()), ifcrypto.ErrAlreadyStarted))
+
+	c, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	require.NoError(t, id.Stop(c))
+	<-id.Done()
+
+}
+
+func TestLocalIssuerPolicyGuard(t *testing.T) {
+
+	guard := gocrypto.NewPolicyGuard(gocrypto.PolicyEngineFunc(
+		func(c context.Context, input ifcrypto.PolicyInput) (ifcrypto.PolicyDecision, error) {
+			return ifcrypto.PolicyDecision{Allow: input.Certificate.Subject == "CN=orders"}, nil
+		},
+	))
+
+	issuer := testIssuer(t, WithIssuerPolicyGuard(guard))
+
+	_, err := Bootstrap(context.Background(), Config{
+		Service: "orders", DNSNames: []string{"orders"}, Issuer: issuer, DisableRotation: true,
+	})
+	require.NoError(t, err)
+
+	_, err = Bootstrap(context.Background(), Config{
+		Service: "billing", DNSNames: []string{"billing"}, Issuer: issuer, DisableRotation: true,
+	})
+	assert.True(t, errors.Is(err, ifcrypto.ErrPolicyDenied))
+
+}
@@ -0,0 +1,469 @@
+// Package goidentity bootstraps the crypto identity of a service in a service mesh.
+//
+// A single `Bootstrap` call generates the identity key, acquires a certificate from a
+// `CertificateIssuer`, exposes `tls.Config` for servers and clients, publishes the public keys
+// as a _JWKS_ and rotates the key and certificate before the certificate expires.
+//
+// The _TLS_ configurations resolve the current certificate on each handshake, hence they
+// survive rotation. For _gRPC_ wrap them using `credentials.NewTLS` from
+// _google.golang.org/grpc/credentials_.
+package goidentity
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/mariotoffia/goservice/interfaces/ifcrypto"
+	"github.com/mariotoffia/goservice/managers/go/gocrypto"
+	"github.com/mariotoffia/goservice/utils/cryptoutils"
+)
+
+// Config is the configuration of a service identity.
+type Config struct {
+	// Service is the service name, used as common name and key id prefix.
+	Service string
+	// DNSNames is the _DNS_ subject alternative names.
+	DNSNames []string
+	// IPAddresses is the _IP_ subject alternative names.
+	IPAddresses []net.IP
+	// URIs is the _URI_ subject alternative names, e.g. a _SPIFFE_ id.
+	URIs []*url.URL
+	// Issuer acquires the certificates and the peer roots.
+	Issuer CertificateIssuer
+	// KeyBits is the _ECDSA_ key size, default 256.
+	KeyBits int
+	// RenewAt is the fraction, 0 - 1, of the certificate lifetime when it is renewed, default 2/3.
+	RenewAt float64
+	// RetryInterval is the wait before a failed renewal is retried, default 30 seconds.
+	RetryInterval time.Duration
+	// DisableRotation do not start the rotation, it may be started with `Identity.Start` or
+	// `Identity.Rotate` called manually.
+	DisableRotation bool
+	// OnRotate is invoked after each successful rotation.
+	OnRotate func(id *Identity)
+	// OnError is invoked when a rotation fails.
+	OnError func(err error)
+	// Clock is the clock, default `time.Now`.
+	Clock func() time.Time
+}
+
+// credential is a identity key and its certificate chain.
+type credential struct {
+	key   *gocrypto.ECDSAPrivateKey
+	chain []*x509.Certificate
+	tls   *tls.Certificate
+	kid   string
+}
+
+// Identity is the crypto identity of a service.
+type Identity struct {
+	cfg      Config
+	mu       sync.RWMutex
+	current  *credential
+	previous []*credential
+	roots    *x509.CertPool
+	version  int
+	rotation *gocrypto.Background
+}
+
+// Bootstrap creates the identity key, acquires the certificate and, unless disabled, starts the
+// rotation that runs until _c_ is done.
+func Bootstrap(c context.Context, cfg Config) (*Identity, error) {
+
+	if cfg.Service == "" {
+		return nil, fmt.Errorf("service name is required")
+	}
+
+	if cfg.Issuer == nil {
+		return nil, fmt.Errorf("certificate issuer is required")
+	}
+
+	if cfg.KeyBits == 0 {
+		cfg.KeyBits = 256
+	}
+
+	if cfg.RenewAt <= 0 || cfg.RenewAt >= 1 {
+		cfg.RenewAt = 2.0 / 3.0
+	}
+
+	if cfg.RetryInterval == 0 {
+		cfg.RetryInterval = 30 * time.Second
+	}
+
+	if cfg.Clock == nil {
+		cfg.Clock = time.Now
+	}
+
+	id := &Identity{cfg: cfg}
+	id.rotation = gocrypto.NewBackground("identity rotation", id.run)
+
+	if err := id.Rotate(c); err != nil {
+		return nil, err
+	}
+
+	if !cfg.DisableRotation {
+
+		if err := id.rotation.Start(c); err != nil {
+			return nil, err
+		}
+
+	}
+
+	return id, nil
+
+}
+
+// Start implements the `ifcrypto.Lifecycle` interface, it starts the rotation when created
+// with _DisableRotation_. The rotation runs until `Stop` is called or _c_ is done.
+func (id *Identity) Start(c context.Context) error {
+	return id.rotation.Start(c)
+}
+
+// Stop implements the `ifcrypto.Lifecycle` interface. A rotation in-flight is completed, or
+// canceled when _c_ is done before.
+func (id *Identity) Stop(c context.Context) error {
+	return id.rotation.Stop(c)
+}
+
+// Done is closed when a started rotation has stopped.
+func (id *Identity) Done() <-chan struct{} {
+	return id.rotation.Done()
+}
+
+// Key returns the current identity key.
+func (id *Identity) Key() ifcrypto.KeyPair {
+
+	id.mu.RLock()
+	defer id.mu.RUnlock()
+
+	return id.current.key
+
+}
+
+// Certificate returns the current certificate chain, leaf first.
+func (id *Identity) Certificate() []*x509.Certificate {
+
+	id.mu.RLock()
+	defer id.mu.RUnlock()
+
+	return id.current.chain
+
+}
+
+// Roots returns the roots that peer certificates are verified against.
+func (id *Identity) Roots() *x509.CertPool {
+
+	id.mu.RLock()
+	defer id.mu.RUnlock()
+
+	return id.roots
+
+}
+
+// ServerTLSConfig returns a _mTLS_ server configuration that requires client certificates
+// issued by the roots of the issuer.
+func (id *Identity) ServerTLSConfig() *tls.Config {
+
+	return &tls.Config{
+		MinVersion: tls.VersionTLS12,
+		ClientAuth: tls.RequireAnyClientCert,
+		GetCertificate: func(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+			return id.tlsCertificate(), nil
+		},
+		VerifyPeerCertificate: id.verifyPeer(x509.ExtKeyUsageClientAuth),
+	}
+
+}
+
+// ClientTLSConfig returns a _mTLS_ client configuration that presents the service certificate
+// and verifies servers against the roots of the issuer.
+func (id *Identity) ClientTLSConfig() *tls.Config {
+
+	return &tls.Config{
+		MinVersion: tls.VersionTLS12,
+		GetClientCertificate: func(*tls.CertificateRequestInfo) (*tls.Certificate, error) {
+			return id.tlsCertificate(), nil
+		},
+		// The roots may change on rotation, hence they are verified in VerifyConnection.
+		InsecureSkipVerify: true,
+		VerifyConnection: func(cs tls.ConnectionState) error {
+
+			if len(cs.PeerCertificates) == 0 {
+				return fmt.Errorf("server presented no certificate")
+			}
+
+			if _, err := id.verify(cs.PeerCertificates, x509.ExtKeyUsageServerAuth); err != nil {
+				return err
+			}
+
+			if cs.ServerName != "" {
+				return cs.PeerCertificates[0].VerifyHostname(cs.ServerName)
+			}
+
+			return nil
+
+		},
+	}
+
+}
+
+// JWKS returns the _JWKS_ of the current and not yet expired previous keys.
+func (id *Identity) JWKS() (*gocrypto.JWKSet, error) {
+
+	id.mu.RLock()
+	defer id.mu.RUnlock()
+
+	set := &gocrypto.JWKSet{Keys: []cryptoutils.JSONWebKey{}}
+
+	for _, cred := range append([]*credential{id.current}, id.previous...) {
+
+		jwk, err := newJSONWebKey(cred.kid, cred.chain)
+		if err != nil {
+			return nil, err
+		}
+
+		set.Keys = append(set.Keys, jwk)
+
+	}
+
+	return set, nil
+
+}
+
+// JWKSHandler returns a `http.Handler` that serves `JWKS`, e.g. on _/.well-known/jwks.json_.
+func (id *Identity) JWKSHandler() http.Handler {
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+
+		set, err := id.JWKS()
+		if err != nil {
+			http.Error(w, "failed to create JWKS", http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/jwk-set+json")
+		w.Header().Set("Cache-Control", "max-age=300")
+
+		_ = json.NewEncoder(w).Encode(set)
+
+	})
+
+}
+
+// Rotate generates a new identity key, acquires a certificate for it and makes it current. The
+// previous key is published in the _JWKS_ until its certificate expires.
+func (id *Identity) Rotate(c context.Context) error {
+
+	id.mu.RLock()
+	version := id.version + 1
+	id.mu.RUnlock()
+
+	kid := fmt.Sprintf("%s-%d", id.cfg.Service, version)
+
+	key, err := gocrypto.NewECDSAPrivateKey(kid, id.cfg.KeyBits, ifcrypto.KeyUsageSign)
+	if err != nil {
+		return err
+	}
+
+	der, err := x509.CreateCertificateRequest(rand.Reader, &x509.CertificateRequest{
+		Subject:     pkix.Name{CommonName: id.cfg.Service},
+		DNSNames:    id.cfg.DNSNames,
+		IPAddresses: id.cfg.IPAddresses,
+		URIs:        id.cfg.URIs,
+	}, key)
+	if err != nil {
+		return err
+	}
+
+	csr, err := x509.ParseCertificateRequest(der)
+	if err != nil {
+		return err
+	}
+
+	chain, err := id.cfg.Issuer.Issue(c, csr)
+	if err != nil {
+		return err
+	}
+
+	if len(chain) == 0 {
+		return fmt.Errorf("issuer returned no certificate")
+	}
+
+	roots, err := id.cfg.Issuer.Roots(c)
+	if err != nil {
+		return err
+	}
+
+	cred := &credential{
+		key:   key,
+		chain: chain,
+		tls:   &tls.Certificate{PrivateKey: key, Leaf: chain[0]},
+		kid:   thumbprint(chain[0]),
+	}
+
+	for _, cert := range chain {
+		cred.tls.Certificate = append(cred.tls.Certificate, cert.Raw)
+	}
+
+	now := id.cfg.Clock()
+
+	id.mu.Lock()
+
+	if id.current != nil {
+		id.previous = append([]*credential{id.current}, id.previous...)
+	}
+
+	previous := id.previous[:0]
+
+	for _, p := range id.previous {
+
+		if now.Before(p.chain[0].NotAfter) {
+			previous = append(previous, p)
+		}
+
+	}
+
+	id.previous = previous
+	id.current = cred
+	id.roots = roots
+	id.version = version
+
+	id.mu.Unlock()
+
+	if id.cfg.OnRotate != nil {
+		id.cfg.OnRotate(id)
+	}
+
+	return nil
+
+}
+
+// run rotates the identity, using _work_, at _RenewAt_ of the certificate lifetime until
+// _stop_ is done.
+func (id *Identity) run(stop, work context.Context) {
+
+	for {
+
+		wait := id.renewIn()
+
+		select {
+		case <-stop.Done():
+			return
+		case <-time.After(wait):
+		}
+
+		for {
+
+			err := id.Rotate(work)
+			if err == nil {
+				break
+			}
+
+			if id.cfg.OnError != nil {
+				id.cfg.OnError(err)
+			}
+
+			select {
+			case <-stop.Done():
+				return
+			case <-time.After(id.cfg.RetryInterval):
+			}
+
+		}
+
+	}
+
+}
+
+// renewIn returns the duration until the current certificate should be renewed.
+func (id *Identity) renewIn() time.Duration {
+
+	leaf := id.Certificate()[0]
+	lifetime := leaf.NotAfter.Sub(leaf.NotBefore)
+	renew := leaf.NotBefore.Add(time.Duration(float64(lifetime) * id.cfg.RenewAt))
+
+	if wait := renew.Sub(id.cfg.Clock()); wait > 0 {
+		return wait
+	}
+
+	return 0
+
+}
+
+func (id *Identity) tlsCertificate() *tls.Certificate {
+
+	id.mu.RLock()
+	defer id.mu.RUnlock()
+
+	return id.current.tls
+
+}
+
+// verifyPeer returns a `tls.Config.VerifyPeerCertificate` that verifies against the current roots.
+func (id *Identity) verifyPeer(usage x509.ExtKeyUsage) func([][]byte, [][]*x509.Certificate) error {
+
+	return func(raw [][]byte, _ [][]*x509.Certificate) error {
+
+		certs := make([]*x509.Certificate, len(raw))
+
+		for i, der := range raw {
+
+			cert, err := x509.ParseCertificate(der)
+			if err != nil {
+				return err
+			}
+
+			certs[i] = cert
+
+		}
+
+		if len(certs) == 0 {
+			return fmt.Errorf("peer presented no certificate")
+		}
+
+		_, err := id.verify(certs, usage)
+
+		return err
+
+	}
+
+}
+
+// verify verifies the peer _certs_, leaf first, against the current roots.
+func (id *Identity) verify(certs []*x509.Certificate, usage x509.ExtKeyUsage) ([][]*x509.Certificate, error) {
+
+	intermediates := x509.NewCertPool()
+
+	for _, cert := range certs[1:] {
+		intermediates.AddCert(cert)
+	}
+
+	return certs[0].Verify(x509.VerifyOptions{
+		Roots:         id.Roots(),
+		Intermediates: intermediates,
+		CurrentTime:   id.cfg.Clock(),
+		KeyUsages:     []x509.ExtKeyUsage{usage},
+	})
+
+}
+
+// thumbprint is the hex encoded _SHA-256_ of the certificate public key, truncated to 16 bytes.
+func thumbprint(cert *x509.Certificate) string {
+
+	sum := sha256.Sum256(cert.RawSubjectPublicKeyInfo)
+
+	return hex.EncodeToString(sum[:16])
+
+}
@@ -0,0 +1,130 @@
+package goidentity
+
+import (
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/x509"
+	"time"
+
+	"github.com/mariotoffia/goservice/managers/go/gocrypto"
+	"github.com/mariotoffia/goservice/utils/cryptoutils"
+)
+
+// CertificateIssuer acquires a service certificate for a _CSR_, e.g. from a internal _CA_, a
+// _ACME_ server or a mesh control plane.
+type CertificateIssuer interface {
+	// Issue returns the certificate chain, leaf first, for the _csr_.
+	Issue(c context.Context, csr *x509.CertificateRequest) ([]*x509.Certificate, error)
+	// Roots returns the roots that peer certificates are verified against.
+	Roots(c context.Context) (*x509.CertPool, error)
+}
+
+// LocalIssuer is a `CertificateIssuer` that issues `cryptoutils.CertificateProfileService`
+// certificates using a in process _CA_ key.
+type LocalIssuer struct {
+	caCert   *x509.Certificate
+	caKey    crypto.Signer
+	validity time.Duration
+	policy   *cryptoutils.IssuancePolicy
+	guard    *gocrypto.PolicyGuard
+}
+
+// LocalIssuerOption configures a `LocalIssuer`.
+type LocalIssuerOption func(i *LocalIssuer)
+
+// WithIssuerValidity sets the validity of issued certificates, default is the profile default.
+func WithIssuerValidity(validity time.Duration) LocalIssuerOption {
+	return func(i *LocalIssuer) {
+		i.validity = validity
+	}
+}
+
+// WithIssuerPolicy sets a issuance policy that each certificate must comply with.
+func WithIssuerPolicy(policy *cryptoutils.IssuancePolicy) LocalIssuerOption {
+	return func(i *LocalIssuer) {
+		i.policy = policy
+	}
+}
+
+// WithIssuerPolicyGuard sets a guard that must authorize each certificate before it is issued.
+func WithIssuerPolicyGuard(guard *gocrypto.PolicyGuard) LocalIssuerOption {
+	return func(i *LocalIssuer) {
+		i.guard = guard
+	}
+}
+
+// NewLocalIssuer creates a new `LocalIssuer` that signs with _caKey_ of _caCert_.
+func NewLocalIssuer(caCert *x509.Certificate, caKey crypto.Signer, opts ...LocalIssuerOption) *LocalIssuer {
+
+	i := &LocalIssuer{caCert: caCert, caKey: caKey}
+
+	for _, opt := range opts {
+		opt(i)
+	}
+
+	return i
+
+}
+
+// Issue implements the `CertificateIssuer` interface. The subject and subject alternative names
+// are copied from the _csr_.
+func (i *LocalIssuer) Issue(c context.Context, csr *x509.CertificateRequest) ([]*x509.Certificate, error) {
+
+	if err := csr.CheckSignature(); err != nil {
+		return nil, err
+	}
+
+	tmpl, _, err := cryptoutils.NewCertificateTemplate(
+		cryptoutils.CertificateProfileService,
+		cryptoutils.CertificateSubject{
+			Subject:     csr.Subject,
+			DNSNames:    csr.DNSNames,
+			IPAddresses: csr.IPAddresses,
+			URIs:        csr.URIs,
+			Validity:    i.validity,
+		},
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	if i.policy != nil {
+
+		if err := cryptoutils.ValidateIssuance(i.caCert, tmpl, i.policy); err != nil {
+			return nil, err
+		}
+
+	}
+
+	if i.guard != nil {
+
+		if err := i.guard.AuthorizeIssuance(c, i.caCert, tmpl); err != nil {
+			return nil, err
+		}
+
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, i.caCert, csr.PublicKey, i.caKey)
+	if err != nil {
+		return nil, err
+	}
+
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		return nil, err
+	}
+
+	return []*x509.Certificate{cert, i.caCert}, nil
+
+}
+
+// Roots implements the `CertificateIssuer` interface and returns the _CA_ certificate.
+func (i *LocalIssuer) Roots(c context.Context) (*x509.CertPool, error) {
+
+	pool := x509.NewCertPool()
+	pool.AddCert(i.caCert)
+
+	return pool, nil
+
+}
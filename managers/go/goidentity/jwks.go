@@ -0,0 +1,33 @@
+package goidentity
+
+import (
+	"crypto/x509"
+
+	"github.com/mariotoffia/goservice/utils/cryptoutils"
+)
+
+// newJSONWebKey creates the _JWK_ of the leaf public key in _chain_ with _kid_ and the _chain_
+// as _x5c_.
+func newJSONWebKey(kid string, chain []*x509.Certificate) (cryptoutils.JSONWebKey, error) {
+
+	jwk, err := cryptoutils.PublicKeyToJWK(chain[0].PublicKey)
+	if err != nil {
+		return cryptoutils.JSONWebKey{}, err
+	}
+
+	jwk.KeyID = kid
+	jwk.Use = "sig"
+
+	if jwk.KeyType == "RSA" {
+		jwk.Algorithm = "RS256"
+	} else {
+		jwk.Algorithm = cryptoutils.JWKCurveAlgorithm(jwk.Curve)
+	}
+
+	for _, cert := range chain {
+		jwk.X5C = append(jwk.X5C, cert.Raw)
+	}
+
+	return *jwk, nil
+
+}
@@ -0,0 +1,53 @@
+package gointoto
+
+import (
+	"crypto"
+	"encoding/json"
+	"fmt"
+
+	"github.com/mariotoffia/goservice/managers/go/godsse"
+)
+
+// Envelope is the _DSSE_ envelope carrying a signed `Statement`.
+type Envelope = godsse.Envelope
+
+// SignStatement marshals the _statement_ and signs it into a _DSSE_ envelope with _signer_
+// under _keyID_. Use `godsse.Envelope.AddSignature` to add co-signers.
+func SignStatement(statement *Statement, keyID string, signer crypto.Signer) (*Envelope, error) {
+
+	payload, err := json.Marshal(statement)
+	if err != nil {
+		return nil, err
+	}
+
+	return godsse.Sign(PayloadType, payload, godsse.KeySigner{KeyID: keyID, Signer: signer})
+
+}
+
+// VerifyStatement verifies the _envelope_ and returns the `Statement`.
+//
+// The _keys_ maps key ids to trusted public keys. At least one signature must be made by
+// a trusted key.
+func VerifyStatement(envelope *Envelope, keys map[string]crypto.PublicKey) (*Statement, error) {
+
+	if envelope.PayloadType != PayloadType {
+		return nil, fmt.Errorf("unexpected payload type: %s", envelope.PayloadType)
+	}
+
+	if _, err := envelope.Verify(keys, 1); err != nil {
+		return nil, err
+	}
+
+	var statement Statement
+
+	if err := json.Unmarshal(envelope.Payload, &statement); err != nil {
+		return nil, err
+	}
+
+	if statement.Type != StatementType {
+		return nil, fmt.Errorf("unsupported statement type: %s", statement.Type)
+	}
+
+	return &statement, nil
+
+}
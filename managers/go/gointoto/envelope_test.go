@@ -0,0 +1,74 @@
+package gointoto
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSignAndVerifyProvenance(t *testing.T) {
+
+	ecKey, err := ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
+	require.NoError(t, err)
+
+	_, edKey, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+
+	subject, err := NewSubject("app.tar.gz", strings.NewReader("artifact"))
+	require.NoError(t, err)
+
+	started := time.Date(2021, 5, 1, 10, 0, 0, 0, time.UTC)
+
+	statement, err := NewStatement(SLSAProvenancePredicateType, &Provenance{
+		BuildDefinition: BuildDefinition{
+			BuildType:          "https://example.com/build/v1",
+			ExternalParameters: map[string]interface{}{"ref": "refs/heads/main"},
+		},
+		RunDetails: RunDetails{
+			Builder:  Builder{ID: "https://ci.example.com"},
+			Metadata: &BuildMetadata{InvocationID: "42", StartedOn: &started},
+		},
+	}, subject)
+	require.NoError(t, err)
+
+	for _, key := range []crypto.Signer{ecKey, edKey} {
+
+		envelope, err := SignStatement(statement, "ci", key)
+		require.NoError(t, err)
+
+		data, err := json.Marshal(envelope)
+		require.NoError(t, err)
+
+		var decoded Envelope
+		require.NoError(t, json.Unmarshal(data, &decoded))
+
+		verified, err := VerifyStatement(&decoded, map[string]crypto.PublicKey{"ci": key.Public()})
+		require.NoError(t, err)
+
+		var provenance Provenance
+		require.NoError(t, verified.DecodePredicate(&provenance))
+
+		assert.Equal(t, "https://ci.example.com", provenance.RunDetails.Builder.ID)
+		assert.Equal(t, subject.Digest["sha256"], verified.Subject[0].Digest["sha256"])
+
+		decoded.Payload = append([]byte{}, decoded.Payload...)
+		decoded.Payload[len(decoded.Payload)-2] ^= 1
+
+		_, err = VerifyStatement(&decoded, map[string]crypto.PublicKey{"ci": key.Public()})
+		assert.Error(t, err)
+
+		_, err = VerifyStatement(envelope, map[string]crypto.PublicKey{"other": key.Public()})
+		assert.Error(t, err)
+
+	}
+
+}
@@ -0,0 +1,109 @@
+package gointoto
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"time"
+)
+
+const (
+	// StatementType is the _in-toto_ statement type, _v1_.
+	StatementType = "https://in-toto.io/Statement/v1"
+	// PayloadType is the _DSSE_ payload type of a _in-toto_ statement.
+	PayloadType = "application/vnd.in-toto+json"
+	// SLSAProvenancePredicateType is the predicate type of `Provenance`.
+	SLSAProvenancePredicateType = "https://slsa.dev/provenance/v1"
+)
+
+// Subject is a artifact that a `Statement` is about.
+type Subject struct {
+	// Name is the name of the artifact, e.g. a file name or image reference.
+	Name string `json:"name"`
+	// Digest maps a algorithm, e.g. _sha256_, to the hex encoded digest of the artifact.
+	Digest map[string]string `json:"digest"`
+}
+
+// NewSubject creates a `Subject` named _name_ with the _sha256_ digest of the content of _r_.
+func NewSubject(name string, r io.Reader) (Subject, error) {
+
+	h := sha256.New()
+
+	if _, err := io.Copy(h, r); err != nil {
+		return Subject{}, err
+	}
+
+	return Subject{Name: name, Digest: map[string]string{"sha256": hex.EncodeToString(h.Sum(nil))}}, nil
+
+}
+
+// Statement is a _in-toto_ attestation statement.
+type Statement struct {
+	Type          string          `json:"_type"`
+	Subject       []Subject       `json:"subject"`
+	PredicateType string          `json:"predicateType"`
+	Predicate     json.RawMessage `json:"predicate,omitempty"`
+}
+
+// NewStatement creates a `Statement` about the _subjects_ with the _predicate_ marshalled as _JSON_.
+func NewStatement(predicateType string, predicate interface{}, subjects ...Subject) (*Statement, error) {
+
+	raw, err := json.Marshal(predicate)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Statement{
+		Type:          StatementType,
+		Subject:       subjects,
+		PredicateType: predicateType,
+		Predicate:     raw,
+	}, nil
+
+}
+
+// DecodePredicate unmarshals the _Predicate_ into _v_.
+func (s *Statement) DecodePredicate(v interface{}) error {
+	return json.Unmarshal(s.Predicate, v)
+}
+
+// Provenance is the _SLSA_ provenance _v1_ predicate.
+type Provenance struct {
+	BuildDefinition BuildDefinition `json:"buildDefinition"`
+	RunDetails      RunDetails      `json:"runDetails"`
+}
+
+// BuildDefinition describes the inputs of a build.
+type BuildDefinition struct {
+	BuildType            string                 `json:"buildType"`
+	ExternalParameters   map[string]interface{} `json:"externalParameters"`
+	InternalParameters   map[string]interface{} `json:"internalParameters,omitempty"`
+	ResolvedDependencies []ResourceDescriptor   `json:"resolvedDependencies,omitempty"`
+}
+
+// ResourceDescriptor describes a build input or output.
+type ResourceDescriptor struct {
+	URI    string            `json:"uri,omitempty"`
+	Digest map[string]string `json:"digest,omitempty"`
+	Name   string            `json:"name,omitempty"`
+}
+
+// RunDetails describes the build execution.
+type RunDetails struct {
+	Builder    Builder              `json:"builder"`
+	Metadata   *BuildMetadata       `json:"metadata,omitempty"`
+	Byproducts []ResourceDescriptor `json:"byproducts,omitempty"`
+}
+
+// Builder identifies the build platform.
+type Builder struct {
+	ID string `json:"id"`
+}
+
+// BuildMetadata is the metadata of a single build invocation.
+type BuildMetadata struct {
+	InvocationID string     `json:"invocationId,omitempty"`
+	StartedOn    *time.Time `json:"startedOn,omitempty"`
+	FinishedOn   *time.Time `json:"finishedOn,omitempty"`
+}
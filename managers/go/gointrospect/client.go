@@ -0,0 +1,386 @@
+package gointrospect
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// maxResponseSize is the maximum size of a introspection response the client accepts.
+const maxResponseSize = 1024 * 1024
+
+// Introspection is the _RFC 7662_ section 2.2 introspection response.
+type Introspection struct {
+	Active    bool   `json:"active"`
+	Scope     string `json:"scope,omitempty"`
+	ClientID  string `json:"client_id,omitempty"`
+	Username  string `json:"username,omitempty"`
+	TokenType string `json:"token_type,omitempty"`
+	Expiry    int64  `json:"exp,omitempty"`
+	IssuedAt  int64  `json:"iat,omitempty"`
+	NotBefore int64  `json:"nbf,omitempty"`
+	Subject   string `json:"sub,omitempty"`
+	// Audience is either a single string or an array of strings.
+	Audience json.RawMessage `json:"aud,omitempty"`
+	Issuer   string          `json:"iss,omitempty"`
+	JwtID    string          `json:"jti,omitempty"`
+}
+
+// clone returns a deep copy of _in_.
+func (in *Introspection) clone() *Introspection {
+
+	out := *in
+	out.Audience = append(json.RawMessage(nil), in.Audience...)
+
+	return &out
+
+}
+
+// Scopes returns the space separated _Scope_ as a slice.
+func (in *Introspection) Scopes() []string {
+	return strings.Fields(in.Scope)
+}
+
+// Audiences returns the _Audience_ regardless if it was a single string or an array.
+func (in *Introspection) Audiences() []string {
+
+	if len(in.Audience) == 0 {
+		return nil
+	}
+
+	var single string
+	if err := json.Unmarshal(in.Audience, &single); err == nil {
+		return []string{single}
+	}
+
+	var multiple []string
+	_ = json.Unmarshal(in.Audience, &multiple)
+
+	return multiple
+
+}
+
+// ClientOption configures a `Client`.
+type ClientOption func(c *Client)
+
+// WithClientCredentials authenticates the client using _HTTP_ basic authentication.
+func WithClientCredentials(clientID, clientSecret string) ClientOption {
+	return func(c *Client) {
+		c.clientID = clientID
+		c.clientSecret = clientSecret
+	}
+}
+
+// WithHTTPClient sets the `http.Client` used to call the introspection endpoint.
+func WithHTTPClient(client *http.Client) ClientOption {
+	return func(c *Client) {
+		if client != nil {
+			c.client = client
+		}
+	}
+}
+
+// WithCacheTTL sets the maximum time an active response is cached (default 1m). An active
+// response is never cached beyond the token expiry. Zero disables caching.
+func WithCacheTTL(ttl time.Duration) ClientOption {
+	return func(c *Client) {
+		c.activeTTL = ttl
+	}
+}
+
+// WithNegativeCacheTTL sets the time an inactive response is cached (default 10s).
+func WithNegativeCacheTTL(ttl time.Duration) ClientOption {
+	return func(c *Client) {
+		c.inactiveTTL = ttl
+	}
+}
+
+// WithMaxCacheEntries limits the number of cached responses (default 10 000).
+func WithMaxCacheEntries(max int) ClientOption {
+	return func(c *Client) {
+		if max > 0 {
+			c.maxEntries = max
+		}
+	}
+}
+
+// WithRequestTimeout bounds the request to the introspection endpoint (default 10s). Since
+// the request is shared by concurrent callers, it is not bounded by their contexts.
+func WithRequestTimeout(timeout time.Duration) ClientOption {
+	return func(c *Client) {
+		if timeout > 0 {
+			c.timeout = timeout
+		}
+	}
+}
+
+// WithClock sets the clock used for cache expiry.
+func WithClock(now func() time.Time) ClientOption {
+	return func(c *Client) {
+		c.now = now
+	}
+}
+
+// cacheEntry is a cached introspection response.
+type cacheEntry struct {
+	result    *Introspection
+	expiresAt time.Time
+}
+
+// call is a in-flight introspection request that concurrent callers wait for.
+type call struct {
+	done   chan struct{}
+	result *Introspection
+	err    error
+}
+
+// Client is a _RFC 7662_ token introspection client with response caching.
+//
+// Concurrent introspection of the same token is collapsed into a single request to the
+// authorization server, so a burst of requests carrying a new token do not cause a
+// stampede. The cache is keyed on a _SHA-256_ of the token, the token itself is not kept.
+type Client struct {
+	endpoint     string
+	clientID     string
+	clientSecret string
+	client       *http.Client
+	activeTTL    time.Duration
+	inactiveTTL  time.Duration
+	maxEntries   int
+	timeout      time.Duration
+	now          func() time.Time
+	mu           sync.Mutex
+	cache        map[[sha256.Size]byte]cacheEntry
+	inflight     map[[sha256.Size]byte]*call
+}
+
+// NewClient creates a new `Client` for the introspection _endpoint_.
+func NewClient(endpoint string, opts ...ClientOption) *Client {
+
+	c := &Client{
+		endpoint:    endpoint,
+		client:      http.DefaultClient,
+		activeTTL:   time.Minute,
+		inactiveTTL: 10 * time.Second,
+		maxEntries:  10000,
+		timeout:     10 * time.Second,
+		now:         time.Now,
+		cache:       map[[sha256.Size]byte]cacheEntry{},
+		inflight:    map[[sha256.Size]byte]*call{},
+	}
+
+	for _, opt := range opts {
+		opt(c)
+	}
+
+	return c
+
+}
+
+// Introspect returns the introspection response for _token_, from cache if possible.
+//
+// An inactive token is not an error, check _Active_ of the returned response. The
+// _tokenTypeHint_ is optional, e.g. _access_token_.
+//
+// Concurrent callers share a single request, that is not canceled by any of the callers but
+// bounded by the request timeout, see `WithRequestTimeout`. Each caller waits until its own
+// _ctx_ is done. The returned response is a copy owned by the caller.
+func (c *Client) Introspect(ctx context.Context, token, tokenTypeHint string) (*Introspection, error) {
+
+	key := sha256.Sum256([]byte(tokenTypeHint + "\x00" + token))
+
+	c.mu.Lock()
+
+	if entry, ok := c.cache[key]; ok {
+
+		if c.now().Before(entry.expiresAt) {
+			c.mu.Unlock()
+			return entry.result.clone(), nil
+		}
+
+		delete(c.cache, key)
+
+	}
+
+	current, ok := c.inflight[key]
+
+	if !ok {
+
+		current = &call{done: make(chan struct{})}
+		c.inflight[key] = current
+
+		go c.run(ctx, key, current, token, tokenTypeHint)
+
+	}
+
+	c.mu.Unlock()
+
+	select {
+	case <-current.done:
+
+		if current.err != nil {
+			return nil, current.err
+		}
+
+		return current.result.clone(), nil
+
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+
+}
+
+// run performs the shared request of _current_ with the values, but not the cancellation, of
+// _ctx_ and caches the response.
+func (c *Client) run(ctx context.Context, key [sha256.Size]byte, current *call, token, tokenTypeHint string) {
+
+	rc, cancel := context.WithTimeout(detachedContext{ctx}, c.timeout)
+	defer cancel()
+
+	current.result, current.err = c.request(rc, token, tokenTypeHint)
+
+	c.mu.Lock()
+
+	delete(c.inflight, key)
+
+	if current.err == nil {
+		c.store(key, current.result)
+	}
+
+	c.mu.Unlock()
+
+	close(current.done)
+
+}
+
+// Invalidate removes any cached response for _token_, e.g. after it has been revoked.
+func (c *Client) Invalidate(token, tokenTypeHint string) {
+
+	key := sha256.Sum256([]byte(tokenTypeHint + "\x00" + token))
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	delete(c.cache, key)
+
+}
+
+// store caches the _result_, must be called with the lock held.
+func (c *Client) store(key [sha256.Size]byte, result *Introspection) {
+
+	now := c.now()
+
+	ttl := c.inactiveTTL
+	if result.Active {
+		ttl = c.activeTTL
+	}
+
+	expiresAt := now.Add(ttl)
+
+	if result.Active && result.Expiry != 0 {
+
+		if exp := time.Unix(result.Expiry, 0); exp.Before(expiresAt) {
+			expiresAt = exp
+		}
+
+	}
+
+	if !now.Before(expiresAt) {
+		return
+	}
+
+	if len(c.cache) >= c.maxEntries {
+
+		for k, entry := range c.cache {
+
+			if !now.Before(entry.expiresAt) {
+				delete(c.cache, k)
+			}
+
+		}
+
+		if len(c.cache) >= c.maxEntries {
+			return
+		}
+
+	}
+
+	c.cache[key] = cacheEntry{result: result, expiresAt: expiresAt}
+
+}
+
+// request calls the introspection endpoint.
+func (c *Client) request(ctx context.Context, token, tokenTypeHint string) (*Introspection, error) {
+
+	form := url.Values{"token": {token}}
+
+	if tokenTypeHint != "" {
+		form.Set("token_type_hint", tokenTypeHint)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.endpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, err
+	}
+
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+
+	if c.clientID != "" {
+		req.SetBasicAuth(url.QueryEscape(c.clientID), url.QueryEscape(c.clientSecret))
+	}
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("token introspection: %s", resp.Status)
+	}
+
+	data, err := ioutil.ReadAll(io.LimitReader(resp.Body, maxResponseSize))
+	if err != nil {
+		return nil, err
+	}
+
+	var result Introspection
+
+	if err := json.Unmarshal(data, &result); err != nil {
+		return nil, err
+	}
+
+	return &result, nil
+
+}
+
+// detachedContext has the values, but not the cancellation, of the parent context.
+type detachedContext struct {
+	parent context.Context
+}
+
+func (d detachedContext) Deadline() (time.Time, bool) {
+	return time.Time{}, false
+}
+
+func (d detachedContext) Done() <-chan struct{} {
+	return nil
+}
+
+func (d detachedContext) Err() error {
+	return nil
+}
+
+func (d detachedContext) Value(key interface{}) interface{} {
+	return d.parent.Value(key)
+}
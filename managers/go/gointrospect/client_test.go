@@ -0,0 +1,135 @@
+package gointrospect
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIntrospectCachesAndCollapsesConcurrentRequests(t *testing.T) {
+
+	var calls int32
+	release := make(chan struct{})
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+
+		atomic.AddInt32(&calls, 1)
+		<-release
+
+		id, secret, _ := r.BasicAuth()
+		assert.Equal(t, "svc", id)
+		assert.Equal(t, "secret", secret)
+
+		assert.NoError(t, r.ParseForm())
+
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{
+			"active": r.PostForm.Get("token") == "good",
+			"scope":  "read write",
+			"aud":    "api",
+			"exp":    time.Now().Add(time.Hour).Unix(),
+		})
+
+	}))
+
+	defer server.Close()
+
+	client := NewClient(server.URL, WithClientCredentials("svc", "secret"))
+
+	var wg sync.WaitGroup
+
+	for i := 0; i < 10; i++ {
+
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			result, err := client.Introspect(context.Background(), "good", "access_token")
+			if assert.NoError(t, err) {
+				assert.True(t, result.Active)
+			}
+		}()
+
+	}
+
+	time.Sleep(50 * time.Millisecond)
+	close(release)
+	wg.Wait()
+
+	assert.Equal(t, int32(1), atomic.LoadInt32(&calls))
+
+	result, err := client.Introspect(context.Background(), "good", "access_token")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"read", "write"}, result.Scopes())
+	assert.Equal(t, []string{"api"}, result.Audiences())
+	assert.Equal(t, int32(1), atomic.LoadInt32(&calls), "served from cache")
+
+	client.Invalidate("good", "access_token")
+
+	result, err = client.Introspect(context.Background(), "bad", "")
+	require.NoError(t, err)
+	assert.False(t, result.Active)
+	assert.Equal(t, int32(2), atomic.LoadInt32(&calls))
+
+}
+
+func TestIntrospectSurvivesLeaderCancellation(t *testing.T) {
+
+	release := make(chan struct{})
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+
+		<-release
+
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{"active": true, "scope": "read"})
+
+	}))
+
+	defer server.Close()
+
+	client := NewClient(server.URL)
+
+	leader, cancel := context.WithCancel(context.Background())
+	leaderErr := make(chan error, 1)
+
+	go func() {
+		_, err := client.Introspect(leader, "good", "")
+		leaderErr <- err
+	}()
+
+	time.Sleep(20 * time.Millisecond)
+
+	waiter := make(chan *Introspection, 1)
+
+	go func() {
+		result, err := client.Introspect(context.Background(), "good", "")
+		assert.NoError(t, err)
+		waiter <- result
+	}()
+
+	time.Sleep(20 * time.Millisecond)
+	cancel()
+
+	assert.Equal(t, context.Canceled, <-leaderErr)
+
+	close(release)
+
+	result := <-waiter
+	require.NotNil(t, result)
+	assert.True(t, result.Active)
+
+	result.Scope = "admin"
+
+	cached, err := client.Introspect(context.Background(), "good", "")
+	require.NoError(t, err)
+	assert.Equal(t, "read", cached.Scope, "callers get a copy")
+
+}
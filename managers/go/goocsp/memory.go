@@ -0,0 +1,62 @@
+package goocsp
+
+import (
+	"context"
+	"math/big"
+	"sync"
+	"time"
+
+	"golang.org/x/crypto/ocsp"
+)
+
+// MemoryStatusSource is a in memory `StatusSource` that keeps a record of issued and
+// revoked certificates.
+type MemoryStatusSource struct {
+	mu      sync.RWMutex
+	records map[string]CertificateStatus
+}
+
+// NewMemoryStatusSource creates a new, empty, `MemoryStatusSource`.
+func NewMemoryStatusSource() *MemoryStatusSource {
+	return &MemoryStatusSource{records: map[string]CertificateStatus{}}
+}
+
+// Issued records that the certificate with _serial_ has been issued and is good.
+func (s *MemoryStatusSource) Issued(serial *big.Int) {
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.records[serial.String()] = CertificateStatus{Status: ocsp.Good}
+
+}
+
+// Revoke marks the certificate with _serial_ as revoked at _at_ with the _reason_.
+//
+// It is possible to revoke a certificate that has not been recorded as issued.
+func (s *MemoryStatusSource) Revoke(serial *big.Int, at time.Time, reason int) {
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.records[serial.String()] = CertificateStatus{
+		Status:           ocsp.Revoked,
+		RevokedAt:        at.UTC(),
+		RevocationReason: reason,
+	}
+
+}
+
+// Status implements the `StatusSource` interface.
+func (s *MemoryStatusSource) Status(c context.Context, serial *big.Int) (CertificateStatus, error) {
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if status, ok := s.records[serial.String()]; ok {
+		return status, nil
+	}
+
+	return CertificateStatus{Status: ocsp.Unknown}, nil
+
+}
@@ -0,0 +1,266 @@
+package goocsp
+
+import (
+	"bytes"
+	"context"
+	"crypto"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math/big"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"golang.org/x/crypto/ocsp"
+)
+
+// maxRequestSize is the maximum size of a _OCSP_ request body.
+const maxRequestSize = 16 * 1024
+
+// CertificateStatus is the revocation status of a single certificate.
+type CertificateStatus struct {
+	// Status is one of `ocsp.Good`, `ocsp.Revoked` or `ocsp.Unknown`.
+	Status int
+	// RevokedAt is when the certificate was revoked, only set when _Status_ is `ocsp.Revoked`.
+	RevokedAt time.Time
+	// RevocationReason is one of the `ocsp` revocation reason codes, e.g. `ocsp.KeyCompromise`.
+	RevocationReason int
+}
+
+// StatusSource resolves the revocation status of certificates issued by a _CA_.
+//
+// This is typically backed by the issued certificate records of a _CA_. A certificate
+// that the source do not know about must be reported as `ocsp.Unknown`.
+type StatusSource interface {
+	// Status returns the status of the certificate with _serial_.
+	Status(c context.Context, serial *big.Int) (CertificateStatus, error)
+}
+
+// ResponderOption configures a `Responder`.
+type ResponderOption func(r *Responder)
+
+// WithResponseValidity sets how long a response is valid, i.e. the _nextUpdate_ (default 1h).
+func WithResponseValidity(validity time.Duration) ResponderOption {
+	return func(r *Responder) {
+		if validity > 0 {
+			r.validity = validity
+		}
+	}
+}
+
+// WithClock sets the clock used for _thisUpdate_ and _nextUpdate_.
+func WithClock(now func() time.Time) ResponderOption {
+	return func(r *Responder) {
+		r.now = now
+	}
+}
+
+// Responder is a minimal _RFC 6960_ _OCSP_ responder for a single issuing _CA_.
+//
+// It answers requests for certificates issued by _issuer_ using the status from a
+// `StatusSource`. Requests for other issuers are answered with _unauthorized_.
+//
+// The `Responder` implements `http.Handler` and supports both _GET_ and _POST_ requests
+// as of _RFC 6960_ appendix A.
+type Responder struct {
+	issuer        *x509.Certificate
+	responderCert *x509.Certificate
+	signer        crypto.Signer
+	source        StatusSource
+	validity      time.Duration
+	now           func() time.Time
+	issuerKeyHash map[crypto.Hash][]byte
+	issuerName    map[crypto.Hash][]byte
+}
+
+// NewResponder creates a new `Responder`.
+//
+// The _issuer_ is the _CA_ certificate whose issued certificates are answered for. The responses
+// are signed with _signer_. If the _CA_ signs the responses itself, _responderCert_ is the same
+// as _issuer_, otherwise it is a delegated _OCSP_ signing certificate issued by the _CA_.
+func NewResponder(
+	issuer, responderCert *x509.Certificate,
+	signer crypto.Signer,
+	source StatusSource,
+	opts ...ResponderOption,
+) (*Responder, error) {
+
+	var spki struct {
+		Algorithm pkix.AlgorithmIdentifier
+		PublicKey asn1.BitString
+	}
+
+	if _, err := asn1.Unmarshal(issuer.RawSubjectPublicKeyInfo, &spki); err != nil {
+		return nil, err
+	}
+
+	r := &Responder{
+		issuer:        issuer,
+		responderCert: responderCert,
+		signer:        signer,
+		source:        source,
+		validity:      time.Hour,
+		now:           time.Now,
+		issuerKeyHash: map[crypto.Hash][]byte{},
+		issuerName:    map[crypto.Hash][]byte{},
+	}
+
+	for _, h := range []crypto.Hash{crypto.SHA1, crypto.SHA256, crypto.SHA384, crypto.SHA512} {
+
+		hsh := h.New()
+		hsh.Write(spki.PublicKey.RightAlign())
+		r.issuerKeyHash[h] = hsh.Sum(nil)
+
+		hsh.Reset()
+		hsh.Write(issuer.RawSubject)
+		r.issuerName[h] = hsh.Sum(nil)
+
+	}
+
+	for _, opt := range opts {
+		opt(r)
+	}
+
+	return r, nil
+
+}
+
+// Respond parses the _DER_ encoded _request_ and returns a _DER_ encoded response.
+//
+// Protocol level failures, such as malformed requests or unknown issuers, are returned
+// as _OCSP_ error responses. An error is only returned if the response could not be produced
+// at all, in such case a `ocsp.InternalErrorErrorResponse` may be sent to the client.
+func (r *Responder) Respond(c context.Context, request []byte) ([]byte, error) {
+
+	req, err := ocsp.ParseRequest(request)
+	if err != nil {
+		return ocsp.MalformedRequestErrorResponse, nil
+	}
+
+	if !r.isIssuer(req) {
+		return ocsp.UnauthorizedErrorResponse, nil
+	}
+
+	status, err := r.source.Status(c, req.SerialNumber)
+	if err != nil {
+		return nil, err
+	}
+
+	now := r.now().UTC().Truncate(time.Minute)
+
+	return ocsp.CreateResponse(r.issuer, r.responderCert, ocsp.Response{
+		Status:           status.Status,
+		SerialNumber:     req.SerialNumber,
+		ThisUpdate:       now,
+		NextUpdate:       now.Add(r.validity),
+		RevokedAt:        status.RevokedAt,
+		RevocationReason: status.RevocationReason,
+		IssuerHash:       req.HashAlgorithm,
+	}, r.signer)
+
+}
+
+// ServeHTTP implements the `http.Handler` interface.
+//
+// A _GET_ request carries the base64 encoded request as the last path segment and a
+// _POST_ request carries the _DER_ encoded request as body.
+func (r *Responder) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+
+	var request []byte
+	var err error
+
+	switch req.Method {
+	case http.MethodGet:
+
+		request, err = decodeGetRequest(req.URL.EscapedPath())
+
+	case http.MethodPost:
+
+		if ct := req.Header.Get("Content-Type"); ct != "application/ocsp-request" {
+			http.Error(w, "unsupported content type", http.StatusUnsupportedMediaType)
+			return
+		}
+
+		request, err = ioutil.ReadAll(io.LimitReader(req.Body, maxRequestSize))
+
+	default:
+
+		w.Header().Set("Allow", "GET, POST")
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+
+	}
+
+	response := ocsp.MalformedRequestErrorResponse
+
+	if err == nil {
+
+		response, err = r.Respond(req.Context(), request)
+
+		if err != nil {
+			response = ocsp.InternalErrorErrorResponse
+		}
+
+	}
+
+	w.Header().Set("Content-Type", "application/ocsp-response")
+
+	if req.Method == http.MethodGet && err == nil {
+		w.Header().Set("Cache-Control", fmt.Sprintf("max-age=%d, public", int(r.validity.Seconds())))
+	}
+
+	_, _ = w.Write(response)
+
+}
+
+// isIssuer checks that the request is for a certificate issued by this responders _CA_.
+func (r *Responder) isIssuer(req *ocsp.Request) bool {
+
+	keyHash, ok := r.issuerKeyHash[req.HashAlgorithm]
+	if !ok {
+		return false
+	}
+
+	return bytes.Equal(keyHash, req.IssuerKeyHash) &&
+		bytes.Equal(r.issuerName[req.HashAlgorithm], req.IssuerNameHash)
+
+}
+
+// decodeGetRequest decodes the base64 encoded request in the _escapedPath_.
+//
+// The request is expected to be the last path segment with any slash in the base64 data
+// percent encoded. Since not all clients encode the slashes, the whole path is tried as well.
+func decodeGetRequest(escapedPath string) ([]byte, error) {
+
+	candidates := []string{
+		escapedPath[strings.LastIndex(escapedPath, "/")+1:],
+		strings.TrimPrefix(escapedPath, "/"),
+	}
+
+	var err error
+
+	for _, candidate := range candidates {
+
+		var unescaped string
+
+		if unescaped, err = url.PathUnescape(candidate); err != nil {
+			continue
+		}
+
+		var request []byte
+
+		if request, err = base64.StdEncoding.DecodeString(unescaped); err == nil {
+			return request, nil
+		}
+
+	}
+
+	return nil, err
+
+}
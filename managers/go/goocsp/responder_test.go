@@ -0,0 +1,92 @@
+package goocsp
+
+import (
+	"bytes"
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/mariotoffia/goservice/utils/cryptoutils"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/ocsp"
+)
+
+func TestResponderReportsStatus(t *testing.T) {
+
+	caKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	caTmpl, _, err := cryptoutils.NewCertificateTemplate(cryptoutils.CertificateProfileCA, cryptoutils.CertificateSubject{
+		Subject: pkix.Name{CommonName: "test ca"},
+	})
+	require.NoError(t, err)
+
+	ca, err := cryptoutils.CreateSelfSignedCertificate(rand.Reader, caTmpl, caKey)
+	require.NoError(t, err)
+
+	leafKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	leafTmpl, _, err := cryptoutils.NewCertificateTemplate(cryptoutils.CertificateProfileTLSServer, cryptoutils.CertificateSubject{
+		DNSNames: []string{"svc.example.com"},
+	})
+	require.NoError(t, err)
+
+	der, err := x509.CreateCertificate(rand.Reader, leafTmpl, ca, &leafKey.PublicKey, caKey)
+	require.NoError(t, err)
+
+	leaf, err := x509.ParseCertificate(der)
+	require.NoError(t, err)
+
+	source := NewMemoryStatusSource()
+	source.Issued(leaf.SerialNumber)
+
+	responder, err := NewResponder(ca, ca, caKey, source)
+	require.NoError(t, err)
+
+	server := httptest.NewServer(responder)
+	defer server.Close()
+
+	request, err := ocsp.CreateRequest(leaf, ca, nil)
+	require.NoError(t, err)
+
+	query := func() *ocsp.Response {
+
+		resp, err := http.Post(server.URL, "application/ocsp-request", bytes.NewReader(request))
+		require.NoError(t, err)
+
+		defer resp.Body.Close()
+
+		body, err := ioutil.ReadAll(resp.Body)
+		require.NoError(t, err)
+
+		parsed, err := ocsp.ParseResponseForCert(body, leaf, ca)
+		require.NoError(t, err)
+
+		return parsed
+	}
+
+	assert.Equal(t, ocsp.Good, query().Status)
+
+	source.Revoke(leaf.SerialNumber, time.Now(), ocsp.KeyCompromise)
+
+	revoked := query()
+	assert.Equal(t, ocsp.Revoked, revoked.Status)
+	assert.Equal(t, ocsp.KeyCompromise, revoked.RevocationReason)
+
+	other, err := ocsp.CreateRequest(ca, leaf, nil)
+	require.NoError(t, err)
+
+	response, err := responder.Respond(context.Background(), other)
+	require.NoError(t, err)
+	assert.Equal(t, ocsp.UnauthorizedErrorResponse, response)
+}
@@ -0,0 +1,325 @@
+package gossh
+
+import (
+	"crypto"
+	"crypto/rand"
+	"fmt"
+	"math/big"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/mariotoffia/goservice/interfaces/ifcrypto"
+	"golang.org/x/crypto/ssh"
+)
+
+// DefaultUserExtensions is the extensions set on user certificates when none are requested,
+// the same set as _ssh-keygen_ uses.
+var DefaultUserExtensions = map[string]string{
+	"permit-X11-forwarding":   "",
+	"permit-agent-forwarding": "",
+	"permit-port-forwarding":  "",
+	"permit-pty":              "",
+	"permit-user-rc":          "",
+}
+
+// CertificateRequest describes an _OpenSSH_ certificate to issue.
+type CertificateRequest struct {
+	// CertType is `ssh.UserCert` or `ssh.HostCert`.
+	CertType uint32
+	// KeyID is a free form identifier logged by the server when the certificate is used.
+	KeyID string
+	// Principals is the user names, or host names, the certificate is valid for. It must
+	// not be empty, since an empty list means any principal.
+	Principals []string
+	// ValidAfter is when the certificate becomes valid. If zero, now minus five minutes is used.
+	ValidAfter time.Time
+	// Validity is how long the certificate is valid from _ValidAfter_, or from now when
+	// _ValidAfter_ is zero.
+	Validity time.Duration
+	// CriticalOptions is e.g. _force-command_ or _source-address_ of a user certificate.
+	CriticalOptions map[string]string
+	// Extensions is the certificate extensions. If `nil` for a user certificate,
+	// `DefaultUserExtensions` is used.
+	Extensions map[string]string
+}
+
+// CertificateAuthority issues _OpenSSH_ user and host certificates.
+type CertificateAuthority struct {
+	signer ssh.Signer
+	now    func() time.Time
+}
+
+// NewCertificateAuthority creates a new `CertificateAuthority` that signs using _signer_.
+//
+// The _signer_ may be any _RSA_, _ECDSA_ or _Ed25519_ `crypto.Signer`, e.g. a remote key.
+// _RSA_ keys signs using _rsa-sha2-512_.
+func NewCertificateAuthority(signer crypto.Signer) (*CertificateAuthority, error) {
+
+	sshSigner, err := ssh.NewSignerFromSigner(signer)
+	if err != nil {
+		return nil, err
+	}
+
+	if sshSigner.PublicKey().Type() == ssh.KeyAlgoRSA {
+
+		algorithmSigner, ok := sshSigner.(ssh.AlgorithmSigner)
+		if !ok {
+			return nil, fmt.Errorf("RSA signer do not support SHA-2 signatures")
+		}
+
+		sshSigner, err = ssh.NewSignerWithAlgorithms(algorithmSigner, []string{ssh.KeyAlgoRSASHA512})
+		if err != nil {
+			return nil, err
+		}
+
+	}
+
+	return &CertificateAuthority{signer: sshSigner, now: time.Now}, nil
+
+}
+
+// NewCertificateAuthorityFromKeyPair creates a new `CertificateAuthority` that signs using
+// _key_, e.g. a in memory or _KMS_ backed key. The key must have `ifcrypto.KeyUsageSign`.
+func NewCertificateAuthorityFromKeyPair(key ifcrypto.KeyPair) (*CertificateAuthority, error) {
+
+	if !hasSignUsage(key) {
+		return nil, fmt.Errorf("key %s do not have usage %s", key.GetID(), ifcrypto.KeyUsageSign)
+	}
+
+	signer, ok := key.(crypto.Signer)
+	if !ok {
+		return nil, fmt.Errorf("key %s is not a crypto.Signer", key.GetID())
+	}
+
+	return NewCertificateAuthority(signer)
+
+}
+
+// PublicKey returns the _CA_ public key.
+func (ca *CertificateAuthority) PublicKey() ssh.PublicKey {
+	return ca.signer.PublicKey()
+}
+
+// AuthorizedKeysLine returns the _authorized_keys_ line that trusts the _CA_ for user certificates.
+func (ca *CertificateAuthority) AuthorizedKeysLine() string {
+	return "cert-authority " + strings.TrimSpace(string(ssh.MarshalAuthorizedKey(ca.PublicKey())))
+}
+
+// KnownHostsLine returns the _known_hosts_ line that trusts the _CA_ for host certificates
+// matching the _hostPattern_, e.g. _*.example.com_.
+func (ca *CertificateAuthority) KnownHostsLine(hostPattern string) string {
+	return "@cert-authority " + hostPattern + " " + strings.TrimSpace(string(ssh.MarshalAuthorizedKey(ca.PublicKey())))
+}
+
+// Issue signs a certificate for the _pub_ key as described by _req_.
+func (ca *CertificateAuthority) Issue(pub ssh.PublicKey, req CertificateRequest) (*ssh.Certificate, error) {
+
+	if req.CertType != ssh.UserCert && req.CertType != ssh.HostCert {
+		return nil, fmt.Errorf("unknown SSH certificate type: %d", req.CertType)
+	}
+
+	if len(req.Principals) == 0 {
+		return nil, fmt.Errorf("SSH certificate must have at least one principal")
+	}
+
+	if req.Validity <= 0 {
+		return nil, fmt.Errorf("SSH certificate validity must be positive")
+	}
+
+	if req.CertType == ssh.HostCert && len(req.CriticalOptions) > 0 {
+		return nil, fmt.Errorf("SSH host certificates do not support critical options")
+	}
+
+	serial, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 63))
+	if err != nil {
+		return nil, err
+	}
+
+	validAfter, validBefore := req.ValidAfter, req.ValidAfter.Add(req.Validity)
+	if validAfter.IsZero() {
+
+		// Backdate for clock skew, but keep the full validity from now.
+		now := ca.now()
+		validAfter, validBefore = now.Add(-5*time.Minute), now.Add(req.Validity)
+
+	}
+
+	extensions := req.Extensions
+	if extensions == nil && req.CertType == ssh.UserCert {
+		extensions = DefaultUserExtensions
+	}
+
+	cert := &ssh.Certificate{
+		Key:             pub,
+		Serial:          serial.Uint64(),
+		CertType:        req.CertType,
+		KeyId:           req.KeyID,
+		ValidPrincipals: append([]string{}, req.Principals...),
+		ValidAfter:      uint64(validAfter.Unix()),
+		ValidBefore:     uint64(validBefore.Unix()),
+		Permissions: ssh.Permissions{
+			CriticalOptions: copyMap(req.CriticalOptions),
+			Extensions:      copyMap(extensions),
+		},
+	}
+
+	if err := cert.SignCert(rand.Reader, ca.signer); err != nil {
+		return nil, err
+	}
+
+	return cert, nil
+
+}
+
+// ParseCertificate parses a presented certificate, either in the _authorized_keys_ format, e.g.
+// the content of a _id_ed25519-cert.pub_ file, or in the _SSH_ wire format.
+func ParseCertificate(data []byte) (*ssh.Certificate, error) {
+
+	pub, _, _, _, err := ssh.ParseAuthorizedKey(data)
+	if err != nil {
+
+		if pub, err = ssh.ParsePublicKey(data); err != nil {
+			return nil, err
+		}
+
+	}
+
+	cert, ok := pub.(*ssh.Certificate)
+	if !ok {
+		return nil, fmt.Errorf("not a SSH certificate: %s", pub.Type())
+	}
+
+	return cert, nil
+
+}
+
+// VerifyUserCertificate verifies that _cert_ is a valid user certificate, signed by one of
+// the _authorities_, for the user _principal_ connecting from _remote_ (may be `nil`).
+func VerifyUserCertificate(cert *ssh.Certificate, principal string, remote net.Addr, authorities ...ssh.PublicKey) error {
+
+	if cert.CertType != ssh.UserCert {
+		return fmt.Errorf("not a SSH user certificate")
+	}
+
+	checker := newChecker(authorities)
+
+	if remote == nil {
+		remote = &net.TCPAddr{IP: net.IPv4zero}
+	}
+
+	if _, err := checker.Authenticate(connMetadata{user: principal, remote: remote}, cert); err != nil {
+		return err
+	}
+
+	// The source-address option is enforced by the ssh server and not the checker
+	if addresses, ok := cert.CriticalOptions["source-address"]; ok {
+		return checkSourceAddress(remote, addresses)
+	}
+
+	return nil
+
+}
+
+// checkSourceAddress checks that _remote_ is within the comma separated _addresses_ list of
+// _IP_ addresses and _CIDR_ ranges.
+func checkSourceAddress(remote net.Addr, addresses string) error {
+
+	tcp, ok := remote.(*net.TCPAddr)
+	if !ok {
+		return fmt.Errorf("SSH certificate source-address requires a TCP remote address")
+	}
+
+	for _, address := range strings.Split(addresses, ",") {
+
+		address = strings.TrimSpace(address)
+
+		if _, ipnet, err := net.ParseCIDR(address); err == nil {
+
+			if ipnet.Contains(tcp.IP) {
+				return nil
+			}
+
+		} else if ip := net.ParseIP(address); ip != nil && ip.Equal(tcp.IP) {
+			return nil
+		}
+
+	}
+
+	return fmt.Errorf("SSH certificate is not valid from %s", tcp.IP)
+
+}
+
+// VerifyHostCertificate verifies that _cert_ is a valid host certificate, signed by one of
+// the _authorities_, for the _host_ (without port).
+func VerifyHostCertificate(cert *ssh.Certificate, host string, authorities ...ssh.PublicKey) error {
+
+	if cert.CertType != ssh.HostCert {
+		return fmt.Errorf("not a SSH host certificate")
+	}
+
+	return newChecker(authorities).CheckHostKey(net.JoinHostPort(host, "22"), &net.TCPAddr{IP: net.IPv4zero}, cert)
+
+}
+
+// newChecker creates a `ssh.CertChecker` that trusts the _authorities_.
+func newChecker(authorities []ssh.PublicKey) *ssh.CertChecker {
+
+	trusted := map[string]struct{}{}
+
+	for _, auth := range authorities {
+		trusted[string(auth.Marshal())] = struct{}{}
+	}
+
+	isAuthority := func(auth ssh.PublicKey) bool {
+		_, ok := trusted[string(auth.Marshal())]
+		return ok
+	}
+
+	return &ssh.CertChecker{
+		IsUserAuthority: isAuthority,
+		IsHostAuthority: func(auth ssh.PublicKey, _ string) bool { return isAuthority(auth) },
+	}
+
+}
+
+// connMetadata is the minimal `ssh.ConnMetadata` needed to authenticate a user certificate.
+type connMetadata struct {
+	user   string
+	remote net.Addr
+}
+
+func (c connMetadata) User() string          { return c.user }
+func (c connMetadata) SessionID() []byte     { return nil }
+func (c connMetadata) ClientVersion() []byte { return nil }
+func (c connMetadata) ServerVersion() []byte { return nil }
+func (c connMetadata) RemoteAddr() net.Addr  { return c.remote }
+func (c connMetadata) LocalAddr() net.Addr   { return c.remote }
+
+// hasSignUsage returns `true` if _key_ has `ifcrypto.KeyUsageSign`.
+func hasSignUsage(key ifcrypto.Key) bool {
+
+	for _, usage := range key.GetKeyUsage() {
+
+		if usage == ifcrypto.KeyUsageSign {
+			return true
+		}
+
+	}
+
+	return false
+
+}
+
+// copyMap returns a copy of _m_, `nil` is returned as a empty map.
+func copyMap(m map[string]string) map[string]string {
+
+	out := make(map[string]string, len(m))
+
+	for k, v := range m {
+		out[k] = v
+	}
+
+	return out
+
+}
@@ -0,0 +1,145 @@
+//go:build !verifyonly
+// +build !verifyonly
+
+package gossh
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mariotoffia/goservice/interfaces/ifcrypto"
+	"github.com/mariotoffia/goservice/managers/go/gocrypto"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/ssh"
+)
+
+func TestIssueAndVerifyCertificates(t *testing.T) {
+
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	userPub, _, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+
+	sshUserPub, err := ssh.NewPublicKey(userPub)
+	require.NoError(t, err)
+
+	for _, key := range []crypto.Signer{rsaKey, ecKey} {
+
+		ca, err := NewCertificateAuthority(key)
+		require.NoError(t, err)
+
+		assert.True(t, strings.HasPrefix(ca.AuthorizedKeysLine(), "cert-authority "))
+		assert.True(t, strings.HasPrefix(ca.KnownHostsLine("*.example.com"), "@cert-authority *.example.com "))
+
+		user, err := ca.Issue(sshUserPub, CertificateRequest{
+			CertType:        ssh.UserCert,
+			KeyID:           "alice@example.com",
+			Principals:      []string{"alice"},
+			Validity:        time.Hour,
+			CriticalOptions: map[string]string{"source-address": "10.0.0.0/8"},
+		})
+		require.NoError(t, err)
+
+		if key == rsaKey {
+			assert.Equal(t, ssh.KeyAlgoRSASHA512, user.Signature.Format)
+		}
+
+		assert.Contains(t, user.Permissions.Extensions, "permit-pty")
+
+		from := &net.TCPAddr{IP: net.ParseIP("10.1.1.1"), Port: 4711}
+
+		assert.NoError(t, VerifyUserCertificate(user, "alice", from, ca.PublicKey()))
+		assert.Error(t, VerifyUserCertificate(user, "bob", from, ca.PublicKey()))
+		assert.Error(t, VerifyUserCertificate(user, "alice", &net.TCPAddr{IP: net.ParseIP("192.168.1.1")}, ca.PublicKey()))
+
+		other, err := NewCertificateAuthority(rsaKey)
+		require.NoError(t, err)
+
+		if key != rsaKey {
+			assert.Error(t, VerifyUserCertificate(user, "alice", from, other.PublicKey()))
+		}
+
+		host, err := ca.Issue(sshUserPub, CertificateRequest{
+			CertType:   ssh.HostCert,
+			Principals: []string{"web.example.com"},
+			Validity:   time.Hour,
+		})
+		require.NoError(t, err)
+
+		assert.NoError(t, VerifyHostCertificate(host, "web.example.com", ca.PublicKey()))
+		assert.Error(t, VerifyHostCertificate(host, "db.example.com", ca.PublicKey()))
+		assert.Error(t, VerifyUserCertificate(host, "web.example.com", nil, ca.PublicKey()))
+
+	}
+
+	ca, err := NewCertificateAuthority(ecKey)
+	require.NoError(t, err)
+
+	expired, err := ca.Issue(sshUserPub, CertificateRequest{
+		CertType:   ssh.UserCert,
+		Principals: []string{"alice"},
+		ValidAfter: time.Now().Add(-2 * time.Hour),
+		Validity:   time.Hour,
+	})
+	require.NoError(t, err)
+
+	assert.Error(t, VerifyUserCertificate(expired, "alice", nil, ca.PublicKey()))
+
+	_, err = ca.Issue(sshUserPub, CertificateRequest{CertType: ssh.UserCert, Validity: time.Hour})
+	assert.Error(t, err, "no principals")
+
+}
+
+func TestCertificateAuthorityFromKeyPair(t *testing.T) {
+
+	key, err := gocrypto.NewED25519PrivateKey("ssh-ca", ifcrypto.KeyUsageSign)
+	require.NoError(t, err)
+
+	ca, err := NewCertificateAuthorityFromKeyPair(key)
+	require.NoError(t, err)
+
+	userPub, _, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+
+	sshUserPub, err := ssh.NewPublicKey(userPub)
+	require.NoError(t, err)
+
+	cert, err := ca.Issue(sshUserPub, CertificateRequest{
+		CertType:   ssh.UserCert,
+		KeyID:      "deploy",
+		Principals: []string{"deploy"},
+		Validity:   5 * time.Minute,
+	})
+	require.NoError(t, err)
+
+	presented, err := ParseCertificate(ssh.MarshalAuthorizedKey(cert))
+	require.NoError(t, err)
+	assert.NoError(t, VerifyUserCertificate(presented, "deploy", nil, ca.PublicKey()))
+
+	presented, err = ParseCertificate(cert.Marshal())
+	require.NoError(t, err)
+	assert.Equal(t, "deploy", presented.KeyId)
+
+	_, err = ParseCertificate(ssh.MarshalAuthorizedKey(sshUserPub))
+	assert.Error(t, err)
+
+	verifyOnly, err := gocrypto.NewED25519PrivateKey("verify-only", ifcrypto.KeyUsageVerify)
+	require.NoError(t, err)
+
+	_, err = NewCertificateAuthorityFromKeyPair(verifyOnly)
+	assert.Error(t, err)
+
+}
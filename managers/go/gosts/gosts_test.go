@@ -0,0 +1,184 @@
+//go:build !verifyonly
+// +build !verifyonly
+
+package gosts
+
+import (
+	"context"
+	"errors"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mariotoffia/goservice/interfaces/ifcrypto"
+	"github.com/mariotoffia/goservice/managers/go/gocrypto"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testIssuer(t *testing.T, now *time.Time, cfg Config) *Issuer {
+
+	cfg.Issuer = "https://sts.internal"
+	cfg.DisableRotation = true
+	cfg.Clock = func() time.Time { return *now }
+
+	if cfg.Templates == nil {
+		cfg.Templates = map[string]ClaimTemplate{
+			"payments": {TTL: time.Minute, Claims: map[string]interface{}{"scope": "charge"}},
+			"ledger":   {Audience: []string{"ledger", "ledger-replica"}},
+		}
+	}
+
+	iss, err := NewIssuer(context.Background(), cfg)
+	require.NoError(t, err)
+
+	return iss
+
+}
+
+func TestIssueAndVerify(t *testing.T) {
+
+	c := context.Background()
+	now := time.Now()
+
+	var issued []*Token
+
+	iss := testIssuer(t, &now, Config{OnIssue: func(token *Token) { issued = append(issued, token) }})
+
+	token, err := iss.Issue(c, "checkout", "payments", map[string]interface{}{"order": "42"})
+	require.NoError(t, err)
+	assert.Equal(t, now.Add(time.Minute), token.ExpiresAt)
+	assert.Equal(t, []string{"payments"}, token.Audience)
+	require.Len(t, issued, 1)
+	assert.Equal(t, token.ID, issued[0].ID)
+
+	verifier := NewVerifier(
+		"https://sts.internal", "payments", iss,
+		WithVerifierClock(func() time.Time { return now }),
+		WithVerifierReplayCache(gocrypto.NewMemoryReplayCache()),
+	)
+
+	claims, err := verifier.Verify(c, token.Value)
+	require.NoError(t, err)
+	assert.Equal(t, "checkout", claims.Subject())
+	assert.Equal(t, token.ID, claims.ID())
+	assert.Equal(t, "charge", claims["scope"])
+	assert.Equal(t, "42", claims["order"])
+
+	_, err = verifier.Verify(c, token.Value)
+	assert.True(t, errors.Is(err, ErrTokenReplayed))
+
+	// The ledger template has two audiences and the default TTL
+	token, err = iss.Issue(c, "checkout", "ledger", nil)
+	require.NoError(t, err)
+	assert.Equal(t, now.Add(5*time.Minute), token.ExpiresAt)
+
+	_, err = verifier.Verify(c, token.Value)
+	assert.True(t, errors.Is(err, ErrInvalidToken), "wrong audience")
+
+	_, err = NewVerifier("https://sts.internal", "ledger-replica", iss).Verify(c, token.Value)
+	require.NoError(t, err)
+
+	_, err = NewVerifier("https://other", "ledger", iss).Verify(c, token.Value)
+	assert.True(t, errors.Is(err, ErrInvalidToken), "wrong issuer")
+
+	// Tampered payload
+	parts := strings.Split(token.Value, ".")
+	_, err = NewVerifier("https://sts.internal", "ledger", iss).Verify(c, parts[0]+"."+parts[1]+"x."+parts[2])
+	assert.True(t, errors.Is(err, ErrInvalidToken))
+
+	expired := NewVerifier(
+		"https://sts.internal", "ledger", iss,
+		WithVerifierClock(func() time.Time { return now.Add(6 * time.Minute) }),
+	)
+
+	_, err = expired.Verify(c, token.Value)
+	assert.True(t, errors.Is(err, ErrTokenExpired))
+
+	_, err = iss.Issue(c, "checkout", "unknown", nil)
+	assert.True(t, errors.Is(err, ErrUnknownTarget))
+
+	_, err = iss.Issue(c, "checkout", "payments", map[string]interface{}{"aud": "other"})
+	assert.Error(t, err, "registered claims can not be set")
+
+	_, err = NewIssuer(c, Config{
+		Issuer:    "https://sts.internal",
+		Templates: map[string]ClaimTemplate{"payments": {Claims: map[string]interface{}{"exp": 0}}},
+	})
+	assert.Error(t, err)
+
+}
+
+func TestIssuerRotatesAndPublishesJWKS(t *testing.T) {
+
+	c := context.Background()
+	now := time.Now()
+
+	iss := testIssuer(t, &now, Config{
+		GenerateKey: func(c context.Context) (ifcrypto.KeyPair, error) {
+			return gocrypto.NewED25519PrivateKey(now.Format(time.RFC3339Nano), ifcrypto.KeyUsageSign)
+		},
+	})
+
+	old, err := iss.Issue(c, "checkout", "payments", nil)
+	require.NoError(t, err)
+
+	now = now.Add(time.Hour)
+	require.NoError(t, iss.Rotate(c))
+
+	current, err := iss.Issue(c, "checkout", "payments", nil)
+	require.NoError(t, err)
+	assert.NotEqual(t, old.KeyID, current.KeyID)
+
+	server := httptest.NewServer(iss.JWKSHandler())
+	defer server.Close()
+
+	jwks := gocrypto.NewRemoteJWKS(server.URL)
+	verifier := NewVerifier(
+		"https://sts.internal", "payments", jwks,
+		WithVerifierClock(func() time.Time { return now }),
+	)
+
+	_, err = verifier.Verify(c, current.Value)
+	require.NoError(t, err)
+
+	set, err := jwks.Keys(c)
+	require.NoError(t, err)
+	assert.Len(t, set.Keys, 2, "the previous key is published until its tokens expire")
+
+	// After the longest TTL the previous key is no longer published
+	now = now.Add(10 * time.Minute)
+	require.NoError(t, iss.Rotate(c))
+
+	keys, err := iss.Keys(c)
+	require.NoError(t, err)
+	assert.Len(t, keys, 2)
+
+	for _, key := range keys {
+		assert.NotEqual(t, old.KeyID, key.GetID())
+	}
+
+}
+
+func TestIssuerDefaultKey(t *testing.T) {
+
+	c := context.Background()
+	now := time.Now()
+
+	iss := testIssuer(t, &now, Config{})
+
+	token, err := iss.Issue(c, "checkout", "payments", nil)
+	require.NoError(t, err)
+
+	header, _, signature, err := parse(token.Value)
+	require.NoError(t, err)
+	assert.Equal(t, "ES256", header.Algorithm)
+	assert.Len(t, signature, 64, "JWS uses the raw r || s encoding")
+
+	set, err := iss.JWKS(c)
+	require.NoError(t, err)
+	require.Len(t, set.Keys, 1)
+	assert.Equal(t, token.KeyID, set.Keys[0].KeyID)
+
+}
@@ -0,0 +1,468 @@
+// Package gosts is a internal security token service that mints short lived
+// service-to-service tokens.
+//
+// A `Issuer` mints _RFC 7519_ _JWT_ from a `ClaimTemplate` per target service, tracks each
+// _jti_ in a `ifcrypto.ReplayCache`, rotates its signing key and publishes the current and
+// still valid previous keys as a _JWKS_. A `Verifier` in the target service verifies the
+// tokens against the _JWKS_, e.g. a `gocrypto.RemoteJWKS`, and may reject replayed tokens.
+//
+// .Example
+// [source,go]
+// ----
+// templates := map[string]gosts.ClaimTemplate{"payments": {TTL: time.Minute}}
+// issuer, err := gosts.NewIssuer(c, gosts.Config{Issuer: "https://sts", Templates: templates})
+//
+// token, err := issuer.Issue(c, "checkout", "payments", nil)
+// ----
+package gosts
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/mariotoffia/goservice/interfaces/ifcrypto"
+	"github.com/mariotoffia/goservice/managers/go/gocrypto"
+)
+
+// ErrUnknownTarget is returned by `Issuer.Issue` when there is no `ClaimTemplate` for the
+// target service.
+var ErrUnknownTarget = fmt.Errorf("unknown target service")
+
+// registeredClaims are set by the `Issuer` and can not be set by templates or callers.
+var registeredClaims = []string{"iss", "sub", "aud", "exp", "nbf", "iat", "jti"}
+
+// ClaimTemplate is the claims of the tokens minted for a target service.
+type ClaimTemplate struct {
+	// Audience is the _aud_ claim, default the target service name.
+	Audience []string
+	// TTL is the token lifetime, default `Config.TTL`.
+	TTL time.Duration
+	// Claims are the additional claims, e.g. _scope_. The registered claims can not be set.
+	Claims map[string]interface{}
+}
+
+// Config is the configuration of a `Issuer`.
+type Config struct {
+	// Issuer is the _iss_ claim, e.g. the _URL_ of the token service.
+	Issuer string
+	// Templates is the claim template of each target service.
+	Templates map[string]ClaimTemplate
+	// TTL is the default token lifetime, default five minutes.
+	TTL time.Duration
+	// RotateEvery is how often the signing key is rotated, default 24 hours.
+	RotateEvery time.Duration
+	// GenerateKey returns a new signing key with a unique id, default a _ECDSA_ _P-256_ key
+	// with its _JWK_ thumbprint as id. _ECDSA_, _Ed25519_ and _RSA_ keys are supported.
+	GenerateKey func(c context.Context) (ifcrypto.KeyPair, error)
+	// ReplayCache tracks the issued _jti_, default a `gocrypto.MemoryReplayCache`.
+	ReplayCache ifcrypto.ReplayCache
+	// DisableRotation do not start the rotation, it may be started with `Issuer.Start` or
+	// `Issuer.Rotate` called manually.
+	DisableRotation bool
+	// OnIssue is invoked with each minted token, e.g. to audit the _jti_.
+	OnIssue func(token *Token)
+	// OnRotate is invoked after each successful rotation.
+	OnRotate func(issuer *Issuer)
+	// OnError is invoked when a rotation fails.
+	OnError func(err error)
+	// Clock is the clock, default `time.Now`.
+	Clock func() time.Time
+}
+
+// Token is a minted token.
+type Token struct {
+	// Value is the compact serialized _JWT_.
+	Value     string
+	ID        string
+	KeyID     string
+	Subject   string
+	Audience  []string
+	IssuedAt  time.Time
+	ExpiresAt time.Time
+}
+
+// signingKey is a signing key and when it was rotated out.
+type signingKey struct {
+	key       ifcrypto.KeyPair
+	alg       string
+	retiredAt time.Time
+}
+
+// Issuer mints service-to-service tokens.
+type Issuer struct {
+	cfg      Config
+	maxTTL   time.Duration
+	mu       sync.RWMutex
+	current  *signingKey
+	previous []*signingKey
+	rotated  time.Time
+	rotation *gocrypto.Background
+}
+
+// NewIssuer validates the templates, creates the first signing key and, unless disabled,
+// starts the rotation that runs until _c_ is done.
+func NewIssuer(c context.Context, cfg Config) (*Issuer, error) {
+
+	if cfg.Issuer == "" {
+		return nil, fmt.Errorf("issuer is required")
+	}
+
+	if len(cfg.Templates) == 0 {
+		return nil, fmt.Errorf("at least one claim template is required")
+	}
+
+	if cfg.TTL == 0 {
+		cfg.TTL = 5 * time.Minute
+	}
+
+	if cfg.RotateEvery == 0 {
+		cfg.RotateEvery = 24 * time.Hour
+	}
+
+	if cfg.GenerateKey == nil {
+		cfg.GenerateKey = generateKey
+	}
+
+	if cfg.Clock == nil {
+		cfg.Clock = time.Now
+	}
+
+	if cfg.ReplayCache == nil {
+		cfg.ReplayCache = gocrypto.NewMemoryReplayCache(gocrypto.WithReplayCacheClock(cfg.Clock))
+	}
+
+	iss := &Issuer{cfg: cfg, maxTTL: cfg.TTL}
+
+	for target, tmpl := range cfg.Templates {
+
+		if err := checkClaims(tmpl.Claims); err != nil {
+			return nil, fmt.Errorf("template %s: %w", target, err)
+		}
+
+		if tmpl.TTL > iss.maxTTL {
+			iss.maxTTL = tmpl.TTL
+		}
+
+	}
+
+	iss.rotation = gocrypto.NewBackground("token issuer rotation", iss.run)
+
+	if err := iss.Rotate(c); err != nil {
+		return nil, err
+	}
+
+	if !cfg.DisableRotation {
+
+		if err := iss.rotation.Start(c); err != nil {
+			return nil, err
+		}
+
+	}
+
+	return iss, nil
+
+}
+
+// Start implements the `ifcrypto.Lifecycle` interface, it starts the rotation when created
+// with _DisableRotation_. The rotation runs until `Stop` is called or _c_ is done.
+func (iss *Issuer) Start(c context.Context) error {
+	return iss.rotation.Start(c)
+}
+
+// Stop implements the `ifcrypto.Lifecycle` interface. A rotation in-flight is completed, or
+// canceled when _c_ is done before.
+func (iss *Issuer) Stop(c context.Context) error {
+	return iss.rotation.Stop(c)
+}
+
+// Done is closed when a started rotation has stopped.
+func (iss *Issuer) Done() <-chan struct{} {
+	return iss.rotation.Done()
+}
+
+// Issue mints a token for _subject_, i.e. the calling service, to the _target_ service. The
+// _claims_ are added to the claims of the target template and may not set the registered
+// claims.
+func (iss *Issuer) Issue(
+	c context.Context,
+	subject, target string,
+	claims map[string]interface{},
+) (*Token, error) {
+
+	tmpl, ok := iss.cfg.Templates[target]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownTarget, target)
+	}
+
+	if err := checkClaims(claims); err != nil {
+		return nil, err
+	}
+
+	ttl := tmpl.TTL
+	if ttl == 0 {
+		ttl = iss.cfg.TTL
+	}
+
+	audience := tmpl.Audience
+	if len(audience) == 0 {
+		audience = []string{target}
+	}
+
+	jti, err := newTokenID()
+	if err != nil {
+		return nil, err
+	}
+
+	now := iss.cfg.Clock()
+	token := &Token{
+		ID:        jti,
+		Subject:   subject,
+		Audience:  audience,
+		IssuedAt:  now,
+		ExpiresAt: now.Add(ttl),
+	}
+
+	replayed, err := iss.cfg.ReplayCache.Use(c, iss.cfg.Issuer, jti, token.ExpiresAt)
+	if err != nil {
+		return nil, err
+	}
+
+	if replayed {
+		return nil, fmt.Errorf("token id %s is already issued", jti)
+	}
+
+	payload := Claims{}
+
+	for name, value := range tmpl.Claims {
+		payload[name] = value
+	}
+
+	for name, value := range claims {
+		payload[name] = value
+	}
+
+	payload["iss"] = iss.cfg.Issuer
+	payload["sub"] = subject
+	payload["aud"] = audience
+	payload["iat"] = now.Unix()
+	payload["nbf"] = now.Unix()
+	payload["exp"] = token.ExpiresAt.Unix()
+	payload["jti"] = jti
+
+	iss.mu.RLock()
+	current := iss.current
+	iss.mu.RUnlock()
+
+	token.KeyID = current.key.GetID()
+
+	if token.Value, err = sign(current.key, current.alg, payload); err != nil {
+		return nil, err
+	}
+
+	if iss.cfg.OnIssue != nil {
+		iss.cfg.OnIssue(token)
+	}
+
+	return token, nil
+
+}
+
+// Keys implements the `gocrypto.JWKSetSource` and returns the current and the previous
+// signing keys that may still have valid tokens.
+func (iss *Issuer) Keys(c context.Context) ([]ifcrypto.Key, error) {
+
+	iss.mu.RLock()
+	defer iss.mu.RUnlock()
+
+	keys := []ifcrypto.Key{iss.current.key}
+
+	for _, previous := range iss.previous {
+		keys = append(keys, previous.key)
+	}
+
+	return keys, nil
+
+}
+
+// Lookup implements the `KeyResolver` interface, such that a `Verifier` in the same process
+// can use the issuer directly.
+func (iss *Issuer) Lookup(c context.Context, kid string) (ifcrypto.PublicKey, error) {
+
+	keys, err := iss.Keys(c)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, key := range keys {
+
+		if key.GetID() == kid {
+			return key.(ifcrypto.KeyPair).GetPublic(), nil
+		}
+
+	}
+
+	return nil, fmt.Errorf("%w: %s", gocrypto.ErrJWKNotFound, kid)
+
+}
+
+// JWKS returns the _JWKS_ of `Keys`.
+func (iss *Issuer) JWKS(c context.Context) (*gocrypto.JWKSet, error) {
+
+	keys, err := iss.Keys(c)
+	if err != nil {
+		return nil, err
+	}
+
+	return gocrypto.NewJWKSet(keys...)
+
+}
+
+// JWKSHandler returns a `http.Handler` that serves `JWKS`, e.g. on _/.well-known/jwks.json_.
+//
+// Clients may cache the _JWKS_ for a tenth of the rotation interval.
+func (iss *Issuer) JWKSHandler() http.Handler {
+	return gocrypto.NewJWKSetHandler(iss.Keys, iss.cfg.RotateEvery/10)
+}
+
+// Rotate generates a new signing key and makes it current. The previous key is published in
+// the _JWKS_ until the longest token lifetime has passed.
+func (iss *Issuer) Rotate(c context.Context) error {
+
+	key, err := iss.cfg.GenerateKey(c)
+	if err != nil {
+		return err
+	}
+
+	alg, err := algorithm(key)
+	if err != nil {
+		return err
+	}
+
+	now := iss.cfg.Clock()
+
+	iss.mu.Lock()
+
+	if iss.current != nil {
+
+		iss.current.retiredAt = now
+		iss.previous = append([]*signingKey{iss.current}, iss.previous...)
+
+	}
+
+	previous := iss.previous[:0]
+
+	for _, p := range iss.previous {
+
+		if now.Before(p.retiredAt.Add(iss.maxTTL)) {
+			previous = append(previous, p)
+		}
+
+	}
+
+	iss.previous = previous
+	iss.current = &signingKey{key: key, alg: alg}
+	iss.rotated = now
+
+	iss.mu.Unlock()
+
+	if iss.cfg.OnRotate != nil {
+		iss.cfg.OnRotate(iss)
+	}
+
+	return nil
+
+}
+
+// run rotates the signing key, using _work_, every _RotateEvery_ until _stop_ is done. A
+// failed rotation is retried after a tenth of the interval.
+func (iss *Issuer) run(stop, work context.Context) {
+
+	for {
+
+		iss.mu.RLock()
+		wait := iss.rotated.Add(iss.cfg.RotateEvery).Sub(iss.cfg.Clock())
+		iss.mu.RUnlock()
+
+		select {
+		case <-stop.Done():
+			return
+		case <-time.After(wait):
+		}
+
+		for {
+
+			err := iss.Rotate(work)
+			if err == nil {
+				break
+			}
+
+			if iss.cfg.OnError != nil {
+				iss.cfg.OnError(err)
+			}
+
+			select {
+			case <-stop.Done():
+				return
+			case <-time.After(iss.cfg.RotateEvery / 10):
+			}
+
+		}
+
+	}
+
+}
+
+// checkClaims returns a error if _claims_ sets a registered claim.
+func checkClaims(claims map[string]interface{}) error {
+
+	for _, name := range registeredClaims {
+
+		if _, ok := claims[name]; ok {
+			return fmt.Errorf("registered claim %s can not be set", name)
+		}
+
+	}
+
+	return nil
+
+}
+
+// generateKey generates a _ECDSA_ _P-256_ key with its _JWK_ thumbprint as id.
+func generateKey(c context.Context) (ifcrypto.KeyPair, error) {
+
+	key, err := gocrypto.NewECDSAPrivateKey("", 256, ifcrypto.KeyUsageSign)
+	if err != nil {
+		return nil, err
+	}
+
+	kid, err := gocrypto.Fingerprint(
+		key,
+		gocrypto.WithFingerprintMethod(gocrypto.FingerprintJWKThumbprint),
+		gocrypto.WithFingerprintEncoding(gocrypto.FingerprintBase64URL),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return gocrypto.NewECDSAPrivateKeyFromKey(kid, key.GetKey().(*ecdsa.PrivateKey), ifcrypto.KeyUsageSign), nil
+
+}
+
+// newTokenID returns a random 128 bit _jti_.
+func newTokenID() (string, error) {
+
+	var id [16]byte
+
+	if _, err := rand.Read(id[:]); err != nil {
+		return "", err
+	}
+
+	return hex.EncodeToString(id[:]), nil
+
+}
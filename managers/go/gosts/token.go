@@ -0,0 +1,227 @@
+package gosts
+
+import (
+	"crypto"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/mariotoffia/goservice/interfaces/ifcrypto"
+	"github.com/mariotoffia/goservice/managers/go/gocrypto"
+)
+
+// Claims is the claims of a token.
+type Claims map[string]interface{}
+
+// Issuer returns the _iss_ claim.
+func (c Claims) Issuer() string {
+	return c.string("iss")
+}
+
+// Subject returns the _sub_ claim.
+func (c Claims) Subject() string {
+	return c.string("sub")
+}
+
+// ID returns the _jti_ claim.
+func (c Claims) ID() string {
+	return c.string("jti")
+}
+
+// Audience returns the _aud_ claim regardless if it is a single string or an array.
+func (c Claims) Audience() []string {
+
+	switch aud := c["aud"].(type) {
+	case string:
+		return []string{aud}
+	case []string:
+		return aud
+	case []interface{}:
+
+		audience := []string{}
+
+		for _, a := range aud {
+
+			if s, ok := a.(string); ok {
+				audience = append(audience, s)
+			}
+
+		}
+
+		return audience
+
+	}
+
+	return nil
+
+}
+
+// ExpiresAt returns the _exp_ claim, or the zero time if not set.
+func (c Claims) ExpiresAt() time.Time {
+	return c.time("exp")
+}
+
+// NotBefore returns the _nbf_ claim, or the zero time if not set.
+func (c Claims) NotBefore() time.Time {
+	return c.time("nbf")
+}
+
+func (c Claims) string(name string) string {
+
+	s, _ := c[name].(string)
+
+	return s
+
+}
+
+// time returns the _NumericDate_ claim _name_.
+func (c Claims) time(name string) time.Time {
+
+	switch v := c[name].(type) {
+	case int64:
+		return time.Unix(v, 0)
+	case float64:
+		return time.Unix(int64(v), 0)
+	case json.Number:
+
+		if seconds, err := v.Int64(); err == nil {
+			return time.Unix(seconds, 0)
+		}
+
+	}
+
+	return time.Time{}
+
+}
+
+// jwsHeader is the _JOSE_ header of the tokens.
+type jwsHeader struct {
+	Algorithm string `json:"alg"`
+	KeyID     string `json:"kid"`
+	Type      string `json:"typ,omitempty"`
+}
+
+// algorithm returns the _JWS_ _alg_ of _key_.
+func algorithm(key ifcrypto.Key) (string, error) {
+
+	switch key.GetKeyType() {
+	case ifcrypto.KeyTypeEccNistP:
+
+		switch key.GetKeySize() {
+		case 256:
+			return "ES256", nil
+		case 384:
+			return "ES384", nil
+		case 521:
+			return "ES512", nil
+		}
+
+	case ifcrypto.KeyTypeEd25519:
+		return "EdDSA", nil
+	case ifcrypto.KeyTypeRsa:
+
+		if key.GetKeySize() >= 2048 {
+			return "RS256", nil
+		}
+
+	}
+
+	return "", fmt.Errorf("key %s can not sign tokens", key.GetID())
+
+}
+
+// signerOpts returns the signer options and hash of the _JWS_ _alg_.
+func signerOpts(alg string) crypto.SignerOpts {
+
+	switch alg {
+	case "ES256":
+		return &gocrypto.ECDSASignerOpts{Hash: crypto.SHA256, Raw: true}
+	case "ES384":
+		return &gocrypto.ECDSASignerOpts{Hash: crypto.SHA384, Raw: true}
+	case "ES512":
+		return &gocrypto.ECDSASignerOpts{Hash: crypto.SHA512, Raw: true}
+	case "RS256":
+		return crypto.SHA256
+	}
+
+	// EdDSA signs the message itself
+	return crypto.Hash(0)
+
+}
+
+// digest returns the digest of the _JWS_ signing _input_ for _opts_.
+func digest(input string, opts crypto.SignerOpts) []byte {
+
+	if opts.HashFunc() == 0 {
+		return []byte(input)
+	}
+
+	h := opts.HashFunc().New()
+	h.Write([]byte(input))
+
+	return h.Sum(nil)
+
+}
+
+// sign returns the compact _JWS_ of _claims_ signed by _key_ with _alg_.
+func sign(key ifcrypto.KeyPair, alg string, claims Claims) (string, error) {
+
+	header, err := json.Marshal(&jwsHeader{Algorithm: alg, KeyID: key.GetID(), Type: "JWT"})
+	if err != nil {
+		return "", err
+	}
+
+	payload, err := json.Marshal(claims)
+	if err != nil {
+		return "", err
+	}
+
+	input := base64.RawURLEncoding.EncodeToString(header) + "." +
+		base64.RawURLEncoding.EncodeToString(payload)
+
+	signer, ok := key.(crypto.Signer)
+	if !ok {
+		return "", fmt.Errorf("key %s is not a signer", key.GetID())
+	}
+
+	opts := signerOpts(alg)
+
+	signature, err := signer.Sign(rand.Reader, digest(input, opts), opts)
+	if err != nil {
+		return "", err
+	}
+
+	return input + "." + base64.RawURLEncoding.EncodeToString(signature), nil
+
+}
+
+// parse splits the compact _JWS_ _token_ into the header, the signing input and the signature.
+func parse(token string) (*jwsHeader, string, []byte, error) {
+
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, "", nil, fmt.Errorf("%w: not a compact JWS", ErrInvalidToken)
+	}
+
+	data, err := base64.RawURLEncoding.DecodeString(parts[0])
+	if err != nil {
+		return nil, "", nil, fmt.Errorf("%w: %v", ErrInvalidToken, err)
+	}
+
+	var header jwsHeader
+
+	if err := json.Unmarshal(data, &header); err != nil {
+		return nil, "", nil, fmt.Errorf("%w: %v", ErrInvalidToken, err)
+	}
+
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, "", nil, fmt.Errorf("%w: %v", ErrInvalidToken, err)
+	}
+
+	return &header, parts[0] + "." + parts[1], signature, nil
+
+}
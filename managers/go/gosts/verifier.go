@@ -0,0 +1,184 @@
+package gosts
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/mariotoffia/goservice/interfaces/ifcrypto"
+)
+
+var (
+	// ErrInvalidToken is returned by `Verifier.Verify` when the token is malformed, has a
+	// invalid signature or claims that do not match the verifier.
+	ErrInvalidToken = fmt.Errorf("invalid token")
+	// ErrTokenExpired is returned by `Verifier.Verify` when the token is expired or not yet
+	// valid.
+	ErrTokenExpired = fmt.Errorf("token expired")
+	// ErrTokenReplayed is returned by `Verifier.Verify` when the _jti_ already was used, see
+	// `WithVerifierReplayCache`.
+	ErrTokenReplayed = fmt.Errorf("token replayed")
+)
+
+// KeyResolver resolves the public key of a _kid_, e.g. a `gocrypto.RemoteJWKS` or a `Issuer`.
+type KeyResolver interface {
+	Lookup(c context.Context, kid string) (ifcrypto.PublicKey, error)
+}
+
+// VerifierOption configures a `Verifier`.
+type VerifierOption func(v *Verifier)
+
+// WithVerifierReplayCache makes the tokens single use by rejecting a _jti_ that already was
+// used with `ErrTokenReplayed`.
+func WithVerifierReplayCache(cache ifcrypto.ReplayCache) VerifierOption {
+	return func(v *Verifier) {
+		v.replay = cache
+	}
+}
+
+// WithVerifierLeeway sets the allowed clock skew to the issuer, default 30 seconds.
+func WithVerifierLeeway(leeway time.Duration) VerifierOption {
+	return func(v *Verifier) {
+		v.leeway = leeway
+	}
+}
+
+// WithVerifierClock sets the clock, default `time.Now`.
+func WithVerifierClock(clock func() time.Time) VerifierOption {
+	return func(v *Verifier) {
+		v.clock = clock
+	}
+}
+
+// Verifier verifies the tokens of a `Issuer` in a target service.
+type Verifier struct {
+	issuer   string
+	audience string
+	keys     KeyResolver
+	replay   ifcrypto.ReplayCache
+	leeway   time.Duration
+	clock    func() time.Time
+}
+
+// NewVerifier creates a new `Verifier` that accepts the tokens of _issuer_ to _audience_,
+// signed by a key from _keys_.
+func NewVerifier(issuer, audience string, keys KeyResolver, opts ...VerifierOption) *Verifier {
+
+	v := &Verifier{
+		issuer:   issuer,
+		audience: audience,
+		keys:     keys,
+		leeway:   30 * time.Second,
+		clock:    time.Now,
+	}
+
+	for _, opt := range opts {
+		opt(v)
+	}
+
+	return v
+
+}
+
+// Verify verifies the signature, issuer, audience and lifetime of the _token_ and returns
+// its claims.
+func (v *Verifier) Verify(c context.Context, token string) (Claims, error) {
+
+	header, input, signature, err := parse(token)
+	if err != nil {
+		return nil, err
+	}
+
+	key, err := v.keys.Lookup(c, header.KeyID)
+	if err != nil {
+		return nil, err
+	}
+
+	// The algorithm is given by the key, never by the token
+	if alg, err := algorithm(key); err != nil || alg != header.Algorithm {
+		return nil, fmt.Errorf("%w: algorithm %s do not match the key", ErrInvalidToken, header.Algorithm)
+	}
+
+	verifier, ok := key.(ifcrypto.SignatureVerifier)
+	if !ok {
+		return nil, fmt.Errorf("key %s can not verify signatures", key.GetID())
+	}
+
+	opts := signerOpts(header.Algorithm)
+
+	if err := verifier.Verify(digest(input, opts), signature, opts); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidToken, err)
+	}
+
+	payload, err := base64.RawURLEncoding.DecodeString(input[strings.IndexByte(input, '.')+1:])
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidToken, err)
+	}
+
+	claims := Claims{}
+	decoder := json.NewDecoder(bytes.NewReader(payload))
+	decoder.UseNumber()
+
+	if err := decoder.Decode(&claims); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidToken, err)
+	}
+
+	if claims.Issuer() != v.issuer {
+		return nil, fmt.Errorf("%w: issuer %s", ErrInvalidToken, claims.Issuer())
+	}
+
+	if !contains(claims.Audience(), v.audience) {
+		return nil, fmt.Errorf("%w: audience do not include %s", ErrInvalidToken, v.audience)
+	}
+
+	now := v.clock()
+	expires := claims.ExpiresAt()
+
+	switch {
+	case expires.IsZero():
+		return nil, fmt.Errorf("%w: no expiry", ErrInvalidToken)
+	case !now.Before(expires.Add(v.leeway)):
+		return nil, ErrTokenExpired
+	case now.Add(v.leeway).Before(claims.NotBefore()):
+		return nil, fmt.Errorf("%w: not yet valid", ErrTokenExpired)
+	}
+
+	if v.replay != nil {
+
+		if claims.ID() == "" {
+			return nil, fmt.Errorf("%w: no token id", ErrInvalidToken)
+		}
+
+		replayed, err := v.replay.Use(c, v.audience, claims.ID(), expires.Add(v.leeway))
+		if err != nil {
+			return nil, err
+		}
+
+		if replayed {
+			return nil, fmt.Errorf("%w: %s", ErrTokenReplayed, claims.ID())
+		}
+
+	}
+
+	return claims, nil
+
+}
+
+// contains returns `true` if _values_ contains _value_.
+func contains(values []string, value string) bool {
+
+	for _, v := range values {
+
+		if v == value {
+			return true
+		}
+
+	}
+
+	return false
+
+}
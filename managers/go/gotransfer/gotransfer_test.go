@@ -0,0 +1,133 @@
+package gotransfer
+
+import (
+	"bytes"
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// memoryTransport is a in memory `FileTransport`.
+type memoryTransport map[string][]byte
+
+func (m memoryTransport) Put(c context.Context, name string, r io.Reader) error {
+
+	data, err := ioutil.ReadAll(r)
+	if err != nil {
+		return err
+	}
+
+	m[name] = data
+
+	return nil
+
+}
+
+func (m memoryTransport) Get(c context.Context, name string) (io.ReadCloser, error) {
+
+	data, ok := m[name]
+	if !ok {
+		return nil, fmt.Errorf("%s not found", name)
+	}
+
+	return ioutil.NopCloser(bytes.NewReader(data)), nil
+
+}
+
+func TestUploadAndDownload(t *testing.T) {
+
+	c := context.Background()
+
+	public, private, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+
+	transport := memoryTransport{}
+
+	uploader := NewTransfer(transport, WithTransferSigner("release", private))
+
+	manifest, err := uploader.Upload(
+		c, "batch.manifest.json",
+		File{Name: "payments.csv", Reader: strings.NewReader("id,amount\n1,100\n")},
+		File{Name: "refunds.csv", Reader: strings.NewReader("id,amount\n")},
+	)
+	require.NoError(t, err)
+	require.Len(t, manifest.Files, 2)
+	assert.Equal(t, int64(16), manifest.Files[0].Size)
+	assert.Contains(t, transport, "batch.manifest.json"+SignatureSuffix)
+
+	downloader := NewTransfer(transport, WithTransferTrustedKey("release", public))
+
+	fetched, err := downloader.Fetch(c, "batch.manifest.json")
+	require.NoError(t, err)
+	assert.Equal(t, manifest.Files, fetched.Files)
+
+	var buf bytes.Buffer
+
+	require.NoError(t, downloader.Download(c, fetched, "payments.csv", &buf))
+	assert.Equal(t, "id,amount\n1,100\n", buf.String())
+
+	// A file altered at the partner is detected
+	transport["payments.csv"] = []byte("id,amount\n1,900\n")
+
+	err = downloader.Download(c, fetched, "payments.csv", ioutil.Discard)
+	assert.True(t, errors.Is(err, ErrDigestMismatch))
+
+	transport["refunds.csv"] = []byte("id,amount\n2,5\n")
+
+	err = downloader.Download(c, fetched, "refunds.csv", ioutil.Discard)
+	assert.True(t, errors.Is(err, ErrDigestMismatch), "longer file")
+
+	err = downloader.Download(c, fetched, "other.csv", ioutil.Discard)
+	assert.True(t, errors.Is(err, ErrNotInManifest))
+
+}
+
+func TestFetchRejectsUntrustedManifest(t *testing.T) {
+
+	c := context.Background()
+
+	public, private, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+
+	_, other, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+
+	transport := memoryTransport{}
+
+	_, err = NewTransfer(transport, WithTransferSigner("release", other)).Upload(
+		c, "batch.manifest.json", File{Name: "a.txt", Reader: strings.NewReader("a")},
+	)
+	require.NoError(t, err)
+
+	downloader := NewTransfer(transport, WithTransferTrustedKey("release", public))
+
+	_, err = downloader.Fetch(c, "batch.manifest.json")
+	assert.Error(t, err, "signed by a untrusted key")
+
+	_, err = NewTransfer(transport, WithTransferSigner("release", private)).Upload(
+		c, "batch.manifest.json", File{Name: "a.txt", Reader: strings.NewReader("a")},
+	)
+	require.NoError(t, err)
+
+	_, err = downloader.Fetch(c, "batch.manifest.json")
+	require.NoError(t, err)
+
+	// A manifest replaced without a new signature is rejected
+	transport["batch.manifest.json"] = bytes.Replace(transport["batch.manifest.json"], []byte(`"size":1`), []byte(`"size":2`), 1)
+
+	_, err = downloader.Fetch(c, "batch.manifest.json")
+	assert.Error(t, err)
+
+	_, err = NewTransfer(transport).Upload(c, "batch.manifest.json")
+	assert.Error(t, err, "no signer")
+
+}
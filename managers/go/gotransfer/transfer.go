@@ -0,0 +1,308 @@
+// Package gotransfer signs artifacts pushed to file transfer partners, e.g. over _SFTP_ or
+// _SCP_, and verifies them after download.
+//
+// The files of a upload are described by a `Manifest`, with the size and _SHA-256_ digest of
+// each file, and the manifest is signed by a detached _DSSE_ envelope. The manifest and its
+// signature are pushed after the files, hence a partner that picks up a batch when the
+// signature arrives never sees a partial upload.
+//
+// The transfer protocol is abstracted by `FileTransport`, e.g. a adapter of a _SFTP_ client
+// such as _github.com/pkg/sftp_.
+//
+// .Example
+// [source,go]
+// ----
+// transfer := gotransfer.NewTransfer(sftpTransport, gotransfer.WithTransferSigner("release", key))
+//
+// manifest, err := transfer.Upload(c, "batch-42.manifest.json", gotransfer.File{Name: "payments.csv", Reader: f})
+// ----
+package gotransfer
+
+import (
+	"bytes"
+	"context"
+	"crypto"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/mariotoffia/goservice/managers/go/godsse"
+)
+
+// ManifestPayloadType is the _DSSE_ payload type of a `Manifest`.
+const ManifestPayloadType = "application/vnd.goservice.transfer-manifest+json"
+
+// SignatureSuffix is appended to the manifest name to get the name of its detached signature.
+const SignatureSuffix = ".sig"
+
+var (
+	// ErrDigestMismatch is returned by `Transfer.Download` when the downloaded file do not match
+	// the manifest.
+	ErrDigestMismatch = fmt.Errorf("file do not match the manifest")
+	// ErrNotInManifest is returned by `Transfer.Download` when the file is not in the manifest.
+	ErrNotInManifest = fmt.Errorf("file not in manifest")
+)
+
+// FileTransport pushes and fetches files to and from a partner, e.g. over _SFTP_ or _SCP_.
+type FileTransport interface {
+	// Put writes the content of _r_ to the file _name_.
+	Put(c context.Context, name string, r io.Reader) error
+	// Get opens the file _name_.
+	Get(c context.Context, name string) (io.ReadCloser, error)
+}
+
+// File is a file to upload.
+type File struct {
+	Name   string
+	Reader io.Reader
+}
+
+// ManifestFile is a file in a `Manifest`.
+type ManifestFile struct {
+	Name string `json:"name"`
+	Size int64  `json:"size"`
+	// SHA256 is the hex encoded _SHA-256_ digest of the file.
+	SHA256 string `json:"sha256"`
+}
+
+// Manifest describes the files of a upload.
+type Manifest struct {
+	Created time.Time      `json:"created"`
+	Files   []ManifestFile `json:"files"`
+}
+
+// File returns the file _name_, or `nil` if not in the manifest.
+func (m *Manifest) File(name string) *ManifestFile {
+
+	for i := range m.Files {
+
+		if m.Files[i].Name == name {
+			return &m.Files[i]
+		}
+
+	}
+
+	return nil
+
+}
+
+// TransferOption configures a `Transfer`.
+type TransferOption func(t *Transfer)
+
+// WithTransferSigner signs the uploaded manifests with _signer_ recorded as _keyID_. When used
+// multiple times the manifest is co-signed by all signers.
+func WithTransferSigner(keyID string, signer crypto.Signer) TransferOption {
+	return func(t *Transfer) {
+		t.signers = append(t.signers, godsse.KeySigner{KeyID: keyID, Signer: signer})
+	}
+}
+
+// WithTransferTrustedKey trusts _key_, under _keyID_, to sign downloaded manifests.
+func WithTransferTrustedKey(keyID string, key crypto.PublicKey) TransferOption {
+	return func(t *Transfer) {
+		t.trusted[keyID] = key
+	}
+}
+
+// WithTransferThreshold sets the number of distinct trusted keys that must have signed a
+// downloaded manifest, default one.
+func WithTransferThreshold(threshold int) TransferOption {
+	return func(t *Transfer) {
+		t.threshold = threshold
+	}
+}
+
+// WithTransferClock sets the clock, default `time.Now`.
+func WithTransferClock(clock func() time.Time) TransferOption {
+	return func(t *Transfer) {
+		t.clock = clock
+	}
+}
+
+// Transfer signs uploads and verifies downloads over a `FileTransport`.
+type Transfer struct {
+	transport FileTransport
+	signers   []godsse.KeySigner
+	trusted   map[string]crypto.PublicKey
+	threshold int
+	clock     func() time.Time
+}
+
+// NewTransfer creates a new `Transfer` over _transport_.
+func NewTransfer(transport FileTransport, opts ...TransferOption) *Transfer {
+
+	t := &Transfer{
+		transport: transport,
+		trusted:   map[string]crypto.PublicKey{},
+		threshold: 1,
+		clock:     time.Now,
+	}
+
+	for _, opt := range opts {
+		opt(t)
+	}
+
+	return t
+
+}
+
+// Upload pushes the _files_, digesting them while pushed, and then the `Manifest` as
+// _manifest_ and its detached signature with the `SignatureSuffix`.
+func (t *Transfer) Upload(c context.Context, manifest string, files ...File) (*Manifest, error) {
+
+	if len(t.signers) == 0 {
+		return nil, fmt.Errorf("at least one signer is required")
+	}
+
+	m := &Manifest{Created: t.clock().UTC(), Files: []ManifestFile{}}
+
+	for _, file := range files {
+
+		if m.File(file.Name) != nil {
+			return nil, fmt.Errorf("duplicate file %s", file.Name)
+		}
+
+		h := sha256.New()
+		counter := &countingReader{r: io.TeeReader(file.Reader, h)}
+
+		if err := t.transport.Put(c, file.Name, counter); err != nil {
+			return nil, fmt.Errorf("put %s: %w", file.Name, err)
+		}
+
+		m.Files = append(m.Files, ManifestFile{
+			Name:   file.Name,
+			Size:   counter.n,
+			SHA256: hex.EncodeToString(h.Sum(nil)),
+		})
+
+	}
+
+	data, err := json.Marshal(m)
+	if err != nil {
+		return nil, err
+	}
+
+	envelope, err := godsse.Sign(ManifestPayloadType, data, t.signers...)
+	if err != nil {
+		return nil, err
+	}
+
+	signature, err := envelope.Marshal()
+	if err != nil {
+		return nil, err
+	}
+
+	if err := t.transport.Put(c, manifest, bytes.NewReader(data)); err != nil {
+		return nil, fmt.Errorf("put %s: %w", manifest, err)
+	}
+
+	if err := t.transport.Put(c, manifest+SignatureSuffix, bytes.NewReader(signature)); err != nil {
+		return nil, fmt.Errorf("put %s: %w", manifest+SignatureSuffix, err)
+	}
+
+	return m, nil
+
+}
+
+// Fetch downloads the _manifest_ and its detached signature and returns the manifest when
+// signed by the trusted keys.
+func (t *Transfer) Fetch(c context.Context, manifest string) (*Manifest, error) {
+
+	data, err := t.get(c, manifest)
+	if err != nil {
+		return nil, err
+	}
+
+	signature, err := t.get(c, manifest+SignatureSuffix)
+	if err != nil {
+		return nil, err
+	}
+
+	envelope, err := godsse.Unmarshal(signature)
+	if err != nil {
+		return nil, err
+	}
+
+	if envelope.PayloadType != ManifestPayloadType || !bytes.Equal(envelope.Payload, data) {
+		return nil, fmt.Errorf("signature of %s is not for the manifest", manifest)
+	}
+
+	if _, err := envelope.Verify(t.trusted, t.threshold); err != nil {
+		return nil, fmt.Errorf("verify %s: %w", manifest, err)
+	}
+
+	var m Manifest
+
+	if err := json.Unmarshal(data, &m); err != nil {
+		return nil, err
+	}
+
+	return &m, nil
+
+}
+
+// Download writes the file _name_ of the verified _manifest_, see `Fetch`, onto _w_.
+//
+// The file is streamed, hence _w_ has received the content before the digest is checked. The
+// content must be discarded when `ErrDigestMismatch` is returned.
+func (t *Transfer) Download(c context.Context, manifest *Manifest, name string, w io.Writer) error {
+
+	file := manifest.File(name)
+	if file == nil {
+		return fmt.Errorf("%w: %s", ErrNotInManifest, name)
+	}
+
+	r, err := t.transport.Get(c, name)
+	if err != nil {
+		return fmt.Errorf("get %s: %w", name, err)
+	}
+
+	defer r.Close()
+
+	h := sha256.New()
+
+	// Read at most one byte more than the manifest size to detect a longer file
+	n, err := io.Copy(io.MultiWriter(w, h), io.LimitReader(r, file.Size+1))
+	if err != nil {
+		return fmt.Errorf("get %s: %w", name, err)
+	}
+
+	if n != file.Size || hex.EncodeToString(h.Sum(nil)) != file.SHA256 {
+		return fmt.Errorf("%w: %s", ErrDigestMismatch, name)
+	}
+
+	return nil
+
+}
+
+// get reads all of the file _name_.
+func (t *Transfer) get(c context.Context, name string) ([]byte, error) {
+
+	r, err := t.transport.Get(c, name)
+	if err != nil {
+		return nil, fmt.Errorf("get %s: %w", name, err)
+	}
+
+	defer r.Close()
+
+	return io.ReadAll(r)
+
+}
+
+// countingReader counts the bytes read.
+type countingReader struct {
+	r io.Reader
+	n int64
+}
+
+func (r *countingReader) Read(p []byte) (int, error) {
+
+	n, err := r.r.Read(p)
+	r.n += int64(n)
+
+	return n, err
+
+}
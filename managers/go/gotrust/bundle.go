@@ -0,0 +1,233 @@
+package gotrust
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// BundleFormatVersion is the highest trust bundle document format this package produces.
+const BundleFormatVersion = 1
+
+// TrustBundle is a versioned set of trust anchors and revocation lists.
+type TrustBundle struct {
+	// Version is a monotonically increasing version of the bundle content. A client never
+	// accepts a bundle with a lower version than it already has.
+	Version uint64
+	// IssuedAt is when this version of the bundle was created.
+	IssuedAt time.Time
+	// Roots is the trusted root certificates.
+	Roots []*x509.Certificate
+	// Intermediates is intermediate certificates that may be used to build chains.
+	Intermediates []*x509.Certificate
+	// CRLs is _DER_ encoded certificate revocation lists.
+	CRLs [][]byte
+}
+
+// RootPool returns a `*x509.CertPool` with all roots.
+func (b *TrustBundle) RootPool() *x509.CertPool {
+
+	pool := x509.NewCertPool()
+
+	for _, cert := range b.Roots {
+		pool.AddCert(cert)
+	}
+
+	return pool
+
+}
+
+// IntermediatePool returns a `*x509.CertPool` with all intermediates.
+func (b *TrustBundle) IntermediatePool() *x509.CertPool {
+
+	pool := x509.NewCertPool()
+
+	for _, cert := range b.Intermediates {
+		pool.AddCert(cert)
+	}
+
+	return pool
+
+}
+
+// bundleDocument is the signed payload of a `TrustBundle`.
+type bundleDocument struct {
+	Format        int       `json:"format"`
+	Version       uint64    `json:"version"`
+	IssuedAt      time.Time `json:"issued_at"`
+	Roots         [][]byte  `json:"roots"`
+	Intermediates [][]byte  `json:"intermediates"`
+	CRLs          [][]byte  `json:"crls"`
+}
+
+// signedBundle is the document sent on the wire.
+type signedBundle struct {
+	// Payload is the _JSON_ encoded `bundleDocument`.
+	Payload []byte `json:"payload"`
+	// KeyID identifies the key that signed the payload.
+	KeyID string `json:"kid"`
+	// Signature is the signature of the _SHA-256_ of the _Payload_, or for _Ed25519_ of
+	// the _Payload_ itself.
+	Signature []byte `json:"signature"`
+}
+
+// MarshalSignedBundle encodes the _bundle_ and signs it with _signer_.
+func MarshalSignedBundle(bundle *TrustBundle, keyID string, signer crypto.Signer) ([]byte, error) {
+
+	doc := bundleDocument{
+		Format:        BundleFormatVersion,
+		Version:       bundle.Version,
+		IssuedAt:      bundle.IssuedAt.UTC(),
+		Roots:         rawCertificates(bundle.Roots),
+		Intermediates: rawCertificates(bundle.Intermediates),
+		CRLs:          bundle.CRLs,
+	}
+
+	payload, err := json.Marshal(&doc)
+	if err != nil {
+		return nil, err
+	}
+
+	signature, err := signPayload(signer, payload)
+	if err != nil {
+		return nil, err
+	}
+
+	return json.Marshal(&signedBundle{Payload: payload, KeyID: keyID, Signature: signature})
+
+}
+
+// UnmarshalSignedBundle verifies and decodes a document produced by `MarshalSignedBundle`.
+//
+// The _keys_ maps key ids to the public keys that are trusted to sign bundles.
+func UnmarshalSignedBundle(data []byte, keys map[string]crypto.PublicKey) (*TrustBundle, error) {
+
+	var signed signedBundle
+
+	if err := json.Unmarshal(data, &signed); err != nil {
+		return nil, err
+	}
+
+	pub, ok := keys[signed.KeyID]
+	if !ok {
+		return nil, fmt.Errorf("trust bundle signed with unknown key: %s", signed.KeyID)
+	}
+
+	if err := verifyPayload(pub, signed.Payload, signed.Signature); err != nil {
+		return nil, err
+	}
+
+	var doc bundleDocument
+
+	if err := json.Unmarshal(signed.Payload, &doc); err != nil {
+		return nil, err
+	}
+
+	if doc.Format < 1 || doc.Format > BundleFormatVersion {
+		return nil, fmt.Errorf("unsupported trust bundle format: %d", doc.Format)
+	}
+
+	bundle := &TrustBundle{
+		Version:  doc.Version,
+		IssuedAt: doc.IssuedAt,
+		CRLs:     doc.CRLs,
+	}
+
+	var err error
+
+	if bundle.Roots, err = parseCertificates(doc.Roots); err != nil {
+		return nil, err
+	}
+
+	if bundle.Intermediates, err = parseCertificates(doc.Intermediates); err != nil {
+		return nil, err
+	}
+
+	return bundle, nil
+
+}
+
+// signPayload signs the _payload_ with _signer_.
+func signPayload(signer crypto.Signer, payload []byte) ([]byte, error) {
+
+	if _, ok := signer.Public().(ed25519.PublicKey); ok {
+		return signer.Sign(rand.Reader, payload, crypto.Hash(0))
+	}
+
+	digest := sha256.Sum256(payload)
+
+	return signer.Sign(rand.Reader, digest[:], crypto.SHA256)
+
+}
+
+// verifyPayload verifies the _signature_ made by `signPayload`.
+func verifyPayload(pub crypto.PublicKey, payload, signature []byte) error {
+
+	digest := sha256.Sum256(payload)
+
+	switch key := pub.(type) {
+	case *rsa.PublicKey:
+
+		return rsa.VerifyPKCS1v15(key, crypto.SHA256, digest[:], signature)
+
+	case *ecdsa.PublicKey:
+
+		if ecdsa.VerifyASN1(key, digest[:], signature) {
+			return nil
+		}
+
+	case ed25519.PublicKey:
+
+		if ed25519.Verify(key, payload, signature) {
+			return nil
+		}
+
+	default:
+
+		return fmt.Errorf("unsupported trust bundle signing key: %T", pub)
+
+	}
+
+	return fmt.Errorf("invalid trust bundle signature")
+
+}
+
+// rawCertificates returns the _DER_ of each certificate.
+func rawCertificates(certs []*x509.Certificate) [][]byte {
+
+	raw := make([][]byte, len(certs))
+
+	for i := range certs {
+		raw[i] = certs[i].Raw
+	}
+
+	return raw
+
+}
+
+// parseCertificates parses each _DER_ encoded certificate.
+func parseCertificates(raw [][]byte) ([]*x509.Certificate, error) {
+
+	certs := make([]*x509.Certificate, len(raw))
+
+	for i := range raw {
+
+		cert, err := x509.ParseCertificate(raw[i])
+		if err != nil {
+			return nil, err
+		}
+
+		certs[i] = cert
+
+	}
+
+	return certs, nil
+
+}
@@ -0,0 +1,310 @@
+package gotrust
+
+import (
+	"bytes"
+	"context"
+	"crypto"
+	"crypto/sha256"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// ErrBundleVersionReused is returned by `BundleHandler.Publish` when the version of the
+// bundle is already published with a different content.
+var ErrBundleVersionReused = fmt.Errorf("trust bundle version reused with different content")
+
+// BundleMediaType is the media type of a signed trust bundle document.
+const BundleMediaType = "application/vnd.goservice.trust-bundle+json"
+
+// maxBundleSize is the maximum size of a trust bundle document the client accepts.
+const maxBundleSize = 8 * 1024 * 1024
+
+// BundleHandler is a `http.Handler` that publishes the current `TrustBundle`.
+//
+// The bundle version is sent as _ETag_ and a client that already has the current version
+// gets a _304 Not Modified_. The client may state which document formats it understands
+// using the _format_ parameter of the _Accept_ header, e.g.
+// _application/vnd.goservice.trust-bundle+json; format=1_. If none of them are supported
+// a _406 Not Acceptable_ is returned.
+type BundleHandler struct {
+	keyID   string
+	signer  crypto.Signer
+	mu      sync.RWMutex
+	version uint64
+	digest  []byte
+	doc     []byte
+}
+
+// NewBundleHandler creates a handler that signs the published bundles with _signer_ under _keyID_.
+func NewBundleHandler(keyID string, signer crypto.Signer) *BundleHandler {
+	return &BundleHandler{keyID: keyID, signer: signer}
+}
+
+// Publish signs and publishes the _bundle_. It is not possible to publish a bundle with a
+// lower version than the current one.
+//
+// Republishing the current version is a no-op if the content, i.e. the roots, intermediates
+// and revocation lists, is the same and fails with `ErrBundleVersionReused` otherwise, since
+// clients that already have the version would never see the new content.
+func (h *BundleHandler) Publish(bundle *TrustBundle) error {
+
+	digest := bundleDigest(bundle)
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.doc != nil {
+
+		if bundle.Version < h.version {
+			return fmt.Errorf("trust bundle version %d is older than published %d", bundle.Version, h.version)
+		}
+
+		if bundle.Version == h.version {
+
+			if !bytes.Equal(digest, h.digest) {
+				return fmt.Errorf("%w: %d", ErrBundleVersionReused, bundle.Version)
+			}
+
+			return nil
+
+		}
+
+	}
+
+	doc, err := MarshalSignedBundle(bundle, h.keyID, h.signer)
+	if err != nil {
+		return err
+	}
+
+	h.version = bundle.Version
+	h.digest = digest
+	h.doc = doc
+
+	return nil
+
+}
+
+// ServeHTTP implements the `http.Handler` interface.
+func (h *BundleHandler) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+
+	if req.Method != http.MethodGet && req.Method != http.MethodHead {
+		w.Header().Set("Allow", "GET, HEAD")
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	if !acceptsFormat(req.Header.Values("Accept"), BundleFormatVersion) {
+		http.Error(w, "no supported trust bundle format", http.StatusNotAcceptable)
+		return
+	}
+
+	h.mu.RLock()
+	version, doc := h.version, h.doc
+	h.mu.RUnlock()
+
+	if doc == nil {
+		http.Error(w, "no trust bundle published", http.StatusServiceUnavailable)
+		return
+	}
+
+	etag := strconv.Quote(strconv.FormatUint(version, 10))
+
+	w.Header().Set("ETag", etag)
+	w.Header().Set("Cache-Control", "no-cache")
+
+	if req.Header.Get("If-None-Match") == etag {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+
+	w.Header().Set("Content-Type", fmt.Sprintf("%s; format=%d", BundleMediaType, BundleFormatVersion))
+
+	if req.Method == http.MethodGet {
+		_, _ = w.Write(doc)
+	}
+
+}
+
+// bundleDigest returns the _SHA-256_ of the content of _bundle_, each certificate and
+// revocation list is length prefixed.
+func bundleDigest(bundle *TrustBundle) []byte {
+
+	h := sha256.New()
+
+	write := func(items [][]byte) {
+
+		fmt.Fprintf(h, "%d:", len(items))
+
+		for _, item := range items {
+			fmt.Fprintf(h, "%d:", len(item))
+			h.Write(item)
+		}
+
+	}
+
+	write(rawCertificates(bundle.Roots))
+	write(rawCertificates(bundle.Intermediates))
+	write(bundle.CRLs)
+
+	return h.Sum(nil)
+
+}
+
+// acceptsFormat checks if the _accept_ headers allows the trust bundle document _format_.
+//
+// A missing header, wildcards or the media type without a _format_ parameter accepts any format.
+func acceptsFormat(accept []string, format int) bool {
+
+	if len(accept) == 0 {
+		return true
+	}
+
+	for _, header := range accept {
+
+		for _, entry := range strings.Split(header, ",") {
+
+			mediaType, params, err := mime.ParseMediaType(strings.TrimSpace(entry))
+			if err != nil {
+				continue
+			}
+
+			switch mediaType {
+			case "*/*", "application/*", "application/json":
+				return true
+			case BundleMediaType:
+
+				formats, ok := params["format"]
+				if !ok {
+					return true
+				}
+
+				for _, f := range strings.Split(formats, " ") {
+
+					if v, err := strconv.Atoi(f); err == nil && v == format {
+						return true
+					}
+
+				}
+
+			}
+
+		}
+
+	}
+
+	return false
+
+}
+
+// BundleClient fetches and verifies trust bundles published by a `BundleHandler`.
+//
+// It keeps the last accepted bundle and refuses any bundle with a lower version, so a
+// replayed old bundle can not roll back a root rotation.
+type BundleClient struct {
+	url    string
+	keys   map[string]crypto.PublicKey
+	client *http.Client
+	mu     sync.RWMutex
+	etag   string
+	bundle *TrustBundle
+}
+
+// NewBundleClient creates a client for the bundle at _url_.
+//
+// The _keys_ maps key ids to the public keys that are trusted to sign bundles. If _client_
+// is `nil`, `http.DefaultClient` is used.
+func NewBundleClient(url string, keys map[string]crypto.PublicKey, client *http.Client) *BundleClient {
+
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	return &BundleClient{url: url, keys: keys, client: client}
+
+}
+
+// Current returns the last accepted bundle or `nil` if none has been fetched.
+func (c *BundleClient) Current() *TrustBundle {
+
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	return c.bundle
+
+}
+
+// Fetch fetches the bundle and returns it along with _changed_ set to `true` if it is
+// a new version. If the server reports that the bundle is not modified, the current
+// bundle is returned.
+func (c *BundleClient) Fetch(ctx context.Context) (bundle *TrustBundle, changed bool, err error) {
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.url, nil)
+	if err != nil {
+		return nil, false, err
+	}
+
+	req.Header.Set("Accept", fmt.Sprintf("%s; format=%d", BundleMediaType, BundleFormatVersion))
+
+	c.mu.RLock()
+	if c.etag != "" {
+		req.Header.Set("If-None-Match", c.etag)
+	}
+	c.mu.RUnlock()
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return nil, false, err
+	}
+
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotModified {
+		return c.Current(), false, nil
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, false, fmt.Errorf("fetching trust bundle: %s", resp.Status)
+	}
+
+	data, err := ioutil.ReadAll(io.LimitReader(resp.Body, maxBundleSize))
+	if err != nil {
+		return nil, false, err
+	}
+
+	bundle, err = UnmarshalSignedBundle(data, c.keys)
+	if err != nil {
+		return nil, false, err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.bundle != nil {
+
+		if bundle.Version < c.bundle.Version {
+
+			return nil, false, fmt.Errorf(
+				"trust bundle version %d is older than current %d", bundle.Version, c.bundle.Version,
+			)
+
+		}
+
+		if bundle.Version == c.bundle.Version {
+			c.etag = resp.Header.Get("ETag")
+			return c.bundle, false, nil
+		}
+
+	}
+
+	c.bundle = bundle
+	c.etag = resp.Header.Get("ETag")
+
+	return bundle, true, nil
+
+}
@@ -0,0 +1,64 @@
+package gotrust
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"errors"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/mariotoffia/goservice/utils/cryptoutils"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBundleDistribution(t *testing.T) {
+
+	signingKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	rootKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	tmpl, _, err := cryptoutils.NewCertificateTemplate(cryptoutils.CertificateProfileCA, cryptoutils.CertificateSubject{
+		Subject: pkix.Name{CommonName: "root"},
+	})
+	require.NoError(t, err)
+
+	root, err := cryptoutils.CreateSelfSignedCertificate(rand.Reader, tmpl, rootKey)
+	require.NoError(t, err)
+
+	handler := NewBundleHandler("bundle-key", signingKey)
+	require.NoError(t, handler.Publish(&TrustBundle{Version: 2, IssuedAt: time.Now(), Roots: []*x509.Certificate{root}}))
+
+	server := httptest.NewServer(handler)
+	defer server.Close()
+
+	client := NewBundleClient(server.URL, map[string]crypto.PublicKey{"bundle-key": &signingKey.PublicKey}, nil)
+
+	bundle, changed, err := client.Fetch(context.Background())
+	require.NoError(t, err)
+	assert.True(t, changed)
+	assert.Equal(t, uint64(2), bundle.Version)
+	assert.Equal(t, root.Raw, bundle.Roots[0].Raw)
+
+	_, changed, err = client.Fetch(context.Background())
+	require.NoError(t, err)
+	assert.False(t, changed)
+
+	assert.Error(t, handler.Publish(&TrustBundle{Version: 1}))
+
+	assert.NoError(t, handler.Publish(&TrustBundle{Version: 2, IssuedAt: time.Now(), Roots: []*x509.Certificate{root}}))
+	assert.True(t, errors.Is(handler.Publish(&TrustBundle{Version: 2, IssuedAt: time.Now()}), ErrBundleVersionReused))
+
+	untrusted := NewBundleClient(server.URL, map[string]crypto.PublicKey{"bundle-key": &rootKey.PublicKey}, nil)
+
+	_, _, err = untrusted.Fetch(context.Background())
+	assert.Error(t, err)
+}
@@ -0,0 +1,113 @@
+package goverify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+
+	"github.com/mariotoffia/goservice/interfaces/ifcrypto"
+)
+
+// maxResponseSize is the maximum size of a verification response the client accepts.
+const maxResponseSize = 64 * 1024
+
+// ClientOption configures a `Client`.
+type ClientOption func(c *Client)
+
+// WithHTTPClient sets the `http.Client` used to call the verification endpoint.
+func WithHTTPClient(client *http.Client) ClientOption {
+	return func(c *Client) {
+		if client != nil {
+			c.client = client
+		}
+	}
+}
+
+// Client calls a verification endpoint served by `Handler`.
+type Client struct {
+	endpoint string
+	client   *http.Client
+}
+
+// NewClient creates a new `Client` for the verification _endpoint_.
+func NewClient(endpoint string, opts ...ClientOption) *Client {
+
+	c := &Client{endpoint: endpoint, client: http.DefaultClient}
+
+	for _, opt := range opts {
+		opt(c)
+	}
+
+	return c
+
+}
+
+// Verify verifies the _signature_ over _message_ using the key _keyID_ and _alg_.
+//
+// A signature that do not verify returns `ErrInvalidSignature`, all other errors are
+// failures to get a verdict from the service.
+func (c *Client) Verify(
+	ctx context.Context,
+	keyID string,
+	alg ifcrypto.SignAlgorithm,
+	message, signature []byte,
+) error {
+
+	resp, err := c.Do(ctx, &VerifyRequest{KeyID: keyID, Algorithm: alg, Message: message, Signature: signature})
+	if err != nil {
+		return err
+	}
+
+	if !resp.Valid {
+		return fmt.Errorf("%w: %s", ErrInvalidSignature, resp.Error)
+	}
+
+	return nil
+
+}
+
+// Do sends the _req_ and returns the response of the service.
+func (c *Client) Do(ctx context.Context, req *VerifyRequest) (*VerifyResponse, error) {
+
+	body, err := json.Marshal(req)
+	if err != nil {
+		return nil, err
+	}
+
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, c.endpoint, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+
+	httpReq.Header.Set("Content-Type", "application/json")
+	httpReq.Header.Set("Accept", "application/json")
+
+	httpResp, err := c.client.Do(httpReq)
+	if err != nil {
+		return nil, err
+	}
+
+	defer httpResp.Body.Close()
+
+	data, err := ioutil.ReadAll(io.LimitReader(httpResp.Body, maxResponseSize))
+	if err != nil {
+		return nil, err
+	}
+
+	var resp VerifyResponse
+
+	if err := json.Unmarshal(data, &resp); err != nil {
+		return nil, fmt.Errorf("signature verification: %s", httpResp.Status)
+	}
+
+	if httpResp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("signature verification: %s: %s", httpResp.Status, resp.Error)
+	}
+
+	return &resp, nil
+
+}
@@ -0,0 +1,78 @@
+package goverify
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"errors"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/mariotoffia/goservice/interfaces/ifcrypto"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestClientVerifiesAgainstHandler(t *testing.T) {
+
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	edPub, edKey, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+
+	keys := map[string]crypto.PublicKey{
+		"rsa": &rsaKey.PublicKey,
+		"ec":  &ecKey.PublicKey,
+		"ed":  edPub,
+	}
+
+	server := httptest.NewServer(NewHandler(func(c context.Context, keyID string) (crypto.PublicKey, error) {
+		return keys[keyID], nil
+	}))
+
+	defer server.Close()
+
+	client := NewClient(server.URL, WithHTTPClient(server.Client()))
+	ctx := context.Background()
+
+	message := []byte("payload")
+	digest := sha256.Sum256(message)
+
+	pss, err := rsa.SignPSS(rand.Reader, rsaKey, crypto.SHA256, digest[:], nil)
+	require.NoError(t, err)
+
+	ecSig, err := ecdsa.SignASN1(rand.Reader, ecKey, digest[:])
+	require.NoError(t, err)
+
+	assert.NoError(t, client.Verify(ctx, "rsa", ifcrypto.SignAlgorithmRsaPssSha256, message, pss))
+	assert.NoError(t, client.Verify(ctx, "ec", ifcrypto.SignAlgorithmEcdSha256, message, ecSig))
+	assert.NoError(t, client.Verify(ctx, "ed", ifcrypto.SignAlgorithmEd25519, message, ed25519.Sign(edKey, message)))
+
+	resp, err := client.Do(ctx, &VerifyRequest{
+		KeyID: "ec", Algorithm: ifcrypto.SignAlgorithmEcdSha256, Digest: digest[:], Signature: ecSig,
+	})
+
+	require.NoError(t, err)
+	assert.True(t, resp.Valid)
+
+	err = client.Verify(ctx, "ec", ifcrypto.SignAlgorithmEcdSha256, []byte("tampered"), ecSig)
+	assert.True(t, errors.Is(err, ErrInvalidSignature))
+
+	err = client.Verify(ctx, "rsa", ifcrypto.SignAlgorithmEcdSha256, message, pss)
+	assert.True(t, errors.Is(err, ErrInvalidSignature))
+
+	err = client.Verify(ctx, "missing", ifcrypto.SignAlgorithmEcdSha256, message, ecSig)
+	require.Error(t, err)
+	assert.True(t, strings.Contains(err.Error(), "404"))
+
+}
@@ -0,0 +1,86 @@
+package goverify
+
+import (
+	"context"
+	"crypto"
+	"encoding/json"
+	"io"
+	"net/http"
+)
+
+// maxRequestSize is the maximum size of a verification request body.
+const maxRequestSize = 1024 * 1024
+
+// KeyResolver resolves the public key with _keyID_. It returns `nil` key if not found.
+type KeyResolver func(c context.Context, keyID string) (crypto.PublicKey, error)
+
+// Handler is a `http.Handler` that verifies signatures.
+//
+// It accepts a _POST_ with a _JSON_ `VerifyRequest` and responds with a `VerifyResponse`. A
+// signature that do not verify is a _200_ response with _valid_ set to `false`. Malformed
+// requests are _400_, unknown keys are _404_ and key resolve failures are _500_.
+type Handler struct {
+	resolve KeyResolver
+}
+
+// NewHandler creates a new `Handler` that looks up keys using _resolve_.
+func NewHandler(resolve KeyResolver) *Handler {
+	return &Handler{resolve: resolve}
+}
+
+// ServeHTTP implements the `http.Handler` interface.
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+
+	if r.Method != http.MethodPost {
+
+		w.Header().Set("Allow", http.MethodPost)
+		writeJSON(w, http.StatusMethodNotAllowed, VerifyResponse{Error: "method not allowed"})
+
+		return
+
+	}
+
+	var req VerifyRequest
+
+	if err := json.NewDecoder(io.LimitReader(r.Body, maxRequestSize)).Decode(&req); err != nil {
+		writeJSON(w, http.StatusBadRequest, VerifyResponse{Error: "malformed request"})
+		return
+	}
+
+	if req.KeyID == "" || len(req.Signature) == 0 {
+		writeJSON(w, http.StatusBadRequest, VerifyResponse{KeyID: req.KeyID, Error: "key_id and signature are required"})
+		return
+	}
+
+	key, err := h.resolve(r.Context(), req.KeyID)
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, VerifyResponse{KeyID: req.KeyID, Error: "failed to resolve key"})
+		return
+	}
+
+	if key == nil {
+		writeJSON(w, http.StatusNotFound, VerifyResponse{KeyID: req.KeyID, Error: "unknown key"})
+		return
+	}
+
+	resp := VerifyResponse{KeyID: req.KeyID, Valid: true}
+
+	if err := VerifySignature(key, req.Algorithm, req.Message, req.Digest, req.Signature); err != nil {
+		resp.Valid = false
+		resp.Error = err.Error()
+	}
+
+	writeJSON(w, http.StatusOK, resp)
+
+}
+
+// writeJSON writes _v_ as _JSON_ with _status_.
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(status)
+
+	_ = json.NewEncoder(w).Encode(v)
+
+}
@@ -0,0 +1,149 @@
+// Package goverify exposes signature verification as a _HTTP/JSON_ service.
+//
+// It allows components that are not written in _Go_ to verify signatures produced by this
+// module by calling a service instead of re-implementing the signature formats. The
+// `Handler` is the server side and `Client` calls it.
+package goverify
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"fmt"
+
+	"github.com/mariotoffia/goservice/interfaces/ifcrypto"
+)
+
+// ErrInvalidSignature is returned when a signature do not verify.
+var ErrInvalidSignature = ifcrypto.ErrInvalidSignature
+
+// VerifyRequest is the body of a verification request.
+//
+// Either _Message_ or _Digest_ must be set. When _Message_ is set it is hashed using the hash
+// of the _Algorithm_. _Ed25519_ signs the message itself, hence _Digest_ can not be used.
+// All binary fields are standard base64 encoded in _JSON_.
+type VerifyRequest struct {
+	KeyID     string                 `json:"key_id"`
+	Algorithm ifcrypto.SignAlgorithm `json:"algorithm"`
+	Message   []byte                 `json:"message,omitempty"`
+	Digest    []byte                 `json:"digest,omitempty"`
+	Signature []byte                 `json:"signature"`
+}
+
+// VerifyResponse is the body of a verification response.
+type VerifyResponse struct {
+	// Valid is `true` if the signature verified.
+	Valid bool `json:"valid"`
+	// KeyID is the key that was used.
+	KeyID string `json:"key_id"`
+	// Error describes why the signature did not verify, if not _Valid_.
+	Error string `json:"error,omitempty"`
+}
+
+// algorithmHashes maps the algorithms to their hash.
+var algorithmHashes = map[ifcrypto.SignAlgorithm]crypto.Hash{
+	ifcrypto.SignAlgorithmRsaPssSha256:      crypto.SHA256,
+	ifcrypto.SignAlgorithmRsaPssSha384:      crypto.SHA384,
+	ifcrypto.SignAlgorithmRsaPssSha512:      crypto.SHA512,
+	ifcrypto.SignAlgorithmRsaPkcs1V15Sha256: crypto.SHA256,
+	ifcrypto.SignAlgorithmRsaPkcs1V15Sha384: crypto.SHA384,
+	ifcrypto.SignAlgorithmRsaPkcs1V15Sha512: crypto.SHA512,
+	ifcrypto.SignAlgorithmEcdSha256:         crypto.SHA256,
+	ifcrypto.SignAlgorithmEcdSha384:         crypto.SHA384,
+	ifcrypto.SignAlgorithmEcdSha512:         crypto.SHA512,
+	ifcrypto.SignAlgorithmEd25519:           0,
+}
+
+// VerifySignature verifies the _signature_ of the _message_, or the _digest_ if _message_
+// is `nil`, using the public _key_ and _alg_.
+func VerifySignature(
+	key crypto.PublicKey,
+	alg ifcrypto.SignAlgorithm,
+	message, digest, signature []byte,
+) error {
+
+	hash, ok := algorithmHashes[alg]
+	if !ok {
+		return fmt.Errorf("unsupported algorithm: %s", alg)
+	}
+
+	if alg == ifcrypto.SignAlgorithmEd25519 {
+
+		pub, ok := key.(ed25519.PublicKey)
+		if !ok {
+			return fmt.Errorf("algorithm %s requires a ed25519 key, got %T", alg, key)
+		}
+
+		if message == nil {
+			return fmt.Errorf("algorithm %s requires the message", alg)
+		}
+
+		if !ed25519.Verify(pub, message, signature) {
+			return ErrInvalidSignature
+		}
+
+		return nil
+
+	}
+
+	if message != nil {
+
+		h := hash.New()
+		h.Write(message)
+		digest = h.Sum(nil)
+
+	}
+
+	if len(digest) != hash.Size() {
+		return fmt.Errorf("digest must be %d bytes for %s", hash.Size(), alg)
+	}
+
+	switch alg {
+	case ifcrypto.SignAlgorithmRsaPssSha256,
+		ifcrypto.SignAlgorithmRsaPssSha384,
+		ifcrypto.SignAlgorithmRsaPssSha512,
+		ifcrypto.SignAlgorithmRsaPkcs1V15Sha256,
+		ifcrypto.SignAlgorithmRsaPkcs1V15Sha384,
+		ifcrypto.SignAlgorithmRsaPkcs1V15Sha512:
+
+		pub, ok := key.(*rsa.PublicKey)
+		if !ok {
+			return fmt.Errorf("algorithm %s requires a rsa key, got %T", alg, key)
+		}
+
+		var err error
+
+		switch alg {
+		case ifcrypto.SignAlgorithmRsaPssSha256,
+			ifcrypto.SignAlgorithmRsaPssSha384,
+			ifcrypto.SignAlgorithmRsaPssSha512:
+
+			err = rsa.VerifyPSS(pub, hash, digest, signature, nil)
+
+		default:
+
+			err = rsa.VerifyPKCS1v15(pub, hash, digest, signature)
+
+		}
+
+		if err != nil {
+			return ErrInvalidSignature
+		}
+
+		return nil
+
+	}
+
+	pub, ok := key.(*ecdsa.PublicKey)
+	if !ok {
+		return fmt.Errorf("algorithm %s requires a ecdsa key, got %T", alg, key)
+	}
+
+	if !ecdsa.VerifyASN1(pub, digest, signature) {
+		return ErrInvalidSignature
+	}
+
+	return nil
+
+}
@@ -0,0 +1,248 @@
+package sqlcounter
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// tableNameRegexp is the allowed format of the table name since it is part of the statements.
+var tableNameRegexp = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_.]{0,127}$`)
+
+// Placeholder returns the bind parameter placeholder for the _i_:th (one based) parameter.
+type Placeholder func(i int) string
+
+// QuestionMarkPlaceholder is the placeholder used by e.g. _MySQL_ and _SQLite_.
+func QuestionMarkPlaceholder(i int) string {
+	return "?"
+}
+
+// DollarPlaceholder is the placeholder used by _PostgreSQL_.
+func DollarPlaceholder(i int) string {
+	return fmt.Sprintf("$%d", i)
+}
+
+// SQLCounterStore implements `ifcrypto.CounterStore` in a _SQL_ table.
+//
+// The table has two columns, _name_ (primary key) and _value_. Each increment is done in
+// a transaction, hence it relies on the database to make the value durable and to serialize
+// concurrent increments from several processes.
+//
+// NOTE: The _value_ column is a signed 64 bit integer in most databases, thus the counters are
+// limited to _2^63 - 1_.
+type SQLCounterStore struct {
+	db          *sql.DB
+	table       string
+	placeholder Placeholder
+	retryable   func(err error) bool
+}
+
+// SQLCounterStoreOption configures a `SQLCounterStore`.
+type SQLCounterStoreOption func(s *SQLCounterStore)
+
+// WithRetryableError replaces `IsRetryableError` to classify the driver errors of a failed
+// reservation that are retried once, e.g. for a driver that `IsRetryableError` do not know.
+func WithRetryableError(retryable func(err error) bool) SQLCounterStoreOption {
+	return func(s *SQLCounterStore) {
+		s.retryable = retryable
+	}
+}
+
+// NewSQLCounterStore creates a new `SQLCounterStore` that stores the counters in _table_.
+//
+// If _placeholder_ is `nil`, the `QuestionMarkPlaceholder` is used.
+func NewSQLCounterStore(
+	db *sql.DB,
+	table string,
+	placeholder Placeholder,
+	opts ...SQLCounterStoreOption,
+) (*SQLCounterStore, error) {
+
+	if !tableNameRegexp.MatchString(table) {
+		return nil, fmt.Errorf("invalid table name: %s", table)
+	}
+
+	if placeholder == nil {
+		placeholder = QuestionMarkPlaceholder
+	}
+
+	s := &SQLCounterStore{
+		db:          db,
+		table:       table,
+		placeholder: placeholder,
+		retryable:   IsRetryableError,
+	}
+
+	for _, opt := range opts {
+		opt(s)
+	}
+
+	return s, nil
+
+}
+
+// retryableSQLStates are the _SQLSTATE_ codes of a unique violation, a serialization failure
+// and a deadlock.
+var retryableSQLStates = map[string]bool{"23505": true, "40001": true, "40P01": true}
+
+// retryableMessages identifies the same errors for drivers that do not expose the _SQLSTATE_,
+// e.g. _MySQL_ and _SQLite_.
+var retryableMessages = []string{
+	"Error 1062",
+	"Error 1213",
+	"UNIQUE constraint failed",
+	"could not serialize access",
+}
+
+// IsRetryableError returns `true` if _err_ is a unique violation, when two processes race to
+// insert a new counter, or a serialization failure or deadlock. Context cancellation is
+// never retryable.
+//
+// Drivers exposing a `SQLState() string` method, e.g. _pgx_ and _lib/pq_, are classified by
+// the _SQLSTATE_, others by the error message.
+func IsRetryableError(err error) bool {
+
+	if err == nil || errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+
+	var state interface{ SQLState() string }
+	if errors.As(err, &state) {
+		return retryableSQLStates[state.SQLState()]
+	}
+
+	for _, msg := range retryableMessages {
+
+		if strings.Contains(err.Error(), msg) {
+			return true
+		}
+
+	}
+
+	return false
+
+}
+
+// CreateTable creates the counter table if it do not exist.
+func (s *SQLCounterStore) CreateTable(c context.Context) error {
+
+	_, err := s.db.ExecContext(c, fmt.Sprintf(
+		"CREATE TABLE IF NOT EXISTS %s (name VARCHAR(128) NOT NULL PRIMARY KEY, value BIGINT NOT NULL)",
+		s.table,
+	))
+
+	return err
+
+}
+
+// Next implements the `ifcrypto.CounterStore` interface.
+func (s *SQLCounterStore) Next(c context.Context, name string) (uint64, error) {
+	return s.Reserve(c, name, 1)
+}
+
+// Reserve implements the `ifcrypto.CounterStore` interface. A failed reservation is retried
+// once if the error is retryable, see `WithRetryableError`.
+func (s *SQLCounterStore) Reserve(c context.Context, name string, n uint64) (uint64, error) {
+
+	if n == 0 {
+		return 0, fmt.Errorf("must reserve at least one value")
+	}
+
+	if n > 1<<62 {
+		return 0, fmt.Errorf("reservation too large: %d", n)
+	}
+
+	first, err := s.reserve(c, name, n)
+
+	if err != nil && c.Err() == nil && s.retryable(err) {
+
+		// Two processes may race to insert a new counter, the loser retries once
+		// and will then update the row inserted by the winner.
+		first, err = s.reserve(c, name, n)
+
+	}
+
+	return first, err
+
+}
+
+// Current implements the `ifcrypto.CounterStore` interface.
+func (s *SQLCounterStore) Current(c context.Context, name string) (uint64, error) {
+
+	var value int64
+
+	err := s.db.QueryRowContext(c, fmt.Sprintf(
+		"SELECT value FROM %s WHERE name = %s", s.table, s.placeholder(1),
+	), name).Scan(&value)
+
+	if err == sql.ErrNoRows {
+		return 0, nil
+	}
+
+	if err != nil {
+		return 0, err
+	}
+
+	return uint64(value), nil
+
+}
+
+// reserve does a single attempt to reserve _n_ values in a transaction.
+func (s *SQLCounterStore) reserve(c context.Context, name string, n uint64) (first uint64, err error) {
+
+	tx, err := s.db.BeginTx(c, nil)
+	if err != nil {
+		return 0, err
+	}
+
+	defer func() {
+
+		if err != nil {
+			_ = tx.Rollback()
+		}
+
+	}()
+
+	res, err := tx.ExecContext(c, fmt.Sprintf(
+		"UPDATE %s SET value = value + %s WHERE name = %s",
+		s.table, s.placeholder(1), s.placeholder(2),
+	), int64(n), name)
+
+	if err != nil {
+		return 0, err
+	}
+
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return 0, err
+	}
+
+	if affected == 0 {
+
+		if _, err = tx.ExecContext(c, fmt.Sprintf(
+			"INSERT INTO %s (name, value) VALUES (%s, %s)",
+			s.table, s.placeholder(1), s.placeholder(2),
+		), name, int64(n)); err != nil {
+			return 0, err
+		}
+
+	}
+
+	var last int64
+
+	if err = tx.QueryRowContext(c, fmt.Sprintf(
+		"SELECT value FROM %s WHERE name = %s", s.table, s.placeholder(1),
+	), name).Scan(&last); err != nil {
+		return 0, err
+	}
+
+	if err = tx.Commit(); err != nil {
+		return 0, err
+	}
+
+	return uint64(last) - n + 1, nil
+
+}
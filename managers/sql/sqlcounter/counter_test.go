@@ -0,0 +1,181 @@
+package sqlcounter
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"sync"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var (
+	updateSQL = regexp.QuoteMeta("UPDATE counters SET value = value + $1 WHERE name = $2")
+	insertSQL = regexp.QuoteMeta("INSERT INTO counters (name, value) VALUES ($1, $2)")
+	selectSQL = regexp.QuoteMeta("SELECT value FROM counters WHERE name = $1")
+)
+
+// sqlStateError is a driver error with a _SQLSTATE_, e.g. _pgx_.
+type sqlStateError string
+
+func (e sqlStateError) Error() string {
+	return "sqlstate " + string(e)
+}
+
+func (e sqlStateError) SQLState() string {
+	return string(e)
+}
+
+func newTestStore(t *testing.T) (*SQLCounterStore, sqlmock.Sqlmock) {
+
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+
+	t.Cleanup(func() {
+
+		assert.NoError(t, mock.ExpectationsWereMet())
+		db.Close()
+
+	})
+
+	store, err := NewSQLCounterStore(db, "counters", DollarPlaceholder)
+	require.NoError(t, err)
+
+	return store, mock
+
+}
+
+func TestReserveInsertsNewCounter(t *testing.T) {
+
+	store, mock := newTestStore(t)
+
+	mock.ExpectBegin()
+	mock.ExpectExec(updateSQL).WithArgs(int64(10), "nonce").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(insertSQL).WithArgs("nonce", int64(10)).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectQuery(selectSQL).WithArgs("nonce").WillReturnRows(sqlmock.NewRows([]string{"value"}).AddRow(10))
+	mock.ExpectCommit()
+
+	first, err := store.Reserve(context.Background(), "nonce", 10)
+	require.NoError(t, err)
+	assert.Equal(t, uint64(1), first)
+
+}
+
+func TestReserveRetriesLostInsertRace(t *testing.T) {
+
+	store, mock := newTestStore(t)
+
+	// The other process inserted the counter between the update and the insert
+	mock.ExpectBegin()
+	mock.ExpectExec(updateSQL).WithArgs(int64(1), "nonce").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(insertSQL).WithArgs("nonce", int64(1)).WillReturnError(sqlStateError("23505"))
+	mock.ExpectRollback()
+
+	mock.ExpectBegin()
+	mock.ExpectExec(updateSQL).WithArgs(int64(1), "nonce").WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectQuery(selectSQL).WithArgs("nonce").WillReturnRows(sqlmock.NewRows([]string{"value"}).AddRow(2))
+	mock.ExpectCommit()
+
+	first, err := store.Next(context.Background(), "nonce")
+	require.NoError(t, err)
+	assert.Equal(t, uint64(2), first)
+
+}
+
+func TestReserveConcurrently(t *testing.T) {
+
+	store, mock := newTestStore(t)
+	mock.MatchExpectationsInOrder(false)
+
+	const n = 8
+
+	for i := 1; i <= n; i++ {
+
+		mock.ExpectBegin()
+		mock.ExpectExec(updateSQL).WithArgs(int64(1), "nonce").WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectQuery(selectSQL).WithArgs("nonce").WillReturnRows(sqlmock.NewRows([]string{"value"}).AddRow(i))
+		mock.ExpectCommit()
+
+	}
+
+	var (
+		wg     sync.WaitGroup
+		mu     sync.Mutex
+		values []int
+	)
+
+	for i := 0; i < n; i++ {
+
+		wg.Add(1)
+
+		go func() {
+
+			defer wg.Done()
+
+			value, err := store.Next(context.Background(), "nonce")
+			assert.NoError(t, err)
+
+			mu.Lock()
+			values = append(values, int(value))
+			mu.Unlock()
+
+		}()
+
+	}
+
+	wg.Wait()
+	sort.Ints(values)
+
+	assert.Equal(t, []int{1, 2, 3, 4, 5, 6, 7, 8}, values)
+
+}
+
+func TestReserveDoNotRetryNonRetryableErrors(t *testing.T) {
+
+	store, mock := newTestStore(t)
+
+	// A retry would fail with a unexpected call error instead of the original error
+	mock.ExpectBegin()
+	mock.ExpectExec(updateSQL).WithArgs(int64(1<<62), "nonce").WillReturnError(sqlStateError("22003"))
+	mock.ExpectRollback()
+
+	_, err := store.Reserve(context.Background(), "nonce", 1<<62)
+	assert.Equal(t, sqlStateError("22003"), err, "overflow of the value column")
+
+	mock.ExpectBegin()
+	mock.ExpectExec(updateSQL).WithArgs(int64(1), "nonce").WillReturnError(fmt.Errorf("driver: %w", context.Canceled))
+	mock.ExpectRollback()
+
+	_, err = store.Reserve(context.Background(), "nonce", 1)
+	assert.True(t, errors.Is(err, context.Canceled))
+
+	_, err = store.Reserve(context.Background(), "nonce", 1<<62+1)
+	assert.Error(t, err, "reservation too large")
+
+	_, err = store.Reserve(context.Background(), "nonce", 0)
+	assert.Error(t, err)
+
+}
+
+func TestIsRetryableError(t *testing.T) {
+
+	for err, retryable := range map[error]bool{
+		sqlStateError("23505"):                              true,
+		sqlStateError("40001"):                              true,
+		sqlStateError("40P01"):                              true,
+		sqlStateError("22003"):                              false,
+		fmt.Errorf("Error 1062 (23000): Duplicate entry"):   true,
+		fmt.Errorf("UNIQUE constraint failed: counters"):    true,
+		fmt.Errorf("wrapped: %w", context.Canceled):         false,
+		fmt.Errorf("wrapped: %w", context.DeadlineExceeded): false,
+		fmt.Errorf("connection refused"):                    false,
+	} {
+		assert.Equal(t, retryable, IsRetryableError(err), err.Error())
+	}
+
+}
@@ -0,0 +1,686 @@
+package cryptoutils
+
+import (
+	"bytes"
+	"encoding/asn1"
+	"encoding/pem"
+	"fmt"
+	"io"
+	"math/big"
+	"strings"
+	"unicode/utf16"
+)
+
+// ErrASN1Malformed is returned when the input to `ParseASN1` is not valid _BER_ or _DER_.
+var ErrASN1Malformed = fmt.Errorf("malformed ASN.1")
+
+// asn1MaxDepth is the maximum nesting depth parsed by `ParseASN1`.
+const asn1MaxDepth = 64
+
+// asn1HexLimit is the number of bytes of a hex value printed by `WriteASN1`.
+const asn1HexLimit = 32
+
+// OIDNames maps dotted object identifiers to the names that `WriteASN1` resolves them to.
+//
+// Partner or vendor specific identifiers may be added to resolve them as well.
+var OIDNames = map[string]string{
+	// PKCS #1 and RSA
+	"1.2.840.113549.1.1.1":  "rsaEncryption",
+	"1.2.840.113549.1.1.4":  "md5WithRSAEncryption",
+	"1.2.840.113549.1.1.5":  "sha1WithRSAEncryption",
+	"1.2.840.113549.1.1.7":  "rsaesOaep",
+	"1.2.840.113549.1.1.8":  "mgf1",
+	"1.2.840.113549.1.1.10": "rsassaPss",
+	"1.2.840.113549.1.1.11": "sha256WithRSAEncryption",
+	"1.2.840.113549.1.1.12": "sha384WithRSAEncryption",
+	"1.2.840.113549.1.1.13": "sha512WithRSAEncryption",
+	// Elliptic curves, EdDSA and DSA
+	"1.2.840.10045.2.1":   "ecPublicKey",
+	"1.2.840.10045.3.1.7": "prime256v1",
+	"1.3.132.0.33":        "secp224r1",
+	"1.3.132.0.34":        "secp384r1",
+	"1.3.132.0.35":        "secp521r1",
+	"1.3.132.0.10":        "secp256k1",
+	"1.2.840.10045.4.1":   "ecdsa-with-SHA1",
+	"1.2.840.10045.4.3.2": "ecdsa-with-SHA256",
+	"1.2.840.10045.4.3.3": "ecdsa-with-SHA384",
+	"1.2.840.10045.4.3.4": "ecdsa-with-SHA512",
+	"1.3.101.110":         "X25519",
+	"1.3.101.111":         "X448",
+	"1.3.101.112":         "Ed25519",
+	"1.3.101.113":         "Ed448",
+	"1.2.840.10040.4.1":   "dsa",
+	// Digests and MACs
+	"1.2.840.113549.2.5":      "md5",
+	"1.3.14.3.2.26":           "sha1",
+	"2.16.840.1.101.3.4.2.1":  "sha256",
+	"2.16.840.1.101.3.4.2.2":  "sha384",
+	"2.16.840.1.101.3.4.2.3":  "sha512",
+	"2.16.840.1.101.3.4.2.4":  "sha224",
+	"1.2.840.113549.2.7":      "hmacWithSHA1",
+	"1.2.840.113549.2.9":      "hmacWithSHA256",
+	"1.2.840.113549.2.10":     "hmacWithSHA384",
+	"1.2.840.113549.2.11":     "hmacWithSHA512",
+	"2.16.840.1.101.3.4.1.2":  "aes128-CBC",
+	"2.16.840.1.101.3.4.1.5":  "aes128-wrap",
+	"2.16.840.1.101.3.4.1.6":  "aes128-GCM",
+	"2.16.840.1.101.3.4.1.22": "aes192-CBC",
+	"2.16.840.1.101.3.4.1.42": "aes256-CBC",
+	"2.16.840.1.101.3.4.1.45": "aes256-wrap",
+	"2.16.840.1.101.3.4.1.46": "aes256-GCM",
+	"1.2.840.113549.3.7":      "des-ede3-cbc",
+	// PKCS #5, #7 / CMS, #9 and #12
+	"1.2.840.113549.1.5.12":      "pbkdf2",
+	"1.2.840.113549.1.5.13":      "pbes2",
+	"1.2.840.113549.1.7.1":       "data",
+	"1.2.840.113549.1.7.2":       "signedData",
+	"1.2.840.113549.1.7.3":       "envelopedData",
+	"1.2.840.113549.1.7.5":       "digestedData",
+	"1.2.840.113549.1.7.6":       "encryptedData",
+	"1.2.840.113549.1.9.1":       "emailAddress",
+	"1.2.840.113549.1.9.3":       "contentType",
+	"1.2.840.113549.1.9.4":       "messageDigest",
+	"1.2.840.113549.1.9.5":       "signingTime",
+	"1.2.840.113549.1.9.7":       "challengePassword",
+	"1.2.840.113549.1.9.14":      "extensionRequest",
+	"1.2.840.113549.1.9.20":      "friendlyName",
+	"1.2.840.113549.1.9.21":      "localKeyID",
+	"1.2.840.113549.1.9.22.1":    "x509Certificate",
+	"1.2.840.113549.1.9.16.1.4":  "tstInfo",
+	"1.2.840.113549.1.9.16.1.23": "authEnvelopedData",
+	"1.2.840.113549.1.9.16.2.47": "signingCertificateV2",
+	"1.2.840.113549.1.12.1.3":    "pbeWithSHAAnd3-KeyTripleDES-CBC",
+	"1.2.840.113549.1.12.1.6":    "pbeWithSHAAnd40BitRC2-CBC",
+	"1.2.840.113549.1.12.10.1.1": "keyBag",
+	"1.2.840.113549.1.12.10.1.2": "pkcs8ShroudedKeyBag",
+	"1.2.840.113549.1.12.10.1.3": "certBag",
+	"1.2.840.113549.1.12.10.1.5": "secretBag",
+	"1.2.840.113549.1.12.10.1.6": "safeContentsBag",
+	"0.9.2342.19200300.100.1.1":  "userId",
+	"0.9.2342.19200300.100.1.25": "domainComponent",
+	// X.500 attributes
+	"2.5.4.3":  "commonName",
+	"2.5.4.5":  "serialNumber",
+	"2.5.4.6":  "countryName",
+	"2.5.4.7":  "localityName",
+	"2.5.4.8":  "stateOrProvinceName",
+	"2.5.4.9":  "streetAddress",
+	"2.5.4.10": "organizationName",
+	"2.5.4.11": "organizationalUnitName",
+	"2.5.4.12": "title",
+	"2.5.4.42": "givenName",
+	"2.5.4.97": "organizationIdentifier",
+	// X.509 extensions
+	"2.5.29.14":   "subjectKeyIdentifier",
+	"2.5.29.15":   "keyUsage",
+	"2.5.29.17":   "subjectAltName",
+	"2.5.29.18":   "issuerAltName",
+	"2.5.29.19":   "basicConstraints",
+	"2.5.29.20":   "cRLNumber",
+	"2.5.29.21":   "cRLReason",
+	"2.5.29.30":   "nameConstraints",
+	"2.5.29.31":   "cRLDistributionPoints",
+	"2.5.29.32":   "certificatePolicies",
+	"2.5.29.32.0": "anyPolicy",
+	"2.5.29.35":   "authorityKeyIdentifier",
+	"2.5.29.37":   "extKeyUsage",
+	// PKIX
+	"1.3.6.1.5.5.7.1.1":       "authorityInfoAccess",
+	"1.3.6.1.5.5.7.3.1":       "serverAuth",
+	"1.3.6.1.5.5.7.3.2":       "clientAuth",
+	"1.3.6.1.5.5.7.3.3":       "codeSigning",
+	"1.3.6.1.5.5.7.3.4":       "emailProtection",
+	"1.3.6.1.5.5.7.3.8":       "timeStamping",
+	"1.3.6.1.5.5.7.3.9":       "OCSPSigning",
+	"1.3.6.1.5.5.7.48.1":      "ocsp",
+	"1.3.6.1.5.5.7.48.1.1":    "basicOCSPResponse",
+	"1.3.6.1.5.5.7.48.2":      "caIssuers",
+	"1.3.6.1.4.1.11129.2.4.2": "ctPrecertificateSCTs",
+}
+
+// OIDName returns the name of _oid_ in `OIDNames` or a empty string if unknown.
+func OIDName(oid asn1.ObjectIdentifier) string {
+	return OIDNames[oid.String()]
+}
+
+// ASN1Node is a single _BER_ / _DER_ element parsed by `ParseASN1`.
+type ASN1Node struct {
+	// Offset is the offset of the element header within the parsed input.
+	Offset int
+	// HeaderLength is the number of bytes of the tag and length.
+	HeaderLength int
+	// Class is the `asn1.ClassUniversal`, `asn1.ClassApplication`, `asn1.ClassContextSpecific`
+	// or `asn1.ClassPrivate`.
+	Class int
+	Tag   int
+	// Constructed is `true` when the element contains other elements.
+	Constructed bool
+	// Indefinite is `true` when the element used the _BER_ indefinite length form.
+	Indefinite bool
+	// Encapsulated is `true` when the children were parsed from the content of a _OCTET STRING_
+	// or _BIT STRING_, e.g. the value of a _X.509_ extension.
+	Encapsulated bool
+	// Bytes is the content, excluding the end-of-contents of a indefinite length element.
+	Bytes    []byte
+	Children []*ASN1Node
+}
+
+// TypeName returns the name of the element type, e.g. _SEQUENCE_ or _[0]_.
+func (n *ASN1Node) TypeName() string {
+
+	switch n.Class {
+	case asn1.ClassApplication:
+		return fmt.Sprintf("[APPLICATION %d]", n.Tag)
+	case asn1.ClassContextSpecific:
+		return fmt.Sprintf("[%d]", n.Tag)
+	case asn1.ClassPrivate:
+		return fmt.Sprintf("[PRIVATE %d]", n.Tag)
+	}
+
+	if name, ok := asn1UniversalNames[n.Tag]; ok {
+		return name
+	}
+
+	return fmt.Sprintf("UNIVERSAL %d", n.Tag)
+
+}
+
+// Value returns the value of a primitive element as text, e.g. the dotted object identifier and
+// its name, a integer or a string. Binary values are hex encoded and truncated.
+func (n *ASN1Node) Value() string {
+
+	if n.Constructed || n.Encapsulated {
+		return ""
+	}
+
+	if n.Class != asn1.ClassUniversal {
+		return asn1Hex(n.Bytes)
+	}
+
+	switch n.Tag {
+	case asn1.TagBoolean:
+
+		if len(n.Bytes) == 1 {
+			return fmt.Sprintf("%t", n.Bytes[0] != 0)
+		}
+
+	case asn1.TagInteger, asn1.TagEnum:
+
+		if len(n.Bytes) == 0 {
+			break
+		}
+
+		v := new(big.Int).SetBytes(n.Bytes)
+
+		if n.Bytes[0]&0x80 != 0 {
+			v.Sub(v, new(big.Int).Lsh(big.NewInt(1), uint(len(n.Bytes)*8)))
+		}
+
+		if v.BitLen() <= 64 {
+			return v.String()
+		}
+
+		return asn1Hex(n.Bytes)
+
+	case asn1.TagBitString:
+
+		if len(n.Bytes) > 0 {
+			return fmt.Sprintf("unused=%d %s", n.Bytes[0], asn1Hex(n.Bytes[1:]))
+		}
+
+	case asn1.TagNull:
+
+		return ""
+
+	case asn1.TagOID:
+
+		oid, err := parseASN1OID(n.Bytes)
+		if err != nil {
+			break
+		}
+
+		if name := OIDName(oid); name != "" {
+			return fmt.Sprintf("%s (%s)", oid, name)
+		}
+
+		return oid.String()
+
+	case asn1.TagUTF8String, asn1.TagPrintableString, asn1.TagT61String, asn1.TagIA5String,
+		asn1.TagNumericString, 26 /* VisibleString */, asn1.TagUTCTime, asn1.TagGeneralizedTime:
+
+		return fmt.Sprintf("%q", string(n.Bytes))
+
+	case asn1.TagBMPString:
+
+		if len(n.Bytes)%2 == 0 {
+
+			s := make([]uint16, len(n.Bytes)/2)
+			for i := range s {
+				s[i] = uint16(n.Bytes[2*i])<<8 | uint16(n.Bytes[2*i+1])
+			}
+
+			return fmt.Sprintf("%q", string(utf16.Decode(s)))
+
+		}
+
+	}
+
+	return asn1Hex(n.Bytes)
+
+}
+
+// ASN1Document is a parsed _PEM_ block or _DER_ input.
+type ASN1Document struct {
+	// Type is the _PEM_ block type or _DER_.
+	Type string
+	// Headers is the _PEM_ headers, if any.
+	Headers map[string]string
+	Nodes   []*ASN1Node
+}
+
+// InspectASN1 parses _data_ for debugging, e.g. keys, certificates, _CSR_ or _CMS_ structures
+// from a partner. If _data_ contains _PEM_ blocks all blocks are parsed, otherwise it is parsed
+// as _DER_ or _BER_.
+func InspectASN1(data []byte) ([]ASN1Document, error) {
+
+	if !bytes.Contains(data, []byte("-----BEGIN ")) {
+
+		nodes, err := ParseASN1(data)
+		if err != nil {
+			return nil, err
+		}
+
+		return []ASN1Document{{Type: "DER", Nodes: nodes}}, nil
+
+	}
+
+	docs := []ASN1Document{}
+
+	for {
+
+		var block *pem.Block
+
+		block, data = pem.Decode(data)
+		if block == nil {
+			break
+		}
+
+		nodes, err := ParseASN1(block.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("PEM block %s: %w", block.Type, err)
+		}
+
+		docs = append(docs, ASN1Document{Type: block.Type, Headers: block.Headers, Nodes: nodes})
+
+	}
+
+	if len(docs) == 0 {
+		return nil, fmt.Errorf("no PEM blocks found")
+	}
+
+	return docs, nil
+
+}
+
+// ParseASN1 parses all elements of the _BER_ or _DER_ encoded _data_ without any knowledge of
+// the structure. _OCTET STRING_ and _BIT STRING_ values that contain a complete constructed
+// element are parsed as well, see `ASN1Node.Encapsulated`.
+func ParseASN1(data []byte) ([]*ASN1Node, error) {
+
+	if len(data) == 0 {
+		return nil, fmt.Errorf("%w: empty input", ErrASN1Malformed)
+	}
+
+	return parseASN1(data, 0, 0)
+
+}
+
+// WriteASN1 pretty-prints the _nodes_ onto _w_, one element per line with the offset, depth,
+// header length, content length and value in the style of _openssl asn1parse_.
+func WriteASN1(w io.Writer, nodes []*ASN1Node) error {
+
+	for _, n := range nodes {
+
+		if err := writeASN1Node(w, n, 0); err != nil {
+			return err
+		}
+
+	}
+
+	return nil
+
+}
+
+// PrintASN1 inspects _data_ using `InspectASN1` and writes all documents onto _w_ using
+// `WriteASN1`, each _PEM_ block is preceded by its type.
+func PrintASN1(w io.Writer, data []byte) error {
+
+	docs, err := InspectASN1(data)
+	if err != nil {
+		return err
+	}
+
+	for i, doc := range docs {
+
+		if i > 0 {
+
+			if _, err := fmt.Fprintln(w); err != nil {
+				return err
+			}
+
+		}
+
+		if _, err := fmt.Fprintf(w, "# %s\n", doc.Type); err != nil {
+			return err
+		}
+
+		if err := WriteASN1(w, doc.Nodes); err != nil {
+			return err
+		}
+
+	}
+
+	return nil
+
+}
+
+var asn1UniversalNames = map[int]string{
+	0:                       "EOC",
+	asn1.TagBoolean:         "BOOLEAN",
+	asn1.TagInteger:         "INTEGER",
+	asn1.TagBitString:       "BIT STRING",
+	asn1.TagOctetString:     "OCTET STRING",
+	asn1.TagNull:            "NULL",
+	asn1.TagOID:             "OBJECT IDENTIFIER",
+	asn1.TagEnum:            "ENUMERATED",
+	asn1.TagUTF8String:      "UTF8String",
+	asn1.TagSequence:        "SEQUENCE",
+	asn1.TagSet:             "SET",
+	asn1.TagNumericString:   "NumericString",
+	asn1.TagPrintableString: "PrintableString",
+	asn1.TagT61String:       "T61String",
+	asn1.TagIA5String:       "IA5String",
+	asn1.TagUTCTime:         "UTCTime",
+	asn1.TagGeneralizedTime: "GeneralizedTime",
+	26:                      "VisibleString",
+	28:                      "UniversalString",
+	asn1.TagBMPString:       "BMPString",
+}
+
+// parseASN1 parses all elements of _data_ that starts at _offset_ in the input.
+func parseASN1(data []byte, offset, depth int) ([]*ASN1Node, error) {
+
+	nodes := []*ASN1Node{}
+
+	for pos := 0; pos < len(data); {
+
+		node, used, err := parseASN1Element(data[pos:], offset+pos, depth)
+		if err != nil {
+			return nil, err
+		}
+
+		nodes = append(nodes, node)
+		pos += used
+
+	}
+
+	return nodes, nil
+
+}
+
+// parseASN1Element parses the first element of _data_ and returns it along with the number of
+// bytes it occupies.
+func parseASN1Element(data []byte, offset, depth int) (*ASN1Node, int, error) {
+
+	if depth > asn1MaxDepth {
+		return nil, 0, fmt.Errorf("%w: nesting too deep at offset %d", ErrASN1Malformed, offset)
+	}
+
+	if len(data) < 2 {
+		return nil, 0, fmt.Errorf("%w: truncated header at offset %d", ErrASN1Malformed, offset)
+	}
+
+	n := &ASN1Node{
+		Offset:      offset,
+		Class:       int(data[0] >> 6),
+		Constructed: data[0]&0x20 != 0,
+		Tag:         int(data[0] & 0x1f),
+	}
+
+	i := 1
+
+	if n.Tag == 0x1f {
+
+		n.Tag = 0
+
+		for {
+
+			if i >= len(data) || n.Tag > 1<<23 {
+				return nil, 0, fmt.Errorf("%w: invalid tag at offset %d", ErrASN1Malformed, offset)
+			}
+
+			b := data[i]
+			i++
+
+			n.Tag = n.Tag<<7 | int(b&0x7f)
+
+			if b&0x80 == 0 {
+				break
+			}
+
+		}
+
+	}
+
+	if i >= len(data) {
+		return nil, 0, fmt.Errorf("%w: truncated header at offset %d", ErrASN1Malformed, offset)
+	}
+
+	length := int(data[i])
+	i++
+
+	if length == 0x80 {
+		return parseASN1Indefinite(n, data, i, depth)
+	}
+
+	if length > 0x80 {
+
+		count := length & 0x7f
+
+		if count > 4 || i+count > len(data) {
+			return nil, 0, fmt.Errorf("%w: invalid length at offset %d", ErrASN1Malformed, offset)
+		}
+
+		length = 0
+
+		for ; count > 0; count-- {
+			length = length<<8 | int(data[i])
+			i++
+		}
+
+	}
+
+	if length < 0 || length > len(data)-i {
+		return nil, 0, fmt.Errorf("%w: truncated content at offset %d", ErrASN1Malformed, offset)
+	}
+
+	n.HeaderLength = i
+	n.Bytes = data[i : i+length]
+
+	if n.Constructed {
+
+		children, err := parseASN1(n.Bytes, offset+i, depth+1)
+		if err != nil {
+			return nil, 0, err
+		}
+
+		n.Children = children
+
+	} else if n.Class == asn1.ClassUniversal && (n.Tag == asn1.TagOctetString || n.Tag == asn1.TagBitString) {
+		parseASN1Encapsulated(n, offset+i, depth)
+	}
+
+	return n, i + length, nil
+
+}
+
+// parseASN1Indefinite parses the children of the indefinite length _n_ whose content starts at
+// _start_ of _data_, until the end-of-contents.
+func parseASN1Indefinite(n *ASN1Node, data []byte, start, depth int) (*ASN1Node, int, error) {
+
+	if !n.Constructed {
+		return nil, 0, fmt.Errorf("%w: indefinite length primitive at offset %d", ErrASN1Malformed, n.Offset)
+	}
+
+	n.Indefinite = true
+	n.HeaderLength = start
+	n.Children = []*ASN1Node{}
+
+	for pos := start; ; {
+
+		if pos+2 > len(data) {
+			return nil, 0, fmt.Errorf("%w: missing end-of-contents at offset %d", ErrASN1Malformed, n.Offset)
+		}
+
+		if data[pos] == 0 && data[pos+1] == 0 {
+
+			n.Bytes = data[start:pos]
+			return n, pos + 2, nil
+
+		}
+
+		child, used, err := parseASN1Element(data[pos:], n.Offset+pos, depth+1)
+		if err != nil {
+			return nil, 0, err
+		}
+
+		n.Children = append(n.Children, child)
+		pos += used
+
+	}
+
+}
+
+// parseASN1Encapsulated parses the content of the _OCTET STRING_ or _BIT STRING_ _n_ as children
+// if it is a complete constructed element. Otherwise _n_ is left as is.
+func parseASN1Encapsulated(n *ASN1Node, offset, depth int) {
+
+	content := n.Bytes
+
+	if n.Tag == asn1.TagBitString {
+
+		if len(content) == 0 || content[0] != 0 {
+			return
+		}
+
+		content = content[1:]
+		offset++
+
+	}
+
+	if len(content) < 2 || content[0]&0x20 == 0 {
+		return
+	}
+
+	children, err := parseASN1(content, offset, depth+1)
+	if err != nil {
+		return
+	}
+
+	n.Children = children
+	n.Encapsulated = true
+
+}
+
+// parseASN1OID decodes the content octets of a object identifier.
+func parseASN1OID(data []byte) (asn1.ObjectIdentifier, error) {
+
+	if len(data) == 0 || data[len(data)-1]&0x80 != 0 {
+		return nil, fmt.Errorf("%w: invalid object identifier", ErrASN1Malformed)
+	}
+
+	oid := asn1.ObjectIdentifier{}
+	v := 0
+
+	for _, b := range data {
+
+		if v > 1<<24 {
+			return nil, fmt.Errorf("%w: object identifier arc too large", ErrASN1Malformed)
+		}
+
+		v = v<<7 | int(b&0x7f)
+
+		if b&0x80 != 0 {
+			continue
+		}
+
+		if len(oid) == 0 {
+
+			if v < 80 {
+				oid = append(oid, v/40, v%40)
+			} else {
+				oid = append(oid, 2, v-80)
+			}
+
+		} else {
+			oid = append(oid, v)
+		}
+
+		v = 0
+
+	}
+
+	return oid, nil
+
+}
+
+// writeASN1Node writes _n_ and its children at _depth_ onto _w_.
+func writeASN1Node(w io.Writer, n *ASN1Node, depth int) error {
+
+	length := fmt.Sprintf("%5d", len(n.Bytes))
+	if n.Indefinite {
+		length = "  inf"
+	}
+
+	form := "prim"
+	if n.Constructed {
+		form = "cons"
+	}
+
+	line := fmt.Sprintf(
+		"%5d:d=%-2d hl=%d l=%s %s: %s%s",
+		n.Offset, depth, n.HeaderLength, length, form, strings.Repeat("  ", depth), n.TypeName(),
+	)
+
+	if n.Encapsulated {
+		line += " :encapsulates"
+	} else if value := n.Value(); value != "" {
+		line += " :" + value
+	}
+
+	if _, err := fmt.Fprintln(w, line); err != nil {
+		return err
+	}
+
+	for _, child := range n.Children {
+
+		if err := writeASN1Node(w, child, depth+1); err != nil {
+			return err
+		}
+
+	}
+
+	return nil
+
+}
+
+// asn1Hex returns _data_ as upper case hex, truncated after `asn1HexLimit` bytes.
+func asn1Hex(data []byte) string {
+
+	if len(data) > asn1HexLimit {
+		return fmt.Sprintf("%X... (%d bytes)", data[:asn1HexLimit], len(data))
+	}
+
+	return fmt.Sprintf("%X", data)
+
+}
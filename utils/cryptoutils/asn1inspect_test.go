@@ -0,0 +1,114 @@
+package cryptoutils
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/pem"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPrintASN1CertificateAndCSR(t *testing.T) {
+
+	cert, key := issue(t, CertificateProfileTLSServer, CertificateSubject{
+		Subject:  pkix.Name{CommonName: "partner.example.com"},
+		DNSNames: []string{"partner.example.com"},
+	}, nil, nil)
+
+	csr, err := x509.CreateCertificateRequest(rand.Reader, &x509.CertificateRequest{
+		Subject:  pkix.Name{CommonName: "device", Organization: []string{"Partner"}},
+		DNSNames: []string{"device.example.com"},
+	}, key)
+	require.NoError(t, err)
+
+	var data bytes.Buffer
+	require.NoError(t, pem.Encode(&data, &pem.Block{Type: "CERTIFICATE", Bytes: cert.Raw}))
+	require.NoError(t, pem.Encode(&data, &pem.Block{Type: "CERTIFICATE REQUEST", Bytes: csr}))
+
+	var out bytes.Buffer
+	require.NoError(t, PrintASN1(&out, data.Bytes()))
+
+	s := out.String()
+
+	assert.Contains(t, s, "# CERTIFICATE\n")
+	assert.Contains(t, s, "# CERTIFICATE REQUEST\n")
+	assert.Contains(t, s, "    0:d=0  hl=")
+	assert.Contains(t, s, "1.2.840.10045.4.3.2 (ecdsa-with-SHA256)")
+	assert.Contains(t, s, "1.2.840.10045.3.1.7 (prime256v1)")
+	assert.Contains(t, s, `2.5.4.3 (commonName)`)
+	assert.Contains(t, s, `"partner.example.com"`)
+	assert.Contains(t, s, "1.2.840.113549.1.9.14 (extensionRequest)")
+	assert.Contains(t, s, "OCTET STRING :encapsulates")
+
+	// The subject alternative name extension value is parsed as a encapsulated SEQUENCE.
+	docs, err := InspectASN1(cert.Raw)
+	require.NoError(t, err)
+	require.Len(t, docs, 1)
+	assert.Equal(t, "DER", docs[0].Type)
+
+	var san *ASN1Node
+
+	walkASN1(docs[0].Nodes, func(n *ASN1Node) {
+
+		if n.Tag == asn1.TagOID && n.Value() == "2.5.29.17 (subjectAltName)" {
+			san = n
+		}
+
+	})
+
+	require.NotNil(t, san)
+
+}
+
+func TestParseASN1IndefiniteLengthAndMalformed(t *testing.T) {
+
+	nodes, err := ParseASN1([]byte{0x30, 0x80, 0x02, 0x01, 0xfb, 0x04, 0x01, 0x07, 0x00, 0x00})
+	require.NoError(t, err)
+	require.Len(t, nodes, 1)
+
+	assert.True(t, nodes[0].Indefinite)
+	require.Len(t, nodes[0].Children, 2)
+	assert.Equal(t, "-5", nodes[0].Children[0].Value())
+	assert.Equal(t, 5, nodes[0].Children[1].Offset)
+	assert.Equal(t, "07", nodes[0].Children[1].Value())
+
+	var out bytes.Buffer
+	require.NoError(t, WriteASN1(&out, nodes))
+	assert.Contains(t, out.String(), "l=  inf cons: SEQUENCE")
+
+	_, err = ParseASN1([]byte{0x30, 0x05, 0x02, 0x01})
+	assert.True(t, errors.Is(err, ErrASN1Malformed))
+
+	_, err = ParseASN1([]byte{0x30, 0x80, 0x02, 0x01, 0x01})
+	assert.True(t, errors.Is(err, ErrASN1Malformed))
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	require.NoError(t, err)
+
+	nodes, err = ParseASN1(der)
+	require.NoError(t, err)
+	assert.Equal(t, "1.2.840.10045.2.1 (ecPublicKey)", nodes[0].Children[1].Children[0].Value())
+
+}
+
+func walkASN1(nodes []*ASN1Node, fn func(n *ASN1Node)) {
+
+	for _, n := range nodes {
+
+		fn(n)
+		walkASN1(n.Children, fn)
+
+	}
+
+}
@@ -0,0 +1,174 @@
+package cryptoutils
+
+import (
+	"crypto/rand"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math/big"
+	"strings"
+	"time"
+
+	"golang.org/x/crypto/chacha20poly1305"
+)
+
+const (
+	// brancaVersion is the version byte of a _Branca_ token.
+	brancaVersion = 0xBA
+	// brancaHeaderSize is the version, timestamp and nonce.
+	brancaHeaderSize = 1 + 4 + chacha20poly1305.NonceSizeX
+	// base62Alphabet is the alphabet used to encode _Branca_ tokens.
+	base62Alphabet = "0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz"
+)
+
+// Branca encodes and decodes _Branca_ tokens as of the
+// https://github.com/tuupola/branca-spec[Branca specification], i.e. _XChaCha20-Poly1305_
+// encrypted payloads with a timestamp, encoded in base62.
+type Branca struct {
+	aead interface {
+		Seal(dst, nonce, plaintext, additionalData []byte) []byte
+		Open(dst, nonce, ciphertext, additionalData []byte) ([]byte, error)
+	}
+}
+
+// NewBranca creates a new `Branca` from the 32 byte _key_.
+func NewBranca(key []byte) (*Branca, error) {
+
+	aead, err := chacha20poly1305.NewX(key)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Branca{aead: aead}, nil
+
+}
+
+// Encode encrypts the _payload_ into a token timestamped now.
+func (b *Branca) Encode(payload []byte) (string, error) {
+	return b.EncodeAt(payload, time.Now())
+}
+
+// EncodeAt encrypts the _payload_ into a token timestamped _at_.
+func (b *Branca) EncodeAt(payload []byte, at time.Time) (string, error) {
+
+	nonce := make([]byte, chacha20poly1305.NonceSizeX)
+
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return "", err
+	}
+
+	return b.encode(payload, at, nonce)
+
+}
+
+// Decode authenticates and decrypts the _token_. If _ttl_ is greater than zero, a token
+// older than _ttl_ is rejected with `ErrTokenExpired`.
+func (b *Branca) Decode(token string, ttl time.Duration) ([]byte, error) {
+	return b.DecodeAt(token, ttl, time.Now())
+}
+
+// DecodeAt is the same as `Decode` but evaluates the _ttl_ against _now_.
+func (b *Branca) DecodeAt(token string, ttl time.Duration, now time.Time) ([]byte, error) {
+
+	data, err := base62Decode(token)
+	if err != nil {
+		return nil, ErrInvalidToken
+	}
+
+	if len(data) < brancaHeaderSize+chacha20poly1305.Overhead || data[0] != brancaVersion {
+		return nil, ErrInvalidToken
+	}
+
+	header := data[:brancaHeaderSize]
+
+	payload, err := b.aead.Open(nil, header[5:], data[brancaHeaderSize:], header)
+	if err != nil {
+		return nil, ErrInvalidToken
+	}
+
+	issued := time.Unix(int64(binary.BigEndian.Uint32(header[1:5])), 0)
+
+	if err := checkTokenTime(issued, ttl, now); err != nil {
+		return nil, err
+	}
+
+	return payload, nil
+
+}
+
+// encode encrypts the _payload_ using the _nonce_.
+func (b *Branca) encode(payload []byte, at time.Time, nonce []byte) (string, error) {
+
+	if at.Unix() < 0 || at.Unix() > int64(^uint32(0)) {
+		return "", fmt.Errorf("branca timestamp out of range: %s", at)
+	}
+
+	header := make([]byte, 5, brancaHeaderSize)
+
+	header[0] = brancaVersion
+	binary.BigEndian.PutUint32(header[1:5], uint32(at.Unix()))
+	header = append(header, nonce...)
+
+	return base62Encode(b.aead.Seal(header, nonce, payload, header)), nil
+
+}
+
+// base62Encode encodes _data_ as a base62 big endian number.
+func base62Encode(data []byte) string {
+
+	n := new(big.Int).SetBytes(data)
+	base := big.NewInt(62)
+	mod := new(big.Int)
+
+	var sb []byte
+
+	for n.Sign() > 0 {
+		n.DivMod(n, base, mod)
+		sb = append(sb, base62Alphabet[mod.Int64()])
+	}
+
+	for _, b := range data {
+
+		if b != 0 {
+			break
+		}
+
+		sb = append(sb, base62Alphabet[0])
+
+	}
+
+	for i, j := 0, len(sb)-1; i < j; i, j = i+1, j-1 {
+		sb[i], sb[j] = sb[j], sb[i]
+	}
+
+	return string(sb)
+
+}
+
+// base62Decode decodes a string produced by `base62Encode`.
+func base62Decode(s string) ([]byte, error) {
+
+	n := new(big.Int)
+	base := big.NewInt(62)
+
+	for _, c := range s {
+
+		i := strings.IndexRune(base62Alphabet, c)
+		if i < 0 {
+			return nil, fmt.Errorf("invalid base62 character: %q", c)
+		}
+
+		n.Mul(n, base)
+		n.Add(n, big.NewInt(int64(i)))
+
+	}
+
+	leading := 0
+
+	for leading < len(s) && s[leading] == base62Alphabet[0] {
+		leading++
+	}
+
+	return append(make([]byte, leading), n.Bytes()...), nil
+
+}
@@ -0,0 +1,226 @@
+package cryptoutils
+
+import (
+	"crypto/rsa"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"hash"
+	"io"
+)
+
+// BYOKWrapAlgorithm is the algorithm to wrap key material with when importing
+// into a remote _KMS_ or _HSM_ (bring your own key).
+type BYOKWrapAlgorithm string
+
+const (
+	// BYOKRsaOaepSha1 is plain _RSA OAEP_ encryption of the key material using _SHA-1_.
+	BYOKRsaOaepSha1 BYOKWrapAlgorithm = "RSAES_OAEP_SHA_1"
+	// BYOKRsaOaepSha256 is plain _RSA OAEP_ encryption of the key material using _SHA-256_.
+	BYOKRsaOaepSha256 BYOKWrapAlgorithm = "RSAES_OAEP_SHA_256"
+	// BYOKRsaAesKeyWrapSha1 is the _CKM_RSA_AES_KEY_WRAP_ mechanism using _SHA-1_ for
+	// the _OAEP_ part. This is what _Azure Key Vault_ requires.
+	BYOKRsaAesKeyWrapSha1 BYOKWrapAlgorithm = "RSA_AES_KEY_WRAP_SHA_1"
+	// BYOKRsaAesKeyWrapSha256 is the _CKM_RSA_AES_KEY_WRAP_ mechanism using _SHA-256_
+	// for the _OAEP_ part.
+	BYOKRsaAesKeyWrapSha256 BYOKWrapAlgorithm = "RSA_AES_KEY_WRAP_SHA_256"
+)
+
+// hash returns the _OAEP_ hash used by the algorithm.
+func (alg BYOKWrapAlgorithm) hash() (hash.Hash, error) {
+
+	switch alg {
+	case BYOKRsaOaepSha1, BYOKRsaAesKeyWrapSha1:
+		return sha1.New(), nil
+	case BYOKRsaOaepSha256, BYOKRsaAesKeyWrapSha256:
+		return sha256.New(), nil
+	}
+
+	return nil, fmt.Errorf("unsupported BYOK wrap algorithm: %s", alg)
+
+}
+
+// isKeyWrap returns `true` if the algorithm is a _CKM_RSA_AES_KEY_WRAP_ mechanism.
+func (alg BYOKWrapAlgorithm) isKeyWrap() bool {
+	return alg == BYOKRsaAesKeyWrapSha1 || alg == BYOKRsaAesKeyWrapSha256
+}
+
+// BYOKWrap wraps the _material_ for import using the _wrappingKey_ handed out
+// by the remote _KMS_.
+//
+// When plain _RSA OAEP_ is used, the _material_ must be small enough to fit
+// within a single _OAEP_ block (e.g. a 256 bit symmetric key). For larger material,
+// such as a _PKCS#8_ encoded private key, use one of the _RSA AES KEY WRAP_
+// algorithms where a ephemeral _AES-256_ key is generated using _rand_, encrypted
+// with _RSA OAEP_ and then used to wrap the _material_ with `AESKeyWrapWithPadding`.
+// The result is the _RSA_ encrypted ephemeral key directly followed by the wrapped
+// _material_.
+func BYOKWrap(
+	rand io.Reader,
+	wrappingKey *rsa.PublicKey,
+	alg BYOKWrapAlgorithm,
+	material []byte,
+) ([]byte, error) {
+
+	if wrappingKey == nil {
+		return nil, fmt.Errorf("must specify a wrapping key")
+	}
+
+	h, err := alg.hash()
+	if err != nil {
+		return nil, err
+	}
+
+	if !alg.isKeyWrap() {
+		return rsa.EncryptOAEP(h, rand, wrappingKey, material, nil)
+	}
+
+	ephemeral := make([]byte, 32)
+
+	if _, err := io.ReadFull(rand, ephemeral); err != nil {
+		return nil, err
+	}
+
+	encrypted, err := rsa.EncryptOAEP(h, rand, wrappingKey, ephemeral, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	wrapped, err := AESKeyWrapWithPadding(ephemeral, material)
+	if err != nil {
+		return nil, err
+	}
+
+	return append(encrypted, wrapped...), nil
+
+}
+
+// BYOKUnwrap is the inverse of `BYOKWrap`.
+//
+// This is mainly useful when implementing the receiving side, for example in
+// a local _KMS_ or when testing.
+func BYOKUnwrap(
+	wrappingKey *rsa.PrivateKey,
+	alg BYOKWrapAlgorithm,
+	wrapped []byte,
+) ([]byte, error) {
+
+	if wrappingKey == nil {
+		return nil, fmt.Errorf("must specify a wrapping key")
+	}
+
+	h, err := alg.hash()
+	if err != nil {
+		return nil, err
+	}
+
+	if !alg.isKeyWrap() {
+		return rsa.DecryptOAEP(h, nil, wrappingKey, wrapped, nil)
+	}
+
+	size := wrappingKey.Size()
+
+	if len(wrapped) <= size {
+		return nil, fmt.Errorf("wrapped key material is too short: %d", len(wrapped))
+	}
+
+	ephemeral, err := rsa.DecryptOAEP(h, nil, wrappingKey, wrapped[:size], nil)
+	if err != nil {
+		return nil, err
+	}
+
+	return AESKeyUnwrapWithPadding(ephemeral, wrapped[size:])
+
+}
+
+// BYOKWrapWithDER is the same as `BYOKWrap` but the _wrappingKey_ is a _DER_ encoded
+// _PKIX_ public key. This is the format that e.g. _AWS KMS_ hands out the wrapping key in.
+func BYOKWrapWithDER(
+	rand io.Reader,
+	wrappingKey []byte,
+	alg BYOKWrapAlgorithm,
+	material []byte,
+) ([]byte, error) {
+
+	key, err := x509.ParsePKIXPublicKey(wrappingKey)
+	if err != nil {
+		return nil, err
+	}
+
+	rsakey, ok := key.(*rsa.PublicKey)
+	if !ok {
+		return nil, fmt.Errorf("not a *rsa.PublicKey: %T", key)
+	}
+
+	return BYOKWrap(rand, rsakey, alg, material)
+
+}
+
+// PrivateKeyToImportMaterial encodes the private _key_ as _PKCS#8 DER_, the
+// format that remote _KMS_ systems expects asymmetric key material in.
+//
+// If _key_ is a `[]byte` it is treated as raw symmetric key material and returned as is.
+func PrivateKeyToImportMaterial(key interface{}) ([]byte, error) {
+
+	if b, ok := key.([]byte); ok {
+		return b, nil
+	}
+
+	return x509.MarshalPKCS8PrivateKey(key)
+
+}
+
+// AzureBYOKHeader is the header section of a _Azure Key Vault_ key transfer blob.
+type AzureBYOKHeader struct {
+	// KeyID is the _kid_ of the _Key Exchange Key_ (KEK) in _Azure Key Vault_.
+	KeyID string `json:"kid"`
+	// Algorithm is always _dir_.
+	Algorithm string `json:"alg"`
+	// Encryption is always _CKM_RSA_AES_KEY_WRAP_.
+	Encryption string `json:"enc"`
+}
+
+// AzureBYOKBlob is the _Azure Key Vault_ key transfer blob (.byok file).
+type AzureBYOKBlob struct {
+	SchemaVersion string          `json:"schema_version"`
+	Header        AzureBYOKHeader `json:"header"`
+	// Ciphertext is the _base64url_ encoded output of `BYOKWrap`.
+	Ciphertext string `json:"ciphertext"`
+	Generator  string `json:"generator"`
+}
+
+// AzureBYOKWrap wraps the _material_ using the _kek_ as required by the _Azure Key Vault_
+// _BYOK_ specification and writes the key transfer blob onto _w_.
+//
+// The _kekID_ is the key identifier of the _KEK_ in _Azure Key Vault_ and the _kek_ is its
+// public portion.
+func AzureBYOKWrap(
+	w io.Writer,
+	rand io.Reader,
+	kekID string,
+	kek *rsa.PublicKey,
+	material []byte,
+) error {
+
+	wrapped, err := BYOKWrap(rand, kek, BYOKRsaAesKeyWrapSha1, material)
+	if err != nil {
+		return err
+	}
+
+	blob := AzureBYOKBlob{
+		SchemaVersion: "1.0.0",
+		Header: AzureBYOKHeader{
+			KeyID:      kekID,
+			Algorithm:  "dir",
+			Encryption: "CKM_RSA_AES_KEY_WRAP",
+		},
+		Ciphertext: base64.RawURLEncoding.EncodeToString(wrapped),
+		Generator:  "goservice",
+	}
+
+	return json.NewEncoder(w).Encode(&blob)
+
+}
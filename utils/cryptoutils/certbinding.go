@@ -0,0 +1,55 @@
+package cryptoutils
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"fmt"
+)
+
+// ErrCertificateBindingMismatch is returned when a certificate bound token is presented
+// over a connection with another, or no, client certificate.
+var ErrCertificateBindingMismatch = fmt.Errorf("token is not bound to the client certificate")
+
+// Confirmation is the _cnf_ claim of a certificate bound token as of _RFC 8705_ section 3.1.
+type Confirmation struct {
+	// X5tS256 is the base64url encoded _SHA-256_ thumbprint of the _DER_ encoded certificate.
+	X5tS256 string `json:"x5t#S256,omitempty"`
+}
+
+// CertificateThumbprintS256 returns the _x5t#S256_ thumbprint of the _cert_.
+func CertificateThumbprintS256(cert *x509.Certificate) string {
+
+	sum := sha256.Sum256(cert.Raw)
+
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+
+}
+
+// NewCertificateConfirmation returns the _cnf_ claim that binds a token to the _cert_.
+// It is used when issuing a token to a client that authenticated using _mTLS_.
+func NewCertificateConfirmation(cert *x509.Certificate) *Confirmation {
+	return &Confirmation{X5tS256: CertificateThumbprintS256(cert)}
+}
+
+// VerifyCertificateBinding checks that the token confirmation _cnf_ matches the client
+// certificate of the _TLS_ connection _state_, typically `http.Request.TLS`.
+//
+// A missing _cnf_, connection or client certificate is reported as `ErrCertificateBindingMismatch`.
+func VerifyCertificateBinding(cnf *Confirmation, state *tls.ConnectionState) error {
+
+	if cnf == nil || cnf.X5tS256 == "" || state == nil || len(state.PeerCertificates) == 0 {
+		return ErrCertificateBindingMismatch
+	}
+
+	thumbprint := CertificateThumbprintS256(state.PeerCertificates[0])
+
+	if subtle.ConstantTimeCompare([]byte(thumbprint), []byte(cnf.X5tS256)) != 1 {
+		return ErrCertificateBindingMismatch
+	}
+
+	return nil
+
+}
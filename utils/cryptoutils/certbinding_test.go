@@ -0,0 +1,33 @@
+package cryptoutils
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCertificateBinding(t *testing.T) {
+
+	client, _ := issue(t, CertificateProfileTLSClient, CertificateSubject{DNSNames: []string{"client.example.com"}}, nil, nil)
+	other, _ := issue(t, CertificateProfileTLSClient, CertificateSubject{DNSNames: []string{"other.example.com"}}, nil, nil)
+
+	cnf := NewCertificateConfirmation(client)
+
+	data, err := json.Marshal(map[string]interface{}{"cnf": cnf})
+	require.NoError(t, err)
+	assert.Contains(t, string(data), `"x5t#S256":"`+cnf.X5tS256+`"`)
+
+	assert.NoError(t, VerifyCertificateBinding(cnf, &tls.ConnectionState{PeerCertificates: []*x509.Certificate{client}}))
+
+	assert.Equal(t, ErrCertificateBindingMismatch,
+		VerifyCertificateBinding(cnf, &tls.ConnectionState{PeerCertificates: []*x509.Certificate{other}}),
+	)
+
+	assert.Equal(t, ErrCertificateBindingMismatch, VerifyCertificateBinding(cnf, nil))
+	assert.Equal(t, ErrCertificateBindingMismatch, VerifyCertificateBinding(nil, &tls.ConnectionState{}))
+
+}
@@ -0,0 +1,326 @@
+package cryptoutils
+
+import (
+	"bytes"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/pem"
+	"fmt"
+	"io"
+)
+
+var (
+	oidPKCS7Data       = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 7, 1}
+	oidPKCS7SignedData = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 7, 2}
+)
+
+// pkcs7ContentInfo is the _ContentInfo_ as of _RFC 2315_ section 7.
+type pkcs7ContentInfo struct {
+	ContentType asn1.ObjectIdentifier
+	Content     asn1.RawValue `asn1:"optional"`
+}
+
+// pkcs7SignedData is the _SignedData_ as of _RFC 2315_ section 9.1.
+type pkcs7SignedData struct {
+	Version          int
+	DigestAlgorithms []pkix.AlgorithmIdentifier `asn1:"set"`
+	ContentInfo      pkcs7ContentInfo
+	Certificates     asn1.RawValue   `asn1:"optional,tag:0"`
+	CRLs             asn1.RawValue   `asn1:"optional,tag:1"`
+	SignerInfos      []asn1.RawValue `asn1:"set"`
+}
+
+// ParsePKCS7Certificates parses the certificates of a _DER_ encoded _PKCS#7_ _SignedData_
+// structure, typically a _.p7b_ file. Signers, if any, are ignored.
+func ParsePKCS7Certificates(der []byte) ([]*x509.Certificate, error) {
+
+	var info pkcs7ContentInfo
+
+	if rest, err := asn1.Unmarshal(der, &info); err != nil {
+		return nil, err
+	} else if len(rest) > 0 {
+		return nil, fmt.Errorf("trailing data after PKCS#7 structure")
+	}
+
+	if !info.ContentType.Equal(oidPKCS7SignedData) {
+		return nil, fmt.Errorf("not a PKCS#7 signed data structure: %s", info.ContentType)
+	}
+
+	var signedData pkcs7SignedData
+
+	if _, err := asn1.Unmarshal(info.Content.Bytes, &signedData); err != nil {
+		return nil, err
+	}
+
+	if len(signedData.Certificates.Bytes) == 0 {
+		return []*x509.Certificate{}, nil
+	}
+
+	return x509.ParseCertificates(signedData.Certificates.Bytes)
+
+}
+
+// MarshalPKCS7Certificates encodes the _certs_ as a degenerate, certificates only, _PKCS#7_
+// _SignedData_ structure (_.p7b_).
+func MarshalPKCS7Certificates(certs []*x509.Certificate) ([]byte, error) {
+
+	var raw []byte
+
+	for _, cert := range certs {
+		raw = append(raw, cert.Raw...)
+	}
+
+	signedData := pkcs7SignedData{
+		Version:          1,
+		DigestAlgorithms: []pkix.AlgorithmIdentifier{},
+		ContentInfo:      pkcs7ContentInfo{ContentType: oidPKCS7Data},
+		Certificates: asn1.RawValue{
+			Class: asn1.ClassContextSpecific, Tag: 0, IsCompound: true, Bytes: raw,
+		},
+		SignerInfos: []asn1.RawValue{},
+	}
+
+	content, err := asn1.Marshal(signedData)
+	if err != nil {
+		return nil, err
+	}
+
+	return asn1.Marshal(pkcs7ContentInfo{
+		ContentType: oidPKCS7SignedData,
+		Content: asn1.RawValue{
+			Class: asn1.ClassContextSpecific, Tag: 0, IsCompound: true, Bytes: content,
+		},
+	})
+
+}
+
+// ParseCertificateBundle parses a certificate bundle in any of the common forms:
+// concatenated _PEM_ certificates, _PEM_ or _DER_ encoded _PKCS#7_, or a single _DER_
+// certificate. Non certificate _PEM_ blocks, such as keys, are skipped.
+//
+// The certificates are returned in the order they appear in the bundle, use
+// `SortCertificateChain` to normalize the order.
+func ParseCertificateBundle(data []byte) ([]*x509.Certificate, error) {
+
+	if block, _ := pem.Decode(data); block == nil {
+
+		if certs, err := ParsePKCS7Certificates(data); err == nil {
+			return certs, nil
+		}
+
+		return x509.ParseCertificates(data)
+
+	}
+
+	certs := []*x509.Certificate{}
+
+	rest := data
+
+	for {
+
+		var block *pem.Block
+		block, rest = pem.Decode(rest)
+
+		if block == nil {
+			break
+		}
+
+		switch block.Type {
+		case "CERTIFICATE":
+
+			cert, err := x509.ParseCertificate(block.Bytes)
+			if err != nil {
+				return nil, err
+			}
+
+			certs = append(certs, cert)
+
+		case "PKCS7":
+
+			p7, err := ParsePKCS7Certificates(block.Bytes)
+			if err != nil {
+				return nil, err
+			}
+
+			certs = append(certs, p7...)
+
+		}
+
+	}
+
+	return certs, nil
+
+}
+
+// CertificatesToPEM writes the _certs_ as concatenated _PEM_ blocks onto _w_.
+func CertificatesToPEM(w io.Writer, certs []*x509.Certificate) error {
+
+	for _, cert := range certs {
+
+		if err := pem.Encode(w, &pem.Block{Type: "CERTIFICATE", Bytes: cert.Raw}); err != nil {
+			return err
+		}
+
+	}
+
+	return nil
+
+}
+
+// CertificatesToPKCS7PEM writes the _certs_ as a single _PEM_ armored _PKCS#7_ block onto _w_.
+func CertificatesToPKCS7PEM(w io.Writer, certs []*x509.Certificate) error {
+
+	der, err := MarshalPKCS7Certificates(certs)
+	if err != nil {
+		return err
+	}
+
+	return pem.Encode(w, &pem.Block{Type: "PKCS7", Bytes: der})
+
+}
+
+// SortCertificateChain orders the _certs_ leaf first, followed by each issuer up
+// to the root. This is the order that e.g. load balancers and _TLS_ servers expects.
+//
+// Duplicates are removed. The leaf is the certificate, that did not issue any other
+// certificate, with the longest chain of issuers. Certificates that are not part of the
+// chain from the leaf, e.g. unrelated certificates, are appended at the end in their
+// original order. If more than one leaf candidate has the longest chain, an error is
+// returned since the chain is ambiguous.
+func SortCertificateChain(certs []*x509.Certificate) ([]*x509.Certificate, error) {
+
+	unique := []*x509.Certificate{}
+
+	for _, cert := range certs {
+
+		duplicate := false
+
+		for _, u := range unique {
+
+			if bytes.Equal(u.Raw, cert.Raw) {
+				duplicate = true
+				break
+			}
+
+		}
+
+		if !duplicate {
+			unique = append(unique, cert)
+		}
+
+	}
+
+	if len(unique) < 2 {
+		return unique, nil
+	}
+
+	issuedOther := make([]bool, len(unique))
+
+	for i, issuer := range unique {
+
+		for j, cert := range unique {
+
+			if i != j && isIssuerOf(issuer, cert) {
+				issuedOther[i] = true
+				break
+			}
+
+		}
+
+	}
+
+	var best []int
+
+	ambiguous := false
+
+	for i := range unique {
+
+		if issuedOther[i] {
+			continue
+		}
+
+		chain := issuerChain(unique, i)
+
+		switch {
+		case len(chain) > len(best):
+			best, ambiguous = chain, false
+		case len(chain) == len(best):
+			ambiguous = true
+		}
+
+	}
+
+	if best == nil {
+		return nil, fmt.Errorf("certificate chain has no leaf")
+	}
+
+	if ambiguous {
+		return nil, fmt.Errorf("ambiguous certificate chain, more than one leaf")
+	}
+
+	used := make([]bool, len(unique))
+	chain := make([]*x509.Certificate, 0, len(unique))
+
+	for _, i := range best {
+		used[i] = true
+		chain = append(chain, unique[i])
+	}
+
+	for i, cert := range unique {
+
+		if !used[i] {
+			chain = append(chain, cert)
+		}
+
+	}
+
+	return chain, nil
+
+}
+
+// issuerChain returns the index of _leaf_ in _certs_ followed by the index of each issuer up
+// to the root, or until the issuer is missing.
+func issuerChain(certs []*x509.Certificate, leaf int) []int {
+
+	used := make([]bool, len(certs))
+	chain := []int{leaf}
+	used[leaf] = true
+
+	for current := certs[leaf]; !isSelfSigned(current); {
+
+		next := -1
+
+		for i, cert := range certs {
+
+			if !used[i] && isIssuerOf(cert, current) {
+				next = i
+				break
+			}
+
+		}
+
+		if next == -1 {
+			break
+		}
+
+		used[next] = true
+		current = certs[next]
+		chain = append(chain, next)
+
+	}
+
+	return chain
+
+}
+
+// isIssuerOf checks if _issuer_ has issued _cert_.
+func isIssuerOf(issuer, cert *x509.Certificate) bool {
+
+	if !bytes.Equal(issuer.RawSubject, cert.RawIssuer) {
+		return false
+	}
+
+	return cert.CheckSignatureFrom(issuer) == nil
+
+}
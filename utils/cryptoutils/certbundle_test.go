@@ -0,0 +1,114 @@
+package cryptoutils
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func issue(
+	t *testing.T,
+	profile CertificateProfile,
+	subject CertificateSubject,
+	parent *x509.Certificate,
+	parentKey *ecdsa.PrivateKey,
+) (*x509.Certificate, *ecdsa.PrivateKey) {
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	tmpl, _, err := NewCertificateTemplate(profile, subject)
+	require.NoError(t, err)
+
+	if parent == nil {
+		parent, parentKey = tmpl, key
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, parent, &key.PublicKey, parentKey)
+	require.NoError(t, err)
+
+	cert, err := x509.ParseCertificate(der)
+	require.NoError(t, err)
+
+	return cert, key
+}
+
+func TestCertificateBundleRoundTripAndOrdering(t *testing.T) {
+
+	root, rootKey := issue(t, CertificateProfileCA, CertificateSubject{
+		Subject: pkix.Name{CommonName: "root"}, MaxPathLen: 1,
+	}, nil, nil)
+
+	intermediate, intermediateKey := issue(t, CertificateProfileCA, CertificateSubject{
+		Subject: pkix.Name{CommonName: "intermediate"},
+	}, root, rootKey)
+
+	leaf, _ := issue(t, CertificateProfileTLSServer, CertificateSubject{
+		DNSNames: []string{"leaf.example.com"},
+	}, intermediate, intermediateKey)
+
+	var buf bytes.Buffer
+	require.NoError(t, CertificatesToPKCS7PEM(&buf, []*x509.Certificate{root, leaf, intermediate}))
+
+	parsed, err := ParseCertificateBundle(buf.Bytes())
+	require.NoError(t, err)
+	require.Len(t, parsed, 3)
+
+	sorted, err := SortCertificateChain(append(parsed, root))
+	require.NoError(t, err)
+	require.Len(t, sorted, 3)
+
+	assert.Equal(t, leaf.Raw, sorted[0].Raw)
+	assert.Equal(t, intermediate.Raw, sorted[1].Raw)
+	assert.Equal(t, root.Raw, sorted[2].Raw)
+
+	buf.Reset()
+	require.NoError(t, CertificatesToPEM(&buf, sorted))
+
+	parsed, err = ParseCertificateBundle(buf.Bytes())
+	require.NoError(t, err)
+	assert.Len(t, parsed, 3)
+}
+
+func TestSortCertificateChainAppendsUnrelatedCertificates(t *testing.T) {
+
+	root, rootKey := issue(t, CertificateProfileCA, CertificateSubject{
+		Subject: pkix.Name{CommonName: "root"}, MaxPathLen: 1,
+	}, nil, nil)
+
+	intermediate, intermediateKey := issue(t, CertificateProfileCA, CertificateSubject{
+		Subject: pkix.Name{CommonName: "intermediate"},
+	}, root, rootKey)
+
+	leaf, _ := issue(t, CertificateProfileTLSServer, CertificateSubject{
+		DNSNames: []string{"leaf.example.com"},
+	}, intermediate, intermediateKey)
+
+	unrelated, _ := issue(t, CertificateProfileTLSServer, CertificateSubject{
+		DNSNames: []string{"other.example.com"},
+	}, nil, nil)
+
+	sorted, err := SortCertificateChain([]*x509.Certificate{unrelated, root, leaf, intermediate})
+	require.NoError(t, err)
+	require.Len(t, sorted, 4)
+
+	assert.Equal(t, leaf.Raw, sorted[0].Raw)
+	assert.Equal(t, intermediate.Raw, sorted[1].Raw)
+	assert.Equal(t, root.Raw, sorted[2].Raw)
+	assert.Equal(t, unrelated.Raw, sorted[3].Raw)
+
+	sibling, _ := issue(t, CertificateProfileTLSServer, CertificateSubject{
+		DNSNames: []string{"sibling.example.com"},
+	}, intermediate, intermediateKey)
+
+	_, err = SortCertificateChain([]*x509.Certificate{root, leaf, sibling, intermediate})
+	assert.Error(t, err, "two leaves with equally long chains")
+
+}
@@ -0,0 +1,367 @@
+package cryptoutils
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"fmt"
+	"io"
+	"math/big"
+	"net"
+	"net/url"
+	"time"
+)
+
+// CertificateProfile is a well-known kind of certificate with its own defaults and rules.
+type CertificateProfile string
+
+const (
+	// CertificateProfileTLSServer is a _TLS_ server (web server, API endpoint) certificate.
+	CertificateProfileTLSServer CertificateProfile = "tls-server"
+	// CertificateProfileTLSClient is a _TLS_ client certificate, e.g. for _mTLS_.
+	CertificateProfileTLSClient CertificateProfile = "tls-client"
+	// CertificateProfileCodeSigning is used to sign code or artifacts.
+	CertificateProfileCodeSigning CertificateProfile = "code-signing"
+	// CertificateProfileEmail is a _S/MIME_ email protection certificate.
+	CertificateProfileEmail CertificateProfile = "email"
+	// CertificateProfileIoTDevice is a device identity certificate that acts as
+	// both _TLS_ client and server.
+	CertificateProfileIoTDevice CertificateProfile = "iot-device"
+	// CertificateProfileService is a short lived workload identity in a service mesh that acts
+	// as both _TLS_ client and server.
+	CertificateProfileService CertificateProfile = "service"
+	// CertificateProfileCA is a intermediate or root certificate authority.
+	CertificateProfileCA CertificateProfile = "ca"
+)
+
+// certificateProfileSpec is the defaults and rules of a `CertificateProfile`.
+type certificateProfileSpec struct {
+	keyUsage        x509.KeyUsage
+	extKeyUsage     []x509.ExtKeyUsage
+	defaultValidity time.Duration
+	maxValidity     time.Duration
+	isCA            bool
+}
+
+// certificateProfiles contains the spec for each `CertificateProfile`.
+var certificateProfiles = map[CertificateProfile]certificateProfileSpec{
+	CertificateProfileTLSServer: {
+		keyUsage:        x509.KeyUsageDigitalSignature | x509.KeyUsageKeyEncipherment,
+		extKeyUsage:     []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		defaultValidity: 90 * 24 * time.Hour,
+		maxValidity:     398 * 24 * time.Hour,
+	},
+	CertificateProfileTLSClient: {
+		keyUsage:        x509.KeyUsageDigitalSignature,
+		extKeyUsage:     []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+		defaultValidity: 90 * 24 * time.Hour,
+		maxValidity:     398 * 24 * time.Hour,
+	},
+	CertificateProfileCodeSigning: {
+		keyUsage:        x509.KeyUsageDigitalSignature,
+		extKeyUsage:     []x509.ExtKeyUsage{x509.ExtKeyUsageCodeSigning},
+		defaultValidity: 365 * 24 * time.Hour,
+		maxValidity:     3 * 365 * 24 * time.Hour,
+	},
+	CertificateProfileEmail: {
+		keyUsage:        x509.KeyUsageDigitalSignature | x509.KeyUsageKeyEncipherment,
+		extKeyUsage:     []x509.ExtKeyUsage{x509.ExtKeyUsageEmailProtection},
+		defaultValidity: 365 * 24 * time.Hour,
+		maxValidity:     825 * 24 * time.Hour,
+	},
+	CertificateProfileIoTDevice: {
+		keyUsage: x509.KeyUsageDigitalSignature | x509.KeyUsageKeyEncipherment,
+		extKeyUsage: []x509.ExtKeyUsage{
+			x509.ExtKeyUsageClientAuth, x509.ExtKeyUsageServerAuth,
+		},
+		defaultValidity: 2 * 365 * 24 * time.Hour,
+		maxValidity:     10 * 365 * 24 * time.Hour,
+	},
+	CertificateProfileService: {
+		keyUsage: x509.KeyUsageDigitalSignature,
+		extKeyUsage: []x509.ExtKeyUsage{
+			x509.ExtKeyUsageClientAuth, x509.ExtKeyUsageServerAuth,
+		},
+		defaultValidity: 24 * time.Hour,
+		maxValidity:     7 * 24 * time.Hour,
+	},
+	CertificateProfileCA: {
+		keyUsage:        x509.KeyUsageCertSign | x509.KeyUsageCRLSign | x509.KeyUsageDigitalSignature,
+		defaultValidity: 5 * 365 * 24 * time.Hour,
+		maxValidity:     25 * 365 * 24 * time.Hour,
+		isCA:            true,
+	},
+}
+
+// CertificateSubject is the input of a certificate template.
+type CertificateSubject struct {
+	// Subject is the distinguished name. If the _CommonName_ is empty, the
+	// first _DNSNames_, _EmailAddresses_ or _URIs_ entry is used.
+	Subject pkix.Name
+	// DNSNames is the _DNS_ subject alternative names.
+	DNSNames []string
+	// IPAddresses is the _IP_ subject alternative names.
+	IPAddresses []net.IP
+	// EmailAddresses is the _RFC 822_ subject alternative names.
+	EmailAddresses []string
+	// URIs is the _URI_ subject alternative names, e.g. a _SPIFFE_ id.
+	URIs []*url.URL
+	// NotBefore is when the certificate becomes valid. If zero, now
+	// minus five minutes is used to allow for clock skew.
+	NotBefore time.Time
+	// Validity is the certificate lifetime. If zero, the profile default is used.
+	Validity time.Duration
+	// MaxPathLen is only used by `CertificateProfileCA`. Zero means that the
+	// _CA_ may only issue leaf certificates.
+	MaxPathLen int
+}
+
+// LintSeverity is how severe a `LintFinding` is.
+type LintSeverity string
+
+const (
+	// LintWarning is something that should be looked into but is not fatal.
+	LintWarning LintSeverity = "warning"
+	// LintError is something that makes the material unfit for use.
+	LintError LintSeverity = "error"
+)
+
+// LintFinding is a single finding when validating crypto material.
+type LintFinding struct {
+	// Severity is the severity of this finding.
+	Severity LintSeverity `json:"severity"`
+	// Code is a stable identifier of the rule, e.g. _missing-san_.
+	Code string `json:"code"`
+	// Message is a human readable description.
+	Message string `json:"message"`
+}
+
+// String returns a single line representation of the finding.
+func (f LintFinding) String() string {
+	return fmt.Sprintf("%s: %s: %s", f.Severity, f.Code, f.Message)
+}
+
+// HasLintErrors returns `true` if any of the _findings_ is a `LintError`.
+func HasLintErrors(findings []LintFinding) bool {
+
+	for i := range findings {
+
+		if findings[i].Severity == LintError {
+			return true
+		}
+
+	}
+
+	return false
+
+}
+
+// NewCertificateTemplate creates a `*x509.Certificate` template for the _profile_.
+//
+// The key usages, extended key usages, validity and basic constraints are set from
+// the _profile_ and a random serial number is generated. The template is linted
+// using `LintCertificateTemplate` and if any `LintError` is found, an error is returned
+// along with the findings. Warnings are only returned as findings.
+func NewCertificateTemplate(
+	profile CertificateProfile,
+	subject CertificateSubject,
+) (*x509.Certificate, []LintFinding, error) {
+
+	spec, ok := certificateProfiles[profile]
+	if !ok {
+		return nil, nil, fmt.Errorf("unknown certificate profile: %s", profile)
+	}
+
+	serial, err := NewCertificateSerialNumber(rand.Reader)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	notBefore := subject.NotBefore
+	if notBefore.IsZero() {
+		notBefore = time.Now().Add(-5 * time.Minute).UTC()
+	}
+
+	validity := subject.Validity
+	if validity == 0 {
+		validity = spec.defaultValidity
+	}
+
+	name := subject.Subject
+	if name.CommonName == "" {
+		name.CommonName = firstSubjectAltName(subject)
+	}
+
+	tmpl := &x509.Certificate{
+		SerialNumber:          serial,
+		Subject:               name,
+		DNSNames:              subject.DNSNames,
+		IPAddresses:           subject.IPAddresses,
+		EmailAddresses:        subject.EmailAddresses,
+		URIs:                  subject.URIs,
+		NotBefore:             notBefore,
+		NotAfter:              notBefore.Add(validity),
+		KeyUsage:              spec.keyUsage,
+		ExtKeyUsage:           spec.extKeyUsage,
+		BasicConstraintsValid: true,
+		IsCA:                  spec.isCA,
+	}
+
+	if spec.isCA {
+		tmpl.MaxPathLen = subject.MaxPathLen
+		tmpl.MaxPathLenZero = subject.MaxPathLen == 0
+	}
+
+	findings := LintCertificateTemplate(profile, tmpl)
+
+	if HasLintErrors(findings) {
+
+		return nil, findings, fmt.Errorf(
+			"certificate template for profile %s has lint errors", profile,
+		)
+
+	}
+
+	return tmpl, findings, nil
+
+}
+
+// LintCertificateTemplate validates the _tmpl_ against the rules of the _profile_.
+func LintCertificateTemplate(profile CertificateProfile, tmpl *x509.Certificate) []LintFinding {
+
+	findings := []LintFinding{}
+
+	add := func(severity LintSeverity, code, format string, args ...interface{}) {
+		findings = append(findings, LintFinding{
+			Severity: severity,
+			Code:     code,
+			Message:  fmt.Sprintf(format, args...),
+		})
+	}
+
+	spec, ok := certificateProfiles[profile]
+	if !ok {
+		add(LintError, "unknown-profile", "unknown certificate profile: %s", profile)
+		return findings
+	}
+
+	if tmpl.SerialNumber == nil || tmpl.SerialNumber.Sign() <= 0 {
+		add(LintError, "invalid-serial", "serial number must be a positive integer")
+	} else if tmpl.SerialNumber.BitLen() < 64 {
+		add(LintWarning, "weak-serial", "serial number should contain at least 64 bits of entropy")
+	}
+
+	if !tmpl.NotAfter.After(tmpl.NotBefore) {
+		add(LintError, "invalid-validity", "not after must be after not before")
+	} else if validity := tmpl.NotAfter.Sub(tmpl.NotBefore); validity > spec.maxValidity {
+		add(LintWarning, "overlong-validity",
+			"validity of %d days exceeds the recommended maximum of %d days for %s",
+			int(validity.Hours()/24), int(spec.maxValidity.Hours()/24), profile,
+		)
+	}
+
+	sans := len(tmpl.DNSNames) + len(tmpl.IPAddresses) + len(tmpl.EmailAddresses) + len(tmpl.URIs)
+
+	switch profile {
+	case CertificateProfileTLSServer:
+
+		if len(tmpl.DNSNames)+len(tmpl.IPAddresses) == 0 {
+			add(LintError, "missing-san",
+				"TLS server certificates must have DNS or IP subject alternative names, the common name is not used by clients",
+			)
+		}
+
+	case CertificateProfileService:
+
+		if len(tmpl.DNSNames)+len(tmpl.IPAddresses)+len(tmpl.URIs) == 0 {
+			add(LintError, "missing-san", "service certificates must have DNS, IP or URI subject alternative names")
+		}
+
+	case CertificateProfileTLSClient, CertificateProfileIoTDevice:
+
+		if sans == 0 {
+			add(LintWarning, "missing-san", "certificate has no subject alternative names")
+		}
+
+	case CertificateProfileEmail:
+
+		if len(tmpl.EmailAddresses) == 0 {
+			add(LintError, "missing-san", "email certificates must have a email subject alternative name")
+		}
+
+	case CertificateProfileCA:
+
+		if sans > 0 {
+			add(LintWarning, "ca-with-san", "certificate authorities should not have subject alternative names")
+		}
+
+	}
+
+	if spec.isCA && !tmpl.IsCA {
+		add(LintError, "ca-flag", "CA profile requires the CA basic constraint")
+	}
+
+	if !spec.isCA && tmpl.IsCA {
+		add(LintError, "ca-flag", "leaf profile %s must not have the CA basic constraint", profile)
+	}
+
+	if tmpl.Subject.CommonName == "" && sans == 0 {
+		add(LintError, "empty-subject", "certificate has neither a common name nor subject alternative names")
+	}
+
+	return findings
+
+}
+
+// NewCertificateSerialNumber generates a random, positive, 127 bit serial number using _random_.
+func NewCertificateSerialNumber(random io.Reader) (*big.Int, error) {
+
+	limit := new(big.Int).Lsh(big.NewInt(1), 127)
+
+	for {
+
+		serial, err := rand.Int(random, limit)
+		if err != nil {
+			return nil, err
+		}
+
+		if serial.Sign() > 0 {
+			return serial, nil
+		}
+
+	}
+
+}
+
+// CreateSelfSignedCertificate signs the _tmpl_ with _signer_ and returns the parsed certificate.
+func CreateSelfSignedCertificate(
+	rand io.Reader,
+	tmpl *x509.Certificate,
+	signer crypto.Signer,
+) (*x509.Certificate, error) {
+
+	der, err := x509.CreateCertificate(rand, tmpl, tmpl, signer.Public(), signer)
+	if err != nil {
+		return nil, err
+	}
+
+	return x509.ParseCertificate(der)
+
+}
+
+// firstSubjectAltName returns the first _SAN_ to use as common name, if any.
+func firstSubjectAltName(subject CertificateSubject) string {
+
+	switch {
+	case len(subject.DNSNames) > 0:
+		return subject.DNSNames[0]
+	case len(subject.EmailAddresses) > 0:
+		return subject.EmailAddresses[0]
+	case len(subject.URIs) > 0:
+		return subject.URIs[0].String()
+	}
+
+	return ""
+
+}
@@ -0,0 +1,65 @@
+package cryptoutils
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTLSServerTemplateRequiresSAN(t *testing.T) {
+
+	_, findings, err := NewCertificateTemplate(CertificateProfileTLSServer, CertificateSubject{})
+
+	assert.Error(t, err)
+	assert.True(t, HasLintErrors(findings))
+}
+
+func TestSelfSignedTLSServerCertificate(t *testing.T) {
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	tmpl, findings, err := NewCertificateTemplate(CertificateProfileTLSServer, CertificateSubject{
+		DNSNames: []string{"api.example.com"},
+		Validity: 800 * 24 * time.Hour,
+	})
+
+	require.NoError(t, err)
+	require.Len(t, findings, 1)
+	assert.Equal(t, "overlong-validity", findings[0].Code)
+
+	cert, err := CreateSelfSignedCertificate(rand.Reader, tmpl, key)
+	require.NoError(t, err)
+
+	assert.Equal(t, "api.example.com", cert.Subject.CommonName)
+	assert.Equal(t, []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth}, cert.ExtKeyUsage)
+	assert.NoError(t, cert.VerifyHostname("api.example.com"))
+}
+
+func TestServiceTemplateIsShortLivedClientAndServer(t *testing.T) {
+
+	_, _, err := NewCertificateTemplate(CertificateProfileService, CertificateSubject{
+		Subject: pkix.Name{CommonName: "orders"},
+	})
+	assert.Error(t, err)
+
+	spiffe, err := url.Parse("spiffe://example.org/orders")
+	require.NoError(t, err)
+
+	tmpl, findings, err := NewCertificateTemplate(CertificateProfileService, CertificateSubject{
+		URIs: []*url.URL{spiffe},
+	})
+
+	require.NoError(t, err)
+	assert.Empty(t, findings)
+	assert.Equal(t, 24*time.Hour, tmpl.NotAfter.Sub(tmpl.NotBefore))
+	assert.Equal(t, []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth, x509.ExtKeyUsageServerAuth}, tmpl.ExtKeyUsage)
+}
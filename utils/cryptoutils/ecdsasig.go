@@ -0,0 +1,124 @@
+package cryptoutils
+
+import (
+	"crypto/elliptic"
+	"encoding/asn1"
+	"fmt"
+	"math/big"
+)
+
+// ecdsaSignature is the _ASN.1_ structure of a _ECDSA_ signature.
+type ecdsaSignature struct {
+	R, S *big.Int
+}
+
+// ECDSACoordinateSize returns the size, in bytes, of _r_ and _s_ in a raw signature on _curve_,
+// e.g. 32 for _P-256_ and 66 for _P-521_.
+func ECDSACoordinateSize(curve elliptic.Curve) int {
+	return (curve.Params().BitSize + 7) / 8
+}
+
+// ECDSASignatureToRaw converts a _ASN.1 DER_ signature to the fixed size _r || s_ encoding used
+// by _JOSE_, _COSE_ and _WebAuthn_, where _size_ is the `ECDSACoordinateSize` of the curve.
+func ECDSASignatureToRaw(der []byte, size int) ([]byte, error) {
+
+	var sig ecdsaSignature
+
+	rest, err := asn1.Unmarshal(der, &sig)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(rest) > 0 {
+		return nil, fmt.Errorf("trailing data after ECDSA signature")
+	}
+
+	if sig.R.Sign() <= 0 || sig.S.Sign() <= 0 {
+		return nil, fmt.Errorf("ECDSA signature values must be positive")
+	}
+
+	if (sig.R.BitLen()+7)/8 > size || (sig.S.BitLen()+7)/8 > size {
+		return nil, fmt.Errorf("ECDSA signature values exceeds %d bytes", size)
+	}
+
+	raw := make([]byte, 2*size)
+	sig.R.FillBytes(raw[:size])
+	sig.S.FillBytes(raw[size:])
+
+	return raw, nil
+
+}
+
+// ECDSASignatureFromRaw converts a _r || s_ signature, as of `ECDSASignatureToRaw`, to
+// _ASN.1 DER_.
+func ECDSASignatureFromRaw(raw []byte) ([]byte, error) {
+
+	if len(raw) == 0 || len(raw)%2 != 0 {
+		return nil, fmt.Errorf("raw ECDSA signature must be of even, non zero, length: %d", len(raw))
+	}
+
+	return AppendECDSASignatureFromRaw(nil, raw)
+
+}
+
+// AppendECDSASignatureFromRaw is `ECDSASignatureFromRaw` that appends the _ASN.1 DER_ to _dst_,
+// hence a pooled buffer may be used to avoid allocations on hot verify paths.
+func AppendECDSASignatureFromRaw(dst, raw []byte) ([]byte, error) {
+
+	if len(raw) == 0 || len(raw)%2 != 0 {
+		return nil, fmt.Errorf("raw ECDSA signature must be of even, non zero, length: %d", len(raw))
+	}
+
+	size := len(raw) / 2
+	r, s := derIntegerBytes(raw[:size]), derIntegerBytes(raw[size:])
+
+	// Each integer is the tag, a single byte length and the content, with a leading zero
+	// if the high bit is set.
+	length := 4 + len(r) + len(s)
+	if r[0]&0x80 != 0 {
+		length++
+	}
+	if s[0]&0x80 != 0 {
+		length++
+	}
+
+	if size > 126 || length > 0xff {
+		return nil, fmt.Errorf("raw ECDSA signature too large: %d", len(raw))
+	}
+
+	dst = append(dst, 0x30)
+	if length >= 0x80 {
+		dst = append(dst, 0x81)
+	}
+	dst = append(dst, byte(length))
+
+	dst = appendDERInteger(dst, r)
+	dst = appendDERInteger(dst, s)
+
+	return dst, nil
+
+}
+
+// derIntegerBytes returns the unsigned big endian _b_ without leading zeros, a zero value is
+// returned as a single zero byte.
+func derIntegerBytes(b []byte) []byte {
+
+	for len(b) > 1 && b[0] == 0 {
+		b = b[1:]
+	}
+
+	return b
+
+}
+
+// appendDERInteger appends the _DER_ _INTEGER_ of the unsigned _b_, as returned by
+// `derIntegerBytes`, onto _dst_.
+func appendDERInteger(dst, b []byte) []byte {
+
+	if b[0]&0x80 != 0 {
+		return append(append(dst, 0x02, byte(len(b)+1), 0x00), b...)
+	}
+
+	return append(append(dst, 0x02, byte(len(b))), b...)
+
+}
@@ -0,0 +1,119 @@
+package cryptoutils
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/asn1"
+	"math/big"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestECDSASignatureRawRoundTrip(t *testing.T) {
+
+	for _, curve := range []elliptic.Curve{elliptic.P256(), elliptic.P384(), elliptic.P521()} {
+
+		key, err := ecdsa.GenerateKey(curve, rand.Reader)
+		require.NoError(t, err)
+
+		digest := sha256.Sum256([]byte("message"))
+
+		der, err := ecdsa.SignASN1(rand.Reader, key, digest[:])
+		require.NoError(t, err)
+
+		size := ECDSACoordinateSize(curve)
+
+		raw, err := ECDSASignatureToRaw(der, size)
+		require.NoError(t, err)
+		assert.Len(t, raw, 2*size)
+
+		back, err := ECDSASignatureFromRaw(raw)
+		require.NoError(t, err)
+		assert.Equal(t, der, back)
+		assert.True(t, ecdsa.VerifyASN1(&key.PublicKey, digest[:], back))
+
+	}
+
+	assert.Equal(t, 66, ECDSACoordinateSize(elliptic.P521()))
+
+	_, err := ECDSASignatureFromRaw(make([]byte, 63))
+	assert.Error(t, err)
+
+	_, err = ECDSASignatureToRaw([]byte{0x30, 0x00}, 32)
+	assert.Error(t, err)
+
+}
+
+func TestAppendECDSASignatureFromRawMatchesASN1(t *testing.T) {
+
+	for _, size := range []int{32, 48, 66} {
+
+		for i := 0; i < 64; i++ {
+
+			raw := make([]byte, 2*size)
+			_, err := rand.Read(raw)
+			require.NoError(t, err)
+
+			switch i {
+			case 0:
+				raw = make([]byte, 2*size)
+			case 1:
+				raw[0], raw[size] = 0xff, 0x80
+			case 2:
+				copy(raw, make([]byte, size-1))
+			}
+
+			expected, err := asn1.Marshal(ecdsaSignature{
+				R: new(big.Int).SetBytes(raw[:size]),
+				S: new(big.Int).SetBytes(raw[size:]),
+			})
+			require.NoError(t, err)
+
+			der, err := AppendECDSASignatureFromRaw(nil, raw)
+			require.NoError(t, err)
+			assert.Equal(t, expected, der)
+
+		}
+
+	}
+
+}
+
+func BenchmarkECDSASignatureFromRaw(b *testing.B) {
+
+	raw := make([]byte, 64)
+	_, _ = rand.Read(raw)
+
+	b.Run("asn1", func(b *testing.B) {
+
+		b.ReportAllocs()
+
+		for i := 0; i < b.N; i++ {
+			_, _ = asn1.Marshal(ecdsaSignature{
+				R: new(big.Int).SetBytes(raw[:32]),
+				S: new(big.Int).SetBytes(raw[32:]),
+			})
+		}
+
+	})
+
+	b.Run("pooled", func(b *testing.B) {
+
+		b.ReportAllocs()
+
+		for i := 0; i < b.N; i++ {
+
+			buf := AcquireBuffer()
+			buf.Grow(len(raw) + 9)
+			_, _ = AppendECDSASignatureFromRaw(buf.Bytes(), raw)
+			ReleaseBuffer(buf)
+
+		}
+
+	})
+
+}
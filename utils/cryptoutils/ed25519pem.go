@@ -0,0 +1,127 @@
+package cryptoutils
+
+import (
+	"crypto/ed25519"
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"io"
+)
+
+// ED25519PrivateKeyToPEM writes the private key onto _w_ as a _PKCS #8_ PEM block.
+//
+// If _public_ is set to `true`, it will include the _PKIX_ public key as well.
+func ED25519PrivateKeyToPEM(w io.Writer, key ed25519.PrivateKey, public bool) error {
+
+	if len(key) != ed25519.PrivateKeySize {
+		return fmt.Errorf("must specify private key to write")
+	}
+
+	privateKeyBytes, err := x509.MarshalPKCS8PrivateKey(key)
+
+	if err != nil {
+		return err
+	}
+
+	privateKeyBlock := &pem.Block{
+		Type:  "PRIVATE KEY",
+		Bytes: privateKeyBytes,
+	}
+
+	if err := pem.Encode(w, privateKeyBlock); err != nil {
+		return err
+	}
+
+	if public {
+		return ED25519PublicKeyToPEM(w, key.Public().(ed25519.PublicKey))
+	}
+
+	return nil
+
+}
+
+// ED25519PublicKeyToPEM writes the public key onto the _w_ `io.Writer` as a _PKIX_ PEM block.
+func ED25519PublicKeyToPEM(w io.Writer, key ed25519.PublicKey) error {
+
+	if len(key) != ed25519.PublicKeySize {
+		return fmt.Errorf("must specify public key to write")
+	}
+
+	publicKeyBytes, err := x509.MarshalPKIXPublicKey(key)
+
+	if err != nil {
+		return err
+	}
+
+	publicKeyBlock := &pem.Block{
+		Type:  "PUBLIC KEY",
+		Bytes: publicKeyBytes,
+	}
+
+	return pem.Encode(w, publicKeyBlock)
+
+}
+
+// PEMToED25519PrivateKey parses the first _PKCS #8_ _Ed25519_ private key in _data_.
+func PEMToED25519PrivateKey(data []byte) (ed25519.PrivateKey, error) {
+
+	keys, err := PEMToKey("", data,
+		func(fqPath string, block *pem.Block) (key interface{}, stop bool, err error) {
+
+			var k interface{}
+			if k, err = x509.ParsePKCS8PrivateKey(block.Bytes); err == nil {
+
+				if _, ok := k.(ed25519.PrivateKey); ok {
+					key = k
+					stop = true
+				}
+
+			}
+
+			return
+
+		}, "PRIVATE KEY")
+
+	if err != nil {
+		return nil, err
+	}
+
+	if len(keys) == 0 {
+		return nil, fmt.Errorf("no ed25519 private key found")
+	}
+
+	return keys[0].(ed25519.PrivateKey), nil
+
+}
+
+// PEMToED25519PublicKey parses the first _PKIX_ _Ed25519_ public key in _data_.
+func PEMToED25519PublicKey(data []byte) (ed25519.PublicKey, error) {
+
+	keys, err := PEMToKey("", data,
+		func(fqPath string, block *pem.Block) (key interface{}, stop bool, err error) {
+
+			var k interface{}
+			if k, err = x509.ParsePKIXPublicKey(block.Bytes); err == nil {
+
+				if _, ok := k.(ed25519.PublicKey); ok {
+					key = k
+					stop = true
+				}
+
+			}
+
+			return
+
+		}, "PUBLIC KEY")
+
+	if err != nil {
+		return nil, err
+	}
+
+	if len(keys) == 0 {
+		return nil, fmt.Errorf("no ed25519 public key found")
+	}
+
+	return keys[0].(ed25519.PublicKey), nil
+
+}
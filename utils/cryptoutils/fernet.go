@@ -0,0 +1,193 @@
+package cryptoutils
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"time"
+)
+
+var (
+	// ErrInvalidToken is returned when a _Fernet_ or _Branca_ token is malformed or
+	// fails authentication.
+	ErrInvalidToken = fmt.Errorf("invalid token")
+	// ErrTokenExpired is returned when a _Fernet_ or _Branca_ token is older than the
+	// allowed time to live, or is issued too far in the future.
+	ErrTokenExpired = fmt.Errorf("token expired")
+)
+
+const (
+	// fernetVersion is the version byte of a _Fernet_ token.
+	fernetVersion = 0x80
+	// tokenMaxClockSkew is how far in the future a token timestamp may be.
+	tokenMaxClockSkew = 60 * time.Second
+)
+
+// Fernet encodes and decodes _Fernet_ tokens as of the
+// https://github.com/fernet/spec[Fernet specification].
+//
+// The tokens are compatible with the _Python_ `cryptography.fernet` module.
+type Fernet struct {
+	signingKey    []byte
+	encryptionKey []byte
+}
+
+// NewFernet creates a new `Fernet` from the 32 byte _key_. The first 16 bytes is the
+// signing key and the last 16 bytes the encryption key.
+func NewFernet(key []byte) (*Fernet, error) {
+
+	if len(key) != 32 {
+		return nil, fmt.Errorf("fernet key must be 32 bytes, got: %d", len(key))
+	}
+
+	return &Fernet{
+		signingKey:    append([]byte{}, key[:16]...),
+		encryptionKey: append([]byte{}, key[16:]...),
+	}, nil
+
+}
+
+// ParseFernetKey decodes the _URL_ safe base64 encoded key used by other _Fernet_ implementations.
+func ParseFernetKey(key string) ([]byte, error) {
+	return base64.URLEncoding.DecodeString(key)
+}
+
+// Encode encrypts the _payload_ into a token timestamped now.
+func (f *Fernet) Encode(payload []byte) (string, error) {
+	return f.EncodeAt(payload, time.Now())
+}
+
+// EncodeAt encrypts the _payload_ into a token timestamped _at_.
+func (f *Fernet) EncodeAt(payload []byte, at time.Time) (string, error) {
+
+	iv := make([]byte, aes.BlockSize)
+
+	if _, err := io.ReadFull(rand.Reader, iv); err != nil {
+		return "", err
+	}
+
+	return f.encode(payload, at, iv)
+
+}
+
+// Decode authenticates and decrypts the _token_. If _ttl_ is greater than zero, a token
+// older than _ttl_ is rejected with `ErrTokenExpired`.
+func (f *Fernet) Decode(token string, ttl time.Duration) ([]byte, error) {
+	return f.DecodeAt(token, ttl, time.Now())
+}
+
+// DecodeAt is the same as `Decode` but evaluates the _ttl_ against _now_.
+func (f *Fernet) DecodeAt(token string, ttl time.Duration, now time.Time) ([]byte, error) {
+
+	data, err := base64.URLEncoding.DecodeString(token)
+	if err != nil {
+		return nil, ErrInvalidToken
+	}
+
+	// version + timestamp + iv + at least one block + hmac
+	if len(data) < 1+8+aes.BlockSize+aes.BlockSize+sha256.Size || data[0] != fernetVersion {
+		return nil, ErrInvalidToken
+	}
+
+	signed, tag := data[:len(data)-sha256.Size], data[len(data)-sha256.Size:]
+
+	mac := hmac.New(sha256.New, f.signingKey)
+	mac.Write(signed)
+
+	if !hmac.Equal(mac.Sum(nil), tag) {
+		return nil, ErrInvalidToken
+	}
+
+	issued := time.Unix(int64(binary.BigEndian.Uint64(data[1:9])), 0)
+
+	if err := checkTokenTime(issued, ttl, now); err != nil {
+		return nil, err
+	}
+
+	iv, ciphertext := data[9:9+aes.BlockSize], signed[9+aes.BlockSize:]
+
+	if len(ciphertext)%aes.BlockSize != 0 {
+		return nil, ErrInvalidToken
+	}
+
+	block, err := aes.NewCipher(f.encryptionKey)
+	if err != nil {
+		return nil, err
+	}
+
+	plaintext := make([]byte, len(ciphertext))
+	cipher.NewCBCDecrypter(block, iv).CryptBlocks(plaintext, ciphertext)
+
+	padding := int(plaintext[len(plaintext)-1])
+
+	if padding == 0 || padding > aes.BlockSize {
+		return nil, ErrInvalidToken
+	}
+
+	for _, b := range plaintext[len(plaintext)-padding:] {
+
+		if int(b) != padding {
+			return nil, ErrInvalidToken
+		}
+
+	}
+
+	return plaintext[:len(plaintext)-padding], nil
+
+}
+
+// encode encrypts the _payload_ using the _iv_.
+func (f *Fernet) encode(payload []byte, at time.Time, iv []byte) (string, error) {
+
+	block, err := aes.NewCipher(f.encryptionKey)
+	if err != nil {
+		return "", err
+	}
+
+	padding := aes.BlockSize - len(payload)%aes.BlockSize
+	plaintext := make([]byte, len(payload)+padding)
+
+	copy(plaintext, payload)
+
+	for i := len(payload); i < len(plaintext); i++ {
+		plaintext[i] = byte(padding)
+	}
+
+	data := make([]byte, 1+8+aes.BlockSize, 1+8+aes.BlockSize+len(plaintext)+sha256.Size)
+
+	data[0] = fernetVersion
+	binary.BigEndian.PutUint64(data[1:9], uint64(at.Unix()))
+	copy(data[9:], iv)
+
+	ciphertext := make([]byte, len(plaintext))
+	cipher.NewCBCEncrypter(block, iv).CryptBlocks(ciphertext, plaintext)
+
+	data = append(data, ciphertext...)
+
+	mac := hmac.New(sha256.New, f.signingKey)
+	mac.Write(data)
+
+	return base64.URLEncoding.EncodeToString(mac.Sum(data)), nil
+
+}
+
+// checkTokenTime checks that a token _issued_ is within the _ttl_ and not too far in the future.
+func checkTokenTime(issued time.Time, ttl time.Duration, now time.Time) error {
+
+	if ttl <= 0 {
+		return nil
+	}
+
+	if issued.Add(ttl).Before(now) || issued.After(now.Add(tokenMaxClockSkew)) {
+		return ErrTokenExpired
+	}
+
+	return nil
+
+}
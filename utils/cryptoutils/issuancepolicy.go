@@ -0,0 +1,381 @@
+package cryptoutils
+
+import (
+	"crypto/x509"
+	"fmt"
+	"net"
+	"strings"
+	"time"
+)
+
+// IssuancePolicy is the operator policy that a certificate must comply with before it is issued.
+//
+// Empty allow lists means that the corresponding name type is not restricted by the policy,
+// the issuing _CA_ name constraints still apply.
+type IssuancePolicy struct {
+	// AllowedDomains is the _DNS_ domains that may be used in _DNS_ names and _URI_ hosts. A
+	// domain _example.com_ permits itself and all subdomains, a domain with a leading dot,
+	// _.example.com_, permits only subdomains.
+	AllowedDomains []string
+	// AllowedIPRanges is the _IP_ ranges that _IP_ subject alternative names must be within.
+	AllowedIPRanges []*net.IPNet
+	// AllowedEmailDomains is the domains of permitted email addresses, same matching as _AllowedDomains_.
+	AllowedEmailDomains []string
+	// AllowedExtKeyUsages is the set of extended key usages that may be requested.
+	AllowedExtKeyUsages []x509.ExtKeyUsage
+	// AllowWildcards permits wildcard _DNS_ names such as _*.example.com_.
+	AllowWildcards bool
+	// AllowCA permits issuing _CA_ certificates.
+	AllowCA bool
+	// MaxValidity is the maximum lifetime of a certificate, zero means unlimited.
+	MaxValidity time.Duration
+}
+
+// PolicyViolation is a single reason why a certificate may not be issued.
+type PolicyViolation struct {
+	// Rule is a stable identifier of the violated rule, e.g. _name-constraint-excluded_.
+	Rule string `json:"rule"`
+	// Field is the certificate field that violated the rule, e.g. _dns_.
+	Field string `json:"field"`
+	// Value is the offending value.
+	Value string `json:"value"`
+	// Message is a human readable description.
+	Message string `json:"message"`
+}
+
+// IssuanceViolationError is returned by `ValidateIssuance` when there are one or more violations.
+type IssuanceViolationError struct {
+	Violations []PolicyViolation
+}
+
+// Error implements the `error` interface.
+func (e *IssuanceViolationError) Error() string {
+
+	msgs := make([]string, len(e.Violations))
+
+	for i, v := range e.Violations {
+		msgs[i] = fmt.Sprintf("%s (%s=%s)", v.Message, v.Field, v.Value)
+	}
+
+	return fmt.Sprintf("certificate violates issuance policy: %s", strings.Join(msgs, ", "))
+
+}
+
+// ValidateIssuance checks that the certificate _tmpl_ may be issued by _issuer_ under the _policy_.
+//
+// It validates the _issuer_ name constraints (_RFC 5280_ section 4.2.1.10), extended key usage
+// and path length constraints, that the validity is within the issuer validity, and that the
+// _tmpl_ complies with the _policy_. The _policy_ may be `nil` to only check the issuer.
+//
+// All violations are collected and returned in a `*IssuanceViolationError`.
+func ValidateIssuance(issuer, tmpl *x509.Certificate, policy *IssuancePolicy) error {
+
+	v := &violations{}
+
+	validateIssuer(v, issuer, tmpl)
+
+	if policy != nil {
+		validatePolicy(v, policy, tmpl)
+	}
+
+	if len(v.list) > 0 {
+		return &IssuanceViolationError{Violations: v.list}
+	}
+
+	return nil
+
+}
+
+// violations collects `PolicyViolation`.
+type violations struct {
+	list []PolicyViolation
+}
+
+// add adds a violation.
+func (v *violations) add(rule, field, value, format string, args ...interface{}) {
+
+	v.list = append(v.list, PolicyViolation{
+		Rule:    rule,
+		Field:   field,
+		Value:   value,
+		Message: fmt.Sprintf(format, args...),
+	})
+
+}
+
+// validateIssuer checks the _tmpl_ against the constraints of the _issuer_.
+func validateIssuer(v *violations, issuer, tmpl *x509.Certificate) {
+
+	if !issuer.IsCA || (issuer.KeyUsage != 0 && issuer.KeyUsage&x509.KeyUsageCertSign == 0) {
+		v.add("issuer-not-ca", "issuer", issuer.Subject.String(), "issuer is not allowed to sign certificates")
+	}
+
+	if tmpl.IsCA && issuer.MaxPathLen == 0 && issuer.MaxPathLenZero {
+		v.add("path-length", "basic-constraints", "ca", "issuer path length constraint do not allow sub CAs")
+	}
+
+	if tmpl.NotBefore.Before(issuer.NotBefore) || tmpl.NotAfter.After(issuer.NotAfter) {
+
+		v.add("validity-outside-issuer", "validity", tmpl.NotAfter.UTC().Format(time.RFC3339),
+			"certificate validity must be within the issuer validity",
+		)
+
+	}
+
+	if len(issuer.ExtKeyUsage) > 0 && !containsExtKeyUsage(issuer.ExtKeyUsage, x509.ExtKeyUsageAny) {
+
+		for _, eku := range tmpl.ExtKeyUsage {
+
+			if !containsExtKeyUsage(issuer.ExtKeyUsage, eku) {
+				v.add("eku-not-permitted-by-issuer", "eku", extKeyUsageName(eku),
+					"extended key usage is not permitted by the issuer",
+				)
+			}
+
+		}
+
+	}
+
+	for _, name := range tmpl.DNSNames {
+		checkNameConstraint(v, "dns", name, name, issuer.PermittedDNSDomains, issuer.ExcludedDNSDomains)
+	}
+
+	for _, uri := range tmpl.URIs {
+		checkNameConstraint(v, "uri", uri.String(), uri.Hostname(), issuer.PermittedURIDomains, issuer.ExcludedURIDomains)
+	}
+
+	for _, email := range tmpl.EmailAddresses {
+		checkEmailConstraint(v, email, issuer.PermittedEmailAddresses, issuer.ExcludedEmailAddresses)
+	}
+
+	for _, ip := range tmpl.IPAddresses {
+
+		if len(issuer.PermittedIPRanges) > 0 && !ipInRanges(ip, issuer.PermittedIPRanges) {
+			v.add("name-constraint-not-permitted", "ip", ip.String(), "IP address is not within the issuer permitted ranges")
+		}
+
+		if ipInRanges(ip, issuer.ExcludedIPRanges) {
+			v.add("name-constraint-excluded", "ip", ip.String(), "IP address is within a issuer excluded range")
+		}
+
+	}
+
+}
+
+// validatePolicy checks the _tmpl_ against the operator _policy_.
+func validatePolicy(v *violations, policy *IssuancePolicy, tmpl *x509.Certificate) {
+
+	if tmpl.IsCA && !policy.AllowCA {
+		v.add("ca-not-allowed", "basic-constraints", "ca", "policy do not allow issuing CA certificates")
+	}
+
+	if policy.MaxValidity > 0 && tmpl.NotAfter.Sub(tmpl.NotBefore) > policy.MaxValidity {
+
+		v.add("validity-too-long", "validity", tmpl.NotAfter.Sub(tmpl.NotBefore).String(),
+			"validity exceeds the policy maximum of %s", policy.MaxValidity,
+		)
+
+	}
+
+	for _, name := range tmpl.DNSNames {
+
+		if strings.HasPrefix(name, "*.") && !policy.AllowWildcards {
+			v.add("wildcard-not-allowed", "dns", name, "policy do not allow wildcard names")
+		}
+
+		if len(policy.AllowedDomains) > 0 && !matchesAnyDomain(name, policy.AllowedDomains) {
+			v.add("domain-not-allowed", "dns", name, "DNS name is not within the policy allowed domains")
+		}
+
+	}
+
+	for _, uri := range tmpl.URIs {
+
+		if len(policy.AllowedDomains) > 0 && !matchesAnyDomain(uri.Hostname(), policy.AllowedDomains) {
+			v.add("domain-not-allowed", "uri", uri.String(), "URI host is not within the policy allowed domains")
+		}
+
+	}
+
+	for _, ip := range tmpl.IPAddresses {
+
+		if len(policy.AllowedIPRanges) > 0 && !ipInRanges(ip, policy.AllowedIPRanges) {
+			v.add("ip-not-allowed", "ip", ip.String(), "IP address is not within the policy allowed ranges")
+		}
+
+	}
+
+	for _, email := range tmpl.EmailAddresses {
+
+		domain := email[strings.LastIndex(email, "@")+1:]
+
+		if len(policy.AllowedEmailDomains) > 0 && !matchesAnyDomain(domain, policy.AllowedEmailDomains) {
+			v.add("email-not-allowed", "email", email, "email domain is not within the policy allowed domains")
+		}
+
+	}
+
+	if len(policy.AllowedExtKeyUsages) > 0 {
+
+		for _, eku := range tmpl.ExtKeyUsage {
+
+			if !containsExtKeyUsage(policy.AllowedExtKeyUsages, eku) {
+				v.add("eku-not-allowed", "eku", extKeyUsageName(eku), "extended key usage is not allowed by policy")
+			}
+
+		}
+
+	}
+
+}
+
+// checkNameConstraint validates a _DNS_ or _URI_ host against the permitted and excluded domains.
+func checkNameConstraint(v *violations, field, value, host string, permitted, excluded []string) {
+
+	if len(permitted) > 0 && !matchesAnyDomain(host, permitted) {
+		v.add("name-constraint-not-permitted", field, value, "name is not within the issuer permitted subtrees")
+	}
+
+	if matchesAnyDomain(host, excluded) {
+		v.add("name-constraint-excluded", field, value, "name is within a issuer excluded subtree")
+	}
+
+}
+
+// checkEmailConstraint validates the _email_ as of _RFC 5280_ where a constraint is either a full
+// mailbox, a host or a domain with a leading dot.
+func checkEmailConstraint(v *violations, email string, permitted, excluded []string) {
+
+	matches := func(constraints []string) bool {
+
+		for _, c := range constraints {
+
+			if strings.Contains(c, "@") {
+
+				if strings.EqualFold(c, email) {
+					return true
+				}
+
+				continue
+
+			}
+
+			domain := email[strings.LastIndex(email, "@")+1:]
+
+			if strings.HasPrefix(c, ".") {
+
+				if matchDomain(domain, c) {
+					return true
+				}
+
+				continue
+
+			}
+
+			if strings.EqualFold(domain, c) {
+				return true
+			}
+
+		}
+
+		return false
+
+	}
+
+	if len(permitted) > 0 && !matches(permitted) {
+		v.add("name-constraint-not-permitted", "email", email, "email is not within the issuer permitted subtrees")
+	}
+
+	if matches(excluded) {
+		v.add("name-constraint-excluded", "email", email, "email is within a issuer excluded subtree")
+	}
+
+}
+
+// matchesAnyDomain returns `true` if _name_ matches any of the _domains_.
+func matchesAnyDomain(name string, domains []string) bool {
+
+	for _, domain := range domains {
+
+		if matchDomain(name, domain) {
+			return true
+		}
+
+	}
+
+	return false
+
+}
+
+// matchDomain matches _name_ against the _domain_ constraint.
+//
+// A _domain_ with a leading dot only matches subdomains, otherwise the domain itself and
+// all its subdomains matches. A wildcard _name_ is matched on its base domain.
+func matchDomain(name, domain string) bool {
+
+	name = strings.ToLower(strings.TrimSuffix(name, "."))
+	domain = strings.ToLower(strings.TrimSuffix(domain, "."))
+
+	if domain == "" {
+		return true
+	}
+
+	if strings.HasPrefix(domain, ".") {
+		return strings.HasSuffix(name, domain) && len(name) > len(domain)
+	}
+
+	return name == domain || strings.HasSuffix(name, "."+domain)
+
+}
+
+// ipInRanges returns `true` if _ip_ is within any of the _ranges_.
+func ipInRanges(ip net.IP, ranges []*net.IPNet) bool {
+
+	for _, r := range ranges {
+
+		if r.Contains(ip) {
+			return true
+		}
+
+	}
+
+	return false
+
+}
+
+// containsExtKeyUsage returns `true` if _eku_ is in _set_.
+func containsExtKeyUsage(set []x509.ExtKeyUsage, eku x509.ExtKeyUsage) bool {
+
+	for _, e := range set {
+
+		if e == eku {
+			return true
+		}
+
+	}
+
+	return false
+
+}
+
+// extKeyUsageNames is the names of the well-known extended key usages.
+var extKeyUsageNames = map[x509.ExtKeyUsage]string{
+	x509.ExtKeyUsageAny:             "any",
+	x509.ExtKeyUsageServerAuth:      "server-auth",
+	x509.ExtKeyUsageClientAuth:      "client-auth",
+	x509.ExtKeyUsageCodeSigning:     "code-signing",
+	x509.ExtKeyUsageEmailProtection: "email-protection",
+	x509.ExtKeyUsageTimeStamping:    "time-stamping",
+	x509.ExtKeyUsageOCSPSigning:     "ocsp-signing",
+}
+
+// extKeyUsageName returns a readable name of the _eku_.
+func extKeyUsageName(eku x509.ExtKeyUsage) string {
+
+	if name, ok := extKeyUsageNames[eku]; ok {
+		return name
+	}
+
+	return fmt.Sprintf("eku-%d", int(eku))
+
+}
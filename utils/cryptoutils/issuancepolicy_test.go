@@ -0,0 +1,89 @@
+package cryptoutils
+
+import (
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"errors"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidateIssuanceNameConstraintsAndPolicy(t *testing.T) {
+
+	_, rootKey := issue(t, CertificateProfileCA, CertificateSubject{Subject: pkix.Name{CommonName: "root"}}, nil, nil)
+
+	tmpl, _, err := NewCertificateTemplate(CertificateProfileCA, CertificateSubject{Subject: pkix.Name{CommonName: "root"}})
+	require.NoError(t, err)
+
+	_, ipnet, _ := net.ParseCIDR("10.0.0.0/8")
+
+	tmpl.PermittedDNSDomainsCritical = true
+	tmpl.PermittedDNSDomains = []string{"example.com"}
+	tmpl.ExcludedDNSDomains = []string{"secret.example.com"}
+	tmpl.PermittedIPRanges = []*net.IPNet{ipnet}
+
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &rootKey.PublicKey, rootKey)
+	require.NoError(t, err)
+
+	ca, err := x509.ParseCertificate(der)
+	require.NoError(t, err)
+
+	leaf, _, err := NewCertificateTemplate(CertificateProfileTLSServer, CertificateSubject{
+		DNSNames:    []string{"www.example.com", "host.secret.example.com", "example.org", "*.example.com"},
+		IPAddresses: []net.IP{net.ParseIP("10.1.2.3"), net.ParseIP("192.168.1.1")},
+	})
+	require.NoError(t, err)
+
+	leaf.ExtKeyUsage = append(leaf.ExtKeyUsage, x509.ExtKeyUsageCodeSigning)
+
+	err = ValidateIssuance(ca, leaf, &IssuancePolicy{
+		AllowedExtKeyUsages: []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		MaxValidity:         24 * time.Hour,
+	})
+
+	var violation *IssuanceViolationError
+	require.True(t, errors.As(err, &violation))
+
+	rules := map[string]string{}
+	for _, v := range violation.Violations {
+		rules[v.Rule+"/"+v.Value] = v.Field
+	}
+
+	assert.Contains(t, rules, "name-constraint-excluded/host.secret.example.com")
+	assert.Contains(t, rules, "name-constraint-not-permitted/example.org")
+	assert.Contains(t, rules, "name-constraint-not-permitted/192.168.1.1")
+	assert.Contains(t, rules, "wildcard-not-allowed/*.example.com")
+	assert.Contains(t, rules, "eku-not-allowed/code-signing")
+	assert.NotContains(t, rules, "name-constraint-not-permitted/www.example.com")
+	assert.NotContains(t, rules, "name-constraint-not-permitted/10.1.2.3")
+
+	hasValidity := false
+	for _, v := range violation.Violations {
+		hasValidity = hasValidity || v.Rule == "validity-too-long"
+	}
+	assert.True(t, hasValidity)
+
+	ok, _, err := NewCertificateTemplate(CertificateProfileTLSServer, CertificateSubject{
+		DNSNames: []string{"api.example.com"},
+	})
+	require.NoError(t, err)
+
+	assert.NoError(t, ValidateIssuance(ca, ok, &IssuancePolicy{AllowedDomains: []string{".example.com"}}))
+	assert.Error(t, ValidateIssuance(ca, ok, &IssuancePolicy{AllowedDomains: []string{"example.org"}}))
+
+}
+
+func TestMatchDomain(t *testing.T) {
+
+	assert.True(t, matchDomain("example.com", "example.com"))
+	assert.True(t, matchDomain("a.Example.com.", "example.com"))
+	assert.False(t, matchDomain("badexample.com", "example.com"))
+	assert.False(t, matchDomain("example.com", ".example.com"))
+	assert.True(t, matchDomain("a.example.com", ".example.com"))
+
+}
@@ -0,0 +1,421 @@
+package cryptoutils
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math/big"
+)
+
+// JSONWebKey is a _RFC 7517_ _JSON Web Key_.
+//
+// All binary members are base64url encoded without padding, except _x5c_ that is standard
+// base64 encoded _DER_ certificates.
+type JSONWebKey struct {
+	KeyType   string   `json:"kty"`
+	KeyID     string   `json:"kid,omitempty"`
+	Use       string   `json:"use,omitempty"`
+	KeyOps    []string `json:"key_ops,omitempty"`
+	Algorithm string   `json:"alg,omitempty"`
+	Curve     string   `json:"crv,omitempty"`
+	X         string   `json:"x,omitempty"`
+	Y         string   `json:"y,omitempty"`
+	N         string   `json:"n,omitempty"`
+	E         string   `json:"e,omitempty"`
+	D         string   `json:"d,omitempty"`
+	P         string   `json:"p,omitempty"`
+	Q         string   `json:"q,omitempty"`
+	DP        string   `json:"dp,omitempty"`
+	DQ        string   `json:"dq,omitempty"`
+	QI        string   `json:"qi,omitempty"`
+	K         string   `json:"k,omitempty"`
+	X5C       [][]byte `json:"x5c,omitempty"`
+}
+
+// jwkCurves maps the _JWK_ curve names to the curves.
+var jwkCurves = map[string]elliptic.Curve{
+	"P-256":     elliptic.P256(),
+	"P-384":     elliptic.P384(),
+	"P-521":     elliptic.P521(),
+	"secp256k1": Secp256k1(),
+}
+
+// JWKCurveAlgorithm returns the _JWS_ algorithm of a _JWK_ _curve_, e.g. _ES256_ for _P-256_.
+func JWKCurveAlgorithm(curve string) string {
+
+	switch curve {
+	case "P-256":
+		return "ES256"
+	case "P-384":
+		return "ES384"
+	case "P-521":
+		return "ES512"
+	case "secp256k1":
+		return "ES256K"
+	case "Ed25519":
+		return "EdDSA"
+	case "X25519":
+		return "ECDH-ES"
+	}
+
+	return ""
+
+}
+
+// PublicKeyToJWK creates the _JWK_ of a `*rsa.PublicKey`, `*ecdsa.PublicKey`, including
+// _secp256k1_, or `ed25519.PublicKey`.
+func PublicKeyToJWK(key crypto.PublicKey) (*JSONWebKey, error) {
+
+	switch pub := key.(type) {
+	case *rsa.PublicKey:
+
+		return &JSONWebKey{
+			KeyType: "RSA",
+			N:       b64(pub.N.Bytes()),
+			E:       b64(big.NewInt(int64(pub.E)).Bytes()),
+		}, nil
+
+	case *ecdsa.PublicKey:
+
+		name := pub.Curve.Params().Name
+		if _, ok := jwkCurves[name]; !ok {
+			return nil, fmt.Errorf("unsupported JWK curve: %s", name)
+		}
+
+		size := ECDSACoordinateSize(pub.Curve)
+
+		return &JSONWebKey{
+			KeyType: "EC",
+			Curve:   name,
+			X:       b64(pub.X.FillBytes(make([]byte, size))),
+			Y:       b64(pub.Y.FillBytes(make([]byte, size))),
+		}, nil
+
+	case ed25519.PublicKey:
+
+		return OctetKeyPairToJWK("Ed25519", pub, nil), nil
+
+	}
+
+	return nil, fmt.Errorf("unsupported JWK public key: %T", key)
+
+}
+
+// PrivateKeyToJWK creates the _JWK_, including the private members, of a `*rsa.PrivateKey`,
+// `*ecdsa.PrivateKey`, including _secp256k1_, or `ed25519.PrivateKey`.
+func PrivateKeyToJWK(key crypto.PrivateKey) (*JSONWebKey, error) {
+
+	switch priv := key.(type) {
+	case *rsa.PrivateKey:
+
+		if len(priv.Primes) != 2 {
+			return nil, fmt.Errorf("multi-prime RSA keys are not supported")
+		}
+
+		jwk, _ := PublicKeyToJWK(&priv.PublicKey)
+
+		priv.Precompute()
+
+		jwk.D = b64(priv.D.Bytes())
+		jwk.P = b64(priv.Primes[0].Bytes())
+		jwk.Q = b64(priv.Primes[1].Bytes())
+		jwk.DP = b64(priv.Precomputed.Dp.Bytes())
+		jwk.DQ = b64(priv.Precomputed.Dq.Bytes())
+		jwk.QI = b64(priv.Precomputed.Qinv.Bytes())
+
+		return jwk, nil
+
+	case *ecdsa.PrivateKey:
+
+		jwk, err := PublicKeyToJWK(&priv.PublicKey)
+		if err != nil {
+			return nil, err
+		}
+
+		jwk.D = b64(priv.D.FillBytes(make([]byte, ECDSACoordinateSize(priv.Curve))))
+
+		return jwk, nil
+
+	case ed25519.PrivateKey:
+
+		return OctetKeyPairToJWK("Ed25519", priv.Public().(ed25519.PublicKey), priv.Seed()), nil
+
+	}
+
+	return nil, fmt.Errorf("unsupported JWK private key: %T", key)
+
+}
+
+// OctetKeyPairToJWK creates a _RFC 8037_ _OKP_ key, e.g. _Ed25519_ or _X25519_. The _private_
+// key is optional.
+func OctetKeyPairToJWK(curve string, public, private []byte) *JSONWebKey {
+
+	jwk := &JSONWebKey{KeyType: "OKP", Curve: curve, X: b64(public)}
+
+	if private != nil {
+		jwk.D = b64(private)
+	}
+
+	return jwk
+
+}
+
+// SymmetricKeyToJWK creates a _oct_ key.
+func SymmetricKeyToJWK(key []byte) *JSONWebKey {
+	return &JSONWebKey{KeyType: "oct", K: b64(key)}
+}
+
+// IsPrivate returns `true` if the _JWK_ holds private or symmetric key material.
+func (j *JSONWebKey) IsPrivate() bool {
+	return j.D != "" || j.K != ""
+}
+
+// Public returns a copy without the private members.
+func (j *JSONWebKey) Public() *JSONWebKey {
+
+	public := *j
+	public.D, public.P, public.Q, public.DP, public.DQ, public.QI, public.K = "", "", "", "", "", "", ""
+
+	return &public
+
+}
+
+// PublicKey returns the `*rsa.PublicKey`, `*ecdsa.PublicKey` or `ed25519.PublicKey`. A
+// _X25519_ key is returned as the 32 byte `[]byte` public key.
+func (j *JSONWebKey) PublicKey() (crypto.PublicKey, error) {
+
+	switch j.KeyType {
+	case "RSA":
+
+		n, err := b64Int(j.N)
+		if err != nil {
+			return nil, err
+		}
+
+		e, err := b64Int(j.E)
+		if err != nil {
+			return nil, err
+		}
+
+		if n.Sign() <= 0 || !e.IsInt64() || e.Int64() < 3 || e.Int64() > 1<<31-1 {
+			return nil, fmt.Errorf("invalid RSA JWK")
+		}
+
+		return &rsa.PublicKey{N: n, E: int(e.Int64())}, nil
+
+	case "EC":
+
+		curve, ok := jwkCurves[j.Curve]
+		if !ok {
+			return nil, fmt.Errorf("unsupported JWK curve: %s", j.Curve)
+		}
+
+		x, err := b64Int(j.X)
+		if err != nil {
+			return nil, err
+		}
+
+		y, err := b64Int(j.Y)
+		if err != nil {
+			return nil, err
+		}
+
+		if !curve.IsOnCurve(x, y) {
+			return nil, fmt.Errorf("JWK point is not on curve %s", j.Curve)
+		}
+
+		return &ecdsa.PublicKey{Curve: curve, X: x, Y: y}, nil
+
+	case "OKP":
+
+		x, err := base64.RawURLEncoding.DecodeString(j.X)
+		if err != nil {
+			return nil, err
+		}
+
+		if len(x) != 32 {
+			return nil, fmt.Errorf("invalid %s JWK public key size: %d", j.Curve, len(x))
+		}
+
+		switch j.Curve {
+		case "Ed25519":
+			return ed25519.PublicKey(x), nil
+		case "X25519":
+			return x, nil
+		}
+
+		return nil, fmt.Errorf("unsupported JWK curve: %s", j.Curve)
+
+	}
+
+	return nil, fmt.Errorf("JWK key type %s has no public key", j.KeyType)
+
+}
+
+// PrivateKey returns the `*rsa.PrivateKey`, `*ecdsa.PrivateKey` or `ed25519.PrivateKey`. A
+// _X25519_ key is returned as the 32 byte `[]byte` private key.
+//
+// The private key is validated against the public members.
+func (j *JSONWebKey) PrivateKey() (crypto.PrivateKey, error) {
+
+	if j.D == "" {
+		return nil, fmt.Errorf("JWK has no private key")
+	}
+
+	public, err := j.PublicKey()
+	if err != nil {
+		return nil, err
+	}
+
+	d, err := base64.RawURLEncoding.DecodeString(j.D)
+	if err != nil {
+		return nil, err
+	}
+
+	switch pub := public.(type) {
+	case *rsa.PublicKey:
+
+		p, err := b64Int(j.P)
+		if err != nil {
+			return nil, err
+		}
+
+		q, err := b64Int(j.Q)
+		if err != nil {
+			return nil, err
+		}
+
+		key := &rsa.PrivateKey{
+			PublicKey: *pub,
+			D:         new(big.Int).SetBytes(d),
+			Primes:    []*big.Int{p, q},
+		}
+
+		if err := key.Validate(); err != nil {
+			return nil, err
+		}
+
+		key.Precompute()
+
+		return key, nil
+
+	case *ecdsa.PublicKey:
+
+		key := &ecdsa.PrivateKey{PublicKey: *pub, D: new(big.Int).SetBytes(d)}
+
+		x, y := pub.Curve.ScalarBaseMult(d)
+
+		if key.D.Sign() <= 0 || key.D.Cmp(pub.Curve.Params().N) >= 0 || x.Cmp(pub.X) != 0 || y.Cmp(pub.Y) != 0 {
+			return nil, fmt.Errorf("JWK private key do not match the public key")
+		}
+
+		return key, nil
+
+	case ed25519.PublicKey:
+
+		if len(d) != ed25519.SeedSize {
+			return nil, fmt.Errorf("invalid Ed25519 JWK private key size: %d", len(d))
+		}
+
+		key := ed25519.NewKeyFromSeed(d)
+
+		if !pub.Equal(key.Public()) {
+			return nil, fmt.Errorf("JWK private key do not match the public key")
+		}
+
+		return key, nil
+
+	case []byte:
+
+		if len(d) != 32 {
+			return nil, fmt.Errorf("invalid X25519 JWK private key size: %d", len(d))
+		}
+
+		return d, nil
+
+	}
+
+	return nil, fmt.Errorf("unsupported JWK key type: %s", j.KeyType)
+
+}
+
+// SymmetricKey returns the key of a _oct_ _JWK_.
+func (j *JSONWebKey) SymmetricKey() ([]byte, error) {
+
+	if j.KeyType != "oct" {
+		return nil, fmt.Errorf("JWK key type %s is not symmetric", j.KeyType)
+	}
+
+	return base64.RawURLEncoding.DecodeString(j.K)
+
+}
+
+// Thumbprint returns the _RFC 7638_ _JWK_ thumbprint using _hash_, normally _SHA-256_.
+//
+// Only the required public members are hashed, hence a private _JWK_ has the same thumbprint
+// as its public portion. Use it e.g. as a stable _kid_.
+func (j *JSONWebKey) Thumbprint(hash crypto.Hash) ([]byte, error) {
+
+	if !hash.Available() {
+		return nil, fmt.Errorf("hash %s is not available", hash)
+	}
+
+	var members map[string]string
+
+	switch j.KeyType {
+	case "RSA":
+		members = map[string]string{"e": j.E, "kty": j.KeyType, "n": j.N}
+	case "EC":
+		members = map[string]string{"crv": j.Curve, "kty": j.KeyType, "x": j.X, "y": j.Y}
+	case "OKP":
+		members = map[string]string{"crv": j.Curve, "kty": j.KeyType, "x": j.X}
+	case "oct":
+		members = map[string]string{"k": j.K, "kty": j.KeyType}
+	default:
+		return nil, fmt.Errorf("unsupported JWK key type: %s", j.KeyType)
+	}
+
+	for name, value := range members {
+
+		if value == "" {
+			return nil, fmt.Errorf("missing JWK member %s", name)
+		}
+
+	}
+
+	// The members are marshalled in lexicographic order without whitespace
+	data, err := json.Marshal(members)
+	if err != nil {
+		return nil, err
+	}
+
+	h := hash.New()
+	h.Write(data)
+
+	return h.Sum(nil), nil
+
+}
+
+func b64(data []byte) string {
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func b64Int(s string) (*big.Int, error) {
+
+	if s == "" {
+		return nil, fmt.Errorf("missing JWK member")
+	}
+
+	data, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, err
+	}
+
+	return new(big.Int).SetBytes(data), nil
+
+}
@@ -0,0 +1,137 @@
+package cryptoutils
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestJWKEd25519RFC8037Vector(t *testing.T) {
+
+	var jwk JSONWebKey
+
+	require.NoError(t, json.Unmarshal([]byte(`{"kty":"OKP","crv":"Ed25519",
+		"d":"nWGxne_9WmC6hEr0kuwsxERJxWl7MmkZcDusAxyuf2A",
+		"x":"11qYAYKxCrfVS_7TyWQHOg7hcvPapiMlrwIaaPcHURo"}`), &jwk))
+
+	key, err := jwk.PrivateKey()
+	require.NoError(t, err)
+
+	back, err := PrivateKeyToJWK(key)
+	require.NoError(t, err)
+	assert.Equal(t, jwk, *back)
+
+	jwk.X = "AAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAA"
+
+	_, err = jwk.PrivateKey()
+	assert.Error(t, err)
+
+}
+
+func TestJWKRoundTrip(t *testing.T) {
+
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+
+	p384, err := ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
+	require.NoError(t, err)
+
+	k1, err := ecdsa.GenerateKey(Secp256k1(), rand.Reader)
+	require.NoError(t, err)
+
+	_, edKey, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+
+	for _, key := range []interface{}{rsaKey, p384, k1, edKey} {
+
+		jwk, err := PrivateKeyToJWK(key)
+		require.NoError(t, err)
+		assert.True(t, jwk.IsPrivate())
+
+		data, err := json.Marshal(jwk)
+		require.NoError(t, err)
+
+		var parsed JSONWebKey
+		require.NoError(t, json.Unmarshal(data, &parsed))
+
+		private, err := parsed.PrivateKey()
+		require.NoError(t, err)
+
+		assert.True(t, private.(interface {
+			Equal(x crypto.PrivateKey) bool
+		}).Equal(key))
+
+		public := parsed.Public()
+		assert.False(t, public.IsPrivate())
+
+		_, err = public.PrivateKey()
+		assert.Error(t, err)
+
+		_, err = public.PublicKey()
+		assert.NoError(t, err)
+
+	}
+
+}
+
+func TestJWKRejectsPointNotOnCurve(t *testing.T) {
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	jwk, err := PublicKeyToJWK(&key.PublicKey)
+	require.NoError(t, err)
+
+	jwk.Y = jwk.X
+
+	_, err = jwk.PublicKey()
+	assert.Error(t, err)
+
+	p224, err := ecdsa.GenerateKey(elliptic.P224(), rand.Reader)
+	require.NoError(t, err)
+
+	_, err = PublicKeyToJWK(&p224.PublicKey)
+	assert.Error(t, err)
+
+}
+
+func TestJWKThumbprintRFC7638AndRFC8037Vectors(t *testing.T) {
+
+	rsaJWK := JSONWebKey{
+		KeyType:   "RSA",
+		KeyID:     "2011-04-29",
+		Algorithm: "RS256",
+		N: "0vx7agoebGcQSuuPiLJXZptN9nndrQmbXEps2aiAFbWhM78LhWx4cbbfAAtVT86zwu1RK7aPFFxuhDR1L6tSoc_BJECPebWKRX" +
+			"jBZCiFV4n3oknjhMstn64tZ_2W-5JsGY4Hc5n9yBXArwl93lqt7_RN5w6Cf0h4QyQ5v-65YGjQR0_FDW2QvzqY368QQMicAtaSq" +
+			"zs8KJZgnYb9c7d0zgdAZHzu6qMQvRL5hajrn1n91CbOpbISD08qNLyrdkt-bFTWhAI4vMQFh6WeZu0fM4lFd2NcRwr3XPksINHa" +
+			"Q-G_xBniIqbw0Ls1jF44-csFCur-kEgU8awapJzKnqDKgw",
+		E: "AQAB",
+	}
+
+	thumbprint, err := rsaJWK.Thumbprint(crypto.SHA256)
+	require.NoError(t, err)
+	assert.Equal(t, "NzbLsXh8uDCcd-6MNwXF4W_7noWXFZAfHkxZsRGC9Xs", b64(thumbprint))
+
+	okpJWK := JSONWebKey{
+		KeyType: "OKP",
+		Curve:   "Ed25519",
+		D:       "nWGxne_9WmC6hEr0kuwsxERJxWl7MmkZcDusAxyuf2A",
+		X:       "11qYAYKxCrfVS_7TyWQHOg7hcvPapiMlrwIaaPcHURo",
+	}
+
+	thumbprint, err = okpJWK.Thumbprint(crypto.SHA256)
+	require.NoError(t, err)
+	assert.Equal(t, "kPrK_qmxVWaYVA9wwBF6Iuo3vVzz7TxHCTwXBygrS4k", b64(thumbprint))
+
+	_, err = (&JSONWebKey{KeyType: "EC", Curve: "P-256"}).Thumbprint(crypto.SHA256)
+	assert.Error(t, err)
+
+}
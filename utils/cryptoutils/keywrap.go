@@ -0,0 +1,218 @@
+package cryptoutils
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/subtle"
+	"encoding/binary"
+	"fmt"
+)
+
+// defaultKeyWrapIV is the default initial value as of _RFC 3394_ section 2.2.3.1.
+var defaultKeyWrapIV = []byte{0xA6, 0xA6, 0xA6, 0xA6, 0xA6, 0xA6, 0xA6, 0xA6}
+
+// alternativeKeyWrapIV is the 32-bit constant prefix of the alternative initial
+// value as of _RFC 5649_ section 3.
+var alternativeKeyWrapIV = []byte{0xA6, 0x59, 0x59, 0xA6}
+
+// AESKeyWrap wraps the _plaintext_ using the _kek_ as of _RFC 3394_.
+//
+// The _plaintext_ must be a multiple of 64 bits and at least 128 bits. Use
+// `AESKeyWrapWithPadding` when the key material do not fulfill those requirements.
+func AESKeyWrap(kek, plaintext []byte) ([]byte, error) {
+
+	if len(plaintext) < 16 || len(plaintext)%8 != 0 {
+
+		return nil, fmt.Errorf(
+			"plaintext must be a multiple of 8 bytes and at least 16 bytes, got: %d",
+			len(plaintext),
+		)
+
+	}
+
+	block, err := aes.NewCipher(kek)
+	if err != nil {
+		return nil, err
+	}
+
+	return wrapBlocks(block, defaultKeyWrapIV, plaintext), nil
+
+}
+
+// AESKeyUnwrap unwraps the _wrapped_ key material using the _kek_ as of _RFC 3394_.
+func AESKeyUnwrap(kek, wrapped []byte) ([]byte, error) {
+
+	if len(wrapped) < 24 || len(wrapped)%8 != 0 {
+		return nil, fmt.Errorf("invalid wrapped key length: %d", len(wrapped))
+	}
+
+	block, err := aes.NewCipher(kek)
+	if err != nil {
+		return nil, err
+	}
+
+	iv, plaintext := unwrapBlocks(block, wrapped)
+
+	if subtle.ConstantTimeCompare(iv, defaultKeyWrapIV) != 1 {
+		return nil, fmt.Errorf("key unwrap integrity check failed")
+	}
+
+	return plaintext, nil
+
+}
+
+// AESKeyWrapWithPadding wraps the _plaintext_ using the _kek_ as of _RFC 5649_.
+//
+// This is the _AES-KWP_ algorithm, also known as _CKM_AES_KEY_WRAP_PAD_, that is
+// used by e.g. _AWS KMS_ and _Azure Key Vault_ when importing key material.
+func AESKeyWrapWithPadding(kek, plaintext []byte) ([]byte, error) {
+
+	if len(plaintext) == 0 {
+		return nil, fmt.Errorf("plaintext must not be empty")
+	}
+
+	block, err := aes.NewCipher(kek)
+	if err != nil {
+		return nil, err
+	}
+
+	iv := make([]byte, 8)
+	copy(iv, alternativeKeyWrapIV)
+	binary.BigEndian.PutUint32(iv[4:], uint32(len(plaintext)))
+
+	padded := make([]byte, (len(plaintext)+7)/8*8)
+	copy(padded, plaintext)
+
+	if len(padded) == 8 {
+
+		out := make([]byte, 16)
+		copy(out, iv)
+		copy(out[8:], padded)
+
+		block.Encrypt(out, out)
+
+		return out, nil
+
+	}
+
+	return wrapBlocks(block, iv, padded), nil
+
+}
+
+// AESKeyUnwrapWithPadding unwraps the _wrapped_ key material using the _kek_ as of _RFC 5649_.
+func AESKeyUnwrapWithPadding(kek, wrapped []byte) ([]byte, error) {
+
+	if len(wrapped) < 16 || len(wrapped)%8 != 0 {
+		return nil, fmt.Errorf("invalid wrapped key length: %d", len(wrapped))
+	}
+
+	block, err := aes.NewCipher(kek)
+	if err != nil {
+		return nil, err
+	}
+
+	var iv, padded []byte
+
+	if len(wrapped) == 16 {
+
+		out := make([]byte, 16)
+		block.Decrypt(out, wrapped)
+
+		iv, padded = out[:8], out[8:]
+
+	} else {
+
+		iv, padded = unwrapBlocks(block, wrapped)
+
+	}
+
+	if subtle.ConstantTimeCompare(iv[:4], alternativeKeyWrapIV) != 1 {
+		return nil, fmt.Errorf("key unwrap integrity check failed")
+	}
+
+	size := int(binary.BigEndian.Uint32(iv[4:]))
+
+	if size > len(padded) || size <= len(padded)-8 {
+		return nil, fmt.Errorf("key unwrap integrity check failed")
+	}
+
+	for _, b := range padded[size:] {
+
+		if b != 0 {
+			return nil, fmt.Errorf("key unwrap integrity check failed")
+		}
+
+	}
+
+	return padded[:size], nil
+
+}
+
+// wrapBlocks is the wrapping process _W_ as of _RFC 3394_ section 2.2.1.
+func wrapBlocks(block cipher.Block, iv, plaintext []byte) []byte {
+
+	n := len(plaintext) / 8
+
+	out := make([]byte, len(plaintext)+8)
+	copy(out, iv)
+	copy(out[8:], plaintext)
+
+	b := make([]byte, 16)
+
+	for j := 0; j < 6; j++ {
+
+		for i := 1; i <= n; i++ {
+
+			copy(b, out[:8])
+			copy(b[8:], out[i*8:i*8+8])
+
+			block.Encrypt(b, b)
+
+			t := uint64(n*j + i)
+			binary.BigEndian.PutUint64(out[:8], binary.BigEndian.Uint64(b[:8])^t)
+
+			copy(out[i*8:], b[8:])
+
+		}
+
+	}
+
+	return out
+
+}
+
+// unwrapBlocks is the unwrapping process _W^-1_ as of _RFC 3394_ section 2.2.2.
+//
+// It returns the recovered integrity check value along with the plaintext.
+func unwrapBlocks(block cipher.Block, wrapped []byte) (iv []byte, plaintext []byte) {
+
+	n := len(wrapped)/8 - 1
+
+	a := make([]byte, 8)
+	copy(a, wrapped[:8])
+
+	plaintext = make([]byte, n*8)
+	copy(plaintext, wrapped[8:])
+
+	b := make([]byte, 16)
+
+	for j := 5; j >= 0; j-- {
+
+		for i := n; i >= 1; i-- {
+
+			t := uint64(n*j + i)
+			binary.BigEndian.PutUint64(b[:8], binary.BigEndian.Uint64(a)^t)
+			copy(b[8:], plaintext[(i-1)*8:i*8])
+
+			block.Decrypt(b, b)
+
+			copy(a, b[:8])
+			copy(plaintext[(i-1)*8:], b[8:])
+
+		}
+
+	}
+
+	return a, plaintext
+
+}
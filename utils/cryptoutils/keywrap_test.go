@@ -0,0 +1,103 @@
+package cryptoutils
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/hex"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func unhex(t *testing.T, s string) []byte {
+
+	b, err := hex.DecodeString(s)
+	require.NoError(t, err)
+
+	return b
+}
+
+func TestAESKeyWrapRFC3394Vector(t *testing.T) {
+
+	kek := unhex(t, "000102030405060708090A0B0C0D0E0F")
+	key := unhex(t, "00112233445566778899AABBCCDDEEFF")
+
+	wrapped, err := AESKeyWrap(kek, key)
+	require.NoError(t, err)
+
+	assert.Equal(t, unhex(t, "1FA68B0A8112B447AEF34BD8FB5A7B829D3E862371D2CFE5"), wrapped)
+
+	unwrapped, err := AESKeyUnwrap(kek, wrapped)
+	require.NoError(t, err)
+
+	assert.Equal(t, key, unwrapped)
+}
+
+func TestAESKeyWrapWithPaddingRFC5649Vectors(t *testing.T) {
+
+	kek := unhex(t, "5840df6e29b02af1ab493b705bf16ea1ae8338f4dcc176a8")
+
+	tests := []struct {
+		key     string
+		wrapped string
+	}{
+		{
+			key:     "c37b7e6492584340bed12207808941155068f738",
+			wrapped: "138bdeaa9b8fa7fc61f97742e72248ee5ae6ae5360d1ae6a5f54f373fa543b6a",
+		},
+		{
+			key:     "466f7250617369",
+			wrapped: "afbeb0f07dfbf5419200f2ccb50bb24f",
+		},
+	}
+
+	for _, tc := range tests {
+
+		wrapped, err := AESKeyWrapWithPadding(kek, unhex(t, tc.key))
+		require.NoError(t, err)
+
+		assert.Equal(t, unhex(t, tc.wrapped), wrapped)
+
+		unwrapped, err := AESKeyUnwrapWithPadding(kek, wrapped)
+		require.NoError(t, err)
+
+		assert.Equal(t, unhex(t, tc.key), unwrapped)
+
+	}
+
+	wrapped, _ := AESKeyWrapWithPadding(kek, unhex(t, tests[0].key))
+	wrapped[3] ^= 0xFF
+
+	_, err := AESKeyUnwrapWithPadding(kek, wrapped)
+	assert.Error(t, err)
+}
+
+func TestBYOKWrapRoundTrip(t *testing.T) {
+
+	wrappingKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+
+	material := make([]byte, 1218)
+	_, _ = rand.Read(material)
+
+	for _, alg := range []BYOKWrapAlgorithm{BYOKRsaAesKeyWrapSha1, BYOKRsaAesKeyWrapSha256} {
+
+		wrapped, err := BYOKWrap(rand.Reader, &wrappingKey.PublicKey, alg, material)
+		require.NoError(t, err)
+
+		unwrapped, err := BYOKUnwrap(wrappingKey, alg, wrapped)
+		require.NoError(t, err)
+
+		assert.Equal(t, material, unwrapped)
+
+	}
+
+	wrapped, err := BYOKWrap(rand.Reader, &wrappingKey.PublicKey, BYOKRsaOaepSha256, material[:32])
+	require.NoError(t, err)
+
+	unwrapped, err := BYOKUnwrap(wrappingKey, BYOKRsaOaepSha256, wrapped)
+	require.NoError(t, err)
+
+	assert.Equal(t, material[:32], unwrapped)
+}
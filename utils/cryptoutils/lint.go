@@ -0,0 +1,148 @@
+package cryptoutils
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/x509"
+	"fmt"
+	"time"
+)
+
+// ExpiryWarningPeriod is the period before expiry when `LintCertificate` starts to
+// report _expiring-soon_ findings.
+var ExpiryWarningPeriod = 30 * 24 * time.Hour
+
+// LintPublicKey checks the public key for weak parameters.
+//
+// Unknown key types do not produce any findings.
+func LintPublicKey(pub crypto.PublicKey) []LintFinding {
+
+	findings := []LintFinding{}
+
+	switch key := pub.(type) {
+	case *rsa.PublicKey:
+
+		if bits := key.N.BitLen(); bits < 2048 {
+
+			findings = append(findings, LintFinding{
+				Severity: LintError,
+				Code:     "weak-rsa-key",
+				Message:  fmt.Sprintf("RSA key size %d is less than 2048 bits", bits),
+			})
+
+		}
+
+		if key.E < 65537 {
+
+			findings = append(findings, LintFinding{
+				Severity: LintWarning,
+				Code:     "small-rsa-exponent",
+				Message:  fmt.Sprintf("RSA public exponent %d is less than 65537", key.E),
+			})
+
+		}
+
+	case *ecdsa.PublicKey:
+
+		if !isStandardCurve(key.Curve) {
+
+			findings = append(findings, LintFinding{
+				Severity: LintError,
+				Code:     "unknown-ec-curve",
+				Message:  fmt.Sprintf("EC curve %s is not a NIST curve", key.Params().Name),
+			})
+
+		} else if bits := key.Params().BitSize; bits < 256 {
+
+			findings = append(findings, LintFinding{
+				Severity: LintWarning,
+				Code:     "weak-ec-curve",
+				Message:  fmt.Sprintf("EC curve %s has less than 256 bits", key.Params().Name),
+			})
+
+		}
+
+	}
+
+	return findings
+
+}
+
+// LintCertificate checks the _cert_ for weak signatures, weak keys and its validity at _now_.
+func LintCertificate(cert *x509.Certificate, now time.Time) []LintFinding {
+
+	findings := LintPublicKey(cert.PublicKey)
+
+	switch cert.SignatureAlgorithm {
+	case x509.MD2WithRSA,
+		x509.MD5WithRSA,
+		x509.SHA1WithRSA,
+		x509.DSAWithSHA1,
+		x509.ECDSAWithSHA1:
+
+		// Self-signed roots are trusted by their presence, not by their signature
+		if !isSelfSigned(cert) {
+
+			findings = append(findings, LintFinding{
+				Severity: LintError,
+				Code:     "weak-signature-algorithm",
+				Message:  fmt.Sprintf("certificate is signed using %s", cert.SignatureAlgorithm),
+			})
+
+		}
+
+	}
+
+	switch {
+	case now.After(cert.NotAfter):
+
+		findings = append(findings, LintFinding{
+			Severity: LintError,
+			Code:     "expired",
+			Message:  fmt.Sprintf("certificate expired at %s", cert.NotAfter.UTC().Format(time.RFC3339)),
+		})
+
+	case now.Before(cert.NotBefore):
+
+		findings = append(findings, LintFinding{
+			Severity: LintWarning,
+			Code:     "not-yet-valid",
+			Message:  fmt.Sprintf("certificate is not valid until %s", cert.NotBefore.UTC().Format(time.RFC3339)),
+		})
+
+	case cert.NotAfter.Sub(now) < ExpiryWarningPeriod:
+
+		findings = append(findings, LintFinding{
+			Severity: LintWarning,
+			Code:     "expiring-soon",
+			Message:  fmt.Sprintf("certificate expires at %s", cert.NotAfter.UTC().Format(time.RFC3339)),
+		})
+
+	}
+
+	return findings
+
+}
+
+// isStandardCurve returns `true` if _curve_ is one of the _NIST_ curves implemented by
+// the standard library. Custom curves are rejected since their parameters can not be trusted.
+func isStandardCurve(curve elliptic.Curve) bool {
+
+	switch curve {
+	case elliptic.P224(), elliptic.P256(), elliptic.P384(), elliptic.P521():
+		return true
+	}
+
+	return false
+
+}
+
+// isSelfSigned returns `true` if the _cert_ subject is the same as the issuer.
+//
+// The signature is not checked since weak signatures may be refused when verifying.
+func isSelfSigned(cert *x509.Certificate) bool {
+	return bytes.Equal(cert.RawSubject, cert.RawIssuer)
+}
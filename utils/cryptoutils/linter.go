@@ -0,0 +1,257 @@
+package cryptoutils
+
+import (
+	"bufio"
+	"crypto"
+	"crypto/rsa"
+	"crypto/sha1"
+	"crypto/x509"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+// LintAction is what a `Linter` do with a finding.
+type LintAction string
+
+const (
+	// LintActionFail makes the check fail with a `*LintFailure`.
+	LintActionFail LintAction = "fail"
+	// LintActionWarn reports the finding to the warning handler but do not fail.
+	LintActionWarn LintAction = "warn"
+	// LintActionIgnore silently drops the finding.
+	LintActionIgnore LintAction = "ignore"
+)
+
+// LintFailure is returned by a `Linter` when one or more findings has the `LintActionFail` action.
+type LintFailure struct {
+	// Findings is the findings that caused the failure.
+	Findings []LintFinding
+}
+
+// Error implements the `error` interface.
+func (f *LintFailure) Error() string {
+
+	msgs := make([]string, len(f.Findings))
+
+	for i := range f.Findings {
+		msgs[i] = f.Findings[i].String()
+	}
+
+	return fmt.Sprintf("weak crypto material: %s", strings.Join(msgs, ", "))
+
+}
+
+// LinterOption configures a `Linter`.
+type LinterOption func(l *Linter)
+
+// WithLintAction sets the _action_ for findings with the _code_, e.g. to fail
+// on _expiring-soon_ or to ignore _small-rsa-exponent_.
+func WithLintAction(code string, action LintAction) LinterOption {
+	return func(l *Linter) {
+		l.policy[code] = action
+	}
+}
+
+// WithLintWarningHandler sets the function that receives all findings with the
+// `LintActionWarn` action. By default warnings are dropped.
+func WithLintWarningHandler(handler func(finding LintFinding)) LinterOption {
+	return func(l *Linter) {
+		l.warn = handler
+	}
+}
+
+// WithLintBlocklist adds a blocklist of known compromised keys.
+func WithLintBlocklist(blocklist KeyBlocklist) LinterOption {
+	return func(l *Linter) {
+		l.blocklists = append(l.blocklists, blocklist)
+	}
+}
+
+// WithLintClock sets the clock used when checking certificate validity.
+func WithLintClock(now func() time.Time) LinterOption {
+	return func(l *Linter) {
+		l.now = now
+	}
+}
+
+// Linter is a configurable weak crypto validation pass, typically applied when keys and
+// certificates are loaded.
+//
+// Each finding is mapped to a `LintAction` by its code. If no explicit action is configured,
+// `LintError` findings fails and `LintWarning` findings warns.
+//
+// .Example
+// [source,go]
+// ----
+// warn := WithLintWarningHandler(func(f LintFinding) { log.Println(f) })
+// linter := NewLinter(WithLintAction("weak-signature-algorithm", LintActionWarn), warn)
+//
+// err := linter.CheckCertificate(cert)
+// ----
+type Linter struct {
+	policy     map[string]LintAction
+	warn       func(finding LintFinding)
+	blocklists []KeyBlocklist
+	now        func() time.Time
+}
+
+// NewLinter creates a new `Linter`.
+func NewLinter(opts ...LinterOption) *Linter {
+
+	l := &Linter{
+		policy: map[string]LintAction{},
+		now:    time.Now,
+	}
+
+	for _, opt := range opts {
+		opt(l)
+	}
+
+	return l
+
+}
+
+// CheckPublicKey lints the _pub_ key and applies the policy.
+func (l *Linter) CheckPublicKey(pub crypto.PublicKey) error {
+	return l.apply(l.lintPublicKey(pub))
+}
+
+// CheckCertificate lints the _cert_ and applies the policy.
+func (l *Linter) CheckCertificate(cert *x509.Certificate) error {
+
+	findings := LintCertificate(cert, l.now())
+	findings = append(findings, l.lintBlocklists(cert.PublicKey)...)
+
+	return l.apply(findings)
+
+}
+
+// Action returns the `LintAction` for the _finding_.
+func (l *Linter) Action(finding LintFinding) LintAction {
+
+	if action, ok := l.policy[finding.Code]; ok {
+		return action
+	}
+
+	if finding.Severity == LintError {
+		return LintActionFail
+	}
+
+	return LintActionWarn
+
+}
+
+// lintPublicKey runs all public key rules including blocklists.
+func (l *Linter) lintPublicKey(pub crypto.PublicKey) []LintFinding {
+	return append(LintPublicKey(pub), l.lintBlocklists(pub)...)
+}
+
+// lintBlocklists checks _pub_ against all configured blocklists.
+func (l *Linter) lintBlocklists(pub crypto.PublicKey) []LintFinding {
+
+	findings := []LintFinding{}
+
+	for _, blocklist := range l.blocklists {
+
+		if finding, ok := blocklist.Check(pub); ok {
+			findings = append(findings, finding)
+		}
+
+	}
+
+	return findings
+
+}
+
+// apply maps each of the _findings_ to its action.
+func (l *Linter) apply(findings []LintFinding) error {
+
+	failed := []LintFinding{}
+
+	for _, finding := range findings {
+
+		switch l.Action(finding) {
+		case LintActionFail:
+			failed = append(failed, finding)
+		case LintActionWarn:
+			if l.warn != nil {
+				l.warn(finding)
+			}
+		}
+
+	}
+
+	if len(failed) > 0 {
+		return &LintFailure{Findings: failed}
+	}
+
+	return nil
+
+}
+
+// KeyBlocklist is a list of known compromised keys.
+type KeyBlocklist interface {
+	// Check returns a finding and `true` if the _pub_ key is in the blocklist.
+	Check(pub crypto.PublicKey) (LintFinding, bool)
+}
+
+// DebianWeakKeyBlocklist is the blocklist of _RSA_ keys generated by the broken _Debian_
+// _OpenSSL_ package (CVE-2008-0166).
+//
+// The list contains the last 20 hex digits of the _SHA-1_ of _"Modulus=<hex modulus>\n"_
+// as distributed in the _openssl-blacklist_ package.
+type DebianWeakKeyBlocklist map[string]struct{}
+
+// ParseDebianWeakKeyBlocklist reads a _openssl-blacklist_ file from _r_.
+//
+// Blank lines and lines starting with _#_ are ignored. It is possible to call this
+// several times with the same blocklist to merge e.g. one file per key size.
+func ParseDebianWeakKeyBlocklist(r io.Reader, blocklist DebianWeakKeyBlocklist) error {
+
+	scanner := bufio.NewScanner(r)
+
+	for scanner.Scan() {
+
+		line := strings.TrimSpace(scanner.Text())
+
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		if len(line) != 20 {
+			return fmt.Errorf("invalid blocklist entry: %s", line)
+		}
+
+		blocklist[strings.ToLower(line)] = struct{}{}
+
+	}
+
+	return scanner.Err()
+
+}
+
+// Check implements the `KeyBlocklist` interface.
+func (b DebianWeakKeyBlocklist) Check(pub crypto.PublicKey) (LintFinding, bool) {
+
+	key, ok := pub.(*rsa.PublicKey)
+	if !ok {
+		return LintFinding{}, false
+	}
+
+	sum := sha1.Sum([]byte(fmt.Sprintf("Modulus=%X\n", key.N)))
+	fingerprint := hex.EncodeToString(sum[:])[20:]
+
+	if _, found := b[fingerprint]; !found {
+		return LintFinding{}, false
+	}
+
+	return LintFinding{
+		Severity: LintError,
+		Code:     "debian-weak-key",
+		Message:  "RSA key is a known weak Debian OpenSSL key (CVE-2008-0166)",
+	}, true
+
+}
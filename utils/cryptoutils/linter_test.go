@@ -0,0 +1,53 @@
+package cryptoutils
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLinterPolicy(t *testing.T) {
+
+	key, err := rsa.GenerateKey(rand.Reader, 1024)
+	require.NoError(t, err)
+
+	err = NewLinter().CheckPublicKey(&key.PublicKey)
+
+	require.Error(t, err)
+	assert.Equal(t, "weak-rsa-key", err.(*LintFailure).Findings[0].Code)
+
+	warnings := []LintFinding{}
+
+	err = NewLinter(
+		WithLintAction("weak-rsa-key", LintActionWarn),
+		WithLintWarningHandler(func(f LintFinding) { warnings = append(warnings, f) }),
+	).CheckPublicKey(&key.PublicKey)
+
+	assert.NoError(t, err)
+	assert.Len(t, warnings, 1)
+}
+
+func TestLinterDebianWeakKeyBlocklist(t *testing.T) {
+
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+
+	sum := sha1.Sum([]byte(fmt.Sprintf("Modulus=%X\n", key.N)))
+
+	blocklist := DebianWeakKeyBlocklist{}
+	require.NoError(t, ParseDebianWeakKeyBlocklist(
+		strings.NewReader("# comment\n\n"+hex.EncodeToString(sum[:])[20:]+"\n"), blocklist,
+	))
+
+	err = NewLinter(WithLintBlocklist(blocklist)).CheckPublicKey(&key.PublicKey)
+
+	require.Error(t, err)
+	assert.Equal(t, "debian-weak-key", err.(*LintFailure).Findings[0].Code)
+}
@@ -0,0 +1,211 @@
+package cryptoutils
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base32"
+	"encoding/binary"
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+// maskFirstNames is the first names `Masker.MaskName` picks replacements from.
+var maskFirstNames = []string{
+	"Alex", "Billie", "Casey", "Dana", "Eli", "Frankie", "Gale", "Harper",
+	"Indy", "Jamie", "Kai", "Lee", "Morgan", "Noa", "Oakley", "Parker",
+	"Quinn", "Reese", "Sam", "Taylor", "Uma", "Val", "Wren", "Xen",
+	"Yael", "Zion", "Robin", "Sky", "Jules", "Kim", "Charlie", "Ari",
+}
+
+// maskLastNames is the last names `Masker.MaskName` picks replacements from.
+var maskLastNames = []string{
+	"Andersson", "Brown", "Carter", "Dubois", "Evans", "Fischer", "Garcia", "Hansen",
+	"Ito", "Jensen", "Kowalski", "Larsen", "Martin", "Nguyen", "Olsen", "Petrov",
+	"Quist", "Rossi", "Schmidt", "Tanaka", "Urban", "Vargas", "Weber", "Xu",
+	"Young", "Zimmer", "Berg", "Lind", "Novak", "Silva", "Moreau", "Khan",
+}
+
+// maskEncoding is the encoding used for pseudonyms, lower case and without padding.
+var maskEncoding = base32.NewEncoding("abcdefghijklmnopqrstuvwxyz234567").WithPadding(base32.NoPadding)
+
+// Masker deterministically replaces personal data with consistent fake values.
+//
+// The same input and key always produces the same output, hence masked data sets can still be
+// joined on the masked columns. The mapping is keyed using _HMAC-SHA256_, without the key it is
+// not possible to reverse, or confirm a guess of, a masked value. Keep the key as secret as the
+// original data and rotate it to break linkability between exports.
+//
+// NOTE: The masking is one-way, it is not format preserving encryption.
+type Masker struct {
+	key []byte
+}
+
+// NewMasker creates a new `Masker` using _key_, which must be at least 16 bytes.
+func NewMasker(key []byte) (*Masker, error) {
+
+	if len(key) < 16 {
+		return nil, fmt.Errorf("masking key must be at least 16 bytes, got: %d", len(key))
+	}
+
+	return &Masker{key: append([]byte{}, key...)}, nil
+
+}
+
+// Pseudonym returns a stable pseudonym of _value_ within _domain_, e.g. _customer-id_. The
+// same value in different domains gives unrelated pseudonyms.
+func (m *Masker) Pseudonym(domain, value string) string {
+	return maskEncoding.EncodeToString(m.mac("pseudonym", domain, value)[:16])
+}
+
+// MaskEmail returns a fake email address for _email_. The local part is replaced and the domain
+// is kept if _keepDomain_ is `true`, otherwise it is replaced with _example.invalid_.
+//
+// The address is normalized to lower case before masking, so differently cased addresses
+// masks to the same value.
+func (m *Masker) MaskEmail(email string, keepDomain bool) string {
+
+	email = strings.ToLower(strings.TrimSpace(email))
+
+	domain := "example.invalid"
+
+	if idx := strings.LastIndex(email, "@"); idx >= 0 && keepDomain {
+		domain = email[idx+1:]
+	}
+
+	return "user-" + maskEncoding.EncodeToString(m.mac("email", email)[:8]) + "@" + domain
+
+}
+
+// MaskDigits replaces every digit of _value_ while keeping all other characters, hence a
+// formatted phone or account number keeps its format. The replacement only depends on the
+// digits, so _+46 70-123 45 67_ and _+46701234567_ masks to the same digits.
+//
+// The first _keep_ digits are kept as is, e.g. to keep a country code for analysis.
+func (m *Masker) MaskDigits(value string, keep int) string {
+
+	var digits []byte
+
+	for _, r := range value {
+
+		if r >= '0' && r <= '9' {
+			digits = append(digits, byte(r))
+		}
+
+	}
+
+	stream := m.digitStream(string(digits), len(digits))
+
+	var sb strings.Builder
+
+	i := 0
+
+	for _, r := range value {
+
+		if r < '0' || r > '9' {
+			sb.WriteRune(r)
+			continue
+		}
+
+		if i < keep {
+			sb.WriteRune(r)
+		} else {
+			sb.WriteByte('0' + stream[i])
+		}
+
+		i++
+
+	}
+
+	return sb.String()
+
+}
+
+// MaskName replaces a personal name with a fake name. The last word is replaced with a last
+// name and all other words with first names, keeping the number of words.
+func (m *Masker) MaskName(name string) string {
+
+	words := strings.Fields(name)
+
+	for i, word := range words {
+
+		list := maskFirstNames
+		if i == len(words)-1 && len(words) > 1 {
+			list = maskLastNames
+		}
+
+		sum := m.mac("name", strings.ToLower(word), fmt.Sprint(i))
+		replacement := list[binary.BigEndian.Uint32(sum)%uint32(len(list))]
+
+		if isUpper(word) {
+			replacement = strings.ToUpper(replacement)
+		}
+
+		words[i] = replacement
+
+	}
+
+	return strings.Join(words, " ")
+
+}
+
+// mac returns the _HMAC_ of the _kind_ and the _parts_, each zero terminated.
+func (m *Masker) mac(kind string, parts ...string) []byte {
+
+	h := hmac.New(sha256.New, m.key)
+	h.Write([]byte(kind))
+	h.Write([]byte{0})
+
+	for _, part := range parts {
+		h.Write([]byte(part))
+		h.Write([]byte{0})
+	}
+
+	return h.Sum(nil)
+
+}
+
+// digitStream returns _n_ uniformly distributed digits derived from _value_.
+func (m *Masker) digitStream(value string, n int) []byte {
+
+	digits := make([]byte, 0, n)
+
+	for counter := 0; len(digits) < n; counter++ {
+
+		for _, b := range m.mac("digits", value, fmt.Sprint(counter)) {
+
+			// Reject to avoid modulo bias
+			if b < 250 && len(digits) < n {
+				digits = append(digits, b%10)
+			}
+
+		}
+
+	}
+
+	return digits
+
+}
+
+// isUpper returns `true` if all letters of _s_ are upper case and there are more than one.
+func isUpper(s string) bool {
+
+	letters := 0
+
+	for _, r := range s {
+
+		if unicode.IsLetter(r) {
+
+			if !unicode.IsUpper(r) {
+				return false
+			}
+
+			letters++
+
+		}
+
+	}
+
+	return letters > 1
+
+}
@@ -0,0 +1,41 @@
+package cryptoutils
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMaskerIsDeterministicAndKeyed(t *testing.T) {
+
+	m, err := NewMasker([]byte("0123456789abcdef"))
+	require.NoError(t, err)
+
+	other, err := NewMasker([]byte("fedcba9876543210"))
+	require.NoError(t, err)
+
+	email := m.MaskEmail("Jane.Doe@Example.com", true)
+	assert.Equal(t, email, m.MaskEmail("jane.doe@example.com ", true))
+	assert.True(t, strings.HasSuffix(email, "@example.com"))
+	assert.NotEqual(t, email, other.MaskEmail("jane.doe@example.com", true))
+	assert.True(t, strings.HasSuffix(m.MaskEmail("jane.doe@example.com", false), "@example.invalid"))
+
+	phone := m.MaskDigits("+46 70-123 45 67", 2)
+	assert.Len(t, phone, len("+46 70-123 45 67"))
+	assert.True(t, strings.HasPrefix(phone, "+46 "))
+	assert.Equal(t, strings.NewReplacer(" ", "", "-", "").Replace(phone), m.MaskDigits("+46701234567", 2))
+
+	name := m.MaskName("Jane Doe")
+	assert.Equal(t, name, m.MaskName("jane doe"))
+	assert.Len(t, strings.Fields(name), 2)
+	assert.Contains(t, maskLastNames, strings.Fields(name)[1])
+
+	assert.Equal(t, m.Pseudonym("customer", "42"), m.Pseudonym("customer", "42"))
+	assert.NotEqual(t, m.Pseudonym("customer", "42"), m.Pseudonym("order", "42"))
+
+	_, err = NewMasker([]byte("short"))
+	assert.Error(t, err)
+
+}
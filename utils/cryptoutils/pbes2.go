@@ -0,0 +1,224 @@
+package cryptoutils
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/des"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"fmt"
+	"hash"
+	"io"
+
+	"golang.org/x/crypto/pbkdf2"
+)
+
+var (
+	// errPBEUnsupported is returned for password based encryption algorithms that are not
+	// supported.
+	errPBEUnsupported = fmt.Errorf("unsupported password based encryption algorithm")
+	// errPBEDecrypt is returned when the decrypted padding is invalid, i.e. most likely a
+	// incorrect password.
+	errPBEDecrypt = fmt.Errorf("password based decryption failed")
+	// errPBEIterations is returned when a iteration count is below one or above
+	// `pbeMaxIterations`.
+	errPBEIterations = fmt.Errorf("password based encryption iteration count out of range")
+)
+
+// pbeMaxIterations caps the key derivation and _MAC_ iteration counts that are read from
+// encrypted keys and _PKCS #12_ files, otherwise a crafted file may burn the CPU for hours.
+const pbeMaxIterations = 10000000
+
+// checkPBEIterations returns `errPBEIterations` when _iterations_ is outside of
+// 1 to `pbeMaxIterations`.
+func checkPBEIterations(iterations int) error {
+
+	if iterations < 1 || iterations > pbeMaxIterations {
+		return fmt.Errorf("%w: %d", errPBEIterations, iterations)
+	}
+
+	return nil
+
+}
+
+var (
+	oidPBES2          = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 5, 13}
+	oidPBKDF2         = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 5, 12}
+	oidHMACWithSHA1   = asn1.ObjectIdentifier{1, 2, 840, 113549, 2, 7}
+	oidHMACWithSHA256 = asn1.ObjectIdentifier{1, 2, 840, 113549, 2, 9}
+	oidHMACWithSHA384 = asn1.ObjectIdentifier{1, 2, 840, 113549, 2, 10}
+	oidHMACWithSHA512 = asn1.ObjectIdentifier{1, 2, 840, 113549, 2, 11}
+	oidAES128CBC      = asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 1, 2}
+	oidAES192CBC      = asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 1, 22}
+	oidAES256CBC      = asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 1, 42}
+	oidDESEDE3CBC     = asn1.ObjectIdentifier{1, 2, 840, 113549, 3, 7}
+)
+
+// pbes2Params is the _PBES2-params_ as of _RFC 8018_ appendix A.4.
+type pbes2Params struct {
+	KeyDerivationFunc pkix.AlgorithmIdentifier
+	EncryptionScheme  pkix.AlgorithmIdentifier
+}
+
+// pbkdf2Params is the _PBKDF2-params_ as of _RFC 8018_ appendix A.2.
+type pbkdf2Params struct {
+	Salt       []byte
+	Iterations int
+	KeyLength  int                      `asn1:"optional"`
+	PRF        pkix.AlgorithmIdentifier `asn1:"optional"`
+}
+
+// pbes2Encrypt encrypts _data_ using _PBES2_ with _PBKDF2-HMAC-SHA256_ over _password_ and
+// _salt_ and _AES-256-CBC_.
+func pbes2Encrypt(
+	data, password, salt []byte,
+	iterations int,
+	rand io.Reader,
+) (pkix.AlgorithmIdentifier, []byte, error) {
+
+	iv := make([]byte, aes.BlockSize)
+
+	if _, err := io.ReadFull(rand, iv); err != nil {
+		return pkix.AlgorithmIdentifier{}, nil, err
+	}
+
+	kdfParams, err := asn1.Marshal(pbkdf2Params{
+		Salt:       salt,
+		Iterations: iterations,
+		PRF:        pkix.AlgorithmIdentifier{Algorithm: oidHMACWithSHA256, Parameters: asn1.NullRawValue},
+	})
+	if err != nil {
+		return pkix.AlgorithmIdentifier{}, nil, err
+	}
+
+	ivParam, err := asn1.Marshal(iv)
+	if err != nil {
+		return pkix.AlgorithmIdentifier{}, nil, err
+	}
+
+	params, err := asn1.Marshal(pbes2Params{
+		KeyDerivationFunc: pkix.AlgorithmIdentifier{Algorithm: oidPBKDF2, Parameters: asn1.RawValue{FullBytes: kdfParams}},
+		EncryptionScheme:  pkix.AlgorithmIdentifier{Algorithm: oidAES256CBC, Parameters: asn1.RawValue{FullBytes: ivParam}},
+	})
+	if err != nil {
+		return pkix.AlgorithmIdentifier{}, nil, err
+	}
+
+	block, err := aes.NewCipher(pbkdf2.Key(password, salt, iterations, 32, sha256.New))
+	if err != nil {
+		return pkix.AlgorithmIdentifier{}, nil, err
+	}
+
+	data = pkcs7Pad(data, block.BlockSize())
+	cipher.NewCBCEncrypter(block, iv).CryptBlocks(data, data)
+
+	return pkix.AlgorithmIdentifier{
+		Algorithm: oidPBES2, Parameters: asn1.RawValue{FullBytes: params},
+	}, data, nil
+
+}
+
+// pbes2Decrypt decrypts _data_ as of _RFC 8018_ _PBES2_ with _PBKDF2_ and _AES-CBC_ or
+// _DES-EDE3-CBC_.
+func pbes2Decrypt(algorithm pkix.AlgorithmIdentifier, data, password []byte) ([]byte, error) {
+
+	var params pbes2Params
+
+	if _, err := asn1.Unmarshal(algorithm.Parameters.FullBytes, &params); err != nil {
+		return nil, err
+	}
+
+	if !params.KeyDerivationFunc.Algorithm.Equal(oidPBKDF2) {
+		return nil, errPBEUnsupported
+	}
+
+	var kdf pbkdf2Params
+
+	if _, err := asn1.Unmarshal(params.KeyDerivationFunc.Parameters.FullBytes, &kdf); err != nil {
+		return nil, err
+	}
+
+	var prf func() hash.Hash
+
+	switch {
+	case len(kdf.PRF.Algorithm) == 0, kdf.PRF.Algorithm.Equal(oidHMACWithSHA1):
+		prf = sha1.New
+	case kdf.PRF.Algorithm.Equal(oidHMACWithSHA256):
+		prf = sha256.New
+	case kdf.PRF.Algorithm.Equal(oidHMACWithSHA384):
+		prf = sha512.New384
+	case kdf.PRF.Algorithm.Equal(oidHMACWithSHA512):
+		prf = sha512.New
+	default:
+		return nil, errPBEUnsupported
+	}
+
+	var keyLen int
+	var newCipher func(key []byte) (cipher.Block, error)
+
+	switch {
+	case params.EncryptionScheme.Algorithm.Equal(oidAES128CBC):
+		keyLen, newCipher = 16, aes.NewCipher
+	case params.EncryptionScheme.Algorithm.Equal(oidAES192CBC):
+		keyLen, newCipher = 24, aes.NewCipher
+	case params.EncryptionScheme.Algorithm.Equal(oidAES256CBC):
+		keyLen, newCipher = 32, aes.NewCipher
+	case params.EncryptionScheme.Algorithm.Equal(oidDESEDE3CBC):
+		keyLen, newCipher = 24, des.NewTripleDESCipher
+	default:
+		return nil, errPBEUnsupported
+	}
+
+	var iv []byte
+
+	if _, err := asn1.Unmarshal(params.EncryptionScheme.Parameters.FullBytes, &iv); err != nil {
+		return nil, err
+	}
+
+	if err := checkPBEIterations(kdf.Iterations); err != nil {
+		return nil, err
+	}
+
+	block, err := newCipher(pbkdf2.Key(password, kdf.Salt, kdf.Iterations, keyLen, prf))
+	if err != nil {
+		return nil, err
+	}
+
+	return cbcDecrypt(block, iv, data)
+
+}
+
+// cbcDecrypt decrypts _data_ and removes the _PKCS #7_ padding.
+func cbcDecrypt(block cipher.Block, iv, data []byte) ([]byte, error) {
+
+	size := block.BlockSize()
+
+	if len(iv) != size || len(data) == 0 || len(data)%size != 0 {
+		return nil, errPBEDecrypt
+	}
+
+	out := make([]byte, len(data))
+	cipher.NewCBCDecrypter(block, iv).CryptBlocks(out, data)
+
+	pad := int(out[len(out)-1])
+
+	if pad == 0 || pad > size || !bytes.Equal(out[len(out)-pad:], bytes.Repeat([]byte{byte(pad)}, pad)) {
+		return nil, errPBEDecrypt
+	}
+
+	return out[:len(out)-pad], nil
+
+}
+
+// pkcs7Pad returns a copy of _data_ with _PKCS #7_ padding.
+func pkcs7Pad(data []byte, size int) []byte {
+
+	pad := size - len(data)%size
+
+	return append(append([]byte{}, data...), bytes.Repeat([]byte{byte(pad)}, pad)...)
+
+}
@@ -0,0 +1,818 @@
+package cryptoutils
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/cipher"
+	"crypto/des"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/pem"
+	"fmt"
+	"hash"
+	"io"
+	"unicode/utf16"
+
+	"golang.org/x/crypto/pkcs12"
+)
+
+// ErrPKCS12IncorrectPassword is returned when the _PKCS #12_ _MAC_ do not verify.
+var ErrPKCS12IncorrectPassword = fmt.Errorf("pkcs12: incorrect password")
+
+var (
+	oidPKCS7EncryptedData      = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 7, 6}
+	oidPKCS12KeyBag            = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 12, 10, 1, 1}
+	oidPKCS12ShroudedKeyBag    = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 12, 10, 1, 2}
+	oidPKCS12CertBag           = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 12, 10, 1, 3}
+	oidPKCS9X509Certificate    = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 9, 22, 1}
+	oidPKCS9FriendlyName       = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 9, 20}
+	oidPKCS9LocalKeyID         = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 9, 21}
+	oidPBEWithSHAAnd3KeyTDES   = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 12, 1, 3}
+	oidDigestSHA1              = asn1.ObjectIdentifier{1, 3, 14, 3, 2, 26}
+	oidDigestSHA256            = asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 2, 1}
+	oidDigestSHA384            = asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 2, 2}
+	oidDigestSHA512            = asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 2, 3}
+	pkcs12DefaultIterations    = 2048
+	pkcs12DefaultMACIterations = 2048
+)
+
+// pkcs12PFX is the _PFX_ as of _RFC 7292_ section 4.
+type pkcs12PFX struct {
+	Version  int
+	AuthSafe pkcs12ContentInfo
+	MacData  pkcs12MacData `asn1:"optional"`
+}
+
+// pkcs12ContentInfo is the _ContentInfo_ with a explicit content.
+type pkcs12ContentInfo struct {
+	ContentType asn1.ObjectIdentifier
+	Content     asn1.RawValue `asn1:"tag:0,explicit,optional"`
+}
+
+type pkcs12MacData struct {
+	Mac        pkcs12DigestInfo
+	MacSalt    []byte
+	Iterations int `asn1:"optional,default:1"`
+}
+
+type pkcs12DigestInfo struct {
+	Algorithm pkix.AlgorithmIdentifier
+	Digest    []byte
+}
+
+type pkcs12EncryptedData struct {
+	Version              int
+	EncryptedContentInfo pkcs12EncryptedContentInfo
+}
+
+type pkcs12EncryptedContentInfo struct {
+	ContentType                asn1.ObjectIdentifier
+	ContentEncryptionAlgorithm pkix.AlgorithmIdentifier
+	EncryptedContent           []byte `asn1:"tag:0,optional"`
+}
+
+type pkcs12SafeBag struct {
+	ID         asn1.ObjectIdentifier
+	Value      asn1.RawValue     `asn1:"tag:0,explicit"`
+	Attributes []pkcs12Attribute `asn1:"set,optional"`
+}
+
+type pkcs12Attribute struct {
+	ID    asn1.ObjectIdentifier
+	Value asn1.RawValue `asn1:"set"`
+}
+
+type pkcs12CertBag struct {
+	ID   asn1.ObjectIdentifier
+	Data []byte `asn1:"tag:0,explicit"`
+}
+
+type pkcs12PBEParams struct {
+	Salt       []byte
+	Iterations int
+}
+
+// PKCS12Bundle is the content of a _PKCS #12_ (_.p12_, _.pfx_) file.
+type PKCS12Bundle struct {
+	// PrivateKey is the private key, e.g. `*rsa.PrivateKey`, `*ecdsa.PrivateKey` or
+	// `ed25519.PrivateKey`.
+	PrivateKey crypto.PrivateKey
+	// Certificate is the certificate of the _PrivateKey_, `nil` if not in the bundle.
+	Certificate *x509.Certificate
+	// CACerts is all other certificates, typically the issuer chain.
+	CACerts []*x509.Certificate
+	// FriendlyName is the friendly name of the key, if any.
+	FriendlyName string
+}
+
+// PKCS12Option configures `EncodePKCS12`.
+type PKCS12Option func(o *pkcs12Options)
+
+type pkcs12Options struct {
+	legacy       bool
+	iterations   int
+	friendlyName string
+	rand         io.Reader
+}
+
+// WithPKCS12Legacy encodes using _pbeWithSHAAnd3-KeyTripleDES-CBC_ and a _SHA-1_ _MAC_ for
+// tools that do not support _PBES2_, e.g. older _Windows_ and _Java_ versions. Default is
+// _PBES2_ with _PBKDF2-HMAC-SHA256_, _AES-256-CBC_ and a _SHA-256_ _MAC_.
+func WithPKCS12Legacy() PKCS12Option {
+	return func(o *pkcs12Options) {
+		o.legacy = true
+	}
+}
+
+// WithPKCS12Iterations sets the key derivation iterations, default 2048.
+func WithPKCS12Iterations(iterations int) PKCS12Option {
+	return func(o *pkcs12Options) {
+		o.iterations = iterations
+	}
+}
+
+// WithPKCS12FriendlyName sets the friendly name of the key and its certificate.
+func WithPKCS12FriendlyName(name string) PKCS12Option {
+	return func(o *pkcs12Options) {
+		o.friendlyName = name
+	}
+}
+
+// DecodePKCS12 decodes the _PKCS #12_ _data_ protected by _password_.
+//
+// Keys and certificates encrypted with _PBES2_ (the _OpenSSL 3_ default) or with
+// _pbeWithSHAAnd3-KeyTripleDES-CBC_ are decoded natively. Files that uses other legacy
+// algorithms, e.g. _RC2_, are decoded by _golang.org/x/crypto/pkcs12_ that only supports
+// _RSA_ and _ECDSA_ keys. The _Certificate_ is the one that matches the private key.
+func DecodePKCS12(data []byte, password string) (*PKCS12Bundle, error) {
+
+	bundle, err := decodePKCS12(data, password)
+	if err == errPBEUnsupported {
+		return decodeLegacyPKCS12(data, password)
+	}
+
+	return bundle, err
+
+}
+
+// EncodePKCS12 encodes the _key_, its _cert_ and the _caCerts_ as a _PKCS #12_ file protected
+// by _password_.
+func EncodePKCS12(
+	key crypto.PrivateKey,
+	cert *x509.Certificate,
+	caCerts []*x509.Certificate,
+	password string,
+	opts ...PKCS12Option,
+) ([]byte, error) {
+
+	o := &pkcs12Options{iterations: pkcs12DefaultIterations, rand: rand.Reader}
+
+	for _, opt := range opts {
+		opt(o)
+	}
+
+	if err := checkPBEIterations(o.iterations); err != nil {
+		return nil, fmt.Errorf("pkcs12: %w", err)
+	}
+
+	pkcs8, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		return nil, err
+	}
+
+	localKeyID := sha1.Sum(cert.Raw)
+
+	attributes, err := pkcs12Attributes(localKeyID[:], o.friendlyName)
+	if err != nil {
+		return nil, err
+	}
+
+	certBags := []pkcs12SafeBag{}
+
+	for i, c := range append([]*x509.Certificate{cert}, caCerts...) {
+
+		bag, err := asn1.Marshal(pkcs12CertBag{ID: oidPKCS9X509Certificate, Data: c.Raw})
+		if err != nil {
+			return nil, err
+		}
+
+		safeBag := pkcs12SafeBag{ID: oidPKCS12CertBag, Value: pkcs12Explicit(bag)}
+
+		if i == 0 {
+			safeBag.Attributes = attributes
+		}
+
+		certBags = append(certBags, safeBag)
+
+	}
+
+	certContents, err := asn1.Marshal(certBags)
+	if err != nil {
+		return nil, err
+	}
+
+	algorithm, encrypted, err := pkcs12Encrypt(certContents, password, o)
+	if err != nil {
+		return nil, err
+	}
+
+	encryptedCerts, err := asn1.Marshal(pkcs12EncryptedData{
+		EncryptedContentInfo: pkcs12EncryptedContentInfo{
+			ContentType:                oidPKCS7Data,
+			ContentEncryptionAlgorithm: algorithm,
+			EncryptedContent:           encrypted,
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	algorithm, encrypted, err = pkcs12Encrypt(pkcs8, password, o)
+	if err != nil {
+		return nil, err
+	}
+
+	shrouded, err := asn1.Marshal(encryptedPrivateKeyInfo{Algorithm: algorithm, EncryptedData: encrypted})
+	if err != nil {
+		return nil, err
+	}
+
+	keyContents, err := asn1.Marshal([]pkcs12SafeBag{{
+		ID:         oidPKCS12ShroudedKeyBag,
+		Value:      pkcs12Explicit(shrouded),
+		Attributes: attributes,
+	}})
+	if err != nil {
+		return nil, err
+	}
+
+	keyData, err := asn1.Marshal(keyContents)
+	if err != nil {
+		return nil, err
+	}
+
+	authSafe, err := asn1.Marshal([]pkcs12ContentInfo{
+		{ContentType: oidPKCS7EncryptedData, Content: pkcs12Explicit(encryptedCerts)},
+		{ContentType: oidPKCS7Data, Content: pkcs12Explicit(keyData)},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	macData := pkcs12MacData{MacSalt: make([]byte, 8), Iterations: pkcs12DefaultMACIterations}
+
+	if _, err := io.ReadFull(o.rand, macData.MacSalt); err != nil {
+		return nil, err
+	}
+
+	macHash, macOID := crypto.SHA256, oidDigestSHA256
+
+	if o.legacy {
+		macHash, macOID = crypto.SHA1, oidDigestSHA1
+	}
+
+	macData.Mac.Algorithm.Algorithm = macOID
+	macData.Mac.Digest = pkcs12MAC(macHash, authSafe, password, macData.MacSalt, macData.Iterations)
+
+	authSafeData, err := asn1.Marshal(authSafe)
+	if err != nil {
+		return nil, err
+	}
+
+	return asn1.Marshal(pkcs12PFX{
+		Version:  3,
+		AuthSafe: pkcs12ContentInfo{ContentType: oidPKCS7Data, Content: pkcs12Explicit(authSafeData)},
+		MacData:  macData,
+	})
+
+}
+
+// decodePKCS12 decodes _data_ natively or returns `errPBEUnsupported`.
+func decodePKCS12(data []byte, password string) (*PKCS12Bundle, error) {
+
+	var pfx pkcs12PFX
+
+	if rest, err := asn1.Unmarshal(data, &pfx); err != nil {
+		return nil, fmt.Errorf("pkcs12: %v", err)
+	} else if len(rest) > 0 {
+		return nil, fmt.Errorf("pkcs12: trailing data")
+	}
+
+	if pfx.Version != 3 {
+		return nil, fmt.Errorf("pkcs12: unsupported version %d", pfx.Version)
+	}
+
+	if !pfx.AuthSafe.ContentType.Equal(oidPKCS7Data) {
+		return nil, fmt.Errorf("pkcs12: only password integrity mode is supported")
+	}
+
+	var authSafe []byte
+
+	if _, err := asn1.Unmarshal(pfx.AuthSafe.Content.Bytes, &authSafe); err != nil {
+		return nil, err
+	}
+
+	macHash, err := pkcs12DigestHash(pfx.MacData.Mac.Algorithm.Algorithm)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := checkPBEIterations(pfx.MacData.Iterations); err != nil {
+		return nil, fmt.Errorf("pkcs12: MAC data: %w", err)
+	}
+
+	mac := pkcs12MAC(macHash, authSafe, password, pfx.MacData.MacSalt, pfx.MacData.Iterations)
+
+	if !hmac.Equal(mac, pfx.MacData.Mac.Digest) {
+
+		// Some implementations encodes the empty password as no bytes at all.
+		if password != "" ||
+			!hmac.Equal(pkcs12MACRaw(macHash, authSafe, nil, pfx.MacData.MacSalt, pfx.MacData.Iterations), pfx.MacData.Mac.Digest) {
+
+			return nil, ErrPKCS12IncorrectPassword
+		}
+
+	}
+
+	var contents []pkcs12ContentInfo
+
+	if _, err := asn1.Unmarshal(authSafe, &contents); err != nil {
+		return nil, err
+	}
+
+	bundle := &PKCS12Bundle{}
+	certs := []*x509.Certificate{}
+
+	for _, ci := range contents {
+
+		var safeContents []byte
+
+		switch {
+		case ci.ContentType.Equal(oidPKCS7Data):
+
+			if _, err := asn1.Unmarshal(ci.Content.Bytes, &safeContents); err != nil {
+				return nil, err
+			}
+
+		case ci.ContentType.Equal(oidPKCS7EncryptedData):
+
+			var encrypted pkcs12EncryptedData
+
+			if _, err := asn1.Unmarshal(ci.Content.Bytes, &encrypted); err != nil {
+				return nil, err
+			}
+
+			safeContents, err = pkcs12Decrypt(
+				encrypted.EncryptedContentInfo.ContentEncryptionAlgorithm,
+				encrypted.EncryptedContentInfo.EncryptedContent,
+				password,
+			)
+			if err != nil {
+				return nil, err
+			}
+
+		default:
+			return nil, fmt.Errorf("pkcs12: unsupported content type %s", ci.ContentType)
+		}
+
+		var bags []pkcs12SafeBag
+
+		if _, err := asn1.Unmarshal(safeContents, &bags); err != nil {
+			return nil, err
+		}
+
+		for _, bag := range bags {
+
+			switch {
+			case bag.ID.Equal(oidPKCS12CertBag):
+
+				var certBag pkcs12CertBag
+
+				if _, err := asn1.Unmarshal(bag.Value.Bytes, &certBag); err != nil {
+					return nil, err
+				}
+
+				if !certBag.ID.Equal(oidPKCS9X509Certificate) {
+					continue
+				}
+
+				cert, err := x509.ParseCertificate(certBag.Data)
+				if err != nil {
+					return nil, err
+				}
+
+				certs = append(certs, cert)
+
+			case bag.ID.Equal(oidPKCS12KeyBag), bag.ID.Equal(oidPKCS12ShroudedKeyBag):
+
+				if bundle.PrivateKey != nil {
+					return nil, fmt.Errorf("pkcs12: more than one private key")
+				}
+
+				pkcs8 := bag.Value.Bytes
+
+				if bag.ID.Equal(oidPKCS12ShroudedKeyBag) {
+
+					var info encryptedPrivateKeyInfo
+
+					if _, err := asn1.Unmarshal(bag.Value.Bytes, &info); err != nil {
+						return nil, err
+					}
+
+					if pkcs8, err = pkcs12Decrypt(info.Algorithm, info.EncryptedData, password); err != nil {
+						return nil, err
+					}
+
+				}
+
+				if bundle.PrivateKey, err = x509.ParsePKCS8PrivateKey(pkcs8); err != nil {
+					return nil, err
+				}
+
+				bundle.FriendlyName = pkcs12FriendlyName(bag.Attributes)
+
+			}
+
+		}
+
+	}
+
+	if bundle.PrivateKey == nil {
+		return nil, fmt.Errorf("pkcs12: no private key")
+	}
+
+	return bundle, bundle.setCertificates(certs)
+
+}
+
+// decodeLegacyPKCS12 decodes _data_ using _golang.org/x/crypto/pkcs12_.
+func decodeLegacyPKCS12(data []byte, password string) (*PKCS12Bundle, error) {
+
+	blocks, err := pkcs12.ToPEM(data, password)
+	if err != nil {
+		if err == pkcs12.ErrIncorrectPassword {
+			return nil, ErrPKCS12IncorrectPassword
+		}
+
+		return nil, err
+	}
+
+	bundle := &PKCS12Bundle{}
+	certs := []*x509.Certificate{}
+
+	for _, block := range blocks {
+
+		switch block.Type {
+		case "CERTIFICATE":
+
+			cert, err := x509.ParseCertificate(block.Bytes)
+			if err != nil {
+				return nil, err
+			}
+
+			certs = append(certs, cert)
+
+		case "PRIVATE KEY":
+
+			if bundle.PrivateKey, err = parseLegacyPKCS12Key(block); err != nil {
+				return nil, err
+			}
+
+			bundle.FriendlyName = block.Headers["friendlyName"]
+
+		}
+
+	}
+
+	if bundle.PrivateKey == nil {
+		return nil, fmt.Errorf("pkcs12: no private key")
+	}
+
+	return bundle, bundle.setCertificates(certs)
+
+}
+
+// parseLegacyPKCS12Key parses the _PKCS #1_ or _SEC 1_ key that `pkcs12.ToPEM` produces.
+func parseLegacyPKCS12Key(block *pem.Block) (crypto.PrivateKey, error) {
+
+	if key, err := x509.ParsePKCS1PrivateKey(block.Bytes); err == nil {
+		return key, nil
+	}
+
+	return x509.ParseECPrivateKey(block.Bytes)
+
+}
+
+// setCertificates sets the certificate that matches the private key and the rest as
+// _CACerts_.
+func (b *PKCS12Bundle) setCertificates(certs []*x509.Certificate) error {
+
+	public, ok := b.PrivateKey.(interface{ Public() crypto.PublicKey })
+	if !ok {
+		return fmt.Errorf("pkcs12: unsupported private key %T", b.PrivateKey)
+	}
+
+	for _, cert := range certs {
+
+		if b.Certificate == nil {
+
+			if key, ok := cert.PublicKey.(interface{ Equal(crypto.PublicKey) bool }); ok && key.Equal(public.Public()) {
+				b.Certificate = cert
+				continue
+			}
+
+		}
+
+		b.CACerts = append(b.CACerts, cert)
+
+	}
+
+	return nil
+
+}
+
+// pkcs12Encrypt encrypts _data_ as of the _o_ options.
+func pkcs12Encrypt(
+	data []byte,
+	password string,
+	o *pkcs12Options,
+) (pkix.AlgorithmIdentifier, []byte, error) {
+
+	salt := make([]byte, 16)
+
+	if _, err := io.ReadFull(o.rand, salt); err != nil {
+		return pkix.AlgorithmIdentifier{}, nil, err
+	}
+
+	if o.legacy {
+
+		salt = salt[:8]
+
+		params, err := asn1.Marshal(pkcs12PBEParams{Salt: salt, Iterations: o.iterations})
+		if err != nil {
+			return pkix.AlgorithmIdentifier{}, nil, err
+		}
+
+		encrypted, err := pkcs12TDESCrypt(data, password, salt, o.iterations, true)
+
+		return pkix.AlgorithmIdentifier{
+			Algorithm: oidPBEWithSHAAnd3KeyTDES, Parameters: asn1.RawValue{FullBytes: params},
+		}, encrypted, err
+
+	}
+
+	return pbes2Encrypt(data, []byte(password), salt, o.iterations, o.rand)
+
+}
+
+// pkcs12Decrypt decrypts _data_ encrypted with _algorithm_.
+func pkcs12Decrypt(algorithm pkix.AlgorithmIdentifier, data []byte, password string) ([]byte, error) {
+
+	switch {
+	case algorithm.Algorithm.Equal(oidPBEWithSHAAnd3KeyTDES):
+
+		var params pkcs12PBEParams
+
+		if _, err := asn1.Unmarshal(algorithm.Parameters.FullBytes, &params); err != nil {
+			return nil, err
+		}
+
+		if err := checkPBEIterations(params.Iterations); err != nil {
+			return nil, err
+		}
+
+		return pkcs12TDESCrypt(data, password, params.Salt, params.Iterations, false)
+
+	case algorithm.Algorithm.Equal(oidPBES2):
+
+		data, err := pbes2Decrypt(algorithm, data, []byte(password))
+		if err == errPBEDecrypt {
+			return nil, ErrPKCS12IncorrectPassword
+		}
+
+		return data, err
+
+	}
+
+	return nil, errPBEUnsupported
+
+}
+
+// pkcs12TDESCrypt encrypts or decrypts using _pbeWithSHAAnd3-KeyTripleDES-CBC_.
+func pkcs12TDESCrypt(data []byte, password string, salt []byte, iterations int, encrypt bool) ([]byte, error) {
+
+	bmp := pkcs12BMPString(password)
+	key := pkcs12KDF(sha1.New, 64, bmp, salt, iterations, 1, 24)
+	iv := pkcs12KDF(sha1.New, 64, bmp, salt, iterations, 2, des.BlockSize)
+
+	block, err := des.NewTripleDESCipher(key)
+	if err != nil {
+		return nil, err
+	}
+
+	if !encrypt {
+
+		data, err := cbcDecrypt(block, iv, data)
+		if err == errPBEDecrypt {
+			return nil, ErrPKCS12IncorrectPassword
+		}
+
+		return data, err
+
+	}
+
+	data = pkcs7Pad(data, block.BlockSize())
+	cipher.NewCBCEncrypter(block, iv).CryptBlocks(data, data)
+
+	return data, nil
+
+}
+
+// pkcs12MAC computes the _MAC_ of _data_ as of _RFC 7292_ appendix B.
+func pkcs12MAC(h crypto.Hash, data []byte, password string, salt []byte, iterations int) []byte {
+	return pkcs12MACRaw(h, data, pkcs12BMPString(password), salt, iterations)
+}
+
+// pkcs12MACRaw computes the _MAC_ using the already encoded _password_.
+func pkcs12MACRaw(h crypto.Hash, data, password, salt []byte, iterations int) []byte {
+
+	blockSize := 64
+
+	if h == crypto.SHA384 || h == crypto.SHA512 {
+		blockSize = 128
+	}
+
+	key := pkcs12KDF(h.New, blockSize, password, salt, iterations, 3, h.Size())
+
+	mac := hmac.New(h.New, key)
+	mac.Write(data)
+
+	return mac.Sum(nil)
+
+}
+
+// pkcs12DigestHash returns the hash of the _MAC_ digest algorithm _oid_.
+func pkcs12DigestHash(oid asn1.ObjectIdentifier) (crypto.Hash, error) {
+
+	switch {
+	case oid.Equal(oidDigestSHA1):
+		return crypto.SHA1, nil
+	case oid.Equal(oidDigestSHA256):
+		return crypto.SHA256, nil
+	case oid.Equal(oidDigestSHA384):
+		return crypto.SHA384, nil
+	case oid.Equal(oidDigestSHA512):
+		return crypto.SHA512, nil
+	}
+
+	return 0, fmt.Errorf("pkcs12: unsupported MAC algorithm %s", oid)
+
+}
+
+// pkcs12KDF derives _size_ bytes of key material with the _PKCS #12_ key derivation function
+// of _RFC 7292_ appendix B.2, where _id_ is 1 for keys, 2 for _IV_ and 3 for _MAC_ keys.
+func pkcs12KDF(h func() hash.Hash, v int, password, salt []byte, iterations int, id byte, size int) []byte {
+
+	fill := func(data []byte) []byte {
+
+		if len(data) == 0 {
+			return nil
+		}
+
+		out := make([]byte, v*((len(data)+v-1)/v))
+
+		for i := range out {
+			out[i] = data[i%len(data)]
+		}
+
+		return out
+
+	}
+
+	d := bytes.Repeat([]byte{id}, v)
+	input := append(fill(salt), fill(password)...)
+
+	var out []byte
+
+	for len(out) < size {
+
+		hh := h()
+		hh.Write(d)
+		hh.Write(input)
+		a := hh.Sum(nil)
+
+		for i := 1; i < iterations; i++ {
+
+			hh.Reset()
+			hh.Write(a)
+			a = hh.Sum(a[:0])
+
+		}
+
+		out = append(out, a...)
+
+		b := fill(a)
+
+		for j := 0; j < len(input); j += v {
+
+			carry := 1
+
+			for k := v - 1; k >= 0; k-- {
+
+				sum := int(input[j+k]) + int(b[k]) + carry
+				input[j+k] = byte(sum)
+				carry = sum >> 8
+
+			}
+
+		}
+
+	}
+
+	return out[:size]
+
+}
+
+// pkcs12BMPString encodes _s_ as a null terminated _BMPString_.
+func pkcs12BMPString(s string) []byte {
+
+	units := utf16.Encode([]rune(s))
+	out := make([]byte, 0, 2*len(units)+2)
+
+	for _, u := range units {
+		out = append(out, byte(u>>8), byte(u))
+	}
+
+	return append(out, 0, 0)
+
+}
+
+// pkcs12Attributes returns the _localKeyId_ and the optional _friendlyName_ attributes.
+func pkcs12Attributes(localKeyID []byte, friendlyName string) ([]pkcs12Attribute, error) {
+
+	value, err := asn1.Marshal(localKeyID)
+	if err != nil {
+		return nil, err
+	}
+
+	attributes := []pkcs12Attribute{{ID: oidPKCS9LocalKeyID, Value: pkcs12Set(value)}}
+
+	if friendlyName != "" {
+
+		bmp := pkcs12BMPString(friendlyName)
+
+		value, err := asn1.Marshal(asn1.RawValue{Tag: asn1.TagBMPString, Bytes: bmp[:len(bmp)-2]})
+		if err != nil {
+			return nil, err
+		}
+
+		attributes = append(attributes, pkcs12Attribute{ID: oidPKCS9FriendlyName, Value: pkcs12Set(value)})
+
+	}
+
+	return attributes, nil
+
+}
+
+// pkcs12FriendlyName returns the _friendlyName_ attribute or empty string.
+func pkcs12FriendlyName(attributes []pkcs12Attribute) string {
+
+	for _, attribute := range attributes {
+
+		if !attribute.ID.Equal(oidPKCS9FriendlyName) {
+			continue
+		}
+
+		var value asn1.RawValue
+
+		if _, err := asn1.Unmarshal(attribute.Value.Bytes, &value); err != nil || len(value.Bytes)%2 != 0 {
+			return ""
+		}
+
+		units := make([]uint16, len(value.Bytes)/2)
+
+		for i := range units {
+			units[i] = uint16(value.Bytes[2*i])<<8 | uint16(value.Bytes[2*i+1])
+		}
+
+		return string(utf16.Decode(units))
+
+	}
+
+	return ""
+
+}
+
+// pkcs12Explicit wraps _der_ as a explicit context specific tag 0 content.
+func pkcs12Explicit(der []byte) asn1.RawValue {
+	return asn1.RawValue{Class: asn1.ClassContextSpecific, Tag: 0, IsCompound: true, Bytes: der}
+}
+
+// pkcs12Set wraps _der_ as a _SET_ value.
+func pkcs12Set(der []byte) asn1.RawValue {
+	return asn1.RawValue{Class: asn1.ClassUniversal, Tag: asn1.TagSet, IsCompound: true, Bytes: der}
+}
@@ -0,0 +1,134 @@
+package cryptoutils
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/pkcs12"
+)
+
+func TestPKCS12RoundTrip(t *testing.T) {
+
+	root, rootKey := issue(t, CertificateProfileCA, CertificateSubject{
+		Subject: pkix.Name{CommonName: "root"},
+	}, nil, nil)
+
+	leaf, leafKey := issue(t, CertificateProfileTLSServer, CertificateSubject{
+		DNSNames: []string{"leaf.example.com"},
+	}, root, rootKey)
+
+	for _, legacy := range []bool{false, true} {
+
+		opts := []PKCS12Option{WithPKCS12FriendlyName("leaf"), WithPKCS12Iterations(1000)}
+
+		if legacy {
+			opts = append(opts, WithPKCS12Legacy())
+		}
+
+		data, err := EncodePKCS12(leafKey, leaf, []*x509.Certificate{root}, "sécret", opts...)
+		require.NoError(t, err)
+
+		bundle, err := DecodePKCS12(data, "sécret")
+		require.NoError(t, err)
+
+		assert.True(t, leafKey.Equal(bundle.PrivateKey))
+		assert.Equal(t, leaf.Raw, bundle.Certificate.Raw)
+		require.Len(t, bundle.CACerts, 1)
+		assert.Equal(t, root.Raw, bundle.CACerts[0].Raw)
+		assert.Equal(t, "leaf", bundle.FriendlyName)
+
+		_, err = DecodePKCS12(data, "wrong")
+		assert.Equal(t, ErrPKCS12IncorrectPassword, err)
+
+		if legacy {
+
+			// Cross check with the legacy only decoder.
+			blocks, err := pkcs12.ToPEM(data, "sécret")
+			require.NoError(t, err)
+			assert.Len(t, blocks, 3)
+
+		}
+
+	}
+
+}
+
+func TestPKCS12Ed25519WithoutPassword(t *testing.T) {
+
+	_, key, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+
+	tmpl, _, err := NewCertificateTemplate(CertificateProfileCodeSigning, CertificateSubject{
+		Subject: pkix.Name{CommonName: "signer"},
+	})
+	require.NoError(t, err)
+
+	cert, err := CreateSelfSignedCertificate(rand.Reader, tmpl, key)
+	require.NoError(t, err)
+
+	data, err := EncodePKCS12(key, cert, nil, "")
+	require.NoError(t, err)
+
+	bundle, err := DecodePKCS12(data, "")
+	require.NoError(t, err)
+
+	assert.Equal(t, key, bundle.PrivateKey)
+	assert.Equal(t, cert.Raw, bundle.Certificate.Raw)
+	assert.Empty(t, bundle.CACerts)
+	assert.Empty(t, bundle.FriendlyName)
+
+}
+
+func TestPKCS12RejectsIterationsOutOfRange(t *testing.T) {
+
+	_, key, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+
+	tmpl, _, err := NewCertificateTemplate(CertificateProfileCodeSigning, CertificateSubject{
+		Subject: pkix.Name{CommonName: "signer"},
+	})
+	require.NoError(t, err)
+
+	cert, err := CreateSelfSignedCertificate(rand.Reader, tmpl, key)
+	require.NoError(t, err)
+
+	data, err := EncodePKCS12(key, cert, nil, "secret")
+	require.NoError(t, err)
+
+	for _, iterations := range []int{0, -1, pbeMaxIterations + 1} {
+
+		_, err := EncodePKCS12(key, cert, nil, "secret", WithPKCS12Iterations(iterations))
+		assert.True(t, errors.Is(err, errPBEIterations), "encode %d", iterations)
+
+		var pfx pkcs12PFX
+
+		_, err = asn1.Unmarshal(data, &pfx)
+		require.NoError(t, err)
+
+		pfx.MacData.Iterations = iterations
+
+		crafted, err := asn1.Marshal(pfx)
+		require.NoError(t, err)
+
+		_, err = DecodePKCS12(crafted, "secret")
+		assert.True(t, errors.Is(err, errPBEIterations), "MAC %d", iterations)
+
+		params, err := asn1.Marshal(pkcs12PBEParams{Salt: []byte("salt"), Iterations: iterations})
+		require.NoError(t, err)
+
+		_, err = pkcs12Decrypt(pkix.AlgorithmIdentifier{
+			Algorithm: oidPBEWithSHAAnd3KeyTDES, Parameters: asn1.RawValue{FullBytes: params},
+		}, make([]byte, 16), "secret")
+
+		assert.True(t, errors.Is(err, errPBEIterations), "3DES %d", iterations)
+
+	}
+
+}
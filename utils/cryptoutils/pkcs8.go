@@ -0,0 +1,154 @@
+package cryptoutils
+
+import (
+	"crypto/rand"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/pem"
+	"fmt"
+	"io"
+)
+
+// ErrIncorrectPassphrase is returned when a encrypted private key can not be decrypted.
+var ErrIncorrectPassphrase = fmt.Errorf("incorrect passphrase")
+
+const (
+	// EncryptedPrivateKeyPEMType is the _PEM_ type of a _RFC 5958_ encrypted _PKCS #8_ key.
+	EncryptedPrivateKeyPEMType = "ENCRYPTED PRIVATE KEY"
+	// EncryptedPrivateKeyIterations is the default _PBKDF2_ iterations when encrypting keys.
+	EncryptedPrivateKeyIterations = 600000
+)
+
+var oidRSAEncryption = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 1, 1}
+
+// encryptedPrivateKeyInfo is the _EncryptedPrivateKeyInfo_ as of _RFC 5958_ section 3.
+type encryptedPrivateKeyInfo struct {
+	Algorithm     pkix.AlgorithmIdentifier
+	EncryptedData []byte
+}
+
+// pkcs8PrivateKey is the _PrivateKeyInfo_ as of _RFC 5208_ section 5.
+type pkcs8PrivateKey struct {
+	Version    int
+	Algorithm  pkix.AlgorithmIdentifier
+	PrivateKey []byte
+}
+
+// EncryptPrivateKeyPEM encrypts the private key _block_ with _passphrase_ into a
+// _ENCRYPTED PRIVATE KEY_ block using _PBES2_ with _PBKDF2-HMAC-SHA256_ and _AES-256-CBC_.
+//
+// The _block_ may be a _PRIVATE KEY_, _RSA PRIVATE KEY_ or _EC PRIVATE KEY_ block, the two
+// latter are converted to _PKCS #8_. If _iterations_ is zero `EncryptedPrivateKeyIterations`
+// is used.
+func EncryptPrivateKeyPEM(block *pem.Block, passphrase []byte, iterations int) (*pem.Block, error) {
+
+	der, err := PrivateKeyBlockToPKCS8(block)
+	if err != nil {
+		return nil, err
+	}
+
+	if iterations == 0 {
+		iterations = EncryptedPrivateKeyIterations
+	}
+
+	if err := checkPBEIterations(iterations); err != nil {
+		return nil, err
+	}
+
+	salt := make([]byte, 16)
+
+	if _, err := io.ReadFull(rand.Reader, salt); err != nil {
+		return nil, err
+	}
+
+	algorithm, encrypted, err := pbes2Encrypt(der, passphrase, salt, iterations, rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := asn1.Marshal(encryptedPrivateKeyInfo{Algorithm: algorithm, EncryptedData: encrypted})
+	if err != nil {
+		return nil, err
+	}
+
+	return &pem.Block{Type: EncryptedPrivateKeyPEMType, Bytes: data}, nil
+
+}
+
+// DecryptPrivateKeyPEM decrypts a _ENCRYPTED PRIVATE KEY_ _block_ into a _PRIVATE KEY_ block.
+//
+// It returns `ErrIncorrectPassphrase` when the _passphrase_ is wrong.
+func DecryptPrivateKeyPEM(block *pem.Block, passphrase []byte) (*pem.Block, error) {
+
+	if block.Type != EncryptedPrivateKeyPEMType {
+		return nil, fmt.Errorf("not a encrypted private key: %s", block.Type)
+	}
+
+	var info encryptedPrivateKeyInfo
+
+	if rest, err := asn1.Unmarshal(block.Bytes, &info); err != nil {
+		return nil, err
+	} else if len(rest) > 0 {
+		return nil, fmt.Errorf("trailing data after encrypted private key")
+	}
+
+	if !info.Algorithm.Algorithm.Equal(oidPBES2) {
+		return nil, fmt.Errorf("%w: %s", errPBEUnsupported, info.Algorithm.Algorithm)
+	}
+
+	der, err := pbes2Decrypt(info.Algorithm, info.EncryptedData, passphrase)
+	if err != nil {
+		if err == errPBEDecrypt {
+			return nil, ErrIncorrectPassphrase
+		}
+
+		return nil, err
+	}
+
+	// A wrong passphrase may still produce valid padding.
+	var key pkcs8PrivateKey
+
+	if rest, err := asn1.Unmarshal(der, &key); err != nil || len(rest) > 0 {
+		return nil, ErrIncorrectPassphrase
+	}
+
+	return &pem.Block{Type: "PRIVATE KEY", Bytes: der}, nil
+
+}
+
+// PrivateKeyBlockToPKCS8 returns the _PKCS #8_ _DER_ of the private key _block_, that may be a
+// _PRIVATE KEY_, _RSA PRIVATE KEY_ or _EC PRIVATE KEY_ block.
+func PrivateKeyBlockToPKCS8(block *pem.Block) ([]byte, error) {
+
+	switch block.Type {
+	case "PRIVATE KEY":
+		return block.Bytes, nil
+	case "RSA PRIVATE KEY":
+
+		return asn1.Marshal(pkcs8PrivateKey{
+			Algorithm:  pkix.AlgorithmIdentifier{Algorithm: oidRSAEncryption, Parameters: asn1.NullRawValue},
+			PrivateKey: block.Bytes,
+		})
+
+	case "EC PRIVATE KEY":
+
+		var sec1 sec1PrivateKey
+
+		if _, err := asn1.Unmarshal(block.Bytes, &sec1); err != nil {
+			return nil, err
+		}
+
+		if len(sec1.NamedCurveOID) == 0 {
+			return nil, fmt.Errorf("EC private key has no named curve")
+		}
+
+		return asn1.Marshal(ecPKCS8{
+			Algorithm:  ecAlgorithmIdentifier{Algorithm: oidECPublicKey, NamedCurve: sec1.NamedCurveOID},
+			PrivateKey: block.Bytes,
+		})
+
+	}
+
+	return nil, fmt.Errorf("unsupported private key PEM block: %s", block.Type)
+
+}
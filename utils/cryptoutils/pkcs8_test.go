@@ -0,0 +1,118 @@
+package cryptoutils
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/asn1"
+	"encoding/pem"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEncryptedPrivateKeyPEMRoundTrip(t *testing.T) {
+
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+
+	ecKey, err := ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
+	require.NoError(t, err)
+
+	ecDER, err := x509.MarshalECPrivateKey(ecKey)
+	require.NoError(t, err)
+
+	_, edKey, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+
+	edDER, err := x509.MarshalPKCS8PrivateKey(edKey)
+	require.NoError(t, err)
+
+	tests := []struct {
+		block *pem.Block
+		key   interface{ Equal(crypto.PrivateKey) bool }
+	}{
+		{&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(rsaKey)}, rsaKey},
+		{&pem.Block{Type: "EC PRIVATE KEY", Bytes: ecDER}, ecKey},
+		{&pem.Block{Type: "PRIVATE KEY", Bytes: edDER}, edKey},
+	}
+
+	for _, test := range tests {
+
+		encrypted, err := EncryptPrivateKeyPEM(test.block, []byte("passphrase"), 1000)
+		require.NoError(t, err)
+		assert.Equal(t, EncryptedPrivateKeyPEMType, encrypted.Type)
+		assert.False(t, bytes.Contains(encrypted.Bytes, test.block.Bytes))
+
+		decrypted, err := DecryptPrivateKeyPEM(encrypted, []byte("passphrase"))
+		require.NoError(t, err)
+		assert.Equal(t, "PRIVATE KEY", decrypted.Type)
+
+		key, err := x509.ParsePKCS8PrivateKey(decrypted.Bytes)
+		require.NoError(t, err)
+
+		assert.True(t, test.key.Equal(key))
+
+		_, err = DecryptPrivateKeyPEM(encrypted, []byte("wrong"))
+		assert.Equal(t, ErrIncorrectPassphrase, err)
+
+	}
+
+}
+
+func TestEncryptedPrivateKeyPEMRejectsIterationsOutOfRange(t *testing.T) {
+
+	_, key, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	require.NoError(t, err)
+
+	block := &pem.Block{Type: "PRIVATE KEY", Bytes: der}
+
+	encrypted, err := EncryptPrivateKeyPEM(block, []byte("passphrase"), 1)
+	require.NoError(t, err)
+
+	for _, iterations := range []int{-1, pbeMaxIterations + 1} {
+
+		_, err := EncryptPrivateKeyPEM(block, []byte("passphrase"), iterations)
+		assert.True(t, errors.Is(err, errPBEIterations), "encrypt %d", iterations)
+
+	}
+
+	for _, iterations := range []int{0, -1, pbeMaxIterations + 1} {
+
+		var info encryptedPrivateKeyInfo
+		var params pbes2Params
+		var kdf pbkdf2Params
+
+		_, err := asn1.Unmarshal(encrypted.Bytes, &info)
+		require.NoError(t, err)
+		_, err = asn1.Unmarshal(info.Algorithm.Parameters.FullBytes, &params)
+		require.NoError(t, err)
+		_, err = asn1.Unmarshal(params.KeyDerivationFunc.Parameters.FullBytes, &kdf)
+		require.NoError(t, err)
+
+		kdf.Iterations = iterations
+
+		params.KeyDerivationFunc.Parameters.FullBytes, err = asn1.Marshal(kdf)
+		require.NoError(t, err)
+		info.Algorithm.Parameters.FullBytes, err = asn1.Marshal(params)
+		require.NoError(t, err)
+
+		crafted, err := asn1.Marshal(info)
+		require.NoError(t, err)
+
+		_, err = DecryptPrivateKeyPEM(&pem.Block{Type: EncryptedPrivateKeyPEMType, Bytes: crafted}, []byte("passphrase"))
+		assert.True(t, errors.Is(err, errPBEIterations), "decrypt %d", iterations)
+
+	}
+
+}
@@ -0,0 +1,79 @@
+package cryptoutils
+
+import (
+	"bytes"
+	"crypto"
+	"hash"
+	"sync"
+)
+
+// maxPooledBuffer is the largest buffer capacity that `ReleaseBuffer` returns to the pool, such
+// that a single large message do not pin memory.
+const maxPooledBuffer = 64 * 1024
+
+var (
+	hashPools  sync.Map // crypto.Hash -> *sync.Pool
+	bufferPool = sync.Pool{New: func() interface{} { return new(bytes.Buffer) }}
+	digestPool = sync.Pool{New: func() interface{} { return new([64]byte) }}
+)
+
+// AcquireHash returns a reset `hash.Hash` of _h_ from a pool. The _h_ must be available. Return
+// it using `ReleaseHash` when done.
+//
+// Reusing hash states removes the per operation allocation on hot sign and verify paths.
+func AcquireHash(h crypto.Hash) hash.Hash {
+
+	pool, ok := hashPools.Load(h)
+	if !ok {
+		pool, _ = hashPools.LoadOrStore(h, &sync.Pool{New: func() interface{} { return h.New() }})
+	}
+
+	hsh := pool.(*sync.Pool).Get().(hash.Hash)
+	hsh.Reset()
+
+	return hsh
+
+}
+
+// ReleaseHash returns _hsh_, acquired by `AcquireHash` with _h_, to the pool.
+func ReleaseHash(h crypto.Hash, hsh hash.Hash) {
+
+	if pool, ok := hashPools.Load(h); ok {
+		pool.(*sync.Pool).Put(hsh)
+	}
+
+}
+
+// AcquireDigest returns a buffer from a pool that fits any digest, use it as `hsh.Sum(d[:0])`.
+// Return it using `ReleaseDigest` when the digest is no longer used.
+func AcquireDigest() *[64]byte {
+	return digestPool.Get().(*[64]byte)
+}
+
+// ReleaseDigest returns _d_, acquired by `AcquireDigest`, to the pool.
+func ReleaseDigest(d *[64]byte) {
+	digestPool.Put(d)
+}
+
+// AcquireBuffer returns a empty scratch buffer from a pool, e.g. for a envelope encoding. Return
+// it using `ReleaseBuffer` when its bytes are no longer used.
+func AcquireBuffer() *bytes.Buffer {
+
+	buf := bufferPool.Get().(*bytes.Buffer)
+	buf.Reset()
+
+	return buf
+
+}
+
+// ReleaseBuffer returns _buf_, acquired by `AcquireBuffer`, to the pool. Buffers that have grown
+// beyond 64 KiB are dropped.
+func ReleaseBuffer(buf *bytes.Buffer) {
+
+	if buf.Cap() > maxPooledBuffer {
+		return
+	}
+
+	bufferPool.Put(buf)
+
+}
@@ -0,0 +1,379 @@
+package cryptoutils
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/sha512"
+	"crypto/subtle"
+	"encoding/base64"
+	"fmt"
+	"hash"
+	"strconv"
+	"strings"
+
+	"golang.org/x/crypto/pbkdf2"
+)
+
+// ScramMechanism is a _SASL_ _SCRAM_ mechanism as of _RFC 5802_ and _RFC 7677_.
+type ScramMechanism string
+
+const (
+	// ScramSHA256 is the _SCRAM-SHA-256_ mechanism.
+	ScramSHA256 ScramMechanism = "SCRAM-SHA-256"
+	// ScramSHA512 is the _SCRAM-SHA-512_ mechanism.
+	ScramSHA512 ScramMechanism = "SCRAM-SHA-512"
+)
+
+// ScramMinIterations is the lowest iteration count that `NewScramCredentials` accepts.
+const ScramMinIterations = 4096
+
+// hash returns the hash function of the mechanism.
+func (m ScramMechanism) hash() (func() hash.Hash, error) {
+
+	switch m {
+	case ScramSHA256:
+		return sha256.New, nil
+	case ScramSHA512:
+		return sha512.New, nil
+	}
+
+	return nil, fmt.Errorf("unsupported SCRAM mechanism: %s", m)
+
+}
+
+// ScramCredentials is the server side stored credentials of a user. It does not contain
+// the password, nor anything that can be used to authenticate as the user.
+type ScramCredentials struct {
+	Mechanism  ScramMechanism `json:"mechanism"`
+	Salt       []byte         `json:"salt"`
+	Iterations int            `json:"iterations"`
+	StoredKey  []byte         `json:"stored_key"`
+	ServerKey  []byte         `json:"server_key"`
+}
+
+// NewScramCredentials derives the stored credentials for _password_.
+//
+// If _salt_ is `nil` a random 16 byte salt is generated. The _password_ is used as is, it
+// is up to the caller to apply _SASLprep_ if non _ASCII_ passwords are used.
+func NewScramCredentials(mechanism ScramMechanism, password string, salt []byte, iterations int) (*ScramCredentials, error) {
+
+	h, err := mechanism.hash()
+	if err != nil {
+		return nil, err
+	}
+
+	if iterations < ScramMinIterations {
+		return nil, fmt.Errorf("SCRAM iterations must be at least %d, got: %d", ScramMinIterations, iterations)
+	}
+
+	if salt == nil {
+
+		salt = make([]byte, 16)
+
+		if _, err := rand.Read(salt); err != nil {
+			return nil, err
+		}
+
+	}
+
+	salted := pbkdf2.Key([]byte(password), salt, iterations, h().Size(), h)
+	clientKey := scramHMAC(h, salted, "Client Key")
+
+	return &ScramCredentials{
+		Mechanism:  mechanism,
+		Salt:       salt,
+		Iterations: iterations,
+		StoredKey:  scramHash(h, clientKey),
+		ServerKey:  scramHMAC(h, salted, "Server Key"),
+	}, nil
+
+}
+
+// ScramClient is the client side of a single _SCRAM_ authentication exchange.
+//
+// Channel binding is not supported, the client always sends the _n,,_ header.
+type ScramClient struct {
+	mechanism   ScramMechanism
+	username    string
+	password    string
+	nonce       string
+	clientFirst string
+	serverSig   []byte
+}
+
+// NewScramClient creates a new client conversation for _username_ and _password_.
+func NewScramClient(mechanism ScramMechanism, username, password string) (*ScramClient, error) {
+
+	if _, err := mechanism.hash(); err != nil {
+		return nil, err
+	}
+
+	nonce := make([]byte, 18)
+
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+
+	return &ScramClient{
+		mechanism: mechanism,
+		username:  username,
+		password:  password,
+		nonce:     base64.RawStdEncoding.EncodeToString(nonce),
+	}, nil
+
+}
+
+// First returns the _client-first-message_.
+func (c *ScramClient) First() string {
+
+	c.clientFirst = "n=" + scramEscape(c.username) + ",r=" + c.nonce
+
+	return "n,," + c.clientFirst
+
+}
+
+// Final processes the _server-first-message_ and returns the _client-final-message_.
+func (c *ScramClient) Final(serverFirst string) (string, error) {
+
+	attrs := scramAttributes(serverFirst)
+
+	nonce, salt64, iter := attrs["r"], attrs["s"], attrs["i"]
+
+	if !strings.HasPrefix(nonce, c.nonce) || len(nonce) == len(c.nonce) {
+		return "", fmt.Errorf("invalid SCRAM server nonce")
+	}
+
+	salt, err := base64.StdEncoding.DecodeString(salt64)
+	if err != nil {
+		return "", fmt.Errorf("invalid SCRAM salt: %v", err)
+	}
+
+	iterations, err := strconv.Atoi(iter)
+	if err != nil || iterations < ScramMinIterations {
+		return "", fmt.Errorf("invalid SCRAM iteration count: %s", iter)
+	}
+
+	h, _ := c.mechanism.hash()
+
+	salted := pbkdf2.Key([]byte(c.password), salt, iterations, h().Size(), h)
+	clientKey := scramHMAC(h, salted, "Client Key")
+
+	withoutProof := "c=biws,r=" + nonce
+	authMessage := c.clientFirst + "," + serverFirst + "," + withoutProof
+
+	clientSig := scramHMAC(h, scramHash(h, clientKey), authMessage)
+	c.serverSig = scramHMAC(h, scramHMAC(h, salted, "Server Key"), authMessage)
+
+	return withoutProof + ",p=" + base64.StdEncoding.EncodeToString(scramXOR(clientKey, clientSig)), nil
+
+}
+
+// Verify verifies the _server-final-message_, i.e. that the server knows the credentials.
+func (c *ScramClient) Verify(serverFinal string) error {
+
+	attrs := scramAttributes(serverFinal)
+
+	if e, ok := attrs["e"]; ok {
+		return fmt.Errorf("SCRAM authentication failed: %s", e)
+	}
+
+	sig, err := base64.StdEncoding.DecodeString(attrs["v"])
+	if err != nil || c.serverSig == nil || !hmac.Equal(sig, c.serverSig) {
+		return fmt.Errorf("invalid SCRAM server signature")
+	}
+
+	return nil
+
+}
+
+// ScramCredentialLookup returns the stored credentials for _username_.
+type ScramCredentialLookup func(username string) (*ScramCredentials, error)
+
+// ScramServer is the server side of a single _SCRAM_ authentication exchange.
+type ScramServer struct {
+	mechanism   ScramMechanism
+	lookup      ScramCredentialLookup
+	username    string
+	credentials *ScramCredentials
+	nonce       string
+	clientFirst string
+	serverFirst string
+}
+
+// NewScramServer creates a new server conversation that resolves users using _lookup_.
+func NewScramServer(mechanism ScramMechanism, lookup ScramCredentialLookup) (*ScramServer, error) {
+
+	if _, err := mechanism.hash(); err != nil {
+		return nil, err
+	}
+
+	return &ScramServer{mechanism: mechanism, lookup: lookup}, nil
+
+}
+
+// Username returns the authenticated user name, valid after `First`.
+func (s *ScramServer) Username() string {
+	return s.username
+}
+
+// First processes the _client-first-message_ and returns the _server-first-message_.
+func (s *ScramServer) First(clientFirst string) (string, error) {
+
+	if !strings.HasPrefix(clientFirst, "n,,") {
+		return "", fmt.Errorf("unsupported SCRAM channel binding")
+	}
+
+	s.clientFirst = clientFirst[3:]
+
+	attrs := scramAttributes(s.clientFirst)
+
+	username, err := scramUnescape(attrs["n"])
+	if err != nil {
+		return "", err
+	}
+
+	if username == "" || attrs["r"] == "" {
+		return "", fmt.Errorf("invalid SCRAM client first message")
+	}
+
+	credentials, err := s.lookup(username)
+	if err != nil {
+		return "", err
+	}
+
+	if credentials.Mechanism != s.mechanism {
+		return "", fmt.Errorf("credentials are for %s, not %s", credentials.Mechanism, s.mechanism)
+	}
+
+	nonce := make([]byte, 18)
+
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+
+	s.username = username
+	s.credentials = credentials
+	s.nonce = attrs["r"] + base64.RawStdEncoding.EncodeToString(nonce)
+
+	s.serverFirst = fmt.Sprintf(
+		"r=%s,s=%s,i=%d",
+		s.nonce, base64.StdEncoding.EncodeToString(credentials.Salt), credentials.Iterations,
+	)
+
+	return s.serverFirst, nil
+
+}
+
+// Final verifies the _client-final-message_ and returns the _server-final-message_.
+func (s *ScramServer) Final(clientFinal string) (string, error) {
+
+	if s.credentials == nil {
+		return "", fmt.Errorf("SCRAM client first message not processed")
+	}
+
+	idx := strings.LastIndex(clientFinal, ",p=")
+	if idx < 0 {
+		return "", fmt.Errorf("invalid SCRAM client final message")
+	}
+
+	withoutProof := clientFinal[:idx]
+	attrs := scramAttributes(withoutProof)
+
+	if attrs["c"] != "biws" || attrs["r"] != s.nonce {
+		return "", fmt.Errorf("invalid SCRAM client final message")
+	}
+
+	proof, err := base64.StdEncoding.DecodeString(clientFinal[idx+3:])
+	if err != nil {
+		return "", fmt.Errorf("invalid SCRAM client proof")
+	}
+
+	h, _ := s.mechanism.hash()
+
+	authMessage := s.clientFirst + "," + s.serverFirst + "," + withoutProof
+	clientSig := scramHMAC(h, s.credentials.StoredKey, authMessage)
+
+	if len(proof) != len(clientSig) {
+		return "", fmt.Errorf("SCRAM authentication failed")
+	}
+
+	clientKey := scramXOR(proof, clientSig)
+
+	if subtle.ConstantTimeCompare(scramHash(h, clientKey), s.credentials.StoredKey) != 1 {
+		return "", fmt.Errorf("SCRAM authentication failed")
+	}
+
+	serverSig := scramHMAC(h, s.credentials.ServerKey, authMessage)
+
+	return "v=" + base64.StdEncoding.EncodeToString(serverSig), nil
+
+}
+
+// scramHMAC computes _HMAC(key, msg)_.
+func scramHMAC(h func() hash.Hash, key []byte, msg string) []byte {
+
+	mac := hmac.New(h, key)
+	mac.Write([]byte(msg))
+
+	return mac.Sum(nil)
+
+}
+
+// scramHash computes _H(data)_.
+func scramHash(h func() hash.Hash, data []byte) []byte {
+
+	hsh := h()
+	hsh.Write(data)
+
+	return hsh.Sum(nil)
+
+}
+
+// scramXOR returns _a XOR b_, both must be of same length.
+func scramXOR(a, b []byte) []byte {
+
+	out := make([]byte, len(a))
+
+	for i := range a {
+		out[i] = a[i] ^ b[i]
+	}
+
+	return out
+
+}
+
+// scramAttributes parses the comma separated _key=value_ attributes of a message.
+func scramAttributes(msg string) map[string]string {
+
+	attrs := map[string]string{}
+
+	for _, attr := range strings.Split(msg, ",") {
+
+		if len(attr) >= 2 && attr[1] == '=' {
+			attrs[attr[:1]] = attr[2:]
+		}
+
+	}
+
+	return attrs
+
+}
+
+// scramEscape escapes a user name as of _RFC 5802_ section 5.1.
+func scramEscape(name string) string {
+	return strings.NewReplacer("=", "=3D", ",", "=2C").Replace(name)
+}
+
+// scramUnescape reverses `scramEscape`.
+func scramUnescape(name string) (string, error) {
+
+	unescaped := strings.NewReplacer("=3D", "=", "=2C", ",").Replace(name)
+
+	if strings.Count(name, "=") != strings.Count(name, "=3D")+strings.Count(name, "=2C") {
+		return "", fmt.Errorf("invalid SCRAM user name encoding")
+	}
+
+	return unescaped, nil
+
+}
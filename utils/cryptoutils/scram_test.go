@@ -0,0 +1,82 @@
+package cryptoutils
+
+import (
+	"encoding/base64"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestScramSHA256RFC7677Vector(t *testing.T) {
+
+	client, err := NewScramClient(ScramSHA256, "user", "pencil")
+	require.NoError(t, err)
+
+	client.nonce = "rOprNGfwEbeRWgbNEkqO"
+
+	assert.Equal(t, "n,,n=user,r=rOprNGfwEbeRWgbNEkqO", client.First())
+
+	final, err := client.Final("r=rOprNGfwEbeRWgbNEkqO%hvYDpWUa2RaTCAfuxFIlj)hNlF$k0,s=W22ZaJ0SNY7soEsUEjb6gQ==,i=4096")
+	require.NoError(t, err)
+
+	assert.Equal(t,
+		"c=biws,r=rOprNGfwEbeRWgbNEkqO%hvYDpWUa2RaTCAfuxFIlj)hNlF$k0,p=dHzbZapWIk4jUhN+Ute9ytag9zjfMHgsqmmiz7AndVQ=",
+		final,
+	)
+
+	assert.NoError(t, client.Verify("v=6rriTRBi23WpRR/wtup+mMhUZUn/dB5nLTJRsjl95G4="))
+	assert.Error(t, client.Verify("v=AAAA"))
+
+}
+
+func TestScramClientServerExchange(t *testing.T) {
+
+	for _, mechanism := range []ScramMechanism{ScramSHA256, ScramSHA512} {
+
+		salt, _ := base64.StdEncoding.DecodeString("W22ZaJ0SNY7soEsUEjb6gQ==")
+
+		creds, err := NewScramCredentials(mechanism, "secret", salt, ScramMinIterations)
+		require.NoError(t, err)
+
+		lookup := func(username string) (*ScramCredentials, error) {
+
+			if username != "kafka,user" {
+				return nil, fmt.Errorf("unknown user")
+			}
+
+			return creds, nil
+
+		}
+
+		for _, password := range []string{"secret", "wrong"} {
+
+			client, err := NewScramClient(mechanism, "kafka,user", password)
+			require.NoError(t, err)
+
+			server, err := NewScramServer(mechanism, lookup)
+			require.NoError(t, err)
+
+			serverFirst, err := server.First(client.First())
+			require.NoError(t, err)
+			assert.Equal(t, "kafka,user", server.Username())
+
+			clientFinal, err := client.Final(serverFirst)
+			require.NoError(t, err)
+
+			serverFinal, err := server.Final(clientFinal)
+
+			if password == "wrong" {
+				assert.Error(t, err)
+				continue
+			}
+
+			require.NoError(t, err)
+			assert.NoError(t, client.Verify(serverFinal))
+
+		}
+
+	}
+
+}
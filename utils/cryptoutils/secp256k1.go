@@ -0,0 +1,556 @@
+package cryptoutils
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/asn1"
+	"fmt"
+	"math/big"
+	"sync"
+)
+
+// secp256k1Curve implements `elliptic.Curve` for the _SECG_ _secp256k1_ curve.
+//
+// The generic `elliptic.CurveParams` implementation assumes _a = -3_ and can not be used for
+// _secp256k1_ where _a = 0_, hence the arithmetic is implemented here using Jacobian
+// coordinates.
+//
+// NOTE: The arithmetic uses `math/big` and is not constant time.
+type secp256k1Curve struct {
+	params *elliptic.CurveParams
+}
+
+var (
+	secp256k1Once     sync.Once
+	secp256k1Instance *secp256k1Curve
+)
+
+// Secp256k1 returns the _SECG_ _secp256k1_ curve used by _Bitcoin_ and _Ethereum_.
+func Secp256k1() elliptic.Curve {
+
+	secp256k1Once.Do(func() {
+
+		params := &elliptic.CurveParams{Name: "secp256k1", BitSize: 256}
+
+		params.P, _ = new(big.Int).SetString("FFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFEFFFFFC2F", 16)
+		params.N, _ = new(big.Int).SetString("FFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFEBAAEDCE6AF48A03BBFD25E8CD0364141", 16)
+		params.B = big.NewInt(7)
+		params.Gx, _ = new(big.Int).SetString("79BE667EF9DCBBAC55A06295CE870B07029BFCDB2DCE28D959F2815B16F81798", 16)
+		params.Gy, _ = new(big.Int).SetString("483ADA7726A3C4655DA4FBFC0E1108A8FD17B448A68554199C47D08FFB10D4B8", 16)
+
+		secp256k1Instance = &secp256k1Curve{params: params}
+
+	})
+
+	return secp256k1Instance
+
+}
+
+// Params implements the `elliptic.Curve` interface.
+func (curve *secp256k1Curve) Params() *elliptic.CurveParams {
+	return curve.params
+}
+
+// IsOnCurve implements the `elliptic.Curve` interface.
+func (curve *secp256k1Curve) IsOnCurve(x, y *big.Int) bool {
+
+	p := curve.params.P
+
+	if x.Sign() < 0 || x.Cmp(p) >= 0 || y.Sign() < 0 || y.Cmp(p) >= 0 {
+		return false
+	}
+
+	// y² = x³ + 7
+	y2 := new(big.Int).Mul(y, y)
+	y2.Mod(y2, p)
+
+	x3 := new(big.Int).Mul(x, x)
+	x3.Mul(x3, x)
+	x3.Add(x3, curve.params.B)
+	x3.Mod(x3, p)
+
+	return x3.Cmp(y2) == 0
+
+}
+
+// jacobian is a point in Jacobian coordinates, _z = 0_ is the point at infinity.
+type jacobian struct {
+	x, y, z *big.Int
+}
+
+// toJacobian converts the affine point, where _(0, 0)_ is infinity.
+func (curve *secp256k1Curve) toJacobian(x, y *big.Int) *jacobian {
+
+	if x.Sign() == 0 && y.Sign() == 0 {
+		return &jacobian{x: new(big.Int), y: new(big.Int), z: new(big.Int)}
+	}
+
+	return &jacobian{x: new(big.Int).Set(x), y: new(big.Int).Set(y), z: big.NewInt(1)}
+
+}
+
+// toAffine converts the Jacobian point _j_, infinity is returned as _(0, 0)_.
+func (curve *secp256k1Curve) toAffine(j *jacobian) (*big.Int, *big.Int) {
+
+	if j.z.Sign() == 0 {
+		return new(big.Int), new(big.Int)
+	}
+
+	p := curve.params.P
+
+	zinv := new(big.Int).ModInverse(j.z, p)
+	zinv2 := new(big.Int).Mul(zinv, zinv)
+
+	x := new(big.Int).Mul(j.x, zinv2)
+	x.Mod(x, p)
+
+	y := new(big.Int).Mul(j.y, zinv2.Mul(zinv2, zinv))
+	y.Mod(y, p)
+
+	return x, y
+
+}
+
+// double returns _2 * a_ using the _dbl-2009-l_ formulas for _a = 0_.
+func (curve *secp256k1Curve) double(a *jacobian) *jacobian {
+
+	if a.z.Sign() == 0 || a.y.Sign() == 0 {
+		return &jacobian{x: new(big.Int), y: new(big.Int), z: new(big.Int)}
+	}
+
+	p := curve.params.P
+	mod := func(v *big.Int) *big.Int { return v.Mod(v, p) }
+
+	A := mod(new(big.Int).Mul(a.x, a.x))
+	B := mod(new(big.Int).Mul(a.y, a.y))
+	C := mod(new(big.Int).Mul(B, B))
+
+	D := new(big.Int).Add(a.x, B)
+	D.Mul(D, D)
+	D.Sub(D, A)
+	D.Sub(D, C)
+	D.Lsh(D, 1)
+	mod(D)
+
+	E := mod(new(big.Int).Mul(A, big.NewInt(3)))
+	F := mod(new(big.Int).Mul(E, E))
+
+	x3 := new(big.Int).Sub(F, new(big.Int).Lsh(D, 1))
+	mod(x3)
+
+	y3 := new(big.Int).Sub(D, x3)
+	y3.Mul(y3, E)
+	y3.Sub(y3, new(big.Int).Lsh(C, 3))
+	mod(y3)
+
+	z3 := new(big.Int).Mul(a.y, a.z)
+	z3.Lsh(z3, 1)
+	mod(z3)
+
+	return &jacobian{x: x3, y: y3, z: z3}
+
+}
+
+// add returns _a + b_ using the _add-2007-bl_ formulas.
+func (curve *secp256k1Curve) add(a, b *jacobian) *jacobian {
+
+	if a.z.Sign() == 0 {
+		return &jacobian{x: new(big.Int).Set(b.x), y: new(big.Int).Set(b.y), z: new(big.Int).Set(b.z)}
+	}
+
+	if b.z.Sign() == 0 {
+		return &jacobian{x: new(big.Int).Set(a.x), y: new(big.Int).Set(a.y), z: new(big.Int).Set(a.z)}
+	}
+
+	p := curve.params.P
+	mod := func(v *big.Int) *big.Int { return v.Mod(v, p) }
+
+	z1z1 := mod(new(big.Int).Mul(a.z, a.z))
+	z2z2 := mod(new(big.Int).Mul(b.z, b.z))
+
+	u1 := mod(new(big.Int).Mul(a.x, z2z2))
+	u2 := mod(new(big.Int).Mul(b.x, z1z1))
+
+	s1 := mod(new(big.Int).Mul(a.y, mod(new(big.Int).Mul(b.z, z2z2))))
+	s2 := mod(new(big.Int).Mul(b.y, mod(new(big.Int).Mul(a.z, z1z1))))
+
+	h := mod(new(big.Int).Sub(u2, u1))
+	r := mod(new(big.Int).Lsh(new(big.Int).Sub(s2, s1), 1))
+
+	if h.Sign() == 0 {
+
+		if r.Sign() == 0 {
+			return curve.double(a)
+		}
+
+		return &jacobian{x: new(big.Int), y: new(big.Int), z: new(big.Int)}
+
+	}
+
+	i := new(big.Int).Lsh(h, 1)
+	i = mod(i.Mul(i, i))
+
+	j := mod(new(big.Int).Mul(h, i))
+	v := mod(new(big.Int).Mul(u1, i))
+
+	x3 := new(big.Int).Mul(r, r)
+	x3.Sub(x3, j)
+	x3.Sub(x3, new(big.Int).Lsh(v, 1))
+	mod(x3)
+
+	y3 := new(big.Int).Sub(v, x3)
+	y3.Mul(y3, r)
+	y3.Sub(y3, new(big.Int).Lsh(new(big.Int).Mul(s1, j), 1))
+	mod(y3)
+
+	z3 := new(big.Int).Add(a.z, b.z)
+	z3.Mul(z3, z3)
+	z3.Sub(z3, z1z1)
+	z3.Sub(z3, z2z2)
+	z3.Mul(z3, h)
+	mod(z3)
+
+	return &jacobian{x: x3, y: y3, z: z3}
+
+}
+
+// Add implements the `elliptic.Curve` interface.
+func (curve *secp256k1Curve) Add(x1, y1, x2, y2 *big.Int) (*big.Int, *big.Int) {
+	return curve.toAffine(curve.add(curve.toJacobian(x1, y1), curve.toJacobian(x2, y2)))
+}
+
+// Double implements the `elliptic.Curve` interface.
+func (curve *secp256k1Curve) Double(x1, y1 *big.Int) (*big.Int, *big.Int) {
+	return curve.toAffine(curve.double(curve.toJacobian(x1, y1)))
+}
+
+// ScalarMult implements the `elliptic.Curve` interface using a Montgomery ladder.
+func (curve *secp256k1Curve) ScalarMult(x1, y1 *big.Int, k []byte) (*big.Int, *big.Int) {
+
+	r0 := &jacobian{x: new(big.Int), y: new(big.Int), z: new(big.Int)}
+	r1 := curve.toJacobian(x1, y1)
+
+	for _, b := range k {
+
+		for bit := 7; bit >= 0; bit-- {
+
+			if (b>>uint(bit))&1 == 0 {
+				r1 = curve.add(r0, r1)
+				r0 = curve.double(r0)
+			} else {
+				r0 = curve.add(r0, r1)
+				r1 = curve.double(r1)
+			}
+
+		}
+
+	}
+
+	return curve.toAffine(r0)
+
+}
+
+// ScalarBaseMult implements the `elliptic.Curve` interface.
+func (curve *secp256k1Curve) ScalarBaseMult(k []byte) (*big.Int, *big.Int) {
+	return curve.ScalarMult(curve.params.Gx, curve.params.Gy, k)
+}
+
+// Secp256k1PublicKeyFromBytes parses a _SEC 1_ compressed (33 bytes) or uncompressed (65 bytes) point.
+func Secp256k1PublicKeyFromBytes(data []byte) (*ecdsa.PublicKey, error) {
+
+	curve := Secp256k1()
+	p := curve.Params().P
+
+	switch {
+	case len(data) == 65 && data[0] == 4:
+
+		x := new(big.Int).SetBytes(data[1:33])
+		y := new(big.Int).SetBytes(data[33:])
+
+		if !curve.IsOnCurve(x, y) {
+			return nil, fmt.Errorf("point is not on secp256k1")
+		}
+
+		return &ecdsa.PublicKey{Curve: curve, X: x, Y: y}, nil
+
+	case len(data) == 33 && (data[0] == 2 || data[0] == 3):
+
+		x := new(big.Int).SetBytes(data[1:])
+
+		if x.Cmp(p) >= 0 {
+			return nil, fmt.Errorf("point is not on secp256k1")
+		}
+
+		// y = sqrt(x³ + 7), p ≡ 3 mod 4
+		y := new(big.Int).Mul(x, x)
+		y.Mul(y, x)
+		y.Add(y, curve.Params().B)
+		y.Mod(y, p)
+
+		y = y.ModSqrt(y, p)
+		if y == nil {
+			return nil, fmt.Errorf("point is not on secp256k1")
+		}
+
+		if y.Bit(0) != uint(data[0]&1) {
+			y.Sub(p, y)
+		}
+
+		return &ecdsa.PublicKey{Curve: curve, X: x, Y: y}, nil
+
+	}
+
+	return nil, fmt.Errorf("invalid secp256k1 public key encoding")
+
+}
+
+// Secp256k1PublicKeyBytes returns the _SEC 1_ encoding of the _key_, compressed or uncompressed.
+func Secp256k1PublicKeyBytes(key *ecdsa.PublicKey, compressed bool) []byte {
+
+	if compressed {
+
+		out := make([]byte, 33)
+		out[0] = byte(2 + key.Y.Bit(0))
+		key.X.FillBytes(out[1:])
+
+		return out
+
+	}
+
+	out := make([]byte, 65)
+	out[0] = 4
+	key.X.FillBytes(out[1:33])
+	key.Y.FillBytes(out[33:])
+
+	return out
+
+}
+
+// Secp256k1PrivateKeyFromBytes creates a private key from the 32 byte big-endian scalar _d_.
+func Secp256k1PrivateKeyFromBytes(d []byte) (*ecdsa.PrivateKey, error) {
+
+	curve := Secp256k1()
+
+	if len(d) != 32 {
+		return nil, fmt.Errorf("secp256k1 private key must be 32 bytes")
+	}
+
+	k := new(big.Int).SetBytes(d)
+
+	if k.Sign() == 0 || k.Cmp(curve.Params().N) >= 0 {
+		return nil, fmt.Errorf("invalid secp256k1 private key")
+	}
+
+	key := &ecdsa.PrivateKey{D: k}
+	key.Curve = curve
+	key.X, key.Y = curve.ScalarBaseMult(d)
+
+	return key, nil
+
+}
+
+// secp256k1Signature is the _ASN.1_ encoding of a _ECDSA_ signature.
+type secp256k1Signature struct {
+	R, S *big.Int
+}
+
+// SignSecp256k1 signs the _digest_ using deterministic nonces as of _RFC 6979_, with
+// _HMAC-SHA256_, and normalizes _s_ to the lower half of the order as required by _Bitcoin_
+// and _Ethereum_. The signature is _ASN.1 DER_ encoded.
+func SignSecp256k1(key *ecdsa.PrivateKey, digest []byte) ([]byte, error) {
+
+	r, s, err := signSecp256k1(key, digest)
+	if err != nil {
+		return nil, err
+	}
+
+	return asn1.Marshal(secp256k1Signature{R: r, S: s})
+
+}
+
+// SignSecp256k1Raw is the same as `SignSecp256k1` but returns the 64 byte _r || s_ encoding.
+func SignSecp256k1Raw(key *ecdsa.PrivateKey, digest []byte) ([]byte, error) {
+
+	r, s, err := signSecp256k1(key, digest)
+	if err != nil {
+		return nil, err
+	}
+
+	out := make([]byte, 64)
+	r.FillBytes(out[:32])
+	s.FillBytes(out[32:])
+
+	return out, nil
+
+}
+
+// VerifySecp256k1 verifies the _ASN.1 DER_, or 64 byte _r || s_, _signature_ of _digest_.
+//
+// High _s_ values are rejected, as by _Bitcoin_ consensus rules.
+func VerifySecp256k1(key *ecdsa.PublicKey, digest, signature []byte) bool {
+
+	var sig secp256k1Signature
+
+	if len(signature) == 64 {
+
+		sig.R = new(big.Int).SetBytes(signature[:32])
+		sig.S = new(big.Int).SetBytes(signature[32:])
+
+	} else if rest, err := asn1.Unmarshal(signature, &sig); err != nil || len(rest) > 0 {
+
+		return false
+
+	}
+
+	curve := Secp256k1()
+	n := curve.Params().N
+	halfN := new(big.Int).Rsh(n, 1)
+
+	if sig.R.Sign() <= 0 || sig.S.Sign() <= 0 || sig.R.Cmp(n) >= 0 || sig.S.Cmp(halfN) > 0 {
+		return false
+	}
+
+	if !curve.IsOnCurve(key.X, key.Y) {
+		return false
+	}
+
+	e := hashToInt(digest, n)
+
+	w := new(big.Int).ModInverse(sig.S, n)
+
+	u1 := new(big.Int).Mul(e, w)
+	u1.Mod(u1, n)
+
+	u2 := new(big.Int).Mul(sig.R, w)
+	u2.Mod(u2, n)
+
+	x1, y1 := curve.ScalarBaseMult(u1.Bytes())
+	x2, y2 := curve.ScalarMult(key.X, key.Y, u2.Bytes())
+	x, y := curve.Add(x1, y1, x2, y2)
+
+	if x.Sign() == 0 && y.Sign() == 0 {
+		return false
+	}
+
+	x.Mod(x, n)
+
+	return x.Cmp(sig.R) == 0
+
+}
+
+// signSecp256k1 returns the low _s_ signature of _digest_.
+func signSecp256k1(key *ecdsa.PrivateKey, digest []byte) (*big.Int, *big.Int, error) {
+
+	curve := Secp256k1()
+	n := curve.Params().N
+
+	if key.Curve != curve {
+		return nil, nil, fmt.Errorf("key is not a secp256k1 key")
+	}
+
+	e := hashToInt(digest, n)
+
+	for attempt := 0; ; attempt++ {
+
+		k := rfc6979Nonce(key.D, digest, n, attempt)
+
+		x, _ := curve.ScalarBaseMult(k.FillBytes(make([]byte, 32)))
+
+		r := new(big.Int).Mod(x, n)
+		if r.Sign() == 0 {
+			continue
+		}
+
+		s := new(big.Int).Mul(r, key.D)
+		s.Add(s, e)
+		s.Mul(s, new(big.Int).ModInverse(k, n))
+		s.Mod(s, n)
+
+		if s.Sign() == 0 {
+			continue
+		}
+
+		if s.Cmp(new(big.Int).Rsh(n, 1)) > 0 {
+			s.Sub(n, s)
+		}
+
+		return r, s, nil
+
+	}
+
+}
+
+// hashToInt converts the _digest_ to a integer as of _SEC 1_ section 4.1.3, step 5.
+func hashToInt(digest []byte, n *big.Int) *big.Int {
+
+	orderBytes := (n.BitLen() + 7) / 8
+
+	if len(digest) > orderBytes {
+		digest = digest[:orderBytes]
+	}
+
+	e := new(big.Int).SetBytes(digest)
+
+	if excess := len(digest)*8 - n.BitLen(); excess > 0 {
+		e.Rsh(e, uint(excess))
+	}
+
+	return e
+
+}
+
+// rfc6979Nonce generates the deterministic nonce of _RFC 6979_ section 3.2 using _HMAC-SHA256_.
+//
+// The _skip_ is the number of valid candidates to skip, used when a candidate resulted in
+// a zero _r_ or _s_.
+func rfc6979Nonce(d *big.Int, digest []byte, n *big.Int, skip int) *big.Int {
+
+	x := d.FillBytes(make([]byte, 32))
+	h := new(big.Int).Mod(hashToInt(digest, n), n).FillBytes(make([]byte, 32))
+
+	v := make([]byte, 32)
+	for i := range v {
+		v[i] = 1
+	}
+
+	k := make([]byte, 32)
+
+	mac := func(key []byte, data ...[]byte) []byte {
+
+		m := hmac.New(sha256.New, key)
+		for _, d := range data {
+			m.Write(d)
+		}
+
+		return m.Sum(nil)
+
+	}
+
+	k = mac(k, v, []byte{0}, x, h)
+	v = mac(k, v)
+	k = mac(k, v, []byte{1}, x, h)
+	v = mac(k, v)
+
+	for {
+
+		v = mac(k, v)
+		candidate := new(big.Int).SetBytes(v)
+
+		if candidate.Sign() > 0 && candidate.Cmp(n) < 0 {
+
+			if skip == 0 {
+				return candidate
+			}
+
+			skip--
+
+		}
+
+		k = mac(k, v, []byte{0})
+		v = mac(k, v)
+
+	}
+
+}
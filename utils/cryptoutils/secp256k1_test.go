@@ -0,0 +1,75 @@
+package cryptoutils
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/pem"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSecp256k1RFC6979Signature(t *testing.T) {
+
+	d := make([]byte, 32)
+	d[31] = 1
+
+	key, err := Secp256k1PrivateKeyFromBytes(d)
+	require.NoError(t, err)
+
+	// Private key one is the generator
+	assert.Equal(t, Secp256k1().Params().Gx, key.X)
+
+	digest := sha256.Sum256([]byte("Satoshi Nakamoto"))
+
+	sig, err := SignSecp256k1Raw(key, digest[:])
+	require.NoError(t, err)
+
+	assert.Equal(t,
+		"934b1ea10a4b3c1757e2b0c017d0b6143ce3c9a7e6a4a49860d7a6ab210ee3d8"+
+			"2442ce9d2b916064108014783e923ec36b49743e2ffa1c4496f01a512aafd9e5",
+		hex.EncodeToString(sig),
+	)
+
+	assert.True(t, VerifySecp256k1(&key.PublicKey, digest[:], sig))
+
+	der, err := SignSecp256k1(key, digest[:])
+	require.NoError(t, err)
+	assert.True(t, VerifySecp256k1(&key.PublicKey, digest[:], der))
+
+	other := sha256.Sum256([]byte("tampered"))
+	assert.False(t, VerifySecp256k1(&key.PublicKey, other[:], sig))
+
+}
+
+func TestSecp256k1PEMAndPointEncoding(t *testing.T) {
+
+	d, _ := hex.DecodeString("c9afa9d845ba75166b5c215767b1d6934e50c3db36e89b127b8a622b120f6721")
+
+	key, err := Secp256k1PrivateKeyFromBytes(d)
+	require.NoError(t, err)
+
+	var buf bytes.Buffer
+	require.NoError(t, Secp256k1PrivateKeyToPEM(&buf, key, true))
+
+	privBlock, rest := pem.Decode(buf.Bytes())
+	require.NotNil(t, privBlock)
+	pubBlock, _ := pem.Decode(rest)
+	require.NotNil(t, pubBlock)
+
+	loaded, err := ParseSecp256k1PrivateKey(privBlock.Bytes)
+	require.NoError(t, err)
+	assert.Equal(t, key.D, loaded.D)
+
+	pub, err := ParseSecp256k1PublicKey(pubBlock.Bytes)
+	require.NoError(t, err)
+	assert.Equal(t, key.X, pub.X)
+	assert.Equal(t, key.Y, pub.Y)
+
+	compressed, err := Secp256k1PublicKeyFromBytes(Secp256k1PublicKeyBytes(&key.PublicKey, true))
+	require.NoError(t, err)
+	assert.Equal(t, key.Y, compressed.Y)
+
+}
@@ -0,0 +1,156 @@
+package cryptoutils
+
+import (
+	"crypto/ecdsa"
+	"encoding/asn1"
+	"encoding/pem"
+	"fmt"
+	"io"
+)
+
+var (
+	// oidECPublicKey is the _RFC 5480_ id-ecPublicKey algorithm.
+	oidECPublicKey = asn1.ObjectIdentifier{1, 2, 840, 10045, 2, 1}
+	// oidSecp256k1 is the _SEC 2_ named curve _secp256k1_.
+	oidSecp256k1 = asn1.ObjectIdentifier{1, 3, 132, 0, 10}
+)
+
+// sec1PrivateKey is the _RFC 5915_ EC private key structure.
+type sec1PrivateKey struct {
+	Version       int
+	PrivateKey    []byte
+	NamedCurveOID asn1.ObjectIdentifier `asn1:"optional,explicit,tag:0"`
+	PublicKey     asn1.BitString        `asn1:"optional,explicit,tag:1"`
+}
+
+// ecAlgorithmIdentifier is the algorithm identifier of a named curve key.
+type ecAlgorithmIdentifier struct {
+	Algorithm  asn1.ObjectIdentifier
+	NamedCurve asn1.ObjectIdentifier
+}
+
+// ecPKCS8 is the _RFC 5208_ private key info of a EC key.
+type ecPKCS8 struct {
+	Version    int
+	Algorithm  ecAlgorithmIdentifier
+	PrivateKey []byte
+}
+
+// ecPKIX is the _RFC 5280_ subject public key info of a EC key.
+type ecPKIX struct {
+	Algorithm ecAlgorithmIdentifier
+	PublicKey asn1.BitString
+}
+
+// Secp256k1PrivateKeyToPEM writes the _secp256k1_ private key onto _w_ as a _SEC 1_
+// _EC PRIVATE KEY_ PEM block, since `x509` do not support the curve.
+//
+// If _public_ is set to `true`, it will include the _PKIX_ public key as well.
+func Secp256k1PrivateKeyToPEM(w io.Writer, key *ecdsa.PrivateKey, public bool) error {
+
+	if key == nil || key.Curve != Secp256k1() {
+		return fmt.Errorf("must specify secp256k1 private key to write")
+	}
+
+	der, err := asn1.Marshal(sec1PrivateKey{
+		Version:       1,
+		PrivateKey:    key.D.FillBytes(make([]byte, 32)),
+		NamedCurveOID: oidSecp256k1,
+		PublicKey:     asn1.BitString{Bytes: Secp256k1PublicKeyBytes(&key.PublicKey, false), BitLength: 65 * 8},
+	})
+
+	if err != nil {
+		return err
+	}
+
+	if err := pem.Encode(w, &pem.Block{Type: "EC PRIVATE KEY", Bytes: der}); err != nil {
+		return err
+	}
+
+	if public {
+		return Secp256k1PublicKeyToPEM(w, &key.PublicKey)
+	}
+
+	return nil
+
+}
+
+// Secp256k1PublicKeyToPEM writes the _secp256k1_ public key onto _w_ as a _PKIX_ PEM block.
+func Secp256k1PublicKeyToPEM(w io.Writer, key *ecdsa.PublicKey) error {
+
+	if key == nil || key.Curve != Secp256k1() {
+		return fmt.Errorf("must specify secp256k1 public key to write")
+	}
+
+	der, err := asn1.Marshal(ecPKIX{
+		Algorithm: ecAlgorithmIdentifier{Algorithm: oidECPublicKey, NamedCurve: oidSecp256k1},
+		PublicKey: asn1.BitString{Bytes: Secp256k1PublicKeyBytes(key, false), BitLength: 65 * 8},
+	})
+
+	if err != nil {
+		return err
+	}
+
+	return pem.Encode(w, &pem.Block{Type: "PUBLIC KEY", Bytes: der})
+
+}
+
+// ParseSecp256k1PrivateKey parses a _SEC 1_ (_EC PRIVATE KEY_) or _PKCS #8_ (_PRIVATE KEY_)
+// _DER_ encoded _secp256k1_ private key.
+func ParseSecp256k1PrivateKey(der []byte) (*ecdsa.PrivateKey, error) {
+
+	var pkcs8 ecPKCS8
+
+	if _, err := asn1.Unmarshal(der, &pkcs8); err == nil && pkcs8.Algorithm.Algorithm.Equal(oidECPublicKey) {
+
+		if !pkcs8.Algorithm.NamedCurve.Equal(oidSecp256k1) {
+			return nil, fmt.Errorf("not a secp256k1 key: %s", pkcs8.Algorithm.NamedCurve)
+		}
+
+		der = pkcs8.PrivateKey
+
+	}
+
+	var sec1 sec1PrivateKey
+
+	if _, err := asn1.Unmarshal(der, &sec1); err != nil {
+		return nil, err
+	}
+
+	if sec1.Version != 1 {
+		return nil, fmt.Errorf("unsupported EC private key version: %d", sec1.Version)
+	}
+
+	if len(sec1.NamedCurveOID) > 0 && !sec1.NamedCurveOID.Equal(oidSecp256k1) {
+		return nil, fmt.Errorf("not a secp256k1 key: %s", sec1.NamedCurveOID)
+	}
+
+	if len(sec1.PrivateKey) > 32 {
+		return nil, fmt.Errorf("invalid secp256k1 private key length")
+	}
+
+	d := make([]byte, 32)
+	copy(d[32-len(sec1.PrivateKey):], sec1.PrivateKey)
+
+	return Secp256k1PrivateKeyFromBytes(d)
+
+}
+
+// ParseSecp256k1PublicKey parses a _PKIX_ _DER_ encoded _secp256k1_ public key.
+func ParseSecp256k1PublicKey(der []byte) (*ecdsa.PublicKey, error) {
+
+	var info ecPKIX
+
+	if rest, err := asn1.Unmarshal(der, &info); err != nil {
+		return nil, err
+	} else if len(rest) > 0 {
+		return nil, fmt.Errorf("trailing data after secp256k1 public key")
+	}
+
+	if !info.Algorithm.Algorithm.Equal(oidECPublicKey) || !info.Algorithm.NamedCurve.Equal(oidSecp256k1) {
+		return nil, fmt.Errorf("not a secp256k1 public key")
+	}
+
+	return Secp256k1PublicKeyFromBytes(info.PublicKey.RightAlign())
+
+}
@@ -0,0 +1,67 @@
+package cryptoutils
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFernetSpecVector(t *testing.T) {
+
+	key, err := ParseFernetKey("cw_0x689RpI-jtRR7oE8h_eQsKImvJapLeSbXpwF4e4=")
+	require.NoError(t, err)
+
+	f, err := NewFernet(key)
+	require.NoError(t, err)
+
+	at, _ := time.Parse(time.RFC3339, "1985-10-26T01:20:00-07:00")
+	iv := []byte{0, 1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15}
+
+	token, err := f.encode([]byte("hello"), at, iv)
+	require.NoError(t, err)
+
+	expected := "gAAAAAAdwJ6wAAECAwQFBgcICQoLDA0ODy021cpGVWKZ_eEwCGM4BLLF_5CV9dOPmrhuVUPgJobwOz7JcbmrR64jVmpU4IwqDA=="
+	assert.Equal(t, expected, token)
+
+	payload, err := f.DecodeAt(token, time.Minute, at.Add(time.Second))
+	require.NoError(t, err)
+	assert.Equal(t, "hello", string(payload))
+
+	_, err = f.DecodeAt(token, time.Minute, at.Add(2*time.Minute))
+	assert.Equal(t, ErrTokenExpired, err)
+
+	_, err = f.DecodeAt(token[:len(token)-4]+"AAA=", 0, at)
+	assert.Equal(t, ErrInvalidToken, err)
+
+}
+
+func TestBrancaSpecVector(t *testing.T) {
+
+	b, err := NewBranca([]byte("supersecretkeyyoushouldnotcommit"))
+	require.NoError(t, err)
+
+	nonce := bytes.Repeat([]byte{1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12}, 2)
+
+	token, err := b.encode([]byte("Hello world!"), time.Unix(123206400, 0), nonce)
+	require.NoError(t, err)
+
+	assert.Equal(t, "875GH233T7IYrxtgXxlQBYiFobZMQdHAT51vChKsAIYCFxZtL1evV54vYqLyZtQ0ekPHt8kJHQp0a", token)
+
+	payload, err := b.DecodeAt(token, 0, time.Now())
+	require.NoError(t, err)
+	assert.Equal(t, "Hello world!", string(payload))
+
+	_, err = b.DecodeAt(token, time.Hour, time.Now())
+	assert.Equal(t, ErrTokenExpired, err)
+
+	fresh, err := b.Encode([]byte{0, 1, 2})
+	require.NoError(t, err)
+
+	payload, err = b.Decode(fresh, time.Hour)
+	require.NoError(t, err)
+	assert.Equal(t, []byte{0, 1, 2}, payload)
+
+}
@@ -0,0 +1,200 @@
+package cryptoutils
+
+import (
+	"encoding/asn1"
+	"encoding/pem"
+	"fmt"
+	"io"
+
+	"golang.org/x/crypto/curve25519"
+)
+
+// oidX25519 is the _RFC 8410_ algorithm identifier of _X25519_.
+var oidX25519 = asn1.ObjectIdentifier{1, 3, 101, 110}
+
+// x25519AlgorithmIdentifier is the _RFC 5280_ algorithm identifier, without parameters.
+type x25519AlgorithmIdentifier struct {
+	Algorithm asn1.ObjectIdentifier
+}
+
+// x25519PKCS8 is the _RFC 5208_ private key info.
+type x25519PKCS8 struct {
+	Version    int
+	Algorithm  x25519AlgorithmIdentifier
+	PrivateKey []byte
+}
+
+// x25519PKIX is the _RFC 5280_ subject public key info.
+type x25519PKIX struct {
+	Algorithm x25519AlgorithmIdentifier
+	PublicKey asn1.BitString
+}
+
+// X25519PrivateKeyToPEM writes the 32 byte _X25519_ private key onto _w_ as a _PKCS #8_ PEM block.
+//
+// If _public_ is set to `true`, it will include the _PKIX_ public key as well.
+func X25519PrivateKeyToPEM(w io.Writer, key []byte, public bool) error {
+
+	if len(key) != curve25519.ScalarSize {
+		return fmt.Errorf("must specify private key to write")
+	}
+
+	// RFC 8410 section 7, the private key is a OCTET STRING within the OCTET STRING
+	inner, err := asn1.Marshal(key)
+	if err != nil {
+		return err
+	}
+
+	privateKeyBytes, err := asn1.Marshal(x25519PKCS8{
+		Algorithm:  x25519AlgorithmIdentifier{Algorithm: oidX25519},
+		PrivateKey: inner,
+	})
+
+	if err != nil {
+		return err
+	}
+
+	if err := pem.Encode(w, &pem.Block{Type: "PRIVATE KEY", Bytes: privateKeyBytes}); err != nil {
+		return err
+	}
+
+	if public {
+
+		pub, err := curve25519.X25519(key, curve25519.Basepoint)
+		if err != nil {
+			return err
+		}
+
+		return X25519PublicKeyToPEM(w, pub)
+
+	}
+
+	return nil
+
+}
+
+// X25519PublicKeyToPEM writes the 32 byte _X25519_ public key onto _w_ as a _PKIX_ PEM block.
+func X25519PublicKeyToPEM(w io.Writer, key []byte) error {
+
+	if len(key) != curve25519.PointSize {
+		return fmt.Errorf("must specify public key to write")
+	}
+
+	publicKeyBytes, err := asn1.Marshal(x25519PKIX{
+		Algorithm: x25519AlgorithmIdentifier{Algorithm: oidX25519},
+		PublicKey: asn1.BitString{Bytes: key, BitLength: len(key) * 8},
+	})
+
+	if err != nil {
+		return err
+	}
+
+	return pem.Encode(w, &pem.Block{Type: "PUBLIC KEY", Bytes: publicKeyBytes})
+
+}
+
+// ParseX25519PKCS8PrivateKey parses a _DER_ encoded _PKCS #8_ _X25519_ private key.
+func ParseX25519PKCS8PrivateKey(der []byte) ([]byte, error) {
+
+	var info x25519PKCS8
+
+	if rest, err := asn1.Unmarshal(der, &info); err != nil {
+		return nil, err
+	} else if len(rest) > 0 {
+		return nil, fmt.Errorf("trailing data after x25519 private key")
+	}
+
+	if !info.Algorithm.Algorithm.Equal(oidX25519) {
+		return nil, fmt.Errorf("not a x25519 private key: %s", info.Algorithm.Algorithm)
+	}
+
+	var key []byte
+
+	if _, err := asn1.Unmarshal(info.PrivateKey, &key); err != nil {
+		return nil, err
+	}
+
+	if len(key) != curve25519.ScalarSize {
+		return nil, fmt.Errorf("invalid x25519 private key size: %d", len(key))
+	}
+
+	return key, nil
+
+}
+
+// ParseX25519PKIXPublicKey parses a _DER_ encoded _PKIX_ _X25519_ public key.
+func ParseX25519PKIXPublicKey(der []byte) ([]byte, error) {
+
+	var info x25519PKIX
+
+	if rest, err := asn1.Unmarshal(der, &info); err != nil {
+		return nil, err
+	} else if len(rest) > 0 {
+		return nil, fmt.Errorf("trailing data after x25519 public key")
+	}
+
+	if !info.Algorithm.Algorithm.Equal(oidX25519) {
+		return nil, fmt.Errorf("not a x25519 public key: %s", info.Algorithm.Algorithm)
+	}
+
+	if info.PublicKey.BitLength != curve25519.PointSize*8 {
+		return nil, fmt.Errorf("invalid x25519 public key size: %d bits", info.PublicKey.BitLength)
+	}
+
+	return info.PublicKey.Bytes, nil
+
+}
+
+// PEMToX25519PrivateKey parses the first _PKCS #8_ _X25519_ private key in _data_.
+func PEMToX25519PrivateKey(data []byte) ([]byte, error) {
+
+	keys, err := PEMToKey("", data,
+		func(fqPath string, block *pem.Block) (key interface{}, stop bool, err error) {
+
+			if k, err := ParseX25519PKCS8PrivateKey(block.Bytes); err == nil {
+				key = k
+				stop = true
+			}
+
+			return
+
+		}, "PRIVATE KEY")
+
+	if err != nil {
+		return nil, err
+	}
+
+	if len(keys) == 0 {
+		return nil, fmt.Errorf("no x25519 private key found")
+	}
+
+	return keys[0].([]byte), nil
+
+}
+
+// PEMToX25519PublicKey parses the first _PKIX_ _X25519_ public key in _data_.
+func PEMToX25519PublicKey(data []byte) ([]byte, error) {
+
+	keys, err := PEMToKey("", data,
+		func(fqPath string, block *pem.Block) (key interface{}, stop bool, err error) {
+
+			if k, err := ParseX25519PKIXPublicKey(block.Bytes); err == nil {
+				key = k
+				stop = true
+			}
+
+			return
+
+		}, "PUBLIC KEY")
+
+	if err != nil {
+		return nil, err
+	}
+
+	if len(keys) == 0 {
+		return nil, fmt.Errorf("no x25519 public key found")
+	}
+
+	return keys[0].([]byte), nil
+
+}